@@ -1,15 +1,27 @@
 package mesh
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
 
 type ClientOptions struct {
-	servers  []string
-	username string
-	password string
+	servers           []string
+	username          string
+	password          string
+	maxReconnects     int
+	reconnectWait     time.Duration
+	reconnectHandler  func()
+	disconnectHandler func(error)
 }
 
 func NewClientOptions() *ClientOptions {
-	return &ClientOptions{}
+	return &ClientOptions{
+		maxReconnects: nats.DefaultMaxReconnect,
+		reconnectWait: nats.DefaultReconnectWait,
+	}
 }
 
 func (opt *ClientOptions) WithServers(servers ...string) *ClientOptions {
@@ -23,12 +35,63 @@ func (opt *ClientOptions) WithAuth(username, password string) *ClientOptions {
 	return opt
 }
 
+// WithMaxReconnects sets how many times the client tries to reconnect after
+// losing its server before giving up and closing. A negative value retries
+// forever.
+func (opt *ClientOptions) WithMaxReconnects(n int) *ClientOptions {
+	opt.maxReconnects = n
+	return opt
+}
+
+// WithReconnectWait sets the delay between reconnect attempts to the same
+// server.
+func (opt *ClientOptions) WithReconnectWait(wait time.Duration) *ClientOptions {
+	opt.reconnectWait = wait
+	return opt
+}
+
+// WithReconnectHandler is called each time the client reconnects.
+func (opt *ClientOptions) WithReconnectHandler(handler func()) *ClientOptions {
+	opt.reconnectHandler = handler
+	return opt
+}
+
+// WithDisconnectHandler is called each time the client loses its server,
+// with the error that caused it if any.
+func (opt *ClientOptions) WithDisconnectHandler(handler func(error)) *ClientOptions {
+	opt.disconnectHandler = handler
+	return opt
+}
+
+func (opt *ClientOptions) toNATSOptions() []nats.Option {
+	opts := []nats.Option{
+		nats.MaxReconnects(opt.maxReconnects),
+		nats.ReconnectWait(opt.reconnectWait),
+	}
+
+	if opt.reconnectHandler != nil {
+		handler := opt.reconnectHandler
+		opts = append(opts, nats.ReconnectHandler(func(*nats.Conn) {
+			handler()
+		}))
+	}
+
+	if opt.disconnectHandler != nil {
+		handler := opt.disconnectHandler
+		opts = append(opts, nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			handler(err)
+		}))
+	}
+
+	return opts
+}
+
 type Client struct {
 	nc *conn
 }
 
 func NewClient(opt *ClientOptions) (*Client, error) {
-	nc, err := newClientConn(opt.servers, opt.username, opt.password)
+	nc, err := newClientConn(opt.servers, opt.username, opt.password, opt.toNATSOptions()...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create nats client connection: %w", err)
 	}
@@ -43,3 +106,37 @@ func (c *Client) Close() {
 		c.nc.Close()
 	}
 }
+
+// ConnStatus is the state of a client's connection to its server.
+type ConnStatus string
+
+const (
+	ConnStatusConnecting   ConnStatus = "connecting"
+	ConnStatusConnected    ConnStatus = "connected"
+	ConnStatusReconnecting ConnStatus = "reconnecting"
+	ConnStatusDisconnected ConnStatus = "disconnected"
+	ConnStatusDraining     ConnStatus = "draining"
+	ConnStatusClosed       ConnStatus = "closed"
+)
+
+// Status reports the current state of the client's connection.
+func (c *Client) Status() ConnStatus {
+	if c.nc == nil || c.nc.conn == nil {
+		return ConnStatusClosed
+	}
+
+	switch c.nc.conn.Status() {
+	case nats.CONNECTING:
+		return ConnStatusConnecting
+	case nats.CONNECTED:
+		return ConnStatusConnected
+	case nats.RECONNECTING:
+		return ConnStatusReconnecting
+	case nats.DRAINING_SUBS, nats.DRAINING_PUBS:
+		return ConnStatusDraining
+	case nats.CLOSED:
+		return ConnStatusClosed
+	default:
+		return ConnStatusDisconnected
+	}
+}
@@ -1,10 +1,12 @@
 package mesh
 
 import (
+	"context"
 	"io"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/rivulet-io/tower/op"
 )
 
 // Ensure Client implements WrapConn interface
@@ -43,6 +45,22 @@ func (c *Client) PublishVolatileBatch(messages []struct {
 	return c.nc.PublishVolatileBatch(messages)
 }
 
+func (c *Client) Request(subject string, payload op.PrimitiveData, timeout time.Duration) (op.PrimitiveData, error) {
+	return c.nc.Request(subject, payload, timeout)
+}
+
+func (c *Client) RequestWithContext(ctx context.Context, subject string, payload op.PrimitiveData) (op.PrimitiveData, error) {
+	return c.nc.RequestWithContext(ctx, subject, payload)
+}
+
+func (c *Client) RespondTo(subject string, handler func(payload op.PrimitiveData) (op.PrimitiveData, error)) (cancel func(), err error) {
+	return c.nc.RespondTo(subject, handler)
+}
+
+func (c *Client) RespondToWithContext(ctx context.Context, subject string, handler func(ctx context.Context, payload op.PrimitiveData) (op.PrimitiveData, error)) (cancel func(), err error) {
+	return c.nc.RespondToWithContext(ctx, subject, handler)
+}
+
 func (c *Client) FlushTimeout(timeout time.Duration) error {
 	return c.nc.FlushTimeout(timeout)
 }
@@ -56,6 +74,14 @@ func (c *Client) SubscribeStreamViaDurable(subscriberID string, subject string,
 	return c.nc.SubscribeStreamViaDurable(subscriberID, subject, handler, errHandler, opt...)
 }
 
+func (c *Client) SubscribeStreamConcurrent(subscriberID string, subject string, concurrency int, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	return c.nc.SubscribeStreamConcurrent(subscriberID, subject, concurrency, handler, errHandler, opt...)
+}
+
+func (c *Client) SubscribeStreamViaDurableWithDeadLetter(subscriberID string, subject string, option DeadLetterOptions, handler func(subject string, msg []byte, delivered int) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	return c.nc.SubscribeStreamViaDurableWithDeadLetter(subscriberID, subject, option, handler, errHandler, opt...)
+}
+
 func (c *Client) PullPersistentViaDurable(subscriberID string, subject string, option PullOptions, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
 	return c.nc.PullPersistentViaDurable(subscriberID, subject, option, handler, errHandler, opt...)
 }
@@ -64,10 +90,22 @@ func (c *Client) SubscribePersistentViaEphemeral(subject string, handler func(su
 	return c.nc.SubscribePersistentViaEphemeral(subject, handler, errHandler, opt...)
 }
 
+func (c *Client) SubscribeStreamFrom(subject string, start StreamStartPolicy, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	return c.nc.SubscribeStreamFrom(subject, start, handler, errHandler, opt...)
+}
+
 func (c *Client) PullPersistentViaEphemeral(subject string, option PullOptions, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
 	return c.nc.PullPersistentViaEphemeral(subject, option, handler, errHandler, opt...)
 }
 
+func (c *Client) ConsumeByHandler(streamName string, routes map[string]func(subject string, msg []byte) error, errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	return c.nc.ConsumeByHandler(streamName, routes, errHandler, opt...)
+}
+
+func (c *Client) ConsumeByRouter(streamName string, router *SubjectRouter, errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	return c.nc.ConsumeByRouter(streamName, router, errHandler, opt...)
+}
+
 func (c *Client) PublishPersistent(subject string, msg []byte, opts ...nats.PubOpt) error {
 	return c.nc.PublishPersistent(subject, msg, opts...)
 }
@@ -76,6 +114,14 @@ func (c *Client) PublishPersistentWithOptions(subject string, msg []byte, opts .
 	return c.nc.PublishPersistentWithOptions(subject, msg, opts...)
 }
 
+func (c *Client) PublishPersistentBatch(subject string, msgs [][]byte) ([]*nats.PubAck, error) {
+	return c.nc.PublishPersistentBatch(subject, msgs)
+}
+
+func (c *Client) PublishPersistentReliable(subject string, msg []byte, opts ReliableOptions) (*nats.PubAck, error) {
+	return c.nc.PublishPersistentReliable(subject, msg, opts)
+}
+
 func (c *Client) DeleteStream(streamName string) error {
 	return c.nc.DeleteStream(streamName)
 }
@@ -84,11 +130,23 @@ func (c *Client) GetStreamInfo(streamName string) (*nats.StreamInfo, error) {
 	return c.nc.GetStreamInfo(streamName)
 }
 
+func (c *Client) StreamStats(streamName string) (StreamStats, error) {
+	return c.nc.StreamStats(streamName)
+}
+
+func (c *Client) ConsumerLag(streamName, durable string) (pending uint64, ackFloor uint64, err error) {
+	return c.nc.ConsumerLag(streamName, durable)
+}
+
 // KV Store operations
 func (c *Client) CreateKeyValueStore(cluster string, config KeyValueStoreConfig) error {
 	return c.nc.CreateKeyValueStore(cluster, config)
 }
 
+func (c *Client) CreateShardedKeyValueStore(cluster string, shards int, config KeyValueStoreConfig) error {
+	return c.nc.CreateShardedKeyValueStore(cluster, shards, config)
+}
+
 func (c *Client) GetFromKeyValueStore(bucket, key string) ([]byte, uint64, error) {
 	return c.nc.GetFromKeyValueStore(bucket, key)
 }
@@ -101,10 +159,18 @@ func (c *Client) UpdateToKeyValueStore(bucket, key string, value []byte, expecte
 	return c.nc.UpdateToKeyValueStore(bucket, key, value, expectedRevision)
 }
 
+func (c *Client) CreateKeyValue(bucket, key string, value []byte) (uint64, error) {
+	return c.nc.CreateKeyValue(bucket, key, value)
+}
+
 func (c *Client) DeleteFromKeyValueStore(bucket, key string) error {
 	return c.nc.DeleteFromKeyValueStore(bucket, key)
 }
 
+func (c *Client) DeleteKeyValueIfRevision(bucket, key string, expected uint64) error {
+	return c.nc.DeleteKeyValueIfRevision(bucket, key, expected)
+}
+
 func (c *Client) PurgeKeyValueStore(bucket, key string) error {
 	return c.nc.PurgeKeyValueStore(bucket, key)
 }
@@ -134,8 +200,8 @@ func (c *Client) CreateObjectStore(cluster string, config ObjectStoreConfig) err
 	return c.nc.CreateObjectStore(cluster, config)
 }
 
-func (c *Client) GetFromObjectStore(bucket, key string) ([]byte, error) {
-	return c.nc.GetFromObjectStore(bucket, key)
+func (c *Client) GetFromObjectStore(bucket, key string, opt ...ObjectGetOptions) ([]byte, error) {
+	return c.nc.GetFromObjectStore(bucket, key, opt...)
 }
 
 func (c *Client) PutToObjectStore(bucket, key string, data []byte, metadata map[string]string) error {
@@ -154,7 +220,7 @@ func (c *Client) GetFromObjectStoreStream(bucket, key string) (io.ReadCloser, er
 	return c.nc.GetFromObjectStoreStream(bucket, key)
 }
 
-func (c *Client) GetObjectInfo(bucket, key string) (*nats.ObjectInfo, error) {
+func (c *Client) GetObjectInfo(bucket, key string) (ObjectInfo, error) {
 	return c.nc.GetObjectInfo(bucket, key)
 }
 
@@ -178,6 +244,15 @@ func (c *Client) CopyObject(sourceBucket, sourceKey, destBucket, destKey string,
 	return c.nc.CopyObject(sourceBucket, sourceKey, destBucket, destKey, metadata)
 }
 
+// Stream archive operations
+func (c *Client) ArchiveStream(streamName, objectBucket string, upTo uint64, opt ...ArchiveOptions) (archived uint64, err error) {
+	return c.nc.ArchiveStream(streamName, objectBucket, upTo, opt...)
+}
+
+func (c *Client) ReplayArchive(objectBucket, archiveName, targetSubject string) (replayed uint64, err error) {
+	return c.nc.ReplayArchive(objectBucket, archiveName, targetSubject)
+}
+
 // Advisory operations
 func (c *Client) SubscribeLeaderChange(stream string, handler func(stream string, leader string, myName string), errHandler func(error)) (cancel func(), err error) {
 	return c.nc.SubscribeLeaderChange(stream, handler, errHandler)
@@ -1,10 +1,14 @@
 package mesh
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
 	"github.com/rivulet-io/tower/util/size"
 )
 
@@ -40,6 +44,15 @@ type ClusterOptions struct {
 	leafPassword             string
 	routes                   []string
 	httpPort                 int
+	accounts                 []*accountOptions
+}
+
+// accountOptions describes a tenant account hosted by the cluster.
+type accountOptions struct {
+	name      string
+	users     []*server.User
+	maxMemory size.Size
+	maxStore  size.Size
 }
 
 func NewClusterOptions(name string) *ClusterOptions {
@@ -142,6 +155,49 @@ func (opt *ClusterOptions) WithHTTPPort(port int) *ClusterOptions {
 	return opt
 }
 
+// WithAccount adds an isolated account named name. Subjects, streams, KV
+// buckets and object stores of one account are invisible to every other
+// account, including the default one that connections without account
+// credentials land in. Connect to an account with the users added by
+// WithAccountUser or through Cluster.Account.
+func (opt *ClusterOptions) WithAccount(name string) *ClusterOptions {
+	opt.account(name)
+	return opt
+}
+
+// WithAccountUser adds a user that clients log in as, with
+// ClientOptions.WithAuth, to work in account. The account is added if
+// needed.
+func (opt *ClusterOptions) WithAccountUser(account, username, password string) *ClusterOptions {
+	a := opt.account(account)
+	a.users = append(a.users, &server.User{Username: username, Password: password})
+	return opt
+}
+
+// WithAccountJetStream limits the memory and storage JetStream may use for
+// account. A zero size leaves that resource unlimited. The account is added
+// if needed. There is no per-account JetStream domain: the domain belongs
+// to the server, so every account shares the cluster's and stays isolated
+// through its own streams and buckets instead.
+func (opt *ClusterOptions) WithAccountJetStream(account string, maxMemory, maxStore size.Size) *ClusterOptions {
+	a := opt.account(account)
+	a.maxMemory = maxMemory
+	a.maxStore = maxStore
+	return opt
+}
+
+func (opt *ClusterOptions) account(name string) *accountOptions {
+	for _, a := range opt.accounts {
+		if a.name == name {
+			return a
+		}
+	}
+
+	a := &accountOptions{name: name}
+	opt.accounts = append(opt.accounts, a)
+	return a
+}
+
 func (opt *ClusterOptions) toNATSConfig() server.Options {
 	return server.Options{
 		ServerName: opt.serverName,
@@ -190,18 +246,138 @@ func (opt *ClusterOptions) toNATSConfig() server.Options {
 	}
 }
 
+// configureAccounts adds the accounts of opt and their users to so. Every
+// account also gets an internal user for Cluster.Account, returned by
+// account name, and connections without credentials are bound to the default
+// account as before.
+func (opt *ClusterOptions) configureAccounts(so *server.Options) (map[string]*server.User, error) {
+	if len(opt.accounts) == 0 {
+		return nil, nil
+	}
+
+	internal := make(map[string]*server.User, len(opt.accounts))
+	newInternalUser := func(name string, acc *server.Account) (*server.User, error) {
+		secret := make([]byte, 16)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate internal password: %w", err)
+		}
+
+		user := &server.User{Username: "$mesh-" + name, Password: hex.EncodeToString(secret), Account: acc}
+		so.Users = append(so.Users, user)
+		return user, nil
+	}
+
+	defaultUser, err := newInternalUser("default", nil)
+	if err != nil {
+		return nil, err
+	}
+	so.NoAuthUser = defaultUser.Username
+
+	for _, a := range opt.accounts {
+		acc := server.NewAccount(a.name)
+		so.Accounts = append(so.Accounts, acc)
+
+		for _, u := range a.users {
+			so.Users = append(so.Users, &server.User{Username: u.Username, Password: u.Password, Account: acc})
+		}
+
+		user, err := newInternalUser(a.name, acc)
+		if err != nil {
+			return nil, err
+		}
+		internal[a.name] = user
+	}
+
+	return internal, nil
+}
+
+// enableAccountJetStream turns JetStream on for the accounts of opt. The
+// server only does so by itself when it hosts nothing but the default
+// account, so that one is enabled here too.
+func (opt *ClusterOptions) enableAccountJetStream(srv *server.Server) error {
+	if len(opt.accounts) == 0 {
+		return nil
+	}
+
+	unlimited := server.JetStreamAccountLimits{
+		MaxMemory:            -1,
+		MaxStore:             -1,
+		MaxStreams:           -1,
+		MaxConsumers:         -1,
+		MaxAckPending:        -1,
+		MemoryMaxStreamBytes: -1,
+		StoreMaxStreamBytes:  -1,
+	}
+
+	if err := srv.GlobalAccount().EnableJetStream(map[string]server.JetStreamAccountLimits{"": unlimited}); err != nil {
+		return fmt.Errorf("failed to enable jetstream for default account: %w", err)
+	}
+
+	for _, a := range opt.accounts {
+		acc, err := srv.LookupAccount(a.name)
+		if err != nil {
+			return fmt.Errorf("failed to look up account %s: %w", a.name, err)
+		}
+
+		limits := unlimited
+		if a.maxMemory.Bytes() > 0 {
+			limits.MaxMemory = int64(a.maxMemory.Bytes())
+		}
+		if a.maxStore.Bytes() > 0 {
+			limits.MaxStore = int64(a.maxStore.Bytes())
+		}
+
+		if err := acc.EnableJetStream(map[string]server.JetStreamAccountLimits{"": limits}); err != nil {
+			return fmt.Errorf("failed to enable jetstream for account %s: %w", a.name, err)
+		}
+	}
+
+	return nil
+}
+
 type Cluster struct {
-	nc *conn
+	nc       *conn
+	accounts map[string]*server.User
 }
 
 func NewCluster(opt *ClusterOptions) (*Cluster, error) {
 	so := opt.toNATSConfig()
+	accounts, err := opt.configureAccounts(&so)
+	if err != nil {
+		return nil, err
+	}
+
 	nc, err := newServerConn(&so)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create nats connection: %w", err)
 	}
 
+	if err := opt.enableAccountJetStream(nc.server); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
 	return &Cluster{
+		nc:       nc,
+		accounts: accounts,
+	}, nil
+}
+
+// Account returns a client connected in-process to the account name, added
+// with ClusterOptions.WithAccount. Everything done through it stays within
+// that account. Close the client when done; it does not stop the node.
+func (c *Cluster) Account(name string) (*Client, error) {
+	user, ok := c.accounts[name]
+	if !ok {
+		return nil, fmt.Errorf("account %s is not configured", name)
+	}
+
+	nc, err := newClientConn([]string{c.nc.server.ClientURL()}, user.Username, user.Password, nats.InProcessServer(c.nc.server))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to account %s: %w", name, err)
+	}
+
+	return &Client{
 		nc: nc,
 	}, nil
 }
@@ -209,3 +385,110 @@ func NewCluster(opt *ClusterOptions) (*Cluster, error) {
 func (c *Cluster) Close() {
 	c.nc.Close()
 }
+
+// Drain gracefully closes the node, letting in-flight publishes and
+// deliveries finish first. It returns ErrDrainTimeout if that takes longer
+// than timeout.
+func (c *Cluster) Drain(timeout time.Duration) error {
+	return c.nc.Drain(timeout)
+}
+
+// PeerInfo describes one member of the JetStream meta cluster.
+type PeerInfo struct {
+	Name    string
+	Leader  bool
+	Current bool          // caught up with the leader
+	Offline bool          // not seen recently
+	Active  time.Duration // time since last seen by the leader
+	Lag     uint64        // operations behind the leader
+}
+
+// ClusterStatus is a point-in-time view of the JetStream meta cluster, as
+// seen from this node.
+type ClusterStatus struct {
+	Name             string
+	Leader           string // empty while an election is in progress
+	JetStreamEnabled bool
+	PeerCount        int
+	Peers            []PeerInfo
+}
+
+// ClusterHealth reports the state of the meta cluster. Replication state is
+// only tracked by the leader, so on other nodes Peers lists the members this
+// node is routed to with Current set and Active and Lag left zero.
+func (c *Cluster) ClusterHealth() (ClusterStatus, error) {
+	srv := c.nc.server
+	if srv == nil || !srv.Running() {
+		return ClusterStatus{}, fmt.Errorf("nats server is not running")
+	}
+
+	jsz, err := srv.Jsz(nil)
+	if err != nil {
+		return ClusterStatus{}, fmt.Errorf("failed to get jetstream info: %w", err)
+	}
+
+	status := ClusterStatus{
+		Name:             srv.ClusterName(),
+		JetStreamEnabled: !jsz.Disabled,
+	}
+	if jsz.Meta != nil {
+		status.Leader = jsz.Meta.Leader
+	}
+
+	self := PeerInfo{Name: srv.Name(), Leader: srv.Name() == status.Leader, Current: true}
+	status.Peers = append(status.Peers, self)
+
+	if self.Leader && jsz.Meta != nil {
+		for _, p := range jsz.Meta.Replicas {
+			status.Peers = append(status.Peers, PeerInfo{
+				Name:    p.Name,
+				Current: p.Current,
+				Offline: p.Offline,
+				Active:  p.Active,
+				Lag:     p.Lag,
+			})
+		}
+	} else {
+		routez, err := srv.Routez(nil)
+		if err != nil {
+			return ClusterStatus{}, fmt.Errorf("failed to get routes: %w", err)
+		}
+
+		// Route pooling opens several connections to the same server
+		seen := map[string]bool{self.Name: true}
+		for _, r := range routez.Routes {
+			if r.RemoteName == "" || seen[r.RemoteName] {
+				continue
+			}
+			seen[r.RemoteName] = true
+			status.Peers = append(status.Peers, PeerInfo{
+				Name:    r.RemoteName,
+				Leader:  r.RemoteName == status.Leader,
+				Current: true,
+			})
+		}
+	}
+
+	sort.Slice(status.Peers, func(i, j int) bool {
+		return status.Peers[i].Name < status.Peers[j].Name
+	})
+	status.PeerCount = len(status.Peers)
+
+	return status, nil
+}
+
+// IsLeader reports whether this node is the JetStream meta leader.
+func (c *Cluster) IsLeader() bool {
+	return c.nc.server != nil && c.nc.server.JetStreamIsLeader()
+}
+
+// Peers returns the members of the meta cluster, including this node. It
+// returns nil if the server is not running.
+func (c *Cluster) Peers() []PeerInfo {
+	status, err := c.ClusterHealth()
+	if err != nil {
+		return nil
+	}
+
+	return status.Peers
+}
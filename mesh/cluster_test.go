@@ -1,11 +1,15 @@
 package mesh
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/nats-io/nats.go"
+
 	"github.com/rivulet-io/tower/util/size"
 )
 
@@ -152,6 +156,14 @@ func SetupThreeNodeCluster(t *testing.T) (*Cluster, *Cluster, *Cluster) {
 		t.Fatalf("failed to create cluster node 3: %v", err)
 	}
 
+	// A failed wait must not leave the nodes holding the fixed ports
+	ready := false
+	defer func() {
+		if !ready {
+			CleanupClusters(cluster1, cluster2, cluster3)
+		}
+	}()
+
 	// Wait for all clusters to be ready
 	waitForClusterReady(t, cluster1, 10*time.Second)
 	waitForClusterReady(t, cluster2, 10*time.Second)
@@ -165,6 +177,7 @@ func SetupThreeNodeCluster(t *testing.T) (*Cluster, *Cluster, *Cluster) {
 	// Additional sleep to ensure cluster formation is complete
 	time.Sleep(2 * time.Second)
 
+	ready = true
 	return cluster1, cluster2, cluster3
 }
 
@@ -211,6 +224,163 @@ func TestThreeNodeCluster(t *testing.T) {
 	})
 }
 
+func TestClusterHealth(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	clusters := []*Cluster{cluster1, cluster2, cluster3}
+
+	// Leader election may still be settling right after setup
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		leaders := 0
+		for _, cluster := range clusters {
+			if cluster.IsLeader() {
+				leaders++
+			}
+		}
+		if leaders == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected exactly one leader, got %d", leaders)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	var leader string
+	for i, cluster := range clusters {
+		status, err := cluster.ClusterHealth()
+		if err != nil {
+			t.Fatalf("node %d: failed to get cluster health: %v", i+1, err)
+		}
+
+		if !status.JetStreamEnabled {
+			t.Errorf("node %d: expected JetStream to be enabled", i+1)
+		}
+		if status.PeerCount != 3 || len(status.Peers) != 3 {
+			t.Errorf("node %d: expected 3 peers, got %d: %+v", i+1, status.PeerCount, status.Peers)
+		}
+		if status.Leader == "" {
+			t.Errorf("node %d: expected a leader", i+1)
+		}
+		if leader == "" {
+			leader = status.Leader
+		} else if status.Leader != leader {
+			t.Errorf("node %d: expected leader %q, got %q", i+1, leader, status.Leader)
+		}
+
+		leaders := 0
+		for _, p := range status.Peers {
+			if p.Leader {
+				leaders++
+				if p.Name != status.Leader {
+					t.Errorf("node %d: peer %q flagged as leader, want %q", i+1, p.Name, status.Leader)
+				}
+			}
+		}
+		if leaders != 1 {
+			t.Errorf("node %d: expected one leader among peers, got %d", i+1, leaders)
+		}
+
+		if got := len(cluster.Peers()); got != 3 {
+			t.Errorf("node %d: expected Peers to return 3 entries, got %d", i+1, got)
+		}
+	}
+
+	cluster3.Close()
+	if _, err := cluster3.ClusterHealth(); err == nil {
+		t.Error("expected error from a closed node")
+	}
+	if cluster3.IsLeader() {
+		t.Error("expected a closed node not to be leader")
+	}
+}
+
+func TestClusterDrain(t *testing.T) {
+	t.Run("acked publishes survive drain", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		config := &PersistentConfig{
+			Name:     "drain",
+			Subjects: []string{"drain.*"},
+			Replicas: 3,
+		}
+		if err := cluster1.nc.CreateOrUpdateStream(config); err != nil {
+			t.Fatalf("failed to create stream: %v", err)
+		}
+
+		var acked atomic.Int64
+		started := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; ; i++ {
+				if i == 10 {
+					close(started)
+				}
+				if err := cluster3.nc.PublishPersistent("drain.events", []byte(fmt.Sprintf("event-%d", i))); err != nil {
+					return
+				}
+				acked.Add(1)
+			}
+		}()
+
+		<-started
+		if err := cluster3.Drain(10 * time.Second); err != nil {
+			t.Fatalf("failed to drain: %v", err)
+		}
+		<-done
+
+		// The stream and meta leaders may be re-electing after the drain
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			info, err := cluster1.nc.GetStreamInfo("drain")
+			if err == nil && info.State.Msgs >= uint64(acked.Load()) {
+				break
+			}
+			if time.Now().After(deadline) {
+				if err != nil {
+					t.Fatalf("failed to get stream info: %v", err)
+				}
+				t.Fatalf("expected at least %d stored messages, got %d", acked.Load(), info.State.Msgs)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		handling := make(chan struct{})
+		_, err := cluster3.nc.SubscribeVolatileViaFanout(
+			"drain.slow",
+			func(subj string, msg []byte, headers nats.Header) ([]byte, nats.Header, bool) {
+				close(handling)
+				time.Sleep(2 * time.Second)
+				return nil, nil, false
+			},
+			func(err error) {
+				t.Logf("Error in slow handler: %v", err)
+			},
+		)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+
+		if err := cluster3.nc.PublishVolatile("drain.slow", []byte("work")); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+		<-handling
+
+		if err := cluster3.Drain(100 * time.Millisecond); !errors.Is(err, ErrDrainTimeout) {
+			t.Errorf("expected ErrDrainTimeout, got %v", err)
+		}
+	})
+}
+
 // Test individual cluster creation with custom configuration
 func TestCustomClusterConfiguration(t *testing.T) {
 	t.Run("custom cluster config", func(t *testing.T) {
@@ -231,3 +401,119 @@ func TestCustomClusterConfiguration(t *testing.T) {
 		t.Log("Custom cluster configuration test passed")
 	})
 }
+
+// Test that accounts isolate the subjects and KV buckets of tenants sharing a node
+func TestClusterAccounts(t *testing.T) {
+	opts := NewClusterOptions("tenant-node").
+		WithListen("127.0.0.1", 4340).
+		WithStoreDir(t.TempDir()).
+		WithJetStreamMaxMemory(size.NewSizeFromMegabytes(50)).
+		WithJetStreamMaxStore(size.NewSizeFromMegabytes(100)).
+		WithAccount("tenant-a").
+		WithAccountUser("tenant-b", "bob", "b-secret").
+		WithAccountJetStream("tenant-b", size.NewSizeFromMegabytes(10), size.NewSizeFromMegabytes(20))
+
+	cluster, err := NewCluster(opts)
+	if err != nil {
+		t.Fatalf("failed to create cluster: %v", err)
+	}
+	defer cluster.Close()
+
+	tenantA, err := cluster.Account("tenant-a")
+	if err != nil {
+		t.Fatalf("failed to connect to tenant-a: %v", err)
+	}
+	defer tenantA.Close()
+
+	tenantB, err := NewClient(NewClientOptions().
+		WithServers("nats://127.0.0.1:4340").
+		WithAuth("bob", "b-secret"))
+	if err != nil {
+		t.Fatalf("failed to connect as tenant-b user: %v", err)
+	}
+	defer tenantB.Close()
+
+	if _, err := cluster.Account("missing"); err == nil {
+		t.Error("expected error for an unknown account")
+	}
+
+	subscribe := func(t *testing.T, c WrapConn) chan []byte {
+		t.Helper()
+
+		received := make(chan []byte, 4)
+		_, err := c.SubscribeVolatileViaFanout(
+			"orders.>",
+			func(subj string, msg []byte, headers nats.Header) ([]byte, nats.Header, bool) {
+				received <- msg
+				return nil, nil, false
+			},
+			func(err error) {
+				t.Logf("Error in handler: %v", err)
+			},
+		)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		if err := c.FlushTimeout(time.Second); err != nil {
+			t.Fatalf("failed to flush: %v", err)
+		}
+		return received
+	}
+
+	t.Run("subjects", func(t *testing.T) {
+		receivedA := subscribe(t, tenantA)
+		receivedDefault := subscribe(t, cluster)
+
+		if err := tenantB.PublishVolatile("orders.1", []byte("from-b")); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+		if err := tenantA.PublishVolatile("orders.2", []byte("from-a")); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+
+		select {
+		case msg := <-receivedA:
+			if string(msg) != "from-a" {
+				t.Errorf("expected tenant-a to receive only its own message, got %q", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for tenant-a message")
+		}
+
+		select {
+		case msg := <-receivedA:
+			t.Errorf("expected no message from tenant-b, got %q", msg)
+		case msg := <-receivedDefault:
+			t.Errorf("expected default account to receive nothing, got %q", msg)
+		case <-time.After(500 * time.Millisecond):
+		}
+	})
+
+	t.Run("key value", func(t *testing.T) {
+		for _, c := range []WrapConn{tenantA, tenantB} {
+			if err := c.CreateKeyValueStore("", KeyValueStoreConfig{Bucket: "config"}); err != nil {
+				t.Fatalf("failed to create bucket: %v", err)
+			}
+		}
+
+		if _, err := tenantA.PutToKeyValueStore("config", "mode", []byte("a")); err != nil {
+			t.Fatalf("failed to put: %v", err)
+		}
+		if _, err := tenantB.PutToKeyValueStore("config", "mode", []byte("b")); err != nil {
+			t.Fatalf("failed to put: %v", err)
+		}
+
+		if value, _, err := tenantA.GetFromKeyValueStore("config", "mode"); err != nil || string(value) != "a" {
+			t.Errorf("expected tenant-a value a, got %q (%v)", value, err)
+		}
+		if value, _, err := tenantB.GetFromKeyValueStore("config", "mode"); err != nil || string(value) != "b" {
+			t.Errorf("expected tenant-b value b, got %q (%v)", value, err)
+		}
+		if cluster.KeyValueStoreExists("config") {
+			t.Error("expected default account not to see tenant buckets")
+		}
+		if err := cluster.CreateKeyValueStore("", KeyValueStoreConfig{Bucket: "shared"}); err != nil {
+			t.Errorf("expected default account to keep jetstream, got %v", err)
+		}
+	})
+}
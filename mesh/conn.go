@@ -1,13 +1,17 @@
 package mesh
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
+	"github.com/rivulet-io/tower/op"
 )
 
 var _ server.Logger = (*DebugLogger)(nil)
@@ -84,6 +88,8 @@ type conn struct {
 	js       nats.JetStreamContext
 	logger   *DebugLogger
 	callback func(*NATSLog)
+
+	kvShardCache sync.Map // bucket -> shard count
 }
 
 func newServerConn(opt *server.Options) (*conn, error) {
@@ -132,9 +138,9 @@ func newServerConn(opt *server.Options) (*conn, error) {
 	return c, nil
 }
 
-func newClientConn(servers []string, username, password string) (*conn, error) {
+func newClientConn(servers []string, username, password string, opts ...nats.Option) (*conn, error) {
 	nc, err := nats.Connect(strings.Join(servers, ","),
-		nats.UserInfo(username, password),
+		append([]nats.Option{nats.UserInfo(username, password)}, opts...)...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to nats server: %w", err)
@@ -159,6 +165,35 @@ func (c *conn) Close() {
 	}
 }
 
+// ErrDrainTimeout is returned by Drain when in-flight work did not finish
+// before the deadline. The connection is closed regardless.
+var ErrDrainTimeout = errors.New("drain timed out")
+
+// Drain stops accepting new messages, lets subscriptions finish the messages
+// already delivered to them, flushes pending publishes and then closes like
+// Close.
+func (c *conn) Drain(timeout time.Duration) error {
+	closed := make(chan struct{})
+	c.conn.SetClosedHandler(func(*nats.Conn) {
+		close(closed)
+	})
+
+	if err := c.conn.Drain(); err != nil {
+		c.Close()
+		return fmt.Errorf("failed to drain connection: %w", err)
+	}
+
+	var err error
+	select {
+	case <-closed:
+	case <-time.After(timeout):
+		err = fmt.Errorf("%w after %s", ErrDrainTimeout, timeout)
+	}
+
+	c.Close()
+	return err
+}
+
 func (c *conn) SetLogCallback(cb func(*NATSLog)) {
 	c.callback = cb
 }
@@ -179,25 +214,41 @@ type WrapConn interface {
 		Data    []byte
 		Headers nats.Header
 	}) error
+	Request(subject string, payload op.PrimitiveData, timeout time.Duration) (op.PrimitiveData, error)
+	RequestWithContext(ctx context.Context, subject string, payload op.PrimitiveData) (op.PrimitiveData, error)
+	RespondTo(subject string, handler func(payload op.PrimitiveData) (op.PrimitiveData, error)) (cancel func(), err error)
+	RespondToWithContext(ctx context.Context, subject string, handler func(ctx context.Context, payload op.PrimitiveData) (op.PrimitiveData, error)) (cancel func(), err error)
 	FlushTimeout(timeout time.Duration) error
 
 	// Stream operations
 	CreateOrUpdateStream(cfg *PersistentConfig) error
 	SubscribeStreamViaDurable(subscriberID string, subject string, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error)
+	SubscribeStreamConcurrent(subscriberID string, subject string, concurrency int, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error)
+	SubscribeStreamViaDurableWithDeadLetter(subscriberID string, subject string, option DeadLetterOptions, handler func(subject string, msg []byte, delivered int) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error)
 	PullPersistentViaDurable(subscriberID string, subject string, option PullOptions, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error)
 	SubscribePersistentViaEphemeral(subject string, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error)
+	SubscribeStreamFrom(subject string, start StreamStartPolicy, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error)
 	PullPersistentViaEphemeral(subject string, option PullOptions, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error)
+	ConsumeByHandler(streamName string, routes map[string]func(subject string, msg []byte) error, errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error)
+	ConsumeByRouter(streamName string, router *SubjectRouter, errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error)
 	PublishPersistent(subject string, msg []byte, opts ...nats.PubOpt) error
 	PublishPersistentWithOptions(subject string, msg []byte, opts ...nats.PubOpt) (*nats.PubAck, error)
+	PublishPersistentReliable(subject string, msg []byte, opts ReliableOptions) (*nats.PubAck, error)
+	PublishPersistentBatch(subject string, msgs [][]byte) ([]*nats.PubAck, error)
 	DeleteStream(streamName string) error
 	GetStreamInfo(streamName string) (*nats.StreamInfo, error)
+	StreamStats(streamName string) (StreamStats, error)
+	ConsumerLag(streamName, durable string) (pending uint64, ackFloor uint64, err error)
 
 	// KV Store operations
 	CreateKeyValueStore(cluster string, config KeyValueStoreConfig) error
+	CreateShardedKeyValueStore(cluster string, shards int, config KeyValueStoreConfig) error
 	GetFromKeyValueStore(bucket, key string) ([]byte, uint64, error)
 	PutToKeyValueStore(bucket, key string, value []byte) (uint64, error)
 	UpdateToKeyValueStore(bucket, key string, value []byte, expectedRevision uint64) (uint64, error)
+	CreateKeyValue(bucket, key string, value []byte) (uint64, error)
 	DeleteFromKeyValueStore(bucket, key string) error
+	DeleteKeyValueIfRevision(bucket, key string, expected uint64) error
 	PurgeKeyValueStore(bucket, key string) error
 	DeleteKeyValueStore(bucket string) error
 	KeyValueStoreExists(bucket string) bool
@@ -207,18 +258,22 @@ type WrapConn interface {
 
 	// Object Store operations
 	CreateObjectStore(cluster string, config ObjectStoreConfig) error
-	GetFromObjectStore(bucket, key string) ([]byte, error)
+	GetFromObjectStore(bucket, key string, opt ...ObjectGetOptions) ([]byte, error)
 	PutToObjectStore(bucket, key string, data []byte, metadata map[string]string) error
 	DeleteFromObjectStore(bucket, key string) error
 	PutToObjectStoreStream(bucket, key string, reader io.Reader, metadata map[string]string) error
 	GetFromObjectStoreStream(bucket, key string) (io.ReadCloser, error)
-	GetObjectInfo(bucket, key string) (*nats.ObjectInfo, error)
+	GetObjectInfo(bucket, key string) (ObjectInfo, error)
 	ListObjects(bucket string) ([]*nats.ObjectInfo, error)
 	ObjectExists(bucket, key string) (bool, error)
 	DeleteObjectStore(bucket string) error
 	PutToObjectStoreChunked(bucket, key string, reader io.Reader, chunkSize int64, metadata map[string]string) error
 	CopyObject(sourceBucket, sourceKey, destBucket, destKey string, metadata map[string]string) error
 
+	// Stream archive operations
+	ArchiveStream(streamName, objectBucket string, upTo uint64, opt ...ArchiveOptions) (archived uint64, err error)
+	ReplayArchive(objectBucket, archiveName, targetSubject string) (replayed uint64, err error)
+
 	// Advisory operations
 	SubscribeLeaderChange(stream string, handler func(stream string, leader string, myName string), errHandler func(error)) (cancel func(), err error)
 }
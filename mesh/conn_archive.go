@@ -0,0 +1,282 @@
+package mesh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	ArchiveHeaderSubject   = "Tower-Archive-Subject"
+	ArchiveHeaderSequence  = "Tower-Archive-Stream-Seq"
+	ArchiveHeaderTimestamp = "Tower-Archive-Timestamp"
+)
+
+// archiveMagic starts every archive object, followed by a format version.
+const (
+	archiveMagic   = "TWRA"
+	archiveVersion = 1
+)
+
+type ArchiveOptions struct {
+	// Name of the archive object. Defaults to "<stream>-<first>-<last>"
+	// with the first and last archived sequence numbers.
+	Name string
+	// Purge removes the archived messages from the stream once the archive
+	// is stored.
+	Purge bool
+}
+
+// ArchiveStream copies the messages of streamName with sequence numbers up
+// to and including upTo into one object in objectBucket and returns how many
+// it archived. Subjects, headers and timestamps are kept, so the archive can
+// be re-ingested with ReplayArchive. Archive metadata records the stream and
+// sequence range.
+func (c *conn) ArchiveStream(streamName, objectBucket string, upTo uint64, opt ...ArchiveOptions) (archived uint64, err error) {
+	var option ArchiveOptions
+	if len(opt) > 0 {
+		option = opt[0]
+	}
+
+	info, err := c.js.StreamInfo(streamName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stream info for %q: %w", streamName, err)
+	}
+
+	first := info.State.FirstSeq
+	last := min(upTo, info.State.LastSeq)
+	if info.State.Msgs == 0 || first == 0 || last < first {
+		return 0, nil
+	}
+
+	name := option.Name
+	if name == "" {
+		name = fmt.Sprintf("%s-%d-%d", streamName, first, last)
+	}
+
+	reader, writer := io.Pipe()
+	written := make(chan uint64, 1)
+	go func() {
+		count, err := c.writeArchive(writer, streamName, first, last)
+		writer.CloseWithError(err)
+		written <- count
+	}()
+
+	err = c.PutToObjectStoreStream(objectBucket, name, reader, map[string]string{
+		"stream":    streamName,
+		"first_seq": strconv.FormatUint(first, 10),
+		"last_seq":  strconv.FormatUint(last, 10),
+	})
+	reader.Close()
+	archived = <-written
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive stream %q: %w", streamName, err)
+	}
+
+	if option.Purge {
+		if err := c.js.PurgeStream(streamName, &nats.StreamPurgeRequest{Sequence: last + 1}); err != nil {
+			return archived, fmt.Errorf("failed to purge stream %q after archiving: %w", streamName, err)
+		}
+	}
+
+	return archived, nil
+}
+
+// writeArchive encodes messages first..last of streamName to w. Each record
+// is the sequence, the timestamp in Unix nanoseconds and the length-prefixed
+// subject, headers and data; integers are varints. Deleted sequences are
+// skipped.
+func (c *conn) writeArchive(w io.Writer, streamName string, first, last uint64) (uint64, error) {
+	bw := bufio.NewWriter(w)
+
+	if _, err := bw.WriteString(archiveMagic); err != nil {
+		return 0, err
+	}
+	if err := bw.WriteByte(archiveVersion); err != nil {
+		return 0, err
+	}
+
+	var count uint64
+	var buf []byte
+	for seq := first; seq <= last; seq++ {
+		msg, err := c.js.GetMsg(streamName, seq)
+		if err != nil {
+			if errors.Is(err, nats.ErrMsgNotFound) {
+				continue
+			}
+			return count, fmt.Errorf("failed to get message %d from stream %q: %w", seq, streamName, err)
+		}
+
+		buf = buf[:0]
+		buf = binary.AppendUvarint(buf, msg.Sequence)
+		buf = binary.AppendVarint(buf, msg.Time.UnixNano())
+		buf = appendArchiveBytes(buf, []byte(msg.Subject))
+		buf = binary.AppendUvarint(buf, uint64(len(msg.Header)))
+		for key, values := range msg.Header {
+			buf = appendArchiveBytes(buf, []byte(key))
+			buf = binary.AppendUvarint(buf, uint64(len(values)))
+			for _, value := range values {
+				buf = appendArchiveBytes(buf, []byte(value))
+			}
+		}
+		buf = appendArchiveBytes(buf, msg.Data)
+
+		if _, err := bw.Write(buf); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, bw.Flush()
+}
+
+func appendArchiveBytes(buf, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+type archivedMsg struct {
+	sequence uint64
+	time     time.Time
+	subject  string
+	header   nats.Header
+	data     []byte
+}
+
+// ReplayArchive publishes every message of an archive written by
+// ArchiveStream to targetSubject, or to its original subject when
+// targetSubject is empty, and returns how many it published. Original
+// headers are kept and the original subject, stream sequence and timestamp
+// are added as Tower-Archive-* headers.
+func (c *conn) ReplayArchive(objectBucket, archiveName, targetSubject string) (replayed uint64, err error) {
+	obj, err := c.GetFromObjectStoreStream(objectBucket, archiveName)
+	if err != nil {
+		return 0, err
+	}
+	defer obj.Close()
+
+	r := bufio.NewReader(obj)
+
+	header := make([]byte, len(archiveMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, fmt.Errorf("failed to read archive %q: %w", archiveName, err)
+	}
+	if string(header[:len(archiveMagic)]) != archiveMagic || header[len(archiveMagic)] != archiveVersion {
+		return 0, fmt.Errorf("object %q is not a supported stream archive", archiveName)
+	}
+
+	for {
+		msg, err := readArchivedMsg(r)
+		if errors.Is(err, io.EOF) {
+			return replayed, nil
+		}
+		if err != nil {
+			return replayed, fmt.Errorf("failed to read archive %q: %w", archiveName, err)
+		}
+
+		out := nats.NewMsg(targetSubject)
+		if targetSubject == "" {
+			out.Subject = msg.subject
+		}
+		for key, values := range msg.header {
+			for _, value := range values {
+				out.Header.Add(key, value)
+			}
+		}
+		out.Header.Set(ArchiveHeaderSubject, msg.subject)
+		out.Header.Set(ArchiveHeaderSequence, strconv.FormatUint(msg.sequence, 10))
+		out.Header.Set(ArchiveHeaderTimestamp, msg.time.UTC().Format(time.RFC3339Nano))
+		out.Data = msg.data
+
+		if _, err := c.js.PublishMsg(out); err != nil {
+			return replayed, fmt.Errorf("failed to replay message %d to subject %q: %w", msg.sequence, out.Subject, err)
+		}
+		replayed++
+	}
+}
+
+// readArchivedMsg decodes one record, returning io.EOF at a clean end of
+// the archive.
+func readArchivedMsg(r *bufio.Reader) (*archivedMsg, error) {
+	seq, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	unexpected := func(err error) error {
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	nanos, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+
+	subject, err := readArchiveBytes(r)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+
+	headerCount, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+
+	var header nats.Header
+	if headerCount > 0 {
+		header = make(nats.Header, headerCount)
+	}
+	for i := uint64(0); i < headerCount; i++ {
+		key, err := readArchiveBytes(r)
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		valueCount, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		for j := uint64(0); j < valueCount; j++ {
+			value, err := readArchiveBytes(r)
+			if err != nil {
+				return nil, unexpected(err)
+			}
+			header[string(key)] = append(header[string(key)], string(value))
+		}
+	}
+
+	data, err := readArchiveBytes(r)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+
+	return &archivedMsg{
+		sequence: seq,
+		time:     time.Unix(0, nanos),
+		subject:  string(subject),
+		header:   header,
+		data:     data,
+	}, nil
+}
+
+func readArchiveBytes(r *bufio.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
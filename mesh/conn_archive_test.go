@@ -0,0 +1,176 @@
+package mesh
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rivulet-io/tower/util/size"
+)
+
+func TestJetStreamArchiveStream(t *testing.T) {
+	t.Run("archive purge and replay", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+			Name:     "orders",
+			Subjects: []string{"orders.*"},
+		})
+		if err != nil {
+			t.Fatalf("failed to create stream: %v", err)
+		}
+
+		err = cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+			Name:     "restored",
+			Subjects: []string{"restored.*"},
+		})
+		if err != nil {
+			t.Fatalf("failed to create stream: %v", err)
+		}
+
+		err = cluster1.nc.CreateObjectStore("test-cluster", ObjectStoreConfig{
+			Bucket:   "archives",
+			MaxBytes: size.Size(5 * 1024 * 1024),
+			Replicas: 1,
+		})
+		if err != nil {
+			t.Fatalf("failed to create object store bucket: %v", err)
+		}
+
+		for i := 1; i <= 5; i++ {
+			msg := nats.NewMsg(fmt.Sprintf("orders.%d", i))
+			msg.Header.Set("Order-Id", fmt.Sprintf("%d", i))
+			msg.Data = []byte(fmt.Sprintf("order %d", i))
+			if _, err := cluster1.nc.js.PublishMsg(msg); err != nil {
+				t.Fatalf("failed to publish message: %v", err)
+			}
+		}
+
+		archived, err := cluster1.nc.ArchiveStream("orders", "archives", 3, ArchiveOptions{Purge: true})
+		if err != nil {
+			t.Fatalf("failed to archive stream: %v", err)
+		}
+		if archived != 3 {
+			t.Fatalf("expected 3 archived messages, got %d", archived)
+		}
+
+		info, err := cluster1.nc.GetObjectInfo("archives", "orders-1-3")
+		if err != nil {
+			t.Fatalf("failed to get archive info: %v", err)
+		}
+		if info.Metadata["stream"] != "orders" || info.Metadata["last_seq"] != "3" {
+			t.Errorf("unexpected archive metadata: %v", info.Metadata)
+		}
+
+		streamInfo, err := cluster1.nc.GetStreamInfo("orders")
+		if err != nil {
+			t.Fatalf("failed to get stream info: %v", err)
+		}
+		if streamInfo.State.Msgs != 2 || streamInfo.State.FirstSeq != 4 {
+			t.Errorf("expected messages 4-5 to remain, got %d messages from %d", streamInfo.State.Msgs, streamInfo.State.FirstSeq)
+		}
+
+		replayed, err := cluster1.nc.ReplayArchive("archives", "orders-1-3", "restored.orders")
+		if err != nil {
+			t.Fatalf("failed to replay archive: %v", err)
+		}
+		if replayed != 3 {
+			t.Fatalf("expected 3 replayed messages, got %d", replayed)
+		}
+
+		for seq := uint64(1); seq <= 3; seq++ {
+			msg, err := cluster1.nc.js.GetMsg("restored", seq)
+			if err != nil {
+				t.Fatalf("failed to get replayed message %d: %v", seq, err)
+			}
+			if string(msg.Data) != fmt.Sprintf("order %d", seq) {
+				t.Errorf("unexpected data for message %d: %q", seq, msg.Data)
+			}
+			if msg.Header.Get("Order-Id") != fmt.Sprintf("%d", seq) {
+				t.Errorf("expected original header on message %d, got %v", seq, msg.Header)
+			}
+			if msg.Header.Get(ArchiveHeaderSubject) != fmt.Sprintf("orders.%d", seq) {
+				t.Errorf("expected original subject on message %d, got %q", seq, msg.Header.Get(ArchiveHeaderSubject))
+			}
+			if _, err := time.Parse(time.RFC3339Nano, msg.Header.Get(ArchiveHeaderTimestamp)); err != nil {
+				t.Errorf("expected original timestamp on message %d: %v", seq, err)
+			}
+		}
+	})
+
+	t.Run("replay to original subjects", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+			Name:     "events",
+			Subjects: []string{"events.*"},
+		})
+		if err != nil {
+			t.Fatalf("failed to create stream: %v", err)
+		}
+
+		err = cluster1.nc.CreateObjectStore("test-cluster", ObjectStoreConfig{
+			Bucket:   "archives",
+			MaxBytes: size.Size(5 * 1024 * 1024),
+			Replicas: 1,
+		})
+		if err != nil {
+			t.Fatalf("failed to create object store bucket: %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := cluster1.nc.PublishPersistent("events.created", []byte("event")); err != nil {
+				t.Fatalf("failed to publish message: %v", err)
+			}
+		}
+
+		archived, err := cluster1.nc.ArchiveStream("events", "archives", 100, ArchiveOptions{Name: "events-backup"})
+		if err != nil {
+			t.Fatalf("failed to archive stream: %v", err)
+		}
+		if archived != 2 {
+			t.Fatalf("expected 2 archived messages, got %d", archived)
+		}
+
+		replayed, err := cluster1.nc.ReplayArchive("archives", "events-backup", "")
+		if err != nil {
+			t.Fatalf("failed to replay archive: %v", err)
+		}
+		if replayed != 2 {
+			t.Fatalf("expected 2 replayed messages, got %d", replayed)
+		}
+
+		// Without purge the originals stay, so replaying doubles the stream
+		streamInfo, err := cluster1.nc.GetStreamInfo("events")
+		if err != nil {
+			t.Fatalf("failed to get stream info: %v", err)
+		}
+		if streamInfo.State.Msgs != 4 {
+			t.Errorf("expected 4 messages after replay, got %d", streamInfo.State.Msgs)
+		}
+	})
+
+	t.Run("empty range", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+			Name:     "empty",
+			Subjects: []string{"empty.*"},
+		})
+		if err != nil {
+			t.Fatalf("failed to create stream: %v", err)
+		}
+
+		archived, err := cluster1.nc.ArchiveStream("empty", "archives", 10)
+		if err != nil {
+			t.Fatalf("expected no error for empty stream, got %v", err)
+		}
+		if archived != 0 {
+			t.Errorf("expected 0 archived messages, got %d", archived)
+		}
+	})
+}
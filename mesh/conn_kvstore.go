@@ -1,7 +1,14 @@
 package mesh
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -50,7 +57,7 @@ func (c *conn) CreateKeyValueStore(cluster string, config KeyValueStoreConfig) e
 }
 
 func (c *conn) GetFromKeyValueStore(bucket, key string) ([]byte, uint64, error) {
-	kv, err := c.js.KeyValue(bucket)
+	kv, err := c.keyValue(bucket, key)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
 	}
@@ -64,7 +71,7 @@ func (c *conn) GetFromKeyValueStore(bucket, key string) ([]byte, uint64, error)
 }
 
 func (c *conn) PutToKeyValueStore(bucket, key string, value []byte) (uint64, error) {
-	kv, err := c.js.KeyValue(bucket)
+	kv, err := c.keyValue(bucket, key)
 	if err != nil {
 		return 0, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
 	}
@@ -78,7 +85,7 @@ func (c *conn) PutToKeyValueStore(bucket, key string, value []byte) (uint64, err
 }
 
 func (c *conn) UpdateToKeyValueStore(bucket, key string, value []byte, expectedRevision uint64) (uint64, error) {
-	kv, err := c.js.KeyValue(bucket)
+	kv, err := c.keyValue(bucket, key)
 	if err != nil {
 		return 0, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
 	}
@@ -91,8 +98,24 @@ func (c *conn) UpdateToKeyValueStore(bucket, key string, value []byte, expectedR
 	return revision, nil
 }
 
+// CreateKeyValue puts key only if it does not exist yet, or was deleted.
+// It fails with nats.ErrKeyExists otherwise.
+func (c *conn) CreateKeyValue(bucket, key string, value []byte) (uint64, error) {
+	kv, err := c.keyValue(bucket, key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
+	}
+
+	revision, err := kv.Create(key, value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create key %q in bucket %q: %w", key, bucket, err)
+	}
+
+	return revision, nil
+}
+
 func (c *conn) DeleteFromKeyValueStore(bucket, key string) error {
-	kv, err := c.js.KeyValue(bucket)
+	kv, err := c.keyValue(bucket, key)
 	if err != nil {
 		return fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
 	}
@@ -104,8 +127,23 @@ func (c *conn) DeleteFromKeyValueStore(bucket, key string) error {
 	return nil
 }
 
+// DeleteKeyValueIfRevision deletes key only if its latest revision is
+// expected, so a holder can't remove a value someone else has since written.
+func (c *conn) DeleteKeyValueIfRevision(bucket, key string, expected uint64) error {
+	kv, err := c.keyValue(bucket, key)
+	if err != nil {
+		return fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
+	}
+
+	if err := kv.Delete(key, nats.LastRevision(expected)); err != nil {
+		return fmt.Errorf("failed to delete key %q at revision %d from bucket %q: %w", key, expected, bucket, err)
+	}
+
+	return nil
+}
+
 func (c *conn) PurgeKeyValueStore(bucket, key string) error {
-	kv, err := c.js.KeyValue(bucket)
+	kv, err := c.keyValue(bucket, key)
 	if err != nil {
 		return fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
 	}
@@ -118,6 +156,25 @@ func (c *conn) PurgeKeyValueStore(bucket, key string) error {
 }
 
 func (c *conn) DeleteKeyValueStore(bucket string) error {
+	if _, shards, err := c.resolveKeyValue(bucket); err == nil && shards > 0 {
+		var errs []error
+		for i := 0; i < shards; i++ {
+			if err := c.js.DeleteKeyValue(kvShardBucket(bucket, i)); err != nil && !errors.Is(err, nats.ErrBucketNotFound) {
+				errs = append(errs, err)
+			}
+		}
+		c.kvShardCache.Delete(bucket)
+
+		// The layout goes last so a failed delete can be retried
+		if len(errs) > 0 {
+			return fmt.Errorf("failed to delete key-value store %q: %w", bucket, errors.Join(errs...))
+		}
+		if err := c.js.DeleteKeyValue(kvShardLayoutBucket(bucket)); err != nil && !errors.Is(err, nats.ErrBucketNotFound) {
+			return fmt.Errorf("failed to delete key-value store %q: %w", bucket, err)
+		}
+		return nil
+	}
+
 	if err := c.js.DeleteKeyValue(bucket); err != nil {
 		return fmt.Errorf("failed to delete key-value store %q: %w", bucket, err)
 	}
@@ -126,16 +183,40 @@ func (c *conn) DeleteKeyValueStore(bucket string) error {
 }
 
 func (c *conn) KeyValueStoreExists(bucket string) bool {
-	_, err := c.js.KeyValue(bucket)
+	_, _, err := c.resolveKeyValue(bucket)
 	return err == nil
 }
 
 func (c *conn) ListKeysInKeyValueStore(bucket string) ([]string, error) {
-	kv, err := c.js.KeyValue(bucket)
+	kv, shards, err := c.resolveKeyValue(bucket)
 	if err != nil {
 		return nil, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
 	}
 
+	if shards > 0 {
+		var keys []string
+		for i := 0; i < shards; i++ {
+			kv, err := c.js.KeyValue(kvShardBucket(bucket, i))
+			if err != nil {
+				return nil, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
+			}
+
+			shardKeys, err := kv.Keys()
+			if err != nil {
+				if errors.Is(err, nats.ErrNoKeysFound) {
+					continue
+				}
+				return nil, fmt.Errorf("failed to list keys in bucket %q: %w", bucket, err)
+			}
+			keys = append(keys, shardKeys...)
+		}
+
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("failed to list keys in bucket %q: %w", bucket, nats.ErrNoKeysFound)
+		}
+		return keys, nil
+	}
+
 	keys, err := kv.Keys()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list keys in bucket %q: %w", bucket, err)
@@ -145,7 +226,16 @@ func (c *conn) ListKeysInKeyValueStore(bucket string) ([]string, error) {
 }
 
 func (c *conn) WatchKeyValueStore(bucket, key string) (nats.KeyWatcher, error) {
-	kv, err := c.js.KeyValue(bucket)
+	// Wildcard patterns may match keys on every shard
+	if strings.ContainsAny(key, "*>") {
+		if _, shards, err := c.resolveKeyValue(bucket); err == nil && shards > 0 {
+			return c.watchKeyValueShards(bucket, shards, func(kv nats.KeyValue) (nats.KeyWatcher, error) {
+				return kv.Watch(key)
+			})
+		}
+	}
+
+	kv, err := c.keyValue(bucket, key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
 	}
@@ -159,11 +249,17 @@ func (c *conn) WatchKeyValueStore(bucket, key string) (nats.KeyWatcher, error) {
 }
 
 func (c *conn) WatchAllKeysInKeyValueStore(bucket string) (nats.KeyWatcher, error) {
-	kv, err := c.js.KeyValue(bucket)
+	kv, shards, err := c.resolveKeyValue(bucket)
 	if err != nil {
 		return nil, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
 	}
 
+	if shards > 0 {
+		return c.watchKeyValueShards(bucket, shards, func(kv nats.KeyValue) (nats.KeyWatcher, error) {
+			return kv.WatchAll()
+		})
+	}
+
 	watcher, err := kv.WatchAll()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create watcher for all keys in bucket %q: %w", bucket, err)
@@ -171,3 +267,244 @@ func (c *conn) WatchAllKeysInKeyValueStore(bucket string) (nats.KeyWatcher, erro
 
 	return watcher, nil
 }
+
+const kvShardSeparator = "_shard_"
+
+// A sharded store records its shard count under kvShardLayoutKey in a bucket
+// of its own, so other connections never have to guess the layout from
+// bucket names.
+const (
+	kvShardLayoutSuffix = "_shards"
+	kvShardLayoutKey    = "count"
+)
+
+func kvShardBucket(bucket string, shard int) string {
+	return bucket + kvShardSeparator + strconv.Itoa(shard)
+}
+
+func kvShardLayoutBucket(bucket string) string {
+	return bucket + kvShardLayoutSuffix
+}
+
+func kvShardOf(key string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// CreateShardedKeyValueStore creates config.Bucket as shards separate
+// buckets. The regular key-value methods route each key to one of them by
+// hash, and listing and watching fan out across all of them.
+func (c *conn) CreateShardedKeyValueStore(cluster string, shards int, config KeyValueStoreConfig) error {
+	if shards <= 0 {
+		return fmt.Errorf("shard count must be positive, got %d", shards)
+	}
+
+	if c.KeyValueStoreExists(config.Bucket) {
+		return fmt.Errorf("key-value store %q already exists and cannot be updated", config.Bucket)
+	}
+
+	name := config.Bucket
+	for i := 0; i < shards; i++ {
+		shardConfig := config
+		shardConfig.Bucket = kvShardBucket(name, i)
+		if err := c.CreateKeyValueStore(cluster, shardConfig); err != nil {
+			for j := 0; j < i; j++ {
+				_ = c.js.DeleteKeyValue(kvShardBucket(name, j))
+			}
+			return fmt.Errorf("failed to create shard %d of key-value store %q: %w", i, name, err)
+		}
+	}
+
+	// The layout is written last, so a half-created store is never found
+	if err := c.createKVShardLayout(cluster, shards, config); err != nil {
+		for i := 0; i < shards; i++ {
+			_ = c.js.DeleteKeyValue(kvShardBucket(name, i))
+		}
+		_ = c.js.DeleteKeyValue(kvShardLayoutBucket(name))
+		return fmt.Errorf("failed to record shard layout of key-value store %q: %w", name, err)
+	}
+
+	c.kvShardCache.Store(name, shards)
+
+	return nil
+}
+
+func (c *conn) createKVShardLayout(cluster string, shards int, config KeyValueStoreConfig) error {
+	if err := c.CreateKeyValueStore(cluster, KeyValueStoreConfig{
+		Bucket:      kvShardLayoutBucket(config.Bucket),
+		Description: fmt.Sprintf("shard layout of key-value store %q", config.Bucket),
+		Replicas:    config.Replicas,
+	}); err != nil {
+		return err
+	}
+
+	layout, err := c.js.KeyValue(kvShardLayoutBucket(config.Bucket))
+	if err != nil {
+		return err
+	}
+
+	_, err = layout.Put(kvShardLayoutKey, []byte(strconv.Itoa(shards)))
+	return err
+}
+
+// resolveKeyValue returns either the plain bucket or, for a sharded store,
+// its shard count. Plain buckets are tried first so they never pay for shard
+// discovery.
+func (c *conn) resolveKeyValue(bucket string) (nats.KeyValue, int, error) {
+	if shards, ok := c.kvShardCache.Load(bucket); ok {
+		return nil, shards.(int), nil
+	}
+
+	kv, err := c.js.KeyValue(bucket)
+	if err == nil || !errors.Is(err, nats.ErrBucketNotFound) {
+		return kv, 0, err
+	}
+
+	if shards, ok := c.kvShards(bucket); ok {
+		return nil, shards, nil
+	}
+
+	return nil, 0, err
+}
+
+// keyValue returns the bucket holding key, resolving sharded stores.
+func (c *conn) keyValue(bucket, key string) (nats.KeyValue, error) {
+	kv, shards, err := c.resolveKeyValue(bucket)
+	if err != nil || shards == 0 {
+		return kv, err
+	}
+
+	return c.js.KeyValue(kvShardBucket(bucket, kvShardOf(key, shards)))
+}
+
+// kvShards reports how many shards back bucket, or false if it is not a
+// sharded store. The layout is read once from the layout bucket and cached,
+// since a store can't be resharded after creation.
+func (c *conn) kvShards(bucket string) (int, bool) {
+	if shards, ok := c.kvShardCache.Load(bucket); ok {
+		return shards.(int), true
+	}
+
+	layout, err := c.js.KeyValue(kvShardLayoutBucket(bucket))
+	if err != nil {
+		return 0, false
+	}
+	entry, err := layout.Get(kvShardLayoutKey)
+	if err != nil {
+		return 0, false
+	}
+	shards, err := strconv.Atoi(string(entry.Value()))
+	if err != nil || shards <= 0 {
+		return 0, false
+	}
+
+	c.kvShardCache.Store(bucket, shards)
+	return shards, true
+}
+
+func (c *conn) watchKeyValueShards(bucket string, shards int, watch func(kv nats.KeyValue) (nats.KeyWatcher, error)) (nats.KeyWatcher, error) {
+	watchers := make([]nats.KeyWatcher, 0, shards)
+	for i := 0; i < shards; i++ {
+		kv, err := c.js.KeyValue(kvShardBucket(bucket, i))
+		if err == nil {
+			var w nats.KeyWatcher
+			if w, err = watch(kv); err == nil {
+				watchers = append(watchers, w)
+				continue
+			}
+		}
+
+		for _, w := range watchers {
+			_ = w.Stop()
+		}
+		return nil, fmt.Errorf("failed to create watcher for bucket %q: %w", bucket, err)
+	}
+
+	return newShardedKeyWatcher(watchers), nil
+}
+
+// shardedKeyWatcher merges the watchers of every shard of a store. Like a
+// single watcher it delivers one nil entry once all initial values are in.
+type shardedKeyWatcher struct {
+	watchers []nats.KeyWatcher
+	updates  chan nats.KeyValueEntry
+	errs     chan error
+	done     chan struct{} // closed by Stop to release blocked sends
+	stopOnce sync.Once
+}
+
+func newShardedKeyWatcher(watchers []nats.KeyWatcher) *shardedKeyWatcher {
+	w := &shardedKeyWatcher{
+		watchers: watchers,
+		updates:  make(chan nats.KeyValueEntry, 256),
+		errs:     make(chan error, len(watchers)),
+		done:     make(chan struct{}),
+	}
+
+	var initial atomic.Int32
+	initial.Store(int32(len(watchers)))
+
+	var wg sync.WaitGroup
+	for _, sw := range watchers {
+		wg.Add(2)
+
+		go func(sw nats.KeyWatcher) {
+			defer wg.Done()
+			for entry := range sw.Updates() {
+				if entry == nil && initial.Add(-1) != 0 {
+					continue
+				}
+				select {
+				case w.updates <- entry:
+				case <-w.done:
+					return
+				}
+			}
+		}(sw)
+
+		go func(sw nats.KeyWatcher) {
+			defer wg.Done()
+			for err := range sw.Error() {
+				select {
+				case w.errs <- err:
+				case <-w.done:
+					return
+				}
+			}
+		}(sw)
+	}
+
+	go func() {
+		wg.Wait()
+		close(w.updates)
+		close(w.errs)
+	}()
+
+	return w
+}
+
+func (w *shardedKeyWatcher) Context() context.Context {
+	return w.watchers[0].Context()
+}
+
+func (w *shardedKeyWatcher) Updates() <-chan nats.KeyValueEntry {
+	return w.updates
+}
+
+func (w *shardedKeyWatcher) Error() <-chan error {
+	return w.errs
+}
+
+func (w *shardedKeyWatcher) Stop() error {
+	w.stopOnce.Do(func() { close(w.done) })
+
+	var errs []error
+	for _, sw := range w.watchers {
+		if err := sw.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
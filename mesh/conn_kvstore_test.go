@@ -1,9 +1,13 @@
 package mesh
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/nats-io/nats.go"
 )
 
 func TestKeyValueStoreCreateBucket(t *testing.T) {
@@ -260,6 +264,76 @@ func TestKeyValueStoreDelete(t *testing.T) {
 	})
 }
 
+func TestKeyValueStoreCreateAndConditionalDelete(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	config := KeyValueStoreConfig{
+		Bucket:   "lease-test",
+		Replicas: 3,
+	}
+
+	err := cluster1.nc.CreateKeyValueStore("test-cluster", config)
+	if err != nil {
+		t.Fatalf("failed to create KV store: %v", err)
+	}
+
+	time.Sleep(2 * time.Second)
+
+	t.Run("create conflict", func(t *testing.T) {
+		revision, err := cluster1.nc.CreateKeyValue("lease-test", "leader", []byte("node-1"))
+		if err != nil {
+			t.Fatalf("failed to create key: %v", err)
+		}
+		if revision == 0 {
+			t.Error("expected a non-zero revision")
+		}
+
+		_, err = cluster2.nc.CreateKeyValue("lease-test", "leader", []byte("node-2"))
+		if !errors.Is(err, nats.ErrKeyExists) {
+			t.Errorf("expected ErrKeyExists, got %v", err)
+		}
+
+		value, _, err := cluster3.nc.GetFromKeyValueStore("lease-test", "leader")
+		if err != nil {
+			t.Fatalf("failed to get key: %v", err)
+		}
+		if string(value) != "node-1" {
+			t.Errorf("expected first writer to win, got %s", value)
+		}
+	})
+
+	t.Run("stale revision delete", func(t *testing.T) {
+		stale, err := cluster1.nc.CreateKeyValue("lease-test", "lease", []byte("v1"))
+		if err != nil {
+			t.Fatalf("failed to create key: %v", err)
+		}
+		current, err := cluster2.nc.UpdateToKeyValueStore("lease-test", "lease", []byte("v2"), stale)
+		if err != nil {
+			t.Fatalf("failed to update key: %v", err)
+		}
+
+		if err := cluster3.nc.DeleteKeyValueIfRevision("lease-test", "lease", stale); err == nil {
+			t.Error("expected delete with stale revision to fail")
+		}
+		if _, _, err := cluster1.nc.GetFromKeyValueStore("lease-test", "lease"); err != nil {
+			t.Errorf("expected key to survive stale delete: %v", err)
+		}
+
+		if err := cluster3.nc.DeleteKeyValueIfRevision("lease-test", "lease", current); err != nil {
+			t.Fatalf("failed to delete with current revision: %v", err)
+		}
+		if _, _, err := cluster1.nc.GetFromKeyValueStore("lease-test", "lease"); !errors.Is(err, nats.ErrKeyNotFound) {
+			t.Errorf("expected ErrKeyNotFound after delete, got %v", err)
+		}
+
+		// A deleted key can be created again
+		if _, err := cluster2.nc.CreateKeyValue("lease-test", "lease", []byte("v3")); err != nil {
+			t.Errorf("failed to recreate deleted key: %v", err)
+		}
+	})
+}
+
 func TestKeyValueStoreTTL(t *testing.T) {
 	t.Run("key expiration", func(t *testing.T) {
 		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
@@ -393,3 +467,187 @@ func TestKeyValueStoreMultipleBuckets(t *testing.T) {
 		t.Logf("Successfully tested %d buckets with proper data isolation", len(buckets))
 	})
 }
+
+func TestShardedKeyValueStore(t *testing.T) {
+	t.Run("route and fan out", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		config := KeyValueStoreConfig{
+			Bucket:   "sharded-test",
+			Replicas: 3,
+		}
+
+		if err := cluster1.nc.CreateShardedKeyValueStore("test-cluster", 4, config); err != nil {
+			t.Fatalf("failed to create sharded KV store: %v", err)
+		}
+
+		if err := cluster1.nc.CreateShardedKeyValueStore("test-cluster", 4, config); err == nil {
+			t.Error("expected error creating duplicate sharded KV store")
+		}
+
+		watcher, err := cluster3.nc.WatchAllKeysInKeyValueStore("sharded-test")
+		if err != nil {
+			t.Fatalf("failed to watch sharded KV store: %v", err)
+		}
+		defer watcher.Stop()
+
+		// Initial values are done once a single nil marker arrives
+		select {
+		case entry := <-watcher.Updates():
+			if entry != nil {
+				t.Fatalf("expected initial nil marker, got %v", entry.Key())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for initial nil marker")
+		}
+
+		const count = 20
+		for i := 0; i < count; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			if _, err := cluster1.nc.PutToKeyValueStore("sharded-test", key, []byte(key)); err != nil {
+				t.Fatalf("failed to put %s: %v", key, err)
+			}
+		}
+
+		// A different connection discovers the shard layout on its own
+		for i := 0; i < count; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			value, _, err := cluster2.nc.GetFromKeyValueStore("sharded-test", key)
+			if err != nil {
+				t.Fatalf("failed to get %s: %v", key, err)
+			}
+			if string(value) != key {
+				t.Errorf("expected %s, got %s", key, value)
+			}
+		}
+
+		keys, err := cluster2.nc.ListKeysInKeyValueStore("sharded-test")
+		if err != nil {
+			t.Fatalf("failed to list keys: %v", err)
+		}
+		if len(keys) != count {
+			t.Errorf("expected %d keys across shards, got %d", count, len(keys))
+		}
+
+		seen := make(map[string]bool)
+		timeout := time.After(10 * time.Second)
+		for len(seen) < count {
+			select {
+			case entry := <-watcher.Updates():
+				if entry != nil {
+					seen[entry.Key()] = true
+				}
+			case <-timeout:
+				t.Fatalf("watcher saw %d of %d keys", len(seen), count)
+			}
+		}
+
+		// Keys must not leak outside their shard
+		shardKeys := 0
+		for i := 0; i < 4; i++ {
+			keys, err := cluster1.nc.ListKeysInKeyValueStore(kvShardBucket("sharded-test", i))
+			if err == nil {
+				shardKeys += len(keys)
+			}
+		}
+		if shardKeys != count {
+			t.Errorf("expected %d keys in shard buckets, got %d", count, shardKeys)
+		}
+
+		if !cluster1.nc.KeyValueStoreExists("sharded-test") {
+			t.Error("expected sharded KV store to exist")
+		}
+
+		if err := cluster1.nc.DeleteKeyValueStore("sharded-test"); err != nil {
+			t.Fatalf("failed to delete sharded KV store: %v", err)
+		}
+
+		for i := 0; i < 4; i++ {
+			if cluster1.nc.KeyValueStoreExists(kvShardBucket("sharded-test", i)) {
+				t.Errorf("expected shard %d to be deleted", i)
+			}
+		}
+		if cluster1.nc.KeyValueStoreExists(kvShardLayoutBucket("sharded-test")) {
+			t.Error("expected shard layout to be deleted")
+		}
+	})
+
+	t.Run("plain buckets named like shards", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		for i := 0; i < 2; i++ {
+			if err := cluster1.nc.CreateKeyValueStore("test-cluster", KeyValueStoreConfig{
+				Bucket:   kvShardBucket("lookalike", i),
+				Replicas: 1,
+			}); err != nil {
+				t.Fatalf("failed to create KV store: %v", err)
+			}
+		}
+
+		if cluster2.nc.KeyValueStoreExists("lookalike") {
+			t.Error("expected plain buckets not to be taken for shards of a store")
+		}
+		if _, err := cluster2.nc.PutToKeyValueStore("lookalike", "key", []byte("value")); err == nil {
+			t.Error("expected put to a missing store to fail")
+		}
+	})
+
+	t.Run("stop without draining", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		if err := cluster1.nc.CreateShardedKeyValueStore("test-cluster", 2, KeyValueStoreConfig{
+			Bucket:   "undrained",
+			Replicas: 1,
+		}); err != nil {
+			t.Fatalf("failed to create sharded KV store: %v", err)
+		}
+
+		// More updates than the merged channel buffers
+		for i := 0; i < 300; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			if _, err := cluster1.nc.PutToKeyValueStore("undrained", key, []byte(key)); err != nil {
+				t.Fatalf("failed to put %s: %v", key, err)
+			}
+		}
+
+		watcher, err := cluster1.nc.WatchAllKeysInKeyValueStore("undrained")
+		if err != nil {
+			t.Fatalf("failed to watch sharded KV store: %v", err)
+		}
+		sharded := watcher.(*shardedKeyWatcher)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for len(sharded.updates) < cap(sharded.updates) {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected the merged channel to fill up, got %d entries", len(sharded.updates))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if err := watcher.Stop(); err != nil {
+			t.Fatalf("failed to stop watcher: %v", err)
+		}
+
+		// Updates is closed once every merging goroutine has returned, which
+		// they only do without a reader if Stop released them
+		time.Sleep(500 * time.Millisecond)
+		for i := 0; i < cap(sharded.updates); i++ {
+			select {
+			case <-sharded.updates:
+			default:
+				t.Fatalf("expected buffered entries, ran out after %d", i)
+			}
+		}
+		select {
+		case _, ok := <-sharded.updates:
+			if ok {
+				t.Error("expected no entries past the buffer after Stop")
+			}
+		case <-time.After(time.Second):
+			t.Error("expected updates to be closed after Stop")
+		}
+	})
+}
@@ -1,9 +1,17 @@
 package mesh
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -17,20 +25,24 @@ func (c *conn) TryLock(bucket, key string) (cancel func(), err error) {
 		return nil, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
 	}
 
-	revision, err := kv.Create(key, []byte(lockValue))
+	lock, err := createLock(kv, key, c.nodeName())
 	if err != nil {
 		return nil, fmt.Errorf("failed to lock key %q in bucket %q: %w", key, bucket, err)
 	}
 
-	return func() {
-		_ = kv.Delete(key, nats.LastRevision(revision))
-	}, nil
+	return lock.release, nil
 }
 
 type LockOptions struct {
 	initialDelay  time.Duration
 	MaxDelay      time.Duration
 	BackOffFactor int
+
+	// AutoRenew keeps a lock taken with Lock alive in a bucket with a TTL by
+	// renewing it every RenewInterval until cancel is called. RenewInterval
+	// defaults to a third of the bucket TTL.
+	AutoRenew     bool
+	RenewInterval time.Duration
 }
 
 func (c *conn) Lock(ctx context.Context, bucket, key string, opt ...LockOptions) (cancel func(), err error) {
@@ -40,7 +52,31 @@ func (c *conn) Lock(ctx context.Context, bucket, key string, opt ...LockOptions)
 		BackOffFactor: 2,
 	}
 	if len(opt) > 0 {
-		option = opt[0]
+		if opt[0].initialDelay > 0 {
+			option.initialDelay = opt[0].initialDelay
+		}
+		if opt[0].MaxDelay > 0 {
+			option.MaxDelay = opt[0].MaxDelay
+		}
+		if opt[0].BackOffFactor > 0 {
+			option.BackOffFactor = opt[0].BackOffFactor
+		}
+		option.AutoRenew = opt[0].AutoRenew
+		option.RenewInterval = opt[0].RenewInterval
+	}
+
+	kv, err := c.js.KeyValue(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
+	}
+
+	renewInterval := option.RenewInterval
+	if option.AutoRenew && renewInterval <= 0 {
+		status, err := kv.Status()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status of key-value store %q: %w", bucket, err)
+		}
+		renewInterval = status.TTL() / 3
 	}
 
 	currentDelay := option.initialDelay
@@ -54,9 +90,12 @@ func (c *conn) Lock(ctx context.Context, bucket, key string, opt ...LockOptions)
 		default:
 		}
 
-		cancel, err = c.TryLock(bucket, key)
+		lock, err := createLock(kv, key, c.nodeName())
 		if err == nil {
-			return cancel, nil
+			if option.AutoRenew && renewInterval > 0 {
+				lock.keepAlive(renewInterval)
+			}
+			return lock.release, nil
 		}
 		if !errors.Is(err, nats.ErrKeyExists) {
 			return nil, fmt.Errorf("failed to lock key %q in bucket %q: %w", key, bucket, err)
@@ -69,6 +108,533 @@ func (c *conn) Lock(ctx context.Context, bucket, key string, opt ...LockOptions)
 	}
 }
 
+// RenewLock rewrites the lock on key so a bucket TTL counts from now again.
+// It renews whatever lock is held, exclusive or shared, and fails with
+// nats.ErrKeyNotFound if there is none.
+func (c *conn) RenewLock(bucket, key string) error {
+	kv, err := c.js.KeyValue(bucket)
+	if err != nil {
+		return fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
+	}
+
+	for {
+		entry, err := kv.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to renew lock on key %q in bucket %q: %w", key, bucket, err)
+		}
+
+		if _, err := kv.Update(key, entry.Value(), entry.Revision()); err == nil {
+			return nil
+		} else if !errors.Is(err, nats.ErrKeyExists) {
+			return fmt.Errorf("failed to renew lock on key %q in bucket %q: %w", key, bucket, err)
+		}
+	}
+}
+
+// lockOwner identifies the holder of an exclusive lock. It is stored, after
+// lockValue, as the value of the lock key.
+type lockOwner struct {
+	Node       string    `json:"node"`
+	Token      string    `json:"token"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+// heldLock is an exclusive lock taken by this process. Its value carries a
+// random token, so release only deletes the key while it still holds this
+// lock, even after the key has been renewed or has expired and been taken by
+// someone else.
+type heldLock struct {
+	kv    nats.KeyValue
+	key   string
+	value []byte
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+func createLock(kv nats.KeyValue, key, node string) (*heldLock, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	owner, err := json.Marshal(lockOwner{
+		Node:       node,
+		Token:      hex.EncodeToString(token),
+		AcquiredAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	value := append([]byte(lockValue+":"), owner...)
+	if _, err := kv.Create(key, value); err != nil {
+		return nil, err
+	}
+
+	return &heldLock{kv: kv, key: key, value: value}, nil
+}
+
+// keepAlive renews the lock every interval until it is released or lost.
+func (l *heldLock) keepAlive(interval time.Duration) {
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+
+		renew := time.NewTicker(interval)
+		defer renew.Stop()
+
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-renew.C:
+				if !l.update(func(entry nats.KeyValueEntry) error {
+					_, err := l.kv.Update(l.key, l.value, entry.Revision())
+					return err
+				}) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (l *heldLock) release() {
+	l.once.Do(func() {
+		if l.stop != nil {
+			close(l.stop)
+			<-l.done
+		}
+
+		l.update(func(entry nats.KeyValueEntry) error {
+			return l.kv.Delete(l.key, nats.LastRevision(entry.Revision()))
+		})
+	})
+}
+
+// update applies write to the lock key while it still holds this lock,
+// retrying when the key changes in between. It reports whether the lock was
+// still held.
+func (l *heldLock) update(write func(entry nats.KeyValueEntry) error) bool {
+	for {
+		entry, err := l.kv.Get(l.key)
+		if err != nil || !bytes.Equal(entry.Value(), l.value) {
+			return false
+		}
+
+		if err := write(entry); !errors.Is(err, nats.ErrKeyExists) {
+			return err == nil
+		}
+	}
+}
+
+// readLockPrefix marks a lock key held by readers; the rest of the value is
+// the number of readers holding it.
+const readLockPrefix = "__read_locked__:"
+
+// TryRLock takes a shared read lock on key. Any number of readers can hold
+// it together, while Lock and TryLock on the same key act as the writer and
+// are excluded until every reader has released. It fails with
+// nats.ErrKeyExists while a writer holds the lock.
+func (c *conn) TryRLock(bucket, key string) (cancel func(), err error) {
+	kv, err := c.js.KeyValue(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
+	}
+
+	for {
+		entry, err := kv.Get(key)
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			if _, err := kv.Create(key, encodeReadLock(1)); err == nil {
+				return func() { releaseReadLock(kv, key) }, nil
+			} else if !errors.Is(err, nats.ErrKeyExists) {
+				return nil, fmt.Errorf("failed to read lock key %q in bucket %q: %w", key, bucket, err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get key %q from bucket %q: %w", key, bucket, err)
+		}
+
+		readers, ok := decodeReadLock(entry.Value())
+		if !ok {
+			return nil, fmt.Errorf("failed to read lock key %q in bucket %q: %w", key, bucket, nats.ErrKeyExists)
+		}
+
+		if _, err := kv.Update(key, encodeReadLock(readers+1), entry.Revision()); err == nil {
+			return func() { releaseReadLock(kv, key) }, nil
+		} else if !errors.Is(err, nats.ErrKeyExists) {
+			return nil, fmt.Errorf("failed to read lock key %q in bucket %q: %w", key, bucket, err)
+		}
+	}
+}
+
+// RLock waits for a shared read lock on key, backing off like Lock while a
+// writer holds it. Writers are not queued, so a steady stream of readers can
+// keep Lock waiting.
+func (c *conn) RLock(ctx context.Context, bucket, key string, opt ...LockOptions) (cancel func(), err error) {
+	option := LockOptions{
+		initialDelay:  time.Millisecond * 10,
+		MaxDelay:      2 * time.Second,
+		BackOffFactor: 2,
+	}
+	if len(opt) > 0 {
+		if opt[0].initialDelay > 0 {
+			option.initialDelay = opt[0].initialDelay
+		}
+		if opt[0].MaxDelay > 0 {
+			option.MaxDelay = opt[0].MaxDelay
+		}
+		if opt[0].BackOffFactor > 0 {
+			option.BackOffFactor = opt[0].BackOffFactor
+		}
+	}
+
+	currentDelay := option.initialDelay
+	backOffFactor := time.Duration(option.BackOffFactor)
+	maxDelay := option.MaxDelay
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		cancel, err = c.TryRLock(bucket, key)
+		if err == nil {
+			return cancel, nil
+		}
+		if !errors.Is(err, nats.ErrKeyExists) {
+			return nil, err
+		}
+		time.Sleep(currentDelay)
+		currentDelay *= backOffFactor
+		if currentDelay > maxDelay {
+			currentDelay = maxDelay
+		}
+	}
+}
+
+func encodeReadLock(readers uint64) []byte {
+	return []byte(readLockPrefix + strconv.FormatUint(readers, 10))
+}
+
+func decodeReadLock(value []byte) (uint64, bool) {
+	count, ok := strings.CutPrefix(string(value), readLockPrefix)
+	if !ok {
+		return 0, false
+	}
+
+	readers, err := strconv.ParseUint(count, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return readers, true
+}
+
+// releaseReadLock drops one reader from key, deleting the key with the last
+// one so writers can take it.
+func releaseReadLock(kv nats.KeyValue, key string) {
+	for {
+		entry, err := kv.Get(key)
+		if err != nil {
+			return
+		}
+
+		readers, ok := decodeReadLock(entry.Value())
+		if !ok {
+			return
+		}
+
+		if readers <= 1 {
+			err = kv.Delete(key, nats.LastRevision(entry.Revision()))
+		} else {
+			_, err = kv.Update(key, encodeReadLock(readers-1), entry.Revision())
+		}
+		if !errors.Is(err, nats.ErrKeyExists) {
+			return
+		}
+	}
+}
+
+type FairLockOptions struct {
+	// TicketTTL is how long a waiter's ticket survives without being
+	// refreshed. Waiters refresh their ticket every TicketTTL/3, so a ticket
+	// older than this belongs to a crashed waiter and is dropped.
+	TicketTTL time.Duration
+}
+
+const defaultFairLockTicketTTL = 10 * time.Second
+
+// FairLock acquires the same lock as Lock and TryLock, but grants it in
+// request order. Each caller draws a ticket from a counter stored next to the
+// lock key and queues it under key.fairq.<ticket>; only the live waiter with
+// the lowest ticket may take the lock.
+func (c *conn) FairLock(ctx context.Context, bucket, key string, opt ...FairLockOptions) (release func(), err error) {
+	option := FairLockOptions{
+		TicketTTL: defaultFairLockTicketTTL,
+	}
+	if len(opt) > 0 && opt[0].TicketTTL > 0 {
+		option = opt[0]
+	}
+
+	kv, err := c.js.KeyValue(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
+	}
+
+	ticket, err := nextFairLockTicket(kv, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to draw ticket for key %q in bucket %q: %w", key, bucket, err)
+	}
+
+	ticketKey := fairLockQueuePrefix(key) + strconv.FormatUint(ticket, 10)
+	if _, err := kv.Put(ticketKey, []byte(lockValue)); err != nil {
+		return nil, fmt.Errorf("failed to enqueue for key %q in bucket %q: %w", key, bucket, err)
+	}
+	defer func() {
+		// Leave the queue whether or not the lock was taken
+		_ = kv.Delete(ticketKey)
+	}()
+
+	// Wake up whenever the lock or the queue changes
+	watcher, err := kv.WatchFiltered([]string{key, fairLockQueuePrefix(key) + "*"}, nats.MetaOnly())
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch key %q in bucket %q: %w", key, bucket, err)
+	}
+	defer watcher.Stop()
+
+	refresh := time.NewTicker(option.TicketTTL / 3)
+	defer refresh.Stop()
+
+	for {
+		head, err := fairLockHead(kv, key, option.TicketTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queue for key %q in bucket %q: %w", key, bucket, err)
+		}
+
+		if head == ticket {
+			release, err = c.TryLock(bucket, key)
+			if err == nil {
+				return release, nil
+			}
+			if !errors.Is(err, nats.ErrKeyExists) {
+				return nil, fmt.Errorf("failed to lock key %q in bucket %q: %w", key, bucket, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-watcher.Updates():
+		case <-refresh.C:
+			if _, err := kv.Put(ticketKey, []byte(lockValue)); err != nil {
+				return nil, fmt.Errorf("failed to refresh ticket for key %q in bucket %q: %w", key, bucket, err)
+			}
+		}
+	}
+}
+
+func fairLockQueuePrefix(key string) string {
+	return key + ".fairq."
+}
+
+// nextFairLockTicket increments the ticket counter of key and returns the
+// new value.
+func nextFairLockTicket(kv nats.KeyValue, key string) (uint64, error) {
+	counterKey := key + ".fairseq"
+
+	for {
+		entry, err := kv.Get(counterKey)
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			if _, err := kv.Create(counterKey, []byte("1")); err == nil {
+				return 1, nil
+			} else if !errors.Is(err, nats.ErrKeyExists) {
+				return 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		current, err := strconv.ParseUint(string(entry.Value()), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ticket counter %q: %w", entry.Value(), err)
+		}
+
+		next := current + 1
+		if _, err := kv.Update(counterKey, []byte(strconv.FormatUint(next, 10)), entry.Revision()); err == nil {
+			return next, nil
+		} else if !errors.Is(err, nats.ErrKeyExists) {
+			return 0, err
+		}
+	}
+}
+
+// fairLockHead returns the lowest live ticket queued for key, or 0 if the
+// queue is empty. Tickets not refreshed within ttl are deleted on the way.
+func fairLockHead(kv nats.KeyValue, key string, ttl time.Duration) (uint64, error) {
+	prefix := fairLockQueuePrefix(key)
+
+	watcher, err := kv.Watch(prefix+"*", nats.IgnoreDeletes())
+	if err != nil {
+		return 0, err
+	}
+	defer watcher.Stop()
+
+	var head uint64
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+
+		ticket, err := strconv.ParseUint(strings.TrimPrefix(entry.Key(), prefix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if time.Since(entry.Created()) > ttl {
+			_ = kv.Delete(entry.Key(), nats.LastRevision(entry.Revision()))
+			continue
+		}
+
+		if head == 0 || ticket < head {
+			head = ticket
+		}
+	}
+
+	return head, nil
+}
+
+// SemaphoreOptions configures Semaphore. Only the backoff fields of
+// LockOptions apply; slots are always kept alive while held.
+type SemaphoreOptions struct {
+	LockOptions
+
+	// SlotTTL is how long a slot survives without being refreshed. Holders
+	// refresh their slot every SlotTTL/3, so a slot older than this belongs
+	// to a crashed holder and is reclaimed.
+	SlotTTL time.Duration
+}
+
+const defaultSemaphoreSlotTTL = 10 * time.Second
+
+// Semaphore waits for one of limit slots on key, so that at most limit
+// holders across the cluster run at once. Each slot is its own key,
+// key.sem.<n>, taken with a create-if-absent write; when all are taken it
+// backs off like Lock. The slot is kept alive until release is called.
+func (c *conn) Semaphore(ctx context.Context, bucket, key string, limit int, opt ...SemaphoreOptions) (release func(), err error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("semaphore limit must be positive, got %d", limit)
+	}
+
+	option := SemaphoreOptions{
+		LockOptions: LockOptions{
+			initialDelay:  time.Millisecond * 10,
+			MaxDelay:      2 * time.Second,
+			BackOffFactor: 2,
+		},
+		SlotTTL: defaultSemaphoreSlotTTL,
+	}
+	if len(opt) > 0 {
+		if opt[0].MaxDelay > 0 {
+			option.MaxDelay = opt[0].MaxDelay
+		}
+		if opt[0].BackOffFactor > 0 {
+			option.BackOffFactor = opt[0].BackOffFactor
+		}
+		if opt[0].SlotTTL > 0 {
+			option.SlotTTL = opt[0].SlotTTL
+		}
+	}
+
+	kv, err := c.js.KeyValue(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
+	}
+
+	currentDelay := option.initialDelay
+	backOffFactor := time.Duration(option.BackOffFactor)
+	maxDelay := option.MaxDelay
+
+	for {
+		for slot := 0; slot < limit; slot++ {
+			slotKey := semaphoreSlotKey(key, slot)
+
+			revision, err := kv.Create(slotKey, []byte(lockValue))
+			if err == nil {
+				return holdSemaphoreSlot(kv, slotKey, revision, option.SlotTTL), nil
+			}
+			if !errors.Is(err, nats.ErrKeyExists) {
+				return nil, fmt.Errorf("failed to take slot of semaphore %q in bucket %q: %w", key, bucket, err)
+			}
+
+			// Reclaim the slot of a holder that stopped refreshing it
+			entry, err := kv.Get(slotKey)
+			if err == nil && time.Since(entry.Created()) > option.SlotTTL {
+				_ = kv.Delete(slotKey, nats.LastRevision(entry.Revision()))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(currentDelay):
+		}
+		currentDelay *= backOffFactor
+		if currentDelay > maxDelay {
+			currentDelay = maxDelay
+		}
+	}
+}
+
+func semaphoreSlotKey(key string, slot int) string {
+	return key + ".sem." + strconv.Itoa(slot)
+}
+
+// holdSemaphoreSlot refreshes slotKey every ttl/3 until the returned release
+// function is called, which frees the slot.
+func holdSemaphoreSlot(kv nats.KeyValue, slotKey string, revision uint64, ttl time.Duration) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		refresh := time.NewTicker(ttl / 3)
+		defer refresh.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-refresh.C:
+				next, err := kv.Update(slotKey, []byte(lockValue), revision)
+				if err != nil {
+					// The slot was reclaimed, so there is nothing left to keep alive
+					return
+				}
+				revision = next
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+			_ = kv.Delete(slotKey, nats.LastRevision(revision))
+		})
+	}
+}
+
 func (c *conn) ForceUnlock(bucket, key string) error {
 	kv, err := c.js.KeyValue(bucket)
 	if err != nil {
@@ -98,3 +664,46 @@ func (c *conn) IsLocked(bucket, key string) (bool, error) {
 
 	return true, nil
 }
+
+// LockInfo returns who holds the exclusive lock on key, as "node/token", and
+// when it was taken. For shared read locks and locks written by older
+// versions the owner is empty and acquiredAt is the time of the last write.
+// It fails with nats.ErrKeyNotFound if the key is not locked.
+func (c *conn) LockInfo(bucket, key string) (owner string, acquiredAt time.Time, err error) {
+	kv, err := c.js.KeyValue(bucket)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to access key-value store %q: %w", bucket, err)
+	}
+
+	entry, err := kv.Get(key)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to get key %q from bucket %q: %w", key, bucket, err)
+	}
+
+	data, ok := bytes.CutPrefix(entry.Value(), []byte(lockValue+":"))
+	if !ok {
+		return "", entry.Created(), nil
+	}
+
+	var lo lockOwner
+	if err := json.Unmarshal(data, &lo); err != nil {
+		return "", entry.Created(), nil
+	}
+
+	return lo.Node + "/" + lo.Token, lo.AcquiredAt, nil
+}
+
+// nodeName names this connection in lock owners: the embedded server name,
+// else the client connection name, else the host name.
+func (c *conn) nodeName() string {
+	if c.server != nil {
+		return c.server.Name()
+	}
+	if c.conn != nil && c.conn.Opts.Name != "" {
+		return c.conn.Opts.Name
+	}
+	if host, err := os.Hostname(); err == nil {
+		return host
+	}
+	return "unknown"
+}
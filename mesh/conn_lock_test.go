@@ -2,9 +2,14 @@ package mesh
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/nats-io/nats.go"
 )
 
 func TestDistributedLockTryLock(t *testing.T) {
@@ -578,68 +583,85 @@ func TestDistributedLockWithTTL(t *testing.T) {
 
 		lockKey := "renewal-resource"
 
+		if err := cluster1.nc.RenewLock("renewal-locks", lockKey); !errors.Is(err, nats.ErrKeyNotFound) {
+			t.Errorf("expected ErrKeyNotFound renewing a lock that is not held, got %v", err)
+		}
+
 		// Node1 acquires lock
 		cancel1, err := cluster1.nc.TryLock("renewal-locks", lockKey)
 		if err != nil {
 			t.Fatalf("failed to acquire lock: %v", err)
 		}
-		defer cancel1()
-
-		// Start renewal process
-		renewalStop := make(chan bool)
-		renewalSuccess := 0
-		renewalFailed := 0
-		var renewalMu sync.Mutex
 
-		go func() {
-			ticker := time.NewTicker(1 * time.Second) // Renew every 1 second
-			defer ticker.Stop()
-
-			for {
-				select {
-				case <-ticker.C:
-					// Try to "renew" by re-acquiring the lock (simulate renewal)
-					// In a real implementation, this would be a dedicated Renew method
-					_, renewErr := cluster1.nc.TryLock("renewal-locks", lockKey)
-
-					renewalMu.Lock()
-					if renewErr != nil {
-						renewalFailed++
-						t.Logf("Lock renewal failed (expected if lock still held): %v", renewErr)
-					} else {
-						renewalSuccess++
-						t.Log("Lock renewal succeeded (unexpected)")
-					}
-					renewalMu.Unlock()
-
-				case <-renewalStop:
-					return
-				}
+		// Renew every second for 5 seconds, well past the TTL
+		for i := 0; i < 5; i++ {
+			time.Sleep(time.Second)
+			if err := cluster1.nc.RenewLock("renewal-locks", lockKey); err != nil {
+				t.Fatalf("failed to renew lock: %v", err)
 			}
-		}()
-
-		// Keep lock alive for 5 seconds (longer than TTL)
-		time.Sleep(5 * time.Second)
-		renewalStop <- true
+			if _, err := cluster2.nc.TryLock("renewal-locks", lockKey); err == nil {
+				t.Fatal("node2 acquired a lock that was being renewed")
+			}
+		}
 
-		// Lock should still be held by original owner
+		// Release must still work after the key was renewed
+		cancel1()
 		isLocked, err := cluster1.nc.IsLocked("renewal-locks", lockKey)
 		if err != nil {
 			t.Fatalf("failed to check lock status: %v", err)
 		}
+		if isLocked {
+			t.Error("lock should be released after renewals")
+		}
+	})
 
-		renewalMu.Lock()
-		t.Logf("Renewal attempts: success=%d, failed=%d", renewalSuccess, renewalFailed)
-		renewalMu.Unlock()
+	t.Run("automatic renewal holds lock past TTL", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
 
-		// The exact behavior depends on implementation, but lock should exist
-		if !isLocked {
-			t.Log("Lock expired despite renewal attempts - this may be expected behavior")
-		} else {
-			t.Log("Lock maintained through renewal period")
+		const ttl = 2 * time.Second
+		kvConfig := KeyValueStoreConfig{
+			Bucket:   "auto-renew-locks",
+			MaxBytes: 1024 * 1024,
+			Replicas: 1,
+			TTL:      ttl,
+		}
+
+		if err := cluster1.nc.CreateKeyValueStore("test-cluster", kvConfig); err != nil {
+			t.Fatalf("failed to create KV store for auto renew locks: %v", err)
+		}
+
+		lockKey := "long-task"
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		release, err := cluster1.nc.Lock(ctx, "auto-renew-locks", lockKey, LockOptions{AutoRenew: true})
+		if err != nil {
+			t.Fatalf("failed to acquire lock: %v", err)
+		}
+
+		// Nobody else gets the lock for twice its TTL
+		deadline := time.Now().Add(2 * ttl)
+		for time.Now().Before(deadline) {
+			for i, node := range []*Cluster{cluster2, cluster3} {
+				if other, err := node.nc.TryLock("auto-renew-locks", lockKey); err == nil {
+					other()
+					t.Fatalf("node %d acquired an auto renewed lock", i+2)
+				}
+			}
+			time.Sleep(200 * time.Millisecond)
 		}
 
-		t.Log("Successfully tested lock renewal behavior")
+		// Once released, renewal stops and the lock is free immediately
+		release()
+		release()
+
+		other, err := cluster2.nc.TryLock("auto-renew-locks", lockKey)
+		if err != nil {
+			t.Fatalf("failed to acquire lock after release: %v", err)
+		}
+		other()
 	})
 
 	t.Run("multiple locks with different TTLs", func(t *testing.T) {
@@ -735,3 +757,454 @@ func TestDistributedLockWithTTL(t *testing.T) {
 		t.Log("Successfully tested different TTL behaviors")
 	})
 }
+
+func TestDistributedFairLock(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	kvConfig := KeyValueStoreConfig{
+		Bucket:   "fair-locks",
+		MaxBytes: 1024 * 1024,
+		Replicas: 1,
+	}
+
+	if err := cluster1.nc.CreateKeyValueStore("test-cluster", kvConfig); err != nil {
+		t.Fatalf("failed to create KV store for locks: %v", err)
+	}
+
+	t.Run("granted in request order", func(t *testing.T) {
+		lockKey := "ordered"
+
+		holder, err := cluster1.nc.TryLock("fair-locks", lockKey)
+		if err != nil {
+			t.Fatalf("failed to acquire lock: %v", err)
+		}
+
+		var mu sync.Mutex
+		var order []int
+		var wg sync.WaitGroup
+		nodes := []*Cluster{cluster1, cluster2, cluster3}
+
+		for i, node := range nodes {
+			wg.Add(1)
+			go func(id int, node *Cluster) {
+				defer wg.Done()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+				defer cancel()
+
+				release, err := node.nc.FairLock(ctx, "fair-locks", lockKey)
+				if err != nil {
+					t.Errorf("waiter %d failed to acquire lock: %v", id, err)
+					return
+				}
+
+				mu.Lock()
+				order = append(order, id)
+				mu.Unlock()
+
+				time.Sleep(50 * time.Millisecond)
+				release()
+			}(i, node)
+
+			// Let each waiter enqueue before the next one arrives
+			time.Sleep(300 * time.Millisecond)
+		}
+
+		holder()
+		wg.Wait()
+
+		if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+			t.Errorf("expected lock to be granted in order [0 1 2], got %v", order)
+		}
+	})
+
+	t.Run("crashed waiter does not block the queue", func(t *testing.T) {
+		lockKey := "crashed"
+
+		kv, err := cluster1.nc.js.KeyValue("fair-locks")
+		if err != nil {
+			t.Fatalf("failed to access KV store: %v", err)
+		}
+
+		// A waiter that queued and then died without refreshing its ticket
+		ticket, err := nextFairLockTicket(kv, lockKey)
+		if err != nil {
+			t.Fatalf("failed to draw ticket: %v", err)
+		}
+		if _, err := kv.Put(fairLockQueuePrefix(lockKey)+strconv.FormatUint(ticket, 10), []byte(lockValue)); err != nil {
+			t.Fatalf("failed to enqueue ticket: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		release, err := cluster2.nc.FairLock(ctx, "fair-locks", lockKey, FairLockOptions{TicketTTL: time.Second})
+		if err != nil {
+			t.Fatalf("failed to acquire lock past crashed waiter: %v", err)
+		}
+		defer release()
+
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("expected to wait for the stale ticket to expire, acquired after %v", elapsed)
+		}
+	})
+
+	t.Run("context cancellation leaves the queue", func(t *testing.T) {
+		lockKey := "cancelled"
+
+		holder, err := cluster1.nc.TryLock("fair-locks", lockKey)
+		if err != nil {
+			t.Fatalf("failed to acquire lock: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+
+		if _, err := cluster2.nc.FairLock(ctx, "fair-locks", lockKey); err == nil {
+			t.Fatal("expected FairLock to fail while the lock is held")
+		}
+
+		holder()
+
+		// The abandoned ticket must not hold up the next waiter
+		ctx2, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel2()
+
+		release, err := cluster3.nc.FairLock(ctx2, "fair-locks", lockKey)
+		if err != nil {
+			t.Fatalf("failed to acquire lock after cancelled waiter: %v", err)
+		}
+		release()
+	})
+}
+
+func TestDistributedRWLock(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	kvConfig := KeyValueStoreConfig{
+		Bucket:   "rw-locks",
+		MaxBytes: 1024 * 1024,
+		Replicas: 1,
+	}
+
+	if err := cluster1.nc.CreateKeyValueStore("test-cluster", kvConfig); err != nil {
+		t.Fatalf("failed to create KV store for locks: %v", err)
+	}
+
+	t.Run("readers coexist and block the writer", func(t *testing.T) {
+		lockKey := "shared"
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		release1, err := cluster1.nc.RLock(ctx, "rw-locks", lockKey)
+		if err != nil {
+			t.Fatalf("first reader failed to acquire lock: %v", err)
+		}
+
+		// Give time for propagation
+		time.Sleep(100 * time.Millisecond)
+
+		release2, err := cluster2.nc.RLock(ctx, "rw-locks", lockKey)
+		if err != nil {
+			t.Fatalf("second reader failed to acquire lock alongside the first: %v", err)
+		}
+
+		acquired := make(chan func(), 1)
+		go func() {
+			release, err := cluster3.nc.Lock(ctx, "rw-locks", lockKey, LockOptions{
+				initialDelay:  10 * time.Millisecond,
+				MaxDelay:      50 * time.Millisecond,
+				BackOffFactor: 2,
+			})
+			if err != nil {
+				t.Errorf("writer failed to acquire lock: %v", err)
+				close(acquired)
+				return
+			}
+			acquired <- release
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("writer acquired the lock while readers held it")
+		case <-time.After(500 * time.Millisecond):
+		}
+
+		release1()
+		select {
+		case <-acquired:
+			t.Fatal("writer acquired the lock while a reader still held it")
+		case <-time.After(300 * time.Millisecond):
+		}
+
+		release2()
+		select {
+		case release, ok := <-acquired:
+			if !ok {
+				return
+			}
+			defer release()
+		case <-time.After(5 * time.Second):
+			t.Fatal("writer did not acquire the lock after readers released")
+		}
+
+		// Readers wait for the writer in turn
+		if _, err := cluster1.nc.TryRLock("rw-locks", lockKey); !errors.Is(err, nats.ErrKeyExists) {
+			t.Errorf("expected TryRLock to fail with ErrKeyExists while writer holds the lock, got %v", err)
+		}
+		shortCtx, shortCancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer shortCancel()
+		if _, err := cluster2.nc.RLock(shortCtx, "rw-locks", lockKey); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected RLock to time out while writer holds the lock, got %v", err)
+		}
+	})
+
+	t.Run("last reader frees the key", func(t *testing.T) {
+		lockKey := "counted"
+
+		var releases []func()
+		for _, node := range []*Cluster{cluster1, cluster2, cluster3} {
+			release, err := node.nc.TryRLock("rw-locks", lockKey)
+			if err != nil {
+				t.Fatalf("failed to acquire read lock: %v", err)
+			}
+			releases = append(releases, release)
+		}
+
+		if _, err := cluster1.nc.TryLock("rw-locks", lockKey); err == nil {
+			t.Fatal("expected TryLock to fail while readers hold the lock")
+		}
+
+		for i, release := range releases {
+			release()
+			locked, err := cluster1.nc.IsLocked("rw-locks", lockKey)
+			if err != nil {
+				t.Fatalf("failed to check lock: %v", err)
+			}
+			if want := i < len(releases)-1; locked != want {
+				t.Errorf("after %d releases expected locked=%v, got %v", i+1, want, locked)
+			}
+		}
+
+		release, err := cluster2.nc.TryLock("rw-locks", lockKey)
+		if err != nil {
+			t.Fatalf("expected writer to acquire lock after all readers released: %v", err)
+		}
+		release()
+	})
+}
+
+func TestDistributedSemaphore(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	kvConfig := KeyValueStoreConfig{
+		Bucket:   "semaphores",
+		MaxBytes: 1024 * 1024,
+		Replicas: 1,
+	}
+
+	if err := cluster1.nc.CreateKeyValueStore("test-cluster", kvConfig); err != nil {
+		t.Fatalf("failed to create KV store for semaphores: %v", err)
+	}
+
+	// Give time for propagation
+	time.Sleep(100 * time.Millisecond)
+
+	t.Run("at most limit holders", func(t *testing.T) {
+		const limit = 3
+		const workers = 10
+
+		var mu sync.Mutex
+		active, peak, completed := 0, 0, 0
+		var wg sync.WaitGroup
+		nodes := []*Cluster{cluster1, cluster2, cluster3}
+
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(id int, node *Cluster) {
+				defer wg.Done()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+
+				release, err := node.nc.Semaphore(ctx, "semaphores", "workers", limit, SemaphoreOptions{
+					LockOptions: LockOptions{MaxDelay: 50 * time.Millisecond, BackOffFactor: 2},
+				})
+				if err != nil {
+					t.Errorf("worker %d failed to acquire semaphore: %v", id, err)
+					return
+				}
+
+				mu.Lock()
+				active++
+				peak = max(peak, active)
+				mu.Unlock()
+
+				time.Sleep(200 * time.Millisecond)
+
+				mu.Lock()
+				active--
+				completed++
+				mu.Unlock()
+				release()
+			}(i, nodes[i%len(nodes)])
+		}
+		wg.Wait()
+
+		if completed != workers {
+			t.Errorf("expected %d workers to complete, got %d", workers, completed)
+		}
+		if peak > limit {
+			t.Errorf("expected at most %d concurrent holders, saw %d", limit, peak)
+		}
+		if peak < 2 {
+			t.Errorf("expected holders to run concurrently, peak was %d", peak)
+		}
+	})
+
+	t.Run("crashed holder slot is reclaimed", func(t *testing.T) {
+		kv, err := cluster1.nc.js.KeyValue("semaphores")
+		if err != nil {
+			t.Fatalf("failed to access KV store: %v", err)
+		}
+
+		// A holder that took the only slot and then died without refreshing it
+		if _, err := kv.Put(semaphoreSlotKey("crashed", 0), []byte(lockValue)); err != nil {
+			t.Fatalf("failed to take slot: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		release, err := cluster2.nc.Semaphore(ctx, "semaphores", "crashed", 1, SemaphoreOptions{SlotTTL: time.Second})
+		if err != nil {
+			t.Fatalf("failed to acquire semaphore past crashed holder: %v", err)
+		}
+		defer release()
+
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("expected to wait for the stale slot to expire, acquired after %v", elapsed)
+		}
+	})
+
+	t.Run("held slot is refreshed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		release, err := cluster1.nc.Semaphore(ctx, "semaphores", "refreshed", 1, SemaphoreOptions{SlotTTL: 600 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("failed to acquire semaphore: %v", err)
+		}
+
+		// Well past the TTL, the live holder must still own the slot
+		shortCtx, shortCancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+		defer shortCancel()
+		if _, err := cluster2.nc.Semaphore(shortCtx, "semaphores", "refreshed", 1, SemaphoreOptions{SlotTTL: 600 * time.Millisecond}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected second holder to time out, got %v", err)
+		}
+
+		release()
+		release()
+
+		release2, err := cluster3.nc.Semaphore(ctx, "semaphores", "refreshed", 1)
+		if err != nil {
+			t.Fatalf("failed to acquire released semaphore: %v", err)
+		}
+		release2()
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		if _, err := cluster1.nc.Semaphore(context.Background(), "semaphores", "bad", 0); err == nil {
+			t.Error("expected error for zero limit")
+		}
+	})
+}
+
+func TestDistributedLockOwnership(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	kvConfig := KeyValueStoreConfig{
+		Bucket:   "owned-locks",
+		MaxBytes: 1024 * 1024,
+		Replicas: 1,
+		TTL:      2 * time.Second,
+	}
+
+	if err := cluster1.nc.CreateKeyValueStore("test-cluster", kvConfig); err != nil {
+		t.Fatalf("failed to create KV store for owned locks: %v", err)
+	}
+
+	lockKey := "owned-resource"
+
+	if _, _, err := cluster1.nc.LockInfo("owned-locks", lockKey); !errors.Is(err, nats.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound for an unlocked key, got %v", err)
+	}
+
+	before := time.Now()
+	cancel1, err := cluster1.nc.TryLock("owned-locks", lockKey)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	// Give time for propagation
+	time.Sleep(100 * time.Millisecond)
+
+	owner1, acquiredAt, err := cluster2.nc.LockInfo("owned-locks", lockKey)
+	if err != nil {
+		t.Fatalf("failed to get lock info: %v", err)
+	}
+	if !strings.HasPrefix(owner1, "node1/") || len(owner1) <= len("node1/") {
+		t.Errorf("expected owner node1/<token>, got %q", owner1)
+	}
+	if acquiredAt.Before(before.Add(-time.Second)) || acquiredAt.After(time.Now()) {
+		t.Errorf("unexpected acquisition time %v", acquiredAt)
+	}
+
+	// Let node1's lock expire and node2 take it over
+	time.Sleep(3 * time.Second)
+	cancel2, err := cluster2.nc.TryLock("owned-locks", lockKey)
+	if err != nil {
+		t.Fatalf("failed to acquire expired lock: %v", err)
+	}
+	defer cancel2()
+
+	owner2, _, err := cluster1.nc.LockInfo("owned-locks", lockKey)
+	if err != nil {
+		t.Fatalf("failed to get lock info: %v", err)
+	}
+	if !strings.HasPrefix(owner2, "node2/") {
+		t.Errorf("expected owner node2/<token>, got %q", owner2)
+	}
+
+	// node1's late cancel must leave node2's lock alone
+	cancel1()
+
+	locked, err := cluster3.nc.IsLocked("owned-locks", lockKey)
+	if err != nil {
+		t.Fatalf("failed to check lock: %v", err)
+	}
+	if !locked {
+		t.Fatal("stale cancel released another node's lock")
+	}
+	if owner, _, _ := cluster3.nc.LockInfo("owned-locks", lockKey); owner != owner2 {
+		t.Errorf("expected owner to stay %q, got %q", owner2, owner)
+	}
+
+	// A read lock has no single owner
+	readKey := "shared-resource"
+	releaseRead, err := cluster1.nc.TryRLock("owned-locks", readKey)
+	if err != nil {
+		t.Fatalf("failed to acquire read lock: %v", err)
+	}
+	defer releaseRead()
+	if owner, at, err := cluster1.nc.LockInfo("owned-locks", readKey); err != nil || owner != "" || at.IsZero() {
+		t.Errorf("expected empty owner with a write time for a read lock, got %q %v (%v)", owner, at, err)
+	}
+}
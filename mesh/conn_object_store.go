@@ -2,6 +2,8 @@ package mesh
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -19,6 +21,48 @@ type ObjectStoreConfig struct {
 	Metadata    map[string]string `json:"metadata,omitempty"`
 }
 
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Bucket      string
+	Name        string
+	Description string
+	Size        uint64
+	Chunks      uint32
+	// Digest is the SHA-256 digest of the object as "SHA-256=" followed by
+	// its URL-safe base64 encoding.
+	Digest   string
+	ModTime  time.Time
+	Metadata map[string]string
+}
+
+// ObjectGetOptions tunes GetFromObjectStore.
+type ObjectGetOptions struct {
+	// VerifyDigest hashes the data read and checks it against the digest
+	// stored with the object, failing for objects stored without one.
+	VerifyDigest bool
+}
+
+// ObjectCorruptedError is returned when an object read back does not match
+// the digest stored with it. Actual is empty when the mismatch was detected
+// while streaming the object.
+type ObjectCorruptedError struct {
+	Bucket   string
+	Name     string
+	Expected string
+	Actual   string
+}
+
+func (e *ObjectCorruptedError) Error() string {
+	if e.Actual == "" {
+		return fmt.Sprintf("object %q in bucket %q is corrupted: digest does not match %s", e.Name, e.Bucket, e.Expected)
+	}
+	return fmt.Sprintf("object %q in bucket %q is corrupted: digest %s does not match %s", e.Name, e.Bucket, e.Actual, e.Expected)
+}
+
+func (e *ObjectCorruptedError) Is(target error) bool {
+	return target == nats.ErrDigestMismatch
+}
+
 func (c *conn) CreateObjectStore(cluster string, config ObjectStoreConfig) error {
 	storeConfig := nats.ObjectStoreConfig{
 		Bucket:      config.Bucket,
@@ -46,7 +90,14 @@ func (c *conn) CreateObjectStore(cluster string, config ObjectStoreConfig) error
 	return nil
 }
 
-func (c *conn) GetFromObjectStore(bucket, key string) ([]byte, error) {
+// GetFromObjectStore reads the whole object key from bucket. A read whose
+// data does not match the stored digest fails with *ObjectCorruptedError.
+func (c *conn) GetFromObjectStore(bucket, key string, opt ...ObjectGetOptions) ([]byte, error) {
+	var option ObjectGetOptions
+	if len(opt) > 0 {
+		option = opt[0]
+	}
+
 	store, err := c.js.ObjectStore(bucket)
 	if err != nil {
 		return nil, fmt.Errorf("failed to access object store %q: %w", bucket, err)
@@ -58,11 +109,37 @@ func (c *conn) GetFromObjectStore(bucket, key string) ([]byte, error) {
 	}
 	defer obj.Close()
 
+	info, err := obj.Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object info %q from bucket %q: %w", key, bucket, err)
+	}
+
 	data, err := io.ReadAll(obj)
 	if err != nil {
+		if errors.Is(err, nats.ErrDigestMismatch) {
+			return nil, &ObjectCorruptedError{Bucket: bucket, Name: key, Expected: info.Digest}
+		}
 		return nil, fmt.Errorf("failed to read object %q from bucket %q: %w", key, bucket, err)
 	}
 
+	if option.VerifyDigest {
+		expected, err := nats.DecodeObjectDigest(info.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode digest of object %q in bucket %q: %w", key, bucket, err)
+		}
+
+		h := sha256.New()
+		h.Write(data)
+		if !bytes.Equal(h.Sum(nil), expected) {
+			return nil, &ObjectCorruptedError{
+				Bucket:   bucket,
+				Name:     key,
+				Expected: info.Digest,
+				Actual:   nats.GetObjectDigestValue(h),
+			}
+		}
+	}
+
 	return data, nil
 }
 
@@ -130,18 +207,27 @@ func (c *conn) GetFromObjectStoreStream(bucket, key string) (io.ReadCloser, erro
 }
 
 // Object information and metadata
-func (c *conn) GetObjectInfo(bucket, key string) (*nats.ObjectInfo, error) {
+func (c *conn) GetObjectInfo(bucket, key string) (ObjectInfo, error) {
 	store, err := c.js.ObjectStore(bucket)
 	if err != nil {
-		return nil, fmt.Errorf("failed to access object store %q: %w", bucket, err)
+		return ObjectInfo{}, fmt.Errorf("failed to access object store %q: %w", bucket, err)
 	}
 
 	info, err := store.GetInfo(key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get object info %q from bucket %q: %w", key, bucket, err)
+		return ObjectInfo{}, fmt.Errorf("failed to get object info %q from bucket %q: %w", key, bucket, err)
 	}
 
-	return info, nil
+	return ObjectInfo{
+		Bucket:      info.Bucket,
+		Name:        info.Name,
+		Description: info.Description,
+		Size:        info.Size,
+		Chunks:      info.Chunks,
+		Digest:      info.Digest,
+		ModTime:     info.ModTime,
+		Metadata:    info.Metadata,
+	}, nil
 }
 
 func (c *conn) ListObjects(bucket string) ([]*nats.ObjectInfo, error) {
@@ -2,9 +2,15 @@ package mesh
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/nats-io/nats.go"
 	"github.com/rivulet-io/tower/util/size"
 )
 
@@ -376,3 +382,99 @@ func TestObjectStoreMultipleBuckets(t *testing.T) {
 		t.Logf("Successfully tested %d buckets with proper data isolation", len(buckets))
 	})
 }
+
+func TestObjectStoreInfoAndDigest(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	config := ObjectStoreConfig{
+		Bucket:   "verified",
+		MaxBytes: size.Size(5 * 1024 * 1024),
+		Replicas: 1,
+	}
+	if err := cluster1.nc.CreateObjectStore("test-cluster", config); err != nil {
+		t.Fatalf("failed to create object store bucket: %v", err)
+	}
+
+	data := bytes.Repeat([]byte("tower object "), 20000)
+	metadata := map[string]string{"content-type": "text/plain", "owner": "tests"}
+	before := time.Now().Add(-time.Second)
+	if err := cluster1.nc.PutToObjectStore("verified", "report.txt", data, metadata); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	digest := "SHA-256=" + base64.URLEncoding.EncodeToString(sum[:])
+
+	t.Run("info", func(t *testing.T) {
+		info, err := cluster2.nc.GetObjectInfo("verified", "report.txt")
+		if err != nil {
+			t.Fatalf("failed to get object info: %v", err)
+		}
+		if info.Bucket != "verified" || info.Name != "report.txt" {
+			t.Errorf("unexpected object identity %s/%s", info.Bucket, info.Name)
+		}
+		if info.Size != uint64(len(data)) {
+			t.Errorf("expected size %d, got %d", len(data), info.Size)
+		}
+		if info.Digest != digest {
+			t.Errorf("expected digest %s, got %s", digest, info.Digest)
+		}
+		if info.ModTime.Before(before) {
+			t.Errorf("expected a recent modification time, got %v", info.ModTime)
+		}
+		if info.Metadata["content-type"] != "text/plain" || info.Metadata["owner"] != "tests" {
+			t.Errorf("unexpected metadata: %v", info.Metadata)
+		}
+
+		if _, err := cluster2.nc.GetObjectInfo("verified", "missing.txt"); err == nil {
+			t.Error("expected error for a missing object")
+		}
+	})
+
+	t.Run("verify digest", func(t *testing.T) {
+		retrieved, err := cluster3.nc.GetFromObjectStore("verified", "report.txt", ObjectGetOptions{VerifyDigest: true})
+		if err != nil {
+			t.Fatalf("failed to get verified object: %v", err)
+		}
+		if !bytes.Equal(retrieved, data) {
+			t.Error("verified object data mismatch")
+		}
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		// Rewrite the stored metadata with the digest of other data
+		store, err := cluster1.nc.js.ObjectStore("verified")
+		if err != nil {
+			t.Fatalf("failed to access object store: %v", err)
+		}
+		info, err := store.GetInfo("report.txt")
+		if err != nil {
+			t.Fatalf("failed to get object info: %v", err)
+		}
+		other := sha256.Sum256([]byte("something else"))
+		info.Digest = "SHA-256=" + base64.URLEncoding.EncodeToString(other[:])
+		meta, err := json.Marshal(info)
+		if err != nil {
+			t.Fatalf("failed to marshal object info: %v", err)
+		}
+		msg := nats.NewMsg(fmt.Sprintf("$O.verified.M.%s", base64.URLEncoding.EncodeToString([]byte("report.txt"))))
+		msg.Header.Set(nats.MsgRollup, nats.MsgRollupSubject)
+		msg.Data = meta
+		if _, err := cluster1.nc.js.PublishMsg(msg); err != nil {
+			t.Fatalf("failed to publish tampered metadata: %v", err)
+		}
+
+		_, err = cluster1.nc.GetFromObjectStore("verified", "report.txt", ObjectGetOptions{VerifyDigest: true})
+		var corrupted *ObjectCorruptedError
+		if !errors.As(err, &corrupted) {
+			t.Fatalf("expected ObjectCorruptedError, got %v", err)
+		}
+		if corrupted.Name != "report.txt" || corrupted.Expected != info.Digest {
+			t.Errorf("unexpected corruption details: %+v", corrupted)
+		}
+		if !errors.Is(err, nats.ErrDigestMismatch) {
+			t.Error("expected corruption to match nats.ErrDigestMismatch")
+		}
+	})
+}
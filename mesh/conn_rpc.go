@@ -0,0 +1,131 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rivulet-io/tower/op"
+)
+
+// RequestErrorHeader carries the error a RespondTo handler returned, in place
+// of a reply value.
+const RequestErrorHeader = "Tower-Request-Error"
+
+// ErrRemoteHandler wraps the error a RespondTo handler returned on the other
+// side of a Request.
+var ErrRemoteHandler = errors.New("remote handler failed")
+
+// Request sends payload to subject and waits up to timeout for a typed reply
+// from a RespondTo handler. Values are encoded as DataFrames.
+func (c *conn) Request(subject string, payload op.PrimitiveData, timeout time.Duration) (op.PrimitiveData, error) {
+	m, err := newTypedRequest(subject, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.conn.RequestMsg(m, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request on subject %q: %w", subject, err)
+	}
+
+	return decodeTypedReply(subject, response)
+}
+
+// RequestWithContext is Request bounded by ctx instead of a timeout.
+func (c *conn) RequestWithContext(ctx context.Context, subject string, payload op.PrimitiveData) (op.PrimitiveData, error) {
+	m, err := newTypedRequest(subject, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.conn.RequestMsgWithContext(ctx, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request on subject %q: %w", subject, err)
+	}
+
+	return decodeTypedReply(subject, response)
+}
+
+// RespondTo answers Requests on subject with handler. An error returned by
+// handler is passed back to the requester, wrapped in ErrRemoteHandler.
+func (c *conn) RespondTo(subject string, handler func(payload op.PrimitiveData) (op.PrimitiveData, error)) (cancel func(), err error) {
+	return c.RespondToWithContext(context.Background(), subject, func(_ context.Context, payload op.PrimitiveData) (op.PrimitiveData, error) {
+		return handler(payload)
+	})
+}
+
+// RespondToWithContext is RespondTo that stops responding once ctx is done.
+// The handler receives ctx.
+func (c *conn) RespondToWithContext(ctx context.Context, subject string, handler func(ctx context.Context, payload op.PrimitiveData) (op.PrimitiveData, error)) (cancel func(), err error) {
+	sub, err := c.conn.Subscribe(subject, func(msg *nats.Msg) {
+		if msg.Reply == "" {
+			return
+		}
+
+		reply := nats.NewMsg(msg.Reply)
+		result, err := callTypedHandler(ctx, handler, msg.Data)
+		if err == nil {
+			reply.Data, err = op.MarshalPrimitive(result)
+		}
+		if err != nil {
+			reply.Data = nil
+			reply.Header.Set(RequestErrorHeader, err.Error())
+		}
+
+		_ = msg.RespondMsg(reply)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to subject %q: %w", subject, err)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = sub.Unsubscribe()
+	})
+
+	return func() {
+		stop()
+		_ = sub.Unsubscribe()
+	}, nil
+}
+
+func callTypedHandler(ctx context.Context, handler func(ctx context.Context, payload op.PrimitiveData) (op.PrimitiveData, error), data []byte) (result op.PrimitiveData, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+
+	payload, err := op.UnmarshalPrimitive(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode request: %w", err)
+	}
+
+	return handler(ctx, payload)
+}
+
+func newTypedRequest(subject string, payload op.PrimitiveData) (*nats.Msg, error) {
+	data, err := op.MarshalPrimitive(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request for subject %q: %w", subject, err)
+	}
+
+	m := nats.NewMsg(subject)
+	m.Data = data
+	return m, nil
+}
+
+func decodeTypedReply(subject string, response *nats.Msg) (op.PrimitiveData, error) {
+	if msg := response.Header.Get(RequestErrorHeader); msg != "" {
+		return nil, fmt.Errorf("request on subject %q: %w: %s", subject, ErrRemoteHandler, msg)
+	}
+
+	value, err := op.UnmarshalPrimitive(response.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode reply from subject %q: %w", subject, err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,102 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rivulet-io/tower/op"
+)
+
+func TestTypedRequestReply(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	t.Run("int echo across nodes", func(t *testing.T) {
+		cancel, err := cluster2.nc.RespondTo("rpc.echo", func(payload op.PrimitiveData) (op.PrimitiveData, error) {
+			v, err := payload.Int()
+			if err != nil {
+				return nil, err
+			}
+			return op.PrimitiveInt(v), nil
+		})
+		if err != nil {
+			t.Fatalf("failed to register responder: %v", err)
+		}
+		defer cancel()
+
+		// Wait for subscription to propagate
+		time.Sleep(100 * time.Millisecond)
+
+		reply, err := cluster1.nc.Request("rpc.echo", op.PrimitiveInt(42), 5*time.Second)
+		if err != nil {
+			t.Fatalf("failed to request: %v", err)
+		}
+		if v, err := reply.Int(); err != nil || v != 42 {
+			t.Errorf("expected 42, got %v (%v)", reply, err)
+		}
+
+		ctx, cancelCtx := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelCtx()
+		reply, err = cluster3.nc.RequestWithContext(ctx, "rpc.echo", op.PrimitiveInt(-7))
+		if err != nil {
+			t.Fatalf("failed to request with context: %v", err)
+		}
+		if v, err := reply.Int(); err != nil || v != -7 {
+			t.Errorf("expected -7, got %v (%v)", reply, err)
+		}
+
+		// The handler's error comes back to the caller
+		_, err = cluster1.nc.Request("rpc.echo", op.PrimitiveString("not a number"), 5*time.Second)
+		if !errors.Is(err, ErrRemoteHandler) {
+			t.Errorf("expected ErrRemoteHandler, got %v", err)
+		}
+	})
+
+	t.Run("handler panic", func(t *testing.T) {
+		cancel, err := cluster2.nc.RespondTo("rpc.panic", func(op.PrimitiveData) (op.PrimitiveData, error) {
+			panic("boom")
+		})
+		if err != nil {
+			t.Fatalf("failed to register responder: %v", err)
+		}
+		defer cancel()
+
+		time.Sleep(100 * time.Millisecond)
+
+		if _, err := cluster1.nc.Request("rpc.panic", op.PrimitiveNull{}, 5*time.Second); !errors.Is(err, ErrRemoteHandler) {
+			t.Errorf("expected ErrRemoteHandler, got %v", err)
+		}
+	})
+
+	t.Run("context stops responder", func(t *testing.T) {
+		ctx, stop := context.WithCancel(context.Background())
+		_, err := cluster2.nc.RespondToWithContext(ctx, "rpc.scoped", func(ctx context.Context, payload op.PrimitiveData) (op.PrimitiveData, error) {
+			s, _ := payload.String()
+			return op.PrimitiveString(fmt.Sprintf("hello %s", s)), nil
+		})
+		if err != nil {
+			t.Fatalf("failed to register responder: %v", err)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		reply, err := cluster1.nc.Request("rpc.scoped", op.PrimitiveString("mesh"), 5*time.Second)
+		if err != nil {
+			t.Fatalf("failed to request: %v", err)
+		}
+		if v, _ := reply.String(); v != "hello mesh" {
+			t.Errorf("expected %q, got %q", "hello mesh", v)
+		}
+
+		stop()
+		time.Sleep(100 * time.Millisecond)
+
+		if _, err := cluster1.nc.Request("rpc.scoped", op.PrimitiveString("mesh"), time.Second); !errors.Is(err, nats.ErrNoResponders) {
+			t.Errorf("expected ErrNoResponders after cancel, got %v", err)
+		}
+	})
+}
@@ -1,9 +1,14 @@
 package mesh
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -72,6 +77,73 @@ type PersistentConfig struct {
 	Metadata map[string]string
 }
 
+const (
+	DeadLetterHeaderSubject     = "Tower-DeadLetter-Subject"
+	DeadLetterHeaderDeliveries  = "Tower-DeadLetter-Deliveries"
+	DeadLetterHeaderOriginalSeq = "Tower-DeadLetter-Stream-Seq"
+)
+
+type DeadLetterOptions struct {
+	// MaxDeliver is how many times a message is handed to the handler before
+	// it is moved to DeadLetterSubject. Defaults to 5.
+	MaxDeliver int
+
+	// DeadLetterSubject receives messages that exhausted MaxDeliver. It must
+	// be captured by a stream.
+	DeadLetterSubject string
+
+	// RetryDelay delays redelivery of a message the handler did not
+	// acknowledge. Zero redelivers immediately.
+	RetryDelay time.Duration
+}
+
+// StreamStartPolicy selects where SubscribeStreamFrom starts reading a
+// stream. The zero value is AllAvailable.
+type StreamStartPolicy struct {
+	deliver  nats.DeliverPolicy
+	sequence uint64
+	time     time.Time
+}
+
+var (
+	// FromNow delivers only messages published after subscribing.
+	FromNow = StreamStartPolicy{deliver: nats.DeliverNewPolicy}
+
+	// AllAvailable delivers every message the stream still holds.
+	AllAvailable = StreamStartPolicy{deliver: nats.DeliverAllPolicy}
+)
+
+// FromSequence starts at stream sequence seq, inclusive.
+func FromSequence(seq uint64) StreamStartPolicy {
+	return StreamStartPolicy{deliver: nats.DeliverByStartSequencePolicy, sequence: seq}
+}
+
+// FromTime starts at the first message stored at or after t.
+func FromTime(t time.Time) StreamStartPolicy {
+	return StreamStartPolicy{deliver: nats.DeliverByStartTimePolicy, time: t}
+}
+
+func (p StreamStartPolicy) subOpt() (nats.SubOpt, error) {
+	switch p.deliver {
+	case nats.DeliverAllPolicy:
+		return nats.DeliverAll(), nil
+	case nats.DeliverNewPolicy:
+		return nats.DeliverNew(), nil
+	case nats.DeliverByStartSequencePolicy:
+		if p.sequence == 0 {
+			return nil, fmt.Errorf("start sequence must be at least 1")
+		}
+		return nats.StartSequence(p.sequence), nil
+	case nats.DeliverByStartTimePolicy:
+		if p.time.IsZero() {
+			return nil, fmt.Errorf("start time cannot be zero")
+		}
+		return nats.StartTime(p.time), nil
+	default:
+		return nil, fmt.Errorf("unsupported deliver policy %v", p.deliver)
+	}
+}
+
 type PullOptions struct {
 	Batch    int
 	MaxWait  time.Duration
@@ -158,6 +230,175 @@ func (c *conn) SubscribeStreamViaDurable(subscriberID string, subject string, ha
 	}, nil
 }
 
+// SubscribeStreamConcurrent is SubscribeStreamViaDurable for handlers that
+// spend most of their time waiting, such as on IO. Up to concurrency
+// messages are handled at once, each acknowledged as soon as its handler
+// returns; the consumer is limited to that many unacknowledged messages so
+// the server holds back the rest. A message the handler does not acknowledge,
+// or whose handler panics, is rejected and redelivered. Cancelling stops
+// dispatch, rejects messages still waiting for a handler so they are
+// redelivered, and waits for running handlers to return.
+func (c *conn) SubscribeStreamConcurrent(subscriberID string, subject string, concurrency int, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	if concurrency <= 0 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", concurrency)
+	}
+
+	slots := make(chan struct{}, concurrency)
+	stop := make(chan struct{})
+	var (
+		mu      sync.Mutex
+		stopped bool
+		running sync.WaitGroup
+	)
+
+	handle := func(msg *nats.Msg) {
+		defer func() {
+			if r := recover(); r != nil {
+				errHandler(fmt.Errorf("handler panicked on subject %q: %v", msg.Subject, r))
+				if err := msg.Nak(); err != nil {
+					errHandler(fmt.Errorf("failed to reject message on subject %q: %w", msg.Subject, err))
+				}
+			}
+		}()
+
+		response, ok, ack := handler(msg.Subject, msg.Data)
+		if ack {
+			if err := msg.Ack(); err != nil {
+				errHandler(fmt.Errorf("failed to acknowledge message on subject %q: %w", msg.Subject, err))
+			}
+		} else if err := msg.Nak(); err != nil {
+			errHandler(fmt.Errorf("failed to reject message on subject %q: %w", msg.Subject, err))
+		}
+		if !ok || msg.Reply == "" {
+			return
+		}
+		if err := msg.Respond(response); err != nil {
+			errHandler(fmt.Errorf("failed to respond to message on subject %q: %w", msg.Subject, err))
+		}
+	}
+
+	opt = append([]nats.SubOpt{nats.MaxAckPending(concurrency)}, opt...)
+	opt = append(opt, nats.ManualAck(), nats.Durable(subscriberID))
+	sub, err := c.js.Subscribe(subject, func(msg *nats.Msg) {
+		// Blocking here holds back delivery until a handler is free
+		acquired := false
+		select {
+		case slots <- struct{}{}:
+			acquired = true
+		case <-stop:
+		}
+
+		// Once cancelled, messages still arriving are handed back
+		mu.Lock()
+		if stopped {
+			mu.Unlock()
+			if acquired {
+				<-slots
+			}
+			if err := msg.Nak(); err != nil {
+				errHandler(fmt.Errorf("failed to reject message on subject %q: %w", msg.Subject, err))
+			}
+			return
+		}
+		running.Add(1)
+		mu.Unlock()
+
+		go func() {
+			defer running.Done()
+			defer func() { <-slots }()
+			handle(msg)
+		}()
+	}, opt...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to subject %q: %w", subject, err)
+	}
+
+	return func() {
+		mu.Lock()
+		if stopped {
+			mu.Unlock()
+			return
+		}
+		stopped = true
+		close(stop)
+		mu.Unlock()
+
+		if err := sub.Unsubscribe(); err != nil {
+			errHandler(fmt.Errorf("failed to unsubscribe from subject %q: %w", subject, err))
+		}
+		running.Wait()
+	}, nil
+}
+
+// SubscribeStreamViaDurableWithDeadLetter is SubscribeStreamViaDurable for
+// work that can fail. The handler is told how many times the message has been
+// delivered; a message it does not acknowledge is redelivered, and once it
+// has been delivered MaxDeliver times it is republished to the dead-letter
+// subject and acknowledged, so a poison message cannot block the consumer.
+func (c *conn) SubscribeStreamViaDurableWithDeadLetter(subscriberID string, subject string, option DeadLetterOptions, handler func(subject string, msg []byte, delivered int) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	if option.DeadLetterSubject == "" {
+		return nil, fmt.Errorf("dead-letter subject cannot be empty")
+	}
+	if option.MaxDeliver <= 0 {
+		option.MaxDeliver = 5
+	}
+
+	opt = append(opt, nats.ManualAck(), nats.Durable(subscriberID))
+	sub, err := c.js.Subscribe(subject, func(msg *nats.Msg) {
+		meta, err := msg.Metadata()
+		if err != nil {
+			errHandler(fmt.Errorf("failed to read metadata of message on subject %q: %w", msg.Subject, err))
+			return
+		}
+
+		response, ok, ack := handler(msg.Subject, msg.Data, int(meta.NumDelivered))
+		switch {
+		case ack:
+			if err := msg.Ack(); err != nil {
+				errHandler(fmt.Errorf("failed to acknowledge message on subject %q: %w", msg.Subject, err))
+			}
+		case int(meta.NumDelivered) < option.MaxDeliver:
+			if err := msg.NakWithDelay(option.RetryDelay); err != nil {
+				errHandler(fmt.Errorf("failed to reject message on subject %q: %w", msg.Subject, err))
+			}
+		default:
+			dead := nats.NewMsg(option.DeadLetterSubject)
+			dead.Data = msg.Data
+			for key, values := range msg.Header {
+				dead.Header[key] = values
+			}
+			dead.Header.Set(DeadLetterHeaderSubject, msg.Subject)
+			dead.Header.Set(DeadLetterHeaderDeliveries, fmt.Sprintf("%d", meta.NumDelivered))
+			dead.Header.Set(DeadLetterHeaderOriginalSeq, fmt.Sprintf("%d", meta.Sequence.Stream))
+			if _, err := c.js.PublishMsg(dead); err != nil {
+				errHandler(fmt.Errorf("failed to dead-letter message on subject %q: %w", msg.Subject, err))
+				// Leave the message to be redelivered and tried again
+				_ = msg.NakWithDelay(option.RetryDelay)
+				return
+			}
+			if err := msg.Ack(); err != nil {
+				errHandler(fmt.Errorf("failed to acknowledge dead-lettered message on subject %q: %w", msg.Subject, err))
+			}
+		}
+
+		if !ok || msg.Reply == "" {
+			return
+		}
+		if err := msg.Respond(response); err != nil {
+			errHandler(fmt.Errorf("failed to respond to message on subject %q: %w", msg.Subject, err))
+		}
+	}, opt...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to subject %q: %w", subject, err)
+	}
+
+	return func() {
+		if err := sub.Unsubscribe(); err != nil {
+			errHandler(fmt.Errorf("failed to unsubscribe from subject %q: %w", subject, err))
+		}
+	}, nil
+}
+
 func (c *conn) PullPersistentViaDurable(subscriberID string, subject string, option PullOptions, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
 	opt = append(opt, nats.ManualAck())
 	sub, err := c.js.PullSubscribe(subject, subscriberID, opt...)
@@ -247,6 +488,17 @@ func (c *conn) SubscribePersistentViaEphemeral(subject string, handler func(subj
 	}, nil
 }
 
+// SubscribeStreamFrom is SubscribePersistentViaEphemeral starting at start,
+// for replaying or backfilling stream history.
+func (c *conn) SubscribeStreamFrom(subject string, start StreamStartPolicy, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	startOpt, err := start.subOpt()
+	if err != nil {
+		return nil, fmt.Errorf("invalid start policy for subject %q: %w", subject, err)
+	}
+
+	return c.SubscribePersistentViaEphemeral(subject, handler, errHandler, append(opt, startOpt)...)
+}
+
 func (c *conn) PullPersistentViaEphemeral(subject string, option PullOptions, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
 	opt = append(opt, nats.ManualAck())
 	sub, err := c.js.PullSubscribe(subject, "", opt...)
@@ -329,6 +581,166 @@ func (c *conn) PublishPersistentWithOptions(subject string, msg []byte, opts ...
 	return ack, nil
 }
 
+const (
+	persistentBatchWindow     = 256
+	persistentBatchAckTimeout = 5 * time.Second
+)
+
+// BatchPublishError is returned by PublishPersistentBatch when some of the
+// messages were not stored.
+type BatchPublishError struct {
+	// Failed holds the error of every failed message by its index in the
+	// batch.
+	Failed map[int]error
+}
+
+func (e *BatchPublishError) Error() string {
+	first := -1
+	for i := range e.Failed {
+		if first < 0 || i < first {
+			first = i
+		}
+	}
+
+	return fmt.Sprintf("failed to publish %d batch messages, first at index %d: %v", len(e.Failed), first, e.Failed[first])
+}
+
+// PublishPersistentBatch publishes msgs to subject without waiting for each
+// ack in turn, keeping at most persistentBatchWindow publishes in flight,
+// and returns once every message is acknowledged or has failed. Acks are
+// returned in the order of msgs; on partial failure the failed entries are
+// nil and the error is a *BatchPublishError naming them.
+func (c *conn) PublishPersistentBatch(subject string, msgs [][]byte) ([]*nats.PubAck, error) {
+	acks := make([]*nats.PubAck, len(msgs))
+	futures := make([]nats.PubAckFuture, len(msgs))
+	failed := make(map[int]error)
+
+	wait := func(i int) {
+		if futures[i] == nil {
+			return
+		}
+
+		select {
+		case ack := <-futures[i].Ok():
+			acks[i] = ack
+		case err := <-futures[i].Err():
+			failed[i] = err
+		case <-time.After(persistentBatchAckTimeout):
+			failed[i] = nats.ErrTimeout
+		}
+	}
+
+	for i, msg := range msgs {
+		if i >= persistentBatchWindow {
+			wait(i - persistentBatchWindow)
+		}
+
+		future, err := c.js.PublishAsync(subject, msg)
+		if err != nil {
+			failed[i] = err
+			continue
+		}
+		futures[i] = future
+	}
+
+	for i := max(len(msgs)-persistentBatchWindow, 0); i < len(msgs); i++ {
+		wait(i)
+	}
+
+	if len(failed) > 0 {
+		return acks, &BatchPublishError{Failed: failed}
+	}
+
+	return acks, nil
+}
+
+// ReliableOptions controls PublishPersistentReliable.
+type ReliableOptions struct {
+	// MsgID is sent as the Nats-Msg-Id header so the stream drops duplicates
+	// of a retried publish. If empty, a random ID is generated.
+	MsgID string
+
+	// MaxAttempts is the total number of publish attempts. Defaults to 5.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry; it doubles after
+	// each attempt up to MaxBackoff. Defaults to 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between retries. Defaults to 2s.
+	MaxBackoff time.Duration
+}
+
+const (
+	defaultReliableMaxAttempts    = 5
+	defaultReliableInitialBackoff = 100 * time.Millisecond
+	defaultReliableMaxBackoff     = 2 * time.Second
+)
+
+// PublishPersistentReliable publishes msg to subject, retrying with backoff
+// while the stream has no responders or is electing a leader. Every attempt
+// carries the same Nats-Msg-Id, so a retry after a lost ack is deduplicated
+// by the stream (within its Duplicates window) instead of stored twice.
+func (c *conn) PublishPersistentReliable(subject string, msg []byte, opts ReliableOptions) (*nats.PubAck, error) {
+	return publishReliable(c.js.PublishMsg, subject, msg, opts)
+}
+
+func publishReliable(publish func(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error), subject string, msg []byte, opts ReliableOptions) (*nats.PubAck, error) {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultReliableMaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = defaultReliableInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultReliableMaxBackoff
+	}
+	if opts.MsgID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, fmt.Errorf("failed to generate message id for subject %q: %w", subject, err)
+		}
+		opts.MsgID = hex.EncodeToString(id)
+	}
+
+	m := nats.NewMsg(subject)
+	m.Data = msg
+	m.Header.Set(nats.MsgIdHdr, opts.MsgID)
+
+	backoff := opts.InitialBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		var ack *nats.PubAck
+		ack, err = publish(m)
+		if err == nil {
+			return ack, nil
+		}
+		if attempt >= opts.MaxAttempts || !isTransientPublishError(err) {
+			break
+		}
+
+		time.Sleep(backoff)
+		backoff = min(backoff*2, opts.MaxBackoff)
+	}
+
+	return nil, fmt.Errorf("failed to publish to subject %q: %w", subject, err)
+}
+
+// isTransientPublishError reports whether a publish failed because the stream
+// was briefly unreachable, as during a leader election, rather than because
+// the publish itself was rejected.
+func isTransientPublishError(err error) bool {
+	if errors.Is(err, nats.ErrNoResponders) ||
+		errors.Is(err, nats.ErrNoStreamResponse) ||
+		errors.Is(err, nats.ErrTimeout) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var apiErr *nats.APIError
+	return errors.As(err, &apiErr) && apiErr.Code == 503
+}
+
 func (c *conn) DeleteStream(streamName string) error {
 	err := c.js.DeleteStream(streamName)
 	if err != nil {
@@ -344,3 +756,299 @@ func (c *conn) GetStreamInfo(streamName string) (*nats.StreamInfo, error) {
 	}
 	return info, nil
 }
+
+// StreamStats summarises the state of a stream.
+type StreamStats struct {
+	Messages  uint64
+	Bytes     uint64
+	FirstSeq  uint64
+	LastSeq   uint64
+	FirstTime time.Time
+	LastTime  time.Time
+	Subjects  uint64
+	Deleted   int
+	Consumers int
+}
+
+// StreamStats returns the message counts and sequence range of streamName.
+func (c *conn) StreamStats(streamName string) (StreamStats, error) {
+	info, err := c.js.StreamInfo(streamName)
+	if err != nil {
+		return StreamStats{}, fmt.Errorf("failed to get stream info for %q: %w", streamName, err)
+	}
+
+	state := info.State
+	return StreamStats{
+		Messages:  state.Msgs,
+		Bytes:     state.Bytes,
+		FirstSeq:  state.FirstSeq,
+		LastSeq:   state.LastSeq,
+		FirstTime: state.FirstTime,
+		LastTime:  state.LastTime,
+		Subjects:  state.NumSubjects,
+		Deleted:   state.NumDeleted,
+		Consumers: state.Consumers,
+	}, nil
+}
+
+// ConsumerLag reports the backlog of the durable consumer on streamName.
+// pending counts the messages it has not acknowledged yet, whether or not
+// they were delivered, and ackFloor is the stream sequence up to which every
+// message is acknowledged.
+func (c *conn) ConsumerLag(streamName, durable string) (pending uint64, ackFloor uint64, err error) {
+	info, err := c.js.ConsumerInfo(streamName, durable)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get consumer info for %q on stream %q: %w", durable, streamName, err)
+	}
+
+	return info.NumPending + uint64(info.NumAckPending), info.AckFloor.Stream, nil
+}
+
+// Handler handles a message dispatched by a SubjectRouter. Returning an error
+// rejects the message so that it is redelivered.
+type Handler func(subject string, msg []byte) error
+
+type subjectRoute struct {
+	pattern []string
+	handler Handler
+}
+
+// UnmatchedAction is what ConsumeByRouter does with a message that neither a
+// route nor the default handler of its router takes.
+type UnmatchedAction string
+
+const (
+	// UnmatchedAck acknowledges and drops the message.
+	UnmatchedAck UnmatchedAction = "ack"
+	// UnmatchedNak rejects the message so that it is redelivered, e.g. to a
+	// consumer that has the route.
+	UnmatchedNak UnmatchedAction = "nak"
+	// UnmatchedTerm rejects the message for good; it is never redelivered.
+	UnmatchedTerm UnmatchedAction = "term"
+)
+
+// SubjectRouter dispatches messages to handlers by subject, so one
+// subscription can serve subjects that would otherwise each need their own.
+// Patterns may use the NATS "*" and ">" wildcards; when several match, the
+// one with more literal tokens wins. Messages no pattern matches go to the
+// handler set with Default, or else are settled by the action set with
+// OnUnmatched, UnmatchedAck unless changed. The zero value is an empty
+// router ready to use, and routes may be added while it is consuming.
+type SubjectRouter struct {
+	mu        sync.RWMutex
+	routes    []subjectRoute
+	fallback  Handler
+	unmatched UnmatchedAction
+}
+
+// Handle routes subjects matching pattern to fn.
+func (r *SubjectRouter) Handle(pattern string, fn Handler) error {
+	tokens, err := parseSubjectPattern(pattern)
+	if err != nil {
+		return err
+	}
+	if fn == nil {
+		return fmt.Errorf("handler for pattern %q cannot be nil", pattern)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, route := range r.routes {
+		if compareSubjectPatterns(route.pattern, tokens) == 0 {
+			return fmt.Errorf("a handler for pattern %q is already registered", pattern)
+		}
+	}
+
+	r.routes = append(r.routes, subjectRoute{pattern: tokens, handler: fn})
+	// Most specific patterns first
+	sort.Slice(r.routes, func(i, j int) bool {
+		return compareSubjectPatterns(r.routes[i].pattern, r.routes[j].pattern) < 0
+	})
+
+	return nil
+}
+
+// Default routes subjects no pattern matches to fn. A nil fn removes the
+// default handler.
+func (r *SubjectRouter) Default(fn Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fallback = fn
+}
+
+// OnUnmatched sets what ConsumeByRouter does with messages that neither a
+// route nor the default handler takes.
+func (r *SubjectRouter) OnUnmatched(action UnmatchedAction) error {
+	switch action {
+	case UnmatchedAck, UnmatchedNak, UnmatchedTerm:
+	default:
+		return fmt.Errorf("unknown unmatched action %q", action)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.unmatched = action
+	return nil
+}
+
+// unmatchedAction returns the action set with OnUnmatched.
+func (r *SubjectRouter) unmatchedAction() UnmatchedAction {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.unmatched == "" {
+		return UnmatchedAck
+	}
+	return r.unmatched
+}
+
+// Route passes msg to the handler of the most specific pattern matching
+// subject, or to the default handler when none does, and returns its error.
+// matched is false when neither takes the message.
+func (r *SubjectRouter) Route(subject string, msg []byte) (matched bool, err error) {
+	tokens := strings.Split(subject, ".")
+
+	r.mu.RLock()
+	handler := r.fallback
+	for _, route := range r.routes {
+		if matchSubjectPattern(route.pattern, tokens) {
+			handler = route.handler
+			break
+		}
+	}
+	r.mu.RUnlock()
+
+	if handler == nil {
+		return false, nil
+	}
+
+	return true, handler(subject, msg)
+}
+
+// ConsumeByHandler subscribes to every subject of streamName and dispatches
+// each message to the route whose pattern matches its subject, as
+// ConsumeByRouter does with a router holding routes.
+func (c *conn) ConsumeByHandler(streamName string, routes map[string]func(subject string, msg []byte) error, errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("at least one route is required")
+	}
+
+	router := &SubjectRouter{}
+	for pattern, handler := range routes {
+		if err := router.Handle(pattern, handler); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.ConsumeByRouter(streamName, router, errHandler, opt...)
+}
+
+// ConsumeByRouter subscribes to every subject of streamName and dispatches
+// each message through router. A message is acknowledged when its handler
+// returns nil and redelivered when it returns an error. Messages no handler
+// takes are settled as set with SubjectRouter.OnUnmatched, acknowledged and
+// dropped by default.
+func (c *conn) ConsumeByRouter(streamName string, router *SubjectRouter, errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	if router == nil {
+		return nil, fmt.Errorf("router cannot be nil")
+	}
+
+	opts := append([]nats.SubOpt{nats.BindStream(streamName), nats.ManualAck()}, opt...)
+	sub, err := c.js.Subscribe(">", func(msg *nats.Msg) {
+		matched, err := router.Route(msg.Subject, msg.Data)
+		if err != nil {
+			errHandler(fmt.Errorf("handler failed for message on subject %q: %w", msg.Subject, err))
+			if err := msg.Nak(); err != nil {
+				errHandler(fmt.Errorf("failed to reject message on subject %q: %w", msg.Subject, err))
+			}
+			return
+		}
+
+		if !matched {
+			switch router.unmatchedAction() {
+			case UnmatchedNak:
+				if err := msg.Nak(); err != nil {
+					errHandler(fmt.Errorf("failed to reject unrouted message on subject %q: %w", msg.Subject, err))
+				}
+				return
+			case UnmatchedTerm:
+				if err := msg.Term(); err != nil {
+					errHandler(fmt.Errorf("failed to terminate unrouted message on subject %q: %w", msg.Subject, err))
+				}
+				return
+			}
+		}
+
+		if err := msg.Ack(); err != nil {
+			errHandler(fmt.Errorf("failed to acknowledge message on subject %q: %w", msg.Subject, err))
+		}
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to stream %q: %w", streamName, err)
+	}
+
+	return func() {
+		if err := sub.Unsubscribe(); err != nil {
+			errHandler(fmt.Errorf("failed to unsubscribe from stream %q: %w", streamName, err))
+		}
+	}, nil
+}
+
+func parseSubjectPattern(pattern string) ([]string, error) {
+	tokens := strings.Split(pattern, ".")
+	for i, token := range tokens {
+		if token == "" {
+			return nil, fmt.Errorf("invalid subject pattern %q: empty token", pattern)
+		}
+		if token == ">" && i != len(tokens)-1 {
+			return nil, fmt.Errorf("invalid subject pattern %q: '>' must be the last token", pattern)
+		}
+	}
+	return tokens, nil
+}
+
+func matchSubjectPattern(pattern, subject []string) bool {
+	for i, token := range pattern {
+		if token == ">" {
+			return len(subject) > i
+		}
+		if i >= len(subject) {
+			return false
+		}
+		if token != "*" && token != subject[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(subject)
+}
+
+// compareSubjectPatterns orders patterns by specificity: more literal tokens
+// first, then patterns without ">", then longer patterns.
+func compareSubjectPatterns(a, b []string) int {
+	literals := func(p []string) (n int) {
+		for _, token := range p {
+			if token != "*" && token != ">" {
+				n++
+			}
+		}
+		return n
+	}
+	tail := func(p []string) bool { return p[len(p)-1] == ">" }
+
+	if la, lb := literals(a), literals(b); la != lb {
+		return lb - la
+	}
+	if ta, tb := tail(a), tail(b); ta != tb {
+		if ta {
+			return 1
+		}
+		return -1
+	}
+	if len(a) != len(b) {
+		return len(b) - len(a)
+	}
+	return strings.Compare(strings.Join(a, "."), strings.Join(b, "."))
+}
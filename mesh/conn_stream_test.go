@@ -1,6 +1,9 @@
 package mesh
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -313,6 +316,319 @@ func TestJetStreamSubscribeStreamViaDurable(t *testing.T) {
 	})
 }
 
+func TestJetStreamSubscribeStreamViaDurableWithDeadLetter(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	if err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+		Name:     "ORDERS",
+		Subjects: []string{"orders.*"},
+	}); err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+	if err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+		Name:     "ORDERS_DLQ",
+		Subjects: []string{"orders-dead"},
+	}); err != nil {
+		t.Fatalf("failed to create dead-letter stream: %v", err)
+	}
+
+	dead, err := cluster3.nc.js.SubscribeSync("orders-dead")
+	if err != nil {
+		t.Fatalf("failed to subscribe to dead-letter subject: %v", err)
+	}
+	defer dead.Unsubscribe()
+
+	const maxDeliver = 3
+	var mu sync.Mutex
+	deliveries := map[string][]int{}
+
+	cancel, err := cluster2.nc.SubscribeStreamViaDurableWithDeadLetter(
+		"order-processor",
+		"orders.*",
+		DeadLetterOptions{MaxDeliver: maxDeliver, DeadLetterSubject: "orders-dead"},
+		func(subject string, msg []byte, delivered int) (response []byte, reply bool, ack bool) {
+			mu.Lock()
+			deliveries[string(msg)] = append(deliveries[string(msg)], delivered)
+			mu.Unlock()
+			// Poison messages are always rejected
+			return nil, false, string(msg) != "poison"
+		},
+		func(err error) {
+			t.Errorf("error in dead-letter handler: %v", err)
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer cancel()
+
+	// Give subscription time to be established
+	time.Sleep(100 * time.Millisecond)
+
+	if err := cluster1.nc.PublishPersistent("orders.new", []byte("poison")); err != nil {
+		t.Fatalf("failed to publish poison message: %v", err)
+	}
+	if err := cluster1.nc.PublishPersistent("orders.new", []byte("good")); err != nil {
+		t.Fatalf("failed to publish good message: %v", err)
+	}
+
+	msg, err := dead.NextMsg(10 * time.Second)
+	if err != nil {
+		t.Fatalf("poison message did not reach the dead-letter subject: %v", err)
+	}
+	if string(msg.Data) != "poison" {
+		t.Errorf("expected poison on the dead-letter subject, got %q", msg.Data)
+	}
+	if got := msg.Header.Get(DeadLetterHeaderSubject); got != "orders.new" {
+		t.Errorf("expected original subject orders.new, got %q", got)
+	}
+	if got := msg.Header.Get(DeadLetterHeaderDeliveries); got != "3" {
+		t.Errorf("expected 3 deliveries in header, got %q", got)
+	}
+	if msg.Header.Get(DeadLetterHeaderOriginalSeq) == "" {
+		t.Error("expected original stream sequence header")
+	}
+
+	// Once dead-lettered the message is acknowledged and never redelivered
+	time.Sleep(500 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	poison := deliveries["poison"]
+	if len(poison) != maxDeliver {
+		t.Fatalf("expected %d attempts for poison message, got %v", maxDeliver, poison)
+	}
+	for i, delivered := range poison {
+		if delivered != i+1 {
+			t.Errorf("expected delivery counts 1..%d, got %v", maxDeliver, poison)
+			break
+		}
+	}
+	if good := deliveries["good"]; len(good) != 1 || good[0] != 1 {
+		t.Errorf("expected good message to be handled once, got %v", good)
+	}
+	if _, err := dead.NextMsg(200 * time.Millisecond); err == nil {
+		t.Error("expected only the poison message on the dead-letter subject")
+	}
+
+	if _, err := cluster1.nc.SubscribeStreamViaDurableWithDeadLetter("x", "orders.*", DeadLetterOptions{}, nil, nil); err == nil {
+		t.Error("expected error without a dead-letter subject")
+	}
+}
+
+func TestJetStreamSubscribeStreamConcurrent(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	if err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+		Name:     "THUMBS",
+		Subjects: []string{"thumbs.*"},
+	}); err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+
+	const (
+		concurrency = 4
+		total       = 40
+	)
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	deliveries := map[string]int{}
+	acked := map[string]bool{}
+	done := make(chan struct{})
+
+	var panics sync.WaitGroup
+	panics.Add(1)
+
+	cancel, err := cluster2.nc.SubscribeStreamConcurrent(
+		"thumbnailer",
+		"thumbs.*",
+		concurrency,
+		func(subject string, msg []byte) (response []byte, reply bool, ack bool) {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			deliveries[string(msg)]++
+			attempt := deliveries[string(msg)]
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			running--
+
+			// One message is rejected and one crashes its worker on the first
+			// attempt; both must come back
+			switch {
+			case string(msg) == "job-7" && attempt == 1:
+				return nil, false, false
+			case string(msg) == "job-13" && attempt == 1:
+				panic("worker failed")
+			}
+
+			acked[string(msg)] = true
+			if len(acked) == total {
+				close(done)
+			}
+			return nil, false, true
+		},
+		func(err error) {
+			if strings.Contains(err.Error(), "worker failed") {
+				panics.Done()
+				return
+			}
+			t.Errorf("error in concurrent handler: %v", err)
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer cancel()
+
+	for i := 0; i < total; i++ {
+		if err := cluster1.nc.PublishPersistent("thumbs.new", []byte(fmt.Sprintf("job-%d", i))); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		mu.Lock()
+		t.Fatalf("timed out with %d of %d messages acknowledged", len(acked), total)
+	}
+	panics.Wait()
+
+	mu.Lock()
+	if maxRunning > concurrency {
+		t.Errorf("expected at most %d handlers at once, got %d", concurrency, maxRunning)
+	}
+	if maxRunning < 2 {
+		t.Errorf("expected handlers to run concurrently, got at most %d", maxRunning)
+	}
+	if deliveries["job-7"] != 2 || deliveries["job-13"] != 2 {
+		t.Errorf("expected rejected and failed messages to be redelivered once, got %d and %d", deliveries["job-7"], deliveries["job-13"])
+	}
+	mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pending, ackFloor, err := cluster1.nc.ConsumerLag("THUMBS", "thumbnailer")
+		if err != nil {
+			t.Fatalf("failed to get consumer lag: %v", err)
+		}
+		if pending == 0 && ackFloor == total {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected all messages acknowledged, got %d pending with ack floor %d", pending, ackFloor)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if _, err := cluster1.nc.SubscribeStreamConcurrent("x", "thumbs.*", 0, nil, nil); err == nil {
+		t.Error("expected error for zero concurrency")
+	}
+}
+
+func TestJetStreamSubscribeStreamConcurrentCancel(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	if err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+		Name:     "RENDERS",
+		Subjects: []string{"renders.*"},
+	}); err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+
+	var (
+		mu        sync.Mutex
+		started   int
+		cancelled bool
+		late      bool
+	)
+	first := make(chan struct{})
+	release := make(chan struct{})
+
+	// A single handler with room for more unacknowledged messages leaves the
+	// next delivery waiting for the handler to free up
+	cancel, err := cluster2.nc.SubscribeStreamConcurrent(
+		"renderer",
+		"renders.*",
+		1,
+		func(subject string, msg []byte) (response []byte, reply bool, ack bool) {
+			mu.Lock()
+			started++
+			late = late || cancelled
+			n := started
+			mu.Unlock()
+
+			if n == 1 {
+				close(first)
+				<-release
+			}
+			return nil, false, true
+		},
+		func(err error) {
+			t.Logf("error in concurrent handler: %v", err)
+		},
+		nats.MaxAckPending(10),
+	)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := cluster1.nc.PublishPersistent("renders.new", []byte(fmt.Sprintf("frame-%d", i))); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+	}
+
+	select {
+	case <-first:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the first handler")
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	cancelled = true
+	mu.Unlock()
+
+	returned := make(chan struct{})
+	go func() {
+		cancel()
+		close(returned)
+	}()
+
+	select {
+	case <-returned:
+		t.Fatal("expected cancel to wait for the running handler")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case <-returned:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for cancel to return")
+	}
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if late {
+		t.Error("expected no handler to start after cancel")
+	}
+	if started != 1 {
+		t.Errorf("expected only the first message to be handled, got %d", started)
+	}
+}
+
 func TestJetStreamPullPersistentViaDurable(t *testing.T) {
 	t.Run("pull subscription", func(t *testing.T) {
 		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
@@ -479,6 +795,100 @@ func TestJetStreamSubscribePersistentViaEphemeral(t *testing.T) {
 	})
 }
 
+func TestJetStreamSubscribeStreamFrom(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	config := &PersistentConfig{
+		Subjects: []string{"history.*"},
+		MaxMsgs:  100,
+	}
+	if err := cluster1.nc.CreateOrUpdateStream(config); err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+
+	var midpoint time.Time
+	for i := 1; i <= 10; i++ {
+		if i == 8 {
+			time.Sleep(50 * time.Millisecond)
+			midpoint = time.Now()
+			time.Sleep(50 * time.Millisecond)
+		}
+		if err := cluster1.nc.PublishPersistent("history.events", []byte(fmt.Sprintf("event-%d", i))); err != nil {
+			t.Fatalf("failed to publish message %d: %v", i, err)
+		}
+	}
+
+	// replay collects everything delivered from start until the stream goes
+	// quiet, so an over-delivery shows up as a wrong count.
+	replay := func(t *testing.T, start StreamStartPolicy) []string {
+		var mu sync.Mutex
+		received := make([]string, 0)
+		cancel, err := cluster3.nc.SubscribeStreamFrom(
+			"history.*",
+			start,
+			func(subject string, msg []byte) (response []byte, reply bool, ack bool) {
+				mu.Lock()
+				received = append(received, string(msg))
+				mu.Unlock()
+				return nil, false, true
+			},
+			func(err error) {
+				t.Logf("Error in replay handler: %v", err)
+			},
+		)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		defer cancel()
+
+		time.Sleep(1 * time.Second)
+
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received...)
+	}
+
+	t.Run("from sequence", func(t *testing.T) {
+		got := replay(t, FromSequence(5))
+		if len(got) != 6 {
+			t.Fatalf("expected 6 messages, got %d: %v", len(got), got)
+		}
+		if got[0] != "event-5" || got[5] != "event-10" {
+			t.Errorf("expected event-5 through event-10, got %v", got)
+		}
+	})
+
+	t.Run("all available", func(t *testing.T) {
+		if got := replay(t, AllAvailable); len(got) != 10 {
+			t.Errorf("expected 10 messages, got %d: %v", len(got), got)
+		}
+	})
+
+	t.Run("from time", func(t *testing.T) {
+		got := replay(t, FromTime(midpoint))
+		if len(got) != 3 || got[0] != "event-8" {
+			t.Errorf("expected event-8 through event-10, got %v", got)
+		}
+	})
+
+	t.Run("from now", func(t *testing.T) {
+		if got := replay(t, FromNow); len(got) != 0 {
+			t.Errorf("expected no historical messages, got %v", got)
+		}
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		handler := func(string, []byte) ([]byte, bool, bool) { return nil, false, true }
+		if _, err := cluster3.nc.SubscribeStreamFrom("history.*", FromSequence(0), handler, nil); err == nil {
+			t.Error("expected error for start sequence 0")
+		}
+		if _, err := cluster3.nc.SubscribeStreamFrom("history.*", FromTime(time.Time{}), handler, nil); err == nil {
+			t.Error("expected error for zero start time")
+		}
+	})
+}
+
 func TestJetStreamPullPersistentViaEphemeral(t *testing.T) {
 	t.Run("ephemeral pull subscription", func(t *testing.T) {
 		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
@@ -559,6 +969,247 @@ func TestJetStreamPullPersistentViaEphemeral(t *testing.T) {
 	})
 }
 
+func TestJetStreamPublishPersistentReliable(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	config := &PersistentConfig{
+		Name:     "payments",
+		Subjects: []string{"payments.*"},
+		MaxMsgs:  100,
+	}
+	if err := cluster1.nc.CreateOrUpdateStream(config); err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+
+	t.Run("retries are deduplicated", func(t *testing.T) {
+		// The first attempt reaches the stream but its ack is lost, the second
+		// never gets there; both look transient to the caller.
+		attempts := 0
+		var msgIDs []string
+		publish := func(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+			attempts++
+			msgIDs = append(msgIDs, m.Header.Get(nats.MsgIdHdr))
+			switch attempts {
+			case 1:
+				if _, err := cluster1.nc.js.PublishMsg(m, opts...); err != nil {
+					return nil, err
+				}
+				return nil, nats.ErrNoResponders
+			case 2:
+				return nil, nats.ErrTimeout
+			default:
+				return cluster1.nc.js.PublishMsg(m, opts...)
+			}
+		}
+
+		ack, err := publishReliable(publish, "payments.captured", []byte("payment-1"), ReliableOptions{InitialBackoff: 10 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("expected 3 attempts, got %d", attempts)
+		}
+		if !ack.Duplicate {
+			t.Error("expected final ack to be flagged as duplicate")
+		}
+		if msgIDs[0] == "" || msgIDs[0] != msgIDs[1] || msgIDs[1] != msgIDs[2] {
+			t.Errorf("expected one generated message id across attempts, got %v", msgIDs)
+		}
+
+		info, err := cluster1.nc.GetStreamInfo("payments")
+		if err != nil {
+			t.Fatalf("failed to get stream info: %v", err)
+		}
+		if info.State.Msgs != 1 {
+			t.Errorf("expected exactly 1 stored message, got %d", info.State.Msgs)
+		}
+	})
+
+	t.Run("caller message id", func(t *testing.T) {
+		opts := ReliableOptions{MsgID: "payment-2"}
+		first, err := cluster2.nc.PublishPersistentReliable("payments.captured", []byte("payment-2"), opts)
+		if err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+		second, err := cluster3.nc.PublishPersistentReliable("payments.captured", []byte("payment-2"), opts)
+		if err != nil {
+			t.Fatalf("failed to republish: %v", err)
+		}
+		if first.Duplicate || !second.Duplicate || first.Sequence != second.Sequence {
+			t.Errorf("expected republish to be deduplicated, got %+v then %+v", first, second)
+		}
+	})
+
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		attempts := 0
+		publish := func(*nats.Msg, ...nats.PubOpt) (*nats.PubAck, error) {
+			attempts++
+			return nil, nats.ErrBadSubject
+		}
+		if _, err := publishReliable(publish, "payments.captured", nil, ReliableOptions{}); !errors.Is(err, nats.ErrBadSubject) {
+			t.Errorf("expected ErrBadSubject, got %v", err)
+		}
+		if attempts != 1 {
+			t.Errorf("expected a single attempt, got %d", attempts)
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		attempts := 0
+		publish := func(*nats.Msg, ...nats.PubOpt) (*nats.PubAck, error) {
+			attempts++
+			return nil, nats.ErrNoResponders
+		}
+		opts := ReliableOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+		if _, err := publishReliable(publish, "payments.captured", nil, opts); !errors.Is(err, nats.ErrNoResponders) {
+			t.Errorf("expected ErrNoResponders, got %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("expected 3 attempts, got %d", attempts)
+		}
+	})
+}
+
+func TestJetStreamPublishPersistentBatch(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	config := &PersistentConfig{
+		Name:     "ingest",
+		Subjects: []string{"ingest.*"},
+	}
+	if err := cluster1.nc.CreateOrUpdateStream(config); err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+
+	msgs := make([][]byte, 1000)
+	for i := range msgs {
+		msgs[i] = []byte(fmt.Sprintf("event-%d", i))
+	}
+
+	t.Run("all messages stored", func(t *testing.T) {
+		start := time.Now()
+		for _, msg := range msgs {
+			if err := cluster2.nc.PublishPersistent("ingest.sequential", msg); err != nil {
+				t.Fatalf("failed to publish: %v", err)
+			}
+		}
+		sequentialDuration := time.Since(start)
+
+		start = time.Now()
+		acks, err := cluster2.nc.PublishPersistentBatch("ingest.batch", msgs)
+		if err != nil {
+			t.Fatalf("failed to publish batch: %v", err)
+		}
+		batchDuration := time.Since(start)
+
+		if len(acks) != len(msgs) {
+			t.Fatalf("expected %d acks, got %d", len(msgs), len(acks))
+		}
+		for i, ack := range acks {
+			if ack == nil || ack.Sequence != uint64(len(msgs)+i+1) {
+				t.Fatalf("expected ack %d with sequence %d, got %+v", i, len(msgs)+i+1, ack)
+			}
+		}
+
+		info, err := cluster1.nc.GetStreamInfo("ingest")
+		if err != nil {
+			t.Fatalf("failed to get stream info: %v", err)
+		}
+		if info.State.Msgs != uint64(2*len(msgs)) {
+			t.Errorf("expected %d stored messages, got %d", 2*len(msgs), info.State.Msgs)
+		}
+
+		// Timings on an in-process node are too noisy to assert on
+		t.Logf("batch publish took %v, sequential publish took %v", batchDuration, sequentialDuration)
+	})
+
+	t.Run("failures are reported by index", func(t *testing.T) {
+		acks, err := cluster2.nc.PublishPersistentBatch("nostream.batch", msgs[:3])
+		var batchErr *BatchPublishError
+		if !errors.As(err, &batchErr) {
+			t.Fatalf("expected BatchPublishError, got %v", err)
+		}
+		if len(batchErr.Failed) != 3 {
+			t.Errorf("expected 3 failed messages, got %v", batchErr.Failed)
+		}
+		for i, ack := range acks {
+			if ack != nil || batchErr.Failed[i] == nil {
+				t.Errorf("expected message %d to fail, got ack %+v", i, ack)
+			}
+		}
+	})
+}
+
+func TestJetStreamConsumerLag(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	config := &PersistentConfig{
+		Name:     "jobs",
+		Subjects: []string{"jobs.*"},
+	}
+	if err := cluster1.nc.CreateOrUpdateStream(config); err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := cluster3.nc.PublishPersistent("jobs.resize", []byte(fmt.Sprintf("job-%d", i))); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+	}
+
+	sub, err := cluster2.nc.js.PullSubscribe("jobs.resize", "workers", nats.ManualAck())
+	if err != nil {
+		t.Fatalf("failed to create pull consumer: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	consumed := 0
+	for consumed < 20 {
+		msgs, err := sub.Fetch(20-consumed, nats.MaxWait(5*time.Second))
+		if err != nil {
+			t.Fatalf("failed to fetch: %v", err)
+		}
+		for _, msg := range msgs {
+			if err := msg.AckSync(); err != nil {
+				t.Fatalf("failed to ack: %v", err)
+			}
+			consumed++
+		}
+	}
+
+	pending, ackFloor, err := cluster1.nc.ConsumerLag("jobs", "workers")
+	if err != nil {
+		t.Fatalf("failed to get consumer lag: %v", err)
+	}
+	if pending != 30 {
+		t.Errorf("expected 30 pending messages, got %d", pending)
+	}
+	if ackFloor != 20 {
+		t.Errorf("expected ack floor 20, got %d", ackFloor)
+	}
+
+	stats, err := cluster2.nc.StreamStats("jobs")
+	if err != nil {
+		t.Fatalf("failed to get stream stats: %v", err)
+	}
+	if stats.Messages != 50 || stats.FirstSeq != 1 || stats.LastSeq != 50 {
+		t.Errorf("expected messages 1-50, got %d messages from %d to %d", stats.Messages, stats.FirstSeq, stats.LastSeq)
+	}
+	if stats.Consumers != 1 || stats.Subjects != 1 || stats.Bytes == 0 {
+		t.Errorf("unexpected stream stats: %+v", stats)
+	}
+
+	if _, _, err := cluster1.nc.ConsumerLag("jobs", "missing"); err == nil {
+		t.Error("expected error for a missing consumer")
+	}
+	if _, err := cluster1.nc.StreamStats("missing"); err == nil {
+		t.Error("expected error for a missing stream")
+	}
+}
+
 func TestJetStreamDeleteStream(t *testing.T) {
 	t.Run("delete stream", func(t *testing.T) {
 		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
@@ -598,3 +1249,325 @@ func TestJetStreamDeleteStream(t *testing.T) {
 		t.Log("Successfully deleted stream")
 	})
 }
+
+func TestSubjectPatternMatching(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		match   bool
+	}{
+		{"orders.created", "orders.created", true},
+		{"orders.created", "orders.deleted", false},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders.created.eu", false},
+		{"orders.>", "orders.created.eu", true},
+		{"orders.>", "orders", false},
+		{"*.created", "users.created", true},
+		{">", "anything.at.all", true},
+	}
+
+	for _, tt := range tests {
+		pattern, err := parseSubjectPattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", tt.pattern, err)
+		}
+		if got := matchSubjectPattern(pattern, strings.Split(tt.subject, ".")); got != tt.match {
+			t.Errorf("%q vs %q: expected %v, got %v", tt.pattern, tt.subject, tt.match, got)
+		}
+	}
+
+	for _, bad := range []string{"orders..created", "orders.>.eu", ""} {
+		if _, err := parseSubjectPattern(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestJetStreamConsumeByHandler(t *testing.T) {
+	t.Run("dispatch by subject", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		if err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+			Name:     "events",
+			Subjects: []string{"events.>"},
+			Replicas: 1,
+		}); err != nil {
+			t.Fatalf("failed to create stream: %v", err)
+		}
+
+		var mu sync.Mutex
+		got := make(map[string][]string)
+		record := func(route string) func(subject string, msg []byte) error {
+			return func(subject string, msg []byte) error {
+				mu.Lock()
+				defer mu.Unlock()
+				got[route] = append(got[route], subject)
+				return nil
+			}
+		}
+
+		attempts := 0
+		cancel, err := cluster2.nc.ConsumeByHandler("events", map[string]func(subject string, msg []byte) error{
+			"events.order.created": record("created"),
+			"events.order.*":       record("order"),
+			"events.retry": func(subject string, msg []byte) error {
+				mu.Lock()
+				defer mu.Unlock()
+				attempts++
+				if attempts == 1 {
+					return fmt.Errorf("transient failure")
+				}
+				got["retry"] = append(got["retry"], subject)
+				return nil
+			},
+			">": record("default"),
+		}, func(err error) {})
+		if err != nil {
+			t.Fatalf("failed to consume: %v", err)
+		}
+		defer cancel()
+
+		for _, subject := range []string{"events.order.created", "events.order.deleted", "events.user.signup", "events.retry"} {
+			if err := cluster1.nc.PublishPersistent(subject, []byte("payload")); err != nil {
+				t.Fatalf("failed to publish %s: %v", subject, err)
+			}
+		}
+
+		deadline := time.After(10 * time.Second)
+		for {
+			mu.Lock()
+			done := len(got["created"]) == 1 && len(got["order"]) == 1 && len(got["default"]) == 1 && len(got["retry"]) == 1
+			mu.Unlock()
+			if done {
+				break
+			}
+			select {
+			case <-deadline:
+				mu.Lock()
+				t.Fatalf("timeout waiting for dispatch, got %v", got)
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if got["created"][0] != "events.order.created" {
+			t.Errorf("expected literal route to win, got %v", got["created"])
+		}
+		if got["order"][0] != "events.order.deleted" {
+			t.Errorf("expected wildcard route for deleted, got %v", got["order"])
+		}
+		if got["default"][0] != "events.user.signup" {
+			t.Errorf("expected default route for signup, got %v", got["default"])
+		}
+		if attempts != 2 {
+			t.Errorf("expected failed message to be redelivered once, got %d attempts", attempts)
+		}
+	})
+}
+
+func TestJetStreamConsumeByRouter(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	if err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+		Name:     "orders",
+		Subjects: []string{"orders.>"},
+		Replicas: 1,
+	}); err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+
+	created := make(chan string, 4)
+	cancelled := make(chan string, 4)
+	router := &SubjectRouter{}
+	if err := router.Handle("orders.*.created", func(subject string, msg []byte) error {
+		created <- subject
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to add route: %v", err)
+	}
+	if err := router.Handle("orders.*.cancelled", func(subject string, msg []byte) error {
+		cancelled <- subject
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to add route: %v", err)
+	}
+
+	if err := router.Handle("orders.*.created", func(subject string, msg []byte) error { return nil }); err == nil {
+		t.Error("expected error for a duplicate pattern")
+	}
+	if err := router.Handle("orders..created", func(subject string, msg []byte) error { return nil }); err == nil {
+		t.Error("expected error for an invalid pattern")
+	}
+	if err := router.Handle("orders.>", nil); err == nil {
+		t.Error("expected error for a nil handler")
+	}
+	if matched, err := router.Route("orders.us.shipped", nil); matched || err != nil {
+		t.Errorf("expected no route for orders.us.shipped, got %v (%v)", matched, err)
+	}
+
+	cancel, err := cluster2.nc.ConsumeByRouter("orders", router, func(err error) {
+		t.Errorf("error in router: %v", err)
+	})
+	if err != nil {
+		t.Fatalf("failed to consume: %v", err)
+	}
+	defer cancel()
+
+	for _, subject := range []string{"orders.us.created", "orders.eu.shipped", "orders.eu.cancelled"} {
+		if err := cluster1.nc.PublishPersistent(subject, []byte("payload")); err != nil {
+			t.Fatalf("failed to publish %s: %v", subject, err)
+		}
+	}
+
+	for name, ch := range map[string]chan string{"orders.us.created": created, "orders.eu.cancelled": cancelled} {
+		select {
+		case subject := <-ch:
+			if subject != name {
+				t.Errorf("expected %s, got %s", name, subject)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout waiting for %s", name)
+		}
+	}
+
+	// The unrouted message is acknowledged along with the routed ones
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var awaiting int
+		for info := range cluster1.nc.js.Consumers("orders") {
+			awaiting += info.NumAckPending + int(info.NumPending)
+		}
+		if awaiting == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected every message acknowledged, got %d outstanding", awaiting)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	select {
+	case subject := <-created:
+		t.Errorf("unexpected extra delivery of %s", subject)
+	case subject := <-cancelled:
+		t.Errorf("unexpected extra delivery of %s", subject)
+	default:
+	}
+
+	if _, err := cluster1.nc.ConsumeByRouter("orders", nil, func(error) {}); err == nil {
+		t.Error("expected error for a nil router")
+	}
+}
+
+func TestJetStreamConsumeByRouterUnmatched(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+	defer CleanupClusters(cluster1, cluster2, cluster3)
+
+	if err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+		Name:     "jobs",
+		Subjects: []string{"jobs.>"},
+		Replicas: 1,
+	}); err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+
+	known := make(chan string, 4)
+	fallback := make(chan string, 16)
+	router := &SubjectRouter{}
+	if err := router.Handle("jobs.known", func(subject string, msg []byte) error {
+		known <- subject
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to add route: %v", err)
+	}
+	if err := router.OnUnmatched("drop"); err == nil {
+		t.Error("expected error for an unknown unmatched action")
+	}
+	if err := router.OnUnmatched(UnmatchedNak); err != nil {
+		t.Fatalf("failed to set unmatched action: %v", err)
+	}
+
+	cancel, err := cluster2.nc.ConsumeByRouter("jobs", router, func(err error) {
+		t.Errorf("error in router: %v", err)
+	})
+	if err != nil {
+		t.Fatalf("failed to consume: %v", err)
+	}
+	defer cancel()
+
+	consumerInfo := func() (redelivered, outstanding int) {
+		for info := range cluster1.nc.js.Consumers("jobs") {
+			redelivered += info.NumRedelivered
+			outstanding += info.NumAckPending + int(info.NumPending)
+		}
+		return redelivered, outstanding
+	}
+	waitFor := func(what string, cond func() bool) {
+		deadline := time.Now().Add(10 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timeout waiting for %s", what)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	// An unrouted message is rejected and keeps coming back
+	if err := cluster1.nc.PublishPersistent("jobs.other", []byte("payload")); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	waitFor("the unrouted message to be redelivered", func() bool {
+		redelivered, _ := consumerInfo()
+		return redelivered > 0
+	})
+
+	// Until a default handler takes it
+	router.Default(func(subject string, msg []byte) error {
+		fallback <- subject
+		return nil
+	})
+	select {
+	case subject := <-fallback:
+		if subject != "jobs.other" {
+			t.Errorf("expected jobs.other, got %s", subject)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for the default handler")
+	}
+	if matched, err := router.Route("jobs.any", nil); !matched || err != nil {
+		t.Errorf("expected the default handler to match jobs.any, got %v (%v)", matched, err)
+	}
+	<-fallback
+
+	// Terminated messages are settled without reaching any handler
+	router.Default(nil)
+	if err := router.OnUnmatched(UnmatchedTerm); err != nil {
+		t.Fatalf("failed to set unmatched action: %v", err)
+	}
+	for _, subject := range []string{"jobs.dropped", "jobs.known"} {
+		if err := cluster1.nc.PublishPersistent(subject, []byte("payload")); err != nil {
+			t.Fatalf("failed to publish %s: %v", subject, err)
+		}
+	}
+	select {
+	case subject := <-known:
+		if subject != "jobs.known" {
+			t.Errorf("expected jobs.known, got %s", subject)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for jobs.known")
+	}
+	waitFor("every message to be settled", func() bool {
+		_, outstanding := consumerInfo()
+		return outstanding == 0
+	})
+
+	select {
+	case subject := <-fallback:
+		t.Errorf("unexpected delivery of %s to the default handler", subject)
+	default:
+	}
+}
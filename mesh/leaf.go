@@ -1,7 +1,9 @@
 package mesh
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/nats-io/nats-server/v2/server"
@@ -15,6 +17,10 @@ type LeafOptions struct {
 	username                 string
 	password                 string
 	leafRemotes              [][]string
+	remoteUsername           string
+	remotePassword           string
+	remoteToken              string
+	remoteTLS                *tls.Config
 	storeDir                 string
 	jetstreamEnabled         bool
 	jetstreamMaxMemory       size.Size
@@ -47,6 +53,29 @@ func (opt *LeafOptions) WithLeafRemotes(remotes ...[]string) *LeafOptions {
 	return opt
 }
 
+// WithLeafCredentials sets the user and password the leaf presents to the
+// hubs in its remotes, matching those given to ClusterOptions.WithLeafNode.
+func (opt *LeafOptions) WithLeafCredentials(username, password string) *LeafOptions {
+	opt.remoteUsername = username
+	opt.remotePassword = password
+	return opt
+}
+
+// WithLeafToken sets a token the leaf presents to the hubs in its remotes. A
+// hub accepts it when configured with the token as the leaf node username and
+// no password. Credentials set with WithLeafCredentials take precedence.
+func (opt *LeafOptions) WithLeafToken(token string) *LeafOptions {
+	opt.remoteToken = token
+	return opt
+}
+
+// WithLeafTLS connects to the hubs in the leaf's remotes over TLS using
+// config.
+func (opt *LeafOptions) WithLeafTLS(config *tls.Config) *LeafOptions {
+	opt.remoteTLS = config
+	return opt
+}
+
 func (opt *LeafOptions) WithStoreDir(dir string) *LeafOptions {
 	opt.storeDir = dir
 	return opt
@@ -82,7 +111,9 @@ func (opt *LeafOptions) toNATSConfig() *server.Options {
 	leafRemotes := make([]*server.RemoteLeafOpts, 0, len(opt.leafRemotes))
 	for _, r := range opt.leafRemotes {
 		leafRemotes = append(leafRemotes, &server.RemoteLeafOpts{
-			URLs: strsToURLs(r),
+			URLs:      opt.remoteURLs(r),
+			TLS:       opt.remoteTLS != nil,
+			TLSConfig: opt.remoteTLS,
 		})
 	}
 
@@ -123,6 +154,22 @@ func (opt *LeafOptions) toNATSConfig() *server.Options {
 	return config
 }
 
+// remoteURLs parses the URLs of a remote, adding the leaf's credentials or
+// token as user info in place of any the URLs carry.
+func (opt *LeafOptions) remoteURLs(remote []string) []*url.URL {
+	urls := strsToURLs(remote)
+	for _, u := range urls {
+		switch {
+		case opt.remoteUsername != "":
+			u.User = url.UserPassword(opt.remoteUsername, opt.remotePassword)
+		case opt.remoteToken != "":
+			u.User = url.User(opt.remoteToken)
+		}
+	}
+
+	return urls
+}
+
 type Leaf struct {
 	nc *conn
 }
@@ -144,3 +191,14 @@ func (l *Leaf) Close() {
 		l.nc.Close()
 	}
 }
+
+// Drain gracefully closes the leaf, letting in-flight publishes and
+// deliveries finish first. It returns ErrDrainTimeout if that takes longer
+// than timeout.
+func (l *Leaf) Drain(timeout time.Duration) error {
+	if l.nc == nil {
+		return nil
+	}
+
+	return l.nc.Drain(timeout)
+}
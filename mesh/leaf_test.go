@@ -1132,3 +1132,134 @@ func TestLeafNodesConnectedToCluster(t *testing.T) {
 		t.Log("✓ Remote clients can successfully operate through leaf nodes with full NATS functionality")
 	})
 }
+
+func TestClientReconnectHandlers(t *testing.T) {
+	cluster1, cluster2, cluster3 := SetupLeafTestThreeNodeCluster(t)
+	defer CleanupLeafTestClusters(cluster1, cluster2, cluster3)
+
+	leaf := SetupLeafNodeConnectedToCluster(t, cluster1, "leaf-reconnect", 4320)
+	defer func() { CleanupLeafNodes(leaf) }()
+
+	disconnected := make(chan error, 10)
+	reconnected := make(chan struct{}, 10)
+
+	clientOpts := NewClientOptions().
+		WithServers(fmt.Sprintf("nats://127.0.0.1:%d", 4320)).
+		WithMaxReconnects(-1).
+		WithReconnectWait(100 * time.Millisecond).
+		WithDisconnectHandler(func(err error) {
+			disconnected <- err
+		}).
+		WithReconnectHandler(func() {
+			reconnected <- struct{}{}
+		})
+	client, err := NewClient(clientOpts)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if status := client.Status(); status != ConnStatusConnected {
+		t.Fatalf("expected status %q, got %q", ConnStatusConnected, status)
+	}
+
+	// Kill the leaf the client is attached to
+	leaf.Close()
+
+	select {
+	case err := <-disconnected:
+		t.Logf("Disconnect handler fired: %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for disconnect handler")
+	}
+
+	if status := client.Status(); status != ConnStatusReconnecting {
+		t.Errorf("expected status %q while the leaf is down, got %q", ConnStatusReconnecting, status)
+	}
+
+	// Bring the leaf back on the same port
+	leaf = SetupLeafNodeConnectedToCluster(t, cluster1, "leaf-reconnect", 4320)
+
+	select {
+	case <-reconnected:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for reconnect handler")
+	}
+
+	if status := client.Status(); status != ConnStatusConnected {
+		t.Errorf("expected status %q after reconnecting, got %q", ConnStatusConnected, status)
+	}
+
+	client.Close()
+	if status := client.Status(); status != ConnStatusClosed {
+		t.Errorf("expected status %q after Close, got %q", ConnStatusClosed, status)
+	}
+}
+
+// Test function to verify leaf nodes authenticate to a hub that requires credentials
+func TestLeafRemoteAuth(t *testing.T) {
+	setupHub := func(t *testing.T, username, password string) *Cluster {
+		t.Helper()
+
+		opts := NewClusterOptions("auth-hub").
+			WithListen("127.0.0.1", 4330).
+			WithStoreDir(t.TempDir()).
+			WithJetStreamMaxMemory(size.NewSizeFromMegabytes(50)).
+			WithJetStreamMaxStore(size.NewSizeFromMegabytes(100)).
+			WithLeafNode("127.0.0.1", 7430, username, password)
+
+		hub, err := NewCluster(opts)
+		if err != nil {
+			t.Fatalf("failed to create hub: %v", err)
+		}
+		return hub
+	}
+
+	connectLeaf := func(t *testing.T, hub *Cluster, opts *LeafOptions) bool {
+		t.Helper()
+
+		leaf, err := NewLeaf(opts.
+			WithListen("127.0.0.1", 4331).
+			WithLeafRemotes([]string{"nats-leaf://127.0.0.1:7430"}))
+		if err != nil {
+			t.Fatalf("failed to create leaf: %v", err)
+		}
+		defer leaf.Close()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if hub.nc.server.NumLeafNodes() > 0 && leaf.nc.server.NumLeafNodes() > 0 {
+				return true
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		return false
+	}
+
+	t.Run("credentials", func(t *testing.T) {
+		hub := setupHub(t, "leaf", "s3cret")
+		defer hub.Close()
+
+		if connectLeaf(t, hub, NewLeafOptions("leaf-wrong").WithLeafCredentials("leaf", "wrong")) {
+			t.Error("expected leaf with wrong credentials to be rejected")
+		}
+		if connectLeaf(t, hub, NewLeafOptions("leaf-anonymous")) {
+			t.Error("expected leaf without credentials to be rejected")
+		}
+		if !connectLeaf(t, hub, NewLeafOptions("leaf-right").WithLeafCredentials("leaf", "s3cret")) {
+			t.Error("expected leaf with correct credentials to connect")
+		}
+	})
+
+	t.Run("token", func(t *testing.T) {
+		hub := setupHub(t, "t0ken", "")
+		defer hub.Close()
+
+		if connectLeaf(t, hub, NewLeafOptions("leaf-wrong").WithLeafToken("other")) {
+			t.Error("expected leaf with wrong token to be rejected")
+		}
+		if !connectLeaf(t, hub, NewLeafOptions("leaf-right").WithLeafToken("t0ken")) {
+			t.Error("expected leaf with correct token to connect")
+		}
+	})
+}
@@ -1,11 +1,13 @@
 package mesh
 
 import (
+	"context"
 	"errors"
 	"io"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/rivulet-io/tower/op"
 )
 
 // Ensure Leaf implements WrapConn interface
@@ -48,6 +50,22 @@ func (l *Leaf) PublishVolatileBatch(messages []struct {
 	return l.nc.PublishVolatileBatch(messages)
 }
 
+func (l *Leaf) Request(subject string, payload op.PrimitiveData, timeout time.Duration) (op.PrimitiveData, error) {
+	return l.nc.Request(subject, payload, timeout)
+}
+
+func (l *Leaf) RequestWithContext(ctx context.Context, subject string, payload op.PrimitiveData) (op.PrimitiveData, error) {
+	return l.nc.RequestWithContext(ctx, subject, payload)
+}
+
+func (l *Leaf) RespondTo(subject string, handler func(payload op.PrimitiveData) (op.PrimitiveData, error)) (cancel func(), err error) {
+	return l.nc.RespondTo(subject, handler)
+}
+
+func (l *Leaf) RespondToWithContext(ctx context.Context, subject string, handler func(ctx context.Context, payload op.PrimitiveData) (op.PrimitiveData, error)) (cancel func(), err error) {
+	return l.nc.RespondToWithContext(ctx, subject, handler)
+}
+
 func (l *Leaf) FlushTimeout(timeout time.Duration) error {
 	return l.nc.FlushTimeout(timeout)
 }
@@ -61,6 +79,14 @@ func (l *Leaf) SubscribeStreamViaDurable(subscriberID string, subject string, ha
 	return l.nc.SubscribeStreamViaDurable(subscriberID, subject, handler, errHandler, opt...)
 }
 
+func (l *Leaf) SubscribeStreamConcurrent(subscriberID string, subject string, concurrency int, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	return l.nc.SubscribeStreamConcurrent(subscriberID, subject, concurrency, handler, errHandler, opt...)
+}
+
+func (l *Leaf) SubscribeStreamViaDurableWithDeadLetter(subscriberID string, subject string, option DeadLetterOptions, handler func(subject string, msg []byte, delivered int) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	return l.nc.SubscribeStreamViaDurableWithDeadLetter(subscriberID, subject, option, handler, errHandler, opt...)
+}
+
 func (l *Leaf) PullPersistentViaDurable(subscriberID string, subject string, option PullOptions, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
 	return l.nc.PullPersistentViaDurable(subscriberID, subject, option, handler, errHandler, opt...)
 }
@@ -69,10 +95,22 @@ func (l *Leaf) SubscribePersistentViaEphemeral(subject string, handler func(subj
 	return l.nc.SubscribePersistentViaEphemeral(subject, handler, errHandler, opt...)
 }
 
+func (l *Leaf) SubscribeStreamFrom(subject string, start StreamStartPolicy, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	return l.nc.SubscribeStreamFrom(subject, start, handler, errHandler, opt...)
+}
+
 func (l *Leaf) PullPersistentViaEphemeral(subject string, option PullOptions, handler func(subject string, msg []byte) (response []byte, reply bool, ack bool), errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
 	return l.nc.PullPersistentViaEphemeral(subject, option, handler, errHandler, opt...)
 }
 
+func (l *Leaf) ConsumeByHandler(streamName string, routes map[string]func(subject string, msg []byte) error, errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	return l.nc.ConsumeByHandler(streamName, routes, errHandler, opt...)
+}
+
+func (l *Leaf) ConsumeByRouter(streamName string, router *SubjectRouter, errHandler func(error), opt ...nats.SubOpt) (cancel func(), err error) {
+	return l.nc.ConsumeByRouter(streamName, router, errHandler, opt...)
+}
+
 func (l *Leaf) PublishPersistent(subject string, msg []byte, opts ...nats.PubOpt) error {
 	return l.nc.PublishPersistent(subject, msg, opts...)
 }
@@ -81,6 +119,14 @@ func (l *Leaf) PublishPersistentWithOptions(subject string, msg []byte, opts ...
 	return l.nc.PublishPersistentWithOptions(subject, msg, opts...)
 }
 
+func (l *Leaf) PublishPersistentBatch(subject string, msgs [][]byte) ([]*nats.PubAck, error) {
+	return l.nc.PublishPersistentBatch(subject, msgs)
+}
+
+func (l *Leaf) PublishPersistentReliable(subject string, msg []byte, opts ReliableOptions) (*nats.PubAck, error) {
+	return l.nc.PublishPersistentReliable(subject, msg, opts)
+}
+
 func (l *Leaf) DeleteStream(streamName string) error {
 	return ErrOperationNotPermittedForLeaf
 }
@@ -89,11 +135,23 @@ func (l *Leaf) GetStreamInfo(streamName string) (*nats.StreamInfo, error) {
 	return l.nc.GetStreamInfo(streamName)
 }
 
+func (l *Leaf) StreamStats(streamName string) (StreamStats, error) {
+	return l.nc.StreamStats(streamName)
+}
+
+func (l *Leaf) ConsumerLag(streamName, durable string) (pending uint64, ackFloor uint64, err error) {
+	return l.nc.ConsumerLag(streamName, durable)
+}
+
 // KV Store operations - Read/Write allowed, Store management not allowed
 func (l *Leaf) CreateKeyValueStore(cluster string, config KeyValueStoreConfig) error {
 	return ErrOperationNotPermittedForLeaf
 }
 
+func (l *Leaf) CreateShardedKeyValueStore(cluster string, shards int, config KeyValueStoreConfig) error {
+	return ErrOperationNotPermittedForLeaf
+}
+
 func (l *Leaf) GetFromKeyValueStore(bucket, key string) ([]byte, uint64, error) {
 	return l.nc.GetFromKeyValueStore(bucket, key)
 }
@@ -106,10 +164,18 @@ func (l *Leaf) UpdateToKeyValueStore(bucket, key string, value []byte, expectedR
 	return l.nc.UpdateToKeyValueStore(bucket, key, value, expectedRevision)
 }
 
+func (l *Leaf) CreateKeyValue(bucket, key string, value []byte) (uint64, error) {
+	return l.nc.CreateKeyValue(bucket, key, value)
+}
+
 func (l *Leaf) DeleteFromKeyValueStore(bucket, key string) error {
 	return l.nc.DeleteFromKeyValueStore(bucket, key)
 }
 
+func (l *Leaf) DeleteKeyValueIfRevision(bucket, key string, expected uint64) error {
+	return l.nc.DeleteKeyValueIfRevision(bucket, key, expected)
+}
+
 func (l *Leaf) PurgeKeyValueStore(bucket, key string) error {
 	return l.nc.PurgeKeyValueStore(bucket, key)
 }
@@ -139,8 +205,8 @@ func (l *Leaf) CreateObjectStore(cluster string, config ObjectStoreConfig) error
 	return ErrOperationNotPermittedForLeaf
 }
 
-func (l *Leaf) GetFromObjectStore(bucket, key string) ([]byte, error) {
-	return l.nc.GetFromObjectStore(bucket, key)
+func (l *Leaf) GetFromObjectStore(bucket, key string, opt ...ObjectGetOptions) ([]byte, error) {
+	return l.nc.GetFromObjectStore(bucket, key, opt...)
 }
 
 func (l *Leaf) PutToObjectStore(bucket, key string, data []byte, metadata map[string]string) error {
@@ -159,7 +225,7 @@ func (l *Leaf) GetFromObjectStoreStream(bucket, key string) (io.ReadCloser, erro
 	return l.nc.GetFromObjectStoreStream(bucket, key)
 }
 
-func (l *Leaf) GetObjectInfo(bucket, key string) (*nats.ObjectInfo, error) {
+func (l *Leaf) GetObjectInfo(bucket, key string) (ObjectInfo, error) {
 	return l.nc.GetObjectInfo(bucket, key)
 }
 
@@ -183,6 +249,15 @@ func (l *Leaf) CopyObject(sourceBucket, sourceKey, destBucket, destKey string, m
 	return l.nc.CopyObject(sourceBucket, sourceKey, destBucket, destKey, metadata)
 }
 
+// Stream archive operations - Replay allowed, Archiving not allowed as it may purge the stream
+func (l *Leaf) ArchiveStream(streamName, objectBucket string, upTo uint64, opt ...ArchiveOptions) (archived uint64, err error) {
+	return 0, ErrOperationNotPermittedForLeaf
+}
+
+func (l *Leaf) ReplayArchive(objectBucket, archiveName, targetSubject string) (replayed uint64, err error) {
+	return l.nc.ReplayArchive(objectBucket, archiveName, targetSubject)
+}
+
 // Advisory operations
 func (l *Leaf) SubscribeLeaderChange(stream string, handler func(stream string, leader string, myName string), errHandler func(error)) (cancel func(), err error) {
 	return l.nc.SubscribeLeaderChange(stream, handler, errHandler)
@@ -0,0 +1,333 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	WorkQueueHeaderError       = "Tower-WorkQueue-Error"
+	WorkQueueHeaderSubject     = "Tower-WorkQueue-Subject"
+	WorkQueueHeaderDeliveries  = "Tower-WorkQueue-Deliveries"
+	WorkQueueHeaderOriginalSeq = "Tower-WorkQueue-Stream-Seq"
+)
+
+type WorkQueueConfig struct {
+	// Subject is the subject jobs are published to. Defaults to the queue name.
+	Subject string
+
+	// MaxDeliver is how many times a job is attempted before it is moved to
+	// the dead-letter subject. Defaults to 5.
+	MaxDeliver int
+
+	// AckWait is how long a handler may run before its context is cancelled,
+	// and how long a job fetched by a worker that died is held before it is
+	// redelivered. Defaults to 30 seconds.
+	AckWait time.Duration
+
+	// RetryDelay delays redelivery of a job whose handler returned an error.
+	// Zero redelivers immediately.
+	RetryDelay time.Duration
+
+	// DeadLetterSubject receives jobs that exhausted MaxDeliver. Defaults to
+	// "<name>.dead". It is stored in its own "<name>_DLQ" stream.
+	DeadLetterSubject string
+
+	// FetchWait is how long an idle worker waits for a job before polling
+	// again. Defaults to 1 second.
+	FetchWait time.Duration
+
+	MaxMsgs  int64
+	MaxBytes int64
+	MaxAge   time.Duration
+	Replicas int
+}
+
+type WorkQueue struct {
+	nc         *conn
+	name       string
+	subject    string
+	dlqSubject string
+	durable    string
+	maxDeliver int
+	ackWait    time.Duration
+	retryDelay time.Duration
+	fetchWait  time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	subs   []*nats.Subscription
+}
+
+// NewWorkQueue creates (or reuses) a work-queue stream named name together
+// with a durable pull consumer shared by every process that opens the same
+// queue, and a companion stream that keeps jobs which failed MaxDeliver times.
+func NewWorkQueue(nc WrapConn, name string, config WorkQueueConfig) (*WorkQueue, error) {
+	c, err := unwrapConn(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("work queue name cannot be empty")
+	}
+
+	if config.Subject == "" {
+		config.Subject = name
+	}
+	if config.MaxDeliver <= 0 {
+		config.MaxDeliver = 5
+	}
+	if config.AckWait <= 0 {
+		config.AckWait = 30 * time.Second
+	}
+	if config.DeadLetterSubject == "" {
+		config.DeadLetterSubject = name + ".dead"
+	}
+	if config.FetchWait <= 0 {
+		config.FetchWait = time.Second
+	}
+
+	if err := nc.CreateOrUpdateStream(&PersistentConfig{
+		Name:      name,
+		Subjects:  []string{config.Subject},
+		Retention: nats.WorkQueuePolicy,
+		MaxMsgs:   config.MaxMsgs,
+		MaxBytes:  config.MaxBytes,
+		MaxAge:    config.MaxAge,
+		Replicas:  config.Replicas,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create work queue stream %q: %w", name, err)
+	}
+
+	if err := nc.CreateOrUpdateStream(&PersistentConfig{
+		Name:     name + "_DLQ",
+		Subjects: []string{config.DeadLetterSubject},
+		Replicas: config.Replicas,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter stream for work queue %q: %w", name, err)
+	}
+
+	// MaxDeliver is enforced by the workers rather than the consumer, so a
+	// job whose dead-lettering fails is redelivered instead of stranded
+	durable := name + "_workers"
+	consumerConfig := &nats.ConsumerConfig{
+		Durable:       durable,
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       config.AckWait,
+		MaxDeliver:    -1,
+		FilterSubject: config.Subject,
+	}
+	if _, err := c.js.AddConsumer(name, consumerConfig); err != nil {
+		if !errors.Is(err, nats.ErrConsumerNameAlreadyInUse) {
+			return nil, fmt.Errorf("failed to create consumer for work queue %q: %w", name, err)
+		}
+		if _, err := c.js.UpdateConsumer(name, consumerConfig); err != nil {
+			return nil, fmt.Errorf("failed to update consumer for work queue %q: %w", name, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WorkQueue{
+		nc:         c,
+		name:       name,
+		subject:    config.Subject,
+		dlqSubject: config.DeadLetterSubject,
+		durable:    durable,
+		maxDeliver: config.MaxDeliver,
+		ackWait:    config.AckWait,
+		retryDelay: config.RetryDelay,
+		fetchWait:  config.FetchWait,
+		ctx:        ctx,
+		cancel:     cancel,
+	}, nil
+}
+
+func (q *WorkQueue) Enqueue(payload []byte) error {
+	if err := q.nc.PublishPersistent(q.subject, payload); err != nil {
+		return fmt.Errorf("failed to enqueue job to work queue %q: %w", q.name, err)
+	}
+
+	return nil
+}
+
+// Process starts concurrency workers that pull jobs and run handler on them.
+// A job is acknowledged when handler returns nil, retried when it returns an
+// error, and moved to the dead-letter subject once it has been attempted
+// MaxDeliver times. The handler's context is cancelled after AckWait or by
+// Close, and the job is held for it until it returns. Workers run until Close
+// is called. errHandler may be nil.
+func (q *WorkQueue) Process(concurrency int, handler func(ctx context.Context, payload []byte) error, errHandler func(error)) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if errHandler == nil {
+		errHandler = func(error) {}
+	}
+
+	sub, err := q.nc.js.PullSubscribe(q.subject, q.durable, nats.Bind(q.name, q.durable))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to work queue %q: %w", q.name, err)
+	}
+
+	q.mu.Lock()
+	q.subs = append(q.subs, sub)
+	q.mu.Unlock()
+
+	for i := 0; i < concurrency; i++ {
+		q.wg.Add(1)
+		go q.work(sub, handler, errHandler)
+	}
+
+	return nil
+}
+
+func (q *WorkQueue) work(sub *nats.Subscription, handler func(ctx context.Context, payload []byte) error, errHandler func(error)) {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		default:
+		}
+
+		msgs, err := sub.Fetch(1, nats.MaxWait(q.fetchWait))
+		if err != nil {
+			if errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+				continue
+			}
+			if q.ctx.Err() != nil {
+				return
+			}
+			errHandler(fmt.Errorf("failed to fetch jobs from work queue %q: %w", q.name, err))
+			time.Sleep(q.fetchWait)
+			continue
+		}
+
+		for _, msg := range msgs {
+			q.handle(msg, handler, errHandler)
+		}
+	}
+}
+
+// run calls handler on the job with a context that expires after AckWait.
+// Until handler returns the server is told the job is still in progress, so
+// it isn't redelivered to another worker while handler winds down.
+func (q *WorkQueue) run(msg *nats.Msg, handler func(ctx context.Context, payload []byte) error, errHandler func(error)) (err error) {
+	ctx, cancel := context.WithTimeout(q.ctx, q.ackWait)
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(q.ackWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := msg.InProgress(); err != nil {
+					errHandler(fmt.Errorf("failed to extend job on work queue %q: %w", q.name, err))
+				}
+			}
+		}
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic on work queue %q: %v", q.name, r)
+		}
+	}()
+	return handler(ctx, msg.Data)
+}
+
+func (q *WorkQueue) handle(msg *nats.Msg, handler func(ctx context.Context, payload []byte) error, errHandler func(error)) {
+	herr := q.run(msg, handler, errHandler)
+
+	if herr == nil {
+		if err := msg.Ack(); err != nil {
+			errHandler(fmt.Errorf("failed to acknowledge job on work queue %q: %w", q.name, err))
+		}
+		return
+	}
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		errHandler(fmt.Errorf("failed to read job metadata on work queue %q: %w", q.name, err))
+		return
+	}
+
+	if int(meta.NumDelivered) < q.maxDeliver {
+		if err := msg.NakWithDelay(q.retryDelay); err != nil {
+			errHandler(fmt.Errorf("failed to reject job on work queue %q: %w", q.name, err))
+		}
+		return
+	}
+
+	dead := nats.NewMsg(q.dlqSubject)
+	dead.Data = msg.Data
+	dead.Header.Set(WorkQueueHeaderError, herr.Error())
+	dead.Header.Set(WorkQueueHeaderSubject, msg.Subject)
+	dead.Header.Set(WorkQueueHeaderDeliveries, fmt.Sprintf("%d", meta.NumDelivered))
+	dead.Header.Set(WorkQueueHeaderOriginalSeq, fmt.Sprintf("%d", meta.Sequence.Stream))
+	if _, err := q.nc.js.PublishMsg(dead); err != nil {
+		errHandler(fmt.Errorf("failed to dead-letter job on work queue %q: %w", q.name, err))
+		// Leave the job to be redelivered and tried again
+		if err := msg.NakWithDelay(q.retryDelay); err != nil {
+			errHandler(fmt.Errorf("failed to reject job on work queue %q: %w", q.name, err))
+		}
+		return
+	}
+
+	if err := msg.Term(); err != nil {
+		errHandler(fmt.Errorf("failed to terminate job on work queue %q: %w", q.name, err))
+	}
+}
+
+// Close stops fetching new jobs, waits for in-flight handlers to finish and
+// releases the subscriptions. The durable consumer and pending jobs are kept.
+func (q *WorkQueue) Close() error {
+	q.cancel()
+	q.wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var errs []error
+	for _, sub := range q.subs {
+		if err := sub.Unsubscribe(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+			errs = append(errs, err)
+		}
+	}
+	q.subs = nil
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close work queue %q: %w", q.name, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// unwrapConn returns the underlying connection of a Cluster, Leaf or Client.
+func unwrapConn(nc WrapConn) (*conn, error) {
+	switch v := nc.(type) {
+	case *Cluster:
+		return v.nc, nil
+	case *Leaf:
+		return v.nc, nil
+	case *Client:
+		return v.nc, nil
+	default:
+		return nil, fmt.Errorf("unsupported connection type %T", nc)
+	}
+}
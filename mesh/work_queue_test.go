@@ -0,0 +1,244 @@
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkQueueProcess(t *testing.T) {
+	t.Run("process all jobs", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		queue, err := NewWorkQueue(cluster1, "jobs", WorkQueueConfig{
+			Replicas: 1,
+		})
+		if err != nil {
+			t.Fatalf("failed to create work queue: %v", err)
+		}
+
+		const jobCount = 20
+		for i := 0; i < jobCount; i++ {
+			if err := queue.Enqueue([]byte(fmt.Sprintf("job-%d", i))); err != nil {
+				t.Fatalf("failed to enqueue job %d: %v", i, err)
+			}
+		}
+
+		var processed atomic.Int32
+		err = queue.Process(4, func(ctx context.Context, payload []byte) error {
+			processed.Add(1)
+			return nil
+		}, func(err error) {
+			t.Logf("work queue error: %v", err)
+		})
+		if err != nil {
+			t.Fatalf("failed to start processing: %v", err)
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+		for processed.Load() < jobCount && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		if err := queue.Close(); err != nil {
+			t.Fatalf("failed to close work queue: %v", err)
+		}
+
+		if processed.Load() != jobCount {
+			t.Fatalf("expected %d processed jobs, got %d", jobCount, processed.Load())
+		}
+
+		info, err := cluster1.nc.GetStreamInfo("jobs")
+		if err != nil {
+			t.Fatalf("failed to get stream info: %v", err)
+		}
+		if info.State.Msgs != 0 {
+			t.Errorf("expected work queue to be drained, %d messages left", info.State.Msgs)
+		}
+	})
+
+	t.Run("dead letter after max deliver", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		queue, err := NewWorkQueue(cluster1, "failing", WorkQueueConfig{
+			MaxDeliver: 2,
+			Replicas:   1,
+		})
+		if err != nil {
+			t.Fatalf("failed to create work queue: %v", err)
+		}
+
+		if err := queue.Enqueue([]byte("poison")); err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+
+		var attempts atomic.Int32
+		err = queue.Process(1, func(ctx context.Context, payload []byte) error {
+			attempts.Add(1)
+			return fmt.Errorf("cannot process %s", payload)
+		}, func(err error) {
+			t.Logf("work queue error: %v", err)
+		})
+		if err != nil {
+			t.Fatalf("failed to start processing: %v", err)
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+		var deadLetters uint64
+		for time.Now().Before(deadline) {
+			info, err := cluster1.nc.GetStreamInfo("failing_DLQ")
+			if err == nil && info.State.Msgs > 0 {
+				deadLetters = info.State.Msgs
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		if err := queue.Close(); err != nil {
+			t.Fatalf("failed to close work queue: %v", err)
+		}
+
+		if deadLetters != 1 {
+			t.Fatalf("expected 1 dead-lettered job, got %d", deadLetters)
+		}
+		if attempts.Load() != 2 {
+			t.Errorf("expected 2 attempts before dead-lettering, got %d", attempts.Load())
+		}
+	})
+
+	t.Run("handler overrunning ack wait", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		queue, err := NewWorkQueue(cluster1, "slow", WorkQueueConfig{
+			AckWait:  time.Second,
+			Replicas: 1,
+		})
+		if err != nil {
+			t.Fatalf("failed to create work queue: %v", err)
+		}
+
+		if err := queue.Enqueue([]byte("slow")); err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+
+		var attempts, cancelled atomic.Int32
+		done := make(chan struct{})
+		err = queue.Process(2, func(ctx context.Context, payload []byte) error {
+			if attempts.Add(1) > 1 {
+				return nil
+			}
+			<-ctx.Done()
+			cancelled.Add(1)
+
+			// Keep running past the deadline; the job must not go elsewhere
+			time.Sleep(2 * time.Second)
+			close(done)
+			return nil
+		}, nil)
+		if err != nil {
+			t.Fatalf("failed to start processing: %v", err)
+		}
+
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the slow handler")
+		}
+		time.Sleep(500 * time.Millisecond)
+
+		if err := queue.Close(); err != nil {
+			t.Fatalf("failed to close work queue: %v", err)
+		}
+
+		if cancelled.Load() != 1 {
+			t.Errorf("expected the handler's context to expire after ack wait")
+		}
+		if attempts.Load() != 1 {
+			t.Errorf("expected the job not to be redelivered while its handler runs, got %d attempts", attempts.Load())
+		}
+
+		info, err := cluster1.nc.GetStreamInfo("slow")
+		if err != nil {
+			t.Fatalf("failed to get stream info: %v", err)
+		}
+		if info.State.Msgs != 0 {
+			t.Errorf("expected the job to be acknowledged, %d messages left", info.State.Msgs)
+		}
+	})
+
+	t.Run("dead letter retried after failed publish", func(t *testing.T) {
+		cluster1, cluster2, cluster3 := SetupThreeNodeCluster(t)
+		defer CleanupClusters(cluster1, cluster2, cluster3)
+
+		queue, err := NewWorkQueue(cluster1, "stranded", WorkQueueConfig{
+			MaxDeliver: 1,
+			RetryDelay: 100 * time.Millisecond,
+			Replicas:   1,
+		})
+		if err != nil {
+			t.Fatalf("failed to create work queue: %v", err)
+		}
+
+		// Without its stream the dead-letter publish fails
+		if err := cluster1.nc.DeleteStream("stranded_DLQ"); err != nil {
+			t.Fatalf("failed to delete dead-letter stream: %v", err)
+		}
+
+		if err := queue.Enqueue([]byte("poison")); err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+
+		failures := make(chan struct{}, 1)
+		err = queue.Process(1, func(ctx context.Context, payload []byte) error {
+			return fmt.Errorf("cannot process %s", payload)
+		}, func(err error) {
+			select {
+			case failures <- struct{}{}:
+			default:
+			}
+		})
+		if err != nil {
+			t.Fatalf("failed to start processing: %v", err)
+		}
+		defer queue.Close()
+
+		select {
+		case <-failures:
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the dead-letter publish to fail")
+		}
+
+		if err := cluster1.nc.CreateOrUpdateStream(&PersistentConfig{
+			Name:     "stranded_DLQ",
+			Subjects: []string{"stranded.dead"},
+			Replicas: 1,
+		}); err != nil {
+			t.Fatalf("failed to recreate dead-letter stream: %v", err)
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			info, err := cluster1.nc.GetStreamInfo("stranded_DLQ")
+			if err == nil && info.State.Msgs == 1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("expected the job to be dead-lettered once the stream is back")
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		info, err := cluster1.nc.GetStreamInfo("stranded")
+		if err != nil {
+			t.Fatalf("failed to get stream info: %v", err)
+		}
+		if info.State.Msgs != 0 {
+			t.Errorf("expected the job to leave the work queue, %d messages left", info.State.Msgs)
+		}
+	})
+}
@@ -15,6 +15,8 @@ import (
 
 type DataType uint8
 
+// DataType values are written to disk, so new types are only ever appended.
+// Frames store the type in a byte of its own, which leaves room for 256.
 const (
 	TypeNull DataType = iota
 	TypeInt
@@ -39,62 +41,342 @@ const (
 	TypeTimeseries
 	TypeBloomFilter
 	TypeShamirShare
+	TypeSortedSet
+	TypeHyperLogLog
+	TypeCountMinSketch
+	TypeGeo
 )
 
 type DataFrameError struct {
 	Op   string
 	Type DataType
 	Msg  string
+	Err  error // sentinel such as ErrTypeMismatch, described when Msg is empty
 }
 
 func (e *DataFrameError) Error() string {
-	return fmt.Sprintf("dataframe %s error for type %v: %s", e.Op, e.Type, e.Msg)
+	msg := e.Msg
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+	return fmt.Sprintf("dataframe %s error for type %v: %s", e.Op, e.Type, msg)
+}
+
+// Unwrap lets errors.Is match type mismatches against ErrTypeMismatch.
+func (e *DataFrameError) Unwrap() error {
+	return e.Err
+}
+
+// dataFrameVersioned opens every frame written today. It is followed by a
+// flags byte and the type byte. Legacy frames packed the flags into the high
+// bits of the type byte, leaving only five bits for the type; none of them
+// had all five set, so the marker can't be mistaken for a legacy type byte.
+const dataFrameVersioned = 0x1F
+
+// dataFrameHeaderSize is the length of the marker, flags and type bytes.
+const dataFrameHeaderSize = 3
+
+// dataFrameTimestampFlag is set when the header carries created/modified
+// timestamps after the expiration field.
+const dataFrameTimestampFlag = 0x80
+
+// dataFrameCompactFlag is set on frames written by MarshalCompact. The
+// compactHas* flags share the flags byte and are followed by the varint
+// encoded fields that are present and the payload. Legacy compact frames
+// kept the compactHas* flags in the byte after the type byte.
+const dataFrameCompactFlag = 0x40
+
+const (
+	compactHasExpiry     = 1 << 0
+	compactHasTimestamps = 1 << 1
+)
+
+// parseFrameHeader returns the type and flags of a marshaled frame and the
+// offset of the fields that follow them.
+func parseFrameHeader(data []byte) (DataType, byte, int, error) {
+	if len(data) == 0 {
+		return TypeNull, 0, 0, fmt.Errorf("data too short to read DataFrame header")
+	}
+	if data[0] != dataFrameVersioned {
+		return DataType(data[0] &^ dataFrameFlags), data[0] & dataFrameFlags, 1, nil
+	}
+	if len(data) < dataFrameHeaderSize {
+		return TypeNull, 0, 0, fmt.Errorf("data too short to read DataFrame header")
+	}
+	return DataType(data[2]), data[1], dataFrameHeaderSize, nil
 }
 
 type DataFrame struct {
-	typ       DataType
-	payload   []byte
-	expiresAt time.Time // zero value means no expiration
+	typ        DataType
+	payload    []byte
+	expiresAt  time.Time // zero value means no expiration
+	createdAt  time.Time // zero value means not tracked
+	modifiedAt time.Time // zero value means not tracked
 }
 
 func (df *DataFrame) Marshal() ([]byte, error) {
+	return df.marshal(nil, nil, "")
+}
+
+// marshal is Marshal with the payload encoded by codec, if any, and sealed
+// with keys, if any, for storage under key.
+func (df *DataFrame) marshal(codec Codec, keys *payloadKeyring, key string) ([]byte, error) {
 	if df == nil {
 		return nil, fmt.Errorf("cannot marshal nil DataFrame")
 	}
 
-	buf := make([]byte, 1+8+len(df.payload))
-	cursor := 0
-	buf[cursor] = byte(df.typ)
-	cursor++
+	tracked := !df.createdAt.IsZero() || !df.modifiedAt.IsZero()
+	payload, encoded := compressPayload(df.payload, codec)
+	sealed := keys != nil && keys.seal != nil
+
+	headerSize := dataFrameHeaderSize + 8
+	if tracked {
+		headerSize += 8 + 8
+	}
+
+	buf := make([]byte, headerSize, headerSize+len(payload))
+	buf[0] = dataFrameVersioned
+	if tracked {
+		buf[1] |= dataFrameTimestampFlag
+	}
+	if encoded || sealed {
+		buf[1] |= dataFrameCodecFlag
+	}
+	buf[2] = byte(df.typ)
+	cursor := dataFrameHeaderSize
 	binary.BigEndian.PutUint64(buf[cursor:], uint64(df.expiresAt.UnixMilli()))
 	cursor += 8
-	copy(buf[cursor:], df.payload)
+	if tracked {
+		binary.BigEndian.PutUint64(buf[cursor:], uint64(df.createdAt.UnixNano()))
+		cursor += 8
+		binary.BigEndian.PutUint64(buf[cursor:], uint64(df.modifiedAt.UnixNano()))
+	}
 
-	return buf, nil
+	return appendPayload(buf, payload, encoded, keys, key)
+}
+
+// appendPayload appends payload to the frame header in buf, sealing it
+// first if there is a sealing key.
+func appendPayload(buf, payload []byte, encoded bool, keys *payloadKeyring, key string) ([]byte, error) {
+	if keys == nil || keys.seal == nil {
+		return append(buf, payload...), nil
+	}
+
+	sealed, err := sealPayload(payload, encoded, keys, frameAAD(buf, key))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(buf, sealed...), nil
+}
+
+// MarshalCompact encodes df like Marshal but leaves out an absent expiration
+// and writes the expiration, timestamps and string length as varints.
+func (df *DataFrame) MarshalCompact() ([]byte, error) {
+	return df.marshalCompact(nil, nil, "")
+}
+
+// marshalCompact is MarshalCompact with the payload encoded by codec, if
+// any, and sealed with keys, if any, for storage under key. An encoded string
+// payload keeps its length prefix inside and carries no varint length.
+func (df *DataFrame) marshalCompact(codec Codec, keys *payloadKeyring, key string) ([]byte, error) {
+	if df == nil {
+		return nil, fmt.Errorf("cannot marshal nil DataFrame")
+	}
+
+	payload, encoded := compressPayload(df.payload, codec)
+	sealed := keys != nil && keys.seal != nil
+	stringLength := df.typ == TypeString && !encoded && !sealed
+	if stringLength {
+		if len(payload) < 4 {
+			return nil, &DataFrameError{Op: "MarshalCompact", Type: df.typ, Msg: "payload too short"}
+		}
+		payload = payload[4:]
+	}
+
+	buf := make([]byte, dataFrameHeaderSize, dataFrameHeaderSize+3*binary.MaxVarintLen64+len(payload))
+	buf[0] = dataFrameVersioned
+	buf[1] = dataFrameCompactFlag
+	if encoded || sealed {
+		buf[1] |= dataFrameCodecFlag
+	}
+	buf[2] = byte(df.typ)
+
+	if !df.expiresAt.IsZero() {
+		buf[1] |= compactHasExpiry
+		buf = binary.AppendUvarint(buf, uint64(df.expiresAt.UnixMilli()))
+	}
+	if !df.createdAt.IsZero() || !df.modifiedAt.IsZero() {
+		buf[1] |= compactHasTimestamps
+		buf = binary.AppendVarint(buf, df.createdAt.UnixNano())
+		buf = binary.AppendVarint(buf, df.modifiedAt.UnixNano())
+	}
+	if stringLength {
+		buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	}
+
+	return appendPayload(buf, payload, encoded, keys, key)
+}
+
+func unmarshalCompactDataFrame(data []byte, keys *payloadKeyring, key string) (*DataFrame, error) {
+	typ, flags, cursor, err := parseFrameHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	has := flags
+	if data[0] != dataFrameVersioned {
+		if len(data) < 2 {
+			return nil, fmt.Errorf("data too short to unmarshal compact DataFrame")
+		}
+		has = data[1]
+		cursor++
+	}
+
+	df := &DataFrame{
+		typ: typ,
+	}
+
+	if has&compactHasExpiry != 0 {
+		ms, n := binary.Uvarint(data[cursor:])
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid expiration in compact DataFrame")
+		}
+		df.expiresAt = time.UnixMilli(int64(ms))
+		cursor += n
+	}
+	if has&compactHasTimestamps != 0 {
+		created, n := binary.Varint(data[cursor:])
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid created time in compact DataFrame")
+		}
+		cursor += n
+		modified, n := binary.Varint(data[cursor:])
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid modified time in compact DataFrame")
+		}
+		cursor += n
+		df.createdAt = time.Unix(0, created)
+		df.modifiedAt = time.Unix(0, modified)
+	}
+
+	if flags&dataFrameCodecFlag != 0 {
+		payload, err := decodePayload(data[cursor:], keys, frameAAD(data[:cursor], key))
+		if err != nil {
+			return nil, err
+		}
+		df.payload = payload
+	} else if df.typ == TypeString {
+		length, n := binary.Uvarint(data[cursor:])
+		if n <= 0 || length != uint64(len(data)-cursor-n) {
+			return nil, &DataFrameError{Op: "UnmarshalCompact", Type: df.typ, Msg: "invalid payload length"}
+		}
+		cursor += n
+		// Keep the in-memory layout used by SetString
+		df.payload = make([]byte, 4+length)
+		binary.BigEndian.PutUint32(df.payload[:4], uint32(length))
+		copy(df.payload[4:], data[cursor:])
+	} else {
+		df.payload = make([]byte, len(data)-cursor)
+		copy(df.payload, data[cursor:])
+	}
+
+	if !df.expiresAt.IsZero() && Now().After(df.expiresAt) {
+		return df, NewDataframeExpiredError("unknown", df.expiresAt)
+	}
+
+	return df, nil
 }
 
+// UnmarshalDataFrame decodes frames written by either Marshal or
+// MarshalCompact.
 func UnmarshalDataFrame(data []byte) (*DataFrame, error) {
-	if len(data) < 1 {
+	return unmarshalDataFrame(data, nil, "")
+}
+
+// unmarshalDataFrame is UnmarshalDataFrame opening sealed payloads stored
+// under key with keys.
+func unmarshalDataFrame(data []byte, keys *payloadKeyring, key string) (*DataFrame, error) {
+	typ, flags, cursor, err := parseFrameHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if flags&dataFrameCompactFlag != 0 {
+		return unmarshalCompactDataFrame(data, keys, key)
+	}
+
+	if len(data) < cursor+8 {
 		return nil, fmt.Errorf("data too short to unmarshal DataFrame")
 	}
 
-	expirtesAt := time.UnixMilli(int64(binary.BigEndian.Uint64(data[1:9])))
+	expirtesAt := time.UnixMilli(int64(binary.BigEndian.Uint64(data[cursor : cursor+8])))
 
 	df := &DataFrame{
-		typ:       DataType(data[0]),
+		typ:       typ,
 		expiresAt: expirtesAt,
 	}
 
+	cursor += 8
+	if flags&dataFrameTimestampFlag != 0 {
+		if len(data) < cursor+16 {
+			return nil, fmt.Errorf("data too short to unmarshal DataFrame timestamps")
+		}
+		df.createdAt = time.Unix(0, int64(binary.BigEndian.Uint64(data[cursor:cursor+8])))
+		df.modifiedAt = time.Unix(0, int64(binary.BigEndian.Uint64(data[cursor+8:cursor+16])))
+		cursor += 16
+	}
+
+	if flags&dataFrameCodecFlag != 0 {
+		payload, err := decodePayload(data[cursor:], keys, frameAAD(data[:cursor], key))
+		if err != nil {
+			return nil, err
+		}
+		df.payload = payload
+	} else {
+		df.payload = make([]byte, len(data)-cursor)
+		copy(df.payload, data[cursor:])
+	}
+
+	// The frame is returned with the error so callers can clean up after it
 	if !expirtesAt.IsZero() && Now().After(expirtesAt) {
 		return df, NewDataframeExpiredError("unknown", expirtesAt)
 	}
 
-	payload := make([]byte, len(data)-9)
-	copy(payload, data[9:])
+	return df, nil
+}
 
-	df.payload = payload
+// peekDataFrame decodes only the header and expiration of a marshaled
+// frame, leaving the payload untouched.
+func peekDataFrame(data []byte) (DataType, time.Time, error) {
+	typ, flags, cursor, err := parseFrameHeader(data)
+	if err != nil {
+		return TypeNull, time.Time{}, err
+	}
 
-	return df, nil
+	if flags&dataFrameCompactFlag != 0 {
+		has := flags
+		if data[0] != dataFrameVersioned {
+			if len(data) < 2 {
+				return typ, time.Time{}, fmt.Errorf("data too short to peek compact DataFrame")
+			}
+			has = data[1]
+			cursor++
+		}
+		if has&compactHasExpiry == 0 {
+			return typ, time.Time{}, nil
+		}
+		ms, n := binary.Uvarint(data[cursor:])
+		if n <= 0 {
+			return typ, time.Time{}, fmt.Errorf("invalid expiration in compact DataFrame")
+		}
+		return typ, time.UnixMilli(int64(ms)), nil
+	}
+
+	if len(data) < cursor+8 {
+		return typ, time.Time{}, fmt.Errorf("data too short to peek DataFrame")
+	}
+
+	return typ, time.UnixMilli(int64(binary.BigEndian.Uint64(data[cursor : cursor+8]))), nil
 }
 
 func NULLDataFrame() *DataFrame {
@@ -129,6 +411,14 @@ func (df *DataFrame) ClearExpiration() {
 	df.expiresAt = time.Time{}
 }
 
+func (df *DataFrame) CreatedAt() time.Time {
+	return df.createdAt
+}
+
+func (df *DataFrame) ModifiedAt() time.Time {
+	return df.modifiedAt
+}
+
 func (df *DataFrame) SetInt(v int64) error {
 	buf := [8]byte{}
 	binary.BigEndian.PutUint64(buf[:], uint64(v))
@@ -139,7 +429,7 @@ func (df *DataFrame) SetInt(v int64) error {
 
 func (df *DataFrame) Int() (int64, error) {
 	if df.typ != TypeInt {
-		return 0, &DataFrameError{Op: "Int", Type: df.typ, Msg: "type mismatch"}
+		return 0, &DataFrameError{Op: "Int", Type: df.typ, Err: ErrTypeMismatch}
 	}
 	if len(df.payload) != 8 {
 		return 0, &DataFrameError{Op: "Int", Type: df.typ, Msg: "invalid payload length"}
@@ -158,7 +448,7 @@ func (df *DataFrame) SetFloat(v float64) error {
 
 func (df *DataFrame) Float() (float64, error) {
 	if df.typ != TypeFloat {
-		return 0, &DataFrameError{Op: "Float", Type: df.typ, Msg: "type mismatch"}
+		return 0, &DataFrameError{Op: "Float", Type: df.typ, Err: ErrTypeMismatch}
 	}
 	if len(df.payload) != 8 {
 		return 0, &DataFrameError{Op: "Float", Type: df.typ, Msg: "invalid payload length"}
@@ -180,7 +470,7 @@ func (df *DataFrame) SetString(v string) error {
 
 func (df *DataFrame) String() (string, error) {
 	if df.typ != TypeString {
-		return "", &DataFrameError{Op: "String", Type: df.typ, Msg: "type mismatch"}
+		return "", &DataFrameError{Op: "String", Type: df.typ, Err: ErrTypeMismatch}
 	}
 	if len(df.payload) < 4 {
 		return "", &DataFrameError{Op: "String", Type: df.typ, Msg: "payload too short"}
@@ -204,7 +494,7 @@ func (df *DataFrame) SetBool(v bool) error {
 
 func (df *DataFrame) Bool() (bool, error) {
 	if df.typ != TypeBool {
-		return false, &DataFrameError{Op: "Bool", Type: df.typ, Msg: "type mismatch"}
+		return false, &DataFrameError{Op: "Bool", Type: df.typ, Err: ErrTypeMismatch}
 	}
 	if len(df.payload) != 1 {
 		return false, &DataFrameError{Op: "Bool", Type: df.typ, Msg: "invalid payload length"}
@@ -222,7 +512,7 @@ func (df *DataFrame) SetTimestamp(v time.Time) error {
 
 func (df *DataFrame) Timestamp() (time.Time, error) {
 	if df.typ != TypeTimestamp {
-		return time.Time{}, &DataFrameError{Op: "Timestamp", Type: df.typ, Msg: "type mismatch"}
+		return time.Time{}, &DataFrameError{Op: "Timestamp", Type: df.typ, Err: ErrTypeMismatch}
 	}
 	if len(df.payload) != 8 {
 		return time.Time{}, &DataFrameError{Op: "Timestamp", Type: df.typ, Msg: "invalid payload length"}
@@ -241,7 +531,7 @@ func (df *DataFrame) SetDuration(v time.Duration) error {
 
 func (df *DataFrame) Duration() (time.Duration, error) {
 	if df.typ != TypeDuration {
-		return 0, &DataFrameError{Op: "Duration", Type: df.typ, Msg: "type mismatch"}
+		return 0, &DataFrameError{Op: "Duration", Type: df.typ, Err: ErrTypeMismatch}
 	}
 	if len(df.payload) != 8 {
 		return 0, &DataFrameError{Op: "Duration", Type: df.typ, Msg: "invalid payload length"}
@@ -259,7 +549,26 @@ func (df *DataFrame) SetBinary(v []byte) error {
 
 func (df *DataFrame) Binary() ([]byte, error) {
 	if df.typ != TypeBinary {
-		return nil, &DataFrameError{Op: "Binary", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "Binary", Type: df.typ, Err: ErrTypeMismatch}
+	}
+	data := make([]byte, len(df.payload))
+	copy(data, df.payload)
+	return data, nil
+}
+
+func (df *DataFrame) SetJSON(v []byte) error {
+	if !json.Valid(v) {
+		return &DataFrameError{Op: "SetJSON", Type: TypeJSON, Msg: "invalid JSON document"}
+	}
+	df.typ = TypeJSON
+	df.payload = make([]byte, len(v))
+	copy(df.payload, v)
+	return nil
+}
+
+func (df *DataFrame) JSON() ([]byte, error) {
+	if df.typ != TypeJSON {
+		return nil, &DataFrameError{Op: "JSON", Type: df.typ, Err: ErrTypeMismatch}
 	}
 	data := make([]byte, len(df.payload))
 	copy(data, df.payload)
@@ -275,7 +584,7 @@ func (df *DataFrame) SetUUID(v *uuid.UUID) error {
 
 func (df *DataFrame) UUID() (*uuid.UUID, error) {
 	if df.typ != TypeUUID {
-		return nil, &DataFrameError{Op: "UUID", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "UUID", Type: df.typ, Err: ErrTypeMismatch}
 	}
 	if len(df.payload) != 16 {
 		return nil, &DataFrameError{Op: "UUID", Type: df.typ, Msg: "invalid payload length"}
@@ -293,7 +602,7 @@ func (df *DataFrame) SetTime(v time.Time) error {
 
 func (df *DataFrame) Time() (time.Time, error) {
 	if df.typ != TypeTime {
-		return time.Time{}, &DataFrameError{Op: "Time", Type: df.typ, Msg: "type mismatch"}
+		return time.Time{}, &DataFrameError{Op: "Time", Type: df.typ, Err: ErrTypeMismatch}
 	}
 	t, err := time.Parse(time.RFC3339Nano, string(df.payload))
 	if err != nil {
@@ -372,7 +681,7 @@ func (df *DataFrame) SetBigInt(value *big.Int) error {
 
 func (df *DataFrame) BigInt() (*big.Int, error) {
 	if df.typ != TypeBigInt {
-		return nil, &DataFrameError{Op: "BigInt", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "BigInt", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	data, err := UnmarshalDataFrameBigIntData(df.payload)
@@ -488,7 +797,7 @@ func (df *DataFrame) SetDecimal(coefficient *big.Int, scale int32) error {
 
 func (df *DataFrame) Decimal() (coefficient *big.Int, scale int32, err error) {
 	if df.typ != TypeDecimal {
-		return nil, 0, &DataFrameError{Op: "Decimal", Type: df.typ, Msg: "type mismatch"}
+		return nil, 0, &DataFrameError{Op: "Decimal", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	data, err := UnmarshalDataFrameDecimalData(df.payload)
@@ -615,7 +924,7 @@ func (df *DataFrame) SetShamirShare(shares map[byte][]byte) error {
 
 func (df *DataFrame) ShamirShare() (map[byte][]byte, error) {
 	if df.typ != TypeShamirShare {
-		return nil, &DataFrameError{Op: "ShamirShare", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "ShamirShare", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	data, err := UnmarshalDataFrameShamirShareData(df.payload)
@@ -656,7 +965,7 @@ func (df *DataFrame) SetRoaringBitmap(v *roaring.Bitmap) error {
 
 func (df *DataFrame) RoaringBitmap() (*roaring.Bitmap, error) {
 	if df.typ != TypeRoaringBitmap {
-		return nil, &DataFrameError{Op: "RoaringBitmap", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "RoaringBitmap", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	bitmap := roaring.New()
@@ -688,7 +997,7 @@ func (df *DataFrame) SetRoaringBitmap64(v *roaring64.Bitmap) error {
 
 func (df *DataFrame) RoaringBitmap64() (*roaring64.Bitmap, error) {
 	if df.typ != TypeRoaringBitmap64 {
-		return nil, &DataFrameError{Op: "RoaringBitmap64", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "RoaringBitmap64", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	bitmap := roaring64.New()
@@ -741,7 +1050,7 @@ func (df *DataFrame) SetPasswordWithOptions(algo PasswordAlgorithm, hash []byte,
 
 func (df *DataFrame) Password() (algo PasswordAlgorithm, hash []byte, salt []byte, opts *PasswordOptions, err error) {
 	if df.typ != TypePassword {
-		return 0, nil, nil, nil, &DataFrameError{Op: "Password", Type: df.typ, Msg: "type mismatch"}
+		return 0, nil, nil, nil, &DataFrameError{Op: "Password", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	value := &PasswordData{}
@@ -796,7 +1105,7 @@ func (df *DataFrame) SetSafeBox(algorithm EncryptionAlgorithm, encryptedData []b
 
 func (df *DataFrame) SafeBox() (algorithm EncryptionAlgorithm, encryptedData []byte, nonce []byte, err error) {
 	if df.typ != TypeSafeBox {
-		return 0, nil, nil, &DataFrameError{Op: "SafeBox", Type: df.typ, Msg: "type mismatch"}
+		return 0, nil, nil, &DataFrameError{Op: "SafeBox", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	if len(df.payload) < 16 {
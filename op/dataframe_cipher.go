@@ -0,0 +1,121 @@
+package op
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrEncryptionKeyRequired is returned when an encrypted value is read by an
+// operator without Options.EncryptionKey.
+var ErrEncryptionKeyRequired = errors.New("value is encrypted but no encryption key is configured")
+
+// ErrDecryptionFailed is returned when an encrypted value can't be opened
+// with any configured key, usually because the key is wrong.
+var ErrDecryptionFailed = errors.New("failed to decrypt value")
+
+// codecIDEncryptedBound takes the place of a codec ID on payloads sealed
+// with the encryption key. It is followed by the nonce and the AES-GCM sealed
+// payload, which starts with codecIDNone or the ID of the codec that encoded
+// it. The frame header and the stored key are authenticated along with it, so
+// a sealed value can't be given another type or expiry, or be moved to
+// another key. codecIDEncrypted marks payloads sealed before that, which are
+// opened without them until RotateEncryptionKey rewrites them.
+const (
+	codecIDNone           byte = 0
+	codecIDEncryptedBound byte = maxBuiltinCodecID - 1
+	codecIDEncrypted      byte = maxBuiltinCodecID
+)
+
+// encryptionKeySize is the key length required for AES-256.
+const encryptionKeySize = 32
+
+// payloadKeyring seals payloads with one key and opens them with any of
+// several, so values written under the previous key stay readable while
+// RotateEncryptionKey rewrites them.
+type payloadKeyring struct {
+	seal cipher.AEAD
+	open []cipher.AEAD
+}
+
+func newPayloadCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", encryptionKeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// frameAAD returns the additional data a sealed payload is bound to: the
+// frame header in front of it and the stored key.
+func frameAAD(header []byte, key string) []byte {
+	aad := make([]byte, 0, len(header)+len(key))
+	return append(append(aad, header...), key...)
+}
+
+// sealPayload encrypts payload under a random nonce, authenticating aad with
+// it. encoded reports whether payload already starts with a codec ID.
+func sealPayload(payload []byte, encoded bool, keys *payloadKeyring, aad []byte) ([]byte, error) {
+	aead := keys.seal
+	plain := payload
+	if !encoded {
+		plain = append([]byte{codecIDNone}, payload...)
+	}
+
+	nonceSize := aead.NonceSize()
+	buf := make([]byte, 1+nonceSize, 1+nonceSize+len(plain)+aead.Overhead())
+	buf[0] = codecIDEncryptedBound
+	nonce := buf[1 : 1+nonceSize]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(buf, nonce, plain, aad), nil
+}
+
+func openPayload(data []byte, keys *payloadKeyring, aad []byte) ([]byte, error) {
+	if keys == nil || len(keys.open) == 0 {
+		return nil, ErrEncryptionKeyRequired
+	}
+	if data[0] == codecIDEncrypted {
+		aad = nil
+	}
+
+	var plain []byte
+	opened := false
+	for _, aead := range keys.open {
+		nonceSize := aead.NonceSize()
+		if len(data) < 1+nonceSize+aead.Overhead() {
+			return nil, fmt.Errorf("%w: payload too short", ErrDecryptionFailed)
+		}
+
+		var err error
+		if plain, err = aead.Open(nil, data[1:1+nonceSize], data[1+nonceSize:], aad); err == nil {
+			opened = true
+			break
+		}
+	}
+	if !opened {
+		return nil, ErrDecryptionFailed
+	}
+
+	if len(plain) == 0 {
+		return nil, fmt.Errorf("%w: sealed payload is missing its codec ID", ErrDecryptionFailed)
+	}
+
+	switch plain[0] {
+	case codecIDNone:
+		return plain[1:], nil
+	case codecIDEncrypted, codecIDEncryptedBound:
+		return nil, fmt.Errorf("%w: payload is sealed twice", ErrDecryptionFailed)
+	}
+
+	return decodePayload(plain, nil, nil)
+}
@@ -0,0 +1,158 @@
+package op
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec compresses DataFrame payloads. Its ID is written in front of every
+// payload it encodes, so values stay readable after Options.Codec is changed
+// or removed. IDs up to maxBuiltinCodecID are reserved for the built-in
+// codecs.
+type Codec interface {
+	ID() byte
+	Encode(src []byte) []byte
+	Decode(src []byte) ([]byte, error)
+}
+
+const (
+	codecIDSnappy     byte = 1
+	codecIDZstd       byte = 2
+	maxBuiltinCodecID byte = 15
+)
+
+// dataFrameCodecFlag is set when the payload was encoded by a codec. The
+// payload then starts with the codec ID.
+const dataFrameCodecFlag = 0x20
+
+// dataFrameFlags masks the flags out of a legacy type byte.
+const dataFrameFlags = dataFrameTimestampFlag | dataFrameCompactFlag | dataFrameCodecFlag
+
+// codecMinPayload is the smallest payload worth handing to a codec.
+const codecMinPayload = 128
+
+// SnappyCodec compresses payloads with Snappy, trading ratio for speed.
+type SnappyCodec struct{}
+
+func (SnappyCodec) ID() byte { return codecIDSnappy }
+
+func (SnappyCodec) Encode(src []byte) []byte {
+	return snappy.Encode(nil, src)
+}
+
+func (SnappyCodec) Decode(src []byte) ([]byte, error) {
+	return snappy.Decode(nil, src)
+}
+
+// ZstdCodec compresses payloads with Zstandard at its default level.
+type ZstdCodec struct{}
+
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil)
+		return enc
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil)
+		return dec
+	})
+)
+
+func (ZstdCodec) ID() byte { return codecIDZstd }
+
+func (ZstdCodec) Encode(src []byte) []byte {
+	return zstdEncoder().EncodeAll(src, nil)
+}
+
+func (ZstdCodec) Decode(src []byte) ([]byte, error) {
+	return zstdDecoder().DecodeAll(src, nil)
+}
+
+// customCodecs holds the codecs of open operators by ID, so frames they
+// wrote can be decoded wherever they are read.
+var customCodecs sync.Map
+
+func registerCodec(codec Codec) error {
+	id := codec.ID()
+	if id == 0 {
+		return fmt.Errorf("codec ID 0 is invalid")
+	}
+
+	if id <= maxBuiltinCodecID {
+		builtin, ok := builtinCodec(id)
+		if !ok || reflect.TypeOf(builtin) != reflect.TypeOf(codec) {
+			return fmt.Errorf("codec ID %d is reserved for built-in codecs", id)
+		}
+		return nil
+	}
+
+	if existing, loaded := customCodecs.LoadOrStore(id, codec); loaded && reflect.TypeOf(existing) != reflect.TypeOf(codec) {
+		return fmt.Errorf("codec ID %d is already used by %T", id, existing)
+	}
+
+	return nil
+}
+
+func builtinCodec(id byte) (Codec, bool) {
+	switch id {
+	case codecIDSnappy:
+		return SnappyCodec{}, true
+	case codecIDZstd:
+		return ZstdCodec{}, true
+	}
+	return nil, false
+}
+
+func lookupCodec(id byte) (Codec, bool) {
+	if codec, ok := builtinCodec(id); ok {
+		return codec, true
+	}
+	if codec, ok := customCodecs.Load(id); ok {
+		return codec.(Codec), true
+	}
+	return nil, false
+}
+
+// compressPayload returns payload encoded by codec behind its ID, or payload
+// itself if there is no codec, it is too small, or encoding doesn't shrink
+// it.
+func compressPayload(payload []byte, codec Codec) ([]byte, bool) {
+	if codec == nil || len(payload) < codecMinPayload {
+		return payload, false
+	}
+
+	compressed := codec.Encode(payload)
+	if len(compressed)+1 >= len(payload) {
+		return payload, false
+	}
+
+	return append([]byte{codec.ID()}, compressed...), true
+}
+
+// decodePayload reverses compressPayload and sealPayload. aad is the frame
+// header and stored key a sealed payload is bound to.
+func decodePayload(data []byte, keys *payloadKeyring, aad []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("encoded payload is missing its codec ID")
+	}
+
+	if data[0] == codecIDEncrypted || data[0] == codecIDEncryptedBound {
+		return openPayload(data, keys, aad)
+	}
+
+	codec, ok := lookupCodec(data[0])
+	if !ok {
+		return nil, fmt.Errorf("unknown codec ID %d", data[0])
+	}
+
+	payload, err := codec.Decode(data[1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode payload with codec %d: %w", data[0], err)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,127 @@
+package op
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+
+	"github.com/rivulet-io/tower/util/size"
+)
+
+// collidingCodec claims the Snappy codec's ID.
+type collidingCodec struct{}
+
+func (collidingCodec) ID() byte { return codecIDSnappy }
+
+func (collidingCodec) Encode(src []byte) []byte { return src }
+
+func (collidingCodec) Decode(src []byte) ([]byte, error) { return src, nil }
+
+func TestCodec(t *testing.T) {
+	open := func(t *testing.T, fs vfs.FS, codec Codec, compact bool) *Operator {
+		t.Helper()
+		tower, err := NewOperator(&Options{
+			Path:            "codec.db",
+			CacheSize:       size.NewSizeFromMegabytes(8),
+			MemTableSize:    size.NewSizeFromMegabytes(16),
+			FS:              fs,
+			Codec:           codec,
+			CompactEncoding: compact,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create tower: %v", err)
+		}
+		return tower
+	}
+
+	rawSize := func(t *testing.T, tower *Operator, key string) int {
+		t.Helper()
+		data, closer, err := tower.db.Get([]byte(key))
+		if err != nil {
+			t.Fatalf("Failed to read raw value: %v", err)
+		}
+		defer closer.Close()
+		return len(data)
+	}
+
+	blob := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog\n", 1<<20/44))
+	text := strings.Repeat("lorem ipsum dolor sit amet ", 200)
+
+	for _, tc := range []struct {
+		name    string
+		codec   Codec
+		compact bool
+	}{
+		{"snappy", SnappyCodec{}, false},
+		{"zstd", ZstdCodec{}, false},
+		{"zstd compact", ZstdCodec{}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tower := open(t, InMemory(), tc.codec, tc.compact)
+			defer tower.Close()
+
+			if err := tower.SetBinary("blob", blob); err != nil {
+				t.Fatalf("Failed to set binary: %v", err)
+			}
+			if err := tower.SetString("text", text); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+			if err := tower.SetString("short", "tiny"); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+
+			if n := rawSize(t, tower, "blob"); n > len(blob)/4 {
+				t.Errorf("Expected stored blob to shrink below %d bytes, got %d", len(blob)/4, n)
+			}
+			if n := rawSize(t, tower, "text"); n >= len(text) {
+				t.Errorf("Expected stored text to shrink below %d bytes, got %d", len(text), n)
+			}
+
+			got, err := tower.GetBinary("blob")
+			if err != nil {
+				t.Fatalf("Failed to get binary: %v", err)
+			}
+			if !bytes.Equal(got, blob) {
+				t.Error("Expected blob to read back unchanged")
+			}
+			if s, err := tower.GetString("text"); err != nil || s != text {
+				t.Errorf("Expected text to read back unchanged (%v)", err)
+			}
+			if s, err := tower.GetString("short"); err != nil || s != "tiny" {
+				t.Errorf("Expected tiny, got %q (%v)", s, err)
+			}
+			if typ, err := tower.TypeOf("blob"); err != nil || typ != TypeBinary {
+				t.Errorf("Expected TypeBinary, got %v (%v)", typ, err)
+			}
+		})
+	}
+
+	t.Run("reconfigured", func(t *testing.T) {
+		fs := InMemory()
+		tower := open(t, fs, ZstdCodec{}, false)
+		if err := tower.SetBinary("blob", blob); err != nil {
+			t.Fatalf("Failed to set binary: %v", err)
+		}
+		tower.Close()
+
+		for _, codec := range []Codec{nil, SnappyCodec{}} {
+			tower := open(t, fs, codec, true)
+			got, err := tower.GetBinary("blob")
+			if err != nil {
+				t.Fatalf("Failed to get binary with codec %T: %v", codec, err)
+			}
+			if !bytes.Equal(got, blob) {
+				t.Errorf("Expected blob to read back unchanged with codec %T", codec)
+			}
+			tower.Close()
+		}
+	})
+
+	t.Run("reserved id", func(t *testing.T) {
+		if _, err := NewOperator(&Options{Path: "codec.db", FS: InMemory(), Codec: collidingCodec{}}); err == nil {
+			t.Error("Expected error for a custom codec using a built-in ID")
+		}
+	})
+}
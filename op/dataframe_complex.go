@@ -3,6 +3,7 @@ package op
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -56,7 +57,7 @@ func (df *DataFrame) SetList(data *ListData) error {
 
 func (df *DataFrame) List() (*ListData, error) {
 	if df.typ != TypeList {
-		return nil, &DataFrameError{Op: "List", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "List", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	value, err := UnmarshalDataFrameListData(df.payload)
@@ -131,7 +132,7 @@ func (df *DataFrame) SetSet(data *SetData) error {
 
 func (df *DataFrame) Set() (*SetData, error) {
 	if df.typ != TypeSet {
-		return nil, &DataFrameError{Op: "Set", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "Set", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	value, err := UnmarshalDataFrameSetData(df.payload)
@@ -162,6 +163,174 @@ func MakeSetItemKey(prefix string, member string) []byte {
 	return buf
 }
 
+type SortedSetData struct {
+	Prefix string
+	Count  uint64
+}
+
+func (zd *SortedSetData) Marshal() ([]byte, error) {
+	buf := make([]byte, 8+len(zd.Prefix))
+	binary.BigEndian.PutUint64(buf[0:8], zd.Count)
+	copy(buf[8:], []byte(zd.Prefix))
+	return buf, nil
+}
+
+func UnmarshalDataFrameSortedSetData(data []byte) (*SortedSetData, error) {
+	if len(data) < 8 {
+		return nil, &DataFrameError{Op: "UnmarshalDataFrameSortedSetData", Type: TypeSortedSet, Msg: "data too short"}
+	}
+	zd := &SortedSetData{}
+	zd.Count = binary.BigEndian.Uint64(data[0:8])
+	zd.Prefix = string(data[8:])
+	return zd, nil
+}
+
+func (df *DataFrame) SetSortedSet(data *SortedSetData) error {
+	if data == nil {
+		return &DataFrameError{
+			Op:   "SetSortedSet",
+			Type: TypeSortedSet,
+			Msg:  "data cannot be nil",
+		}
+	}
+
+	buf, err := data.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal sorted set data: %w", err)
+	}
+
+	df.typ = TypeSortedSet
+	df.payload = buf
+
+	return nil
+}
+
+func (df *DataFrame) SortedSet() (*SortedSetData, error) {
+	if df.typ != TypeSortedSet {
+		return nil, &DataFrameError{Op: "SortedSet", Type: df.typ, Err: ErrTypeMismatch}
+	}
+
+	value, err := UnmarshalDataFrameSortedSetData(df.payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sorted set data: %w", err)
+	}
+
+	return value, nil
+}
+
+// Geo sets share the layout of sorted sets, scoring each member by the
+// geohash of its position.
+func (df *DataFrame) SetGeo(data *SortedSetData) error {
+	if data == nil {
+		return &DataFrameError{
+			Op:   "SetGeo",
+			Type: TypeGeo,
+			Msg:  "data cannot be nil",
+		}
+	}
+
+	buf, err := data.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal geo data: %w", err)
+	}
+
+	df.typ = TypeGeo
+	df.payload = buf
+
+	return nil
+}
+
+func (df *DataFrame) Geo() (*SortedSetData, error) {
+	if df.typ != TypeGeo {
+		return nil, &DataFrameError{Op: "Geo", Type: df.typ, Err: ErrTypeMismatch}
+	}
+
+	value, err := UnmarshalDataFrameSortedSetData(df.payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal geo data: %w", err)
+	}
+
+	return value, nil
+}
+
+// scoredSetData returns the metadata of a sorted set or geo set.
+func (df *DataFrame) scoredSetData() (*SortedSetData, error) {
+	if df.typ == TypeGeo {
+		return df.Geo()
+	}
+	return df.SortedSet()
+}
+
+// setScoredSetData updates the metadata of a sorted set or geo set without
+// changing which of the two df holds.
+func (df *DataFrame) setScoredSetData(data *SortedSetData) error {
+	if df.typ == TypeGeo {
+		return df.SetGeo(data)
+	}
+	return df.SetSortedSet(data)
+}
+
+const SortedSetTypeMarker = "{:zset:}"
+
+// Sorted set items live in two namespaces under the entry key: member keys
+// hold each member's score, score keys order the members by score.
+const (
+	sortedSetMemberSpace = 'm'
+	sortedSetScoreSpace  = 's'
+)
+
+func MakeSortedSetEntryKey(prefix string) []byte {
+	buf := make([]byte, len(prefix)+len(SortedSetTypeMarker)+1)
+	copy(buf, []byte(prefix))
+	buf[len(prefix)] = ':'
+	copy(buf[len(prefix)+1:], []byte(SortedSetTypeMarker))
+	return buf
+}
+
+func MakeSortedSetMemberKey(prefix string, member string) []byte {
+	buf := make([]byte, len(prefix)+len(SortedSetTypeMarker)+len(member)+4)
+	copy(buf, []byte(prefix))
+	buf[len(prefix)] = ':'
+	copy(buf[len(prefix)+1:], []byte(SortedSetTypeMarker))
+	buf[len(prefix)+1+len(SortedSetTypeMarker)] = ':'
+	buf[len(prefix)+1+len(SortedSetTypeMarker)+1] = sortedSetMemberSpace
+	buf[len(prefix)+1+len(SortedSetTypeMarker)+2] = ':'
+	copy(buf[len(prefix)+1+len(SortedSetTypeMarker)+3:], []byte(member))
+	return buf
+}
+
+// MakeSortedSetScoreKey encodes score so that keys sort in score order,
+// with members of equal score ordered by their encoding.
+func MakeSortedSetScoreKey(prefix string, score float64, member string) []byte {
+	buf := make([]byte, len(prefix)+len(SortedSetTypeMarker)+8+len(member)+4)
+	copy(buf, []byte(prefix))
+	buf[len(prefix)] = ':'
+	copy(buf[len(prefix)+1:], []byte(SortedSetTypeMarker))
+	buf[len(prefix)+1+len(SortedSetTypeMarker)] = ':'
+	buf[len(prefix)+1+len(SortedSetTypeMarker)+1] = sortedSetScoreSpace
+	buf[len(prefix)+1+len(SortedSetTypeMarker)+2] = ':'
+	binary.BigEndian.PutUint64(buf[len(prefix)+1+len(SortedSetTypeMarker)+3:], sortableScore(score))
+	copy(buf[len(prefix)+1+len(SortedSetTypeMarker)+3+8:], []byte(member))
+	return buf
+}
+
+// sortableScore maps score to an integer with the same ordering by flipping
+// the sign bit of positive values and every bit of negative ones.
+func sortableScore(score float64) uint64 {
+	bits := math.Float64bits(score)
+	if bits&(1<<63) != 0 {
+		return ^bits
+	}
+	return bits | 1<<63
+}
+
+func scoreFromSortable(bits uint64) float64 {
+	if bits&(1<<63) != 0 {
+		return math.Float64frombits(bits &^ (1 << 63))
+	}
+	return math.Float64frombits(^bits)
+}
+
 type MapData struct {
 	Prefix string
 	Count  uint64
@@ -207,7 +376,7 @@ func (df *DataFrame) SetMap(data *MapData) error {
 
 func (df *DataFrame) Map() (*MapData, error) {
 	if df.typ != TypeMap {
-		return nil, &DataFrameError{Op: "Map", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "Map", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	value, err := UnmarshalDataFrameMapData(df.payload)
@@ -279,7 +448,7 @@ func (df *DataFrame) SetTimeseries(data *TimeseriesData) error {
 
 func (df *DataFrame) Timeseries() (*TimeseriesData, error) {
 	if df.typ != TypeTimeseries {
-		return nil, &DataFrameError{Op: "Timeseries", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "Timeseries", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	value, err := UnmarshalDataFrameTimeseriesData(df.payload)
@@ -315,9 +484,18 @@ type BloomFilterData struct {
 	Slots  int
 	Salt   string
 	Count  uint64
+	// Hashes and Bits are set instead of Slots for filters sized from an
+	// expected item count, which keep their bit array in the payload rather
+	// than in item keys.
+	Hashes int
+	Bits   []byte
 }
 
 func (bfd *BloomFilterData) Marshal() ([]byte, error) {
+	if bfd.Slots == 0 {
+		return bfd.marshalBits()
+	}
+
 	buf := make([]byte, 4+len(bfd.Salt)+1+8+len(bfd.Prefix))
 	binary.BigEndian.PutUint32(buf[0:4], uint32(bfd.Slots))
 	copy(buf[4:], []byte(bfd.Salt))
@@ -338,10 +516,48 @@ func UnmarshalDataFrameBloomFilterData(data []byte) (*BloomFilterData, error) {
 		return nil, &DataFrameError{Op: "UnmarshalDataFrameBloomFilterData", Type: TypeBloomFilter, Msg: "invalid separator"}
 	}
 	bfd.Count = binary.BigEndian.Uint64(data[4+len("bloom_salt_2025")+1 : 4+len("bloom_salt_2025")+1+8])
+	if bfd.Slots == 0 {
+		return unmarshalBloomFilterBits(bfd, data[4+len("bloom_salt_2025")+1+8:])
+	}
 	bfd.Prefix = string(data[4+len("bloom_salt_2025")+1+8:])
 	return bfd, nil
 }
 
+// marshalBits encodes a bit array filter. It shares the header of the slot
+// format with Slots set to zero, followed by the hash count, the
+// length-prefixed prefix and the bit array.
+func (bfd *BloomFilterData) marshalBits() ([]byte, error) {
+	if bfd.Hashes <= 0 || len(bfd.Bits) == 0 {
+		return nil, &DataFrameError{Op: "MarshalBloomFilterData", Type: TypeBloomFilter, Msg: "bit array filter needs hashes and bits"}
+	}
+
+	header := 4 + len(bfd.Salt) + 1 + 8
+	buf := make([]byte, header+4+4+len(bfd.Prefix)+len(bfd.Bits))
+	copy(buf[4:], []byte(bfd.Salt))
+	buf[4+len(bfd.Salt)] = ':'
+	binary.BigEndian.PutUint64(buf[4+len(bfd.Salt)+1:], bfd.Count)
+	binary.BigEndian.PutUint32(buf[header:], uint32(bfd.Hashes))
+	binary.BigEndian.PutUint32(buf[header+4:], uint32(len(bfd.Prefix)))
+	copy(buf[header+8:], []byte(bfd.Prefix))
+	copy(buf[header+8+len(bfd.Prefix):], bfd.Bits)
+	return buf, nil
+}
+
+func unmarshalBloomFilterBits(bfd *BloomFilterData, data []byte) (*BloomFilterData, error) {
+	if len(data) < 8 {
+		return nil, &DataFrameError{Op: "UnmarshalDataFrameBloomFilterData", Type: TypeBloomFilter, Msg: "data too short"}
+	}
+	bfd.Hashes = int(binary.BigEndian.Uint32(data[0:4]))
+	prefixLen := int(binary.BigEndian.Uint32(data[4:8]))
+	if len(data) < 8+prefixLen {
+		return nil, &DataFrameError{Op: "UnmarshalDataFrameBloomFilterData", Type: TypeBloomFilter, Msg: "invalid prefix length"}
+	}
+	bfd.Prefix = string(data[8 : 8+prefixLen])
+	bfd.Bits = make([]byte, len(data)-8-prefixLen)
+	copy(bfd.Bits, data[8+prefixLen:])
+	return bfd, nil
+}
+
 func (df *DataFrame) SetBloomFilter(data *BloomFilterData) error {
 	if data == nil {
 		return &DataFrameError{
@@ -364,7 +580,7 @@ func (df *DataFrame) SetBloomFilter(data *BloomFilterData) error {
 
 func (df *DataFrame) BloomFilter() (*BloomFilterData, error) {
 	if df.typ != TypeBloomFilter {
-		return nil, &DataFrameError{Op: "BloomFilter", Type: df.typ, Msg: "type mismatch"}
+		return nil, &DataFrameError{Op: "BloomFilter", Type: df.typ, Err: ErrTypeMismatch}
 	}
 
 	value, err := UnmarshalDataFrameBloomFilterData(df.payload)
@@ -394,3 +610,143 @@ func MakeBloomFilterItemKey(prefix string, item string) []byte {
 	copy(buf[len(prefix)+1+len(BloomFilterTypeMarker)+1:], []byte(item))
 	return buf
 }
+
+// HyperLogLogData holds the registers of a HyperLogLog estimator: one byte
+// per register, 1<<Precision registers in total.
+type HyperLogLogData struct {
+	Precision uint8
+	Registers []byte
+}
+
+func (hd *HyperLogLogData) Marshal() ([]byte, error) {
+	if hd.Precision < hllMinPrecision || hd.Precision > hllMaxPrecision || len(hd.Registers) != 1<<hd.Precision {
+		return nil, &DataFrameError{Op: "MarshalHyperLogLogData", Type: TypeHyperLogLog, Msg: "invalid register count"}
+	}
+
+	buf := make([]byte, 1+len(hd.Registers))
+	buf[0] = hd.Precision
+	copy(buf[1:], hd.Registers)
+	return buf, nil
+}
+
+func UnmarshalDataFrameHyperLogLogData(data []byte) (*HyperLogLogData, error) {
+	if len(data) < 1 {
+		return nil, &DataFrameError{Op: "UnmarshalDataFrameHyperLogLogData", Type: TypeHyperLogLog, Msg: "data too short"}
+	}
+	hd := &HyperLogLogData{Precision: data[0]}
+	if hd.Precision < hllMinPrecision || hd.Precision > hllMaxPrecision || len(data)-1 != 1<<hd.Precision {
+		return nil, &DataFrameError{Op: "UnmarshalDataFrameHyperLogLogData", Type: TypeHyperLogLog, Msg: "invalid register count"}
+	}
+	hd.Registers = make([]byte, len(data)-1)
+	copy(hd.Registers, data[1:])
+	return hd, nil
+}
+
+func (df *DataFrame) SetHyperLogLog(data *HyperLogLogData) error {
+	if data == nil {
+		return &DataFrameError{
+			Op:   "SetHyperLogLog",
+			Type: TypeHyperLogLog,
+			Msg:  "data cannot be nil",
+		}
+	}
+
+	buf, err := data.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal hyperloglog data: %w", err)
+	}
+
+	df.typ = TypeHyperLogLog
+	df.payload = buf
+
+	return nil
+}
+
+func (df *DataFrame) HyperLogLog() (*HyperLogLogData, error) {
+	if df.typ != TypeHyperLogLog {
+		return nil, &DataFrameError{Op: "HyperLogLog", Type: df.typ, Err: ErrTypeMismatch}
+	}
+
+	value, err := UnmarshalDataFrameHyperLogLogData(df.payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal hyperloglog data: %w", err)
+	}
+
+	return value, nil
+}
+
+// CountMinSketchData holds a count-min sketch: Depth rows of Width counters,
+// stored row after row, and the total of all increments.
+type CountMinSketchData struct {
+	Width    uint32
+	Depth    uint32
+	Total    uint64
+	Counters []uint64
+}
+
+func (cd *CountMinSketchData) Marshal() ([]byte, error) {
+	if cd.Width == 0 || cd.Depth == 0 || len(cd.Counters) != int(cd.Width)*int(cd.Depth) {
+		return nil, &DataFrameError{Op: "MarshalCountMinSketchData", Type: TypeCountMinSketch, Msg: "counter count does not match width and depth"}
+	}
+
+	buf := make([]byte, 4+4+8+8*len(cd.Counters))
+	binary.BigEndian.PutUint32(buf[0:4], cd.Width)
+	binary.BigEndian.PutUint32(buf[4:8], cd.Depth)
+	binary.BigEndian.PutUint64(buf[8:16], cd.Total)
+	for i, c := range cd.Counters {
+		binary.BigEndian.PutUint64(buf[16+i*8:], c)
+	}
+	return buf, nil
+}
+
+func UnmarshalDataFrameCountMinSketchData(data []byte) (*CountMinSketchData, error) {
+	if len(data) < 16 {
+		return nil, &DataFrameError{Op: "UnmarshalDataFrameCountMinSketchData", Type: TypeCountMinSketch, Msg: "data too short"}
+	}
+	cd := &CountMinSketchData{}
+	cd.Width = binary.BigEndian.Uint32(data[0:4])
+	cd.Depth = binary.BigEndian.Uint32(data[4:8])
+	cd.Total = binary.BigEndian.Uint64(data[8:16])
+	cells := uint64(cd.Width) * uint64(cd.Depth)
+	if cells == 0 || uint64(len(data)-16) != cells*8 {
+		return nil, &DataFrameError{Op: "UnmarshalDataFrameCountMinSketchData", Type: TypeCountMinSketch, Msg: "invalid counter count"}
+	}
+	cd.Counters = make([]uint64, cells)
+	for i := range cd.Counters {
+		cd.Counters[i] = binary.BigEndian.Uint64(data[16+i*8:])
+	}
+	return cd, nil
+}
+
+func (df *DataFrame) SetCountMinSketch(data *CountMinSketchData) error {
+	if data == nil {
+		return &DataFrameError{
+			Op:   "SetCountMinSketch",
+			Type: TypeCountMinSketch,
+			Msg:  "data cannot be nil",
+		}
+	}
+
+	buf, err := data.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal count-min sketch data: %w", err)
+	}
+
+	df.typ = TypeCountMinSketch
+	df.payload = buf
+
+	return nil
+}
+
+func (df *DataFrame) CountMinSketch() (*CountMinSketchData, error) {
+	if df.typ != TypeCountMinSketch {
+		return nil, &DataFrameError{Op: "CountMinSketch", Type: df.typ, Err: ErrTypeMismatch}
+	}
+
+	value, err := UnmarshalDataFrameCountMinSketchData(df.payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal count-min sketch data: %w", err)
+	}
+
+	return value, nil
+}
@@ -3,8 +3,34 @@ package op
 import (
 	"errors"
 	"time"
+
+	"github.com/cockroachdb/pebble"
 )
 
+// ErrKeyNotFound is returned when an operation needs a key that was never
+// written, was removed, or has expired.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrTypeMismatch is returned when a key holds a value of a different type
+// than the operation works on.
+var ErrTypeMismatch = errors.New("type mismatch")
+
+// ErrContainerExists is returned when creating a list, set, sorted set, map,
+// time series or bloom filter under a key that already holds a value.
+var ErrContainerExists = errors.New("container already exists")
+
+// ErrItemExists is returned when renaming a map field or set member onto one
+// that already exists without overwriting it.
+var ErrItemExists = errors.New("item already exists")
+
+// keyNotFound replaces Pebble's not found error with ErrKeyNotFound.
+func keyNotFound(err error) error {
+	if errors.Is(err, pebble.ErrNotFound) {
+		return ErrKeyNotFound
+	}
+	return err
+}
+
 type DataframeExpiredError struct {
 	id        string
 	expiredAt time.Time
@@ -14,6 +40,12 @@ func (e *DataframeExpiredError) Error() string {
 	return "dataframe with id " + e.id + " expired at " + e.expiredAt.String()
 }
 
+// Is reports an expired value as ErrKeyNotFound, since it is gone as far as
+// callers are concerned.
+func (e *DataframeExpiredError) Is(target error) bool {
+	return target == ErrKeyNotFound
+}
+
 func IsDataframeExpiredError(err error) *DataframeExpiredError {
 	var de *DataframeExpiredError
 	if errors.As(err, &de) {
@@ -0,0 +1,523 @@
+package op
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/RoaringBitmap/roaring/v2/roaring64"
+	"github.com/google/uuid"
+)
+
+var dataTypeNames = map[DataType]string{
+	TypeNull:            "null",
+	TypeInt:             "int",
+	TypeFloat:           "float",
+	TypeDecimal:         "decimal",
+	TypeBigInt:          "bigint",
+	TypeString:          "string",
+	TypeBool:            "bool",
+	TypeTimestamp:       "timestamp",
+	TypeTime:            "time",
+	TypeDuration:        "duration",
+	TypeBinary:          "binary",
+	TypeUUID:            "uuid",
+	TypeRoaringBitmap:   "roaring_bitmap",
+	TypeRoaringBitmap64: "roaring_bitmap64",
+	TypePassword:        "password",
+	TypeSafeBox:         "safebox",
+	TypeJSON:            "json",
+	TypeList:            "list",
+	TypeMap:             "map",
+	TypeSet:             "set",
+	TypeTimeseries:      "timeseries",
+	TypeBloomFilter:     "bloom_filter",
+	TypeShamirShare:     "shamir_share",
+	TypeSortedSet:       "sorted_set",
+	TypeHyperLogLog:     "hyperloglog",
+	TypeCountMinSketch:  "count_min_sketch",
+	TypeGeo:             "geo",
+}
+
+// dataFrameJSON is the JSON form of a DataFrame. Value holds a
+// representation suited to Type: decimals and big integers as strings,
+// binary payloads as base64, timestamps and times as RFC 3339, durations as
+// integer nanoseconds, and collection headers as objects.
+type dataFrameJSON struct {
+	Type       string          `json:"type"`
+	Value      json.RawMessage `json:"value"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+	CreatedAt  *time.Time      `json:"created_at,omitempty"`
+	ModifiedAt *time.Time      `json:"modified_at,omitempty"`
+}
+
+type safeBoxJSON struct {
+	Algorithm EncryptionAlgorithm `json:"algorithm"`
+	Data      []byte              `json:"data"`
+	Nonce     []byte              `json:"nonce"`
+}
+
+// MarshalJSON encodes the frame as {"type": ..., "value": ...} plus its
+// expiration and tracking timestamps when set. Unlike Marshal, the output is
+// meant for other languages and for humans reading logs.
+func (df *DataFrame) MarshalJSON() ([]byte, error) {
+	name, ok := dataTypeNames[df.typ]
+	if !ok {
+		return nil, &DataFrameError{Op: "MarshalJSON", Type: df.typ, Msg: "unknown type"}
+	}
+
+	value, err := df.jsonValue()
+	if err != nil {
+		return nil, err
+	}
+
+	out := dataFrameJSON{Type: name, Value: value}
+	if !df.expiresAt.IsZero() {
+		out.ExpiresAt = &df.expiresAt
+	}
+	if !df.createdAt.IsZero() {
+		out.CreatedAt = &df.createdAt
+	}
+	if !df.modifiedAt.IsZero() {
+		out.ModifiedAt = &df.modifiedAt
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a frame encoded by MarshalJSON.
+func (df *DataFrame) UnmarshalJSON(data []byte) error {
+	var in dataFrameJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return fmt.Errorf("failed to decode dataframe json: %w", err)
+	}
+
+	typ, ok := dataTypeByName(in.Type)
+	if !ok {
+		return &DataFrameError{Op: "UnmarshalJSON", Type: TypeNull, Msg: fmt.Sprintf("unknown type %q", in.Type)}
+	}
+
+	decoded := NULLDataFrame()
+	if err := decoded.setJSONValue(typ, in.Value); err != nil {
+		return err
+	}
+	if in.ExpiresAt != nil {
+		decoded.expiresAt = *in.ExpiresAt
+	}
+	if in.CreatedAt != nil {
+		decoded.createdAt = *in.CreatedAt
+	}
+	if in.ModifiedAt != nil {
+		decoded.modifiedAt = *in.ModifiedAt
+	}
+
+	*df = *decoded
+	return nil
+}
+
+func dataTypeByName(name string) (DataType, bool) {
+	for typ, n := range dataTypeNames {
+		if n == name {
+			return typ, true
+		}
+	}
+	return TypeNull, false
+}
+
+func (df *DataFrame) jsonValue() (json.RawMessage, error) {
+	var v any
+	switch df.typ {
+	case TypeNull:
+		return json.RawMessage("null"), nil
+	case TypeInt:
+		i, err := df.Int()
+		if err != nil {
+			return nil, err
+		}
+		v = i
+	case TypeFloat:
+		f, err := df.Float()
+		if err != nil {
+			return nil, err
+		}
+		// JSON numbers can't hold these, so they travel as strings
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			v = fmt.Sprint(f)
+		} else {
+			v = f
+		}
+	case TypeDecimal:
+		coefficient, scale, err := df.Decimal()
+		if err != nil {
+			return nil, err
+		}
+		v = formatDecimal(coefficient, scale)
+	case TypeBigInt:
+		i, err := df.BigInt()
+		if err != nil {
+			return nil, err
+		}
+		v = i.String()
+	case TypeString:
+		s, err := df.String()
+		if err != nil {
+			return nil, err
+		}
+		v = s
+	case TypeBool:
+		b, err := df.Bool()
+		if err != nil {
+			return nil, err
+		}
+		v = b
+	case TypeTimestamp:
+		t, err := df.Timestamp()
+		if err != nil {
+			return nil, err
+		}
+		v = t.UTC().Format(time.RFC3339Nano)
+	case TypeTime:
+		// The payload already is RFC 3339, including the original zone
+		v = string(df.payload)
+	case TypeDuration:
+		d, err := df.Duration()
+		if err != nil {
+			return nil, err
+		}
+		v = int64(d)
+	case TypeBinary:
+		b, err := df.Binary()
+		if err != nil {
+			return nil, err
+		}
+		v = b
+	case TypeUUID:
+		u, err := df.UUID()
+		if err != nil {
+			return nil, err
+		}
+		v = u.String()
+	case TypeRoaringBitmap, TypeRoaringBitmap64:
+		// The portable roaring format is readable by every roaring library
+		v = df.payload
+	case TypePassword, TypeJSON:
+		return json.RawMessage(df.payload), nil
+	case TypeSafeBox:
+		algorithm, data, nonce, err := df.SafeBox()
+		if err != nil {
+			return nil, err
+		}
+		v = safeBoxJSON{Algorithm: algorithm, Data: data, Nonce: nonce}
+	case TypeShamirShare:
+		shares, err := df.ShamirShare()
+		if err != nil {
+			return nil, err
+		}
+		v = shares
+	case TypeList:
+		data, err := df.List()
+		if err != nil {
+			return nil, err
+		}
+		v = data
+	case TypeMap:
+		data, err := df.Map()
+		if err != nil {
+			return nil, err
+		}
+		v = data
+	case TypeSet:
+		data, err := df.Set()
+		if err != nil {
+			return nil, err
+		}
+		v = data
+	case TypeSortedSet:
+		data, err := df.SortedSet()
+		if err != nil {
+			return nil, err
+		}
+		v = data
+	case TypeGeo:
+		data, err := df.Geo()
+		if err != nil {
+			return nil, err
+		}
+		v = data
+	case TypeTimeseries:
+		data, err := df.Timeseries()
+		if err != nil {
+			return nil, err
+		}
+		v = data
+	case TypeBloomFilter:
+		data, err := df.BloomFilter()
+		if err != nil {
+			return nil, err
+		}
+		v = data
+	case TypeHyperLogLog:
+		data, err := df.HyperLogLog()
+		if err != nil {
+			return nil, err
+		}
+		v = data
+	case TypeCountMinSketch:
+		data, err := df.CountMinSketch()
+		if err != nil {
+			return nil, err
+		}
+		v = data
+	default:
+		return nil, &DataFrameError{Op: "MarshalJSON", Type: df.typ, Msg: "unknown type"}
+	}
+
+	return json.Marshal(v)
+}
+
+func (df *DataFrame) setJSONValue(typ DataType, raw json.RawMessage) error {
+	decode := func(v any) error {
+		if err := json.Unmarshal(raw, v); err != nil {
+			return &DataFrameError{Op: "UnmarshalJSON", Type: typ, Msg: err.Error()}
+		}
+		return nil
+	}
+
+	switch typ {
+	case TypeNull:
+		return nil
+	case TypeInt:
+		var i int64
+		if err := decode(&i); err != nil {
+			return err
+		}
+		return df.SetInt(i)
+	case TypeFloat:
+		var f float64
+		if err := json.Unmarshal(raw, &f); err != nil {
+			var s string
+			if err := decode(&s); err != nil {
+				return err
+			}
+			if f, err = parseNonFiniteFloat(s); err != nil {
+				return &DataFrameError{Op: "UnmarshalJSON", Type: typ, Msg: err.Error()}
+			}
+		}
+		return df.SetFloat(f)
+	case TypeDecimal:
+		var s string
+		if err := decode(&s); err != nil {
+			return err
+		}
+		coefficient, scale, err := parseDecimal(s)
+		if err != nil {
+			return &DataFrameError{Op: "UnmarshalJSON", Type: typ, Msg: err.Error()}
+		}
+		return df.SetDecimal(coefficient, scale)
+	case TypeBigInt:
+		var s string
+		if err := decode(&s); err != nil {
+			return err
+		}
+		i, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return &DataFrameError{Op: "UnmarshalJSON", Type: typ, Msg: fmt.Sprintf("invalid integer %q", s)}
+		}
+		return df.SetBigInt(i)
+	case TypeString:
+		var s string
+		if err := decode(&s); err != nil {
+			return err
+		}
+		return df.SetString(s)
+	case TypeBool:
+		var b bool
+		if err := decode(&b); err != nil {
+			return err
+		}
+		return df.SetBool(b)
+	case TypeTimestamp, TypeTime:
+		var s string
+		if err := decode(&s); err != nil {
+			return err
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return &DataFrameError{Op: "UnmarshalJSON", Type: typ, Msg: err.Error()}
+		}
+		if typ == TypeTime {
+			return df.SetTime(t)
+		}
+		return df.SetTimestamp(t)
+	case TypeDuration:
+		var d int64
+		if err := decode(&d); err != nil {
+			return err
+		}
+		return df.SetDuration(time.Duration(d))
+	case TypeBinary:
+		var b []byte
+		if err := decode(&b); err != nil {
+			return err
+		}
+		return df.SetBinary(b)
+	case TypeUUID:
+		var s string
+		if err := decode(&s); err != nil {
+			return err
+		}
+		u, err := uuid.Parse(s)
+		if err != nil {
+			return &DataFrameError{Op: "UnmarshalJSON", Type: typ, Msg: err.Error()}
+		}
+		return df.SetUUID(&u)
+	case TypeRoaringBitmap:
+		var b []byte
+		if err := decode(&b); err != nil {
+			return err
+		}
+		bm := roaring.New()
+		if err := bm.UnmarshalBinary(b); err != nil {
+			return &DataFrameError{Op: "UnmarshalJSON", Type: typ, Msg: err.Error()}
+		}
+		df.typ, df.payload = typ, b
+		return nil
+	case TypeRoaringBitmap64:
+		var b []byte
+		if err := decode(&b); err != nil {
+			return err
+		}
+		bm := roaring64.New()
+		if err := bm.UnmarshalBinary(b); err != nil {
+			return &DataFrameError{Op: "UnmarshalJSON", Type: typ, Msg: err.Error()}
+		}
+		df.typ, df.payload = typ, b
+		return nil
+	case TypePassword:
+		var data PasswordData
+		if err := decode(&data); err != nil {
+			return err
+		}
+		if len(data.Hash) == 0 || len(data.Salt) == 0 {
+			return &DataFrameError{Op: "UnmarshalJSON", Type: typ, Msg: "hash and salt cannot be empty"}
+		}
+		df.typ, df.payload = typ, append([]byte(nil), raw...)
+		return nil
+	case TypeJSON:
+		return df.SetJSON(raw)
+	case TypeSafeBox:
+		var data safeBoxJSON
+		if err := decode(&data); err != nil {
+			return err
+		}
+		return df.SetSafeBox(data.Algorithm, data.Data, data.Nonce)
+	case TypeShamirShare:
+		var shares map[byte][]byte
+		if err := decode(&shares); err != nil {
+			return err
+		}
+		return df.SetShamirShare(shares)
+	case TypeList:
+		var data ListData
+		if err := decode(&data); err != nil {
+			return err
+		}
+		return df.SetList(&data)
+	case TypeMap:
+		var data MapData
+		if err := decode(&data); err != nil {
+			return err
+		}
+		return df.SetMap(&data)
+	case TypeSet:
+		var data SetData
+		if err := decode(&data); err != nil {
+			return err
+		}
+		return df.SetSet(&data)
+	case TypeSortedSet:
+		var data SortedSetData
+		if err := decode(&data); err != nil {
+			return err
+		}
+		return df.SetSortedSet(&data)
+	case TypeGeo:
+		var data SortedSetData
+		if err := decode(&data); err != nil {
+			return err
+		}
+		return df.SetGeo(&data)
+	case TypeTimeseries:
+		var data TimeseriesData
+		if err := decode(&data); err != nil {
+			return err
+		}
+		return df.SetTimeseries(&data)
+	case TypeBloomFilter:
+		var data BloomFilterData
+		if err := decode(&data); err != nil {
+			return err
+		}
+		return df.SetBloomFilter(&data)
+	case TypeHyperLogLog:
+		var data HyperLogLogData
+		if err := decode(&data); err != nil {
+			return err
+		}
+		return df.SetHyperLogLog(&data)
+	case TypeCountMinSketch:
+		var data CountMinSketchData
+		if err := decode(&data); err != nil {
+			return err
+		}
+		return df.SetCountMinSketch(&data)
+	}
+
+	return &DataFrameError{Op: "UnmarshalJSON", Type: typ, Msg: "unknown type"}
+}
+
+func parseNonFiniteFloat(s string) (float64, error) {
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "+Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	}
+	return 0, fmt.Errorf("invalid float %q", s)
+}
+
+// formatDecimal renders coefficient×10^-scale in plain notation, keeping
+// trailing zeros so the scale survives a round trip.
+func formatDecimal(coefficient *big.Int, scale int32) string {
+	digits := new(big.Int).Abs(coefficient).String()
+	if scale > 0 {
+		if pad := int(scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(scale)] + "." + digits[len(digits)-int(scale):]
+	}
+	if coefficient.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+func parseDecimal(s string) (*big.Int, int32, error) {
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	coefficient, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok || strings.ContainsAny(fracPart, "+-") {
+		return nil, 0, fmt.Errorf("invalid decimal %q", s)
+	}
+	return coefficient, int32(len(fracPart)), nil
+}
+
+var (
+	_ json.Marshaler   = (*DataFrame)(nil)
+	_ json.Unmarshaler = (*DataFrame)(nil)
+)
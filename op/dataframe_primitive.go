@@ -2,6 +2,7 @@ package op
 
 import (
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +19,8 @@ type PrimitiveData interface {
 	Duration() (time.Duration, error)
 	Binary() ([]byte, error)
 	UUID() (uuid.UUID, error)
+	Decimal() (coefficient *big.Int, scale int32, err error)
+	BigInt() (*big.Int, error)
 }
 
 type PrimitiveInt int64
@@ -62,6 +65,14 @@ func (p PrimitiveInt) UUID() (uuid.UUID, error) {
 	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is int")
 }
 
+func (p PrimitiveInt) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is int")
+}
+
+func (p PrimitiveInt) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is int")
+}
+
 type PrimitiveFloat float64
 
 func (p PrimitiveFloat) Type() DataType {
@@ -104,6 +115,14 @@ func (p PrimitiveFloat) UUID() (uuid.UUID, error) {
 	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is float")
 }
 
+func (p PrimitiveFloat) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is float")
+}
+
+func (p PrimitiveFloat) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is float")
+}
+
 type PrimitiveString string
 
 func (p PrimitiveString) Type() DataType {
@@ -146,6 +165,14 @@ func (p PrimitiveString) UUID() (uuid.UUID, error) {
 	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is string")
 }
 
+func (p PrimitiveString) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is string")
+}
+
+func (p PrimitiveString) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is string")
+}
+
 type PrimitiveBool bool
 
 func (p PrimitiveBool) Type() DataType {
@@ -188,6 +215,14 @@ func (p PrimitiveBool) UUID() (uuid.UUID, error) {
 	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is bool")
 }
 
+func (p PrimitiveBool) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is bool")
+}
+
+func (p PrimitiveBool) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is bool")
+}
+
 type PrimitiveBinary []byte
 
 func (p PrimitiveBinary) Type() DataType {
@@ -232,6 +267,14 @@ func (p PrimitiveBinary) UUID() (uuid.UUID, error) {
 	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is binary")
 }
 
+func (p PrimitiveBinary) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is binary")
+}
+
+func (p PrimitiveBinary) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is binary")
+}
+
 type PrimitiveTimestamp int64
 
 func (p PrimitiveTimestamp) Type() DataType {
@@ -274,6 +317,14 @@ func (p PrimitiveTimestamp) UUID() (uuid.UUID, error) {
 	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is timestamp")
 }
 
+func (p PrimitiveTimestamp) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is timestamp")
+}
+
+func (p PrimitiveTimestamp) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is timestamp")
+}
+
 type PrimitiveTime time.Time
 
 func (p PrimitiveTime) Type() DataType {
@@ -316,6 +367,14 @@ func (p PrimitiveTime) UUID() (uuid.UUID, error) {
 	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is time")
 }
 
+func (p PrimitiveTime) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is time")
+}
+
+func (p PrimitiveTime) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is time")
+}
+
 type PrimitiveDuration time.Duration
 
 func (p PrimitiveDuration) Type() DataType {
@@ -358,6 +417,14 @@ func (p PrimitiveDuration) UUID() (uuid.UUID, error) {
 	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is duration")
 }
 
+func (p PrimitiveDuration) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is duration")
+}
+
+func (p PrimitiveDuration) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is duration")
+}
+
 type PrimitiveUUID uuid.UUID
 
 func (p PrimitiveUUID) Type() DataType {
@@ -399,3 +466,201 @@ func (p PrimitiveUUID) Binary() ([]byte, error) {
 func (p PrimitiveUUID) UUID() (uuid.UUID, error) {
 	return uuid.UUID(p), nil
 }
+
+func (p PrimitiveUUID) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is UUID")
+}
+
+func (p PrimitiveUUID) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is UUID")
+}
+
+// PrimitiveDecimal is the fixed-point number Coefficient×10^-Scale, stored
+// exactly as DataFrame.SetDecimal does.
+type PrimitiveDecimal struct {
+	Coefficient *big.Int
+	Scale       int32
+}
+
+func (p PrimitiveDecimal) Type() DataType {
+	return TypeDecimal
+}
+
+func (p PrimitiveDecimal) Int() (int64, error) {
+	return 0, fmt.Errorf("this is not an int, type is decimal")
+}
+
+func (p PrimitiveDecimal) Float() (float64, error) {
+	return 0, fmt.Errorf("this is not a float, type is decimal")
+}
+
+func (p PrimitiveDecimal) String() (string, error) {
+	return "", fmt.Errorf("this is not a string, type is decimal")
+}
+
+func (p PrimitiveDecimal) Bool() (bool, error) {
+	return false, fmt.Errorf("this is not a bool, type is decimal")
+}
+
+func (p PrimitiveDecimal) Timestamp() (int64, error) {
+	return 0, fmt.Errorf("this is not a timestamp, type is decimal")
+}
+
+func (p PrimitiveDecimal) Time() (time.Time, error) {
+	return time.Time{}, fmt.Errorf("this is not a time, type is decimal")
+}
+
+func (p PrimitiveDecimal) Duration() (time.Duration, error) {
+	return 0, fmt.Errorf("this is not a duration, type is decimal")
+}
+
+func (p PrimitiveDecimal) Binary() ([]byte, error) {
+	return nil, fmt.Errorf("this is not a binary, type is decimal")
+}
+
+func (p PrimitiveDecimal) UUID() (uuid.UUID, error) {
+	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is decimal")
+}
+
+func (p PrimitiveDecimal) Decimal() (*big.Int, int32, error) {
+	if p.Coefficient == nil {
+		return nil, 0, fmt.Errorf("decimal coefficient cannot be nil")
+	}
+	return new(big.Int).Set(p.Coefficient), p.Scale, nil
+}
+
+func (p PrimitiveDecimal) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is decimal")
+}
+
+// PrimitiveBigInt is an integer of arbitrary size.
+type PrimitiveBigInt struct {
+	Value *big.Int
+}
+
+func (p PrimitiveBigInt) Type() DataType {
+	return TypeBigInt
+}
+
+func (p PrimitiveBigInt) Int() (int64, error) {
+	return 0, fmt.Errorf("this is not an int, type is big int")
+}
+
+func (p PrimitiveBigInt) Float() (float64, error) {
+	return 0, fmt.Errorf("this is not a float, type is big int")
+}
+
+func (p PrimitiveBigInt) String() (string, error) {
+	return "", fmt.Errorf("this is not a string, type is big int")
+}
+
+func (p PrimitiveBigInt) Bool() (bool, error) {
+	return false, fmt.Errorf("this is not a bool, type is big int")
+}
+
+func (p PrimitiveBigInt) Timestamp() (int64, error) {
+	return 0, fmt.Errorf("this is not a timestamp, type is big int")
+}
+
+func (p PrimitiveBigInt) Time() (time.Time, error) {
+	return time.Time{}, fmt.Errorf("this is not a time, type is big int")
+}
+
+func (p PrimitiveBigInt) Duration() (time.Duration, error) {
+	return 0, fmt.Errorf("this is not a duration, type is big int")
+}
+
+func (p PrimitiveBigInt) Binary() ([]byte, error) {
+	return nil, fmt.Errorf("this is not a binary, type is big int")
+}
+
+func (p PrimitiveBigInt) UUID() (uuid.UUID, error) {
+	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is big int")
+}
+
+func (p PrimitiveBigInt) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is big int")
+}
+
+func (p PrimitiveBigInt) BigInt() (*big.Int, error) {
+	if p.Value == nil {
+		return nil, fmt.Errorf("big int value cannot be nil")
+	}
+	return new(big.Int).Set(p.Value), nil
+}
+
+// PrimitiveNull is the primitive counterpart of NULLDataFrame. It stands in
+// for values that are absent, such as missing fields in MapMultiGet.
+type PrimitiveNull struct{}
+
+func (p PrimitiveNull) Type() DataType {
+	return TypeNull
+}
+
+func (p PrimitiveNull) Int() (int64, error) {
+	return 0, fmt.Errorf("this is not an int, type is null")
+}
+
+func (p PrimitiveNull) Float() (float64, error) {
+	return 0, fmt.Errorf("this is not a float, type is null")
+}
+
+func (p PrimitiveNull) String() (string, error) {
+	return "", fmt.Errorf("this is not a string, type is null")
+}
+
+func (p PrimitiveNull) Bool() (bool, error) {
+	return false, fmt.Errorf("this is not a bool, type is null")
+}
+
+func (p PrimitiveNull) Timestamp() (int64, error) {
+	return 0, fmt.Errorf("this is not a timestamp, type is null")
+}
+
+func (p PrimitiveNull) Time() (time.Time, error) {
+	return time.Time{}, fmt.Errorf("this is not a time, type is null")
+}
+
+func (p PrimitiveNull) Duration() (time.Duration, error) {
+	return 0, fmt.Errorf("this is not a duration, type is null")
+}
+
+func (p PrimitiveNull) Binary() ([]byte, error) {
+	return nil, fmt.Errorf("this is not a binary, type is null")
+}
+
+func (p PrimitiveNull) UUID() (uuid.UUID, error) {
+	return uuid.UUID{}, fmt.Errorf("this is not a UUID, type is null")
+}
+
+func (p PrimitiveNull) Decimal() (*big.Int, int32, error) {
+	return nil, 0, fmt.Errorf("this is not a decimal, type is null")
+}
+
+func (p PrimitiveNull) BigInt() (*big.Int, error) {
+	return nil, fmt.Errorf("this is not a big int, type is null")
+}
+
+// MarshalPrimitive encodes value as a DataFrame, so it can be sent over the
+// wire and decoded with UnmarshalPrimitive.
+func MarshalPrimitive(value PrimitiveData) ([]byte, error) {
+	df := NULLDataFrame()
+	if value != nil && value.Type() != TypeNull {
+		var err error
+		if df, err = primitiveDataFrame(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return df.Marshal()
+}
+
+// UnmarshalPrimitive decodes a value encoded by MarshalPrimitive.
+func UnmarshalPrimitive(data []byte) (PrimitiveData, error) {
+	df, err := UnmarshalDataFrame(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataFramePrimitive(df)
+}
@@ -1,10 +1,19 @@
 ﻿package op
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/RoaringBitmap/roaring/v2/roaring64"
 	"github.com/google/uuid"
+
+	"github.com/rivulet-io/tower/util/size"
 )
 
 func TestDataFrameCreation(t *testing.T) {
@@ -243,6 +252,397 @@ func TestDataFrameMarshalUnmarshal(t *testing.T) {
 	}
 }
 
+func TestDataFrameHeader(t *testing.T) {
+	t.Run("types beyond the legacy type byte", func(t *testing.T) {
+		// Legacy frames would have read these back with flags set
+		for _, typ := range []DataType{0x1F, 0x20, 0x40, 0x80, 0xFF} {
+			df := &DataFrame{typ: typ, payload: []byte("payload")}
+			for _, marshal := range []func() ([]byte, error){df.Marshal, df.MarshalCompact} {
+				data, err := marshal()
+				if err != nil {
+					t.Fatalf("Marshal failed: %v", err)
+				}
+				df2, err := UnmarshalDataFrame(data)
+				if err != nil {
+					t.Fatalf("Unmarshal failed: %v", err)
+				}
+				if df2.Type() != typ || string(df2.payload) != "payload" {
+					t.Errorf("Expected type %d with its payload, got type %d with %q", typ, df2.Type(), df2.payload)
+				}
+			}
+		}
+	})
+
+	t.Run("legacy frames", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+		// type|timestamp flag, expiration, created, modified, payload
+		data := []byte{byte(TypeInt) | dataFrameTimestampFlag}
+		data = binary.BigEndian.AppendUint64(data, uint64(expiresAt.UnixMilli()))
+		data = binary.BigEndian.AppendUint64(data, 100)
+		data = binary.BigEndian.AppendUint64(data, 200)
+		data = binary.BigEndian.AppendUint64(data, 42)
+
+		df, err := UnmarshalDataFrame(data)
+		if err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if v, err := df.Int(); err != nil || v != 42 {
+			t.Errorf("Expected 42, got %d (%v)", v, err)
+		}
+		if !df.Expiration().Equal(expiresAt) || df.CreatedAt().UnixNano() != 100 || df.ModifiedAt().UnixNano() != 200 {
+			t.Errorf("Header fields don't match: got %v, %v, %v", df.Expiration(), df.CreatedAt(), df.ModifiedAt())
+		}
+
+		// type|compact flag, compactHas* flags, expiration, length, "hi"
+		data = []byte{byte(TypeString) | dataFrameCompactFlag, compactHasExpiry}
+		data = binary.AppendUvarint(data, uint64(expiresAt.UnixMilli()))
+		data = append(data, 2, 'h', 'i')
+
+		df, err = UnmarshalDataFrame(data)
+		if err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if s, err := df.String(); err != nil || s != "hi" {
+			t.Errorf("Expected 'hi', got %q (%v)", s, err)
+		}
+		typ, peeked, err := peekDataFrame(data)
+		if err != nil || typ != TypeString || !peeked.Equal(expiresAt) {
+			t.Errorf("Expected to peek TypeString expiring at %v, got %v at %v (%v)", expiresAt, typ, peeked, err)
+		}
+	})
+}
+
+func TestDataFrameCompactEncoding(t *testing.T) {
+	t.Run("smaller than legacy", func(t *testing.T) {
+		df := &DataFrame{}
+		df.SetString("hi")
+
+		legacy, err := df.Marshal()
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		compact, err := df.MarshalCompact()
+		if err != nil {
+			t.Fatalf("MarshalCompact failed: %v", err)
+		}
+		if len(compact) >= len(legacy) {
+			t.Errorf("Expected compact encoding to be smaller, got %d vs %d bytes", len(compact), len(legacy))
+		}
+		// marker, flags, type, length, "hi"
+		if len(compact) != 6 {
+			t.Errorf("Expected 6 bytes, got %d", len(compact))
+		}
+	})
+
+	t.Run("round trip with header fields", func(t *testing.T) {
+		df := &DataFrame{}
+		df.SetString("hello world")
+		df.SetExpiration(time.Now().Add(time.Hour).Truncate(time.Millisecond))
+		df.createdAt = time.Unix(0, 1700000000123456789)
+		df.modifiedAt = time.Unix(0, 1700000001123456789)
+
+		data, err := df.MarshalCompact()
+		if err != nil {
+			t.Fatalf("MarshalCompact failed: %v", err)
+		}
+
+		df2, err := UnmarshalDataFrame(data)
+		if err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if s, _ := df2.String(); s != "hello world" {
+			t.Errorf("Expected 'hello world', got %q", s)
+		}
+		if !df2.Expiration().Equal(df.Expiration()) {
+			t.Errorf("Expected expiration %v, got %v", df.Expiration(), df2.Expiration())
+		}
+		if !df2.CreatedAt().Equal(df.createdAt) || !df2.ModifiedAt().Equal(df.modifiedAt) {
+			t.Errorf("Timestamps don't match: got %v, %v", df2.CreatedAt(), df2.ModifiedAt())
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		df := &DataFrame{}
+		df.SetInt(1)
+		df.SetExpiration(time.Now().Add(-time.Minute))
+
+		data, err := df.MarshalCompact()
+		if err != nil {
+			t.Fatalf("MarshalCompact failed: %v", err)
+		}
+		if _, err := UnmarshalDataFrame(data); err == nil {
+			t.Error("Expected expired error")
+		}
+	})
+
+	t.Run("both encodings in one store", func(t *testing.T) {
+		fs := InMemory()
+		open := func(compact bool) *Operator {
+			tower, err := NewOperator(&Options{
+				Path:            "test.db",
+				BytesPerSync:    size.NewSizeFromBytes(32 * 1024),
+				CacheSize:       size.NewSizeFromMegabytes(64),
+				MemTableSize:    size.NewSizeFromMegabytes(4),
+				FS:              fs,
+				CompactEncoding: compact,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create tower: %v", err)
+			}
+			return tower
+		}
+
+		tower := open(false)
+		if err := tower.SetString("legacy", "old"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		tower.Close()
+
+		tower = open(true)
+		defer tower.Close()
+
+		if err := tower.SetString("compact", "new"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if err := tower.CreateList("list"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		if _, err := tower.PushRightList("list", PrimitiveInt(7)); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+
+		if v, err := tower.GetString("legacy"); err != nil || v != "old" {
+			t.Errorf("Expected legacy value 'old', got %q (%v)", v, err)
+		}
+		if v, err := tower.GetString("compact"); err != nil || v != "new" {
+			t.Errorf("Expected compact value 'new', got %q (%v)", v, err)
+		}
+		if v, err := tower.GetListIndex("list", 0); err != nil {
+			t.Errorf("Failed to index list: %v", err)
+		} else if i, _ := v.Int(); i != 7 {
+			t.Errorf("Expected 7, got %d", i)
+		}
+	})
+}
+
+func TestDataFrameJSONRoundTrip(t *testing.T) {
+	id := uuid.MustParse("9b2f6a4e-3c1d-4f7a-8e5b-2a6c9d0e1f23")
+	when := time.Date(2024, 3, 15, 10, 30, 0, 123456789, time.FixedZone("KST", 9*3600))
+
+	tests := []struct {
+		name string
+		set  func(df *DataFrame) error
+	}{
+		{"null", func(df *DataFrame) error { return nil }},
+		{"int", func(df *DataFrame) error { return df.SetInt(math.MinInt64) }},
+		{"float", func(df *DataFrame) error { return df.SetFloat(0.1) }},
+		{"float nan", func(df *DataFrame) error { return df.SetFloat(math.NaN()) }},
+		{"float inf", func(df *DataFrame) error { return df.SetFloat(math.Inf(-1)) }},
+		{"decimal", func(df *DataFrame) error { return df.SetDecimal(big.NewInt(-5), 3) }},
+		{"decimal trailing zeros", func(df *DataFrame) error { return df.SetDecimal(big.NewInt(12300), 2) }},
+		{"bigint", func(df *DataFrame) error {
+			v, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+			return df.SetBigInt(v)
+		}},
+		{"string", func(df *DataFrame) error { return df.SetString("héllo \"world\"") }},
+		{"bool", func(df *DataFrame) error { return df.SetBool(true) }},
+		{"timestamp", func(df *DataFrame) error { return df.SetTimestamp(when) }},
+		{"time", func(df *DataFrame) error { return df.SetTime(when) }},
+		{"duration", func(df *DataFrame) error { return df.SetDuration(-90*time.Minute + 1) }},
+		{"binary", func(df *DataFrame) error { return df.SetBinary([]byte{0, 0xff, 0x10}) }},
+		{"uuid", func(df *DataFrame) error { return df.SetUUID(&id) }},
+		{"roaring bitmap", func(df *DataFrame) error { return df.SetRoaringBitmap(roaring.BitmapOf(1, 5, 100000)) }},
+		{"roaring bitmap64", func(df *DataFrame) error { return df.SetRoaringBitmap64(roaring64.BitmapOf(1, 1<<40)) }},
+		{"password", func(df *DataFrame) error {
+			return df.SetPasswordWithOptions(PasswordAlgorithmBcrypt, []byte("hash"), []byte("salt"), &PasswordOptions{BcryptCost: 12})
+		}},
+		{"safebox", func(df *DataFrame) error {
+			return df.SetSafeBox(EncryptionAlgorithmAES256GCM, []byte("ciphertext"), []byte("nonce"))
+		}},
+		{"json", func(df *DataFrame) error { return df.SetJSON([]byte(`{"a":[1,2,{"b":null}]}`)) }},
+		{"list", func(df *DataFrame) error {
+			return df.SetList(&ListData{Prefix: "l:", HeadIndex: -2, TailIndex: 3, Length: 6})
+		}},
+		{"map", func(df *DataFrame) error { return df.SetMap(&MapData{Prefix: "m:", Count: 4}) }},
+		{"set", func(df *DataFrame) error { return df.SetSet(&SetData{Prefix: "s:", Count: 2}) }},
+		{"sorted set", func(df *DataFrame) error { return df.SetSortedSet(&SortedSetData{Prefix: "z:", Count: 9}) }},
+		{"geo", func(df *DataFrame) error { return df.SetGeo(&SortedSetData{Prefix: "g:", Count: 5}) }},
+		{"timeseries", func(df *DataFrame) error { return df.SetTimeseries(&TimeseriesData{Prefix: "ts:"}) }},
+		{"bloom filter", func(df *DataFrame) error {
+			return df.SetBloomFilter(&BloomFilterData{Prefix: "bf:", Salt: "bloom_salt_2025", Count: 3, Hashes: 4, Bits: []byte{0xaa, 0x55}})
+		}},
+		{"shamir share", func(df *DataFrame) error {
+			return df.SetShamirShare(map[byte][]byte{1: []byte("one"), 200: []byte("two")})
+		}},
+		{"hyperloglog", func(df *DataFrame) error {
+			return df.SetHyperLogLog(&HyperLogLogData{Precision: 4, Registers: []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}})
+		}},
+		{"count-min sketch", func(df *DataFrame) error {
+			return df.SetCountMinSketch(&CountMinSketchData{Width: 3, Depth: 2, Total: 7, Counters: []uint64{1, 0, 6, 4, 3, 0}})
+		}},
+	}
+
+	seen := make(map[DataType]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df := NULLDataFrame()
+			if err := tt.set(df); err != nil {
+				t.Fatalf("Failed to set value: %v", err)
+			}
+			df.SetExpiration(when.Add(time.Hour))
+			seen[df.Type()] = true
+
+			data, err := json.Marshal(df)
+			if err != nil {
+				t.Fatalf("Failed to marshal json: %v", err)
+			}
+
+			var got DataFrame
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Failed to unmarshal %s: %v", data, err)
+			}
+
+			again, err := json.Marshal(&got)
+			if err != nil {
+				t.Fatalf("Failed to marshal decoded frame: %v", err)
+			}
+			if !bytes.Equal(data, again) {
+				t.Errorf("Round trip changed %s to %s", data, again)
+			}
+
+			// Shamir shares are encoded in map order, so only their JSON is stable
+			if df.Type() != TypeShamirShare {
+				want, _ := df.Marshal()
+				have, _ := got.Marshal()
+				if !bytes.Equal(want, have) {
+					t.Errorf("Round trip through %s changed the frame", data)
+				}
+			}
+		})
+	}
+
+	for typ := range dataTypeNames {
+		if !seen[typ] {
+			t.Errorf("No round trip case for type %s", dataTypeNames[typ])
+		}
+	}
+
+	t.Run("readable values", func(t *testing.T) {
+		df := NULLDataFrame()
+		df.SetDecimal(big.NewInt(-5), 3)
+		data, _ := json.Marshal(df)
+		if string(data) != `{"type":"decimal","value":"-0.005"}` {
+			t.Errorf("Unexpected decimal json %s", data)
+		}
+
+		df.SetTimestamp(when)
+		data, _ = json.Marshal(df)
+		if string(data) != `{"type":"timestamp","value":"2024-03-15T01:30:00.123456789Z"}` {
+			t.Errorf("Unexpected timestamp json %s", data)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, in := range []string{
+			`{"type":"nope","value":1}`,
+			`{"type":"int","value":"1"}`,
+			`{"type":"decimal","value":"1.2.3"}`,
+			`{"type":"uuid","value":"x"}`,
+			`{"type":"roaring_bitmap","value":"AAAA"}`,
+			`{"type":"password","value":{}}`,
+		} {
+			var df DataFrame
+			if err := json.Unmarshal([]byte(in), &df); err == nil {
+				t.Errorf("Expected error decoding %s", in)
+			}
+		}
+	})
+}
+
+// geoPrimitive claims a type that has no primitive form.
+type geoPrimitive struct{ PrimitiveNull }
+
+func (geoPrimitive) Type() DataType { return TypeGeo }
+
+func TestPrimitiveMarshalRoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 123)
+	values := []PrimitiveData{
+		PrimitiveInt(-42),
+		PrimitiveFloat(3.5),
+		PrimitiveString("hello"),
+		PrimitiveBool(true),
+		PrimitiveTimestamp(now.UnixNano()),
+		PrimitiveTime(now),
+		PrimitiveDuration(90 * time.Second),
+		PrimitiveBinary([]byte{0, 1, 2}),
+		PrimitiveUUID(uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")),
+		PrimitiveDecimal{Coefficient: big.NewInt(12345), Scale: 2},
+		PrimitiveBigInt{Value: new(big.Int).Lsh(big.NewInt(1), 100)},
+		PrimitiveNull{},
+	}
+
+	for _, value := range values {
+		data, err := MarshalPrimitive(value)
+		if err != nil {
+			t.Fatalf("Failed to marshal %T: %v", value, err)
+		}
+		got, err := UnmarshalPrimitive(data)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal %T: %v", value, err)
+		}
+		// Times travel as timestamps
+		wantType := value.Type()
+		if wantType == TypeTime {
+			wantType = TypeTimestamp
+		}
+		if got.Type() != wantType {
+			t.Errorf("Expected type %v, got %v", wantType, got.Type())
+			continue
+		}
+
+		switch value.Type() {
+		case TypeBinary:
+			want, _ := value.Binary()
+			have, _ := got.Binary()
+			if string(want) != string(have) {
+				t.Errorf("Expected %v, got %v", want, have)
+			}
+		case TypeTimestamp, TypeTime:
+			have, _ := got.Timestamp()
+			if have != now.UnixNano() {
+				t.Errorf("Expected %d, got %d", now.UnixNano(), have)
+			}
+		case TypeDecimal:
+			wantCoefficient, wantScale, _ := value.Decimal()
+			coefficient, scale, _ := got.Decimal()
+			if coefficient.Cmp(wantCoefficient) != 0 || scale != wantScale {
+				t.Errorf("Expected %v scale %d, got %v scale %d", wantCoefficient, wantScale, coefficient, scale)
+			}
+		case TypeBigInt:
+			want, _ := value.BigInt()
+			have, _ := got.BigInt()
+			if have.Cmp(want) != 0 {
+				t.Errorf("Expected %v, got %v", want, have)
+			}
+		default:
+			if got != value {
+				t.Errorf("Expected %v, got %v", value, got)
+			}
+		}
+	}
+
+	if _, err := MarshalPrimitive(PrimitiveDecimal{}); err == nil {
+		t.Error("Expected error marshaling a decimal without a coefficient")
+	}
+	if _, err := MarshalPrimitive(geoPrimitive{}); err == nil {
+		t.Error("Expected error marshaling an unsupported type")
+	}
+	if _, err := UnmarshalPrimitive([]byte("garbage")); err == nil {
+		t.Error("Expected error unmarshaling garbage")
+	}
+}
+
 func TestDataFrameTypeConversion(t *testing.T) {
 	// Test string conversion
 	t.Run("string conversions", func(t *testing.T) {
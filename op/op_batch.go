@@ -0,0 +1,168 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBatchClosed is returned when a batch is used after Commit or Discard.
+var ErrBatchClosed = errors.New("batch is already committed or discarded")
+
+// Batch collects writes to several keys and applies them all at once in
+// Commit, or none of them at all. Writes are staged in memory and do not
+// touch the store until then; the first invalid write is reported by Commit.
+// A Batch is not safe for concurrent use.
+type Batch struct {
+	op     *Operator
+	writes []batchWrite
+	err    error
+	closed bool
+}
+
+type batchWrite struct {
+	key   string
+	value *DataFrame // nil deletes the key
+}
+
+// NewBatch starts an empty batch bound to op and its context.
+func (op *Operator) NewBatch() *Batch {
+	return &Batch{op: op}
+}
+
+// Len returns the number of staged writes.
+func (b *Batch) Len() int {
+	return len(b.writes)
+}
+
+func (b *Batch) Set(key string, value PrimitiveData) {
+	if b.err != nil {
+		return
+	}
+
+	df, err := primitiveDataFrame(value)
+	if err != nil {
+		b.err = fmt.Errorf("invalid write for key %s: %w", key, err)
+		return
+	}
+
+	b.writes = append(b.writes, batchWrite{key: key, value: df})
+}
+
+func (b *Batch) SetInt(key string, value int64) {
+	b.Set(key, PrimitiveInt(value))
+}
+
+func (b *Batch) SetFloat(key string, value float64) {
+	b.Set(key, PrimitiveFloat(value))
+}
+
+func (b *Batch) SetString(key string, value string) {
+	b.Set(key, PrimitiveString(value))
+}
+
+func (b *Batch) SetBool(key string, value bool) {
+	b.Set(key, PrimitiveBool(value))
+}
+
+func (b *Batch) SetBinary(key string, value []byte) {
+	b.Set(key, PrimitiveBinary(value))
+}
+
+// Delete removes key at commit time. Items of a list, map, set or other
+// compound value are removed along with it; a missing key is ignored.
+func (b *Batch) Delete(key string) {
+	if b.err != nil {
+		return
+	}
+
+	b.writes = append(b.writes, batchWrite{key: key})
+}
+
+// Discard drops every staged write. It is safe to call after Commit.
+func (b *Batch) Discard() {
+	b.writes = nil
+	b.closed = true
+}
+
+// Commit applies every staged write in a single Pebble batch, in the order
+// they were staged. All keys are locked in sorted order for the duration of
+// the commit, so batches over overlapping keys cannot deadlock. If Commit
+// fails nothing is written. The batch cannot be reused afterwards.
+func (b *Batch) Commit() (err error) {
+	op := b.op
+	defer op.traceOperation("CommitBatch", "")(&err)
+
+	if b.closed {
+		return ErrBatchClosed
+	}
+	b.closed = true
+
+	if b.err != nil {
+		return b.err
+	}
+	if len(b.writes) == 0 {
+		return nil
+	}
+
+	unlock, err := op.lockKeys(b.keys()...)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return b.apply()
+}
+
+// keys returns the staged keys in staging order.
+func (b *Batch) keys() []string {
+	keys := make([]string, len(b.writes))
+	for i, w := range b.writes {
+		keys[i] = w.key
+	}
+	return keys
+}
+
+// apply writes the staged writes in one Pebble batch. The caller holds the
+// locks of every staged key.
+func (b *Batch) apply() error {
+	op := b.op
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	for _, w := range b.writes {
+		if w.value != nil {
+			if err := op.setInBatch(batch, w.key, w.value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The key may only exist as an earlier write in this batch, so it
+		// is deleted even when the store has nothing for it
+		df, err := op.get(w.key)
+		if err != nil && !isNotExist(err) {
+			return fmt.Errorf("failed to get key %s: %w", w.key, err)
+		}
+		if err == nil {
+			entryKey, err := compoundEntryKey(df)
+			if err != nil {
+				return err
+			}
+			if entryKey != "" {
+				if err := batch.DeleteRange([]byte(entryKey+":"), []byte(entryKey+";"), nil); err != nil {
+					return fmt.Errorf("failed to delete items of %s: %w", w.key, err)
+				}
+			}
+		}
+		if err := batch.Delete([]byte(w.key), nil); err != nil {
+			return fmt.Errorf("failed to delete key %s: %w", w.key, err)
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return nil
+}
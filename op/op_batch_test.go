@@ -0,0 +1,182 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestBatchCommit(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.SetString("stale", "value"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if err := tower.CreateList("queue"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if _, err := tower.PushRightList("queue", PrimitiveString("job")); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+
+	batch := tower.NewBatch()
+	batch.SetInt("count", 3)
+	batch.SetFloat("ratio", 0.5)
+	batch.SetString("name", "tower")
+	batch.SetBool("ready", true)
+	batch.SetBinary("blob", []byte{1, 2, 3})
+	batch.Delete("stale")
+	batch.Delete("queue")
+	batch.SetString("temp", "x")
+	batch.Delete("temp")
+	if batch.Len() != 9 {
+		t.Errorf("Expected 9 staged writes, got %d", batch.Len())
+	}
+
+	// Nothing is visible before Commit
+	if exists, _ := tower.Exists("count"); exists {
+		t.Error("Expected staged write to be invisible before commit")
+	}
+
+	if err := batch.Commit(); err != nil {
+		t.Fatalf("Failed to commit batch: %v", err)
+	}
+
+	if v, err := tower.GetInt("count"); err != nil || v != 3 {
+		t.Errorf("Expected count 3, got %d (%v)", v, err)
+	}
+	if v, err := tower.GetFloat("ratio"); err != nil || v != 0.5 {
+		t.Errorf("Expected ratio 0.5, got %v (%v)", v, err)
+	}
+	if v, err := tower.GetString("name"); err != nil || v != "tower" {
+		t.Errorf("Expected name tower, got %q (%v)", v, err)
+	}
+	if v, err := tower.GetBool("ready"); err != nil || !v {
+		t.Errorf("Expected ready true, got %v (%v)", v, err)
+	}
+	if v, err := tower.GetBinary("blob"); err != nil || len(v) != 3 {
+		t.Errorf("Expected 3 byte blob, got %v (%v)", v, err)
+	}
+	for _, key := range []string{"stale", "queue", "temp"} {
+		if exists, err := tower.Exists(key); err != nil || exists {
+			t.Errorf("Expected %s to be deleted, got %v (%v)", key, exists, err)
+		}
+	}
+
+	// Deleting the list must not leave its items behind
+	raw := 0
+	if err := tower.rangePrefix("queue", func(k string, df *DataFrame) error {
+		raw++
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to scan keys: %v", err)
+	}
+	if raw != 0 {
+		t.Errorf("Expected no list keys left, got %d", raw)
+	}
+
+	if err := batch.Commit(); !errors.Is(err, ErrBatchClosed) {
+		t.Errorf("Expected ErrBatchClosed on second commit, got %v", err)
+	}
+}
+
+func TestBatchFailedCommit(t *testing.T) {
+	t.Run("invalid write", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		if err := tower.SetInt("balance", 100); err != nil {
+			t.Fatalf("Failed to set int: %v", err)
+		}
+
+		batch := tower.NewBatch()
+		batch.SetInt("balance", 50)
+		batch.SetString("audit", "debit")
+		batch.Set("bad", nil)
+		batch.Delete("balance")
+		if err := batch.Commit(); err == nil {
+			t.Fatal("Expected commit to fail")
+		}
+
+		if v, err := tower.GetInt("balance"); err != nil || v != 100 {
+			t.Errorf("Expected balance to stay 100, got %d (%v)", v, err)
+		}
+		if exists, _ := tower.Exists("audit"); exists {
+			t.Error("Expected no partial write for audit")
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		batch := tower.WithContext(ctx).NewBatch()
+		batch.SetInt("a", 1)
+		batch.SetInt("b", 2)
+		cancel()
+
+		if err := batch.Commit(); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		for _, key := range []string{"a", "b"} {
+			if exists, _ := tower.Exists(key); exists {
+				t.Errorf("Expected no partial write for %s", key)
+			}
+		}
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		batch := tower.NewBatch()
+		batch.SetInt("a", 1)
+		batch.Discard()
+
+		if err := batch.Commit(); !errors.Is(err, ErrBatchClosed) {
+			t.Errorf("Expected ErrBatchClosed after discard, got %v", err)
+		}
+		if exists, _ := tower.Exists("a"); exists {
+			t.Error("Expected discarded write to be dropped")
+		}
+	})
+}
+
+func TestBatchConcurrentCommits(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	// Batches touching the same keys in opposite order must not deadlock
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			batch := tower.NewBatch()
+			keys := []string{"x", "y", "z"}
+			if i%2 == 1 {
+				keys = []string{"z", "y", "x"}
+			}
+			for _, key := range keys {
+				batch.SetString(key, fmt.Sprintf("batch-%d", i))
+			}
+			if err := batch.Commit(); err != nil {
+				t.Errorf("Failed to commit batch %d: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Every key ends up written by the same batch
+	x, _ := tower.GetString("x")
+	y, _ := tower.GetString("y")
+	z, _ := tower.GetString("z")
+	if x != y || y != z {
+		t.Errorf("Expected all keys from one batch, got %s %s %s", x, y, z)
+	}
+}
@@ -10,12 +10,17 @@ import (
 // ================================
 
 // SetBigInt sets a BigInt value for the given key
-func (op *Operator) SetBigInt(key string, value *big.Int) error {
-	unlock := op.lock(key)
+func (op *Operator) SetBigInt(key string, value *big.Int) (err error) {
+	defer op.traceOperation("SetBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
-	err := df.SetBigInt(value)
+	err = df.SetBigInt(value)
 	if err != nil {
 		return fmt.Errorf("failed to set BigInt: %w", err)
 	}
@@ -24,8 +29,13 @@ func (op *Operator) SetBigInt(key string, value *big.Int) error {
 }
 
 // GetBigInt retrieves a BigInt value for the given key
-func (op *Operator) GetBigInt(key string) (*big.Int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetBigInt(key string) (_ *big.Int, err error) {
+	defer op.traceOperation("GetBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -34,15 +44,20 @@ func (op *Operator) GetBigInt(key string) (*big.Int, error) {
 	}
 
 	if df.Type() != TypeBigInt {
-		return nil, fmt.Errorf("key %s is not a BigInt", key)
+		return nil, fmt.Errorf("key %s is not a BigInt: %w", key, ErrTypeMismatch)
 	}
 
 	return df.BigInt()
 }
 
 // AddBigInt adds a value to the BigInt stored at key
-func (op *Operator) AddBigInt(key string, delta *big.Int) (*big.Int, error) {
-	unlock := op.lock(key)
+func (op *Operator) AddBigInt(key string, delta *big.Int) (_ *big.Int, err error) {
+	defer op.traceOperation("AddBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -51,7 +66,7 @@ func (op *Operator) AddBigInt(key string, delta *big.Int) (*big.Int, error) {
 	}
 
 	if df.Type() != TypeBigInt {
-		return nil, fmt.Errorf("key %s is not a BigInt", key)
+		return nil, fmt.Errorf("key %s is not a BigInt: %w", key, ErrTypeMismatch)
 	}
 
 	current, err := df.BigInt()
@@ -75,8 +90,13 @@ func (op *Operator) AddBigInt(key string, delta *big.Int) (*big.Int, error) {
 }
 
 // SubBigInt subtracts a value from the BigInt stored at key
-func (op *Operator) SubBigInt(key string, delta *big.Int) (*big.Int, error) {
-	unlock := op.lock(key)
+func (op *Operator) SubBigInt(key string, delta *big.Int) (_ *big.Int, err error) {
+	defer op.traceOperation("SubBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -85,7 +105,7 @@ func (op *Operator) SubBigInt(key string, delta *big.Int) (*big.Int, error) {
 	}
 
 	if df.Type() != TypeBigInt {
-		return nil, fmt.Errorf("key %s is not a BigInt", key)
+		return nil, fmt.Errorf("key %s is not a BigInt: %w", key, ErrTypeMismatch)
 	}
 
 	current, err := df.BigInt()
@@ -109,8 +129,13 @@ func (op *Operator) SubBigInt(key string, delta *big.Int) (*big.Int, error) {
 }
 
 // MulBigInt multiplies the BigInt stored at key by a factor
-func (op *Operator) MulBigInt(key string, factor *big.Int) (*big.Int, error) {
-	unlock := op.lock(key)
+func (op *Operator) MulBigInt(key string, factor *big.Int) (_ *big.Int, err error) {
+	defer op.traceOperation("MulBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -119,7 +144,7 @@ func (op *Operator) MulBigInt(key string, factor *big.Int) (*big.Int, error) {
 	}
 
 	if df.Type() != TypeBigInt {
-		return nil, fmt.Errorf("key %s is not a BigInt", key)
+		return nil, fmt.Errorf("key %s is not a BigInt: %w", key, ErrTypeMismatch)
 	}
 
 	current, err := df.BigInt()
@@ -143,12 +168,17 @@ func (op *Operator) MulBigInt(key string, factor *big.Int) (*big.Int, error) {
 }
 
 // DivBigInt divides the BigInt stored at key by a divisor
-func (op *Operator) DivBigInt(key string, divisor *big.Int) (*big.Int, error) {
-	unlock := op.lock(key)
+func (op *Operator) DivBigInt(key string, divisor *big.Int) (_ *big.Int, err error) {
+	defer op.traceOperation("DivBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	if divisor.Sign() == 0 {
-		return nil, fmt.Errorf("division by zero")
+		return nil, ErrDivisionByZero
 	}
 
 	df, err := op.get(key)
@@ -157,7 +187,7 @@ func (op *Operator) DivBigInt(key string, divisor *big.Int) (*big.Int, error) {
 	}
 
 	if df.Type() != TypeBigInt {
-		return nil, fmt.Errorf("key %s is not a BigInt", key)
+		return nil, fmt.Errorf("key %s is not a BigInt: %w", key, ErrTypeMismatch)
 	}
 
 	current, err := df.BigInt()
@@ -181,8 +211,13 @@ func (op *Operator) DivBigInt(key string, divisor *big.Int) (*big.Int, error) {
 }
 
 // ModBigInt computes the modulus of the BigInt stored at key
-func (op *Operator) ModBigInt(key string, modulus *big.Int) (*big.Int, error) {
-	unlock := op.lock(key)
+func (op *Operator) ModBigInt(key string, modulus *big.Int) (_ *big.Int, err error) {
+	defer op.traceOperation("ModBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	if modulus.Sign() == 0 {
@@ -195,7 +230,7 @@ func (op *Operator) ModBigInt(key string, modulus *big.Int) (*big.Int, error) {
 	}
 
 	if df.Type() != TypeBigInt {
-		return nil, fmt.Errorf("key %s is not a BigInt", key)
+		return nil, fmt.Errorf("key %s is not a BigInt: %w", key, ErrTypeMismatch)
 	}
 
 	current, err := df.BigInt()
@@ -219,8 +254,13 @@ func (op *Operator) ModBigInt(key string, modulus *big.Int) (*big.Int, error) {
 }
 
 // CmpBigInt compares the BigInt stored at key with another value
-func (op *Operator) CmpBigInt(key string, other *big.Int) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) CmpBigInt(key string, other *big.Int) (_ int, err error) {
+	defer op.traceOperation("CmpBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -229,7 +269,7 @@ func (op *Operator) CmpBigInt(key string, other *big.Int) (int, error) {
 	}
 
 	if df.Type() != TypeBigInt {
-		return 0, fmt.Errorf("key %s is not a BigInt", key)
+		return 0, fmt.Errorf("key %s is not a BigInt: %w", key, ErrTypeMismatch)
 	}
 
 	current, err := df.BigInt()
@@ -240,9 +280,78 @@ func (op *Operator) CmpBigInt(key string, other *big.Int) (int, error) {
 	return current.Cmp(other), nil
 }
 
+// CompareBigInt compares the BigInt stored at key with value and returns -1,
+// 0 or 1 like CompareInt.
+func (op *Operator) CompareBigInt(key string, value *big.Int) (_ int, err error) {
+	defer op.traceOperation("CompareBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	current, err := op.getBigInt(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return current.Cmp(value), nil
+}
+
+// SetBigIntIfGreater stores value at key if it is greater than the BigInt
+// stored there, and returns the BigInt stored afterwards.
+func (op *Operator) SetBigIntIfGreater(key string, value *big.Int) (_ *big.Int, err error) {
+	defer op.traceOperation("SetBigIntIfGreater", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	df := NULLDataFrame()
+	if err := df.SetBigInt(value); err != nil {
+		return nil, fmt.Errorf("failed to set BigInt: %w", err)
+	}
+
+	current, err := op.getBigInt(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if value.Cmp(current) <= 0 {
+		return current, nil
+	}
+
+	if err := op.set(key, df); err != nil {
+		return nil, fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return new(big.Int).Set(value), nil
+}
+
+func (op *Operator) getBigInt(key string) (*big.Int, error) {
+	df, err := op.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if df.Type() != TypeBigInt {
+		return nil, fmt.Errorf("key %s is not a BigInt: %w", key, ErrTypeMismatch)
+	}
+
+	return df.BigInt()
+}
+
 // NegBigInt negates the BigInt stored at key
-func (op *Operator) NegBigInt(key string) (*big.Int, error) {
-	unlock := op.lock(key)
+func (op *Operator) NegBigInt(key string) (_ *big.Int, err error) {
+	defer op.traceOperation("NegBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -251,7 +360,7 @@ func (op *Operator) NegBigInt(key string) (*big.Int, error) {
 	}
 
 	if df.Type() != TypeBigInt {
-		return nil, fmt.Errorf("key %s is not a BigInt", key)
+		return nil, fmt.Errorf("key %s is not a BigInt: %w", key, ErrTypeMismatch)
 	}
 
 	current, err := df.BigInt()
@@ -275,8 +384,13 @@ func (op *Operator) NegBigInt(key string) (*big.Int, error) {
 }
 
 // AbsBigInt computes the absolute value of the BigInt stored at key
-func (op *Operator) AbsBigInt(key string) (*big.Int, error) {
-	unlock := op.lock(key)
+func (op *Operator) AbsBigInt(key string) (_ *big.Int, err error) {
+	defer op.traceOperation("AbsBigInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -285,7 +399,7 @@ func (op *Operator) AbsBigInt(key string) (*big.Int, error) {
 	}
 
 	if df.Type() != TypeBigInt {
-		return nil, fmt.Errorf("key %s is not a BigInt", key)
+		return nil, fmt.Errorf("key %s is not a BigInt: %w", key, ErrTypeMismatch)
 	}
 
 	current, err := df.BigInt()
@@ -112,5 +112,49 @@ func TestBigIntOperations(t *testing.T) {
 			t.Errorf("NegBigInt: expected %s, got %s", expected.String(), negResult.String())
 		}
 	})
+	t.Run("compare and set if greater", func(t *testing.T) {
+		key := "compare_bigint"
+		big1, _ := new(big.Int).SetString("100000000000000000000", 10)
+		big2, _ := new(big.Int).SetString("100000000000000000001", 10)
+		if err := tower.SetBigInt(key, big1); err != nil {
+			t.Fatalf("SetBigInt failed: %v", err)
+		}
+
+		for _, tt := range []struct {
+			value    *big.Int
+			expected int
+		}{
+			{big1, 0},
+			{big2, -1},
+			{big.NewInt(-1), 1},
+		} {
+			cmp, err := tower.CompareBigInt(key, tt.value)
+			if err != nil {
+				t.Fatalf("CompareBigInt failed: %v", err)
+			}
+			if cmp != tt.expected {
+				t.Errorf("CompareBigInt(%s): expected %d, got %d", tt.value, tt.expected, cmp)
+			}
+		}
+
+		result, err := tower.SetBigIntIfGreater(key, big.NewInt(5))
+		if err != nil || result.Cmp(big1) != 0 {
+			t.Errorf("Expected %s to stay, got %v (%v)", big1, result, err)
+		}
+		result, err = tower.SetBigIntIfGreater(key, big2)
+		if err != nil || result.Cmp(big2) != 0 {
+			t.Errorf("Expected %s, got %v (%v)", big2, result, err)
+		}
+		if stored, _ := tower.GetBigInt(key); stored.Cmp(big2) != 0 {
+			t.Errorf("Expected stored %s, got %s", big2, stored)
+		}
+
+		if _, err := tower.SetBigIntIfGreater(key, nil); err == nil {
+			t.Error("Expected error for nil value")
+		}
+		if _, err := tower.CompareBigInt("missing_bigint", big1); err == nil {
+			t.Error("Expected error for missing key")
+		}
+	})
 }
 
@@ -3,10 +3,16 @@
 import (
 	"bytes"
 	"fmt"
+	"time"
 )
 
-func (op *Operator) SetBinary(key string, value []byte) error {
-	unlock := op.lock(key)
+func (op *Operator) SetBinary(key string, value []byte) (err error) {
+	defer op.traceOperation("SetBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -21,8 +27,32 @@ func (op *Operator) SetBinary(key string, value []byte) error {
 	return nil
 }
 
-func (op *Operator) GetBinary(key string) ([]byte, error) {
-	unlock := op.lock(key)
+// SetBinaryEx sets key to value and makes it expire after ttl, in a single
+// write.
+func (op *Operator) SetBinaryEx(key string, value []byte, ttl time.Duration) (err error) {
+	defer op.traceOperation("SetBinaryEx", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df := NULLDataFrame()
+	if err := df.SetBinary(value); err != nil {
+		return fmt.Errorf("failed to set binary value: %w", err)
+	}
+
+	return op.setExpiring(key, df, ttl)
+}
+
+func (op *Operator) GetBinary(key string) (_ []byte, err error) {
+	defer op.traceOperation("GetBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -39,8 +69,13 @@ func (op *Operator) GetBinary(key string) ([]byte, error) {
 }
 
 // Byte manipulation operations
-func (op *Operator) AppendBinary(key string, data []byte) ([]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) AppendBinary(key string, data []byte) (_ []byte, err error) {
+	defer op.traceOperation("AppendBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -65,8 +100,52 @@ func (op *Operator) AppendBinary(key string, data []byte) ([]byte, error) {
 	return newValue, nil
 }
 
-func (op *Operator) PrependBinary(key string, data []byte) ([]byte, error) {
-	unlock := op.lock(key)
+// AppendBinaryOrCreate appends data to the binary value at key, creating the
+// key with data as its value if it does not exist yet.
+func (op *Operator) AppendBinaryOrCreate(key string, data []byte) (_ []byte, err error) {
+	defer op.traceOperation("AppendBinaryOrCreate", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		if !isNotExist(err) {
+			return nil, fmt.Errorf("failed to get key %s: %w", key, err)
+		}
+		df = NULLDataFrame()
+		if err := df.SetBinary(nil); err != nil {
+			return nil, fmt.Errorf("failed to set binary value: %w", err)
+		}
+	}
+
+	current, err := df.Binary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get binary value for key %s: %w", key, err)
+	}
+
+	newValue := append(current, data...)
+	if err := df.SetBinary(newValue); err != nil {
+		return nil, fmt.Errorf("failed to set binary value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return nil, fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return newValue, nil
+}
+
+func (op *Operator) PrependBinary(key string, data []byte) (_ []byte, err error) {
+	defer op.traceOperation("PrependBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -92,8 +171,13 @@ func (op *Operator) PrependBinary(key string, data []byte) ([]byte, error) {
 }
 
 // Length and sub-byte operations
-func (op *Operator) GetBinaryLength(key string) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetBinaryLength(key string) (_ int, err error) {
+	defer op.traceOperation("GetBinaryLength", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -109,8 +193,13 @@ func (op *Operator) GetBinaryLength(key string) (int, error) {
 	return len(current), nil
 }
 
-func (op *Operator) GetBinarySubstring(key string, start, length int) ([]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetBinarySubstring(key string, start, length int) (_ []byte, err error) {
+	defer op.traceOperation("GetBinarySubstring", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -138,8 +227,43 @@ func (op *Operator) GetBinarySubstring(key string, start, length int) ([]byte, e
 }
 
 // Comparison operations
-func (op *Operator) CompareBinaryEqual(key string, other []byte) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) SwapBinary(key string, newValue []byte) (_ []byte, err error) {
+	defer op.traceOperation("SwapBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	current, err := df.Binary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get binary value for key %s: %w", key, err)
+	}
+
+	if err := df.SetBinary(newValue); err != nil {
+		return nil, fmt.Errorf("failed to set binary value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return nil, fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return current, nil
+}
+
+func (op *Operator) CompareBinaryEqual(key string, other []byte) (_ bool, err error) {
+	defer op.traceOperation("CompareBinaryEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -155,8 +279,13 @@ func (op *Operator) CompareBinaryEqual(key string, other []byte) (bool, error) {
 	return bytes.Equal(current, other), nil
 }
 
-func (op *Operator) CompareBinary(key string, other []byte) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareBinary(key string, other []byte) (_ int, err error) {
+	defer op.traceOperation("CompareBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -173,8 +302,13 @@ func (op *Operator) CompareBinary(key string, other []byte) (int, error) {
 }
 
 // Bit operations
-func (op *Operator) AndBinary(key string, mask []byte) ([]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) AndBinary(key string, mask []byte) (_ []byte, err error) {
+	defer op.traceOperation("AndBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -210,8 +344,13 @@ func (op *Operator) AndBinary(key string, mask []byte) ([]byte, error) {
 	return newValue, nil
 }
 
-func (op *Operator) OrBinary(key string, mask []byte) ([]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) OrBinary(key string, mask []byte) (_ []byte, err error) {
+	defer op.traceOperation("OrBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -247,8 +386,13 @@ func (op *Operator) OrBinary(key string, mask []byte) ([]byte, error) {
 	return newValue, nil
 }
 
-func (op *Operator) XorBinary(key string, mask []byte) ([]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) XorBinary(key string, mask []byte) (_ []byte, err error) {
+	defer op.traceOperation("XorBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -285,8 +429,13 @@ func (op *Operator) XorBinary(key string, mask []byte) ([]byte, error) {
 }
 
 // Search operations
-func (op *Operator) ContainsBinary(key string, sub []byte) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ContainsBinary(key string, sub []byte) (_ bool, err error) {
+	defer op.traceOperation("ContainsBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -302,8 +451,13 @@ func (op *Operator) ContainsBinary(key string, sub []byte) (bool, error) {
 	return bytes.Contains(current, sub), nil
 }
 
-func (op *Operator) GetBinaryIndex(key string, sub []byte) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetBinaryIndex(key string, sub []byte) (_ int, err error) {
+	defer op.traceOperation("GetBinaryIndex", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -320,8 +474,13 @@ func (op *Operator) GetBinaryIndex(key string, sub []byte) (int, error) {
 }
 
 // Conversion operations
-func (op *Operator) ReverseBinary(key string) ([]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) ReverseBinary(key string) (_ []byte, err error) {
+	defer op.traceOperation("ReverseBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -352,3 +511,43 @@ func (op *Operator) ReverseBinary(key string) ([]byte, error) {
 	return newValue, nil
 }
 
+// CompareAndSwapBinary writes newValue only if the value stored at key equals
+// expected, and reports whether it did. A missing key matches an empty
+// expected value, so it can also be used to create the key.
+func (op *Operator) CompareAndSwapBinary(key string, expected, newValue []byte) (_ bool, err error) {
+	defer op.traceOperation("CompareAndSwapBinary", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		if !isNotExist(err) {
+			return false, fmt.Errorf("failed to get key %s: %w", key, err)
+		}
+		if len(expected) != 0 {
+			return false, nil
+		}
+		df = NULLDataFrame()
+	} else {
+		current, err := df.Binary()
+		if err != nil {
+			return false, fmt.Errorf("failed to get binary value for key %s: %w", key, err)
+		}
+		if !bytes.Equal(current, expected) {
+			return false, nil
+		}
+	}
+
+	if err := df.SetBinary(newValue); err != nil {
+		return false, fmt.Errorf("failed to set binary value: %w", err)
+	}
+	if err := op.set(key, df); err != nil {
+		return false, fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return true, nil
+}
@@ -2,6 +2,8 @@
 
 import (
 	"bytes"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/rivulet-io/tower/util/size"
@@ -562,6 +564,42 @@ func TestBinaryOperations(t *testing.T) {
 		}
 	})
 
+	// Test append-or-create semantics
+	t.Run("AppendBinaryOrCreate", func(t *testing.T) {
+		key := "test:binary:append_or_create"
+
+		result, err := tower.AppendBinaryOrCreate(key, []byte("Hello"))
+		if err != nil {
+			t.Fatalf("Failed to append-or-create on missing key: %v", err)
+		}
+		if !bytes.Equal(result, []byte("Hello")) {
+			t.Errorf("Expected 'Hello', got %s", result)
+		}
+
+		result, err = tower.AppendBinaryOrCreate(key, []byte(", World"))
+		if err != nil {
+			t.Fatalf("Failed to append-or-create on existing key: %v", err)
+		}
+		if !bytes.Equal(result, []byte("Hello, World")) {
+			t.Errorf("Expected 'Hello, World', got %s", result)
+		}
+
+		stored, err := tower.GetBinary(key)
+		if err != nil {
+			t.Fatalf("Failed to get binary: %v", err)
+		}
+		if !bytes.Equal(stored, []byte("Hello, World")) {
+			t.Errorf("Stored value: expected 'Hello, World', got %s", stored)
+		}
+
+		if err := tower.SetString("test:binary:append_or_create_string", "text"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if _, err := tower.AppendBinaryOrCreate("test:binary:append_or_create_string", []byte("x")); err == nil {
+			t.Error("Expected type mismatch error when appending to a string key")
+		}
+	})
+
 	// Test nil and empty byte handling
 	t.Run("NilAndEmptyBytes", func(t *testing.T) {
 		key := "test:binary:nil_empty"
@@ -609,3 +647,72 @@ func TestBinaryOperations(t *testing.T) {
 		}
 	})
 }
+
+func TestCompareAndSwapBinary(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if ok, err := tower.CompareAndSwapBinary("cas", nil, []byte{0}); err != nil || !ok {
+		t.Fatalf("Expected create to succeed, got %v (%v)", ok, err)
+	}
+	if ok, err := tower.CompareAndSwapBinary("missing", []byte{1}, []byte{2}); err != nil || ok {
+		t.Errorf("Expected swap on missing key with non-empty expected to fail, got %v (%v)", ok, err)
+	}
+	if ok, err := tower.CompareAndSwapBinary("cas", []byte{9}, []byte{1}); err != nil || ok {
+		t.Errorf("Expected mismatched swap to fail, got %v (%v)", ok, err)
+	}
+
+	var wg sync.WaitGroup
+	var successes atomic.Int32
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := tower.CompareAndSwapBinary("cas", []byte{0}, []byte{1, byte(i)})
+			if err != nil {
+				t.Errorf("CompareAndSwapBinary failed: %v", err)
+				return
+			}
+			if ok {
+				successes.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := successes.Load(); n != 1 {
+		t.Fatalf("Expected exactly one successful swap, got %d", n)
+	}
+	if v, _ := tower.GetBinary("cas"); len(v) != 2 || v[0] != 1 {
+		t.Errorf("Expected a swapped value, got %v", v)
+	}
+}
+
+func TestSwapBinary(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.SetBinary("key", []byte("old")); err != nil {
+		t.Fatalf("Failed to set binary: %v", err)
+	}
+	prev, err := tower.SwapBinary("key", []byte("new"))
+	if err != nil {
+		t.Fatalf("SwapBinary failed: %v", err)
+	}
+	if !bytes.Equal(prev, []byte("old")) {
+		t.Errorf("Expected previous value old, got %q", prev)
+	}
+	if v, _ := tower.GetBinary("key"); !bytes.Equal(v, []byte("new")) {
+		t.Errorf("Expected new, got %q", v)
+	}
+
+	if err := tower.SetString("str", "x"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if _, err := tower.SwapBinary("str", []byte("y")); err == nil {
+		t.Error("Expected type mismatch error")
+	}
+	if v, _ := tower.GetString("str"); v != "x" {
+		t.Errorf("Expected string to be untouched, got %q", v)
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"math"
 )
 
 // CreateBloomFilter creates a new Bloom filter
-func (op *Operator) CreateBloomFilter(key string, slots int) error {
+func (op *Operator) CreateBloomFilter(key string, slots int) (err error) {
+	defer op.traceOperation("CreateBloomFilter", key)(&err)
+
 	if slots == 0 {
 		slots = 3 // Default slot count
 	}
@@ -15,13 +18,16 @@ func (op *Operator) CreateBloomFilter(key string, slots int) error {
 		return fmt.Errorf("slots must be between 3 and 5")
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	// Check if already exists
-	_, err := op.get(key)
+	_, err = op.get(key)
 	if err == nil {
-		return fmt.Errorf("bloom filter %s already exists", key)
+		return fmt.Errorf("bloom filter %s: %w", key, ErrContainerExists)
 	}
 
 	// Create BloomFilterData
@@ -41,9 +47,128 @@ func (op *Operator) CreateBloomFilter(key string, slots int) error {
 	return op.set(key, df)
 }
 
+// CreateBloomFilterWithRate creates a Bloom filter sized for expectedItems
+// at the given false positive rate. Unlike CreateBloomFilter it keeps a bit
+// array in the metadata, so its size is fixed no matter how many items are
+// added.
+func (op *Operator) CreateBloomFilterWithRate(key string, expectedItems uint, falsePositiveRate float64) (err error) {
+	defer op.traceOperation("CreateBloomFilterWithRate", key)(&err)
+
+	if expectedItems == 0 {
+		return fmt.Errorf("expected items must be positive")
+	}
+	if !(falsePositiveRate > 0 && falsePositiveRate < 1) {
+		return fmt.Errorf("false positive rate must be between 0 and 1, got %v", falsePositiveRate)
+	}
+
+	bits, hashes := optimalBloomFilterSize(expectedItems, falsePositiveRate)
+	if bits > math.MaxUint32 {
+		return fmt.Errorf("bloom filter of %d bits is too large", bits)
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Check if already exists
+	_, err = op.get(key)
+	if err == nil {
+		return fmt.Errorf("bloom filter %s: %w", key, ErrContainerExists)
+	}
+
+	data := &BloomFilterData{
+		Prefix: key,
+		Salt:   "bloom_salt_2025",
+		Hashes: hashes,
+		Bits:   make([]byte, bits/8),
+	}
+
+	df := NULLDataFrame()
+	err = df.SetBloomFilter(data)
+	if err != nil {
+		return fmt.Errorf("failed to set bloom filter data: %w", err)
+	}
+
+	return op.set(key, df)
+}
+
+// BloomAdd adds item to the Bloom filter at key. Items of any primitive type
+// are accepted by filters created with CreateBloomFilterWithRate; filters
+// created with a slot count only take strings.
+func (op *Operator) BloomAdd(key string, item PrimitiveData) (err error) {
+	defer op.traceOperation("BloomAdd", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("bloom filter %s does not exist: %w", key, err)
+	}
+
+	bfd, err := df.BloomFilter()
+	if err != nil {
+		return fmt.Errorf("failed to get bloom filter data: %w", err)
+	}
+
+	if bfd.Bits != nil {
+		return op.addBloomFilterBits(key, df, bfd, item)
+	}
+
+	itemStr, err := item.String()
+	if err != nil {
+		return fmt.Errorf("bloom filter %s only accepts strings: %w", key, err)
+	}
+
+	return op.addBloomFilterItem(key, df, bfd, itemStr)
+}
+
+// BloomContains reports whether item may have been added to the Bloom filter
+// at key. It never reports false for an added item.
+func (op *Operator) BloomContains(key string, item PrimitiveData) (_ bool, err error) {
+	defer op.traceOperation("BloomContains", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return false, fmt.Errorf("bloom filter %s does not exist: %w", key, err)
+	}
+
+	bfd, err := df.BloomFilter()
+	if err != nil {
+		return false, fmt.Errorf("failed to get bloom filter data: %w", err)
+	}
+
+	if bfd.Bits != nil {
+		return op.containsBloomFilterBits(bfd, item)
+	}
+
+	itemStr, err := item.String()
+	if err != nil {
+		return false, fmt.Errorf("bloom filter %s only accepts strings: %w", key, err)
+	}
+
+	return op.containsBloomFilterItem(bfd, itemStr)
+}
+
 // AddBloomFilter adds an element to the Bloom filter
-func (op *Operator) AddBloomFilter(key, item string) error {
-	unlock := op.lock(key)
+func (op *Operator) AddBloomFilter(key, item string) (err error) {
+	defer op.traceOperation("AddBloomFilter", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	// Get metadata
@@ -57,6 +182,16 @@ func (op *Operator) AddBloomFilter(key, item string) error {
 		return fmt.Errorf("failed to get bloom filter data: %w", err)
 	}
 
+	if bfd.Bits != nil {
+		return op.addBloomFilterBits(key, df, bfd, PrimitiveString(item))
+	}
+
+	return op.addBloomFilterItem(key, df, bfd, item)
+}
+
+// addBloomFilterItem stores the slots of item under its own item key in a
+// filter created with a slot count.
+func (op *Operator) addBloomFilterItem(key string, df *DataFrame, bfd *BloomFilterData, item string) error {
 	// Calculate hash slot
 	slots := op.getBloomFilterSlots(item, bfd.Slots, bfd.Salt)
 
@@ -69,7 +204,7 @@ func (op *Operator) AddBloomFilter(key, item string) error {
 	// Store item
 	itemKey := string(MakeBloomFilterItemKey(bfd.Prefix, item))
 	itemDf := NULLDataFrame()
-	err = itemDf.SetBinary(slotBytes)
+	err := itemDf.SetBinary(slotBytes)
 	if err != nil {
 		return fmt.Errorf("failed to set slot data: %w", err)
 	}
@@ -90,8 +225,13 @@ func (op *Operator) AddBloomFilter(key, item string) error {
 }
 
 // ContainsBloomFilter checks if element exists in Bloom filter
-func (op *Operator) ContainsBloomFilter(key, item string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ContainsBloomFilter(key, item string) (_ bool, err error) {
+	defer op.traceOperation("ContainsBloomFilter", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	// Get metadata
@@ -105,6 +245,14 @@ func (op *Operator) ContainsBloomFilter(key, item string) (bool, error) {
 		return false, fmt.Errorf("failed to get bloom filter data: %w", err)
 	}
 
+	if bfd.Bits != nil {
+		return op.containsBloomFilterBits(bfd, PrimitiveString(item))
+	}
+
+	return op.containsBloomFilterItem(bfd, item)
+}
+
+func (op *Operator) containsBloomFilterItem(bfd *BloomFilterData, item string) (bool, error) {
 	// Calculate hash slot
 	slots := op.getBloomFilterSlots(item, bfd.Slots, bfd.Salt)
 
@@ -137,8 +285,13 @@ func (op *Operator) ContainsBloomFilter(key, item string) (bool, error) {
 }
 
 // ClearBloomFilter initializes the Bloom filter
-func (op *Operator) ClearBloomFilter(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) ClearBloomFilter(key string) (err error) {
+	defer op.traceOperation("ClearBloomFilter", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	// Get metadata
@@ -163,6 +316,7 @@ func (op *Operator) ClearBloomFilter(key string) error {
 
 	// Reset Count
 	bfd.Count = 0
+	clear(bfd.Bits)
 	err = df.SetBloomFilter(bfd)
 	if err != nil {
 		return fmt.Errorf("failed to update bloom filter data: %w", err)
@@ -172,8 +326,13 @@ func (op *Operator) ClearBloomFilter(key string) error {
 }
 
 // CountBloomFilter returns the number of elements in Bloom filter
-func (op *Operator) CountBloomFilter(key string) (uint64, error) {
-	unlock := op.lock(key)
+func (op *Operator) CountBloomFilter(key string) (_ uint64, err error) {
+	defer op.traceOperation("CountBloomFilter", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -190,8 +349,13 @@ func (op *Operator) CountBloomFilter(key string) (uint64, error) {
 }
 
 // DeleteBloomFilter completely deletes the Bloom filter
-func (op *Operator) DeleteBloomFilter(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) DeleteBloomFilter(key string) (err error) {
+	defer op.traceOperation("DeleteBloomFilter", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	return op.deleteBloomFilter(key)
@@ -237,5 +401,83 @@ func (op *Operator) getBloomFilterSlots(item string, slots int, salt string) []i
 	return result
 }
 
+// optimalBloomFilterSize returns the bit count, rounded up to whole bytes,
+// and hash count that keep n items at false positive rate p.
+func optimalBloomFilterSize(n uint, p float64) (bits uint64, hashes int) {
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	bits = (uint64(m) + 7) / 8 * 8
+
+	hashes = int(math.Round(float64(bits) / float64(n) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+
+	return bits, hashes
+}
+
+// bloomFilterBitIndexes derives the bit positions of item by double hashing
+// the two halves of its 128-bit FNV-1a hash. The type is hashed along with
+// the value, so PrimitiveInt(1) and PrimitiveString("1") differ.
+func bloomFilterBitIndexes(item PrimitiveData, hashes int, bits uint64) ([]uint64, error) {
+	itemDf, err := listItemDataFrame(item)
+	if err != nil {
+		return nil, err
+	}
+
+	h := fnv.New128a()
+	h.Write([]byte{byte(itemDf.typ)})
+	h.Write(itemDf.payload)
+	sum := h.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+
+	indexes := make([]uint64, hashes)
+	for i := range indexes {
+		indexes[i] = (h1 + uint64(i)*h2) % bits
+	}
 
+	return indexes, nil
+}
+
+// addBloomFilterBits sets the bits of item and counts it when at least one
+// bit was still clear.
+func (op *Operator) addBloomFilterBits(key string, df *DataFrame, bfd *BloomFilterData, item PrimitiveData) error {
+	indexes, err := bloomFilterBitIndexes(item, bfd.Hashes, uint64(len(bfd.Bits))*8)
+	if err != nil {
+		return fmt.Errorf("invalid bloom filter item: %w", err)
+	}
+
+	changed := false
+	for _, idx := range indexes {
+		mask := byte(1) << (idx % 8)
+		if bfd.Bits[idx/8]&mask == 0 {
+			bfd.Bits[idx/8] |= mask
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
 
+	bfd.Count++
+	if err := df.SetBloomFilter(bfd); err != nil {
+		return fmt.Errorf("failed to update bloom filter data: %w", err)
+	}
+
+	return op.set(key, df)
+}
+
+func (op *Operator) containsBloomFilterBits(bfd *BloomFilterData, item PrimitiveData) (bool, error) {
+	indexes, err := bloomFilterBitIndexes(item, bfd.Hashes, uint64(len(bfd.Bits))*8)
+	if err != nil {
+		return false, fmt.Errorf("invalid bloom filter item: %w", err)
+	}
+
+	for _, idx := range indexes {
+		if bfd.Bits[idx/8]&(byte(1)<<(idx%8)) == 0 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
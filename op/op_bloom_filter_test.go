@@ -1,6 +1,7 @@
 ﻿package op
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -141,4 +142,122 @@ func TestBloomFilterSlots(t *testing.T) {
 	}
 }
 
+func TestBloomFilterWithRate(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test_bloom_rate"
+	const items = 10000
+	const rate = 0.01
+
+	if err := tower.CreateBloomFilterWithRate(key, items, rate); err != nil {
+		t.Fatalf("Failed to create Bloom filter: %v", err)
+	}
+	if err := tower.CreateBloomFilterWithRate(key, items, rate); err == nil {
+		t.Error("Expected error creating an existing Bloom filter")
+	}
+
+	for i := 0; i < items; i++ {
+		if err := tower.BloomAdd(key, PrimitiveString(fmt.Sprintf("member-%d", i))); err != nil {
+			t.Fatalf("Failed to add item %d: %v", i, err)
+		}
+	}
+
+	// No added item may be reported missing
+	for i := 0; i < items; i++ {
+		contains, err := tower.BloomContains(key, PrimitiveString(fmt.Sprintf("member-%d", i)))
+		if err != nil {
+			t.Fatalf("Failed to check item %d: %v", i, err)
+		}
+		if !contains {
+			t.Fatalf("False negative for item %d", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < items; i++ {
+		contains, err := tower.BloomContains(key, PrimitiveString(fmt.Sprintf("other-%d", i)))
+		if err != nil {
+			t.Fatalf("Failed to check item %d: %v", i, err)
+		}
+		if contains {
+			falsePositives++
+		}
+	}
+	measured := float64(falsePositives) / items
+	t.Logf("Measured false positive rate %.4f for configured %.4f", measured, rate)
+	if measured > rate*2 {
+		t.Errorf("False positive rate %.4f is far above configured %.4f", measured, rate)
+	}
+
+	count, err := tower.CountBloomFilter(key)
+	if err != nil {
+		t.Fatalf("Failed to get count: %v", err)
+	}
+	if count < items*99/100 || count > items {
+		t.Errorf("Expected count close to %d, got %d", items, count)
+	}
 
+	t.Run("typed items", func(t *testing.T) {
+		key := "test_bloom_typed"
+		if err := tower.CreateBloomFilterWithRate(key, 100, 0.001); err != nil {
+			t.Fatalf("Failed to create Bloom filter: %v", err)
+		}
+		if err := tower.BloomAdd(key, PrimitiveInt(42)); err != nil {
+			t.Fatalf("Failed to add int item: %v", err)
+		}
+		if err := tower.AddBloomFilter(key, "apple"); err != nil {
+			t.Fatalf("Failed to add string item: %v", err)
+		}
+
+		if ok, err := tower.BloomContains(key, PrimitiveInt(42)); err != nil || !ok {
+			t.Errorf("Expected int item to be present, got %v (%v)", ok, err)
+		}
+		if ok, err := tower.BloomContains(key, PrimitiveString("apple")); err != nil || !ok {
+			t.Errorf("Expected string item to be present, got %v (%v)", ok, err)
+		}
+		if ok, err := tower.ContainsBloomFilter(key, "apple"); err != nil || !ok {
+			t.Errorf("Expected string item to be present, got %v (%v)", ok, err)
+		}
+
+		if err := tower.ClearBloomFilter(key); err != nil {
+			t.Fatalf("Failed to clear Bloom filter: %v", err)
+		}
+		if ok, err := tower.BloomContains(key, PrimitiveInt(42)); err != nil || ok {
+			t.Errorf("Expected cleared filter to be empty, got %v (%v)", ok, err)
+		}
+	})
+
+	t.Run("slot filters", func(t *testing.T) {
+		key := "test_bloom_slots"
+		if err := tower.CreateBloomFilter(key, 3); err != nil {
+			t.Fatalf("Failed to create Bloom filter: %v", err)
+		}
+		if err := tower.BloomAdd(key, PrimitiveString("apple")); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+		if ok, err := tower.ContainsBloomFilter(key, "apple"); err != nil || !ok {
+			t.Errorf("Expected item to be present, got %v (%v)", ok, err)
+		}
+		if err := tower.BloomAdd(key, PrimitiveInt(1)); err == nil {
+			t.Error("Expected error adding a non-string item to a slot filter")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if err := tower.BloomAdd("missing_bloom", PrimitiveString("x")); err == nil {
+			t.Error("Expected error adding to a non-existent filter")
+		}
+		if _, err := tower.BloomContains("missing_bloom", PrimitiveString("x")); err == nil {
+			t.Error("Expected error checking a non-existent filter")
+		}
+		if err := tower.CreateBloomFilterWithRate("bad_bloom", 0, 0.01); err == nil {
+			t.Error("Expected error for zero expected items")
+		}
+		for _, rate := range []float64{0, 1, -0.5} {
+			if err := tower.CreateBloomFilterWithRate("bad_bloom", 100, rate); err == nil {
+				t.Errorf("Expected error for rate %v", rate)
+			}
+		}
+	})
+}
@@ -4,8 +4,13 @@ import (
 	"fmt"
 )
 
-func (op *Operator) SetBool(key string, value bool) error {
-	unlock := op.lock(key)
+func (op *Operator) SetBool(key string, value bool) (err error) {
+	defer op.traceOperation("SetBool", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -20,8 +25,13 @@ func (op *Operator) SetBool(key string, value bool) error {
 	return nil
 }
 
-func (op *Operator) GetBool(key string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetBool(key string) (_ bool, err error) {
+	defer op.traceOperation("GetBool", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -38,8 +48,13 @@ func (op *Operator) GetBool(key string) (bool, error) {
 }
 
 // Logical operations
-func (op *Operator) AndBool(key string, other bool) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) AndBool(key string, other bool) (_ bool, err error) {
+	defer op.traceOperation("AndBool", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -64,8 +79,13 @@ func (op *Operator) AndBool(key string, other bool) (bool, error) {
 	return newValue, nil
 }
 
-func (op *Operator) OrBool(key string, other bool) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) OrBool(key string, other bool) (_ bool, err error) {
+	defer op.traceOperation("OrBool", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -90,8 +110,13 @@ func (op *Operator) OrBool(key string, other bool) (bool, error) {
 	return newValue, nil
 }
 
-func (op *Operator) XorBool(key string, other bool) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) XorBool(key string, other bool) (_ bool, err error) {
+	defer op.traceOperation("XorBool", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -116,8 +141,13 @@ func (op *Operator) XorBool(key string, other bool) (bool, error) {
 	return newValue, nil
 }
 
-func (op *Operator) NotBool(key string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) NotBool(key string) (_ bool, err error) {
+	defer op.traceOperation("NotBool", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -143,8 +173,13 @@ func (op *Operator) NotBool(key string) (bool, error) {
 }
 
 // Comparison operations
-func (op *Operator) EqualBool(key string, other bool) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) EqualBool(key string, other bool) (_ bool, err error) {
+	defer op.traceOperation("EqualBool", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -161,8 +196,13 @@ func (op *Operator) EqualBool(key string, other bool) (bool, error) {
 }
 
 // Toggle operations
-func (op *Operator) ToggleBool(key string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ToggleBool(key string) (_ bool, err error) {
+	defer op.traceOperation("ToggleBool", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -188,8 +228,13 @@ func (op *Operator) ToggleBool(key string) (bool, error) {
 }
 
 // Conditional set operations
-func (op *Operator) SetBoolIfTrue(key string, condition bool) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetBoolIfTrue(key string, condition bool) (_ bool, err error) {
+	defer op.traceOperation("SetBoolIfTrue", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -215,8 +260,13 @@ func (op *Operator) SetBoolIfTrue(key string, condition bool) (bool, error) {
 	return current, nil
 }
 
-func (op *Operator) SetBoolIfFalse(key string, condition bool) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetBoolIfFalse(key string, condition bool) (_ bool, err error) {
+	defer op.traceOperation("SetBoolIfFalse", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -242,8 +292,13 @@ func (op *Operator) SetBoolIfFalse(key string, condition bool) (bool, error) {
 	return current, nil
 }
 
-func (op *Operator) SetBoolIfEqual(key string, expected, newValue bool) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetBoolIfEqual(key string, expected, newValue bool) (_ bool, err error) {
+	defer op.traceOperation("SetBoolIfEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
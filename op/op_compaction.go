@@ -0,0 +1,101 @@
+package op
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// DBStats summarises the on-disk state of the store.
+type DBStats struct {
+	// Tables is the number of SSTables across all levels.
+	Tables int64
+	// TablesSize is the total size of those SSTables in bytes.
+	TablesSize int64
+	// DiskUsage estimates the bytes used on disk, including the WAL and
+	// tables that are obsolete but not yet removed.
+	DiskUsage uint64
+}
+
+// Flush writes the memtable out to an SSTable and waits until it is done.
+func (op *Operator) Flush() (err error) {
+	defer op.traceOperation("Flush", "")(&err)
+
+	if err := op.db.Flush(); err != nil {
+		return fmt.Errorf("failed to flush: %w", err)
+	}
+
+	return nil
+}
+
+// Compact compacts the key range [start, end), dropping deleted and
+// overwritten entries in it. Like every other key, start and end are
+// relative to the operator's namespace, so an operator from WithNamespace
+// compacts only its own keys. Use it to reclaim space after large deletes
+// such as DeletePrefix or TTL sweeps; it blocks until the compaction is done.
+func (op *Operator) Compact(start, end []byte) (err error) {
+	defer op.traceOperation("Compact", string(start))(&err)
+
+	if string(start) >= string(end) {
+		return fmt.Errorf("invalid range: start key %q must be less than end key %q", start, end)
+	}
+
+	if err := op.db.Compact(start, end, true); err != nil {
+		return fmt.Errorf("failed to compact range [%q, %q): %w", start, end, err)
+	}
+
+	return nil
+}
+
+// CompactAll flushes the memtable and compacts every SSTable, including
+// ones left holding nothing but tombstones.
+func (op *Operator) CompactAll() (err error) {
+	defer op.traceOperation("CompactAll", "")(&err)
+
+	return op.compactAll()
+}
+
+func (op *Operator) compactAll() error {
+	if err := op.db.Flush(); err != nil {
+		return fmt.Errorf("failed to flush: %w", err)
+	}
+
+	levels, err := op.db.SSTables()
+	if err != nil {
+		return fmt.Errorf("failed to list sstables: %w", err)
+	}
+
+	var start, end []byte
+	for _, tables := range levels {
+		for _, table := range tables {
+			if smallest := table.Smallest.UserKey; start == nil || bytes.Compare(smallest, start) < 0 {
+				start = smallest
+			}
+			if largest := table.Largest.UserKey; end == nil || bytes.Compare(largest, end) > 0 {
+				end = largest
+			}
+		}
+	}
+	if start == nil {
+		return nil
+	}
+
+	// The largest key is inclusive, so extend the range just past it
+	end = append(bytes.Clone(end), 0)
+	if err := op.db.DB.Compact(start, end, true); err != nil {
+		return fmt.Errorf("failed to compact: %w", err)
+	}
+
+	return nil
+}
+
+// Stats returns the current SSTable count and disk usage.
+func (op *Operator) Stats() DBStats {
+	metrics := op.db.Metrics()
+	total := metrics.Total()
+
+	return DBStats{
+		Tables:     total.NumFiles,
+		TablesSize: total.Size,
+		DiskUsage:  metrics.DiskSpaceUsage(),
+	}
+}
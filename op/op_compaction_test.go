@@ -0,0 +1,53 @@
+package op
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompaction(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	value := strings.Repeat("x", 512)
+	for i := 0; i < 5000; i++ {
+		if err := tower.SetString(fmt.Sprintf("churn:%05d", i), value); err != nil {
+			t.Fatalf("Failed to set key: %v", err)
+		}
+	}
+	if err := tower.SetString("keep", "value"); err != nil {
+		t.Fatalf("Failed to set key: %v", err)
+	}
+
+	if err := tower.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	before := tower.Stats()
+	if before.Tables == 0 || before.TablesSize == 0 || before.DiskUsage == 0 {
+		t.Fatalf("Expected flushed tables to show in stats, got %+v", before)
+	}
+
+	if n, err := tower.DeletePrefix("churn:"); err != nil || n != 5000 {
+		t.Fatalf("Expected to delete 5000 keys, got %d (%v)", n, err)
+	}
+	if err := tower.CompactAll(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+
+	after := tower.Stats()
+	if after.TablesSize >= before.TablesSize/10 {
+		t.Errorf("Expected compaction to reclaim space, tables went from %d to %d bytes", before.TablesSize, after.TablesSize)
+	}
+
+	if v, err := tower.GetString("keep"); err != nil || v != "value" {
+		t.Errorf("Expected surviving key to be intact, got %q (%v)", v, err)
+	}
+
+	if err := tower.Compact([]byte("a"), []byte("z")); err != nil {
+		t.Errorf("Failed to compact range: %v", err)
+	}
+	if err := tower.Compact([]byte("z"), []byte("a")); err == nil {
+		t.Error("Expected error for an inverted range")
+	}
+}
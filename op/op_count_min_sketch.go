@@ -0,0 +1,159 @@
+package op
+
+import (
+	"fmt"
+	"math"
+)
+
+// CreateCMS creates a count-min sketch at key. Its estimates exceed the true
+// count by at most epsilon times the total of all increments, with
+// probability 1-delta; they are never below it. The sketch has
+// ceil(e/epsilon) counters in each of ceil(ln(1/delta)) rows.
+func (op *Operator) CreateCMS(key string, epsilon, delta float64) (err error) {
+	defer op.traceOperation("CreateCMS", key)(&err)
+
+	if !(epsilon > 0 && epsilon < 1) {
+		return fmt.Errorf("epsilon must be between 0 and 1, got %v", epsilon)
+	}
+	if !(delta > 0 && delta < 1) {
+		return fmt.Errorf("delta must be between 0 and 1, got %v", delta)
+	}
+
+	width, depth, err := countMinSketchSize(epsilon, delta)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Check if already exists
+	if _, err := op.get(key); err == nil {
+		return fmt.Errorf("count-min sketch %s: %w", key, ErrContainerExists)
+	}
+
+	data := &CountMinSketchData{
+		Width:    uint32(width),
+		Depth:    uint32(depth),
+		Counters: make([]uint64, width*depth),
+	}
+
+	df := NULLDataFrame()
+	if err := df.SetCountMinSketch(data); err != nil {
+		return fmt.Errorf("failed to set count-min sketch data: %w", err)
+	}
+
+	return op.set(key, df)
+}
+
+// CMSIncr adds count occurrences of item to the sketch at key. Counters
+// saturate instead of wrapping around.
+func (op *Operator) CMSIncr(key string, item PrimitiveData, count uint64) (err error) {
+	defer op.traceOperation("CMSIncr", key)(&err)
+
+	if count == 0 {
+		return fmt.Errorf("count must be positive")
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("count-min sketch %s does not exist: %w", key, err)
+	}
+
+	cd, err := df.CountMinSketch()
+	if err != nil {
+		return fmt.Errorf("failed to get count-min sketch data: %w", err)
+	}
+
+	cells, err := cd.cells(item)
+	if err != nil {
+		return err
+	}
+	for _, cell := range cells {
+		cd.Counters[cell] = saturatingAdd(cd.Counters[cell], count)
+	}
+	cd.Total = saturatingAdd(cd.Total, count)
+
+	if err := df.SetCountMinSketch(cd); err != nil {
+		return fmt.Errorf("failed to update count-min sketch data: %w", err)
+	}
+
+	return op.set(key, df)
+}
+
+// CMSQuery returns the estimated number of occurrences of item in the sketch
+// at key. The estimate is never below the true count.
+func (op *Operator) CMSQuery(key string, item PrimitiveData) (_ uint64, err error) {
+	defer op.traceOperation("CMSQuery", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return 0, fmt.Errorf("count-min sketch %s does not exist: %w", key, err)
+	}
+
+	cd, err := df.CountMinSketch()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get count-min sketch data: %w", err)
+	}
+
+	cells, err := cd.cells(item)
+	if err != nil {
+		return 0, err
+	}
+
+	estimate := uint64(math.MaxUint64)
+	for _, cell := range cells {
+		estimate = min(estimate, cd.Counters[cell])
+	}
+
+	return estimate, nil
+}
+
+// cells returns the counter of item in each row, indexed into Counters. The
+// row positions come from the same double hashing as Bloom filter bits.
+func (cd *CountMinSketchData) cells(item PrimitiveData) ([]uint64, error) {
+	indexes, err := bloomFilterBitIndexes(item, int(cd.Depth), uint64(cd.Width))
+	if err != nil {
+		return nil, fmt.Errorf("invalid count-min sketch item: %w", err)
+	}
+
+	for row := range indexes {
+		indexes[row] += uint64(row) * uint64(cd.Width)
+	}
+
+	return indexes, nil
+}
+
+// countMinSketchSize returns the row width and row count that bound the
+// overestimate by epsilon of the total with probability 1-delta.
+func countMinSketchSize(epsilon, delta float64) (width, depth uint64, err error) {
+	w := math.Ceil(math.E / epsilon)
+	d := max(math.Ceil(math.Log(1/delta)), 1)
+	if w*d*8 > math.MaxUint32 {
+		return 0, 0, fmt.Errorf("count-min sketch of %vx%v counters is too large", d, w)
+	}
+
+	return uint64(w), uint64(d), nil
+}
+
+func saturatingAdd(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
@@ -0,0 +1,72 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCountMinSketch(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	const epsilon = 0.001
+
+	key := "requests"
+	if err := tower.CreateCMS(key, epsilon, 0.01); err != nil {
+		t.Fatalf("Failed to create count-min sketch: %v", err)
+	}
+	if err := tower.CreateCMS(key, epsilon, 0.01); !errors.Is(err, ErrContainerExists) {
+		t.Errorf("Expected ErrContainerExists, got %v", err)
+	}
+	for _, tt := range []struct{ epsilon, delta float64 }{{0, 0.01}, {1, 0.01}, {0.01, 0}, {0.01, 1}, {1e-12, 0.01}} {
+		if err := tower.CreateCMS("invalid", tt.epsilon, tt.delta); err == nil {
+			t.Errorf("Expected error for epsilon %v and delta %v", tt.epsilon, tt.delta)
+		}
+	}
+
+	// Zipf-like traffic: client i sends 10000/(i+1) requests
+	const clients = 2000
+	counts := make([]uint64, clients)
+	var total uint64
+	for i := range counts {
+		counts[i] = uint64(10000 / (i + 1))
+		total += counts[i]
+		if err := tower.CMSIncr(key, PrimitiveString(fmt.Sprintf("client-%d", i)), counts[i]); err != nil {
+			t.Fatalf("Failed to increment: %v", err)
+		}
+	}
+	// Counts accumulate across calls
+	if err := tower.CMSIncr(key, PrimitiveString("client-0"), 5); err != nil {
+		t.Fatalf("Failed to increment: %v", err)
+	}
+	counts[0] += 5
+	total += 5
+
+	bound := uint64(epsilon * float64(total))
+	for i, want := range counts {
+		got, err := tower.CMSQuery(key, PrimitiveString(fmt.Sprintf("client-%d", i)))
+		if err != nil {
+			t.Fatalf("Failed to query: %v", err)
+		}
+		if got < want {
+			t.Fatalf("client-%d: estimate %d is below the true count %d", i, got, want)
+		}
+		// The heavy hitters stay within the error bound
+		if i < 10 && got-want > bound {
+			t.Errorf("client-%d: estimate %d exceeds %d by more than %d", i, got, want, bound)
+		}
+	}
+
+	// An item that was never added reads as a small count
+	if got, err := tower.CMSQuery(key, PrimitiveInt(42)); err != nil || got > bound {
+		t.Errorf("Expected an unseen item to stay within %d, got %d (%v)", bound, got, err)
+	}
+
+	if err := tower.CMSIncr(key, PrimitiveString("client-0"), 0); err == nil {
+		t.Error("Expected error for a zero count")
+	}
+	if _, err := tower.CMSQuery("missing", PrimitiveString("x")); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
@@ -11,12 +11,17 @@ import (
 // ================================
 
 // SetDecimal sets a decimal value for the given key
-func (op *Operator) SetDecimal(key string, coefficient *big.Int, scale int32) error {
-	unlock := op.lock(key)
+func (op *Operator) SetDecimal(key string, coefficient *big.Int, scale int32) (err error) {
+	defer op.traceOperation("SetDecimal", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
-	err := df.SetDecimal(coefficient, scale)
+	err = df.SetDecimal(coefficient, scale)
 	if err != nil {
 		return fmt.Errorf("failed to set decimal: %w", err)
 	}
@@ -25,8 +30,13 @@ func (op *Operator) SetDecimal(key string, coefficient *big.Int, scale int32) er
 }
 
 // GetDecimal retrieves a decimal value for the given key
-func (op *Operator) GetDecimal(key string) (*big.Int, int32, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetDecimal(key string) (_ *big.Int, _ int32, err error) {
+	defer op.traceOperation("GetDecimal", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer unlock()
 
 	return op.getDecimal(key)
@@ -39,15 +49,20 @@ func (op *Operator) getDecimal(key string) (coefficient *big.Int, scale int32, e
 	}
 
 	if df.Type() != TypeDecimal {
-		return nil, 0, fmt.Errorf("key %s is not a decimal", key)
+		return nil, 0, fmt.Errorf("key %s is not a decimal: %w", key, ErrTypeMismatch)
 	}
 
 	return df.Decimal()
 }
 
 // SetDecimalFromFloat sets a decimal value from a float64
-func (op *Operator) SetDecimalFromFloat(key string, value float64, scale int32) error {
-	unlock := op.lock(key)
+func (op *Operator) SetDecimalFromFloat(key string, value float64, scale int32) (err error) {
+	defer op.traceOperation("SetDecimalFromFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	if scale < 0 {
@@ -68,7 +83,7 @@ func (op *Operator) SetDecimalFromFloat(key string, value float64, scale int32)
 	coefficient, _ := f.Int(nil)
 
 	df := NULLDataFrame()
-	err := df.SetDecimal(coefficient, scale)
+	err = df.SetDecimal(coefficient, scale)
 	if err != nil {
 		return fmt.Errorf("failed to set decimal: %w", err)
 	}
@@ -77,8 +92,13 @@ func (op *Operator) SetDecimalFromFloat(key string, value float64, scale int32)
 }
 
 // GetDecimalAsFloat retrieves a decimal value as float64
-func (op *Operator) GetDecimalAsFloat(key string) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetDecimalAsFloat(key string) (_ float64, err error) {
+	defer op.traceOperation("GetDecimalAsFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	coefficient, scale, err := op.getDecimal(key)
@@ -97,8 +117,13 @@ func (op *Operator) GetDecimalAsFloat(key string) (float64, error) {
 }
 
 // AddDecimal adds a decimal value to the decimal stored at key
-func (op *Operator) AddDecimal(key string, deltaCoefficient *big.Int, deltaScale int32) (*big.Int, int32, error) {
-	unlock := op.lock(key)
+func (op *Operator) AddDecimal(key string, deltaCoefficient *big.Int, deltaScale int32) (_ *big.Int, _ int32, err error) {
+	defer op.traceOperation("AddDecimal", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -107,7 +132,7 @@ func (op *Operator) AddDecimal(key string, deltaCoefficient *big.Int, deltaScale
 	}
 
 	if df.Type() != TypeDecimal {
-		return nil, 0, fmt.Errorf("key %s is not a decimal", key)
+		return nil, 0, fmt.Errorf("key %s is not a decimal: %w", key, ErrTypeMismatch)
 	}
 
 	currentCoeff, currentScale, err := df.Decimal()
@@ -132,8 +157,13 @@ func (op *Operator) AddDecimal(key string, deltaCoefficient *big.Int, deltaScale
 }
 
 // SubDecimal subtracts a decimal value from the decimal stored at key
-func (op *Operator) SubDecimal(key string, deltaCoefficient *big.Int, deltaScale int32) (*big.Int, int32, error) {
-	unlock := op.lock(key)
+func (op *Operator) SubDecimal(key string, deltaCoefficient *big.Int, deltaScale int32) (_ *big.Int, _ int32, err error) {
+	defer op.traceOperation("SubDecimal", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -142,7 +172,7 @@ func (op *Operator) SubDecimal(key string, deltaCoefficient *big.Int, deltaScale
 	}
 
 	if df.Type() != TypeDecimal {
-		return nil, 0, fmt.Errorf("key %s is not a decimal", key)
+		return nil, 0, fmt.Errorf("key %s is not a decimal: %w", key, ErrTypeMismatch)
 	}
 
 	currentCoeff, currentScale, err := df.Decimal()
@@ -168,8 +198,13 @@ func (op *Operator) SubDecimal(key string, deltaCoefficient *big.Int, deltaScale
 }
 
 // MulDecimal multiplies the decimal stored at key by a factor
-func (op *Operator) MulDecimal(key string, factorCoefficient *big.Int, factorScale int32) (*big.Int, int32, error) {
-	unlock := op.lock(key)
+func (op *Operator) MulDecimal(key string, factorCoefficient *big.Int, factorScale int32) (_ *big.Int, _ int32, err error) {
+	defer op.traceOperation("MulDecimal", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -178,7 +213,7 @@ func (op *Operator) MulDecimal(key string, factorCoefficient *big.Int, factorSca
 	}
 
 	if df.Type() != TypeDecimal {
-		return nil, 0, fmt.Errorf("key %s is not a decimal", key)
+		return nil, 0, fmt.Errorf("key %s is not a decimal: %w", key, ErrTypeMismatch)
 	}
 
 	currentCoeff, currentScale, err := df.Decimal()
@@ -203,13 +238,22 @@ func (op *Operator) MulDecimal(key string, factorCoefficient *big.Int, factorSca
 	return resultCoeff, resultScale, nil
 }
 
-// DivDecimal divides the decimal stored at key by a divisor
-func (op *Operator) DivDecimal(key string, divisorCoefficient *big.Int, divisorScale int32, resultScale int32) (*big.Int, int32, error) {
-	unlock := op.lock(key)
+// DivDecimal divides the decimal stored at key by a divisor and stores the
+// quotient at resultScale, rounding half away from zero (half-up)
+func (op *Operator) DivDecimal(key string, divisorCoefficient *big.Int, divisorScale int32, resultScale int32) (_ *big.Int, _ int32, err error) {
+	defer op.traceOperation("DivDecimal", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer unlock()
 
-	if divisorCoefficient.Sign() == 0 {
-		return nil, 0, fmt.Errorf("division by zero")
+	if divisorCoefficient == nil || divisorCoefficient.Sign() == 0 {
+		return nil, 0, ErrDivisionByZero
+	}
+	if resultScale < 0 {
+		return nil, 0, fmt.Errorf("result scale cannot be negative")
 	}
 
 	df, err := op.get(key)
@@ -218,7 +262,7 @@ func (op *Operator) DivDecimal(key string, divisorCoefficient *big.Int, divisorS
 	}
 
 	if df.Type() != TypeDecimal {
-		return nil, 0, fmt.Errorf("key %s is not a decimal", key)
+		return nil, 0, fmt.Errorf("key %s is not a decimal: %w", key, ErrTypeMismatch)
 	}
 
 	currentCoeff, currentScale, err := df.Decimal()
@@ -244,7 +288,7 @@ func (op *Operator) DivDecimal(key string, divisorCoefficient *big.Int, divisorS
 	}
 
 	// Perform the division
-	resultCoeff := new(big.Int).Div(cDividend, cDivisor)
+	resultCoeff := divideHalfUp(cDividend, cDivisor)
 
 	err = df.SetDecimal(resultCoeff, resultScale)
 	if err != nil {
@@ -260,8 +304,13 @@ func (op *Operator) DivDecimal(key string, divisorCoefficient *big.Int, divisorS
 }
 
 // CmpDecimal compares the decimal stored at key with another decimal
-func (op *Operator) CmpDecimal(key string, otherCoefficient *big.Int, otherScale int32) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) CmpDecimal(key string, otherCoefficient *big.Int, otherScale int32) (_ int, err error) {
+	defer op.traceOperation("CmpDecimal", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -270,7 +319,7 @@ func (op *Operator) CmpDecimal(key string, otherCoefficient *big.Int, otherScale
 	}
 
 	if df.Type() != TypeDecimal {
-		return 0, fmt.Errorf("key %s is not a decimal", key)
+		return 0, fmt.Errorf("key %s is not a decimal: %w", key, ErrTypeMismatch)
 	}
 
 	currentCoeff, currentScale, err := df.Decimal()
@@ -281,6 +330,58 @@ func (op *Operator) CmpDecimal(key string, otherCoefficient *big.Int, otherScale
 	return compareDecimals(currentCoeff, currentScale, otherCoefficient, otherScale), nil
 }
 
+// CompareDecimal compares the decimal stored at key with coefficient and
+// scale and returns -1, 0 or 1 like CompareInt. Scales are aligned first, so
+// 1.50 and 1.5 compare equal.
+func (op *Operator) CompareDecimal(key string, coefficient *big.Int, scale int32) (_ int, err error) {
+	defer op.traceOperation("CompareDecimal", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	currentCoeff, currentScale, err := op.getDecimal(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return compareDecimals(currentCoeff, currentScale, coefficient, scale), nil
+}
+
+// SetDecimalIfGreater stores coefficient and scale at key if they are greater
+// than the decimal stored there, and returns the decimal stored afterwards.
+func (op *Operator) SetDecimalIfGreater(key string, coefficient *big.Int, scale int32) (_ *big.Int, _ int32, err error) {
+	defer op.traceOperation("SetDecimalIfGreater", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer unlock()
+
+	df := NULLDataFrame()
+	if err := df.SetDecimal(coefficient, scale); err != nil {
+		return nil, 0, fmt.Errorf("failed to set decimal: %w", err)
+	}
+
+	currentCoeff, currentScale, err := op.getDecimal(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if compareDecimals(coefficient, scale, currentCoeff, currentScale) <= 0 {
+		return currentCoeff, currentScale, nil
+	}
+
+	if err := op.set(key, df); err != nil {
+		return nil, 0, fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return new(big.Int).Set(coefficient), scale, nil
+}
+
 // ================================
 // Helper Functions for Decimal Operations
 // ================================
@@ -292,6 +393,27 @@ func addDecimals(coeff1 *big.Int, scale1 int32, coeff2 *big.Int, scale2 int32) (
 	return result, finalScale
 }
 
+// divideHalfUp returns dividend / divisor rounded half away from zero
+func divideHalfUp(dividend, divisor *big.Int) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(dividend, divisor, new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	// Round away from zero when the remainder is at least half the divisor
+	twice := new(big.Int).Abs(remainder)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(new(big.Int).Abs(divisor)) >= 0 {
+		if dividend.Sign() == divisor.Sign() {
+			quotient.Add(quotient, big.NewInt(1))
+		} else {
+			quotient.Sub(quotient, big.NewInt(1))
+		}
+	}
+
+	return quotient
+}
+
 // compareDecimals compares two decimals
 func compareDecimals(coeff1 *big.Int, scale1 int32, coeff2 *big.Int, scale2 int32) int {
 	alignedCoeff1, alignedCoeff2, _ := alignDecimals(coeff1, scale1, coeff2, scale2)
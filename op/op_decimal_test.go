@@ -1,6 +1,7 @@
 ﻿package op
 
 import (
+	"errors"
 	"math"
 	"math/big"
 	"testing"
@@ -127,3 +128,143 @@ func TestDecimalOperations(t *testing.T) {
 	})
 }
 
+func TestDecimalArithmetic(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	t.Run("scale alignment", func(t *testing.T) {
+		key := "aligned_decimal"
+		// 1.5 + 2.25 = 3.75
+		if err := tower.SetDecimal(key, big.NewInt(15), 1); err != nil {
+			t.Fatalf("Failed to set decimal: %v", err)
+		}
+		coeff, scale, err := tower.AddDecimal(key, big.NewInt(225), 2)
+		if err != nil || coeff.Cmp(big.NewInt(375)) != 0 || scale != 2 {
+			t.Errorf("Expected (375, 2), got (%v, %d) (%v)", coeff, scale, err)
+		}
+
+		// 3.75 - 4.125 = -0.375
+		coeff, scale, err = tower.SubDecimal(key, big.NewInt(4125), 3)
+		if err != nil || coeff.Cmp(big.NewInt(-375)) != 0 || scale != 3 {
+			t.Errorf("Expected (-375, 3), got (%v, %d) (%v)", coeff, scale, err)
+		}
+
+		stored, storedScale, err := tower.GetDecimal(key)
+		if err != nil || stored.Cmp(big.NewInt(-375)) != 0 || storedScale != 3 {
+			t.Errorf("Expected stored (-375, 3), got (%v, %d) (%v)", stored, storedScale, err)
+		}
+	})
+
+	t.Run("division rounding", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			coeff        int64
+			scale        int32
+			divisor      int64
+			divisorScale int32
+			resultScale  int32
+			expected     int64
+		}{
+			{"two thirds rounds up", 2, 0, 3, 0, 2, 67},
+			{"one third rounds down", 1, 0, 3, 0, 2, 33},
+			{"exact half rounds up", 1, 0, 8, 0, 2, 13},
+			{"negative half rounds away from zero", -1, 0, 8, 0, 2, -13},
+			{"negative divisor", 2, 0, -3, 0, 2, -67},
+			{"below half keeps quotient", 1, 0, 16, 0, 1, 1},
+			{"exact division", 750, 2, 25, 1, 1, 30},
+			{"divisor scale above result scale", 100, 2, 6, 3, 0, 167},
+			{"dividend scale above result scale", 12345, 3, 1, 0, 1, 123},
+			{"rounds at scale zero", 25, 1, 1, 0, 0, 3},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				key := "div_decimal"
+				if err := tower.SetDecimal(key, big.NewInt(tt.coeff), tt.scale); err != nil {
+					t.Fatalf("Failed to set decimal: %v", err)
+				}
+
+				coeff, scale, err := tower.DivDecimal(key, big.NewInt(tt.divisor), tt.divisorScale, tt.resultScale)
+				if err != nil {
+					t.Fatalf("DivDecimal failed: %v", err)
+				}
+				if coeff.Cmp(big.NewInt(tt.expected)) != 0 || scale != tt.resultScale {
+					t.Errorf("Expected (%d, %d), got (%v, %d)", tt.expected, tt.resultScale, coeff, scale)
+				}
+			})
+		}
+	})
+
+	t.Run("division errors", func(t *testing.T) {
+		key := "div_error_decimal"
+		if err := tower.SetDecimal(key, big.NewInt(10), 0); err != nil {
+			t.Fatalf("Failed to set decimal: %v", err)
+		}
+
+		if _, _, err := tower.DivDecimal(key, big.NewInt(0), 0, 2); err == nil {
+			t.Error("Expected error for division by zero")
+		}
+		if _, _, err := tower.DivDecimal(key, big.NewInt(3), 0, -1); err == nil {
+			t.Error("Expected error for negative result scale")
+		}
+
+		coeff, scale, err := tower.GetDecimal(key)
+		if err != nil || coeff.Cmp(big.NewInt(10)) != 0 || scale != 0 {
+			t.Errorf("Expected value to be untouched, got (%v, %d) (%v)", coeff, scale, err)
+		}
+	})
+
+	t.Run("compare and set if greater", func(t *testing.T) {
+		key := "compare_decimal"
+		if err := tower.SetDecimal(key, big.NewInt(150), 2); err != nil {
+			t.Fatalf("Failed to set decimal: %v", err)
+		}
+
+		tests := []struct {
+			coeff    int64
+			scale    int32
+			expected int
+		}{
+			{15, 1, 0},    // 1.50 == 1.5
+			{1500, 3, 0},  // 1.50 == 1.500
+			{149, 2, 1},   // 1.50 > 1.49
+			{1501, 3, -1}, // 1.50 < 1.501
+			{2, 0, -1},    // 1.50 < 2
+			{-15, 1, 1},   // 1.50 > -1.5
+			{14999, 4, 1}, // 1.50 > 1.4999
+		}
+		for _, tt := range tests {
+			cmp, err := tower.CompareDecimal(key, big.NewInt(tt.coeff), tt.scale)
+			if err != nil {
+				t.Fatalf("CompareDecimal failed: %v", err)
+			}
+			if cmp != tt.expected {
+				t.Errorf("CompareDecimal(%d, %d): expected %d, got %d", tt.coeff, tt.scale, tt.expected, cmp)
+			}
+		}
+
+		// An equal value at another scale does not replace the stored one
+		coeff, scale, err := tower.SetDecimalIfGreater(key, big.NewInt(15), 1)
+		if err != nil || coeff.Cmp(big.NewInt(150)) != 0 || scale != 2 {
+			t.Errorf("Expected (150, 2) to stay, got (%v, %d) (%v)", coeff, scale, err)
+		}
+		coeff, scale, err = tower.SetDecimalIfGreater(key, big.NewInt(1501), 3)
+		if err != nil || coeff.Cmp(big.NewInt(1501)) != 0 || scale != 3 {
+			t.Errorf("Expected (1501, 3), got (%v, %d) (%v)", coeff, scale, err)
+		}
+		coeff, scale, err = tower.GetDecimal(key)
+		if err != nil || coeff.Cmp(big.NewInt(1501)) != 0 || scale != 3 {
+			t.Errorf("Expected stored (1501, 3), got (%v, %d) (%v)", coeff, scale, err)
+		}
+
+		if _, err := tower.CompareDecimal("missing_decimal", big.NewInt(1), 0); err == nil {
+			t.Error("Expected error for missing key")
+		}
+		if err := tower.SetInt("int_not_decimal", 1); err != nil {
+			t.Fatalf("Failed to set int: %v", err)
+		}
+		if _, _, err := tower.SetDecimalIfGreater("int_not_decimal", big.NewInt(2), 0); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("Expected ErrTypeMismatch, got %v", err)
+		}
+	})
+}
@@ -5,8 +5,13 @@ import (
 	"time"
 )
 
-func (op *Operator) SetDuration(key string, value time.Duration) error {
-	unlock := op.lock(key)
+func (op *Operator) SetDuration(key string, value time.Duration) (err error) {
+	defer op.traceOperation("SetDuration", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -21,8 +26,13 @@ func (op *Operator) SetDuration(key string, value time.Duration) error {
 	return nil
 }
 
-func (op *Operator) GetDuration(key string) (time.Duration, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetDuration(key string) (_ time.Duration, err error) {
+	defer op.traceOperation("GetDuration", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -38,8 +48,13 @@ func (op *Operator) GetDuration(key string) (time.Duration, error) {
 	return value, nil
 }
 
-func (op *Operator) AddDuration(key string, delta time.Duration) (time.Duration, error) {
-	unlock := op.lock(key)
+func (op *Operator) AddDuration(key string, delta time.Duration) (_ time.Duration, err error) {
+	defer op.traceOperation("AddDuration", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -68,8 +83,13 @@ func (op *Operator) SubDuration(key string, delta time.Duration) (time.Duration,
 	return op.AddDuration(key, -delta)
 }
 
-func (op *Operator) MulDuration(key string, factor int64) (time.Duration, error) {
-	unlock := op.lock(key)
+func (op *Operator) MulDuration(key string, factor int64) (_ time.Duration, err error) {
+	defer op.traceOperation("MulDuration", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -94,12 +114,17 @@ func (op *Operator) MulDuration(key string, factor int64) (time.Duration, error)
 	return newValue, nil
 }
 
-func (op *Operator) DivDuration(key string, divisor int64) (time.Duration, error) {
+func (op *Operator) DivDuration(key string, divisor int64) (_ time.Duration, err error) {
+	defer op.traceOperation("DivDuration", key)(&err)
+
 	if divisor == 0 {
-		return 0, fmt.Errorf("division by zero")
+		return 0, ErrDivisionByZero
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -124,8 +149,13 @@ func (op *Operator) DivDuration(key string, divisor int64) (time.Duration, error
 	return newValue, nil
 }
 
-func (op *Operator) NegDuration(key string) (time.Duration, error) {
-	unlock := op.lock(key)
+func (op *Operator) NegDuration(key string) (_ time.Duration, err error) {
+	defer op.traceOperation("NegDuration", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -150,8 +180,13 @@ func (op *Operator) NegDuration(key string) (time.Duration, error) {
 	return newValue, nil
 }
 
-func (op *Operator) AbsDuration(key string) (time.Duration, error) {
-	unlock := op.lock(key)
+func (op *Operator) AbsDuration(key string) (_ time.Duration, err error) {
+	defer op.traceOperation("AbsDuration", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -179,8 +214,13 @@ func (op *Operator) AbsDuration(key string) (time.Duration, error) {
 	return newValue, nil
 }
 
-func (op *Operator) SwapDuration(key string, newValue time.Duration) (time.Duration, error) {
-	unlock := op.lock(key)
+func (op *Operator) SwapDuration(key string, newValue time.Duration) (_ time.Duration, err error) {
+	defer op.traceOperation("SwapDuration", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -204,8 +244,13 @@ func (op *Operator) SwapDuration(key string, newValue time.Duration) (time.Durat
 	return current, nil
 }
 
-func (op *Operator) CompareDuration(key string, value time.Duration) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareDuration(key string, value time.Duration) (_ int, err error) {
+	defer op.traceOperation("CompareDuration", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -226,8 +271,13 @@ func (op *Operator) CompareDuration(key string, value time.Duration) (int, error
 	return 0, nil
 }
 
-func (op *Operator) SetDurationIfGreater(key string, value time.Duration) (time.Duration, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetDurationIfGreater(key string, value time.Duration) (_ time.Duration, err error) {
+	defer op.traceOperation("SetDurationIfGreater", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -252,8 +302,13 @@ func (op *Operator) SetDurationIfGreater(key string, value time.Duration) (time.
 	return current, nil
 }
 
-func (op *Operator) SetDurationIfLess(key string, value time.Duration) (time.Duration, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetDurationIfLess(key string, value time.Duration) (_ time.Duration, err error) {
+	defer op.traceOperation("SetDurationIfLess", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -278,8 +333,13 @@ func (op *Operator) SetDurationIfLess(key string, value time.Duration) (time.Dur
 	return current, nil
 }
 
-func (op *Operator) SetDurationIfEqual(key string, expected, newValue time.Duration) (time.Duration, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetDurationIfEqual(key string, expected, newValue time.Duration) (_ time.Duration, err error) {
+	defer op.traceOperation("SetDurationIfEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
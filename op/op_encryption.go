@@ -0,0 +1,146 @@
+package op
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// RotateEncryptionKey re-encrypts every stored value under newKey. oldKey is
+// the key the values were written with; values written before encryption was
+// turned on are read as plaintext either way. An empty newKey decrypts the
+// store back to plaintext.
+//
+// New writes are sealed with newKey as soon as rotation starts, and values
+// under either key stay readable while it runs. If it fails, both keys stay
+// readable and it can be run again with the same arguments.
+func (op *Operator) RotateEncryptionKey(oldKey, newKey []byte) (err error) {
+	defer op.traceOperation("RotateEncryptionKey", "")(&err)
+
+	var oldAEAD, newAEAD cipher.AEAD
+	if len(oldKey) > 0 {
+		if oldAEAD, err = newPayloadCipher(oldKey); err != nil {
+			return fmt.Errorf("invalid old encryption key: %w", err)
+		}
+	}
+	if len(newKey) > 0 {
+		if newAEAD, err = newPayloadCipher(newKey); err != nil {
+			return fmt.Errorf("invalid new encryption key: %w", err)
+		}
+	}
+
+	// Keep whatever the operator could already open, so values written
+	// under a key other than oldKey don't become unreadable mid-rotation
+	rotating := &payloadKeyring{seal: newAEAD}
+	if newAEAD != nil {
+		rotating.open = append(rotating.open, newAEAD)
+	}
+	if oldAEAD != nil {
+		rotating.open = append(rotating.open, oldAEAD)
+	}
+	if current := op.keys.Load(); current != nil {
+		rotating.open = append(rotating.open, current.open...)
+	}
+	op.storeKeyring(rotating)
+
+	if err := op.reencryptAll(); err != nil {
+		return fmt.Errorf("failed to rotate encryption key: %w", err)
+	}
+
+	if err := op.db.Flush(); err != nil {
+		return fmt.Errorf("failed to rotate encryption key: %w", err)
+	}
+
+	final := &payloadKeyring{seal: newAEAD}
+	if newAEAD != nil {
+		final.open = []cipher.AEAD{newAEAD}
+	}
+	op.storeKeyring(final)
+
+	return nil
+}
+
+func (op *Operator) storeKeyring(keys *payloadKeyring) {
+	if keys.seal == nil && len(keys.open) == 0 {
+		op.keys.Store(nil)
+		return
+	}
+	op.keys.Store(keys)
+}
+
+// reencryptAll rewrites every value with the current keyring. Each value is
+// read and written under the lock of the key that owns it, so concurrent
+// writes are never overwritten with stale data.
+func (op *Operator) reencryptAll() error {
+	iter, err := op.db.NewIter(&pebble.IterOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := op.ctxErr(); err != nil {
+			return fmt.Errorf("iterator error: %w", err)
+		}
+
+		key := string(iter.Key())
+		if err := op.reencrypt(key); err != nil {
+			return err
+		}
+	}
+
+	return iter.Error()
+}
+
+func (op *Operator) reencrypt(key string) error {
+	unlock, err := op.lock(itemOwnerKey(key))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := op.ctxErr(); err != nil {
+		return fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	data, closer, err := op.db.Get([]byte(key))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	// Expired values are rewritten too and left to the TTL sweep
+	df, err := op.unmarshal(key, data)
+	closer.Close()
+	if err != nil && IsDataframeExpiredError(err) == nil {
+		return fmt.Errorf("failed to unmarshal dataframe for key %s: %w", key, err)
+	}
+
+	sealed, err := op.marshal(key, df)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dataframe for key %s: %w", key, err)
+	}
+
+	if err := op.db.Set([]byte(key), sealed, pebble.NoSync); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// itemOwnerKey returns the top-level key an item key is stored under, or key
+// itself when it is not an item key.
+func itemOwnerKey(key string) string {
+	owner := key
+	for _, marker := range itemKeyMarkers {
+		if i := strings.Index(key, marker); i >= 0 && i < len(owner) {
+			owner = key[:i]
+		}
+	}
+	return owner
+}
@@ -0,0 +1,286 @@
+package op
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+
+	"github.com/rivulet-io/tower/util/size"
+)
+
+func TestEncryption(t *testing.T) {
+	keyA := bytes.Repeat([]byte{0xA5}, 32)
+	keyB := bytes.Repeat([]byte{0x5A}, 32)
+	secret := "correct horse battery staple"
+
+	open := func(t *testing.T, fs vfs.FS, key []byte, codec Codec, compact bool) *Operator {
+		t.Helper()
+		tower, err := NewOperator(&Options{
+			Path:            "encrypted.db",
+			CacheSize:       size.NewSizeFromMegabytes(8),
+			MemTableSize:    size.NewSizeFromMegabytes(16),
+			FS:              fs,
+			EncryptionKey:   key,
+			Codec:           codec,
+			CompactEncoding: compact,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create tower: %v", err)
+		}
+		return tower
+	}
+
+	raw := func(t *testing.T, tower *Operator, key string) []byte {
+		t.Helper()
+		data, closer, err := tower.db.Get([]byte(key))
+		if err != nil {
+			t.Fatalf("Failed to read raw value: %v", err)
+		}
+		defer closer.Close()
+		return append([]byte(nil), data...)
+	}
+
+	// assertSealed fails for every stored value that contains the secret
+	assertSealed := func(t *testing.T, tower *Operator) {
+		t.Helper()
+		iter, err := tower.db.NewIter(nil)
+		if err != nil {
+			t.Fatalf("Failed to create iterator: %v", err)
+		}
+		defer iter.Close()
+		for iter.First(); iter.Valid(); iter.Next() {
+			if bytes.Contains(iter.Value(), []byte(secret)) {
+				t.Errorf("Expected %q to be stored encrypted", iter.Key())
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		codec   Codec
+		compact bool
+	}{
+		{"plain", nil, false},
+		{"compact", nil, true},
+		{"zstd", ZstdCodec{}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tower := open(t, InMemory(), keyA, tc.codec, tc.compact)
+			defer tower.Close()
+
+			if err := tower.SetString("secret", secret); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+			if err := tower.CreateList("list"); err != nil {
+				t.Fatalf("Failed to create list: %v", err)
+			}
+			if _, err := tower.PushRightList("list", PrimitiveString(secret)); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+			assertSealed(t, tower)
+
+			// A random nonce per write means equal values never look alike
+			if err := tower.SetString("other", secret); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+			if bytes.Equal(raw(t, tower, "secret"), raw(t, tower, "other")) {
+				t.Error("Expected equal values to be stored differently")
+			}
+
+			if s, err := tower.GetString("secret"); err != nil || s != secret {
+				t.Errorf("Expected %q, got %q (%v)", secret, s, err)
+			}
+			if item, err := tower.GetListIndex("list", 0); err != nil {
+				t.Errorf("Failed to get list item: %v", err)
+			} else if s, _ := item.String(); s != secret {
+				t.Errorf("Expected %q, got %q", secret, s)
+			}
+			if typ, err := tower.TypeOf("secret"); err != nil || typ != TypeString {
+				t.Errorf("Expected TypeString, got %v (%v)", typ, err)
+			}
+		})
+	}
+
+	t.Run("wrong key", func(t *testing.T) {
+		fs := InMemory()
+		tower := open(t, fs, keyA, nil, false)
+		if err := tower.SetString("secret", secret); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		tower.Close()
+
+		tower = open(t, fs, keyB, nil, false)
+		if _, err := tower.GetString("secret"); !errors.Is(err, ErrDecryptionFailed) {
+			t.Errorf("Expected ErrDecryptionFailed, got %v", err)
+		}
+		tower.Close()
+
+		tower = open(t, fs, nil, nil, false)
+		if _, err := tower.GetString("secret"); !errors.Is(err, ErrEncryptionKeyRequired) {
+			t.Errorf("Expected ErrEncryptionKeyRequired, got %v", err)
+		}
+		tower.Close()
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		if _, err := NewOperator(&Options{Path: "encrypted.db", FS: InMemory(), EncryptionKey: []byte("short")}); err == nil {
+			t.Error("Expected error for a key that is not 32 bytes")
+		}
+	})
+
+	t.Run("migrate and rotate", func(t *testing.T) {
+		fs := InMemory()
+		tower := open(t, fs, nil, nil, false)
+		if err := tower.SetString("legacy", secret); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if err := tower.CreateMap("map"); err != nil {
+			t.Fatalf("Failed to create map: %v", err)
+		}
+		if err := tower.SetMapKey("map", PrimitiveString("field"), PrimitiveString(secret)); err != nil {
+			t.Fatalf("Failed to set map key: %v", err)
+		}
+		tower.Close()
+
+		// Plaintext written before the key was set stays readable
+		tower = open(t, fs, keyA, nil, false)
+		if s, err := tower.GetString("legacy"); err != nil || s != secret {
+			t.Errorf("Expected %q, got %q (%v)", secret, s, err)
+		}
+		if !bytes.Contains(raw(t, tower, "legacy"), []byte(secret)) {
+			t.Fatal("Expected legacy value to still be plaintext")
+		}
+
+		if err := tower.RotateEncryptionKey(nil, keyA); err != nil {
+			t.Fatalf("Failed to encrypt store: %v", err)
+		}
+		assertSealed(t, tower)
+
+		if err := tower.RotateEncryptionKey(keyA, keyB); err != nil {
+			t.Fatalf("Failed to rotate key: %v", err)
+		}
+		if s, err := tower.GetString("legacy"); err != nil || s != secret {
+			t.Errorf("Expected %q after rotation, got %q (%v)", secret, s, err)
+		}
+		tower.Close()
+
+		tower = open(t, fs, keyA, nil, false)
+		if _, err := tower.GetString("legacy"); !errors.Is(err, ErrDecryptionFailed) {
+			t.Errorf("Expected old key to be rejected, got %v", err)
+		}
+		tower.Close()
+
+		tower = open(t, fs, keyB, nil, false)
+		defer tower.Close()
+		if value, err := tower.GetMapKey("map", PrimitiveString("field")); err != nil {
+			t.Errorf("Failed to get map key with the new key: %v", err)
+		} else if s, _ := value.String(); s != secret {
+			t.Errorf("Expected %q, got %q", secret, s)
+		}
+	})
+
+	t.Run("header and key are authenticated", func(t *testing.T) {
+		tower := open(t, InMemory(), keyA, nil, false)
+		defer tower.Close()
+
+		for _, key := range []string{"a", "b"} {
+			if err := tower.SetString(key, secret); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+		}
+		original := raw(t, tower, "a")
+
+		tamper := []struct {
+			name   string
+			modify func(data []byte)
+		}{
+			{"type", func(data []byte) { data[2] = byte(TypeBinary) }},
+			{"expiry", func(data []byte) { data[dataFrameHeaderSize+7] ^= 1 }},
+		}
+		for _, tc := range tamper {
+			data := append([]byte(nil), original...)
+			tc.modify(data)
+			if err := tower.db.Set([]byte("a"), data, nil); err != nil {
+				t.Fatalf("Failed to write raw value: %v", err)
+			}
+			if _, err := tower.get("a"); !errors.Is(err, ErrDecryptionFailed) {
+				t.Errorf("Expected a changed %s to be rejected, got %v", tc.name, err)
+			}
+		}
+
+		// A value copied to another key doesn't open there
+		if err := tower.db.Set([]byte("b"), original, nil); err != nil {
+			t.Fatalf("Failed to write raw value: %v", err)
+		}
+		if _, err := tower.GetString("b"); !errors.Is(err, ErrDecryptionFailed) {
+			t.Errorf("Expected a value moved to another key to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("values sealed without header", func(t *testing.T) {
+		tower := open(t, InMemory(), keyA, nil, false)
+		defer tower.Close()
+
+		df := NULLDataFrame()
+		df.SetString(secret)
+		data, err := df.Marshal()
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+		header := data[:dataFrameHeaderSize+8]
+		header[1] |= dataFrameCodecFlag
+		sealed, err := sealPayload(df.payload, false, tower.keys.Load(), nil)
+		if err != nil {
+			t.Fatalf("Failed to seal: %v", err)
+		}
+		sealed[0] = codecIDEncrypted
+
+		if err := tower.db.Set([]byte("older"), append(header, sealed...), nil); err != nil {
+			t.Fatalf("Failed to write raw value: %v", err)
+		}
+		if s, err := tower.GetString("older"); err != nil || s != secret {
+			t.Errorf("Expected %q, got %q (%v)", secret, s, err)
+		}
+	})
+
+	t.Run("moved items", func(t *testing.T) {
+		tower := open(t, InMemory(), keyA, nil, true)
+		defer tower.Close()
+
+		if err := tower.CreateList("list"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		for _, v := range []string{"one", "three", "four"} {
+			if _, err := tower.PushRightList("list", PrimitiveString(v)); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+		}
+
+		// Inserting and removing shift items to other keys
+		if _, err := tower.ListInsertBefore("list", PrimitiveString("three"), PrimitiveString("two")); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+		if _, err := tower.ListRemove("list", 1, PrimitiveString("one")); err != nil {
+			t.Fatalf("Failed to remove: %v", err)
+		}
+		if ok, err := tower.RenameKeyNX("list", "renamed"); err != nil || !ok {
+			t.Fatalf("Failed to rename list: %v", err)
+		}
+
+		values, err := tower.GetListRange("renamed", 0, -1)
+		if err != nil {
+			t.Fatalf("Failed to get list range: %v", err)
+		}
+		var got []string
+		for _, v := range values {
+			s, _ := v.String()
+			got = append(got, s)
+		}
+		if strings.Join(got, ",") != "two,three,four" {
+			t.Errorf("Expected two,three,four, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package op
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// KeyEventKind tells what happened to the key of a KeyEvent.
+type KeyEventKind uint8
+
+const (
+	KeyEventSet KeyEventKind = iota + 1
+	KeyEventDelete
+	KeyEventExpire
+	// KeyEventOverflow stands in for events a subscriber lost because its
+	// queue was full. It has no key; Dropped tells how many were lost.
+	KeyEventOverflow
+)
+
+// subscriberQueueLimit is how many undelivered events a subscriber holds
+// before it starts dropping new ones.
+const subscriberQueueLimit = 4096
+
+// KeyEvent describes a committed change to a top-level key. Type is the type
+// written by a set and TypeNull for deletes and expirations.
+type KeyEvent struct {
+	Key     string
+	Kind    KeyEventKind
+	Type    DataType
+	Dropped int // events lost in place of a KeyEventOverflow
+}
+
+type eventBus struct {
+	mu     sync.RWMutex
+	subs   map[uint64]*subscriber
+	nextID uint64
+	active atomic.Int32
+}
+
+// subscriber queues events for its own goroutine, so publishers never wait
+// on a slow callback and each subscriber sees events in commit order.
+type subscriber struct {
+	pattern string
+	prefix  bool
+	strip   int // namespace length cut from delivered keys
+	fn      func(KeyEvent)
+
+	mu      sync.Mutex
+	queue   []KeyEvent
+	dropped int // events discarded since the queue was last taken
+	wake    chan struct{}
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[uint64]*subscriber)}
+}
+
+// Subscribe calls fn for every change to a key matching keyPattern: an exact
+// key, or a prefix followed by '*'. Events arrive asynchronously after the
+// change is committed, one at a time and in commit order. Writes to the
+// items of lists, maps and other containers are not reported themselves,
+// only the updates to the container key they cause. DeleteRange and
+// DeletePrefix report nothing. On a namespaced operator keyPattern matches
+// keys within the namespace, which events report without the prefix. cancel
+// stops delivery; events not yet delivered are dropped.
+//
+// Each subscription holds up to 4096 undelivered events. While fn falls that
+// far behind, further events are discarded, and fn is later called with a
+// single KeyEventOverflow in their place, so it can rescan the keys it
+// follows.
+func (op *Operator) Subscribe(keyPattern string, fn func(event KeyEvent)) (cancel func()) {
+	s := &subscriber{
+		pattern: keyPattern,
+		strip:   len(op.db.ns),
+		fn:      fn,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+	if strings.HasSuffix(keyPattern, "*") {
+		s.pattern, s.prefix = strings.TrimSuffix(keyPattern, "*"), true
+	}
+	s.pattern = op.db.rawKey(s.pattern)
+
+	bus := op.events
+	bus.mu.Lock()
+	id := bus.nextID
+	bus.nextID++
+	bus.subs[id] = s
+	bus.active.Add(1)
+	bus.mu.Unlock()
+
+	go s.run()
+
+	return func() {
+		bus.mu.Lock()
+		if _, ok := bus.subs[id]; ok {
+			delete(bus.subs, id)
+			bus.active.Add(-1)
+		}
+		bus.mu.Unlock()
+		s.close()
+	}
+}
+
+func (s *subscriber) run() {
+	for {
+		select {
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+
+		for {
+			s.mu.Lock()
+			events := s.queue
+			if s.dropped > 0 {
+				events = append(events, KeyEvent{Kind: KeyEventOverflow, Dropped: s.dropped})
+				s.dropped = 0
+			}
+			s.queue = nil
+			s.mu.Unlock()
+			if len(events) == 0 {
+				break
+			}
+
+			for _, event := range events {
+				select {
+				case <-s.stop:
+					return
+				default:
+				}
+				if event.Kind != KeyEventOverflow {
+					event.Key = event.Key[s.strip:]
+				}
+				s.fn(event)
+			}
+		}
+	}
+}
+
+func (s *subscriber) close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+func (s *subscriber) matches(key string) bool {
+	if s.prefix {
+		return strings.HasPrefix(key, s.pattern)
+	}
+	return key == s.pattern
+}
+
+func (s *subscriber) push(events []KeyEvent) {
+	s.mu.Lock()
+	for _, event := range events {
+		if !s.matches(event.Key) {
+			continue
+		}
+		if len(s.queue) >= subscriberQueueLimit {
+			s.dropped++
+			continue
+		}
+		s.queue = append(s.queue, event)
+	}
+	queued := len(s.queue) > 0
+	s.mu.Unlock()
+
+	if queued {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (b *eventBus) publish(events ...KeyEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, s := range b.subs {
+		s.push(events)
+	}
+}
+
+// close stops every subscriber.
+func (b *eventBus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, s := range b.subs {
+		delete(b.subs, id)
+		b.active.Add(-1)
+		s.close()
+	}
+}
+
+// publishKey reports a change to key unless nobody listens or key is an item
+// or system key. Events carry the stored key, namespace included.
+func (op *Operator) publishKey(key string, kind KeyEventKind, typ DataType) {
+	if op.events.active.Load() == 0 || !isEventKey(key) {
+		return
+	}
+
+	op.events.publish(KeyEvent{Key: op.db.rawKey(key), Kind: kind, Type: typ})
+}
+
+// publishBatch reports the sets and deletes of a committed batch. Deletes
+// are reported as expirations when expired is set.
+func (op *Operator) publishBatch(batch *storeBatch, expired bool) {
+	if op.events.active.Load() == 0 {
+		return
+	}
+
+	var events []KeyEvent
+	reader := batch.Reader()
+	for {
+		kind, ukey, value, ok, err := reader.Next()
+		if !ok || err != nil {
+			break
+		}
+
+		key := string(ukey)
+		if !isEventKey(key[len(batch.store.ns):]) {
+			continue
+		}
+
+		switch kind {
+		case pebble.InternalKeyKindSet:
+			typ, _, err := peekDataFrame(value)
+			if err != nil {
+				continue
+			}
+			events = append(events, KeyEvent{Key: key, Kind: KeyEventSet, Type: typ})
+		case pebble.InternalKeyKindDelete, pebble.InternalKeyKindSingleDelete:
+			eventKind := KeyEventDelete
+			if expired {
+				eventKind = KeyEventExpire
+			}
+			events = append(events, KeyEvent{Key: key, Kind: eventKind})
+		}
+	}
+
+	op.events.publish(events...)
+}
+
+func isEventKey(key string) bool {
+	return !strings.HasPrefix(key, systemKeyPrefix) && !isItemKey(key)
+}
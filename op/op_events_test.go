@@ -0,0 +1,162 @@
+package op
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rivulet-io/tower/util/size"
+)
+
+func TestSubscribe(t *testing.T) {
+	for _, groupCommit := range []bool{false, true} {
+		name := "direct"
+		if groupCommit {
+			name = "group commit"
+		}
+		t.Run(name, func(t *testing.T) {
+			tower, err := NewOperator(&Options{
+				Path:         "events.db",
+				FS:           InMemory(),
+				CacheSize:    size.NewSizeFromMegabytes(8),
+				MemTableSize: size.NewSizeFromMegabytes(16),
+				GroupCommit:  groupCommit,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create tower: %v", err)
+			}
+			defer tower.Close()
+
+			events := make(chan KeyEvent, 16)
+			cancel := tower.Subscribe("user:*", func(event KeyEvent) {
+				events <- event
+			})
+			exact := make(chan KeyEvent, 16)
+			defer tower.Subscribe("config", func(event KeyEvent) {
+				exact <- event
+			})()
+
+			expect := func(t *testing.T, ch chan KeyEvent, want KeyEvent) {
+				t.Helper()
+				select {
+				case got := <-ch:
+					if got != want {
+						t.Errorf("Expected %+v, got %+v", want, got)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("Timed out waiting for %+v", want)
+				}
+			}
+
+			if err := tower.SetString("order:1", "ignored"); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+			if err := tower.SetString("user:42", "ada"); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+			expect(t, events, KeyEvent{Key: "user:42", Kind: KeyEventSet, Type: TypeString})
+
+			if err := tower.Remove("user:42"); err != nil {
+				t.Fatalf("Failed to remove: %v", err)
+			}
+			expect(t, events, KeyEvent{Key: "user:42", Kind: KeyEventDelete})
+
+			// Item writes surface only as updates of the container key
+			if err := tower.CreateSortedSet("user:scores"); err != nil {
+				t.Fatalf("Failed to create sorted set: %v", err)
+			}
+			expect(t, events, KeyEvent{Key: "user:scores", Kind: KeyEventSet, Type: TypeSortedSet})
+			if _, err := tower.AddSortedSetMember("user:scores", PrimitiveString("ada"), 1); err != nil {
+				t.Fatalf("Failed to add member: %v", err)
+			}
+			expect(t, events, KeyEvent{Key: "user:scores", Kind: KeyEventSet, Type: TypeSortedSet})
+
+			if err := tower.SetInt("user:session", 1); err != nil {
+				t.Fatalf("Failed to set int: %v", err)
+			}
+			expect(t, events, KeyEvent{Key: "user:session", Kind: KeyEventSet, Type: TypeInt})
+			if err := tower.SetTTL("user:session", time.Now().Add(time.Second)); err != nil {
+				t.Fatalf("Failed to set TTL: %v", err)
+			}
+			expect(t, events, KeyEvent{Key: "user:session", Kind: KeyEventSet, Type: TypeInt})
+			time.Sleep(2 * time.Second)
+			if _, err := tower.GetInt("user:session"); err == nil {
+				t.Fatal("Expected key to have expired")
+			}
+			expect(t, events, KeyEvent{Key: "user:session", Kind: KeyEventExpire})
+
+			if err := tower.SetBool("config", true); err != nil {
+				t.Fatalf("Failed to set bool: %v", err)
+			}
+			if err := tower.SetBool("config:extra", true); err != nil {
+				t.Fatalf("Failed to set bool: %v", err)
+			}
+			expect(t, exact, KeyEvent{Key: "config", Kind: KeyEventSet, Type: TypeBool})
+
+			cancel()
+			if err := tower.SetString("user:43", "bob"); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+			select {
+			case event := <-events:
+				t.Errorf("Expected no events after cancel, got %+v", event)
+			case event := <-exact:
+				t.Errorf("Expected no further events for config, got %+v", event)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestSubscribeOverflow(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	var received []KeyEvent
+	done := make(chan struct{})
+	total := subscriberQueueLimit + 100
+
+	cancel := tower.Subscribe("*", func(event KeyEvent) {
+		if len(received) == 0 {
+			close(blocked)
+			<-release // Hold up delivery until every write is queued
+		}
+		received = append(received, event)
+		if event.Kind == KeyEventOverflow {
+			close(done)
+		}
+	})
+	defer cancel()
+
+	for i := 0; i < total; i++ {
+		if err := tower.SetInt(fmt.Sprintf("key%d", i), int64(i)); err != nil {
+			t.Fatalf("Failed to set int: %v", err)
+		}
+		if i == 0 {
+			<-blocked // The rest must queue behind the first event
+		}
+	}
+	close(release)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the overflow event, got %d events", len(received))
+	}
+
+	overflow := received[len(received)-1]
+	if overflow.Key != "" || overflow.Dropped == 0 {
+		t.Errorf("Expected an overflow event without key and with a drop count, got %+v", overflow)
+	}
+	delivered := received[:len(received)-1]
+	if len(delivered)+overflow.Dropped != total {
+		t.Errorf("Expected %d delivered and dropped events, got %d and %d", total, len(delivered), overflow.Dropped)
+	}
+	for i, event := range delivered {
+		if want := fmt.Sprintf("key%d", i); event.Key != want || event.Kind != KeyEventSet {
+			t.Fatalf("Expected set of %s in commit order, got %+v", want, event)
+		}
+	}
+}
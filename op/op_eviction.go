@@ -0,0 +1,442 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+
+	"github.com/rivulet-io/tower/util/synx"
+)
+
+type EvictionPolicy int
+
+const (
+	EvictionNone EvictionPolicy = iota
+	EvictionLRU
+	EvictionLFU
+	EvictionTTLFirst
+)
+
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictionNone:
+		return "none"
+	case EvictionLRU:
+		return "lru"
+	case EvictionLFU:
+		return "lfu"
+	case EvictionTTLFirst:
+		return "ttl-first"
+	default:
+		return fmt.Sprintf("EvictionPolicy(%d)", int(p))
+	}
+}
+
+const defaultEvictionInterval = 10 * time.Second
+
+const systemKeyPrefix = "__system__:"
+
+type keyAccess struct {
+	ns         string
+	lastAccess atomic.Int64
+	hits       atomic.Uint64
+}
+
+type evictor struct {
+	policy   EvictionPolicy
+	maxSize  int64
+	interval time.Duration
+	access   *synx.ConcurrentMap[string, *keyAccess]
+	onEvict  atomic.Pointer[func(key string)]
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newEvictor(opt *Options) *evictor {
+	if opt.EvictionPolicy == EvictionNone || opt.MaxStoreSize.Bytes() <= 0 {
+		return nil
+	}
+
+	interval := opt.EvictionInterval
+	if interval <= 0 {
+		interval = defaultEvictionInterval
+	}
+
+	return &evictor{
+		policy:   opt.EvictionPolicy,
+		maxSize:  opt.MaxStoreSize.Bytes(),
+		interval: interval,
+		access:   synx.NewConcurrentMap[string, *keyAccess](),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// touch records an access to key in the namespace ns. Accesses are tracked
+// by stored key so that namespaces sharing the evictor don't collide.
+func (e *evictor) touch(ns, key string) {
+	raw := ns + key
+	a, ok := e.access.Load(raw)
+	if !ok {
+		a, _ = e.access.LoadOrStore(raw, &keyAccess{ns: ns})
+	}
+	a.lastAccess.Store(time.Now().UnixNano())
+	a.hits.Add(1)
+}
+
+// record makes sure a written key has an access record. Reads of a missing
+// key drop the one its lock created, so a key created by a read-modify-write
+// gets it back here.
+func (e *evictor) record(ns, key string) {
+	if isSystemKey(key) || isItemKey(key) {
+		return
+	}
+	if _, ok := e.access.Load(ns + key); ok {
+		return
+	}
+
+	a := &keyAccess{ns: ns}
+	a.lastAccess.Store(time.Now().UnixNano())
+	a.hits.Add(1)
+	e.access.LoadOrStore(ns+key, a)
+}
+
+func (op *Operator) startEvictor() {
+	go func() {
+		defer close(op.evictor.done)
+
+		ticker := time.NewTicker(op.evictor.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-op.evictor.stop:
+				return
+			case <-ticker.C:
+				if _, err := op.Evict(); err != nil {
+					log.Printf("error evicting keys: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (op *Operator) stopEvictor() {
+	op.evictor.once.Do(func() {
+		close(op.evictor.stop)
+	})
+	<-op.evictor.done
+}
+
+// SetEvictionHandler registers fn to be called with every key the evictor
+// removes. It is a no-op when eviction is disabled.
+func (op *Operator) SetEvictionHandler(fn func(key string)) {
+	if op.evictor == nil {
+		return
+	}
+	op.evictor.onEvict.Store(&fn)
+}
+
+// Evict runs one eviction pass: while the store is larger than
+// Options.MaxStoreSize it removes keys in the order given by
+// Options.EvictionPolicy and returns how many were removed. Keys not
+// accessed since the operator was opened, such as those left over from a
+// previous run, go before any accessed key. Expired keys are left to the TTL
+// sweep.
+//
+// The size is the one Pebble reports for live SSTables and unflushed writes,
+// so after removing enough keys to cover the excess the pass compacts the
+// store to reclaim their space and measures again.
+func (op *Operator) Evict() (int, error) {
+	if op.evictor == nil {
+		return 0, nil
+	}
+
+	size := op.storeSize()
+	if size <= op.evictor.maxSize {
+		return 0, nil
+	}
+
+	candidates, err := op.evictionCandidates()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list eviction candidates: %w", err)
+	}
+
+	evicted := 0
+	for size > op.evictor.maxSize && len(candidates) > 0 {
+		var freed int64
+		for freed < size-op.evictor.maxSize && len(candidates) > 0 {
+			c := candidates[0]
+			candidates = candidates[1:]
+
+			key := c.ns + c.key
+			n, ok, err := op.withNamespace(c.ns).evictKey(c.key)
+			if err != nil {
+				return evicted, fmt.Errorf("failed to evict key %s: %w", key, err)
+			}
+			if !ok {
+				continue
+			}
+
+			freed += n
+			evicted++
+
+			if fn := op.evictor.onEvict.Load(); fn != nil && *fn != nil {
+				(*fn)(key)
+			}
+		}
+
+		if err := op.compactAll(); err != nil {
+			return evicted, fmt.Errorf("failed to reclaim evicted keys: %w", err)
+		}
+		size = op.storeSize()
+	}
+
+	return evicted, nil
+}
+
+type evictionCandidate struct {
+	ns         string
+	key        string
+	lastAccess int64
+	hits       uint64
+	expiresAt  int64
+}
+
+// evictionCandidates returns the keys the evictor may remove, tracked or
+// not, in the order of its policy. Untracked keys come from a scan of the
+// whole store, which only runs once the store is over budget.
+func (op *Operator) evictionCandidates() ([]evictionCandidate, error) {
+	var candidates []evictionCandidate
+	op.evictor.access.Range(func(raw string, a *keyAccess) bool {
+		key := raw[len(a.ns):]
+		if strings.HasPrefix(key, systemKeyPrefix) {
+			return true
+		}
+		candidates = append(candidates, evictionCandidate{
+			ns:         a.ns,
+			key:        key,
+			lastAccess: a.lastAccess.Load(),
+			hits:       a.hits.Load(),
+		})
+		return true
+	})
+
+	untracked, err := op.untrackedKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range untracked {
+		candidates = append(candidates, evictionCandidate{key: raw})
+	}
+
+	switch op.evictor.policy {
+	case EvictionLFU:
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].hits != candidates[j].hits {
+				return candidates[i].hits < candidates[j].hits
+			}
+			return candidates[i].lastAccess < candidates[j].lastAccess
+		})
+	case EvictionTTLFirst:
+		for i := range candidates {
+			candidates[i].expiresAt = op.withNamespace(candidates[i].ns).peekExpiresAt(candidates[i].key)
+		}
+		// Keys that expire soonest go first, then the rest by recency
+		sort.Slice(candidates, func(i, j int) bool {
+			ci, cj := candidates[i], candidates[j]
+			if (ci.expiresAt > 0) != (cj.expiresAt > 0) {
+				return ci.expiresAt > 0
+			}
+			if ci.expiresAt != cj.expiresAt {
+				return ci.expiresAt < cj.expiresAt
+			}
+			return ci.lastAccess < cj.lastAccess
+		})
+	default:
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].lastAccess < candidates[j].lastAccess
+		})
+	}
+
+	return candidates, nil
+}
+
+// untrackedKeys returns the stored top-level keys, in every namespace, that
+// have no access record.
+func (op *Operator) untrackedKeys() ([]string, error) {
+	iter, err := op.db.DB.NewIter(&pebble.IterOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var keys []string
+	for iter.First(); iter.Valid(); iter.Next() {
+		raw := string(iter.Key())
+		if isSystemKey(raw) || isItemKey(raw) {
+			continue
+		}
+		if _, ok := op.evictor.access.Load(raw); ok {
+			continue
+		}
+		keys = append(keys, raw)
+	}
+
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("iterator error: %w", err)
+	}
+
+	return keys, nil
+}
+
+// peekExpiresAt returns the expiration of key in Unix milliseconds, or 0.
+func (op *Operator) peekExpiresAt(key string) int64 {
+	data, closer, err := op.db.Get([]byte(key))
+	if err != nil {
+		return 0
+	}
+	defer closer.Close()
+
+	df, err := op.unmarshal(key, data)
+	if df == nil || err != nil {
+		return 0
+	}
+
+	if df.expiresAt.IsZero() {
+		return 0
+	}
+
+	return df.expiresAt.UnixMilli()
+}
+
+// evictKey removes key and reports how many bytes it occupied. It takes the
+// key lock directly so the eviction itself doesn't count as an access.
+func (op *Operator) evictKey(key string) (int64, bool, error) {
+	raw := op.db.rawKey(key)
+	locker, _ := op.lockers.LoadOrStore(raw, &sync.RWMutex{})
+	locker.Lock()
+	op.markHeld(raw)
+	defer func() {
+		op.held.Delete(raw)
+		locker.Unlock()
+	}()
+
+	data, closer, err := op.db.Get([]byte(key))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			op.evictor.access.Delete(raw)
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	df, err := op.unmarshal(key, data)
+	closer.Close()
+	if err != nil {
+		return 0, false, nil
+	}
+
+	size, err := op.memoryUsage(key, df)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err := op.removeKey(key, df.typ); err != nil {
+		return 0, false, err
+	}
+	op.evictor.access.Delete(raw)
+
+	return size, true, nil
+}
+
+// removeKey deletes key by type for callers already holding the key lock.
+func (op *Operator) removeKey(key string, dataType DataType) error {
+	switch dataType {
+	case TypeList:
+		return op.deleteList(key)
+	case TypeMap:
+		return op.deleteMap(key)
+	case TypeSet:
+		return op.deleteSet(key)
+	case TypeTimeseries:
+		return op.deleteTimeSeries(key)
+	case TypeBloomFilter:
+		return op.deleteBloomFilter(key)
+	case TypeSortedSet:
+		return op.deleteSortedSet(key)
+	case TypeGeo:
+		return op.deleteGeo(key)
+	}
+
+	return op.delete(key)
+}
+
+// storeSize returns the bytes held in live SSTables plus the writes still
+// only in the WAL, across every namespace. It reads Pebble's metrics rather
+// than the keys, so it costs the same however large the store is.
+func (op *Operator) storeSize() int64 {
+	m := op.db.Metrics()
+
+	size := int64(m.WAL.Size)
+	for _, level := range m.Levels {
+		size += level.Size
+	}
+
+	return size
+}
+
+// forget drops the access record of the stored key raw once it is gone, so
+// that deleted keys and lookups of missing ones don't pile up in the
+// evictor.
+func (op *Operator) forget(raw string) {
+	if op.evictor != nil {
+		op.evictor.access.Delete(raw)
+	}
+}
+
+// forgetRange drops the access records of stored keys in [lower, upper).
+func (op *Operator) forgetRange(lower, upper []byte) {
+	if op.evictor == nil {
+		return
+	}
+
+	op.evictor.access.Range(func(raw string, _ *keyAccess) bool {
+		if raw >= string(lower) && raw < string(upper) {
+			op.evictor.access.Delete(raw)
+		}
+		return true
+	})
+}
+
+// trackBatch records the keys written by batch and drops the access records
+// of those it deleted. Its range deletions only ever cover the items of
+// compound values, which have no records of their own.
+func (op *Operator) trackBatch(batch *storeBatch) {
+	if op.evictor == nil {
+		return
+	}
+
+	ns := batch.store.ns
+	reader := batch.Reader()
+	for {
+		kind, ukey, _, ok, err := reader.Next()
+		if !ok || err != nil {
+			break
+		}
+		switch kind {
+		case pebble.InternalKeyKindSet:
+			op.evictor.record(ns, string(ukey[len(ns):]))
+		case pebble.InternalKeyKindDelete, pebble.InternalKeyKindSingleDelete:
+			op.evictor.access.Delete(string(ukey))
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package op
+
+import (
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+
+	"github.com/rivulet-io/tower/util/size"
+)
+
+func createEvictionTestTower(t *testing.T, policy EvictionPolicy, maxSize int64) *Operator {
+	return openEvictionTestTower(t, InMemory(), policy, maxSize)
+}
+
+func openEvictionTestTower(t *testing.T, fs vfs.FS, policy EvictionPolicy, maxSize int64) *Operator {
+	tower, err := NewOperator(&Options{
+		Path:             "test.db",
+		BytesPerSync:     size.NewSizeFromBytes(32 * 1024),
+		CacheSize:        size.NewSizeFromMegabytes(64),
+		MemTableSize:     size.NewSizeFromMegabytes(4),
+		FS:               fs,
+		MaxStoreSize:     size.NewSizeFromBytes(maxSize),
+		EvictionPolicy:   policy,
+		EvictionInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tower: %v", err)
+	}
+	return tower
+}
+
+// storedKeys counts the raw keys left in the store, items included.
+func storedKeys(t *testing.T, tower *Operator) int {
+	iter, err := tower.db.DB.NewIter(&pebble.IterOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create iterator: %v", err)
+	}
+	defer iter.Close()
+
+	n := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		n++
+	}
+	return n
+}
+
+// randomPayload returns 1KB of random bytes, which don't compress, so the
+// stored size of a key tracks its payload.
+func randomPayload(t *testing.T) string {
+	payload := make([]byte, 1024)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatalf("Failed to generate payload: %v", err)
+	}
+	return string(payload)
+}
+
+func TestEviction(t *testing.T) {
+
+	t.Run("disabled by default", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		for i := 0; i < 10; i++ {
+			if err := tower.SetString(fmt.Sprintf("key%d", i), randomPayload(t)); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+		}
+
+		evicted, err := tower.Evict()
+		if err != nil || evicted != 0 {
+			t.Errorf("Expected (0, nil), got (%d, %v)", evicted, err)
+		}
+	})
+
+	t.Run("lru", func(t *testing.T) {
+		tower := createEvictionTestTower(t, EvictionLRU, 6000)
+		defer tower.Close()
+
+		var evictedKeys []string
+		tower.SetEvictionHandler(func(key string) {
+			evictedKeys = append(evictedKeys, key)
+		})
+
+		for i := 0; i < 10; i++ {
+			if err := tower.SetString(fmt.Sprintf("key%d", i), randomPayload(t)); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+		}
+
+		// Make key0 the most recently used
+		if _, err := tower.GetString("key0"); err != nil {
+			t.Fatalf("Failed to get string: %v", err)
+		}
+
+		evicted, err := tower.Evict()
+		if err != nil {
+			t.Fatalf("Failed to evict: %v", err)
+		}
+		if evicted == 0 {
+			t.Fatal("Expected keys to be evicted")
+		}
+		if evicted != len(evictedKeys) {
+			t.Errorf("Expected handler to see %d keys, got %d", evicted, len(evictedKeys))
+		}
+		if evictedKeys[0] != "key1" {
+			t.Errorf("Expected least recently used key1 first, got %s", evictedKeys[0])
+		}
+
+		if _, err := tower.GetString("key0"); err != nil {
+			t.Errorf("Expected recently used key0 to survive: %v", err)
+		}
+
+		if size := tower.storeSize(); size > 6000 {
+			t.Errorf("Expected store within budget, got %d bytes", size)
+		}
+	})
+
+	t.Run("lfu", func(t *testing.T) {
+		tower := createEvictionTestTower(t, EvictionLFU, 3000)
+		defer tower.Close()
+
+		for i := 0; i < 5; i++ {
+			if err := tower.SetString(fmt.Sprintf("key%d", i), randomPayload(t)); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+		}
+
+		// key4 is written last but read often
+		for i := 0; i < 5; i++ {
+			if _, err := tower.GetString("key4"); err != nil {
+				t.Fatalf("Failed to get string: %v", err)
+			}
+		}
+
+		if _, err := tower.Evict(); err != nil {
+			t.Fatalf("Failed to evict: %v", err)
+		}
+
+		if _, err := tower.GetString("key4"); err != nil {
+			t.Errorf("Expected frequently used key4 to survive: %v", err)
+		}
+		if _, err := tower.GetString("key0"); err == nil {
+			t.Error("Expected rarely used key0 to be evicted")
+		}
+	})
+
+	t.Run("ttl first", func(t *testing.T) {
+		tower := createEvictionTestTower(t, EvictionTTLFirst, 3000)
+		defer tower.Close()
+
+		for i := 0; i < 5; i++ {
+			if err := tower.SetString(fmt.Sprintf("key%d", i), randomPayload(t)); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+		}
+
+		if err := tower.SetTTL("key4", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("Failed to set TTL: %v", err)
+		}
+
+		var evictedKeys []string
+		tower.SetEvictionHandler(func(key string) {
+			evictedKeys = append(evictedKeys, key)
+		})
+
+		if _, err := tower.Evict(); err != nil {
+			t.Fatalf("Failed to evict: %v", err)
+		}
+		if len(evictedKeys) == 0 || evictedKeys[0] != "key4" {
+			t.Errorf("Expected key with TTL to be evicted first, got %v", evictedKeys)
+		}
+	})
+
+	t.Run("compound structures", func(t *testing.T) {
+		tower := createEvictionTestTower(t, EvictionLRU, 300)
+		defer tower.Close()
+
+		if err := tower.CreateList("list"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		for i := 0; i < 5; i++ {
+			if _, err := tower.PushRightList("list", PrimitiveString(randomPayload(t))); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+		}
+
+		if _, err := tower.Evict(); err != nil {
+			t.Fatalf("Failed to evict: %v", err)
+		}
+
+		if n := storedKeys(t, tower); n != 0 {
+			t.Errorf("Expected list and all its items to be evicted, %d keys left", n)
+		}
+	})
+
+	t.Run("reopened over budget", func(t *testing.T) {
+		fs := InMemory()
+
+		tower := openEvictionTestTower(t, fs, EvictionNone, 0)
+		for i := 0; i < 10; i++ {
+			if err := tower.SetString(fmt.Sprintf("key%d", i), randomPayload(t)); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+		}
+		if err := tower.Close(); err != nil {
+			t.Fatalf("Failed to close tower: %v", err)
+		}
+
+		tower = openEvictionTestTower(t, fs, EvictionLRU, 6000)
+		defer tower.Close()
+
+		// A key read after reopening outlives the ones never touched
+		if _, err := tower.GetString("key0"); err != nil {
+			t.Fatalf("Failed to get string: %v", err)
+		}
+
+		evicted, err := tower.Evict()
+		if err != nil {
+			t.Fatalf("Failed to evict: %v", err)
+		}
+		if evicted == 0 {
+			t.Fatal("Expected keys written before reopening to be evicted")
+		}
+		if size := tower.storeSize(); size > 6000 {
+			t.Errorf("Expected store within budget, got %d bytes", size)
+		}
+		if _, err := tower.GetString("key0"); err != nil {
+			t.Errorf("Expected recently used key0 to survive: %v", err)
+		}
+	})
+
+	t.Run("access records", func(t *testing.T) {
+		tower := createEvictionTestTower(t, EvictionLRU, 1<<20)
+		defer tower.Close()
+
+		tracked := func() int {
+			n := 0
+			tower.evictor.access.Range(func(string, *keyAccess) bool {
+				n++
+				return true
+			})
+			return n
+		}
+
+		for i := 0; i < 10; i++ {
+			if _, err := tower.GetString(fmt.Sprintf("missing%d", i)); err == nil {
+				t.Fatal("Expected missing key to fail")
+			}
+		}
+		if n := tracked(); n != 0 {
+			t.Errorf("Expected misses to leave no access records, got %d", n)
+		}
+
+		for i := 0; i < 3; i++ {
+			if err := tower.SetString(fmt.Sprintf("key%d", i), randomPayload(t)); err != nil {
+				t.Fatalf("Failed to set string: %v", err)
+			}
+		}
+		if err := tower.CreateList("list"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		if n := tracked(); n != 4 {
+			t.Fatalf("Expected 4 access records, got %d", n)
+		}
+
+		if err := tower.Remove("key0"); err != nil {
+			t.Fatalf("Failed to remove key: %v", err)
+		}
+		if err := tower.DeleteList("list"); err != nil {
+			t.Fatalf("Failed to delete list: %v", err)
+		}
+		if _, err := tower.DeletePrefix("key"); err != nil {
+			t.Fatalf("Failed to delete prefix: %v", err)
+		}
+		if n := tracked(); n != 0 {
+			t.Errorf("Expected deletes to drop access records, got %d", n)
+		}
+	})
+}
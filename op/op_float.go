@@ -1,12 +1,23 @@
 ﻿package op
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"time"
 )
 
-func (op *Operator) SetFloat(key string, value float64) error {
-	unlock := op.lock(key)
+// ErrNonFiniteFloat is returned when an arithmetic operation would store NaN
+// or an infinity.
+var ErrNonFiniteFloat = errors.New("float result is not finite")
+
+func (op *Operator) SetFloat(key string, value float64) (err error) {
+	defer op.traceOperation("SetFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -21,8 +32,32 @@ func (op *Operator) SetFloat(key string, value float64) error {
 	return nil
 }
 
-func (op *Operator) GetFloat(key string) (float64, error) {
-	unlock := op.lock(key)
+// SetFloatEx sets key to value and makes it expire after ttl, in a single
+// write.
+func (op *Operator) SetFloatEx(key string, value float64, ttl time.Duration) (err error) {
+	defer op.traceOperation("SetFloatEx", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df := NULLDataFrame()
+	if err := df.SetFloat(value); err != nil {
+		return fmt.Errorf("failed to set float value: %w", err)
+	}
+
+	return op.setExpiring(key, df, ttl)
+}
+
+func (op *Operator) GetFloat(key string) (_ float64, err error) {
+	defer op.traceOperation("GetFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -38,8 +73,13 @@ func (op *Operator) GetFloat(key string) (float64, error) {
 	return value, nil
 }
 
-func (op *Operator) AddFloat(key string, delta float64) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) AddFloat(key string, delta float64) (_ float64, err error) {
+	defer op.traceOperation("AddFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -53,6 +93,9 @@ func (op *Operator) AddFloat(key string, delta float64) (float64, error) {
 	}
 
 	newValue := current + delta
+	if math.IsNaN(newValue) || math.IsInf(newValue, 0) {
+		return 0, fmt.Errorf("%w: %v", ErrNonFiniteFloat, newValue)
+	}
 	if err := df.SetFloat(newValue); err != nil {
 		return 0, fmt.Errorf("failed to set float value: %w", err)
 	}
@@ -68,8 +111,13 @@ func (op *Operator) SubFloat(key string, delta float64) (float64, error) {
 	return op.AddFloat(key, -delta)
 }
 
-func (op *Operator) MulFloat(key string, factor float64) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) MulFloat(key string, factor float64) (_ float64, err error) {
+	defer op.traceOperation("MulFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -83,6 +131,9 @@ func (op *Operator) MulFloat(key string, factor float64) (float64, error) {
 	}
 
 	newValue := current * factor
+	if math.IsNaN(newValue) || math.IsInf(newValue, 0) {
+		return 0, fmt.Errorf("%w: %v", ErrNonFiniteFloat, newValue)
+	}
 	if err := df.SetFloat(newValue); err != nil {
 		return 0, fmt.Errorf("failed to set float value: %w", err)
 	}
@@ -94,12 +145,17 @@ func (op *Operator) MulFloat(key string, factor float64) (float64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) DivFloat(key string, divisor float64) (float64, error) {
+func (op *Operator) DivFloat(key string, divisor float64) (_ float64, err error) {
+	defer op.traceOperation("DivFloat", key)(&err)
+
 	if divisor == 0 {
-		return 0, fmt.Errorf("division by zero")
+		return 0, ErrDivisionByZero
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -113,6 +169,9 @@ func (op *Operator) DivFloat(key string, divisor float64) (float64, error) {
 	}
 
 	newValue := current / divisor
+	if math.IsNaN(newValue) || math.IsInf(newValue, 0) {
+		return 0, fmt.Errorf("%w: %v", ErrNonFiniteFloat, newValue)
+	}
 	if err := df.SetFloat(newValue); err != nil {
 		return 0, fmt.Errorf("failed to set float value: %w", err)
 	}
@@ -124,8 +183,13 @@ func (op *Operator) DivFloat(key string, divisor float64) (float64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) NegFloat(key string) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) NegFloat(key string) (_ float64, err error) {
+	defer op.traceOperation("NegFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -150,8 +214,13 @@ func (op *Operator) NegFloat(key string) (float64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) AbsFloat(key string) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) AbsFloat(key string) (_ float64, err error) {
+	defer op.traceOperation("AbsFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -176,8 +245,13 @@ func (op *Operator) AbsFloat(key string) (float64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) SwapFloat(key string, newValue float64) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) SwapFloat(key string, newValue float64) (_ float64, err error) {
+	defer op.traceOperation("SwapFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -202,8 +276,13 @@ func (op *Operator) SwapFloat(key string, newValue float64) (float64, error) {
 }
 
 // Comparison operations
-func (op *Operator) CompareFloat(key string, value float64) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareFloat(key string, value float64) (_ int, err error) {
+	defer op.traceOperation("CompareFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -224,8 +303,13 @@ func (op *Operator) CompareFloat(key string, value float64) (int, error) {
 	return 0, nil
 }
 
-func (op *Operator) SetFloatIfGreater(key string, value float64) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetFloatIfGreater(key string, value float64) (_ float64, err error) {
+	defer op.traceOperation("SetFloatIfGreater", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -250,8 +334,13 @@ func (op *Operator) SetFloatIfGreater(key string, value float64) (float64, error
 	return current, nil
 }
 
-func (op *Operator) SetFloatIfLess(key string, value float64) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetFloatIfLess(key string, value float64) (_ float64, err error) {
+	defer op.traceOperation("SetFloatIfLess", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -276,8 +365,13 @@ func (op *Operator) SetFloatIfLess(key string, value float64) (float64, error) {
 	return current, nil
 }
 
-func (op *Operator) SetFloatIfEqual(key string, expected, newValue float64) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetFloatIfEqual(key string, expected, newValue float64) (_ float64, err error) {
+	defer op.traceOperation("SetFloatIfEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -303,12 +397,17 @@ func (op *Operator) SetFloatIfEqual(key string, expected, newValue float64) (flo
 }
 
 // Range and limit operations
-func (op *Operator) ClampFloat(key string, min, max float64) (float64, error) {
+func (op *Operator) ClampFloat(key string, min, max float64) (_ float64, err error) {
+	defer op.traceOperation("ClampFloat", key)(&err)
+
 	if min > max {
 		return 0, fmt.Errorf("min cannot be greater than max")
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -340,8 +439,13 @@ func (op *Operator) ClampFloat(key string, min, max float64) (float64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) MinFloat(key string, value float64) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) MinFloat(key string, value float64) (_ float64, err error) {
+	defer op.traceOperation("MinFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -371,8 +475,13 @@ func (op *Operator) MinFloat(key string, value float64) (float64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) MaxFloat(key string, value float64) (float64, error) {
-	unlock := op.lock(key)
+func (op *Operator) MaxFloat(key string, value float64) (_ float64, err error) {
+	defer op.traceOperation("MaxFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -1,6 +1,7 @@
 ﻿package op
 
 import (
+	"errors"
 	"math"
 	"testing"
 
@@ -119,8 +120,8 @@ func TestFloatOperations(t *testing.T) {
 		}
 
 		_, err = tower.DivFloat(key, 0.0)
-		if err == nil {
-			t.Error("Expected error when dividing by zero")
+		if !errors.Is(err, ErrDivisionByZero) {
+			t.Errorf("Expected ErrDivisionByZero, got %v", err)
 		}
 	})
 
@@ -422,3 +423,145 @@ func TestFloatOperations(t *testing.T) {
 	})
 }
 
+func TestSwapFloat(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.SetFloat("rate", 1.5); err != nil {
+		t.Fatalf("Failed to set float: %v", err)
+	}
+	prev, err := tower.SwapFloat("rate", 2.5)
+	if err != nil {
+		t.Fatalf("SwapFloat failed: %v", err)
+	}
+	if prev != 1.5 {
+		t.Errorf("Expected previous value 1.5, got %v", prev)
+	}
+	if v, _ := tower.GetFloat("rate"); v != 2.5 {
+		t.Errorf("Expected 2.5, got %v", v)
+	}
+
+	if err := tower.SetString("str", "x"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if _, err := tower.SwapFloat("str", 1); err == nil {
+		t.Error("Expected type mismatch error")
+	}
+	if v, _ := tower.GetString("str"); v != "x" {
+		t.Errorf("Expected string to be untouched, got %q", v)
+	}
+}
+
+func TestFloatNonFiniteResults(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.SetFloat("big", math.MaxFloat64); err != nil {
+		t.Fatalf("Failed to set float: %v", err)
+	}
+	if _, err := tower.AddFloat("big", math.MaxFloat64); !errors.Is(err, ErrNonFiniteFloat) {
+		t.Errorf("Expected ErrNonFiniteFloat from AddFloat, got %v", err)
+	}
+	if _, err := tower.MulFloat("big", 2); !errors.Is(err, ErrNonFiniteFloat) {
+		t.Errorf("Expected ErrNonFiniteFloat from MulFloat, got %v", err)
+	}
+	if _, err := tower.DivFloat("big", 1e-300); !errors.Is(err, ErrNonFiniteFloat) {
+		t.Errorf("Expected ErrNonFiniteFloat from DivFloat, got %v", err)
+	}
+	if _, err := tower.AddFloat("big", math.NaN()); !errors.Is(err, ErrNonFiniteFloat) {
+		t.Errorf("Expected ErrNonFiniteFloat for NaN, got %v", err)
+	}
+	if v, _ := tower.GetFloat("big"); v != math.MaxFloat64 {
+		t.Errorf("Expected rejected results not to be stored, got %v", v)
+	}
+}
+
+func TestClampFloat(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	tests := []struct {
+		name     string
+		initial  float64
+		min, max float64
+		want     float64
+	}{
+		{"below", -1.5, 0, 10, 0},
+		{"above", 12.5, 0, 10, 10},
+		{"inside", 5.25, 0, 10, 5.25},
+		{"at min", 0, 0, 10, 0},
+		{"at max", 10, 0, 10, 10},
+		{"point range", 3, 7.5, 7.5, 7.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tower.SetFloat("clamp", tt.initial); err != nil {
+				t.Fatalf("Failed to set float: %v", err)
+			}
+			got, err := tower.ClampFloat("clamp", tt.min, tt.max)
+			if err != nil {
+				t.Fatalf("ClampFloat failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ClampFloat = %v, want %v", got, tt.want)
+			}
+			if stored, _ := tower.GetFloat("clamp"); stored != tt.want {
+				t.Errorf("Stored %v, want %v", stored, tt.want)
+			}
+		})
+	}
+
+	if _, err := tower.ClampFloat("clamp", 2, 1); err == nil {
+		t.Error("Expected error when min is greater than max")
+	}
+}
+
+func TestSetFloatIfGreaterOrLess(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.SetFloat("high", 5); err != nil {
+		t.Fatalf("Failed to set float: %v", err)
+	}
+	steps := []struct {
+		value float64
+		want  float64
+	}{
+		{3, 5}, {5, 5}, {5.5, 5.5}, {-10, 5.5}, {100, 100},
+	}
+	for _, step := range steps {
+		got, err := tower.SetFloatIfGreater("high", step.value)
+		if err != nil {
+			t.Fatalf("SetFloatIfGreater failed: %v", err)
+		}
+		if got != step.want {
+			t.Errorf("SetFloatIfGreater(%v) = %v, want %v", step.value, got, step.want)
+		}
+	}
+
+	if err := tower.SetFloat("low", 5); err != nil {
+		t.Fatalf("Failed to set float: %v", err)
+	}
+	steps = []struct {
+		value float64
+		want  float64
+	}{
+		{7, 5}, {5, 5}, {4.5, 4.5}, {10, 4.5}, {-100, -100},
+	}
+	for _, step := range steps {
+		got, err := tower.SetFloatIfLess("low", step.value)
+		if err != nil {
+			t.Fatalf("SetFloatIfLess failed: %v", err)
+		}
+		if got != step.want {
+			t.Errorf("SetFloatIfLess(%v) = %v, want %v", step.value, got, step.want)
+		}
+	}
+	if v, _ := tower.GetFloat("low"); v != -100 {
+		t.Errorf("Expected stored low -100, got %v", v)
+	}
+
+	if _, err := tower.SetFloatIfGreater("missing", 1); err == nil {
+		t.Error("Expected error for missing key")
+	}
+}
@@ -0,0 +1,400 @@
+package op
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// GeoUnit selects the unit of distances passed to and returned by geo
+// operations.
+type GeoUnit uint8
+
+const (
+	GeoUnitMeters GeoUnit = iota
+	GeoUnitKilometers
+	GeoUnitMiles
+	GeoUnitFeet
+)
+
+// Positions are stored as 52-bit geohashes, 26 bits per axis, the same
+// encoding Redis uses. Latitudes are limited to the range of Web Mercator.
+const (
+	geoStepMax     = 26
+	geoLatMin      = -85.05112878
+	geoLatMax      = 85.05112878
+	geoLonMin      = -180.0
+	geoLonMax      = 180.0
+	geoEarthRadius = 6372797.560856 // meters
+)
+
+// GeoMember is a member of a geo set together with its position and, in
+// radius query results, its distance from the center.
+type GeoMember struct {
+	Member   PrimitiveData
+	Lon      float64
+	Lat      float64
+	Distance float64
+}
+
+// CreateGeo creates an empty geo set at key.
+func (op *Operator) CreateGeo(key string) (err error) {
+	defer op.traceOperation("CreateGeo", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Check if already exists
+	if _, err := op.get(key); err == nil {
+		return fmt.Errorf("geo set %s: %w", key, ErrContainerExists)
+	}
+
+	geoData := &SortedSetData{
+		Prefix: key,
+		Count:  0,
+	}
+
+	df := NULLDataFrame()
+	if err := df.SetGeo(geoData); err != nil {
+		return fmt.Errorf("failed to create geo data: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to set geo metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (op *Operator) DeleteGeo(key string) (err error) {
+	defer op.traceOperation("DeleteGeo", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return op.deleteGeo(key)
+}
+
+func (op *Operator) deleteGeo(key string) error {
+	_, geoData, err := op.getGeo(key)
+	if err != nil {
+		return err
+	}
+
+	return op.deleteSortedSetItems(key, geoData)
+}
+
+// GeoAdd stores member at lon, lat and returns the cardinality. Adding an
+// existing member again moves it.
+func (op *Operator) GeoAdd(key string, member PrimitiveData, lon, lat float64) (_ int64, err error) {
+	defer op.traceOperation("GeoAdd", key)(&err)
+
+	if lon < geoLonMin || lon > geoLonMax || lat < geoLatMin || lat > geoLatMax || math.IsNaN(lon) || math.IsNaN(lat) {
+		return 0, fmt.Errorf("invalid position %v,%v", lon, lat)
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, geoData, err := op.getGeo(key)
+	if err != nil {
+		return 0, err
+	}
+
+	_, count, err := op.setSortedSetMember(key, df, geoData, member, float64(geohashEncode(lon, lat)), false)
+	return count, err
+}
+
+// GeoPos returns the stored position of member. Positions are quantized to
+// their geohash cell, so they can be off from the added ones by up to about
+// a meter.
+func (op *Operator) GeoPos(key string, member PrimitiveData) (lon, lat float64, err error) {
+	defer op.traceOperation("GeoPos", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+
+	_, geoData, err := op.getGeo(key)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lon, lat, err = op.geoPos(geoData, member)
+	return lon, lat, err
+}
+
+// GeoDist returns the distance between members a and b in unit.
+func (op *Operator) GeoDist(key string, a, b PrimitiveData, unit GeoUnit) (_ float64, err error) {
+	defer op.traceOperation("GeoDist", key)(&err)
+
+	factor, err := unit.meters()
+	if err != nil {
+		return 0, err
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	_, geoData, err := op.getGeo(key)
+	if err != nil {
+		return 0, err
+	}
+
+	lonA, latA, err := op.geoPos(geoData, a)
+	if err != nil {
+		return 0, err
+	}
+	lonB, latB, err := op.geoPos(geoData, b)
+	if err != nil {
+		return 0, err
+	}
+
+	return geoDistance(lonA, latA, lonB, latB) / factor, nil
+}
+
+// GeoRadius returns the members within radius of lon, lat, nearest first.
+// Radius and the returned distances are in unit.
+func (op *Operator) GeoRadius(key string, lon, lat, radius float64, unit GeoUnit) (_ []GeoMember, err error) {
+	defer op.traceOperation("GeoRadius", key)(&err)
+
+	factor, err := unit.meters()
+	if err != nil {
+		return nil, err
+	}
+	if lon < geoLonMin || lon > geoLonMax || lat < geoLatMin || lat > geoLatMax || math.IsNaN(lon) || math.IsNaN(lat) {
+		return nil, fmt.Errorf("invalid position %v,%v", lon, lat)
+	}
+	if !(radius >= 0) {
+		return nil, fmt.Errorf("radius must not be negative, got %v", radius)
+	}
+	radiusMeters := radius * factor
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	_, geoData, err := op.getGeo(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []GeoMember{}
+	for _, r := range geohashSearchRanges(lon, lat, radiusMeters) {
+		err := op.rangeSortedSet(geoData, float64(r[0]), func(scoreKey []byte, score float64, memberDf *DataFrame) (bool, error) {
+			if score >= float64(r[1]) {
+				return false, nil
+			}
+
+			memberLon, memberLat := geohashDecode(uint64(score))
+			dist := geoDistance(lon, lat, memberLon, memberLat)
+			if dist > radiusMeters {
+				return true, nil
+			}
+
+			value, err := listItemValue(memberDf)
+			if err != nil {
+				return false, err
+			}
+			result = append(result, GeoMember{Member: value, Lon: memberLon, Lat: memberLat, Distance: dist / factor})
+			return true, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to range geo set: %w", err)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Distance < result[j].Distance
+	})
+
+	return result, nil
+}
+
+func (op *Operator) getGeo(key string) (*DataFrame, *SortedSetData, error) {
+	df, err := op.get(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("geo set %s does not exist: %w", key, err)
+	}
+
+	geoData, err := df.Geo()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get geo data: %w", err)
+	}
+
+	return df, geoData, nil
+}
+
+func (op *Operator) geoPos(geoData *SortedSetData, member PrimitiveData) (float64, float64, error) {
+	memberID, _, err := sortedSetMemberID(member)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	score, exists, err := op.sortedSetScore(geoData, memberID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !exists {
+		return 0, 0, fmt.Errorf("member does not exist: %w", ErrKeyNotFound)
+	}
+
+	lon, lat := geohashDecode(uint64(score))
+	return lon, lat, nil
+}
+
+func (u GeoUnit) meters() (float64, error) {
+	switch u {
+	case GeoUnitMeters:
+		return 1, nil
+	case GeoUnitKilometers:
+		return 1000, nil
+	case GeoUnitMiles:
+		return 1609.34, nil
+	case GeoUnitFeet:
+		return 0.3048, nil
+	}
+
+	return 0, fmt.Errorf("unknown geo unit %d", u)
+}
+
+// geohashEncode interleaves the 26-bit cell indexes of lon and lat, lon in
+// the odd bits and lat in the even ones.
+func geohashEncode(lon, lat float64) uint64 {
+	lonIdx := geohashCell(lon, geoLonMin, geoLonMax, geoStepMax)
+	latIdx := geohashCell(lat, geoLatMin, geoLatMax, geoStepMax)
+	return geohashInterleave(latIdx, lonIdx)
+}
+
+// geohashDecode returns the center of the cell of hash.
+func geohashDecode(hash uint64) (lon, lat float64) {
+	latIdx, lonIdx := geohashDeinterleave(hash)
+	cells := float64(uint64(1) << geoStepMax)
+
+	lonWidth := (geoLonMax - geoLonMin) / cells
+	latHeight := (geoLatMax - geoLatMin) / cells
+	lon = geoLonMin + (float64(lonIdx)+0.5)*lonWidth
+	lat = geoLatMin + (float64(latIdx)+0.5)*latHeight
+	return lon, lat
+}
+
+// geohashCell returns the index of the cell holding v when [lo, hi] is split
+// into 1<<step cells.
+func geohashCell(v, lo, hi float64, step uint) uint64 {
+	cells := uint64(1) << step
+	idx := uint64((v - lo) / (hi - lo) * float64(cells))
+	return min(idx, cells-1)
+}
+
+func geohashInterleave(even, odd uint64) uint64 {
+	return geohashSpread(even) | geohashSpread(odd)<<1
+}
+
+func geohashDeinterleave(hash uint64) (even, odd uint64) {
+	return geohashSqueeze(hash), geohashSqueeze(hash >> 1)
+}
+
+// geohashSpread moves the low 32 bits of x to the even bit positions.
+func geohashSpread(x uint64) uint64 {
+	x &= 0xFFFFFFFF
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+// geohashSqueeze undoes geohashSpread.
+func geohashSqueeze(x uint64) uint64 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x>>4) & 0x00FF00FF00FF00FF
+	x = (x | x>>8) & 0x0000FFFF0000FFFF
+	x = (x | x>>16) & 0x00000000FFFFFFFF
+	return x
+}
+
+// geohashSearchRanges returns the half-open score ranges of the cell holding
+// lon, lat and its eight neighbours, at the finest step whose cells are at
+// least as large as the bounding box of the radius. Together they cover every
+// point within radius meters.
+func geohashSearchRanges(lon, lat, radius float64) [][2]uint64 {
+	angle := radius / geoEarthRadius
+	latDelta := angle * 180 / math.Pi
+
+	// Past a pole every longitude is in range
+	lonDelta := 360.0
+	if s := math.Sin(angle) / math.Cos(lat*math.Pi/180); s < 1 {
+		lonDelta = math.Asin(s) * 180 / math.Pi
+	}
+
+	step := uint(geoStepMax)
+	for step > 0 {
+		cells := float64(uint64(1) << step)
+		if (geoLatMax-geoLatMin)/cells >= latDelta && (geoLonMax-geoLonMin)/cells >= lonDelta {
+			break
+		}
+		step--
+	}
+	if step == 0 {
+		return [][2]uint64{{0, 1 << (2 * geoStepMax)}}
+	}
+
+	cells := uint64(1) << step
+	latIdx := geohashCell(lat, geoLatMin, geoLatMax, step)
+	lonIdx := geohashCell(lon, geoLonMin, geoLonMax, step)
+	shift := 2 * (geoStepMax - step)
+
+	seen := make(map[uint64]bool, 9)
+	ranges := make([][2]uint64, 0, 9)
+	for dLat := -1; dLat <= 1; dLat++ {
+		cellLat := int64(latIdx) + int64(dLat)
+		if cellLat < 0 || cellLat >= int64(cells) {
+			continue // No wrapping across the poles
+		}
+		for dLon := -1; dLon <= 1; dLon++ {
+			// Longitude wraps around the antimeridian
+			cellLon := uint64((int64(lonIdx) + int64(dLon) + int64(cells)) % int64(cells))
+			hash := geohashInterleave(uint64(cellLat), cellLon)
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			ranges = append(ranges, [2]uint64{hash << shift, (hash + 1) << shift})
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
+
+	return ranges
+}
+
+// geoDistance returns the great circle distance in meters between two
+// positions using the haversine formula.
+func geoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	lat1r := lat1 * math.Pi / 180
+	lat2r := lat2 * math.Pi / 180
+	u := math.Sin((lat2r - lat1r) / 2)
+	v := math.Sin((lon2 - lon1) * math.Pi / 180 / 2)
+	return 2 * geoEarthRadius * math.Asin(math.Sqrt(u*u+math.Cos(lat1r)*math.Cos(lat2r)*v*v))
+}
@@ -0,0 +1,121 @@
+package op
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestGeo(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "cities"
+	if err := tower.CreateGeo(key); err != nil {
+		t.Fatalf("Failed to create geo set: %v", err)
+	}
+	if err := tower.CreateGeo(key); !errors.Is(err, ErrContainerExists) {
+		t.Errorf("Expected ErrContainerExists, got %v", err)
+	}
+
+	cities := []struct {
+		name     string
+		lon, lat float64
+	}{
+		{"Palermo", 13.361389, 38.115556},
+		{"Catania", 15.087269, 37.502669},
+		{"Rome", 12.496366, 41.902782},
+		{"Paris", 2.352222, 48.856613},
+		{"London", -0.127758, 51.507351},
+	}
+	for i, c := range cities {
+		count, err := tower.GeoAdd(key, PrimitiveString(c.name), c.lon, c.lat)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", c.name, err)
+		}
+		if count != int64(i+1) {
+			t.Errorf("Expected %d members, got %d", i+1, count)
+		}
+	}
+
+	for _, c := range cities {
+		lon, lat, err := tower.GeoPos(key, PrimitiveString(c.name))
+		if err != nil {
+			t.Fatalf("Failed to get position of %s: %v", c.name, err)
+		}
+		if math.Abs(lon-c.lon) > 1e-5 || math.Abs(lat-c.lat) > 1e-5 {
+			t.Errorf("%s: expected %v,%v, got %v,%v", c.name, c.lon, c.lat, lon, lat)
+		}
+	}
+
+	// Reference distances as reported by Redis GEODIST
+	dist, err := tower.GeoDist(key, PrimitiveString("Palermo"), PrimitiveString("Catania"), GeoUnitMeters)
+	if err != nil {
+		t.Fatalf("Failed to get distance: %v", err)
+	}
+	if math.Abs(dist-166274.1516) > 1 {
+		t.Errorf("Expected about 166274.15 m from Palermo to Catania, got %v", dist)
+	}
+	dist, err = tower.GeoDist(key, PrimitiveString("Palermo"), PrimitiveString("Catania"), GeoUnitKilometers)
+	if err != nil || math.Abs(dist-166.2742) > 0.001 {
+		t.Errorf("Expected about 166.27 km, got %v (%v)", dist, err)
+	}
+	dist, err = tower.GeoDist(key, PrimitiveString("Paris"), PrimitiveString("London"), GeoUnitKilometers)
+	if err != nil || math.Abs(dist-343.5) > 1.5 {
+		t.Errorf("Expected about 343.5 km from Paris to London, got %v (%v)", dist, err)
+	}
+
+	radius := func(t *testing.T, lon, lat, r float64, unit GeoUnit, want ...string) []GeoMember {
+		t.Helper()
+		members, err := tower.GeoRadius(key, lon, lat, r, unit)
+		if err != nil {
+			t.Fatalf("GeoRadius failed: %v", err)
+		}
+		if len(members) != len(want) {
+			t.Fatalf("Expected %v, got %v", want, members)
+		}
+		for i, m := range members {
+			if name, _ := m.Member.String(); name != want[i] {
+				t.Errorf("Expected %v nearest first, got %s at %d", want, name, i)
+			}
+		}
+		return members
+	}
+
+	members := radius(t, 15, 37, 200, GeoUnitKilometers, "Catania", "Palermo")
+	if math.Abs(members[0].Distance-56.4413) > 0.01 || math.Abs(members[1].Distance-190.4424) > 0.01 {
+		t.Errorf("Expected distances 56.44 and 190.44 km, got %v and %v", members[0].Distance, members[1].Distance)
+	}
+	radius(t, 15, 37, 100, GeoUnitKilometers, "Catania")
+	radius(t, 15, 37, 600, GeoUnitKilometers, "Catania", "Palermo", "Rome")
+	radius(t, 2.35, 48.85, 400, GeoUnitKilometers, "Paris", "London")
+	radius(t, 2.35, 48.85, 1, GeoUnitMiles, "Paris")
+	radius(t, 0, 0, 1000, GeoUnitKilometers)
+	radius(t, 0, 0, 20000, GeoUnitKilometers, "Catania", "Palermo", "Rome", "Paris", "London")
+
+	// Cells next to the antimeridian are neighbours
+	if _, err := tower.GeoAdd(key, PrimitiveString("east"), 179.9, 0); err != nil {
+		t.Fatalf("Failed to add: %v", err)
+	}
+	if _, err := tower.GeoAdd(key, PrimitiveString("west"), -179.9, 0); err != nil {
+		t.Fatalf("Failed to add: %v", err)
+	}
+	radius(t, 179.95, 0, 30, GeoUnitKilometers, "east", "west")
+
+	if _, err := tower.GeoAdd(key, PrimitiveString("pole"), 0, 89); err == nil {
+		t.Error("Expected error for a latitude outside the mercator range")
+	}
+	if _, _, err := tower.GeoPos(key, PrimitiveString("missing")); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+	if _, err := tower.GeoDist(key, PrimitiveString("Rome"), PrimitiveString("Paris"), GeoUnit(9)); err == nil {
+		t.Error("Expected error for an unknown unit")
+	}
+
+	if err := tower.DeleteGeo(key); err != nil {
+		t.Fatalf("Failed to delete geo set: %v", err)
+	}
+	if _, err := tower.GeoRadius(key, 15, 37, 200, GeoUnitKilometers); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound after delete, got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+)
+
+const (
+	defaultGroupCommitInterval = 2 * time.Millisecond
+	defaultGroupCommitMaxBatch = 128
+)
+
+var errOperatorClosed = errors.New("operator is closed")
+
+type commitRequest struct {
+	batch   *storeBatch
+	done    chan error
+	expired bool
+}
+
+type groupCommitter struct {
+	interval time.Duration
+	maxBatch int
+	requests chan *commitRequest
+	mu       sync.RWMutex
+	closed   bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newGroupCommitter(opt *Options) *groupCommitter {
+	if !opt.GroupCommit {
+		return nil
+	}
+
+	interval := opt.GroupCommitInterval
+	if interval <= 0 {
+		interval = defaultGroupCommitInterval
+	}
+
+	maxBatch := opt.GroupCommitMaxBatch
+	if maxBatch <= 0 {
+		maxBatch = defaultGroupCommitMaxBatch
+	}
+
+	return &groupCommitter{
+		interval: interval,
+		maxBatch: maxBatch,
+		requests: make(chan *commitRequest, maxBatch),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (op *Operator) startGroupCommitter() {
+	c := op.committer
+
+	go func() {
+		defer close(c.done)
+
+		for {
+			var pending []*commitRequest
+			select {
+			case req := <-c.requests:
+				pending = append(pending, req)
+			case <-c.stop:
+				// No new requests can arrive once stop is closed
+				for {
+					select {
+					case req := <-c.requests:
+						pending = append(pending, req)
+					default:
+						op.flushGroup(pending)
+						return
+					}
+				}
+			}
+
+			timer := time.NewTimer(c.interval)
+		collect:
+			for len(pending) < c.maxBatch {
+				select {
+				case req := <-c.requests:
+					pending = append(pending, req)
+				case <-timer.C:
+					break collect
+				case <-c.stop:
+					break collect
+				}
+			}
+			timer.Stop()
+
+			op.flushGroup(pending)
+		}
+	}()
+}
+
+func (op *Operator) stopGroupCommitter() {
+	c := op.committer
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	close(c.stop)
+	<-c.done
+}
+
+// flushGroup commits the pending batches together with a single sync and
+// reports the outcome to every waiter.
+func (op *Operator) flushGroup(pending []*commitRequest) {
+	if len(pending) == 0 {
+		return
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	var err error
+	for _, req := range pending {
+		if err = batch.Apply(req.batch.Batch, nil); err != nil {
+			err = fmt.Errorf("failed to apply batch: %w", err)
+			break
+		}
+	}
+	if err == nil {
+		if err = batch.Commit(pebble.Sync); err != nil {
+			err = fmt.Errorf("failed to commit batch: %w", err)
+		}
+	}
+
+	for _, req := range pending {
+		if err == nil {
+			op.committed(req.batch, req.expired)
+		}
+		req.done <- err
+	}
+}
+
+// committed runs after batch has been committed: it bumps the versions of
+// watched keys the batch wrote, updates the evictor's access records and
+// publishes its key events.
+func (op *Operator) committed(batch *storeBatch, expired bool) {
+	op.watches.touchBatch(batch)
+	op.trackBatch(batch)
+	op.publishBatch(batch, expired)
+}
+
+// commitAsync commits batch and returns a channel that receives the result
+// once it is durable. With group commit the batch is queued for the next
+// flush; otherwise it is committed before returning. The batch must stay open
+// until the result arrives.
+func (op *Operator) commitAsync(batch *storeBatch) <-chan error {
+	return op.enqueueCommit(batch, false)
+}
+
+// enqueueCommit is commitAsync for batches whose deletes remove expired keys
+// when expired is set, so that they are published as expirations.
+func (op *Operator) enqueueCommit(batch *storeBatch, expired bool) <-chan error {
+	done := make(chan error, 1)
+
+	if op.committer == nil {
+		err := batch.Commit(nil)
+		if err == nil {
+			op.committed(batch, expired)
+		}
+		done <- err
+		return done
+	}
+
+	c := op.committer
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		done <- errOperatorClosed
+		return done
+	}
+
+	c.requests <- &commitRequest{batch: batch, done: done, expired: expired}
+
+	return done
+}
+
+func (op *Operator) commit(batch *storeBatch) (err error) {
+	defer op.observe("commit")(&err)
+
+	return <-op.commitAsync(batch)
+}
+
+func (op *Operator) commitExpired(batch *storeBatch) (err error) {
+	defer op.observe("commit")(&err)
+
+	return <-op.enqueueCommit(batch, true)
+}
+
+// SetAsync writes value at key and returns a channel that receives nil once
+// the write is durable, or the error that prevented it. The key stays locked
+// until then, so later operations on it observe the write. With
+// Options.GroupCommit, writes from concurrent callers share a single commit.
+func (op *Operator) SetAsync(key string, value PrimitiveData) <-chan error {
+	end := op.traceOperation("SetAsync", key)
+	result := make(chan error, 1)
+
+	fail := func(err error) <-chan error {
+		end(&err)
+		result <- err
+		return result
+	}
+
+	df, err := primitiveDataFrame(value)
+	if err != nil {
+		return fail(fmt.Errorf("failed to convert value: %w", err))
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return fail(err)
+	}
+
+	batch := op.db.NewBatch()
+	if err := op.setInBatch(batch, key, df); err != nil {
+		batch.Close()
+		unlock()
+		return fail(err)
+	}
+
+	done := op.commitAsync(batch)
+	go func() {
+		err := <-done
+		batch.Close()
+		unlock()
+
+		if err != nil {
+			err = fmt.Errorf("failed to set key %s: %w", key, err)
+		}
+		end(&err)
+		result <- err
+	}()
+
+	return result
+}
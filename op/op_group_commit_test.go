@@ -0,0 +1,112 @@
+package op
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rivulet-io/tower/util/size"
+)
+
+func createGroupCommitTestTower(t *testing.T) *Operator {
+	tower, err := NewOperator(&Options{
+		Path:                "test.db",
+		BytesPerSync:        size.NewSizeFromBytes(32 * 1024),
+		CacheSize:           size.NewSizeFromMegabytes(64),
+		MemTableSize:        size.NewSizeFromMegabytes(4),
+		FS:                  InMemory(),
+		GroupCommit:         true,
+		GroupCommitInterval: 5 * time.Millisecond,
+		GroupCommitMaxBatch: 16,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tower: %v", err)
+	}
+	return tower
+}
+
+func TestGroupCommit(t *testing.T) {
+	t.Run("concurrent writers", func(t *testing.T) {
+		tower := createGroupCommitTestTower(t)
+		defer tower.Close()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if err := tower.SetInt(fmt.Sprintf("key%d", i), int64(i)); err != nil {
+					t.Errorf("Failed to set int: %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		for i := 0; i < 100; i++ {
+			v, err := tower.GetInt(fmt.Sprintf("key%d", i))
+			if err != nil || v != int64(i) {
+				t.Errorf("Expected key%d = %d, got %d (%v)", i, i, v, err)
+			}
+		}
+	})
+
+	t.Run("compound structures", func(t *testing.T) {
+		tower := createGroupCommitTestTower(t)
+		defer tower.Close()
+
+		if err := tower.CreateList("list"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		for i := 0; i < 10; i++ {
+			if _, err := tower.PushRightList("list", PrimitiveInt(int64(i))); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+		}
+
+		length, err := tower.GetListLength("list")
+		if err != nil || length != 10 {
+			t.Errorf("Expected length 10, got %d (%v)", length, err)
+		}
+	})
+
+	t.Run("set async", func(t *testing.T) {
+		tower := createGroupCommitTestTower(t)
+		defer tower.Close()
+
+		results := make([]<-chan error, 0, 10)
+		for i := 0; i < 10; i++ {
+			results = append(results, tower.SetAsync("key", PrimitiveInt(int64(i))))
+		}
+		for _, result := range results {
+			if err := <-result; err != nil {
+				t.Fatalf("Failed to set async: %v", err)
+			}
+		}
+
+		// Writes to the same key apply in call order
+		v, err := tower.GetInt("key")
+		if err != nil || v != 9 {
+			t.Errorf("Expected last write 9, got %d (%v)", v, err)
+		}
+
+		if err := <-tower.SetAsync("other", PrimitiveString("value")); err != nil {
+			t.Fatalf("Failed to set async: %v", err)
+		}
+		if s, err := tower.GetString("other"); err != nil || s != "value" {
+			t.Errorf("Expected 'value', got %q (%v)", s, err)
+		}
+	})
+
+	t.Run("set async without group commit", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		if err := <-tower.SetAsync("key", PrimitiveBool(true)); err != nil {
+			t.Fatalf("Failed to set async: %v", err)
+		}
+		if v, err := tower.GetBool("key"); err != nil || !v {
+			t.Errorf("Expected true, got %v (%v)", v, err)
+		}
+	})
+}
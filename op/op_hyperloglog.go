@@ -0,0 +1,226 @@
+package op
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// HyperLogLog estimates the number of distinct items added to it in a fixed
+// 16 KiB of registers. With m = 1<<hllPrecision registers the standard error
+// of the estimate is 1.04/sqrt(m), about 0.81%.
+const (
+	hllPrecision    = 14
+	hllMinPrecision = 4
+	hllMaxPrecision = 18
+)
+
+// CreateHLL creates an empty HyperLogLog estimator at key.
+func (op *Operator) CreateHLL(key string) (err error) {
+	defer op.traceOperation("CreateHLL", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Check if already exists
+	if _, err := op.get(key); err == nil {
+		return fmt.Errorf("hyperloglog %s: %w", key, ErrContainerExists)
+	}
+
+	data := &HyperLogLogData{
+		Precision: hllPrecision,
+		Registers: make([]byte, 1<<hllPrecision),
+	}
+
+	df := NULLDataFrame()
+	if err := df.SetHyperLogLog(data); err != nil {
+		return fmt.Errorf("failed to set hyperloglog data: %w", err)
+	}
+
+	return op.set(key, df)
+}
+
+// HLLAdd adds item to the estimator at key. Items of any primitive type are
+// accepted, and the type is hashed along with the value, so PrimitiveInt(1)
+// and PrimitiveString("1") count as different items.
+func (op *Operator) HLLAdd(key string, item PrimitiveData) (err error) {
+	defer op.traceOperation("HLLAdd", key)(&err)
+
+	hash, err := hllHash(item)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("hyperloglog %s does not exist: %w", key, err)
+	}
+
+	hd, err := df.HyperLogLog()
+	if err != nil {
+		return fmt.Errorf("failed to get hyperloglog data: %w", err)
+	}
+
+	if !hd.add(hash) {
+		return nil // No register changed
+	}
+
+	if err := df.SetHyperLogLog(hd); err != nil {
+		return fmt.Errorf("failed to update hyperloglog data: %w", err)
+	}
+
+	return op.set(key, df)
+}
+
+// HLLCount returns the estimated number of distinct items added to the
+// estimator at key.
+func (op *Operator) HLLCount(key string) (_ uint64, err error) {
+	defer op.traceOperation("HLLCount", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return 0, fmt.Errorf("hyperloglog %s does not exist: %w", key, err)
+	}
+
+	hd, err := df.HyperLogLog()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get hyperloglog data: %w", err)
+	}
+
+	return hd.estimate(), nil
+}
+
+// HLLMerge folds the estimators at keys into destKey, which then counts the
+// union of their items. destKey keeps its own items and is created if it does
+// not exist.
+func (op *Operator) HLLMerge(destKey string, keys ...string) (err error) {
+	defer op.traceOperation("HLLMerge", destKey)(&err)
+
+	unlock, err := op.lockKeys(append([]string{destKey}, keys...)...)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(destKey)
+	var dest *HyperLogLogData
+	switch {
+	case err == nil:
+		if dest, err = df.HyperLogLog(); err != nil {
+			return fmt.Errorf("failed to get hyperloglog data of %s: %w", destKey, err)
+		}
+	case isNotExist(err):
+		df = NULLDataFrame()
+		dest = &HyperLogLogData{
+			Precision: hllPrecision,
+			Registers: make([]byte, 1<<hllPrecision),
+		}
+	default:
+		return fmt.Errorf("failed to get hyperloglog %s: %w", destKey, err)
+	}
+
+	for _, key := range keys {
+		if key == destKey {
+			continue
+		}
+
+		srcDf, err := op.get(key)
+		if err != nil {
+			return fmt.Errorf("hyperloglog %s does not exist: %w", key, err)
+		}
+
+		src, err := srcDf.HyperLogLog()
+		if err != nil {
+			return fmt.Errorf("failed to get hyperloglog data of %s: %w", key, err)
+		}
+
+		if src.Precision != dest.Precision {
+			return fmt.Errorf("hyperloglog %s has precision %d, expected %d", key, src.Precision, dest.Precision)
+		}
+
+		for i, rank := range src.Registers {
+			dest.Registers[i] = max(dest.Registers[i], rank)
+		}
+	}
+
+	if err := df.SetHyperLogLog(dest); err != nil {
+		return fmt.Errorf("failed to update hyperloglog data: %w", err)
+	}
+
+	return op.set(destKey, df)
+}
+
+// add records hash and reports whether a register changed. The top Precision
+// bits pick the register, which keeps the longest run of leading zeros seen in
+// the remaining bits, plus one.
+func (hd *HyperLogLogData) add(hash uint64) bool {
+	index := hash >> (64 - hd.Precision)
+	rank := byte(bits.LeadingZeros64(hash<<hd.Precision|1<<(hd.Precision-1)) + 1)
+	if rank <= hd.Registers[index] {
+		return false
+	}
+	hd.Registers[index] = rank
+	return true
+}
+
+// estimate applies the raw HyperLogLog estimate, falling back to linear
+// counting while registers are still empty. The hash is 64 bits wide, so no
+// large range correction is needed.
+func (hd *HyperLogLogData) estimate() uint64 {
+	m := float64(len(hd.Registers))
+
+	sum := 0.0
+	zeros := 0
+	for _, rank := range hd.Registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(math.Round(estimate))
+}
+
+// hllHash hashes the encoded item with 64-bit FNV-1a and runs the result
+// through the splitmix64 finalizer, since FNV alone leaves the high bits that
+// pick the register poorly mixed for short, similar inputs.
+func hllHash(item PrimitiveData) (uint64, error) {
+	itemDf, err := listItemDataFrame(item)
+	if err != nil {
+		return 0, err
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte{byte(itemDf.typ)})
+	h.Write(itemDf.payload)
+
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x, nil
+}
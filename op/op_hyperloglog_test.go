@@ -0,0 +1,88 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	within := func(t *testing.T, got uint64, want int, tolerance float64) {
+		t.Helper()
+		if diff := math.Abs(float64(got)-float64(want)) / float64(want); diff > tolerance {
+			t.Errorf("Expected about %d, got %d (%.2f%% off)", want, got, diff*100)
+		}
+	}
+
+	key := "visitors"
+	if err := tower.CreateHLL(key); err != nil {
+		t.Fatalf("Failed to create hyperloglog: %v", err)
+	}
+	if err := tower.CreateHLL(key); !errors.Is(err, ErrContainerExists) {
+		t.Errorf("Expected ErrContainerExists, got %v", err)
+	}
+
+	count, err := tower.HLLCount(key)
+	if err != nil || count != 0 {
+		t.Fatalf("Expected 0 for an empty estimator, got %d (%v)", count, err)
+	}
+
+	const n = 100000
+	for i := 0; i < n; i++ {
+		if err := tower.HLLAdd(key, PrimitiveString(fmt.Sprintf("user-%d", i))); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	// Adding items again leaves the estimate alone
+	for i := 0; i < 1000; i++ {
+		if err := tower.HLLAdd(key, PrimitiveString(fmt.Sprintf("user-%d", i))); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+
+	count, err = tower.HLLCount(key)
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	within(t, count, n, 0.03)
+
+	// Small counts come from linear counting and are close to exact
+	small := "small"
+	if err := tower.CreateHLL(small); err != nil {
+		t.Fatalf("Failed to create hyperloglog: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := tower.HLLAdd(small, PrimitiveInt(int64(i))); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	count, err = tower.HLLCount(small)
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	within(t, count, 100, 0.02)
+
+	// The union of user-* and 0..99 holds n+100 distinct items
+	if err := tower.HLLMerge("merged", key, small); err != nil {
+		t.Fatalf("Failed to merge: %v", err)
+	}
+	count, err = tower.HLLCount("merged")
+	if err != nil {
+		t.Fatalf("Failed to count merged: %v", err)
+	}
+	within(t, count, n+100, 0.03)
+
+	if err := tower.HLLMerge("merged", "missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for a missing source, got %v", err)
+	}
+	if err := tower.SetString("plain", "value"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if err := tower.HLLAdd("plain", PrimitiveString("x")); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch, got %v", err)
+	}
+}
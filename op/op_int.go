@@ -1,11 +1,26 @@
 ﻿package op
 
 import (
+	"errors"
 	"fmt"
+	"time"
 )
 
-func (op *Operator) SetInt(key string, value int64) error {
-	unlock := op.lock(key)
+// ErrDivisionByZero is returned by the division operations when the divisor
+// is zero.
+var ErrDivisionByZero = errors.New("division by zero")
+
+// ErrIntOverflow is returned by the checked operations when the result does
+// not fit in an int64.
+var ErrIntOverflow = errors.New("int64 overflow")
+
+func (op *Operator) SetInt(key string, value int64) (err error) {
+	defer op.traceOperation("SetInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -20,8 +35,32 @@ func (op *Operator) SetInt(key string, value int64) error {
 	return nil
 }
 
-func (op *Operator) GetInt(key string) (int64, error) {
-	unlock := op.lock(key)
+// SetIntEx sets key to value and makes it expire after ttl, in a single
+// write.
+func (op *Operator) SetIntEx(key string, value int64, ttl time.Duration) (err error) {
+	defer op.traceOperation("SetIntEx", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df := NULLDataFrame()
+	if err := df.SetInt(value); err != nil {
+		return fmt.Errorf("failed to set int value: %w", err)
+	}
+
+	return op.setExpiring(key, df, ttl)
+}
+
+func (op *Operator) GetInt(key string) (_ int64, err error) {
+	defer op.traceOperation("GetInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -37,8 +76,16 @@ func (op *Operator) GetInt(key string) (int64, error) {
 	return value, nil
 }
 
-func (op *Operator) AddInt(key string, delta int64) (int64, error) {
-	unlock := op.lock(key)
+// AddInt adds delta to the int at key and returns the result. It wraps
+// around on overflow like Go integer arithmetic; use AddIntChecked when that
+// must be an error instead.
+func (op *Operator) AddInt(key string, delta int64) (_ int64, err error) {
+	defer op.traceOperation("AddInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -63,6 +110,112 @@ func (op *Operator) AddInt(key string, delta int64) (int64, error) {
 	return newValue, nil
 }
 
+// AddIntChecked is AddInt that fails with ErrIntOverflow, leaving the stored
+// value untouched, when the result would overflow or underflow an int64.
+func (op *Operator) AddIntChecked(key string, delta int64) (_ int64, err error) {
+	defer op.traceOperation("AddIntChecked", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	current, err := df.Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get int value for key %s: %w", key, err)
+	}
+
+	newValue := current + delta
+	if (delta > 0 && newValue < current) || (delta < 0 && newValue > current) {
+		return 0, fmt.Errorf("%w: %d + %d", ErrIntOverflow, current, delta)
+	}
+
+	if err := df.SetInt(newValue); err != nil {
+		return 0, fmt.Errorf("failed to set int value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return 0, fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return newValue, nil
+}
+
+// IncIntEx increments the counter at key and returns the new count. A
+// missing or expired counter starts over at 1 and expires after ttl; an
+// existing one keeps its expiration, so the counter covers a fixed window.
+// A counter without an expiration is given one.
+func (op *Operator) IncIntEx(key string, ttl time.Duration) (_ int64, err error) {
+	defer op.traceOperation("IncIntEx", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return op.incIntEx(key, ttl, false)
+}
+
+// IncIntExRefresh is IncIntEx that resets the expiration to ttl on every
+// increment, so the counter only expires after ttl without increments.
+func (op *Operator) IncIntExRefresh(key string, ttl time.Duration) (_ int64, err error) {
+	defer op.traceOperation("IncIntExRefresh", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return op.incIntEx(key, ttl, true)
+}
+
+func (op *Operator) incIntEx(key string, ttl time.Duration, refresh bool) (int64, error) {
+	if ttl <= 0 {
+		return 0, fmt.Errorf("ttl must be positive, got %v", ttl)
+	}
+
+	var newValue int64
+	df, err := op.get(key)
+	switch {
+	case err == nil:
+		current, err := df.Int()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get int value for key %s: %w", key, err)
+		}
+		newValue = current + 1
+	case isNotExist(err) || IsDataframeExpiredError(err) != nil:
+		df = NULLDataFrame()
+		newValue = 1
+	default:
+		return 0, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	if err := df.SetInt(newValue); err != nil {
+		return 0, fmt.Errorf("failed to set int value: %w", err)
+	}
+
+	if refresh || df.Expiration().IsZero() {
+		if err := op.setExpiring(key, df, ttl); err != nil {
+			return 0, err
+		}
+		return newValue, nil
+	}
+
+	if err := op.set(key, df); err != nil {
+		return 0, fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return newValue, nil
+}
+
 func (op *Operator) SubInt(key string, delta int64) (int64, error) {
 	return op.AddInt(key, -delta)
 }
@@ -75,8 +228,13 @@ func (op *Operator) DecrementInt(key string) (int64, error) {
 	return op.SubInt(key, 1)
 }
 
-func (op *Operator) MulInt(key string, factor int64) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) MulInt(key string, factor int64) (_ int64, err error) {
+	defer op.traceOperation("MulInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -101,12 +259,17 @@ func (op *Operator) MulInt(key string, factor int64) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) DivInt(key string, divisor int64) (int64, error) {
+func (op *Operator) DivInt(key string, divisor int64) (_ int64, err error) {
+	defer op.traceOperation("DivInt", key)(&err)
+
 	if divisor == 0 {
-		return 0, fmt.Errorf("division by zero")
+		return 0, ErrDivisionByZero
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -131,12 +294,17 @@ func (op *Operator) DivInt(key string, divisor int64) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) ModInt(key string, modulus int64) (int64, error) {
+func (op *Operator) ModInt(key string, modulus int64) (_ int64, err error) {
+	defer op.traceOperation("ModInt", key)(&err)
+
 	if modulus == 0 {
 		return 0, fmt.Errorf("modulus by zero")
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -161,8 +329,13 @@ func (op *Operator) ModInt(key string, modulus int64) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) NegInt(key string) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) NegInt(key string) (_ int64, err error) {
+	defer op.traceOperation("NegInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -187,8 +360,13 @@ func (op *Operator) NegInt(key string) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) AbsInt(key string) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) AbsInt(key string) (_ int64, err error) {
+	defer op.traceOperation("AbsInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -216,8 +394,13 @@ func (op *Operator) AbsInt(key string) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) SwapInt(key string, newValue int64) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) SwapInt(key string, newValue int64) (_ int64, err error) {
+	defer op.traceOperation("SwapInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -242,8 +425,13 @@ func (op *Operator) SwapInt(key string, newValue int64) (int64, error) {
 }
 
 // Comparison operations
-func (op *Operator) CompareInt(key string, value int64) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareInt(key string, value int64) (_ int, err error) {
+	defer op.traceOperation("CompareInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -265,8 +453,13 @@ func (op *Operator) CompareInt(key string, value int64) (int, error) {
 }
 
 // Conditional set operations
-func (op *Operator) SetIntIfGreater(key string, value int64) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetIntIfGreater(key string, value int64) (_ int64, err error) {
+	defer op.traceOperation("SetIntIfGreater", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -291,8 +484,13 @@ func (op *Operator) SetIntIfGreater(key string, value int64) (int64, error) {
 	return current, nil
 }
 
-func (op *Operator) SetIntIfLess(key string, value int64) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetIntIfLess(key string, value int64) (_ int64, err error) {
+	defer op.traceOperation("SetIntIfLess", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -317,8 +515,13 @@ func (op *Operator) SetIntIfLess(key string, value int64) (int64, error) {
 	return current, nil
 }
 
-func (op *Operator) SetIntIfEqual(key string, expected, newValue int64) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetIntIfEqual(key string, expected, newValue int64) (_ int64, err error) {
+	defer op.traceOperation("SetIntIfEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -344,12 +547,17 @@ func (op *Operator) SetIntIfEqual(key string, expected, newValue int64) (int64,
 }
 
 // Range and limit operations
-func (op *Operator) ClampInt(key string, min, max int64) (int64, error) {
+func (op *Operator) ClampInt(key string, min, max int64) (_ int64, err error) {
+	defer op.traceOperation("ClampInt", key)(&err)
+
 	if min > max {
 		return 0, fmt.Errorf("min cannot be greater than max")
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -381,8 +589,13 @@ func (op *Operator) ClampInt(key string, min, max int64) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) MinInt(key string, value int64) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) MinInt(key string, value int64) (_ int64, err error) {
+	defer op.traceOperation("MinInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -412,8 +625,13 @@ func (op *Operator) MinInt(key string, value int64) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) MaxInt(key string, value int64) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) MaxInt(key string, value int64) (_ int64, err error) {
+	defer op.traceOperation("MaxInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -444,8 +662,13 @@ func (op *Operator) MaxInt(key string, value int64) (int64, error) {
 }
 
 // Bit operations
-func (op *Operator) AndInt(key string, mask int64) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) AndInt(key string, mask int64) (_ int64, err error) {
+	defer op.traceOperation("AndInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -470,8 +693,13 @@ func (op *Operator) AndInt(key string, mask int64) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) OrInt(key string, mask int64) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) OrInt(key string, mask int64) (_ int64, err error) {
+	defer op.traceOperation("OrInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -496,8 +724,13 @@ func (op *Operator) OrInt(key string, mask int64) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) XorInt(key string, mask int64) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) XorInt(key string, mask int64) (_ int64, err error) {
+	defer op.traceOperation("XorInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -522,12 +755,17 @@ func (op *Operator) XorInt(key string, mask int64) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) ShiftLeftInt(key string, bits uint) (int64, error) {
+func (op *Operator) ShiftLeftInt(key string, bits uint) (_ int64, err error) {
+	defer op.traceOperation("ShiftLeftInt", key)(&err)
+
 	if bits > 63 {
 		return 0, fmt.Errorf("shift bits cannot be greater than 63")
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -552,12 +790,17 @@ func (op *Operator) ShiftLeftInt(key string, bits uint) (int64, error) {
 	return newValue, nil
 }
 
-func (op *Operator) ShiftRightInt(key string, bits uint) (int64, error) {
+func (op *Operator) ShiftRightInt(key string, bits uint) (_ int64, err error) {
+	defer op.traceOperation("ShiftRightInt", key)(&err)
+
 	if bits > 63 {
 		return 0, fmt.Errorf("shift bits cannot be greater than 63")
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -1,7 +1,10 @@
 package op
 
 import (
+	"errors"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/rivulet-io/tower/util/size"
 )
@@ -446,3 +449,111 @@ func TestIntOperations(t *testing.T) {
 	})
 }
 
+func TestAddIntChecked(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	tests := []struct {
+		name     string
+		initial  int64
+		delta    int64
+		want     int64
+		overflow bool
+	}{
+		{"up to max", math.MaxInt64 - 1, 1, math.MaxInt64, false},
+		{"past max", math.MaxInt64, 1, 0, true},
+		{"far past max", math.MaxInt64 - 10, math.MaxInt64, 0, true},
+		{"down to min", math.MinInt64 + 1, -1, math.MinInt64, false},
+		{"past min", math.MinInt64, -1, 0, true},
+		{"far past min", math.MinInt64 + 10, math.MinInt64, 0, true},
+		{"min plus max", math.MinInt64, math.MaxInt64, -1, false},
+		{"zero delta at max", math.MaxInt64, 0, math.MaxInt64, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tower.SetInt("counter", tt.initial); err != nil {
+				t.Fatalf("Failed to set int: %v", err)
+			}
+
+			got, err := tower.AddIntChecked("counter", tt.delta)
+			stored, _ := tower.GetInt("counter")
+			if tt.overflow {
+				if !errors.Is(err, ErrIntOverflow) {
+					t.Fatalf("Expected ErrIntOverflow, got %d (%v)", got, err)
+				}
+				if stored != tt.initial {
+					t.Errorf("Expected value to stay %d, got %d", tt.initial, stored)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddIntChecked failed: %v", err)
+			}
+			if got != tt.want || stored != tt.want {
+				t.Errorf("Expected %d, got %d (stored %d)", tt.want, got, stored)
+			}
+		})
+	}
+
+	// AddInt keeps wrapping around
+	if err := tower.SetInt("wrap", math.MaxInt64); err != nil {
+		t.Fatalf("Failed to set int: %v", err)
+	}
+	if v, err := tower.AddInt("wrap", 1); err != nil || v != math.MinInt64 {
+		t.Errorf("Expected AddInt to wrap to MinInt64, got %d (%v)", v, err)
+	}
+}
+
+func TestIncIntEx(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	for i := int64(1); i <= 3; i++ {
+		if v, err := tower.IncIntEx("hits", time.Second); err != nil || v != i {
+			t.Fatalf("Expected count %d, got %d (%v)", i, v, err)
+		}
+	}
+	if ttl, err := tower.GetTTL("hits"); err != nil || ttl <= 0 || ttl > time.Second {
+		t.Errorf("Expected TTL within (0, 1s], got %v (%v)", ttl, err)
+	}
+
+	// A plain counter is given the TTL on its first IncIntEx
+	if err := tower.SetInt("plain", 5); err != nil {
+		t.Fatalf("Failed to set int: %v", err)
+	}
+	if v, err := tower.IncIntEx("plain", time.Hour); err != nil || v != 6 {
+		t.Errorf("Expected 6, got %d (%v)", v, err)
+	}
+	if ttl, err := tower.GetTTL("plain"); err != nil || ttl == NoExpiration {
+		t.Errorf("Expected plain counter to expire, got %v (%v)", ttl, err)
+	}
+
+	if _, err := tower.IncIntEx("hits", 0); err == nil {
+		t.Error("Expected error for a non-positive TTL")
+	}
+	if err := tower.SetString("name", "value"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if _, err := tower.IncIntEx("name", time.Second); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch, got %v", err)
+	}
+
+	// Once the window has passed the counter starts over
+	time.Sleep(2500 * time.Millisecond)
+	if v, err := tower.IncIntEx("hits", time.Second); err != nil || v != 1 {
+		t.Errorf("Expected counter to reset to 1, got %d (%v)", v, err)
+	}
+
+	if v, err := tower.IncIntExRefresh("sliding", time.Hour); err != nil || v != 1 {
+		t.Errorf("Expected 1, got %d (%v)", v, err)
+	}
+	if err := tower.SetTTL("sliding", Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to set TTL: %v", err)
+	}
+	if v, err := tower.IncIntExRefresh("sliding", time.Hour); err != nil || v != 2 {
+		t.Errorf("Expected 2, got %d (%v)", v, err)
+	}
+	if ttl, err := tower.GetTTL("sliding"); err != nil || ttl <= time.Minute {
+		t.Errorf("Expected TTL to be refreshed to an hour, got %v (%v)", ttl, err)
+	}
+}
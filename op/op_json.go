@@ -0,0 +1,475 @@
+package op
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrJSONPathNotFound is returned when a path does not resolve to a value in
+// the stored document.
+var ErrJSONPathNotFound = errors.New("json path not found")
+
+// SetJSON stores doc, which must be a valid JSON document, at key.
+func (op *Operator) SetJSON(key string, doc []byte) (err error) {
+	defer op.traceOperation("SetJSON", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df := NULLDataFrame()
+	if err := df.SetJSON(doc); err != nil {
+		return fmt.Errorf("failed to set json value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// GetJSON returns the whole JSON document stored at key.
+func (op *Operator) GetJSON(key string) (_ []byte, err error) {
+	defer op.traceOperation("GetJSON", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	doc, err := df.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get json value for key %s: %w", key, err)
+	}
+
+	return doc, nil
+}
+
+// JSONGet returns the value at path in the document stored at key. Paths
+// use dots for object fields and brackets for array indexes, as in
+// "a.b[0].c"; an empty path selects the whole document. Strings, bools and
+// numbers come back as PrimitiveString, PrimitiveBool and PrimitiveInt or
+// PrimitiveFloat, null as PrimitiveNull, and objects and arrays as
+// PrimitiveBinary holding their JSON encoding.
+func (op *Operator) JSONGet(key, path string) (_ PrimitiveData, err error) {
+	defer op.traceOperation("JSONGet", key)(&err)
+
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	_, root, err := op.getJSONDocument(key)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := lookupJSONPath(root, segments, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonPrimitive(node)
+}
+
+// JSONSet sets the value at path in the document stored at key, creating
+// the document and any missing intermediate objects. Array indexes must
+// already exist. A PrimitiveBinary value is taken as an encoded JSON value,
+// so whole objects and arrays can be set.
+func (op *Operator) JSONSet(key, path string, value PrimitiveData) (err error) {
+	defer op.traceOperation("JSONSet", key)(&err)
+
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return err
+	}
+
+	node, err := jsonNode(value)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, root, err := op.getJSONDocument(key)
+	if err != nil {
+		if !isNotExist(err) {
+			return err
+		}
+		df, root = NULLDataFrame(), nil
+	}
+
+	root, err = setJSONPath(root, segments, node, path)
+	if err != nil {
+		return err
+	}
+
+	return op.setJSONDocument(key, df, root)
+}
+
+// JSONDelete removes the object field or array element at path from the
+// document stored at key. Later array elements shift down.
+func (op *Operator) JSONDelete(key, path string) (err error) {
+	defer op.traceOperation("JSONDelete", key)(&err)
+
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		return fmt.Errorf("cannot delete the document root, remove the key instead")
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, root, err := op.getJSONDocument(key)
+	if err != nil {
+		return err
+	}
+
+	parentPath, last := segments[:len(segments)-1], segments[len(segments)-1]
+	parent, err := lookupJSONPath(root, parentPath, path)
+	if err != nil {
+		return err
+	}
+
+	switch node := parent.(type) {
+	case map[string]any:
+		if last.isIndex {
+			return fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+		}
+		if _, ok := node[last.key]; !ok {
+			return fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+		}
+		delete(node, last.key)
+	case []any:
+		if !last.isIndex || last.index >= len(node) {
+			return fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+		}
+		parent = append(node[:last.index], node[last.index+1:]...)
+	default:
+		return fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+	}
+
+	root, err = setJSONPath(root, parentPath, parent, path)
+	if err != nil {
+		return err
+	}
+
+	return op.setJSONDocument(key, df, root)
+}
+
+// JSONArrayAppend appends values to the array at path in the document
+// stored at key and returns the new array length.
+func (op *Operator) JSONArrayAppend(key, path string, values ...PrimitiveData) (_ int, err error) {
+	defer op.traceOperation("JSONArrayAppend", key)(&err)
+
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return 0, err
+	}
+
+	nodes := make([]any, len(values))
+	for i, value := range values {
+		if nodes[i], err = jsonNode(value); err != nil {
+			return 0, err
+		}
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, root, err := op.getJSONDocument(key)
+	if err != nil {
+		return 0, err
+	}
+
+	target, err := lookupJSONPath(root, segments, path)
+	if err != nil {
+		return 0, err
+	}
+
+	array, ok := target.([]any)
+	if !ok {
+		return 0, fmt.Errorf("value at path %q is not an array", path)
+	}
+	array = append(array, nodes...)
+
+	root, err = setJSONPath(root, segments, array, path)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := op.setJSONDocument(key, df, root); err != nil {
+		return 0, err
+	}
+
+	return len(array), nil
+}
+
+// getJSONDocument loads and decodes the document at key. The caller must
+// hold the key lock.
+func (op *Operator) getJSONDocument(key string) (*DataFrame, any, error) {
+	df, err := op.get(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	doc, err := df.JSON()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get json value for key %s: %w", key, err)
+	}
+
+	root, err := decodeJSON(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode json value for key %s: %w", key, err)
+	}
+
+	return df, root, nil
+}
+
+// setJSONDocument encodes root into df, keeping its expiration, and stores
+// it at key.
+func (op *Operator) setJSONDocument(key string, df *DataFrame, root any) error {
+	doc, err := encodeJSON(root)
+	if err != nil {
+		return fmt.Errorf("failed to encode json value: %w", err)
+	}
+
+	if err := df.SetJSON(doc); err != nil {
+		return fmt.Errorf("failed to set json value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath splits a path such as "a.b[0].c" into its segments.
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	var segments []jsonPathSegment
+
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid json path %q: unclosed bracket", path)
+			}
+			index, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid json path %q: bad array index %q", path, path[i+1:i+end])
+			}
+			segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+			i += end + 1
+			continue
+		case '.':
+			if len(segments) == 0 {
+				return nil, fmt.Errorf("invalid json path %q: leading dot", path)
+			}
+			i++
+		default:
+			if len(segments) > 0 {
+				return nil, fmt.Errorf("invalid json path %q: missing dot before field", path)
+			}
+		}
+
+		end := i
+		for end < len(path) && path[end] != '.' && path[end] != '[' {
+			end++
+		}
+		if end == i {
+			return nil, fmt.Errorf("invalid json path %q: empty field name", path)
+		}
+		segments = append(segments, jsonPathSegment{key: path[i:end]})
+		i = end
+	}
+
+	return segments, nil
+}
+
+func lookupJSONPath(node any, segments []jsonPathSegment, path string) (any, error) {
+	for _, segment := range segments {
+		switch current := node.(type) {
+		case map[string]any:
+			if segment.isIndex {
+				return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+			}
+			child, ok := current[segment.key]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+			}
+			node = child
+		case []any:
+			if !segment.isIndex || segment.index >= len(current) {
+				return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+			}
+			node = current[segment.index]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+		}
+	}
+
+	return node, nil
+}
+
+// setJSONPath returns node with value placed at segments, creating missing
+// objects on the way.
+func setJSONPath(node any, segments []jsonPathSegment, value any, path string) (any, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	segment, rest := segments[0], segments[1:]
+	if segment.isIndex {
+		array, ok := node.([]any)
+		if !ok || segment.index >= len(array) {
+			return nil, fmt.Errorf("%w: %s", ErrJSONPathNotFound, path)
+		}
+		child, err := setJSONPath(array[segment.index], rest, value, path)
+		if err != nil {
+			return nil, err
+		}
+		array[segment.index] = child
+		return array, nil
+	}
+
+	if node == nil {
+		node = map[string]any{}
+	}
+	object, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot set field %q of a non-object in path %q", segment.key, path)
+	}
+	child, err := setJSONPath(object[segment.key], rest, value, path)
+	if err != nil {
+		return nil, err
+	}
+	object[segment.key] = child
+	return object, nil
+}
+
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var node any
+	if err := decoder.Decode(&node); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func encodeJSON(node any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonNode converts value to its decoded JSON form.
+func jsonNode(value PrimitiveData) (any, error) {
+	if value == nil {
+		return nil, fmt.Errorf("value cannot be nil")
+	}
+
+	switch value.Type() {
+	case TypeNull:
+		return nil, nil
+	case TypeInt:
+		intVal, _ := value.Int()
+		return json.Number(strconv.FormatInt(intVal, 10)), nil
+	case TypeFloat:
+		floatVal, _ := value.Float()
+		data, err := json.Marshal(floatVal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid json number: %w", err)
+		}
+		return json.Number(data), nil
+	case TypeString:
+		strVal, _ := value.String()
+		return strVal, nil
+	case TypeBool:
+		boolVal, _ := value.Bool()
+		return boolVal, nil
+	case TypeBinary:
+		binVal, _ := value.Binary()
+		node, err := decodeJSON(binVal)
+		if err != nil {
+			return nil, fmt.Errorf("binary value is not valid json: %w", err)
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("unsupported data type for json: %v", value.Type())
+	}
+}
+
+// jsonPrimitive converts a decoded JSON value back to PrimitiveData.
+func jsonPrimitive(node any) (PrimitiveData, error) {
+	switch v := node.(type) {
+	case nil:
+		return PrimitiveNull{}, nil
+	case bool:
+		return PrimitiveBool(v), nil
+	case string:
+		return PrimitiveString(v), nil
+	case json.Number:
+		if intVal, err := v.Int64(); err == nil {
+			return PrimitiveInt(intVal), nil
+		}
+		floatVal, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid json number %s: %w", v, err)
+		}
+		return PrimitiveFloat(floatVal), nil
+	default:
+		data, err := encodeJSON(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode json value: %w", err)
+		}
+		return PrimitiveBinary(data), nil
+	}
+}
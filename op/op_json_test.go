@@ -0,0 +1,174 @@
+package op
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	doc := `{"user":{"name":"alice","age":30,"score":9.5,"active":true,"nick":null},"tags":["a","b",{"c":1}]}`
+	if err := tower.SetJSON("doc", []byte(doc)); err != nil {
+		t.Fatalf("Failed to set json: %v", err)
+	}
+
+	t.Run("get nested", func(t *testing.T) {
+		tests := []struct {
+			path string
+			want PrimitiveData
+		}{
+			{"user.name", PrimitiveString("alice")},
+			{"user.age", PrimitiveInt(30)},
+			{"user.score", PrimitiveFloat(9.5)},
+			{"user.active", PrimitiveBool(true)},
+			{"user.nick", PrimitiveNull{}},
+			{"tags[1]", PrimitiveString("b")},
+			{"tags[2].c", PrimitiveInt(1)},
+			{"tags[2]", PrimitiveBinary(`{"c":1}`)},
+		}
+		for _, tt := range tests {
+			got, err := tower.JSONGet("doc", tt.path)
+			if err != nil {
+				t.Errorf("JSONGet(%q) failed: %v", tt.path, err)
+				continue
+			}
+			if got.Type() != tt.want.Type() {
+				t.Errorf("JSONGet(%q) type = %v, want %v", tt.path, got.Type(), tt.want.Type())
+				continue
+			}
+			if tt.want.Type() == TypeBinary {
+				gotBin, _ := got.Binary()
+				wantBin, _ := tt.want.Binary()
+				if string(gotBin) != string(wantBin) {
+					t.Errorf("JSONGet(%q) = %s, want %s", tt.path, gotBin, wantBin)
+				}
+			} else if tt.want.Type() != TypeNull && got != tt.want {
+				t.Errorf("JSONGet(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		for _, path := range []string{"missing", "user.missing", "tags[5]", "user[0]", "tags.a", "user.name.first"} {
+			if _, err := tower.JSONGet("doc", path); !errors.Is(err, ErrJSONPathNotFound) {
+				t.Errorf("JSONGet(%q) expected ErrJSONPathNotFound, got %v", path, err)
+			}
+		}
+		for _, path := range []string{".a", "a..b", "a[x]", "a[0", "a[-1]", "a[0]b"} {
+			if _, err := tower.JSONGet("doc", path); err == nil || errors.Is(err, ErrJSONPathNotFound) {
+				t.Errorf("JSONGet(%q) expected a syntax error, got %v", path, err)
+			}
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		if err := tower.JSONSet("doc", "user.name", PrimitiveString("bob")); err != nil {
+			t.Fatalf("Failed to set field: %v", err)
+		}
+		if err := tower.JSONSet("doc", "tags[0]", PrimitiveInt(7)); err != nil {
+			t.Fatalf("Failed to set array element: %v", err)
+		}
+		if err := tower.JSONSet("doc", "user.address.city", PrimitiveString("Seoul")); err != nil {
+			t.Fatalf("Failed to set with intermediate objects: %v", err)
+		}
+		if err := tower.JSONSet("doc", "user.meta", PrimitiveBinary(`{"x":[1,2]}`)); err != nil {
+			t.Fatalf("Failed to set raw json: %v", err)
+		}
+
+		if v, _ := tower.JSONGet("doc", "user.name"); v != PrimitiveString("bob") {
+			t.Errorf("Expected bob, got %v", v)
+		}
+		if v, _ := tower.JSONGet("doc", "tags[0]"); v != PrimitiveInt(7) {
+			t.Errorf("Expected 7, got %v", v)
+		}
+		if v, _ := tower.JSONGet("doc", "user.address.city"); v != PrimitiveString("Seoul") {
+			t.Errorf("Expected Seoul, got %v", v)
+		}
+		if v, _ := tower.JSONGet("doc", "user.meta.x[1]"); v != PrimitiveInt(2) {
+			t.Errorf("Expected 2, got %v", v)
+		}
+
+		if err := tower.JSONSet("doc", "tags[9]", PrimitiveInt(1)); !errors.Is(err, ErrJSONPathNotFound) {
+			t.Errorf("Expected ErrJSONPathNotFound for out of range index, got %v", err)
+		}
+		if err := tower.JSONSet("doc", "user.name.first", PrimitiveString("x")); err == nil {
+			t.Error("Expected error setting a field of a string")
+		}
+		if err := tower.JSONSet("doc", "user.bad", PrimitiveBinary("{")); err == nil {
+			t.Error("Expected error for invalid raw json")
+		}
+	})
+
+	t.Run("set creates document", func(t *testing.T) {
+		if err := tower.JSONSet("fresh", "a.b[0]", PrimitiveInt(1)); !errors.Is(err, ErrJSONPathNotFound) {
+			t.Errorf("Expected ErrJSONPathNotFound for missing array, got %v", err)
+		}
+		if err := tower.JSONSet("fresh", "a.b", PrimitiveBool(false)); err != nil {
+			t.Fatalf("Failed to set on missing key: %v", err)
+		}
+		raw, err := tower.GetJSON("fresh")
+		if err != nil {
+			t.Fatalf("Failed to get json: %v", err)
+		}
+		if string(raw) != `{"a":{"b":false}}` {
+			t.Errorf("Unexpected document %s", raw)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := tower.JSONDelete("doc", "tags[1]"); err != nil {
+			t.Fatalf("Failed to delete element: %v", err)
+		}
+		if v, _ := tower.JSONGet("doc", "tags[1].c"); v != PrimitiveInt(1) {
+			t.Errorf("Expected later elements to shift down, got %v", v)
+		}
+		if err := tower.JSONDelete("doc", "user.address"); err != nil {
+			t.Fatalf("Failed to delete field: %v", err)
+		}
+		if _, err := tower.JSONGet("doc", "user.address.city"); !errors.Is(err, ErrJSONPathNotFound) {
+			t.Errorf("Expected deleted field to be gone, got %v", err)
+		}
+		if err := tower.JSONDelete("doc", "user.address"); !errors.Is(err, ErrJSONPathNotFound) {
+			t.Errorf("Expected ErrJSONPathNotFound deleting twice, got %v", err)
+		}
+		if err := tower.JSONDelete("doc", ""); err == nil {
+			t.Error("Expected error deleting the root")
+		}
+	})
+
+	t.Run("array append", func(t *testing.T) {
+		n, err := tower.JSONArrayAppend("doc", "tags", PrimitiveString("z"), PrimitiveNull{}, PrimitiveBinary(`[1]`))
+		if err != nil {
+			t.Fatalf("Failed to append: %v", err)
+		}
+		if n != 5 {
+			t.Errorf("Expected length 5, got %d", n)
+		}
+		if v, _ := tower.JSONGet("doc", "tags[2]"); v != PrimitiveString("z") {
+			t.Errorf("Expected z, got %v", v)
+		}
+		if v, _ := tower.JSONGet("doc", "tags[4][0]"); v != PrimitiveInt(1) {
+			t.Errorf("Expected nested 1, got %v", v)
+		}
+		if _, err := tower.JSONArrayAppend("doc", "user", PrimitiveInt(1)); err == nil {
+			t.Error("Expected error appending to an object")
+		}
+		if _, err := tower.JSONArrayAppend("doc", "nope", PrimitiveInt(1)); !errors.Is(err, ErrJSONPathNotFound) {
+			t.Errorf("Expected ErrJSONPathNotFound, got %v", err)
+		}
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		if err := tower.SetString("plain", "x"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if _, err := tower.JSONGet("plain", "a"); err == nil {
+			t.Error("Expected error reading json path of a string key")
+		}
+		if err := tower.SetJSON("badjson", []byte("{")); err == nil {
+			t.Error("Expected error storing invalid json")
+		}
+	})
+}
@@ -1,13 +1,32 @@
 ﻿package op
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
+	"sort"
+
+	"github.com/cockroachdb/pebble"
 )
 
+// ErrListEmpty is returned by operations that take an item from a list
+// that has none.
+var ErrListEmpty = errors.New("list is empty")
+
+// ErrListPivotNotFound is returned by ListInsertBefore and ListInsertAfter
+// when no item equals the pivot.
+var ErrListPivotNotFound = errors.New("pivot not found in list")
+
 // List management operations
-func (op *Operator) CreateList(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) CreateList(key string) (err error) {
+	defer op.traceOperation("CreateList", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	// Store list metadata directly to key
@@ -15,9 +34,49 @@ func (op *Operator) CreateList(key string) error {
 
 	// Check if already exists
 	if _, err := op.get(listKey); err == nil {
-		return fmt.Errorf("list %s already exists", key)
+		return fmt.Errorf("list %s: %w", key, ErrContainerExists)
+	}
+
+	return op.createList(key)
+}
+
+// EnsureList creates an empty list at key unless one already exists. It
+// fails with a type mismatch when key holds something other than a list.
+func (op *Operator) EnsureList(key string) error {
+	_, err := op.EnsureListCreated(key)
+	return err
+}
+
+// EnsureListCreated is EnsureList that also reports whether the list was
+// created by this call.
+func (op *Operator) EnsureListCreated(key string) (_ bool, err error) {
+	defer op.traceOperation("EnsureListCreated", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if df, err := op.get(key); err == nil {
+		if _, err := df.List(); err != nil {
+			return false, fmt.Errorf("list %s: %w", key, err)
+		}
+		return false, nil
+	}
+
+	if err := op.createList(key); err != nil {
+		return false, err
 	}
 
+	return true, nil
+}
+
+// createList stores the metadata of an empty list at key, which the caller
+// holds the lock for.
+func (op *Operator) createList(key string) error {
+	listKey := key
+
 	// Create new list data
 	listData := &ListData{
 		Prefix:    key,
@@ -38,8 +97,13 @@ func (op *Operator) CreateList(key string) error {
 	return nil
 }
 
-func (op *Operator) DeleteList(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) DeleteList(key string) (err error) {
+	defer op.traceOperation("DeleteList", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	return op.deleteList(key)
@@ -74,18 +138,28 @@ func (op *Operator) deleteList(key string) error {
 	return nil
 }
 
-func (op *Operator) ExistsList(key string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ExistsList(key string) (_ bool, err error) {
+	defer op.traceOperation("ExistsList", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	listKey := key
-	_, err := op.get(listKey)
+	_, err = op.get(listKey)
 	return err == nil, nil
 }
 
 // Basic Push/Pop operations
-func (op *Operator) PushLeftList(key string, value PrimitiveData) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) PushLeftList(key string, value PrimitiveData) (_ int64, err error) {
+	defer op.traceOperation("PushLeftList", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	listKey := key
@@ -109,40 +183,17 @@ func (op *Operator) PushLeftList(key string, value PrimitiveData) (int64, error)
 	newIndex := listData.HeadIndex - 1
 
 	// Set value to DataFrame
-	itemDf := NULLDataFrame()
-	switch value.Type() {
-	case TypeInt:
-		intVal, _ := value.Int()
-		if err := itemDf.SetInt(intVal); err != nil {
-			return 0, fmt.Errorf("failed to set int value: %w", err)
-		}
-	case TypeFloat:
-		floatVal, _ := value.Float()
-		if err := itemDf.SetFloat(floatVal); err != nil {
-			return 0, fmt.Errorf("failed to set float value: %w", err)
-		}
-	case TypeString:
-		strVal, _ := value.String()
-		if err := itemDf.SetString(strVal); err != nil {
-			return 0, fmt.Errorf("failed to set string value: %w", err)
-		}
-	case TypeBool:
-		boolVal, _ := value.Bool()
-		if err := itemDf.SetBool(boolVal); err != nil {
-			return 0, fmt.Errorf("failed to set bool value: %w", err)
-		}
-	case TypeBinary:
-		binVal, _ := value.Binary()
-		if err := itemDf.SetBinary(binVal); err != nil {
-			return 0, fmt.Errorf("failed to set binary value: %w", err)
-		}
-	default:
-		return 0, fmt.Errorf("unsupported value type")
-	}
-
-	// Store item
+	itemDf, err := listItemDataFrame(value)
+	if err != nil {
+		return 0, err
+	}
+
+	// Store item and metadata in one batch so they can't drift apart
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
 	itemKey := string(MakeListItemKey(key, newIndex))
-	if err := op.set(itemKey, itemDf); err != nil {
+	if err := op.setInBatch(batch, itemKey, itemDf); err != nil {
 		return 0, fmt.Errorf("failed to set list item: %w", err)
 	}
 
@@ -154,17 +205,190 @@ func (op *Operator) PushLeftList(key string, value PrimitiveData) (int64, error)
 		return 0, fmt.Errorf("failed to update list metadata: %w", err)
 	}
 
-	if err := op.set(listKey, df); err != nil {
+	if err := op.setInBatch(batch, listKey, df); err != nil {
 		return 0, fmt.Errorf("failed to update list metadata: %w", err)
 	}
 
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit list batch: %w", err)
+	}
+
 	return listData.Length, nil
 }
 
-func (op *Operator) PushRightList(key string, value PrimitiveData) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) PushRightList(key string, value PrimitiveData) (_ int64, err error) {
+	defer op.traceOperation("PushRightList", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return op.pushRightList(key, value)
+}
+
+// PushRightListBounded pushes value only while the list holds fewer than
+// maxLen items. A full list is left untouched and pushed is false, so the
+// producer can back off instead of overwriting. The current length is
+// always returned.
+func (op *Operator) PushRightListBounded(key string, value PrimitiveData, maxLen int64) (pushed bool, length int64, err error) {
+	defer op.traceOperation("PushRightListBounded", key)(&err)
+
+	if maxLen <= 0 {
+		return false, 0, fmt.Errorf("max length must be positive, got %d", maxLen)
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, 0, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return false, 0, fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	if listData.Length >= maxLen {
+		return false, listData.Length, nil
+	}
+
+	length, err = op.pushRightList(key, value)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return true, length, nil
+}
+
+// PushRightCapped appends value and then drops items from the head until
+// the list holds at most maxLen items, all under one lock and batch, so the
+// list works as a ring buffer keeping the newest maxLen values. It returns
+// the resulting length.
+func (op *Operator) PushRightCapped(key string, value PrimitiveData, maxLen int64) (_ int64, err error) {
+	defer op.traceOperation("PushRightCapped", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return op.pushListCapped(key, value, maxLen, false)
+}
+
+// PushLeftCapped is PushRightCapped for the other end: it prepends value and
+// drops items from the tail.
+func (op *Operator) PushLeftCapped(key string, value PrimitiveData, maxLen int64) (_ int64, err error) {
+	defer op.traceOperation("PushLeftCapped", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
+	return op.pushListCapped(key, value, maxLen, true)
+}
+
+func (op *Operator) pushListCapped(key string, value PrimitiveData, maxLen int64, left bool) (int64, error) {
+	if maxLen <= 0 {
+		return 0, fmt.Errorf("max length must be positive, got %d", maxLen)
+	}
+
+	df, err := op.get(key)
+	if err != nil {
+		return 0, fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	if listData.Length >= math.MaxInt64-1 {
+		return 0, fmt.Errorf("list has too many members")
+	}
+
+	itemDf, err := listItemDataFrame(value)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	newIndex := listData.TailIndex + 1
+	if left {
+		newIndex = listData.HeadIndex - 1
+	}
+	if err := op.setInBatch(batch, string(MakeListItemKey(key, newIndex)), itemDf); err != nil {
+		return 0, fmt.Errorf("failed to set list item: %w", err)
+	}
+	if left {
+		listData.HeadIndex = newIndex
+	} else {
+		listData.TailIndex = newIndex
+	}
+	listData.Length++
+
+	// Trim the opposite end back down to maxLen
+	for ; listData.Length > maxLen; listData.Length-- {
+		index := listData.HeadIndex
+		if left {
+			index = listData.TailIndex
+		}
+		if err := batch.Delete(MakeListItemKey(key, index), nil); err != nil {
+			return 0, fmt.Errorf("failed to delete list item: %w", err)
+		}
+		if left {
+			listData.TailIndex--
+		} else {
+			listData.HeadIndex++
+		}
+	}
+
+	if err := df.SetList(listData); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.setInBatch(batch, key, df); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit list batch: %w", err)
+	}
+
+	return listData.Length, nil
+}
+
+func (op *Operator) pushRightList(key string, value PrimitiveData) (int64, error) {
+	// Store item and metadata in one batch so they can't drift apart
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	length, err := op.pushRightListInBatch(batch, key, value)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit list batch: %w", err)
+	}
+
+	return length, nil
+}
+
+// pushRightListInBatch stages a right push in batch for the caller to
+// commit. The caller must hold the list lock.
+func (op *Operator) pushRightListInBatch(batch *storeBatch, key string, value PrimitiveData) (int64, error) {
 	listKey := key
 
 	// Get list metadata
@@ -185,41 +409,13 @@ func (op *Operator) PushRightList(key string, value PrimitiveData) (int64, error
 	// Calculate new index (increase TailIndex for right addition)
 	newIndex := listData.TailIndex + 1
 
-	// Set value to DataFrame
-	itemDf := NULLDataFrame()
-	switch value.Type() {
-	case TypeInt:
-		intVal, _ := value.Int()
-		if err := itemDf.SetInt(intVal); err != nil {
-			return 0, fmt.Errorf("failed to set int value: %w", err)
-		}
-	case TypeFloat:
-		floatVal, _ := value.Float()
-		if err := itemDf.SetFloat(floatVal); err != nil {
-			return 0, fmt.Errorf("failed to set float value: %w", err)
-		}
-	case TypeString:
-		strVal, _ := value.String()
-		if err := itemDf.SetString(strVal); err != nil {
-			return 0, fmt.Errorf("failed to set string value: %w", err)
-		}
-	case TypeBool:
-		boolVal, _ := value.Bool()
-		if err := itemDf.SetBool(boolVal); err != nil {
-			return 0, fmt.Errorf("failed to set bool value: %w", err)
-		}
-	case TypeBinary:
-		binVal, _ := value.Binary()
-		if err := itemDf.SetBinary(binVal); err != nil {
-			return 0, fmt.Errorf("failed to set binary value: %w", err)
-		}
-	default:
-		return 0, fmt.Errorf("unsupported value type")
-	}
-
-	// Store item
+	itemDf, err := listItemDataFrame(value)
+	if err != nil {
+		return 0, err
+	}
+
 	itemKey := string(MakeListItemKey(key, newIndex))
-	if err := op.set(itemKey, itemDf); err != nil {
+	if err := op.setInBatch(batch, itemKey, itemDf); err != nil {
 		return 0, fmt.Errorf("failed to set list item: %w", err)
 	}
 
@@ -231,15 +427,138 @@ func (op *Operator) PushRightList(key string, value PrimitiveData) (int64, error
 		return 0, fmt.Errorf("failed to update list metadata: %w", err)
 	}
 
-	if err := op.set(listKey, df); err != nil {
+	if err := op.setInBatch(batch, listKey, df); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	return listData.Length, nil
+}
+
+// PushRightMany appends values in order under a single lock and batch and
+// returns the final length.
+func (op *Operator) PushRightMany(key string, values ...PrimitiveData) (_ int64, err error) {
+	defer op.traceOperation("PushRightMany", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return op.pushListMany(key, values, false)
+}
+
+// PushLeftMany prepends values under a single lock and batch so that
+// values[0] ends up leftmost, and returns the final length.
+func (op *Operator) PushLeftMany(key string, values ...PrimitiveData) (_ int64, err error) {
+	defer op.traceOperation("PushLeftMany", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return op.pushListMany(key, values, true)
+}
+
+func (op *Operator) pushListMany(key string, values []PrimitiveData, left bool) (int64, error) {
+	listKey := key
+
+	// Get list metadata
+	df, err := op.get(listKey)
+	if err != nil {
+		return 0, fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	if len(values) == 0 {
+		return listData.Length, nil
+	}
+
+	n := int64(len(values))
+	if listData.Length >= math.MaxInt64-1-n {
+		return 0, fmt.Errorf("list has too many members")
+	}
+
+	// Convert everything up front so a bad value writes nothing
+	items := make([]*DataFrame, len(values))
+	for i, value := range values {
+		if items[i], err = listItemDataFrame(value); err != nil {
+			return 0, err
+		}
+	}
+
+	// The new items occupy a contiguous index range on either end
+	firstIndex := listData.TailIndex + 1
+	if left {
+		firstIndex = listData.HeadIndex - n
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	for i, itemDf := range items {
+		itemKey := string(MakeListItemKey(key, firstIndex+int64(i)))
+		if err := op.setInBatch(batch, itemKey, itemDf); err != nil {
+			return 0, fmt.Errorf("failed to set list item: %w", err)
+		}
+	}
+
+	// Update metadata
+	if left {
+		listData.HeadIndex = firstIndex
+	} else {
+		listData.TailIndex = firstIndex + n - 1
+	}
+	listData.Length += n
+
+	if err := df.SetList(listData); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.setInBatch(batch, listKey, df); err != nil {
 		return 0, fmt.Errorf("failed to update list metadata: %w", err)
 	}
 
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit list batch: %w", err)
+	}
+
 	return listData.Length, nil
 }
 
-func (op *Operator) PopLeftList(key string) (PrimitiveData, error) {
-	unlock := op.lock(key)
+// listItemDataFrame converts value to the DataFrame stored for an item of a
+// list, map or set. Null values cannot be stored in containers.
+func listItemDataFrame(value PrimitiveData) (*DataFrame, error) {
+	if value == nil || value.Type() == TypeNull {
+		return nil, fmt.Errorf("unsupported value type")
+	}
+
+	return primitiveDataFrame(value)
+}
+
+// listItemValue converts an item stored by listItemDataFrame back to its
+// primitive value.
+func listItemValue(df *DataFrame) (PrimitiveData, error) {
+	if df.Type() == TypeNull {
+		return nil, fmt.Errorf("unsupported data type")
+	}
+
+	return dataFramePrimitive(df)
+}
+
+func (op *Operator) PopLeftList(key string) (_ PrimitiveData, err error) {
+	defer op.traceOperation("PopLeftList", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	listKey := key
@@ -256,7 +575,7 @@ func (op *Operator) PopLeftList(key string) (PrimitiveData, error) {
 	}
 
 	if listData.Length == 0 {
-		return nil, fmt.Errorf("list is empty")
+		return nil, ErrListEmpty
 	}
 
 	// Get left item
@@ -267,25 +586,9 @@ func (op *Operator) PopLeftList(key string) (PrimitiveData, error) {
 	}
 
 	// Extract value
-	var value PrimitiveData
-	switch itemDf.Type() {
-	case TypeInt:
-		intVal, _ := itemDf.Int()
-		value = PrimitiveInt(intVal)
-	case TypeFloat:
-		floatVal, _ := itemDf.Float()
-		value = PrimitiveFloat(floatVal)
-	case TypeString:
-		strVal, _ := itemDf.String()
-		value = PrimitiveString(strVal)
-	case TypeBool:
-		boolVal, _ := itemDf.Bool()
-		value = PrimitiveBool(boolVal)
-	case TypeBinary:
-		binVal, _ := itemDf.Binary()
-		value = PrimitiveBinary(binVal)
-	default:
-		return nil, fmt.Errorf("unsupported data type")
+	value, err := listItemValue(itemDf)
+	if err != nil {
+		return nil, err
 	}
 
 	// Delete item
@@ -308,8 +611,13 @@ func (op *Operator) PopLeftList(key string) (PrimitiveData, error) {
 	return value, nil
 }
 
-func (op *Operator) PopRightList(key string) (PrimitiveData, error) {
-	unlock := op.lock(key)
+func (op *Operator) PopRightList(key string) (_ PrimitiveData, err error) {
+	defer op.traceOperation("PopRightList", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	listKey := key
@@ -326,7 +634,7 @@ func (op *Operator) PopRightList(key string) (PrimitiveData, error) {
 	}
 
 	if listData.Length == 0 {
-		return nil, fmt.Errorf("list is empty")
+		return nil, ErrListEmpty
 	}
 
 	// Get right item
@@ -337,25 +645,9 @@ func (op *Operator) PopRightList(key string) (PrimitiveData, error) {
 	}
 
 	// Extract value
-	var value PrimitiveData
-	switch itemDf.Type() {
-	case TypeInt:
-		intVal, _ := itemDf.Int()
-		value = PrimitiveInt(intVal)
-	case TypeFloat:
-		floatVal, _ := itemDf.Float()
-		value = PrimitiveFloat(floatVal)
-	case TypeString:
-		strVal, _ := itemDf.String()
-		value = PrimitiveString(strVal)
-	case TypeBool:
-		boolVal, _ := itemDf.Bool()
-		value = PrimitiveBool(boolVal)
-	case TypeBinary:
-		binVal, _ := itemDf.Binary()
-		value = PrimitiveBinary(binVal)
-	default:
-		return nil, fmt.Errorf("unsupported data type")
+	value, err := listItemValue(itemDf)
+	if err != nil {
+		return nil, err
 	}
 
 	// Delete item
@@ -378,92 +670,215 @@ func (op *Operator) PopRightList(key string) (PrimitiveData, error) {
 	return value, nil
 }
 
-// Query operations
-func (op *Operator) GetListLength(key string) (int64, error) {
-	unlock := op.lock(key)
-	defer unlock()
-
-	listKey := key
-
-	df, err := op.get(listKey)
-	if err != nil {
-		return 0, fmt.Errorf("list %s does not exist: %w", key, err)
-	}
+// PopRightPushLeft moves the item at the tail of srcKey to the head of
+// dstKey and returns it. Both lists are locked for the whole move and the
+// change is committed in one batch, so the item is never in both lists or in
+// neither, even across a crash. When srcKey is empty it returns ErrListEmpty
+// and leaves dstKey untouched. srcKey and dstKey may be the same list, which
+// rotates it; see RotateList.
+func (op *Operator) PopRightPushLeft(srcKey, dstKey string) (_ PrimitiveData, err error) {
+	defer op.traceOperation("PopRightPushLeft", srcKey)(&err)
 
-	listData, err := df.List()
+	unlock, err := op.lockKeys(srcKey, dstKey)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get list data: %w", err)
+		return nil, err
 	}
+	defer unlock()
 
-	return listData.Length, nil
+	return op.popRightPushLeft(srcKey, dstKey)
 }
 
-func (op *Operator) GetListIndex(key string, index int64) (PrimitiveData, error) {
-	unlock := op.lock(key)
+// RotateList moves the tail item of the list at key to its head and returns
+// it. It returns ErrListEmpty when the list has no items.
+func (op *Operator) RotateList(key string) (_ PrimitiveData, err error) {
+	defer op.traceOperation("RotateList", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
-	listKey := key
+	return op.popRightPushLeft(key, key)
+}
 
-	df, err := op.get(listKey)
+func (op *Operator) popRightPushLeft(srcKey, dstKey string) (PrimitiveData, error) {
+	srcDf, err := op.get(srcKey)
 	if err != nil {
-		return nil, fmt.Errorf("list %s does not exist: %w", key, err)
+		return nil, fmt.Errorf("list %s does not exist: %w", srcKey, err)
 	}
 
-	listData, err := df.List()
+	srcData, err := srcDf.List()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get list data: %w", err)
 	}
 
-	if listData.Length == 0 {
-		return nil, fmt.Errorf("list is empty")
+	// A rotation updates a single metadata record
+	dstDf, dstData := srcDf, srcData
+	if dstKey != srcKey {
+		dstDf, err = op.get(dstKey)
+		if err != nil {
+			return nil, fmt.Errorf("list %s does not exist: %w", dstKey, err)
+		}
+
+		dstData, err = dstDf.List()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get list data: %w", err)
+		}
 	}
 
-	// Normalize index (support negative index)
-	actualIndex := index
-	if index < 0 {
-		actualIndex = listData.TailIndex + index + 1
-	} else {
-		actualIndex = listData.HeadIndex + index
+	if srcData.Length == 0 {
+		return nil, ErrListEmpty
 	}
 
-	if actualIndex < listData.HeadIndex || actualIndex > listData.TailIndex {
-		return nil, fmt.Errorf("index out of range")
+	if dstKey != srcKey && dstData.Length >= math.MaxInt64-1 {
+		return nil, fmt.Errorf("list has too many members")
 	}
 
-	// Get item
-	itemKey := string(MakeListItemKey(key, actualIndex))
+	// Get right item
+	itemKey := string(MakeListItemKey(srcKey, srcData.TailIndex))
 	itemDf, err := op.get(itemKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get list item: %w", err)
 	}
 
-	// Extract value
-	var value PrimitiveData
-	switch itemDf.Type() {
-	case TypeInt:
-		intVal, _ := itemDf.Int()
-		value = PrimitiveInt(intVal)
-	case TypeFloat:
-		floatVal, _ := itemDf.Float()
-		value = PrimitiveFloat(floatVal)
-	case TypeString:
-		strVal, _ := itemDf.String()
-		value = PrimitiveString(strVal)
-	case TypeBool:
-		boolVal, _ := itemDf.Bool()
-		value = PrimitiveBool(boolVal)
-	case TypeBinary:
-		binVal, _ := itemDf.Binary()
-		value = PrimitiveBinary(binVal)
-	default:
-		return nil, fmt.Errorf("unsupported data type")
+	value, err := listItemValue(itemDf)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if err := batch.Delete([]byte(itemKey), nil); err != nil {
+		return nil, fmt.Errorf("failed to delete list item: %w", err)
+	}
+
+	srcData.TailIndex--
+	srcData.Length--
+
+	// Calculate new index (decrease HeadIndex for left addition)
+	newIndex := dstData.HeadIndex - 1
+
+	newItemKey := string(MakeListItemKey(dstKey, newIndex))
+	if err := op.setInBatch(batch, newItemKey, itemDf); err != nil {
+		return nil, fmt.Errorf("failed to set list item: %w", err)
+	}
+
+	dstData.HeadIndex = newIndex
+	dstData.Length++
+
+	// Update metadata
+	if err := srcDf.SetList(srcData); err != nil {
+		return nil, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.setInBatch(batch, srcKey, srcDf); err != nil {
+		return nil, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if dstKey != srcKey {
+		if err := dstDf.SetList(dstData); err != nil {
+			return nil, fmt.Errorf("failed to update list metadata: %w", err)
+		}
+
+		if err := op.setInBatch(batch, dstKey, dstDf); err != nil {
+			return nil, fmt.Errorf("failed to update list metadata: %w", err)
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return nil, fmt.Errorf("failed to commit list batch: %w", err)
+	}
+
+	return value, nil
+}
+
+// Query operations
+func (op *Operator) GetListLength(key string) (_ int64, err error) {
+	defer op.traceOperation("GetListLength", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	listKey := key
+
+	df, err := op.get(listKey)
+	if err != nil {
+		return 0, fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	return listData.Length, nil
+}
+
+func (op *Operator) GetListIndex(key string, index int64) (_ PrimitiveData, err error) {
+	defer op.traceOperation("GetListIndex", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	listKey := key
+
+	df, err := op.get(listKey)
+	if err != nil {
+		return nil, fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	if listData.Length == 0 {
+		return nil, ErrListEmpty
+	}
+
+	// Normalize index (support negative index)
+	actualIndex := index
+	if index < 0 {
+		actualIndex = listData.TailIndex + index + 1
+	} else {
+		actualIndex = listData.HeadIndex + index
+	}
+
+	if actualIndex < listData.HeadIndex || actualIndex > listData.TailIndex {
+		return nil, fmt.Errorf("index out of range")
+	}
+
+	// Get item
+	itemKey := string(MakeListItemKey(key, actualIndex))
+	itemDf, err := op.get(itemKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list item: %w", err)
+	}
+
+	// Extract value
+	value, err := listItemValue(itemDf)
+	if err != nil {
+		return nil, err
 	}
 
 	return value, nil
 }
 
-func (op *Operator) GetListRange(key string, start, end int64) ([]PrimitiveData, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetListRange(key string, start, end int64) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("GetListRange", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	return op.listRange(key, start, end)
@@ -520,24 +935,8 @@ func (op *Operator) listRange(key string, start, end int64) ([]PrimitiveData, er
 			continue // Skip if no item
 		}
 
-		var value PrimitiveData
-		switch itemDf.Type() {
-		case TypeInt:
-			intVal, _ := itemDf.Int()
-			value = PrimitiveInt(intVal)
-		case TypeFloat:
-			floatVal, _ := itemDf.Float()
-			value = PrimitiveFloat(floatVal)
-		case TypeString:
-			strVal, _ := itemDf.String()
-			value = PrimitiveString(strVal)
-		case TypeBool:
-			boolVal, _ := itemDf.Bool()
-			value = PrimitiveBool(boolVal)
-		case TypeBinary:
-			binVal, _ := itemDf.Binary()
-			value = PrimitiveBinary(binVal)
-		default:
+		value, err := listItemValue(itemDf)
+		if err != nil {
 			continue
 		}
 
@@ -547,9 +946,84 @@ func (op *Operator) listRange(key string, start, end int64) ([]PrimitiveData, er
 	return result, nil
 }
 
+// ForEachListItem calls fn for every item from head to tail, with its index
+// counted from the head, until fn returns false. Items are read one at a
+// time rather than collected like GetListRange does, so it suits lists too
+// long to hold in memory. The key lock is held throughout, so fn must not
+// operate on the same list.
+func (op *Operator) ForEachListItem(key string, fn func(index int64, value PrimitiveData) bool) (err error) {
+	defer op.traceOperation("ForEachListItem", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return op.forEachListItem(key, false, fn)
+}
+
+// ForEachListItemReverse is ForEachListItem from tail to head. Indexes are
+// still counted from the head, so the first call gets the largest.
+func (op *Operator) ForEachListItemReverse(key string, fn func(index int64, value PrimitiveData) bool) (err error) {
+	defer op.traceOperation("ForEachListItemReverse", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return op.forEachListItem(key, true, fn)
+}
+
+func (op *Operator) forEachListItem(key string, reverse bool, fn func(index int64, value PrimitiveData) bool) error {
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	for i := int64(0); i < listData.Length; i++ {
+		if err := op.ctxErr(); err != nil {
+			return fmt.Errorf("failed to iterate list %s: %w", key, err)
+		}
+
+		index := i
+		if reverse {
+			index = listData.Length - 1 - i
+		}
+
+		itemDf, err := op.get(string(MakeListItemKey(key, listData.HeadIndex+index)))
+		if err != nil {
+			continue // Skip if no item
+		}
+
+		value, err := listItemValue(itemDf)
+		if err != nil {
+			continue
+		}
+
+		if !fn(index, value) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
 // Update operations
-func (op *Operator) SetListIndex(key string, index int64, value PrimitiveData) error {
-	unlock := op.lock(key)
+func (op *Operator) SetListIndex(key string, index int64, value PrimitiveData) (err error) {
+	defer op.traceOperation("SetListIndex", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	listKey := key
@@ -565,7 +1039,7 @@ func (op *Operator) SetListIndex(key string, index int64, value PrimitiveData) e
 	}
 
 	if listData.Length == 0 {
-		return fmt.Errorf("list is empty")
+		return ErrListEmpty
 	}
 
 	// Normalize index
@@ -581,35 +1055,9 @@ func (op *Operator) SetListIndex(key string, index int64, value PrimitiveData) e
 	}
 
 	// Set value to DataFrame
-	itemDf := NULLDataFrame()
-	switch value.Type() {
-	case TypeInt:
-		intVal, _ := value.Int()
-		if err := itemDf.SetInt(intVal); err != nil {
-			return fmt.Errorf("failed to set int value: %w", err)
-		}
-	case TypeFloat:
-		floatVal, _ := value.Float()
-		if err := itemDf.SetFloat(floatVal); err != nil {
-			return fmt.Errorf("failed to set float value: %w", err)
-		}
-	case TypeString:
-		strVal, _ := value.String()
-		if err := itemDf.SetString(strVal); err != nil {
-			return fmt.Errorf("failed to set string value: %w", err)
-		}
-	case TypeBool:
-		boolVal, _ := value.Bool()
-		if err := itemDf.SetBool(boolVal); err != nil {
-			return fmt.Errorf("failed to set bool value: %w", err)
-		}
-	case TypeBinary:
-		binVal, _ := value.Binary()
-		if err := itemDf.SetBinary(binVal); err != nil {
-			return fmt.Errorf("failed to set binary value: %w", err)
-		}
-	default:
-		return fmt.Errorf("unsupported value type")
+	itemDf, err := listItemDataFrame(value)
+	if err != nil {
+		return err
 	}
 
 	// Update item
@@ -621,8 +1069,13 @@ func (op *Operator) SetListIndex(key string, index int64, value PrimitiveData) e
 	return nil
 }
 
-func (op *Operator) TrimList(key string, start, end int64) error {
-	unlock := op.lock(key)
+func (op *Operator) TrimList(key string, start, end int64) (err error) {
+	defer op.traceOperation("TrimList", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	listKey := key
@@ -702,8 +1155,13 @@ func (op *Operator) TrimList(key string, start, end int64) error {
 	return nil
 }
 
-func (op *Operator) GetAllListMembersAndDelete(key string) ([]PrimitiveData, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetAllListMembersAndDelete(key string) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("GetAllListMembersAndDelete", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -731,3 +1189,622 @@ func (op *Operator) GetAllListMembersAndDelete(key string) ([]PrimitiveData, err
 
 	return members, nil
 }
+
+// PopLeftBatchAndAck reads up to max elements from the head of the list and
+// passes them to ack. The elements are removed only if ack returns nil;
+// otherwise the list is left untouched and ack's error is returned. The list
+// lock is held while ack runs, so ack must be fast.
+func (op *Operator) PopLeftBatchAndAck(key string, max int, ack func([]PrimitiveData) error) (_ int, err error) {
+	defer op.traceOperation("PopLeftBatchAndAck", key)(&err)
+
+	if max <= 0 {
+		return 0, fmt.Errorf("max must be positive")
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return 0, fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	if listData.Length == 0 {
+		return 0, nil
+	}
+
+	count := int64(max)
+	if count > listData.Length {
+		count = listData.Length
+	}
+
+	values := make([]PrimitiveData, 0, count)
+	for i := listData.HeadIndex; i < listData.HeadIndex+count; i++ {
+		itemDf, err := op.get(string(MakeListItemKey(key, i)))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get list item: %w", err)
+		}
+
+		value, err := listItemValue(itemDf)
+		if err != nil {
+			return 0, err
+		}
+
+		values = append(values, value)
+	}
+
+	if err := ack(values); err != nil {
+		return 0, fmt.Errorf("ack rejected batch: %w", err)
+	}
+
+	// Remove the acknowledged items and update metadata in one batch
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	for i := listData.HeadIndex; i < listData.HeadIndex+count; i++ {
+		if err := batch.Delete(MakeListItemKey(key, i), nil); err != nil {
+			return 0, fmt.Errorf("failed to delete list item: %w", err)
+		}
+	}
+
+	listData.HeadIndex += count
+	listData.Length -= count
+
+	if err := df.SetList(listData); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.setInBatch(batch, key, df); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit list batch: %w", err)
+	}
+
+	return int(count), nil
+}
+
+// RepairList reconciles the list metadata with the item keys actually
+// stored. Items outside HeadIndex..TailIndex are removed as orphans, and the
+// remaining items are renumbered so the list has no holes. If the metadata
+// itself is gone, every leftover item is an orphan.
+func (op *Operator) RepairList(key string) (_ int64, err error) {
+	defer op.traceOperation("RepairList", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if err := op.ctxErr(); err != nil {
+		return 0, fmt.Errorf("failed to repair list %s: %w", key, err)
+	}
+
+	// Collect the indexes of all stored items. Negative indexes encode with a
+	// leading 0xff byte, so bound the scan by the byte after ':' instead.
+	entryKey := string(MakeListEntryKey(key))
+	prefix := entryKey + ":"
+	iter, err := op.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(prefix),
+		UpperBound: []byte(entryKey + ";"),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
+	}
+
+	var indexes []int64
+	for iter.First(); iter.Valid(); iter.Next() {
+		k := iter.Key()
+		if len(k) != len(prefix)+8 {
+			continue
+		}
+		indexes = append(indexes, int64(binary.BigEndian.Uint64(k[len(prefix):])))
+	}
+	if err := iter.Error(); err != nil {
+		iter.Close()
+		return 0, fmt.Errorf("iterator error: %w", err)
+	}
+	iter.Close()
+
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	df, err := op.get(key)
+	if err != nil {
+		if !isNotExist(err) {
+			return 0, fmt.Errorf("failed to get list %s: %w", key, err)
+		}
+
+		for _, idx := range indexes {
+			if err := batch.Delete(MakeListItemKey(key, idx), nil); err != nil {
+				return 0, fmt.Errorf("failed to delete orphan list item: %w", err)
+			}
+		}
+
+		if err := op.commit(batch); err != nil {
+			return 0, fmt.Errorf("failed to commit list batch: %w", err)
+		}
+
+		return int64(len(indexes)), nil
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	// Remove orphans outside the live range
+	var orphans int64
+	live := make([]int64, 0, len(indexes))
+	for _, idx := range indexes {
+		if idx >= listData.HeadIndex && idx <= listData.TailIndex {
+			live = append(live, idx)
+			continue
+		}
+
+		if err := batch.Delete(MakeListItemKey(key, idx), nil); err != nil {
+			return 0, fmt.Errorf("failed to delete orphan list item: %w", err)
+		}
+		orphans++
+	}
+
+	// Close holes left by missing items
+	for i, idx := range live {
+		newIndex := listData.HeadIndex + int64(i)
+		if idx == newIndex {
+			continue
+		}
+
+		value, closer, err := op.db.Get(MakeListItemKey(key, idx))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get list item: %w", err)
+		}
+		value, err = op.rebind(string(MakeListItemKey(key, idx)), string(MakeListItemKey(key, newIndex)), value)
+		if err == nil {
+			err = batch.Set(MakeListItemKey(key, newIndex), value, nil)
+		}
+		closer.Close()
+		if err != nil {
+			return 0, fmt.Errorf("failed to move list item: %w", err)
+		}
+
+		if err := batch.Delete(MakeListItemKey(key, idx), nil); err != nil {
+			return 0, fmt.Errorf("failed to move list item: %w", err)
+		}
+	}
+
+	tailIndex := listData.HeadIndex + int64(len(live)) - 1
+	if orphans == 0 && listData.TailIndex == tailIndex && listData.Length == int64(len(live)) {
+		return 0, nil
+	}
+
+	listData.TailIndex = tailIndex
+	listData.Length = int64(len(live))
+
+	if err := df.SetList(listData); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.setInBatch(batch, key, df); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit list batch: %w", err)
+	}
+
+	return orphans, nil
+}
+
+// SearchSortedList binary-searches a list the caller keeps sorted by cmp,
+// which returns a negative number, zero or a positive number when a sorts
+// before, equal to or after b. It returns the position of target, or the
+// position at which it would have to be inserted to keep the order.
+func (op *Operator) SearchSortedList(key string, target PrimitiveData, cmp func(a, b PrimitiveData) int) (index int64, found bool, err error) {
+	defer op.traceOperation("SearchSortedList", key)(&err)
+
+	if cmp == nil {
+		return 0, false, fmt.Errorf("compare function cannot be nil")
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, false, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return 0, false, fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	// Search positions [lo, hi) relative to HeadIndex
+	lo, hi := int64(0), listData.Length
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		itemDf, err := op.get(string(MakeListItemKey(key, listData.HeadIndex+mid)))
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to get list item: %w", err)
+		}
+
+		value, err := listItemValue(itemDf)
+		if err != nil {
+			return 0, false, err
+		}
+
+		c := cmp(value, target)
+		switch {
+		case c == 0:
+			return mid, true, nil
+		case c < 0:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+
+	return lo, false, nil
+}
+
+// ListPosition returns the indexes of items equal to value, comparing type
+// and value, like Redis LPOS. rank picks the match to start from: 1 is the
+// first match from the head, 2 the second, and -1 the first from the tail,
+// in which case indexes are returned from the tail towards the head. count
+// limits how many indexes are returned, with 0 returning every match from
+// rank on. Indexes are counted from the head either way.
+func (op *Operator) ListPosition(key string, value PrimitiveData, rank int, count int) (_ []int64, err error) {
+	defer op.traceOperation("ListPosition", key)(&err)
+
+	if rank == 0 {
+		return nil, fmt.Errorf("rank cannot be zero")
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("count cannot be negative, got %d", count)
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return nil, fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	valueDf, err := listItemDataFrame(value)
+	if err != nil {
+		return nil, err
+	}
+
+	reverse := rank < 0
+	skip := rank - 1
+	if reverse {
+		skip = -rank - 1
+	}
+
+	positions := []int64{}
+	for i := int64(0); i < listData.Length; i++ {
+		if err := op.ctxErr(); err != nil {
+			return nil, fmt.Errorf("failed to search list %s: %w", key, err)
+		}
+
+		index := i
+		if reverse {
+			index = listData.Length - 1 - i
+		}
+
+		itemDf, err := op.get(string(MakeListItemKey(key, listData.HeadIndex+index)))
+		if err != nil {
+			if isNotExist(err) {
+				continue // Skip if no item
+			}
+			return nil, fmt.Errorf("failed to get list item: %w", err)
+		}
+		if itemDf.typ != valueDf.typ || !bytes.Equal(itemDf.payload, valueDf.payload) {
+			continue
+		}
+
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		positions = append(positions, index)
+		if count > 0 && len(positions) == count {
+			break
+		}
+	}
+
+	return positions, nil
+}
+
+// ListInsertBefore inserts value in front of the first item equal to pivot,
+// comparing type and value, and returns the new length. It returns
+// ErrListPivotNotFound when no item matches.
+func (op *Operator) ListInsertBefore(key string, pivot, value PrimitiveData) (_ int64, err error) {
+	defer op.traceOperation("ListInsertBefore", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return op.listInsert(key, pivot, value, false)
+}
+
+// ListInsertAfter inserts value behind the first item equal to pivot,
+// comparing type and value, and returns the new length. It returns
+// ErrListPivotNotFound when no item matches.
+func (op *Operator) ListInsertAfter(key string, pivot, value PrimitiveData) (_ int64, err error) {
+	defer op.traceOperation("ListInsertAfter", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return op.listInsert(key, pivot, value, true)
+}
+
+// listInsert opens a slot next to the pivot by moving the items on the
+// shorter side of it one index outwards, extending HeadIndex or TailIndex,
+// so an insert near either end touches few items.
+func (op *Operator) listInsert(key string, pivot, value PrimitiveData, after bool) (int64, error) {
+	listKey := key
+
+	df, err := op.get(listKey)
+	if err != nil {
+		return 0, fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	if listData.Length >= math.MaxInt64-1 {
+		return 0, fmt.Errorf("list has too many members")
+	}
+
+	pivotDf, err := listItemDataFrame(pivot)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pivot: %w", err)
+	}
+
+	itemDf, err := listItemDataFrame(value)
+	if err != nil {
+		return 0, err
+	}
+
+	// Find the pivot position relative to HeadIndex
+	pos := int64(-1)
+	for i := int64(0); i < listData.Length; i++ {
+		candidate, err := op.get(string(MakeListItemKey(key, listData.HeadIndex+i)))
+		if err != nil {
+			if isNotExist(err) {
+				continue // Skip if no item
+			}
+			return 0, fmt.Errorf("failed to get list item: %w", err)
+		}
+		if candidate.typ == pivotDf.typ && bytes.Equal(candidate.payload, pivotDf.payload) {
+			pos = i
+			break
+		}
+	}
+	if pos < 0 {
+		return 0, ErrListPivotNotFound
+	}
+
+	// The new item takes relative position at
+	at := pos
+	if after {
+		at++
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if at < listData.Length-at {
+		// Move the items in front of the slot one index to the left
+		for i := listData.HeadIndex; i < listData.HeadIndex+at; i++ {
+			if err := op.moveListItemInBatch(batch, key, i, i-1); err != nil {
+				return 0, err
+			}
+		}
+		listData.HeadIndex--
+	} else {
+		// Move the items from the slot on one index to the right
+		for i := listData.TailIndex; i >= listData.HeadIndex+at; i-- {
+			if err := op.moveListItemInBatch(batch, key, i, i+1); err != nil {
+				return 0, err
+			}
+		}
+		listData.TailIndex++
+	}
+
+	itemKey := string(MakeListItemKey(key, listData.HeadIndex+at))
+	if err := op.setInBatch(batch, itemKey, itemDf); err != nil {
+		return 0, fmt.Errorf("failed to set list item: %w", err)
+	}
+
+	// Update metadata
+	listData.Length++
+
+	if err := df.SetList(listData); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.setInBatch(batch, listKey, df); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit list batch: %w", err)
+	}
+
+	return listData.Length, nil
+}
+
+// ListRemove removes items equal to value, comparing type and value, and
+// returns how many it removed. Like Redis LREM, a positive count removes up
+// to count matches from the head, a negative count up to -count matches from
+// the tail, and zero removes every match.
+//
+// Item keys are indexes, so removing from the middle would leave holes. The
+// items behind each removed one are re-keyed one index down for every
+// removal before them, keeping indexes contiguous from HeadIndex; TailIndex
+// and Length shrink accordingly.
+func (op *Operator) ListRemove(key string, count int64, value PrimitiveData) (removed int64, err error) {
+	defer op.traceOperation("ListRemove", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	listKey := key
+
+	df, err := op.get(listKey)
+	if err != nil {
+		return 0, fmt.Errorf("list %s does not exist: %w", key, err)
+	}
+
+	listData, err := df.List()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get list data: %w", err)
+	}
+
+	valueDf, err := listItemDataFrame(value)
+	if err != nil {
+		return 0, err
+	}
+
+	// Find matching indexes in list order
+	var matches []int64
+	for i := listData.HeadIndex; i <= listData.TailIndex; i++ {
+		itemDf, err := op.get(string(MakeListItemKey(key, i)))
+		if err != nil {
+			if isNotExist(err) {
+				continue // Skip if no item
+			}
+			return 0, fmt.Errorf("failed to get list item: %w", err)
+		}
+		if itemDf.typ == valueDf.typ && bytes.Equal(itemDf.payload, valueDf.payload) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch {
+	case count > 0 && int64(len(matches)) > count:
+		matches = matches[:count]
+	case count < 0 && int64(len(matches)) > -count:
+		matches = matches[int64(len(matches))+count:]
+	}
+	if len(matches) == 0 {
+		return 0, nil
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	// Compact from the first match on, writing survivors at next
+	next := matches[0]
+	for i, m := matches[0], 0; i <= listData.TailIndex; i++ {
+		if m < len(matches) && matches[m] == i {
+			m++
+			continue
+		}
+		if err := op.moveListItemInBatch(batch, key, i, next); err != nil {
+			return 0, err
+		}
+		next++
+	}
+
+	// Clear the slots vacated at the tail
+	for i := next; i <= listData.TailIndex; i++ {
+		if err := batch.Delete(MakeListItemKey(key, i), nil); err != nil {
+			return 0, fmt.Errorf("failed to delete list item: %w", err)
+		}
+	}
+
+	// Update metadata
+	listData.TailIndex = next - 1
+	listData.Length = listData.TailIndex - listData.HeadIndex + 1
+	if listData.Length == 0 {
+		listData.HeadIndex = 0
+		listData.TailIndex = -1
+	}
+
+	if err := df.SetList(listData); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.setInBatch(batch, listKey, df); err != nil {
+		return 0, fmt.Errorf("failed to update list metadata: %w", err)
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit list batch: %w", err)
+	}
+
+	return int64(len(matches)), nil
+}
+
+// moveListItemInBatch stages a copy of the stored item at index from to index
+// to, keeping its encoded value as is unless it is sealed to its key. A hole
+// at from becomes a hole at to.
+func (op *Operator) moveListItemInBatch(batch *storeBatch, key string, from, to int64) error {
+	if from == to {
+		return nil
+	}
+
+	value, closer, err := op.db.Get(MakeListItemKey(key, from))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			if err := batch.Delete(MakeListItemKey(key, to), nil); err != nil {
+				return fmt.Errorf("failed to move list item: %w", err)
+			}
+			return nil
+		}
+		return fmt.Errorf("failed to get list item: %w", err)
+	}
+	defer closer.Close()
+
+	value, err = op.rebind(string(MakeListItemKey(key, from)), string(MakeListItemKey(key, to)), value)
+	if err != nil {
+		return fmt.Errorf("failed to move list item: %w", err)
+	}
+	if err := batch.Set(MakeListItemKey(key, to), value, nil); err != nil {
+		return fmt.Errorf("failed to move list item: %w", err)
+	}
+
+	return nil
+}
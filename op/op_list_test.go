@@ -1,8 +1,16 @@
 ﻿package op
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/rivulet-io/tower/util/size"
 )
@@ -462,3 +470,1118 @@ func TestListConcurrentAccess(t *testing.T) {
 	}
 }
 
+func TestListPopLeftBatchAndAck(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test_list_batch"
+
+	if err := tower.CreateList(key); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := tower.PushRightList(key, PrimitiveInt(i)); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+	}
+
+	// Rejected ack leaves the list untouched
+	_, err := tower.PopLeftBatchAndAck(key, 3, func(values []PrimitiveData) error {
+		return fmt.Errorf("downstream unavailable")
+	})
+	if err == nil {
+		t.Fatal("Expected error when ack fails")
+	}
+
+	length, err := tower.GetListLength(key)
+	if err != nil {
+		t.Fatalf("Failed to get list length: %v", err)
+	}
+	if length != 5 {
+		t.Errorf("Expected length 5 after rejected ack, got %d", length)
+	}
+
+	// Accepted ack removes the batch
+	var received []int64
+	n, err := tower.PopLeftBatchAndAck(key, 3, func(values []PrimitiveData) error {
+		for _, v := range values {
+			i, _ := v.Int()
+			received = append(received, i)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to pop batch: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 popped items, got %d", n)
+	}
+	for i, v := range received {
+		if v != int64(i) {
+			t.Errorf("Expected item %d to be %d, got %d", i, i, v)
+		}
+	}
+
+	remaining, err := tower.GetListRange(key, 0, -1)
+	if err != nil {
+		t.Fatalf("Failed to get list range: %v", err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("Expected 2 remaining items, got %d", len(remaining))
+	}
+	if v, _ := remaining[0].Int(); v != 3 {
+		t.Errorf("Expected head to be 3, got %d", v)
+	}
+
+	// Max larger than the list drains it
+	n, err = tower.PopLeftBatchAndAck(key, 10, func(values []PrimitiveData) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to drain list: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 popped items, got %d", n)
+	}
+
+	n, err = tower.PopLeftBatchAndAck(key, 10, func(values []PrimitiveData) error {
+		t.Error("ack should not be called on empty list")
+		return nil
+	})
+	if err != nil || n != 0 {
+		t.Errorf("Expected (0, nil) on empty list, got (%d, %v)", n, err)
+	}
+}
+
+func TestRepairList(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	t.Run("removes orphans outside range", func(t *testing.T) {
+		key := "repair_orphans"
+		if err := tower.CreateList(key); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := tower.PushRightList(key, PrimitiveInt(i)); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+		}
+
+		// Simulate an item write whose metadata update never landed
+		orphan := NULLDataFrame()
+		orphan.SetInt(99)
+		if err := tower.set(string(MakeListItemKey(key, 3)), orphan); err != nil {
+			t.Fatalf("Failed to write orphan: %v", err)
+		}
+
+		removed, err := tower.RepairList(key)
+		if err != nil {
+			t.Fatalf("Failed to repair list: %v", err)
+		}
+		if removed != 1 {
+			t.Errorf("Expected 1 orphan removed, got %d", removed)
+		}
+
+		if _, err := tower.get(string(MakeListItemKey(key, 3))); err == nil {
+			t.Error("Expected orphan item to be deleted")
+		}
+
+		length, _ := tower.GetListLength(key)
+		if length != 3 {
+			t.Errorf("Expected length 3, got %d", length)
+		}
+	})
+
+	t.Run("closes holes", func(t *testing.T) {
+		key := "repair_holes"
+		if err := tower.CreateList(key); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		for i := 0; i < 4; i++ {
+			if _, err := tower.PushRightList(key, PrimitiveInt(i)); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+		}
+
+		// Simulate an interrupted delete that lost an item
+		if err := tower.delete(string(MakeListItemKey(key, 1))); err != nil {
+			t.Fatalf("Failed to delete item: %v", err)
+		}
+
+		removed, err := tower.RepairList(key)
+		if err != nil {
+			t.Fatalf("Failed to repair list: %v", err)
+		}
+		if removed != 0 {
+			t.Errorf("Expected 0 orphans removed, got %d", removed)
+		}
+
+		values, err := tower.GetListRange(key, 0, -1)
+		if err != nil {
+			t.Fatalf("Failed to get list range: %v", err)
+		}
+		expected := []int64{0, 2, 3}
+		if len(values) != len(expected) {
+			t.Fatalf("Expected %d items, got %d", len(expected), len(values))
+		}
+		for i, v := range values {
+			if n, _ := v.Int(); n != expected[i] {
+				t.Errorf("Expected item %d to be %d, got %d", i, expected[i], n)
+			}
+		}
+	})
+
+	t.Run("missing metadata", func(t *testing.T) {
+		key := "repair_no_meta"
+		if err := tower.CreateList(key); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := tower.PushLeftList(key, PrimitiveInt(i)); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+		}
+		if err := tower.delete(key); err != nil {
+			t.Fatalf("Failed to delete metadata: %v", err)
+		}
+
+		removed, err := tower.RepairList(key)
+		if err != nil {
+			t.Fatalf("Failed to repair list: %v", err)
+		}
+		if removed != 2 {
+			t.Errorf("Expected 2 orphans removed, got %d", removed)
+		}
+	})
+
+	t.Run("consistent list is untouched", func(t *testing.T) {
+		key := "repair_ok"
+		if err := tower.CreateList(key); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		if _, err := tower.PushRightList(key, PrimitiveInt(1)); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+
+		removed, err := tower.RepairList(key)
+		if err != nil || removed != 0 {
+			t.Errorf("Expected (0, nil), got (%d, %v)", removed, err)
+		}
+	})
+}
+
+func TestSearchSortedList(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "sorted_list"
+	if err := tower.CreateList(key); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	cmp := func(a, b PrimitiveData) int {
+		x, _ := a.Int()
+		y, _ := b.Int()
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	t.Run("empty list", func(t *testing.T) {
+		index, found, err := tower.SearchSortedList(key, PrimitiveInt(5), cmp)
+		if err != nil || found || index != 0 {
+			t.Errorf("Expected (0, false, nil), got (%d, %v, %v)", index, found, err)
+		}
+	})
+
+	// Build 10, 20, ..., 50 with a left push so HeadIndex is negative
+	for _, v := range []int64{20, 30, 40, 50} {
+		if _, err := tower.PushRightList(key, PrimitiveInt(v)); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+	}
+	if _, err := tower.PushLeftList(key, PrimitiveInt(10)); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+
+	tests := []struct {
+		target int64
+		index  int64
+		found  bool
+	}{
+		{10, 0, true},
+		{30, 2, true},
+		{50, 4, true},
+		{5, 0, false},
+		{35, 3, false},
+		{60, 5, false},
+	}
+
+	for _, tt := range tests {
+		index, found, err := tower.SearchSortedList(key, PrimitiveInt(tt.target), cmp)
+		if err != nil {
+			t.Fatalf("Failed to search for %d: %v", tt.target, err)
+		}
+		if index != tt.index || found != tt.found {
+			t.Errorf("Search %d: expected (%d, %v), got (%d, %v)", tt.target, tt.index, tt.found, index, found)
+		}
+	}
+
+	if _, _, err := tower.SearchSortedList(key, PrimitiveInt(1), nil); err == nil {
+		t.Error("Expected error for nil compare function")
+	}
+}
+
+func TestPushRightListBounded(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "bounded"
+	if err := tower.CreateList(key); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		pushed, length, err := tower.PushRightListBounded(key, PrimitiveInt(int64(i)), 3)
+		if err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+		if !pushed || length != int64(i) {
+			t.Errorf("Expected (true, %d), got (%v, %d)", i, pushed, length)
+		}
+	}
+
+	pushed, length, err := tower.PushRightListBounded(key, PrimitiveInt(4), 3)
+	if err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if pushed || length != 3 {
+		t.Errorf("Expected full list to reject push with (false, 3), got (%v, %d)", pushed, length)
+	}
+
+	// Nothing was evicted to make room
+	head, err := tower.GetListIndex(key, 0)
+	if err != nil {
+		t.Fatalf("Failed to get head: %v", err)
+	}
+	if v, _ := head.Int(); v != 1 {
+		t.Errorf("Expected head 1, got %d", v)
+	}
+
+	if _, err := tower.PopLeftList(key); err != nil {
+		t.Fatalf("Failed to pop: %v", err)
+	}
+	pushed, length, err = tower.PushRightListBounded(key, PrimitiveInt(4), 3)
+	if err != nil || !pushed || length != 3 {
+		t.Errorf("Expected push after pop to succeed with (true, 3), got (%v, %d, %v)", pushed, length, err)
+	}
+
+	if _, _, err := tower.PushRightListBounded(key, PrimitiveInt(5), 0); err == nil {
+		t.Error("Expected error for non-positive max length")
+	}
+	if _, _, err := tower.PushRightListBounded("missing", PrimitiveInt(1), 3); err == nil {
+		t.Error("Expected error for missing list")
+	}
+}
+
+func TestListPushCapped(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "ring"
+	if err := tower.CreateList(key); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		length, err := tower.PushRightCapped(key, PrimitiveInt(int64(i)), 100)
+		if err != nil {
+			t.Fatalf("Failed to push %d: %v", i, err)
+		}
+		if want := min(int64(i+1), 100); length != want {
+			t.Fatalf("Expected length %d after push %d, got %d", want, i, length)
+		}
+	}
+
+	values, err := tower.GetListRange(key, 0, -1)
+	if err != nil {
+		t.Fatalf("Failed to get range: %v", err)
+	}
+	if len(values) != 100 {
+		t.Fatalf("Expected 100 values, got %d", len(values))
+	}
+	for i, value := range values {
+		if v, _ := value.Int(); v != int64(900+i) {
+			t.Fatalf("Expected %d at index %d, got %d", 900+i, i, v)
+		}
+	}
+
+	// Trimmed items are removed from the store, not just hidden
+	removed, err := tower.RepairList(key)
+	if err != nil {
+		t.Fatalf("Failed to repair list: %v", err)
+	}
+	if removed != 0 {
+		t.Errorf("Expected no orphaned items, got %d", removed)
+	}
+
+	// Pushing on the left trims the tail, down to a smaller cap at once
+	length, err := tower.PushLeftCapped(key, PrimitiveInt(-1), 3)
+	if err != nil {
+		t.Fatalf("Failed to push left: %v", err)
+	}
+	if length != 3 {
+		t.Errorf("Expected length 3, got %d", length)
+	}
+	values, err = tower.GetListRange(key, 0, -1)
+	if err != nil {
+		t.Fatalf("Failed to get range: %v", err)
+	}
+	var got []int64
+	for _, value := range values {
+		v, _ := value.Int()
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int64{-1, 900, 901}) {
+		t.Errorf("Expected [-1 900 901], got %v", got)
+	}
+
+	if _, err := tower.PushRightCapped(key, PrimitiveInt(1), 0); err == nil {
+		t.Error("Expected error for non-positive max length")
+	}
+	if _, err := tower.PushLeftCapped("missing", PrimitiveInt(1), 3); err == nil {
+		t.Error("Expected error for missing list")
+	}
+}
+
+func TestListPushMany(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "many"
+	if err := tower.CreateList(key); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	length, err := tower.PushRightMany(key, PrimitiveInt(3), PrimitiveInt(4), PrimitiveInt(5))
+	if err != nil || length != 3 {
+		t.Fatalf("Expected (3, nil), got (%d, %v)", length, err)
+	}
+
+	length, err = tower.PushLeftMany(key, PrimitiveInt(1), PrimitiveInt(2))
+	if err != nil || length != 5 {
+		t.Fatalf("Expected (5, nil), got (%d, %v)", length, err)
+	}
+
+	items, err := tower.GetListRange(key, 0, -1)
+	if err != nil {
+		t.Fatalf("Failed to get range: %v", err)
+	}
+	got := make([]int64, len(items))
+	for i, item := range items {
+		got[i], _ = item.Int()
+	}
+	if fmt.Sprint(got) != "[1 2 3 4 5]" {
+		t.Errorf("Expected [1 2 3 4 5], got %v", got)
+	}
+
+	// Mixes with single pushes
+	if _, err := tower.PushLeftList(key, PrimitiveInt(0)); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if _, err := tower.PushRightMany(key, PrimitiveInt(6)); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if v, _ := tower.GetListIndex(key, 0); v != PrimitiveInt(0) {
+		t.Errorf("Expected head 0, got %v", v)
+	}
+	if v, _ := tower.GetListIndex(key, -1); v != PrimitiveInt(6) {
+		t.Errorf("Expected tail 6, got %v", v)
+	}
+
+	if length, err := tower.PushRightMany(key); err != nil || length != 7 {
+		t.Errorf("Expected empty push to return (7, nil), got (%d, %v)", length, err)
+	}
+
+	// An unsupported value rejects the whole batch
+	if _, err := tower.PushRightMany(key, PrimitiveInt(7), PrimitiveNull{}); err == nil {
+		t.Error("Expected error for unsupported value")
+	}
+	if length, _ := tower.GetListLength(key); length != 7 {
+		t.Errorf("Expected length to stay 7, got %d", length)
+	}
+
+	if _, err := tower.PushLeftMany("missing", PrimitiveInt(1)); err == nil {
+		t.Error("Expected error for missing list")
+	}
+}
+
+func listContents(t *testing.T, tower *Operator, key string) string {
+	t.Helper()
+
+	values, err := tower.GetListRange(key, 0, -1)
+	if err != nil {
+		t.Fatalf("Failed to get list range: %v", err)
+	}
+	return fmt.Sprint(values)
+}
+
+func createTestIntList(t *testing.T, tower *Operator, key string, values ...int64) {
+	t.Helper()
+
+	if err := tower.CreateList(key); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	for _, v := range values {
+		if _, err := tower.PushRightList(key, PrimitiveInt(v)); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+	}
+}
+
+func TestListInsert(t *testing.T) {
+	tests := []struct {
+		name  string
+		after bool
+		pivot int64
+		want  string
+	}{
+		{"before head", false, 1, "[0 1 2 3 4 5]"},
+		{"before near head", false, 2, "[1 0 2 3 4 5]"},
+		{"before near tail", false, 5, "[1 2 3 4 0 5]"},
+		{"after head", true, 1, "[1 0 2 3 4 5]"},
+		{"after near tail", true, 4, "[1 2 3 4 0 5]"},
+		{"after tail", true, 5, "[1 2 3 4 5 0]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tower := createTestTower(t)
+			defer tower.Close()
+
+			createTestIntList(t, tower, "list", 1, 2, 3, 4, 5)
+
+			insert := tower.ListInsertBefore
+			if tt.after {
+				insert = tower.ListInsertAfter
+			}
+			length, err := insert("list", PrimitiveInt(tt.pivot), PrimitiveInt(0))
+			if err != nil || length != 6 {
+				t.Fatalf("Expected length 6, got %d (%v)", length, err)
+			}
+			if got := listContents(t, tower, "list"); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+
+			// Pushes and pops keep working on the shifted indexes
+			if _, err := tower.PushLeftList("list", PrimitiveInt(-1)); err != nil {
+				t.Fatalf("Failed to push left: %v", err)
+			}
+			if got := listContents(t, tower, "list"); got != "[-1 "+tt.want[1:] {
+				t.Errorf("Expected [-1 %s, got %s", tt.want[1:], got)
+			}
+			items := strings.Fields(strings.Trim(tt.want, "[]"))
+			if v, err := tower.PopRightList("list"); err != nil {
+				t.Fatalf("Failed to pop right: %v", err)
+			} else if n, _ := v.Int(); fmt.Sprint(n) != items[len(items)-1] {
+				t.Errorf("Expected tail %s, got %d", items[len(items)-1], n)
+			}
+			if repaired, err := tower.RepairList("list"); err != nil || repaired != 0 {
+				t.Errorf("Expected consistent list, repair removed %d (%v)", repaired, err)
+			}
+		})
+	}
+
+	t.Run("first matching pivot", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		createTestIntList(t, tower, "list", 7, 8, 7)
+
+		if _, err := tower.ListInsertAfter("list", PrimitiveInt(7), PrimitiveInt(0)); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+		if got := listContents(t, tower, "list"); got != "[7 0 8 7]" {
+			t.Errorf("Expected [7 0 8 7], got %s", got)
+		}
+	})
+
+	t.Run("pivot not found", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		createTestIntList(t, tower, "list", 1, 2, 3)
+
+		// A string "1" is not the int 1
+		_, err := tower.ListInsertBefore("list", PrimitiveString("1"), PrimitiveInt(0))
+		if !errors.Is(err, ErrListPivotNotFound) {
+			t.Errorf("Expected ErrListPivotNotFound, got %v", err)
+		}
+		if got := listContents(t, tower, "list"); got != "[1 2 3]" {
+			t.Errorf("Expected list to be unchanged, got %s", got)
+		}
+
+		if err := tower.CreateList("empty"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		if _, err := tower.ListInsertAfter("empty", PrimitiveInt(1), PrimitiveInt(0)); !errors.Is(err, ErrListPivotNotFound) {
+			t.Errorf("Expected ErrListPivotNotFound on empty list, got %v", err)
+		}
+
+		if _, err := tower.ListInsertAfter("missing", PrimitiveInt(1), PrimitiveInt(0)); err == nil || errors.Is(err, ErrListPivotNotFound) {
+			t.Errorf("Expected does-not-exist error, got %v", err)
+		}
+	})
+}
+
+func TestListRemove(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int64
+		removed int64
+		want    string
+	}{
+		{"all", 0, 3, "[1 2 3]"},
+		{"from head", 2, 2, "[1 2 9 3]"},
+		{"from tail", -2, 2, "[9 1 2 3]"},
+		{"more than present", 10, 3, "[1 2 3]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tower := createTestTower(t)
+			defer tower.Close()
+
+			createTestIntList(t, tower, "list", 9, 1, 9, 2, 9, 3)
+
+			removed, err := tower.ListRemove("list", tt.count, PrimitiveInt(9))
+			if err != nil || removed != tt.removed {
+				t.Fatalf("Expected %d removed, got %d (%v)", tt.removed, removed, err)
+			}
+			if got := listContents(t, tower, "list"); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+
+			// Survivors are re-keyed without holes
+			length, err := tower.GetListLength("list")
+			if err != nil || length != 6-tt.removed {
+				t.Errorf("Expected length %d, got %d (%v)", 6-tt.removed, length, err)
+			}
+			if repaired, err := tower.RepairList("list"); err != nil || repaired != 0 {
+				t.Errorf("Expected no orphaned items, repair removed %d (%v)", repaired, err)
+			}
+			last, err := tower.GetListIndex("list", -1)
+			if err != nil {
+				t.Fatalf("Failed to get last item: %v", err)
+			}
+			items := strings.Fields(strings.Trim(tt.want, "[]"))
+			if n, _ := last.Int(); fmt.Sprint(n) != items[len(items)-1] {
+				t.Errorf("Expected last item %s, got %d", items[len(items)-1], n)
+			}
+		})
+	}
+
+	t.Run("re-keying after pops", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		// Left pushes and pops move HeadIndex away from zero
+		createTestIntList(t, tower, "list", 1, 2, 3)
+		for _, v := range []int64{5, 4} {
+			if _, err := tower.PushLeftList("list", PrimitiveInt(v)); err != nil {
+				t.Fatalf("Failed to push left: %v", err)
+			}
+		}
+		if _, err := tower.PopLeftList("list"); err != nil {
+			t.Fatalf("Failed to pop: %v", err)
+		}
+
+		if removed, err := tower.ListRemove("list", 0, PrimitiveInt(2)); err != nil || removed != 1 {
+			t.Fatalf("Expected 1 removed, got %d (%v)", removed, err)
+		}
+		if got := listContents(t, tower, "list"); got != "[5 1 3]" {
+			t.Errorf("Expected [5 1 3], got %s", got)
+		}
+
+		if _, err := tower.PushRightList("list", PrimitiveInt(6)); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+		if got := listContents(t, tower, "list"); got != "[5 1 3 6]" {
+			t.Errorf("Expected [5 1 3 6], got %s", got)
+		}
+	})
+
+	t.Run("remove everything", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		createTestIntList(t, tower, "list", 4, 4, 4)
+
+		if removed, err := tower.ListRemove("list", 0, PrimitiveInt(4)); err != nil || removed != 3 {
+			t.Fatalf("Expected 3 removed, got %d (%v)", removed, err)
+		}
+		if length, err := tower.GetListLength("list"); err != nil || length != 0 {
+			t.Errorf("Expected empty list, got %d (%v)", length, err)
+		}
+		if _, err := tower.PushRightList("list", PrimitiveInt(1)); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+		if got := listContents(t, tower, "list"); got != "[1]" {
+			t.Errorf("Expected [1], got %s", got)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		createTestIntList(t, tower, "list", 1, 2)
+
+		if removed, err := tower.ListRemove("list", 0, PrimitiveInt(3)); err != nil || removed != 0 {
+			t.Errorf("Expected nothing removed, got %d (%v)", removed, err)
+		}
+		if got := listContents(t, tower, "list"); got != "[1 2]" {
+			t.Errorf("Expected [1 2], got %s", got)
+		}
+	})
+}
+
+func TestListPopRightPushLeft(t *testing.T) {
+	t.Run("between lists", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		createTestIntList(t, tower, "src", 1, 2, 3)
+		createTestIntList(t, tower, "dst", 9)
+
+		for _, want := range []int64{3, 2} {
+			value, err := tower.PopRightPushLeft("src", "dst")
+			if err != nil {
+				t.Fatalf("Failed to move item: %v", err)
+			}
+			if n, _ := value.Int(); n != want {
+				t.Errorf("Expected %d, got %d", want, n)
+			}
+		}
+
+		if got := listContents(t, tower, "src"); got != "[1]" {
+			t.Errorf("Expected source [1], got %s", got)
+		}
+		if got := listContents(t, tower, "dst"); got != "[2 3 9]" {
+			t.Errorf("Expected destination [2 3 9], got %s", got)
+		}
+		for key, want := range map[string]int64{"src": 1, "dst": 3} {
+			if length, err := tower.GetListLength(key); err != nil || length != want {
+				t.Errorf("Expected %s length %d, got %d (%v)", key, want, length, err)
+			}
+		}
+	})
+
+	t.Run("empty source", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		createTestIntList(t, tower, "src")
+		createTestIntList(t, tower, "dst", 1)
+
+		if _, err := tower.PopRightPushLeft("src", "dst"); !errors.Is(err, ErrListEmpty) {
+			t.Errorf("Expected ErrListEmpty, got %v", err)
+		}
+		if got := listContents(t, tower, "dst"); got != "[1]" {
+			t.Errorf("Expected destination untouched, got %s", got)
+		}
+		if _, err := tower.PopRightList("src"); !errors.Is(err, ErrListEmpty) {
+			t.Errorf("Expected ErrListEmpty from pop, got %v", err)
+		}
+	})
+
+	t.Run("missing destination", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		createTestIntList(t, tower, "src", 1, 2)
+
+		if _, err := tower.PopRightPushLeft("src", "missing"); err == nil {
+			t.Error("Expected error for missing destination")
+		}
+		if got := listContents(t, tower, "src"); got != "[1 2]" {
+			t.Errorf("Expected source untouched, got %s", got)
+		}
+	})
+
+	t.Run("opposing moves", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		createTestIntList(t, tower, "a", 1, 2, 3, 4, 5)
+		createTestIntList(t, tower, "b", 6, 7, 8, 9, 10)
+
+		// Both directions lock the same two keys; items are never lost
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				tower.PopRightPushLeft("a", "b")
+			}()
+			go func() {
+				defer wg.Done()
+				tower.PopRightPushLeft("b", "a")
+			}()
+		}
+		wg.Wait()
+
+		lenA, _ := tower.GetListLength("a")
+		lenB, _ := tower.GetListLength("b")
+		if lenA+lenB != 10 {
+			t.Errorf("Expected 10 items in total, got %d", lenA+lenB)
+		}
+	})
+}
+
+func TestRotateList(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	values := []int64{1, 2, 3, 4, 5}
+	createTestIntList(t, tower, "ring", values...)
+
+	for n := 1; n <= 12; n++ {
+		value, err := tower.RotateList("ring")
+		if err != nil {
+			t.Fatalf("Failed to rotate: %v", err)
+		}
+
+		// After n rotations the list starts at len-n mod len
+		shift := len(values) - n%len(values)
+		want := append(append([]int64{}, values[shift%len(values):]...), values[:shift%len(values)]...)
+		if got := listContents(t, tower, "ring"); got != fmt.Sprint(want) {
+			t.Fatalf("After %d rotations expected %v, got %s", n, want, got)
+		}
+		if v, _ := value.Int(); v != want[0] {
+			t.Errorf("Expected rotated item %d, got %d", want[0], v)
+		}
+	}
+
+	if length, err := tower.GetListLength("ring"); err != nil || length != int64(len(values)) {
+		t.Errorf("Expected length %d, got %d (%v)", len(values), length, err)
+	}
+	if repaired, err := tower.RepairList("ring"); err != nil || repaired != 0 {
+		t.Errorf("Expected no orphaned items, repair removed %d (%v)", repaired, err)
+	}
+
+	if err := tower.CreateList("empty"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if _, err := tower.RotateList("empty"); !errors.Is(err, ErrListEmpty) {
+		t.Errorf("Expected ErrListEmpty, got %v", err)
+	}
+}
+
+func TestForEachListItem(t *testing.T) {
+	metrics := &capturingMetrics{}
+	tower, err := NewOperator(&Options{
+		Path:         "test.db",
+		CacheSize:    size.NewSizeFromMegabytes(8),
+		MemTableSize: size.NewSizeFromMegabytes(4),
+		FS:           InMemory(),
+		Metrics:      metrics,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tower: %v", err)
+	}
+	defer tower.Close()
+
+	key := "log"
+	if err := tower.CreateList(key); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if _, err := tower.PushRightList(key, PrimitiveInt(int64(i))); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+	}
+	// Push to the head too, so items live at negative stored indexes
+	if _, err := tower.PushLeftList(key, PrimitiveInt(-1)); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+
+	// countReads runs fn and returns how many values it read from storage
+	countReads := func(fn func() error) int {
+		metrics.reset()
+		if err := fn(); err != nil {
+			t.Fatalf("Iteration failed: %v", err)
+		}
+		reads := 0
+		for _, op := range metrics.reset() {
+			if op.name == "get" {
+				reads++
+			}
+		}
+		return reads
+	}
+
+	var seen []int64
+	reads := countReads(func() error {
+		return tower.ForEachListItem(key, func(index int64, value PrimitiveData) bool {
+			v, _ := value.Int()
+			if v != index-1 {
+				t.Errorf("Expected value %d at index %d, got %d", index-1, index, v)
+			}
+			seen = append(seen, index)
+			return len(seen) < 5
+		})
+	})
+	if len(seen) != 5 || seen[0] != 0 || seen[4] != 4 {
+		t.Errorf("Expected indexes 0 to 4, got %v", seen)
+	}
+	// The list metadata plus the five items visited
+	if reads != 6 {
+		t.Errorf("Expected 6 reads after stopping early, got %d", reads)
+	}
+
+	seen = nil
+	reads = countReads(func() error {
+		return tower.ForEachListItemReverse(key, func(index int64, value PrimitiveData) bool {
+			seen = append(seen, index)
+			return len(seen) < 3
+		})
+	})
+	if len(seen) != 3 || seen[0] != 100 || seen[2] != 98 {
+		t.Errorf("Expected indexes 100 to 98, got %v", seen)
+	}
+	if reads != 4 {
+		t.Errorf("Expected 4 reads after stopping early, got %d", reads)
+	}
+
+	total := 0
+	if err := tower.ForEachListItem(key, func(index int64, value PrimitiveData) bool {
+		total++
+		return true
+	}); err != nil {
+		t.Fatalf("Iteration failed: %v", err)
+	}
+	if total != 101 {
+		t.Errorf("Expected 101 items, got %d", total)
+	}
+
+	if err := tower.ForEachListItem("missing", func(int64, PrimitiveData) bool { return true }); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestEnsureContainers(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	created, err := tower.EnsureListCreated("jobs")
+	if err != nil || !created {
+		t.Fatalf("Expected first EnsureListCreated to create the list, got %v (%v)", created, err)
+	}
+	if _, err := tower.PushRightList("jobs", PrimitiveString("a")); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+
+	// A second call is a no-op and leaves the existing items alone
+	if err := tower.EnsureList("jobs"); err != nil {
+		t.Fatalf("EnsureList failed on an existing list: %v", err)
+	}
+	created, err = tower.EnsureListCreated("jobs")
+	if err != nil || created {
+		t.Errorf("Expected EnsureListCreated to report an existing list, got %v (%v)", created, err)
+	}
+	if n, err := tower.GetListLength("jobs"); err != nil || n != 1 {
+		t.Errorf("Expected a single list with 1 item, got %d (%v)", n, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := tower.EnsureSet("tags"); err != nil {
+			t.Fatalf("EnsureSet failed: %v", err)
+		}
+		if err := tower.EnsureMap("config"); err != nil {
+			t.Fatalf("EnsureMap failed: %v", err)
+		}
+	}
+	if ok, err := tower.ExistsSet("tags"); err != nil || !ok {
+		t.Errorf("Expected set to exist, got %v (%v)", ok, err)
+	}
+	if n, err := tower.GetMapLength("config"); err != nil || n != 0 {
+		t.Errorf("Expected empty map, got %d (%v)", n, err)
+	}
+
+	if err := tower.SetString("plain", "value"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if err := tower.EnsureList("plain"); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch, got %v", err)
+	}
+	if err := tower.EnsureMap("jobs"); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch, got %v", err)
+	}
+}
+
+func TestContainerRichTypes(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	price := PrimitiveDecimal{Coefficient: big.NewInt(1999), Scale: 2}
+	huge := PrimitiveBigInt{Value: new(big.Int).Lsh(big.NewInt(1), 80)}
+
+	if err := tower.CreateList("events"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if _, err := tower.PushRightList("events", PrimitiveTimestamp(at.UnixNano())); err != nil {
+		t.Fatalf("Failed to push timestamp: %v", err)
+	}
+	if _, err := tower.PushLeftList("events", PrimitiveDuration(time.Minute)); err != nil {
+		t.Fatalf("Failed to push duration: %v", err)
+	}
+	if _, err := tower.PushRightMany("events", PrimitiveUUID(id), price, huge); err != nil {
+		t.Fatalf("Failed to push values: %v", err)
+	}
+
+	value, err := tower.GetListIndex("events", 1)
+	if err != nil {
+		t.Fatalf("Failed to get list item: %v", err)
+	}
+	if value.Type() != TypeTimestamp {
+		t.Fatalf("Expected a timestamp, got type %v", value.Type())
+	}
+	if ts, _ := value.Timestamp(); ts != at.UnixNano() {
+		t.Errorf("Expected timestamp %d, got %d", at.UnixNano(), ts)
+	}
+
+	if value, err := tower.PopLeftList("events"); err != nil || value != PrimitiveDuration(time.Minute) {
+		t.Errorf("Expected to pop a minute, got %v (%v)", value, err)
+	}
+	if value, err := tower.GetListIndex("events", 1); err != nil || value != PrimitiveUUID(id) {
+		t.Errorf("Expected the UUID, got %v (%v)", value, err)
+	}
+	if value, err := tower.PopRightList("events"); err != nil {
+		t.Errorf("Failed to pop big int: %v", err)
+	} else if v, err := value.BigInt(); err != nil || v.Cmp(huge.Value) != 0 {
+		t.Errorf("Expected %v, got %v (%v)", huge.Value, v, err)
+	}
+	if err := tower.SetListIndex("events", 0, price); err != nil {
+		t.Fatalf("Failed to set list item: %v", err)
+	}
+	if value, err := tower.GetListIndex("events", 0); err != nil {
+		t.Errorf("Failed to get list item: %v", err)
+	} else if coefficient, scale, _ := value.Decimal(); coefficient.Cmp(price.Coefficient) != 0 || scale != 2 {
+		t.Errorf("Expected 19.99, got %v scale %d", coefficient, scale)
+	}
+
+	if err := tower.CreateMap("order"); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+	if err := tower.SetMapKey("order", PrimitiveString("price"), price); err != nil {
+		t.Fatalf("Failed to set decimal field: %v", err)
+	}
+	if err := tower.SetMapKey("order", PrimitiveString("placed"), PrimitiveTimestamp(at.UnixNano())); err != nil {
+		t.Fatalf("Failed to set timestamp field: %v", err)
+	}
+	if value, err := tower.GetMapKey("order", PrimitiveString("price")); err != nil || value.Type() != TypeDecimal {
+		t.Errorf("Expected a decimal field, got %v (%v)", value, err)
+	}
+	if values, err := tower.GetMapValues("order"); err != nil || len(values) != 2 {
+		t.Errorf("Expected 2 map values, got %v (%v)", values, err)
+	}
+
+	if err := tower.CreateSet("owners"); err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+	for _, member := range []PrimitiveData{PrimitiveUUID(id), PrimitiveUUID(id), huge, PrimitiveDuration(time.Second)} {
+		if _, err := tower.AddSetMember("owners", member); err != nil {
+			t.Fatalf("Failed to add %v: %v", member, err)
+		}
+	}
+	if ok, err := tower.ContainsSetMember("owners", PrimitiveUUID(id)); err != nil || !ok {
+		t.Errorf("Expected the UUID to be a member, got %v (%v)", ok, err)
+	}
+	members, err := tower.GetSetMembers("owners")
+	if err != nil || len(members) != 3 {
+		t.Fatalf("Expected 3 members, got %v (%v)", members, err)
+	}
+	types := make([]DataType, 0, len(members))
+	for _, member := range members {
+		types = append(types, member.Type())
+	}
+	slices.Sort(types)
+	if !slices.Equal(types, []DataType{TypeBigInt, TypeDuration, TypeUUID}) {
+		t.Errorf("Expected members to keep their types, got %v", types)
+	}
+}
+
+func TestListPosition(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "positions"
+	if err := tower.CreateList(key); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	// a b a c a 1
+	if _, err := tower.PushRightMany(key,
+		PrimitiveString("a"), PrimitiveString("b"), PrimitiveString("a"),
+		PrimitiveString("c"), PrimitiveString("a"), PrimitiveInt(1),
+	); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	// Shift HeadIndex so indexes are clearly relative to the head
+	if _, err := tower.PushLeftList(key, PrimitiveString("z")); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if _, err := tower.PopLeftList(key); err != nil {
+		t.Fatalf("Failed to pop: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value PrimitiveData
+		rank  int
+		count int
+		want  []int64
+	}{
+		{"all from head", PrimitiveString("a"), 1, 0, []int64{0, 2, 4}},
+		{"first match", PrimitiveString("a"), 1, 1, []int64{0}},
+		{"second match on", PrimitiveString("a"), 2, 0, []int64{2, 4}},
+		{"count limit", PrimitiveString("a"), 1, 2, []int64{0, 2}},
+		{"last match", PrimitiveString("a"), -1, 1, []int64{4}},
+		{"all from tail", PrimitiveString("a"), -1, 0, []int64{4, 2, 0}},
+		{"second from tail", PrimitiveString("a"), -2, 1, []int64{2}},
+		{"rank past matches", PrimitiveString("a"), 4, 0, []int64{}},
+		{"single match", PrimitiveString("c"), 1, 0, []int64{3}},
+		{"missing value", PrimitiveString("x"), 1, 0, []int64{}},
+		{"type must match", PrimitiveString("1"), 1, 0, []int64{}},
+		{"int value", PrimitiveInt(1), -1, 0, []int64{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tower.ListPosition(key, tt.value, tt.rank, tt.count)
+			if err != nil {
+				t.Fatalf("Failed to get positions: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := tower.ListPosition(key, PrimitiveString("a"), 0, 0); err == nil {
+		t.Error("Expected error for rank 0")
+	}
+	if _, err := tower.ListPosition(key, PrimitiveString("a"), 1, -1); err == nil {
+		t.Error("Expected error for a negative count")
+	}
+	if _, err := tower.ListPosition("missing", PrimitiveString("a"), 1, 0); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
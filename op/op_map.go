@@ -1,14 +1,23 @@
 ﻿package op
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strings"
+	"time"
+
+	"github.com/cockroachdb/pebble"
 )
 
 // Map operations
-func (op *Operator) CreateMap(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) CreateMap(key string) (err error) {
+	defer op.traceOperation("CreateMap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	// Store Map metadata directly to key
@@ -16,9 +25,49 @@ func (op *Operator) CreateMap(key string) error {
 
 	// Check if already exists
 	if _, err := op.get(mapKey); err == nil {
-		return fmt.Errorf("map %s already exists", key)
+		return fmt.Errorf("map %s: %w", key, ErrContainerExists)
+	}
+
+	return op.createMap(key)
+}
+
+// EnsureMap creates an empty map at key unless one already exists. It
+// fails with a type mismatch when key holds something other than a map.
+func (op *Operator) EnsureMap(key string) error {
+	_, err := op.EnsureMapCreated(key)
+	return err
+}
+
+// EnsureMapCreated is EnsureMap that also reports whether the map was
+// created by this call.
+func (op *Operator) EnsureMapCreated(key string) (_ bool, err error) {
+	defer op.traceOperation("EnsureMapCreated", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if df, err := op.get(key); err == nil {
+		if _, err := df.Map(); err != nil {
+			return false, fmt.Errorf("map %s: %w", key, err)
+		}
+		return false, nil
 	}
 
+	if err := op.createMap(key); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// createMap stores the metadata of an empty map at key, which the caller
+// holds the lock for.
+func (op *Operator) createMap(key string) error {
+	mapKey := key
+
 	// Create new Map data
 	mapData := &MapData{
 		Prefix: key,
@@ -37,8 +86,13 @@ func (op *Operator) CreateMap(key string) error {
 	return nil
 }
 
-func (op *Operator) DeleteMap(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) DeleteMap(key string) (err error) {
+	defer op.traceOperation("DeleteMap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	return op.deleteMap(key)
@@ -60,8 +114,7 @@ func (op *Operator) deleteMap(key string) error {
 
 	// Delete all fields
 	if mapData.Count > 0 {
-		prefix := string(MakeMapEntryKey(mapData.Prefix)) + ":"
-		err = op.rangePrefix(prefix, func(k string, df *DataFrame) error {
+		_, err = op.rangeMapFields(mapData, func(k string, df *DataFrame) error {
 			return op.delete(k)
 		})
 		if err != nil {
@@ -77,17 +130,27 @@ func (op *Operator) deleteMap(key string) error {
 	return nil
 }
 
-func (op *Operator) ExistsMap(key string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ExistsMap(key string) (_ bool, err error) {
+	defer op.traceOperation("ExistsMap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	mapKey := key
-	_, err := op.get(mapKey)
+	_, err = op.get(mapKey)
 	return err == nil, nil
 }
 
-func (op *Operator) SetMapKey(key string, field PrimitiveData, value PrimitiveData) error {
-	unlock := op.lock(key)
+func (op *Operator) SetMapKey(key string, field PrimitiveData, value PrimitiveData) (err error) {
+	defer op.traceOperation("SetMapKey", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	mapKey := key
@@ -112,7 +175,7 @@ func (op *Operator) SetMapKey(key string, field PrimitiveData, value PrimitiveDa
 
 	// Check if already exists
 	isNew := false
-	if _, err := op.get(fieldKey); err != nil {
+	if _, err := op.getMapField(key, df, mapData, fieldKey); err != nil {
 		isNew = true
 	}
 
@@ -122,35 +185,9 @@ func (op *Operator) SetMapKey(key string, field PrimitiveData, value PrimitiveDa
 	}
 
 	// Set value to DataFrame
-	valueDf := NULLDataFrame()
-	switch value.Type() {
-	case TypeInt:
-		intVal, _ := value.Int()
-		if err := valueDf.SetInt(intVal); err != nil {
-			return fmt.Errorf("failed to set int value: %w", err)
-		}
-	case TypeFloat:
-		floatVal, _ := value.Float()
-		if err := valueDf.SetFloat(floatVal); err != nil {
-			return fmt.Errorf("failed to set float value: %w", err)
-		}
-	case TypeString:
-		strVal, _ := value.String()
-		if err := valueDf.SetString(strVal); err != nil {
-			return fmt.Errorf("failed to set string value: %w", err)
-		}
-	case TypeBool:
-		boolVal, _ := value.Bool()
-		if err := valueDf.SetBool(boolVal); err != nil {
-			return fmt.Errorf("failed to set bool value: %w", err)
-		}
-	case TypeBinary:
-		binVal, _ := value.Binary()
-		if err := valueDf.SetBinary(binVal); err != nil {
-			return fmt.Errorf("failed to set binary value: %w", err)
-		}
-	default:
-		return fmt.Errorf("unsupported value type")
+	valueDf, err := listItemDataFrame(value)
+	if err != nil {
+		return err
 	}
 
 	// Store value
@@ -173,8 +210,13 @@ func (op *Operator) SetMapKey(key string, field PrimitiveData, value PrimitiveDa
 	return nil
 }
 
-func (op *Operator) GetMapKey(key string, field PrimitiveData) (PrimitiveData, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetMapKey(key string, field PrimitiveData) (_ PrimitiveData, err error) {
+	defer op.traceOperation("GetMapKey", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	mapKey := key
@@ -185,7 +227,7 @@ func (op *Operator) GetMapKey(key string, field PrimitiveData) (PrimitiveData, e
 		return nil, fmt.Errorf("map %s does not exist: %w", key, err)
 	}
 
-	_, err = df.Map()
+	mapData, err := df.Map()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get map data: %w", err)
 	}
@@ -198,38 +240,27 @@ func (op *Operator) GetMapKey(key string, field PrimitiveData) (PrimitiveData, e
 	fieldKey := string(MakeMapItemKey(key, fieldStr))
 
 	// Get value
-	valueDf, err := op.get(fieldKey)
+	valueDf, err := op.getMapField(key, df, mapData, fieldKey)
 	if err != nil {
 		return nil, fmt.Errorf("field does not exist: %w", err)
 	}
 
 	// Extract value
-	var value PrimitiveData
-	switch valueDf.Type() {
-	case TypeInt:
-		intVal, _ := valueDf.Int()
-		value = PrimitiveInt(intVal)
-	case TypeFloat:
-		floatVal, _ := valueDf.Float()
-		value = PrimitiveFloat(floatVal)
-	case TypeString:
-		strVal, _ := valueDf.String()
-		value = PrimitiveString(strVal)
-	case TypeBool:
-		boolVal, _ := valueDf.Bool()
-		value = PrimitiveBool(boolVal)
-	case TypeBinary:
-		binVal, _ := valueDf.Binary()
-		value = PrimitiveBinary(binVal)
-	default:
-		return nil, fmt.Errorf("unsupported data type")
+	value, err := listItemValue(valueDf)
+	if err != nil {
+		return nil, err
 	}
 
 	return value, nil
 }
 
-func (op *Operator) DeleteMapKey(key string, field PrimitiveData) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) DeleteMapKey(key string, field PrimitiveData) (_ int64, err error) {
+	defer op.traceOperation("DeleteMapKey", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	mapKey := key
@@ -253,7 +284,7 @@ func (op *Operator) DeleteMapKey(key string, field PrimitiveData) (int64, error)
 	fieldKey := string(MakeMapItemKey(key, fieldStr))
 
 	// Check if exists
-	if _, err := op.get(fieldKey); err != nil {
+	if _, err := op.getMapField(key, df, mapData, fieldKey); err != nil {
 		return int64(mapData.Count), nil // No count change if not exists
 	}
 
@@ -276,8 +307,221 @@ func (op *Operator) DeleteMapKey(key string, field PrimitiveData) (int64, error)
 	return int64(mapData.Count), nil
 }
 
-func (op *Operator) GetMapKeys(key string) ([]PrimitiveData, error) {
-	unlock := op.lock(key)
+// MapRenameField moves the value of oldField to newField in one batch,
+// keeping its type and expiration. When newField already exists it is
+// replaced if overwrite is set and ErrItemExists is returned otherwise.
+// Renaming a field to itself does nothing.
+func (op *Operator) MapRenameField(key string, oldField, newField PrimitiveData, overwrite bool) (err error) {
+	defer op.traceOperation("MapRenameField", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("map %s does not exist: %w", key, err)
+	}
+
+	mapData, err := df.Map()
+	if err != nil {
+		return fmt.Errorf("failed to get map data: %w", err)
+	}
+
+	oldStr, err := oldField.String()
+	if err != nil {
+		return fmt.Errorf("failed to get field string: %w", err)
+	}
+	newStr, err := newField.String()
+	if err != nil {
+		return fmt.Errorf("failed to get field string: %w", err)
+	}
+	oldKey := string(MakeMapItemKey(key, oldStr))
+	newKey := string(MakeMapItemKey(key, newStr))
+
+	valueDf, err := op.getMapField(key, df, mapData, oldKey)
+	if err != nil {
+		return fmt.Errorf("field does not exist: %w", err)
+	}
+
+	if oldKey == newKey {
+		return nil
+	}
+
+	_, err = op.getMapField(key, df, mapData, newKey)
+	if err != nil && !isNotExist(err) && IsDataframeExpiredError(err) == nil {
+		return fmt.Errorf("failed to get field %s: %w", newStr, err)
+	}
+	replaced := err == nil
+	if replaced && !overwrite {
+		return fmt.Errorf("field %s of map %s: %w", newStr, key, ErrItemExists)
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if err := op.setInBatch(batch, newKey, valueDf); err != nil {
+		return fmt.Errorf("failed to set map field: %w", err)
+	}
+	if err := batch.Delete([]byte(oldKey), nil); err != nil {
+		return fmt.Errorf("failed to delete map field: %w", err)
+	}
+
+	if replaced {
+		mapData.Count--
+
+		if err := df.SetMap(mapData); err != nil {
+			return fmt.Errorf("failed to update map metadata: %w", err)
+		}
+
+		if err := op.setInBatch(batch, key, df); err != nil {
+			return fmt.Errorf("failed to update map metadata: %w", err)
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return fmt.Errorf("failed to commit map batch: %w", err)
+	}
+
+	return nil
+}
+
+// MapMultiGet reads fields under a single lock and returns their values in
+// the same order. Missing fields come back as PrimitiveNull instead of
+// failing the whole call.
+func (op *Operator) MapMultiGet(key string, fields []PrimitiveData) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("MapMultiGet", key)(&err)
+
+	fieldKeys := make([]string, len(fields))
+	for i, field := range fields {
+		fieldStr, err := field.String()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get field string: %w", err)
+		}
+		fieldKeys[i] = string(MakeMapItemKey(key, fieldStr))
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return nil, fmt.Errorf("map %s does not exist: %w", key, err)
+	}
+
+	mapData, err := df.Map()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get map data: %w", err)
+	}
+
+	result := make([]PrimitiveData, len(fieldKeys))
+	for i, fieldKey := range fieldKeys {
+		valueDf, err := op.getMapField(key, df, mapData, fieldKey)
+		if err != nil {
+			if !isNotExist(err) {
+				return nil, fmt.Errorf("failed to get map field: %w", err)
+			}
+			result[i] = PrimitiveNull{}
+			continue
+		}
+
+		value, err := listItemValue(valueDf)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+
+	return result, nil
+}
+
+// MapMultiSet writes every field of pairs under a single lock and commits
+// them together with the field count in one batch.
+func (op *Operator) MapMultiSet(key string, pairs map[PrimitiveData]PrimitiveData) (err error) {
+	defer op.traceOperation("MapMultiSet", key)(&err)
+
+	fieldKeys := make(map[string]*DataFrame, len(pairs))
+	for field, value := range pairs {
+		fieldStr, err := field.String()
+		if err != nil {
+			return fmt.Errorf("failed to get field string: %w", err)
+		}
+
+		valueDf, err := listItemDataFrame(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for field %s: %w", fieldStr, err)
+		}
+		fieldKeys[string(MakeMapItemKey(key, fieldStr))] = valueDf
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("map %s does not exist: %w", key, err)
+	}
+
+	mapData, err := df.Map()
+	if err != nil {
+		return fmt.Errorf("failed to get map data: %w", err)
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	var added uint64
+	for fieldKey, valueDf := range fieldKeys {
+		if _, err := op.getMapField(key, df, mapData, fieldKey); err != nil {
+			if !isNotExist(err) {
+				return fmt.Errorf("failed to get map field: %w", err)
+			}
+			added++
+		}
+
+		if err := op.setInBatch(batch, fieldKey, valueDf); err != nil {
+			return fmt.Errorf("failed to set map field: %w", err)
+		}
+	}
+
+	if added > 0 {
+		if mapData.Count > math.MaxUint64-1-added {
+			return fmt.Errorf("map has too many fields")
+		}
+
+		mapData.Count += added
+
+		if err := df.SetMap(mapData); err != nil {
+			return fmt.Errorf("failed to update map metadata: %w", err)
+		}
+
+		if err := op.setInBatch(batch, key, df); err != nil {
+			return fmt.Errorf("failed to update map metadata: %w", err)
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return fmt.Errorf("failed to commit map batch: %w", err)
+	}
+
+	return nil
+}
+
+func (op *Operator) GetMapKeys(key string) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("GetMapKeys", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	mapKey := key
@@ -300,26 +544,30 @@ func (op *Operator) GetMapKeys(key string) ([]PrimitiveData, error) {
 	// Collect all keys
 	result := make([]PrimitiveData, 0, mapData.Count)
 	prefix := string(MakeMapEntryKey(mapData.Prefix)) + ":"
-	err = op.rangePrefix(prefix, func(k string, df *DataFrame) error {
-		parts := strings.Split(k, ": {:map:} :")
-		if len(parts) != 2 {
-			return nil // skip invalid key
-		}
-		fieldStr := parts[1]
-
-		value := PrimitiveString(fieldStr)
-		result = append(result, value)
+	removed, err := op.rangeMapFields(mapData, func(k string, df *DataFrame) error {
+		result = append(result, PrimitiveString(strings.TrimPrefix(k, prefix)))
 		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to range map keys: %w", err)
 	}
 
+	if removed > 0 {
+		if err := op.updateMapData(mapKey, df, mapData); err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
 
-func (op *Operator) GetMapValues(key string) ([]PrimitiveData, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetMapValues(key string) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("GetMapValues", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	mapKey := key
@@ -341,26 +589,9 @@ func (op *Operator) GetMapValues(key string) ([]PrimitiveData, error) {
 
 	// Collect all values
 	result := make([]PrimitiveData, 0, mapData.Count)
-	prefix := string(MakeMapEntryKey(mapData.Prefix)) + ":"
-	err = op.rangePrefix(prefix, func(k string, df *DataFrame) error {
-		var value PrimitiveData
-		switch df.Type() {
-		case TypeInt:
-			intVal, _ := df.Int()
-			value = PrimitiveInt(intVal)
-		case TypeFloat:
-			floatVal, _ := df.Float()
-			value = PrimitiveFloat(floatVal)
-		case TypeString:
-			strVal, _ := df.String()
-			value = PrimitiveString(strVal)
-		case TypeBool:
-			boolVal, _ := df.Bool()
-			value = PrimitiveBool(boolVal)
-		case TypeBinary:
-			binVal, _ := df.Binary()
-			value = PrimitiveBinary(binVal)
-		default:
+	removed, err := op.rangeMapFields(mapData, func(k string, df *DataFrame) error {
+		value, err := listItemValue(df)
+		if err != nil {
 			return nil // skip unsupported types
 		}
 		result = append(result, value)
@@ -370,11 +601,104 @@ func (op *Operator) GetMapValues(key string) ([]PrimitiveData, error) {
 		return nil, fmt.Errorf("failed to range map values: %w", err)
 	}
 
+	if removed > 0 {
+		if err := op.updateMapData(mapKey, df, mapData); err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
 
-func (op *Operator) GetMapLength(key string) (int64, error) {
-	unlock := op.lock(key)
+// MapEntry is a map field together with its value.
+type MapEntry struct {
+	Field PrimitiveData
+	Value PrimitiveData
+}
+
+// ForEachMapEntry calls fn for every field and its value, in field order,
+// until fn returns false. Fields and values come from a single pass, so they
+// always pair up, which separate GetMapKeys and GetMapValues calls can't
+// promise. The key lock is held throughout, so fn must not operate on the
+// same map.
+func (op *Operator) ForEachMapEntry(key string, fn func(field, value PrimitiveData) bool) (err error) {
+	defer op.traceOperation("ForEachMapEntry", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return op.forEachMapEntry(key, fn)
+}
+
+// MapEntries returns every field of the map with its value, in field order.
+func (op *Operator) MapEntries(key string) (_ []MapEntry, err error) {
+	defer op.traceOperation("MapEntries", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result := []MapEntry{}
+	err = op.forEachMapEntry(key, func(field, value PrimitiveData) bool {
+		result = append(result, MapEntry{Field: field, Value: value})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (op *Operator) forEachMapEntry(key string, fn func(field, value PrimitiveData) bool) error {
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("map %s does not exist: %w", key, err)
+	}
+
+	mapData, err := df.Map()
+	if err != nil {
+		return fmt.Errorf("failed to get map data: %w", err)
+	}
+
+	if mapData.Count == 0 {
+		return nil
+	}
+
+	prefix := string(MakeMapEntryKey(mapData.Prefix)) + ":"
+	removed, err := op.rangeMapFields(mapData, func(k string, valueDf *DataFrame) error {
+		value, err := dataFramePrimitive(valueDf)
+		if err != nil {
+			return nil // skip unsupported types
+		}
+		if !fn(PrimitiveString(strings.TrimPrefix(k, prefix)), value) {
+			return errStopRange
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to range map entries: %w", err)
+	}
+
+	if removed > 0 {
+		return op.updateMapData(key, df, mapData)
+	}
+
+	return nil
+}
+
+func (op *Operator) GetMapLength(key string) (_ int64, err error) {
+	defer op.traceOperation("GetMapLength", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	mapKey := key
@@ -389,11 +713,21 @@ func (op *Operator) GetMapLength(key string) (int64, error) {
 		return 0, fmt.Errorf("failed to get map data: %w", err)
 	}
 
+	// Purge expired fields so the count covers live ones only
+	if err := op.removeExpiredMapFields(mapKey, df, mapData); err != nil {
+		return 0, err
+	}
+
 	return int64(mapData.Count), nil
 }
 
-func (op *Operator) ClearMap(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) ClearMap(key string) (err error) {
+	defer op.traceOperation("ClearMap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	mapKey := key
@@ -411,8 +745,7 @@ func (op *Operator) ClearMap(key string) error {
 
 	// Delete all fields
 	if mapData.Count > 0 {
-		prefix := string(MakeMapEntryKey(mapData.Prefix)) + ":"
-		err = op.rangePrefix(prefix, func(k string, df *DataFrame) error {
+		_, err = op.rangeMapFields(mapData, func(k string, df *DataFrame) error {
 			return op.delete(k)
 		})
 		if err != nil {
@@ -433,5 +766,493 @@ func (op *Operator) ClearMap(key string) error {
 	return nil
 }
 
+// MapClampInt clamps the int stored in field to [min, max] and returns the
+// result. An absent field is created holding zero clamped to the range.
+func (op *Operator) MapClampInt(key string, field PrimitiveData, min, max int64) (_ int64, err error) {
+	defer op.traceOperation("MapClampInt", key)(&err)
+
+	if min > max {
+		return 0, fmt.Errorf("min cannot be greater than max")
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return 0, fmt.Errorf("map %s does not exist: %w", key, err)
+	}
+
+	mapData, err := df.Map()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get map data: %w", err)
+	}
+
+	fieldStr, err := field.String()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get field string: %w", err)
+	}
+	fieldKey := string(MakeMapItemKey(key, fieldStr))
+
+	var current int64
+	isNew := false
+	valueDf, err := op.getMapField(key, df, mapData, fieldKey)
+	if err != nil {
+		if !isNotExist(err) {
+			return 0, fmt.Errorf("failed to get map field: %w", err)
+		}
+		isNew = true
+		valueDf = NULLDataFrame()
+	} else {
+		current, err = valueDf.Int()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get int value for field %s: %w", fieldStr, err)
+		}
+	}
+
+	if isNew && mapData.Count >= math.MaxUint64-1 {
+		return 0, fmt.Errorf("map has too many fields")
+	}
+
+	newValue := current
+	if newValue < min {
+		newValue = min
+	} else if newValue > max {
+		newValue = max
+	}
+
+	if !isNew && newValue == current {
+		return newValue, nil
+	}
+
+	if err := valueDf.SetInt(newValue); err != nil {
+		return 0, fmt.Errorf("failed to set int value: %w", err)
+	}
+
+	// Store field and metadata in one batch
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if err := op.setInBatch(batch, fieldKey, valueDf); err != nil {
+		return 0, fmt.Errorf("failed to set map field: %w", err)
+	}
+
+	if isNew {
+		mapData.Count++
+
+		if err := df.SetMap(mapData); err != nil {
+			return 0, fmt.Errorf("failed to update map metadata: %w", err)
+		}
+
+		if err := op.setInBatch(batch, key, df); err != nil {
+			return 0, fmt.Errorf("failed to update map metadata: %w", err)
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit map batch: %w", err)
+	}
+
+	return newValue, nil
+}
+
+// MapClampFloat clamps the float stored in field to [min, max] and returns the
+// result. An absent field is created holding zero clamped to the range.
+func (op *Operator) MapClampFloat(key string, field PrimitiveData, min, max float64) (_ float64, err error) {
+	defer op.traceOperation("MapClampFloat", key)(&err)
+
+	if min > max {
+		return 0, fmt.Errorf("min cannot be greater than max")
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return 0, fmt.Errorf("map %s does not exist: %w", key, err)
+	}
+
+	mapData, err := df.Map()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get map data: %w", err)
+	}
+
+	fieldStr, err := field.String()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get field string: %w", err)
+	}
+	fieldKey := string(MakeMapItemKey(key, fieldStr))
+
+	var current float64
+	isNew := false
+	valueDf, err := op.getMapField(key, df, mapData, fieldKey)
+	if err != nil {
+		if !isNotExist(err) {
+			return 0, fmt.Errorf("failed to get map field: %w", err)
+		}
+		isNew = true
+		valueDf = NULLDataFrame()
+	} else {
+		current, err = valueDf.Float()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get float value for field %s: %w", fieldStr, err)
+		}
+	}
+
+	if isNew && mapData.Count >= math.MaxUint64-1 {
+		return 0, fmt.Errorf("map has too many fields")
+	}
+
+	newValue := current
+	if newValue < min {
+		newValue = min
+	} else if newValue > max {
+		newValue = max
+	}
+
+	if !isNew && newValue == current {
+		return newValue, nil
+	}
+
+	if err := valueDf.SetFloat(newValue); err != nil {
+		return 0, fmt.Errorf("failed to set float value: %w", err)
+	}
+
+	// Store field and metadata in one batch
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if err := op.setInBatch(batch, fieldKey, valueDf); err != nil {
+		return 0, fmt.Errorf("failed to set map field: %w", err)
+	}
+
+	if isNew {
+		mapData.Count++
+
+		if err := df.SetMap(mapData); err != nil {
+			return 0, fmt.Errorf("failed to update map metadata: %w", err)
+		}
+
+		if err := op.setInBatch(batch, key, df); err != nil {
+			return 0, fmt.Errorf("failed to update map metadata: %w", err)
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit map batch: %w", err)
+	}
+
+	return newValue, nil
+}
+
+// MapIncrInt adds delta to the int stored in field and returns the result. An
+// absent field is created holding delta.
+func (op *Operator) MapIncrInt(key string, field PrimitiveData, delta int64) (_ int64, err error) {
+	defer op.traceOperation("MapIncrInt", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var newValue int64
+	_, err = op.modifyMapField(key, field, func(fieldStr string, valueDf *DataFrame, isNew bool) (bool, error) {
+		var current int64
+		if !isNew {
+			v, err := valueDf.Int()
+			if err != nil {
+				return false, fmt.Errorf("failed to get int value for field %s: %w", fieldStr, err)
+			}
+			current = v
+		}
+
+		newValue = current + delta
+		if err := valueDf.SetInt(newValue); err != nil {
+			return false, fmt.Errorf("failed to set int value: %w", err)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return newValue, nil
+}
+
+// MapIncrFloat adds delta to the float stored in field and returns the
+// result. An absent field is created holding delta.
+func (op *Operator) MapIncrFloat(key string, field PrimitiveData, delta float64) (_ float64, err error) {
+	defer op.traceOperation("MapIncrFloat", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var newValue float64
+	_, err = op.modifyMapField(key, field, func(fieldStr string, valueDf *DataFrame, isNew bool) (bool, error) {
+		var current float64
+		if !isNew {
+			v, err := valueDf.Float()
+			if err != nil {
+				return false, fmt.Errorf("failed to get float value for field %s: %w", fieldStr, err)
+			}
+			current = v
+		}
+
+		newValue = current + delta
+		if err := valueDf.SetFloat(newValue); err != nil {
+			return false, fmt.Errorf("failed to set float value: %w", err)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return newValue, nil
+}
+
+// MapSetNX stores value in field only when the field is absent and reports
+// whether it did.
+func (op *Operator) MapSetNX(key string, field PrimitiveData, value PrimitiveData) (_ bool, err error) {
+	defer op.traceOperation("MapSetNX", key)(&err)
+
+	newDf, err := listItemDataFrame(value)
+	if err != nil {
+		return false, err
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	return op.modifyMapField(key, field, func(fieldStr string, valueDf *DataFrame, isNew bool) (bool, error) {
+		if !isNew {
+			return false, nil
+		}
+		*valueDf = *newDf
+		return true, nil
+	})
+}
+
+// MapSetEx sets field to value so that it drops out of the map on its own
+// after ttl. Setting the field again without a TTL makes it permanent.
+func (op *Operator) MapSetEx(key string, field PrimitiveData, value PrimitiveData, ttl time.Duration) (err error) {
+	defer op.traceOperation("MapSetEx", key)(&err)
+
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive, got %v", ttl)
+	}
+
+	newDf, err := listItemDataFrame(value)
+	if err != nil {
+		return err
+	}
+	expireAt := Now().Add(ttl)
+	newDf.SetExpiration(expireAt)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := op.modifyMapField(key, field, func(fieldStr string, valueDf *DataFrame, isNew bool) (bool, error) {
+		*valueDf = *newDf
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	// The sweep purges expired fields of maps it finds among the candidates
+	if err := op.addCandidatesForExpiration(key, expireAt); err != nil {
+		return fmt.Errorf("failed to add map %s to expiration candidates: %w", key, err)
+	}
+
+	return nil
+}
+
+// modifyMapField loads field and passes its DataFrame to fn, which updates
+// it in place and reports whether it should be stored. An absent field is
+// passed as an empty DataFrame with isNew set and counts towards the map once
+// stored. Field and metadata are written in one batch. The caller must hold
+// the map lock.
+func (op *Operator) modifyMapField(key string, field PrimitiveData, fn func(fieldStr string, valueDf *DataFrame, isNew bool) (bool, error)) (bool, error) {
+	df, err := op.get(key)
+	if err != nil {
+		return false, fmt.Errorf("map %s does not exist: %w", key, err)
+	}
+
+	mapData, err := df.Map()
+	if err != nil {
+		return false, fmt.Errorf("failed to get map data: %w", err)
+	}
 
+	fieldStr, err := field.String()
+	if err != nil {
+		return false, fmt.Errorf("failed to get field string: %w", err)
+	}
+	fieldKey := string(MakeMapItemKey(key, fieldStr))
+
+	isNew := false
+	valueDf, err := op.getMapField(key, df, mapData, fieldKey)
+	if err != nil {
+		if !isNotExist(err) {
+			return false, fmt.Errorf("failed to get map field: %w", err)
+		}
+		isNew = true
+		valueDf = NULLDataFrame()
+	}
+
+	if isNew && mapData.Count >= math.MaxUint64-1 {
+		return false, fmt.Errorf("map has too many fields")
+	}
+
+	changed, err := fn(fieldStr, valueDf, isNew)
+	if err != nil || !changed {
+		return false, err
+	}
+
+	// Store field and metadata in one batch
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if err := op.setInBatch(batch, fieldKey, valueDf); err != nil {
+		return false, fmt.Errorf("failed to set map field: %w", err)
+	}
 
+	if isNew {
+		mapData.Count++
+
+		if err := df.SetMap(mapData); err != nil {
+			return false, fmt.Errorf("failed to update map metadata: %w", err)
+		}
+
+		if err := op.setInBatch(batch, key, df); err != nil {
+			return false, fmt.Errorf("failed to update map metadata: %w", err)
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return false, fmt.Errorf("failed to commit map batch: %w", err)
+	}
+
+	return true, nil
+}
+
+// getMapField reads a map field. get deletes a field it finds expired, so the
+// map's count is adjusted and saved to match. The caller must hold the map
+// lock.
+func (op *Operator) getMapField(key string, df *DataFrame, mapData *MapData, fieldKey string) (*DataFrame, error) {
+	valueDf, err := op.get(fieldKey)
+	if err != nil && op.lazyExpire && IsDataframeExpiredError(err) != nil && mapData.Count > 0 {
+		mapData.Count--
+		if err := op.updateMapData(key, df, mapData); err != nil {
+			return nil, err
+		}
+	}
+
+	return valueDf, err
+}
+
+// rangeMapFields calls fn for every live field of the map in field order.
+// Expired fields are skipped, deleted and taken off mapData.Count, and their
+// number is returned so the caller can save the adjusted count.
+func (op *Operator) rangeMapFields(mapData *MapData, fn func(key string, df *DataFrame) error) (int, error) {
+	if err := op.ctxErr(); err != nil {
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
+	}
+
+	entryKey := string(MakeMapEntryKey(mapData.Prefix))
+	iter, err := op.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(entryKey + ":"),
+		UpperBound: []byte(entryKey + ";"),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var expired []string
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := op.ctxErr(); err != nil {
+			return 0, fmt.Errorf("iterator error: %w", err)
+		}
+
+		key := string(iter.Key())
+		df, err := op.unmarshal(key, iter.Value())
+		if err != nil {
+			if IsDataframeExpiredError(err) != nil {
+				expired = append(expired, key)
+				continue
+			}
+			return 0, fmt.Errorf("failed to unmarshal dataframe for key %s: %w", key, err)
+		}
+		if fn == nil {
+			continue
+		}
+		if err := fn(key, df); err != nil {
+			if errors.Is(err, errStopRange) {
+				break
+			}
+			return 0, fmt.Errorf("callback error for key %s: %w", key, err)
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("iterator error: %w", err)
+	}
+
+	for _, key := range expired {
+		if err := op.delete(key); err != nil {
+			return 0, fmt.Errorf("failed to delete expired map field: %w", err)
+		}
+		if mapData.Count > 0 {
+			mapData.Count--
+		}
+	}
+
+	return len(expired), nil
+}
+
+// removeExpiredMapFields deletes the expired fields of the map stored at key
+// and saves the adjusted count.
+func (op *Operator) removeExpiredMapFields(key string, df *DataFrame, mapData *MapData) error {
+	if mapData.Count == 0 {
+		return nil
+	}
+
+	removed, err := op.rangeMapFields(mapData, nil)
+	if err != nil {
+		return fmt.Errorf("failed to range map fields: %w", err)
+	}
+	if removed == 0 {
+		return nil
+	}
+
+	return op.updateMapData(key, df, mapData)
+}
+
+func (op *Operator) updateMapData(key string, df *DataFrame, mapData *MapData) error {
+	if err := df.SetMap(mapData); err != nil {
+		return fmt.Errorf("failed to update map metadata: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to update map metadata: %w", err)
+	}
+
+	return nil
+}
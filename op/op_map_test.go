@@ -1,7 +1,17 @@
 ﻿package op
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+
+	"github.com/rivulet-io/tower/util/size"
 )
 
 func TestMapBasicOperations(t *testing.T) {
@@ -172,3 +182,586 @@ func TestMapErrorCases(t *testing.T) {
 	}
 }
 
+
+func TestMapClamp(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test_map_clamp"
+	if err := tower.CreateMap(key); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+
+	t.Run("int", func(t *testing.T) {
+		if err := tower.SetMapKey(key, PrimitiveString("hp"), PrimitiveInt(150)); err != nil {
+			t.Fatalf("Failed to set map key: %v", err)
+		}
+
+		value, err := tower.MapClampInt(key, PrimitiveString("hp"), 0, 100)
+		if err != nil {
+			t.Fatalf("Failed to clamp: %v", err)
+		}
+		if value != 100 {
+			t.Errorf("Expected 100, got %d", value)
+		}
+
+		stored, err := tower.GetMapKey(key, PrimitiveString("hp"))
+		if err != nil {
+			t.Fatalf("Failed to get map key: %v", err)
+		}
+		if v, _ := stored.Int(); v != 100 {
+			t.Errorf("Expected stored 100, got %d", v)
+		}
+	})
+
+	t.Run("absent field", func(t *testing.T) {
+		value, err := tower.MapClampInt(key, PrimitiveString("mana"), 10, 50)
+		if err != nil {
+			t.Fatalf("Failed to clamp: %v", err)
+		}
+		if value != 10 {
+			t.Errorf("Expected 10, got %d", value)
+		}
+
+		length, err := tower.GetMapLength(key)
+		if err != nil {
+			t.Fatalf("Failed to get map length: %v", err)
+		}
+		if length != 2 {
+			t.Errorf("Expected 2 fields, got %d", length)
+		}
+	})
+
+	t.Run("float", func(t *testing.T) {
+		if err := tower.SetMapKey(key, PrimitiveString("ratio"), PrimitiveFloat(-0.5)); err != nil {
+			t.Fatalf("Failed to set map key: %v", err)
+		}
+
+		value, err := tower.MapClampFloat(key, PrimitiveString("ratio"), 0, 1)
+		if err != nil {
+			t.Fatalf("Failed to clamp: %v", err)
+		}
+		if value != 0 {
+			t.Errorf("Expected 0, got %f", value)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if err := tower.SetMapKey(key, PrimitiveString("name"), PrimitiveString("x")); err != nil {
+			t.Fatalf("Failed to set map key: %v", err)
+		}
+
+		if _, err := tower.MapClampInt(key, PrimitiveString("name"), 0, 10); err == nil {
+			t.Error("Expected error for non-numeric field")
+		}
+		if _, err := tower.MapClampInt(key, PrimitiveString("hp"), 10, 0); err == nil {
+			t.Error("Expected error for inverted range")
+		}
+		if _, err := tower.MapClampInt("missing_map", PrimitiveString("hp"), 0, 10); err == nil {
+			t.Error("Expected error for missing map")
+		}
+	})
+}
+
+func TestMapIncr(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test_map_incr"
+	if err := tower.CreateMap(key); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+
+	t.Run("int creates absent field", func(t *testing.T) {
+		value, err := tower.MapIncrInt(key, PrimitiveString("hits"), 5)
+		if err != nil {
+			t.Fatalf("Failed to increment: %v", err)
+		}
+		if value != 5 {
+			t.Errorf("Expected 5, got %d", value)
+		}
+
+		value, err = tower.MapIncrInt(key, PrimitiveString("hits"), -7)
+		if err != nil {
+			t.Fatalf("Failed to increment: %v", err)
+		}
+		if value != -2 {
+			t.Errorf("Expected -2, got %d", value)
+		}
+
+		stored, err := tower.GetMapKey(key, PrimitiveString("hits"))
+		if err != nil {
+			t.Fatalf("Failed to get map key: %v", err)
+		}
+		if v, _ := stored.Int(); v != -2 {
+			t.Errorf("Expected stored -2, got %d", v)
+		}
+
+		length, err := tower.GetMapLength(key)
+		if err != nil || length != 1 {
+			t.Errorf("Expected 1 field, got %d (%v)", length, err)
+		}
+	})
+
+	t.Run("float creates absent field", func(t *testing.T) {
+		value, err := tower.MapIncrFloat(key, PrimitiveString("ratio"), 0.25)
+		if err != nil {
+			t.Fatalf("Failed to increment: %v", err)
+		}
+		if value != 0.25 {
+			t.Errorf("Expected 0.25, got %f", value)
+		}
+
+		value, err = tower.MapIncrFloat(key, PrimitiveString("ratio"), 0.5)
+		if err != nil {
+			t.Fatalf("Failed to increment: %v", err)
+		}
+		if value != 0.75 {
+			t.Errorf("Expected 0.75, got %f", value)
+		}
+
+		length, err := tower.GetMapLength(key)
+		if err != nil || length != 2 {
+			t.Errorf("Expected 2 fields, got %d (%v)", length, err)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := tower.MapIncrInt(key, PrimitiveString("counter"), 1); err != nil {
+					t.Errorf("Failed to increment: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		stored, err := tower.GetMapKey(key, PrimitiveString("counter"))
+		if err != nil {
+			t.Fatalf("Failed to get map key: %v", err)
+		}
+		if v, _ := stored.Int(); v != 100 {
+			t.Errorf("Expected 100, got %d", v)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if err := tower.SetMapKey(key, PrimitiveString("name"), PrimitiveString("x")); err != nil {
+			t.Fatalf("Failed to set map key: %v", err)
+		}
+
+		if _, err := tower.MapIncrInt(key, PrimitiveString("name"), 1); err == nil {
+			t.Error("Expected error for string field")
+		}
+		if _, err := tower.MapIncrInt(key, PrimitiveString("ratio"), 1); err == nil {
+			t.Error("Expected error incrementing a float field as int")
+		}
+		if _, err := tower.MapIncrFloat(key, PrimitiveString("hits"), 1); err == nil {
+			t.Error("Expected error incrementing an int field as float")
+		}
+		if _, err := tower.MapIncrInt("missing_map", PrimitiveString("hits"), 1); err == nil {
+			t.Error("Expected error for missing map")
+		}
+
+		stored, err := tower.GetMapKey(key, PrimitiveString("name"))
+		if err != nil {
+			t.Fatalf("Failed to get map key: %v", err)
+		}
+		if v, _ := stored.String(); v != "x" {
+			t.Errorf("Expected field to be untouched, got %q", v)
+		}
+	})
+}
+
+func TestMapSetNX(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test_map_setnx"
+	if err := tower.CreateMap(key); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+
+	set, err := tower.MapSetNX(key, PrimitiveString("owner"), PrimitiveString("alice"))
+	if err != nil || !set {
+		t.Fatalf("Expected first MapSetNX to set, got %v (%v)", set, err)
+	}
+
+	set, err = tower.MapSetNX(key, PrimitiveString("owner"), PrimitiveString("bob"))
+	if err != nil || set {
+		t.Fatalf("Expected second MapSetNX to be skipped, got %v (%v)", set, err)
+	}
+
+	stored, err := tower.GetMapKey(key, PrimitiveString("owner"))
+	if err != nil {
+		t.Fatalf("Failed to get map key: %v", err)
+	}
+	if v, _ := stored.String(); v != "alice" {
+		t.Errorf("Expected alice, got %q", v)
+	}
+
+	set, err = tower.MapSetNX(key, PrimitiveString("count"), PrimitiveInt(3))
+	if err != nil || !set {
+		t.Fatalf("Expected MapSetNX to set a new field, got %v (%v)", set, err)
+	}
+	if value, err := tower.MapIncrInt(key, PrimitiveString("count"), 1); err != nil || value != 4 {
+		t.Errorf("Expected 4, got %d (%v)", value, err)
+	}
+
+	length, err := tower.GetMapLength(key)
+	if err != nil || length != 2 {
+		t.Errorf("Expected 2 fields, got %d (%v)", length, err)
+	}
+
+	if _, err := tower.MapSetNX("missing_map", PrimitiveString("owner"), PrimitiveString("x")); err == nil {
+		t.Error("Expected error for missing map")
+	}
+}
+
+func TestMapMulti(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test_map_multi"
+	if err := tower.CreateMap(key); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+	if err := tower.SetMapKey(key, PrimitiveString("name"), PrimitiveString("old")); err != nil {
+		t.Fatalf("Failed to set map key: %v", err)
+	}
+
+	err := tower.MapMultiSet(key, map[PrimitiveData]PrimitiveData{
+		PrimitiveString("name"):   PrimitiveString("tower"),
+		PrimitiveString("count"):  PrimitiveInt(7),
+		PrimitiveString("active"): PrimitiveBool(true),
+	})
+	if err != nil {
+		t.Fatalf("Failed to multi set: %v", err)
+	}
+
+	length, err := tower.GetMapLength(key)
+	if err != nil || length != 3 {
+		t.Errorf("Expected 3 fields, got %d (%v)", length, err)
+	}
+
+	values, err := tower.MapMultiGet(key, []PrimitiveData{
+		PrimitiveString("count"),
+		PrimitiveString("missing"),
+		PrimitiveString("name"),
+		PrimitiveString("active"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to multi get: %v", err)
+	}
+	if len(values) != 4 {
+		t.Fatalf("Expected 4 values, got %d", len(values))
+	}
+	if v, err := values[0].Int(); err != nil || v != 7 {
+		t.Errorf("Expected count 7, got %d (%v)", v, err)
+	}
+	if values[1].Type() != TypeNull {
+		t.Errorf("Expected null for missing field, got type %v", values[1].Type())
+	}
+	if v, err := values[2].String(); err != nil || v != "tower" {
+		t.Errorf("Expected overwritten name, got %q (%v)", v, err)
+	}
+	if v, err := values[3].Bool(); err != nil || !v {
+		t.Errorf("Expected active true, got %v (%v)", v, err)
+	}
+
+	if _, err := tower.MapMultiGet("missing_map", []PrimitiveData{PrimitiveString("name")}); err == nil {
+		t.Error("Expected error for missing map")
+	}
+	if err := tower.MapMultiSet("missing_map", map[PrimitiveData]PrimitiveData{PrimitiveString("a"): PrimitiveInt(1)}); err == nil {
+		t.Error("Expected error for missing map")
+	}
+}
+
+func TestMapEntries(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "profile"
+	if err := tower.CreateMap(key); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+
+	entries, err := tower.MapEntries(key)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Expected no entries for an empty map, got %v (%v)", entries, err)
+	}
+
+	values := map[string]PrimitiveData{
+		"name":   PrimitiveString("ada"),
+		"age":    PrimitiveInt(36),
+		"score":  PrimitiveFloat(9.5),
+		"admin":  PrimitiveBool(true),
+		"avatar": PrimitiveBinary([]byte{1, 2, 3}),
+	}
+	for field, value := range values {
+		if err := tower.SetMapKey(key, PrimitiveString(field), value); err != nil {
+			t.Fatalf("Failed to set map key: %v", err)
+		}
+	}
+
+	entries, err = tower.MapEntries(key)
+	if err != nil {
+		t.Fatalf("MapEntries failed: %v", err)
+	}
+	fields, err := tower.GetMapKeys(key)
+	if err != nil {
+		t.Fatalf("GetMapKeys failed: %v", err)
+	}
+	fieldValues, err := tower.GetMapValues(key)
+	if err != nil {
+		t.Fatalf("GetMapValues failed: %v", err)
+	}
+
+	if len(entries) != len(values) || len(fields) != len(values) || len(fieldValues) != len(values) {
+		t.Fatalf("Expected %d entries, keys and values, got %d, %d and %d", len(values), len(entries), len(fields), len(fieldValues))
+	}
+	for i, entry := range entries {
+		field, _ := entry.Field.String()
+		if want, _ := fields[i].String(); field != want {
+			t.Errorf("Entry %d: expected field %s, got %s", i, want, field)
+		}
+		if !reflect.DeepEqual(entry.Value, fieldValues[i]) {
+			t.Errorf("Entry %d: expected value %v, got %v", i, fieldValues[i], entry.Value)
+		}
+		if !reflect.DeepEqual(entry.Value, values[field]) {
+			t.Errorf("Field %s: expected %v, got %v", field, values[field], entry.Value)
+		}
+		if i > 0 {
+			if prev, _ := entries[i-1].Field.String(); prev >= field {
+				t.Errorf("Expected fields in order, got %s before %s", prev, field)
+			}
+		}
+	}
+
+	visited := 0
+	if err := tower.ForEachMapEntry(key, func(field, value PrimitiveData) bool {
+		visited++
+		return visited < 2
+	}); err != nil {
+		t.Fatalf("ForEachMapEntry failed: %v", err)
+	}
+	if visited != 2 {
+		t.Errorf("Expected iteration to stop after 2 entries, got %d", visited)
+	}
+
+	if _, err := tower.MapEntries("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestMapSetEx(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "session"
+	if err := tower.CreateMap(key); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+
+	if err := tower.MapSetEx(key, PrimitiveString("token"), PrimitiveString("abc"), time.Second); err != nil {
+		t.Fatalf("MapSetEx failed: %v", err)
+	}
+	if err := tower.MapSetEx(key, PrimitiveString("csrf"), PrimitiveString("xyz"), time.Second); err != nil {
+		t.Fatalf("MapSetEx failed: %v", err)
+	}
+	if err := tower.SetMapKey(key, PrimitiveString("user"), PrimitiveString("ada")); err != nil {
+		t.Fatalf("Failed to set map key: %v", err)
+	}
+	if err := tower.MapSetEx(key, PrimitiveString("user"), PrimitiveString("ada"), 0); err == nil {
+		t.Error("Expected error for a non-positive TTL")
+	}
+
+	length, err := tower.GetMapLength(key)
+	if err != nil || length != 3 {
+		t.Fatalf("Expected 3 fields, got %d (%v)", length, err)
+	}
+	if value, err := tower.GetMapKey(key, PrimitiveString("token")); err != nil {
+		t.Errorf("Failed to get field before expiry: %v", err)
+	} else if s, _ := value.String(); s != "abc" {
+		t.Errorf("Expected abc, got %s", s)
+	}
+
+	// Wait for token and csrf to expire
+	time.Sleep(2 * time.Second)
+
+	if _, err := tower.GetMapKey(key, PrimitiveString("token")); err == nil {
+		t.Error("Expected expired field to be gone")
+	}
+	length, err = tower.GetMapLength(key)
+	if err != nil || length != 1 {
+		t.Errorf("Expected 1 field after expiry, got %d (%v)", length, err)
+	}
+	entries, err := tower.MapEntries(key)
+	if err != nil {
+		t.Fatalf("MapEntries failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry after expiry, got %v", entries)
+	}
+	if field, _ := entries[0].Field.String(); field != "user" {
+		t.Errorf("Expected user to remain, got %s", field)
+	}
+
+	// The map is queued for the sweep, which reclaims expired fields
+	// without a read. It lands in the bucket after the field expires, so
+	// read the buckets of both ends of the call.
+	before := time.Now()
+	if err := tower.MapSetEx(key, PrimitiveString("token"), PrimitiveString("def"), time.Second); err != nil {
+		t.Fatalf("MapSetEx failed: %v", err)
+	}
+	after := time.Now()
+	time.Sleep(2 * time.Second)
+	var candidates []string
+	for _, at := range []time.Time{before, after} {
+		bucket := time.UnixMilli(tower.ceilTTLTimestamp(at.Add(time.Second)))
+		members, err := tower.extractCandidatesForExpiration(bucket)
+		if err != nil {
+			t.Fatalf("Failed to extract expiration candidates: %v", err)
+		}
+		candidates = append(candidates, members...)
+	}
+	if !slices.Contains(candidates, key) {
+		t.Errorf("Expected %s among expiration candidates, got %v", key, candidates)
+	}
+
+	unlock, err := tower.lock(key)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+	df, err := tower.get(key)
+	if err != nil {
+		t.Fatalf("Failed to get map: %v", err)
+	}
+	mapData, err := df.Map()
+	if err != nil {
+		t.Fatalf("Failed to get map data: %v", err)
+	}
+	if err := tower.removeExpiredMapFields(key, df, mapData); err != nil {
+		t.Fatalf("Failed to remove expired fields: %v", err)
+	}
+	unlock()
+	if mapData.Count != 1 {
+		t.Errorf("Expected 1 field after the sweep, got %d", mapData.Count)
+	}
+	if _, _, err := tower.db.Get(MakeMapItemKey(key, "token")); !errors.Is(err, pebble.ErrNotFound) {
+		t.Errorf("Expected expired field to be deleted, got %v", err)
+	}
+}
+
+func TestMapRenameField(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "profile"
+	if err := tower.CreateMap(key); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+	if err := tower.SetMapKey(key, PrimitiveString("age"), PrimitiveInt(36)); err != nil {
+		t.Fatalf("Failed to set map key: %v", err)
+	}
+	if err := tower.SetMapKey(key, PrimitiveString("name"), PrimitiveString("ada")); err != nil {
+		t.Fatalf("Failed to set map key: %v", err)
+	}
+
+	// The value keeps its type under the new field
+	if err := tower.MapRenameField(key, PrimitiveString("age"), PrimitiveString("years"), false); err != nil {
+		t.Fatalf("MapRenameField failed: %v", err)
+	}
+	if _, err := tower.GetMapKey(key, PrimitiveString("age")); err == nil {
+		t.Error("Expected old field to be gone")
+	}
+	value, err := tower.GetMapKey(key, PrimitiveString("years"))
+	if err != nil {
+		t.Fatalf("Failed to get renamed field: %v", err)
+	}
+	if v, err := value.Int(); err != nil || v != 36 {
+		t.Errorf("Expected int 36, got %v (%v)", value, err)
+	}
+
+	// Renaming onto an existing field without overwrite leaves both intact
+	err = tower.MapRenameField(key, PrimitiveString("years"), PrimitiveString("name"), false)
+	if !errors.Is(err, ErrItemExists) {
+		t.Errorf("Expected ErrItemExists, got %v", err)
+	}
+	if value, err := tower.GetMapKey(key, PrimitiveString("name")); err != nil {
+		t.Errorf("Failed to get field: %v", err)
+	} else if s, _ := value.String(); s != "ada" {
+		t.Errorf("Expected ada, got %s", s)
+	}
+	if length, err := tower.GetMapLength(key); err != nil || length != 2 {
+		t.Errorf("Expected 2 fields, got %d (%v)", length, err)
+	}
+
+	if err := tower.MapRenameField(key, PrimitiveString("years"), PrimitiveString("name"), true); err != nil {
+		t.Fatalf("MapRenameField with overwrite failed: %v", err)
+	}
+	if value, err := tower.GetMapKey(key, PrimitiveString("name")); err != nil {
+		t.Errorf("Failed to get field: %v", err)
+	} else if v, _ := value.Int(); v != 36 {
+		t.Errorf("Expected overwritten field to hold 36, got %v", value)
+	}
+	if length, err := tower.GetMapLength(key); err != nil || length != 1 {
+		t.Errorf("Expected 1 field after overwrite, got %d (%v)", length, err)
+	}
+
+	if err := tower.MapRenameField(key, PrimitiveString("name"), PrimitiveString("name"), false); err != nil {
+		t.Errorf("Expected renaming a field to itself to succeed, got %v", err)
+	}
+	if err := tower.MapRenameField(key, PrimitiveString("missing"), PrimitiveString("other"), false); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for a missing field, got %v", err)
+	}
+	if err := tower.MapRenameField("nope", PrimitiveString("a"), PrimitiveString("b"), false); err == nil {
+		t.Error("Expected error for a missing map")
+	}
+}
+
+func BenchmarkMapMultiGet(b *testing.B) {
+	tower, err := NewOperator(&Options{
+		Path:         "data",
+		FS:           InMemory(),
+		CacheSize:    size.NewSizeFromMegabytes(64),
+		MemTableSize: size.NewSizeFromMegabytes(16),
+		BytesPerSync: size.NewSizeFromKilobytes(512),
+	})
+	if err != nil {
+		b.Fatalf("Failed to create tower: %v", err)
+	}
+	defer tower.Close()
+
+	key := "bench_map"
+	if err := tower.CreateMap(key); err != nil {
+		b.Fatalf("Failed to create map: %v", err)
+	}
+
+	fields := make([]PrimitiveData, 32)
+	pairs := make(map[PrimitiveData]PrimitiveData, len(fields))
+	for i := range fields {
+		fields[i] = PrimitiveString(fmt.Sprintf("field_%d", i))
+		pairs[fields[i]] = PrimitiveInt(i)
+	}
+	if err := tower.MapMultiSet(key, pairs); err != nil {
+		b.Fatalf("Failed to multi set: %v", err)
+	}
+
+	b.Run("GetMapKey", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, field := range fields {
+				tower.GetMapKey(key, field)
+			}
+		}
+	})
+
+	b.Run("MapMultiGet", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tower.MapMultiGet(key, fields)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package op
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics receives the duration and outcome of every operation. Exported
+// operations are reported under their method name, such as "SetInt", and
+// the storage accesses beneath them as "get", "set" and "delete", or as
+// "commit" for writes batched together, such as those of containers and
+// group commit. ObserveOp runs on the caller's goroutine, so it must be
+// cheap and safe for concurrent use.
+type Metrics interface {
+	ObserveOp(op string, dur time.Duration, err error)
+}
+
+// NopMetrics discards every observation. Operators without Options.Metrics
+// behave as if given NopMetrics, without paying for the timing.
+type NopMetrics struct{}
+
+func (NopMetrics) ObserveOp(string, time.Duration, error) {}
+
+// observe times a storage access for Options.Metrics; use it as
+//
+//	defer op.observe("get")(&err)
+func (op *Operator) observe(name string) func(err *error) {
+	if op.metrics == nil {
+		return noopTraceEnd
+	}
+
+	start := time.Now()
+	return func(err *error) {
+		var opErr error
+		if err != nil {
+			opErr = *err
+		}
+		op.metrics.ObserveOp(name, time.Since(start), opErr)
+	}
+}
+
+// PrometheusMetrics records operations in the tower_operation_duration_seconds
+// histogram, labelled by operation and by status "ok" or "error". Its
+// _count series gives the throughput of each operation.
+type PrometheusMetrics struct {
+	durations *prometheus.HistogramVec
+}
+
+// NewPrometheusMetrics creates a PrometheusMetrics registered with reg.
+func NewPrometheusMetrics(reg prometheus.Registerer) (*PrometheusMetrics, error) {
+	durations := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "tower",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of Tower operations.",
+		Buckets:   prometheus.ExponentialBuckets(10e-6, 4, 10),
+	}, []string{"operation", "status"})
+
+	if err := reg.Register(durations); err != nil {
+		return nil, err
+	}
+
+	return &PrometheusMetrics{durations: durations}, nil
+}
+
+func (m *PrometheusMetrics) ObserveOp(op string, dur time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.durations.WithLabelValues(op, status).Observe(dur.Seconds())
+}
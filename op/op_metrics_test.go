@@ -0,0 +1,146 @@
+package op
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rivulet-io/tower/util/size"
+)
+
+type capturedOp struct {
+	name string
+	err  error
+}
+
+type capturingMetrics struct {
+	mu  sync.Mutex
+	ops []capturedOp
+}
+
+func (m *capturingMetrics) ObserveOp(op string, dur time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ops = append(m.ops, capturedOp{name: op, err: err})
+}
+
+func (m *capturingMetrics) reset() []capturedOp {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ops := m.ops
+	m.ops = nil
+	return ops
+}
+
+func TestMetrics(t *testing.T) {
+	openWith := func(t *testing.T, metrics Metrics) *Operator {
+		t.Helper()
+		tower, err := NewOperator(&Options{
+			Path:         "test.db",
+			CacheSize:    size.NewSizeFromMegabytes(8),
+			MemTableSize: size.NewSizeFromMegabytes(4),
+			FS:           InMemory(),
+			Metrics:      metrics,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create tower: %v", err)
+		}
+		return tower
+	}
+
+	t.Run("capturing", func(t *testing.T) {
+		metrics := &capturingMetrics{}
+		tower := openWith(t, metrics)
+		defer tower.Close()
+
+		if err := tower.SetInt("counter", 1); err != nil {
+			t.Fatalf("Failed to set int: %v", err)
+		}
+
+		counts := map[string]int{}
+		for _, op := range metrics.reset() {
+			if op.err != nil {
+				t.Errorf("Expected %s to succeed, got %v", op.name, op.err)
+			}
+			counts[op.name]++
+		}
+		if counts["set"] != 1 {
+			t.Errorf("Expected exactly one set observation, got %d", counts["set"])
+		}
+		if counts["SetInt"] != 1 {
+			t.Errorf("Expected exactly one SetInt observation, got %d", counts["SetInt"])
+		}
+
+		if _, err := tower.GetInt("missing"); err == nil {
+			t.Fatal("Expected error for missing key")
+		}
+		failed := map[string]bool{}
+		for _, op := range metrics.reset() {
+			failed[op.name] = op.err != nil
+		}
+		if !failed["get"] || !failed["GetInt"] {
+			t.Errorf("Expected failed get and GetInt observations, got %v", failed)
+		}
+	})
+
+	t.Run("batched writes", func(t *testing.T) {
+		metrics := &capturingMetrics{}
+		tower := openWith(t, metrics)
+		defer tower.Close()
+
+		if err := tower.CreateList("queue"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		metrics.reset()
+
+		if _, err := tower.PushLeftList("queue", PrimitiveString("job")); err != nil {
+			t.Fatalf("Failed to push list item: %v", err)
+		}
+
+		counts := map[string]int{}
+		for _, op := range metrics.reset() {
+			counts[op.name]++
+		}
+		if counts["commit"] != 1 {
+			t.Errorf("Expected exactly one commit observation, got %d", counts["commit"])
+		}
+	})
+
+	t.Run("prometheus", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		metrics, err := NewPrometheusMetrics(reg)
+		if err != nil {
+			t.Fatalf("Failed to create metrics: %v", err)
+		}
+		tower := openWith(t, metrics)
+		defer tower.Close()
+
+		for i := 0; i < 3; i++ {
+			if err := tower.SetInt("counter", int64(i)); err != nil {
+				t.Fatalf("Failed to set int: %v", err)
+			}
+		}
+
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatalf("Failed to gather metrics: %v", err)
+		}
+		if len(families) != 1 || families[0].GetName() != "tower_operation_duration_seconds" {
+			t.Fatalf("Expected tower_operation_duration_seconds, got %v", families)
+		}
+
+		var sets uint64
+		for _, metric := range families[0].GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "operation" && label.GetValue() == "SetInt" {
+					sets += metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		if sets != 3 {
+			t.Errorf("Expected 3 SetInt samples, got %d", sets)
+		}
+	})
+}
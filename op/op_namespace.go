@@ -0,0 +1,190 @@
+package op
+
+import (
+	"io"
+	"strings"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// store is the Pebble DB as seen from one namespace. Keys going in are
+// prefixed with the namespace and keys coming out of iterators have it
+// stripped, so the operator code above works with the same keys whether or
+// not it is namespaced. The root operator has an empty namespace, for which
+// keys pass through untouched. Methods not overridden here, such as Flush or
+// Metrics, act on the whole store.
+type store struct {
+	*pebble.DB
+	ns string
+}
+
+// WithNamespace returns an operator that keeps its keys under prefix, sharing
+// storage, locks and background work with op. Every key it reads or writes,
+// including the items of lists, maps and other containers and its own TTL
+// bookkeeping, is stored with prefix in front; scans, DeletePrefix and
+// DeleteRange only see keys within the namespace and return them without
+// the prefix. Namespaces nest: a namespace of a namespace appends to its
+// prefix.
+//
+// prefix should end in a separator, such as "tenant1:", so that no
+// namespace is a prefix of another's keys. Key events and eviction handlers
+// registered on op report the full stored keys of namespaced operators.
+// Expired keys of a namespace are swept by calling StartTTLTimer or
+// TruncateExpired on its operator. Closing a namespaced operator does nothing;
+// the shared store stays open until op is closed.
+func (op *Operator) WithNamespace(prefix string) *Operator {
+	bound := *op
+	bound.db = &store{DB: op.db.DB, ns: op.db.ns + prefix}
+	bound.derived = true
+	return &bound
+}
+
+// Namespace returns the key prefix of op, empty for the root operator.
+func (op *Operator) Namespace() string {
+	return op.db.ns
+}
+
+// withNamespace returns a view of op over the namespace ns, which is the full
+// prefix rather than one relative to op.
+func (op *Operator) withNamespace(ns string) *Operator {
+	if ns == op.db.ns {
+		return op
+	}
+	bound := *op
+	bound.db = &store{DB: op.db.DB, ns: ns}
+	bound.derived = true
+	return &bound
+}
+
+// isSystemKey reports whether key holds internal bookkeeping, either of the
+// operator scanning it or of a namespace below it.
+func isSystemKey(key string) bool {
+	return strings.Contains(key, systemKeyPrefix)
+}
+
+// rawKey returns key as stored, with the namespace in front.
+func (s *store) rawKey(key string) string {
+	return s.ns + key
+}
+
+func (s *store) key(key []byte) []byte {
+	if s.ns == "" {
+		return key
+	}
+	raw := make([]byte, 0, len(s.ns)+len(key))
+	return append(append(raw, s.ns...), key...)
+}
+
+// bounds prefixes the bounds of o, limiting unbounded ends to the namespace.
+func (s *store) bounds(o *pebble.IterOptions) *pebble.IterOptions {
+	if s.ns == "" {
+		return o
+	}
+
+	bounded := pebble.IterOptions{}
+	if o != nil {
+		bounded = *o
+	}
+	bounded.LowerBound = s.key(bounded.LowerBound)
+	if bounded.UpperBound != nil {
+		bounded.UpperBound = s.key(bounded.UpperBound)
+	} else {
+		bounded.UpperBound = prefixEnd([]byte(s.ns))
+	}
+	return &bounded
+}
+
+func (s *store) Get(key []byte) ([]byte, io.Closer, error) {
+	return s.DB.Get(s.key(key))
+}
+
+func (s *store) Set(key, value []byte, opts *pebble.WriteOptions) error {
+	return s.DB.Set(s.key(key), value, opts)
+}
+
+func (s *store) Delete(key []byte, opts *pebble.WriteOptions) error {
+	return s.DB.Delete(s.key(key), opts)
+}
+
+func (s *store) DeleteRange(start, end []byte, opts *pebble.WriteOptions) error {
+	return s.DB.DeleteRange(s.key(start), s.key(end), opts)
+}
+
+func (s *store) Compact(start, end []byte, parallelize bool) error {
+	return s.DB.Compact(s.key(start), s.key(end), parallelize)
+}
+
+func (s *store) NewIter(o *pebble.IterOptions) (*storeIter, error) {
+	iter, err := s.DB.NewIter(s.bounds(o))
+	if err != nil {
+		return nil, err
+	}
+	return &storeIter{Iterator: iter, store: s}, nil
+}
+
+func (s *store) NewBatch() *storeBatch {
+	return &storeBatch{Batch: s.DB.NewBatch(), store: s}
+}
+
+func (s *store) NewSnapshot() *storeSnapshot {
+	return &storeSnapshot{Snapshot: s.DB.NewSnapshot(), store: s}
+}
+
+// storeReader is what scans need from a store or a snapshot of it.
+type storeReader interface {
+	Get(key []byte) ([]byte, io.Closer, error)
+	NewIter(o *pebble.IterOptions) (*storeIter, error)
+}
+
+type storeIter struct {
+	*pebble.Iterator
+	store *store
+}
+
+func (it *storeIter) Key() []byte {
+	return it.Iterator.Key()[len(it.store.ns):]
+}
+
+func (it *storeIter) SeekGE(key []byte) bool {
+	return it.Iterator.SeekGE(it.store.key(key))
+}
+
+func (it *storeIter) SeekLT(key []byte) bool {
+	return it.Iterator.SeekLT(it.store.key(key))
+}
+
+// storeBatch is a Pebble batch writing within a namespace. Reader and Apply
+// are not overridden and work with the stored keys.
+type storeBatch struct {
+	*pebble.Batch
+	store *store
+}
+
+func (b *storeBatch) Set(key, value []byte, opts *pebble.WriteOptions) error {
+	return b.Batch.Set(b.store.key(key), value, opts)
+}
+
+func (b *storeBatch) Delete(key []byte, opts *pebble.WriteOptions) error {
+	return b.Batch.Delete(b.store.key(key), opts)
+}
+
+func (b *storeBatch) DeleteRange(start, end []byte, opts *pebble.WriteOptions) error {
+	return b.Batch.DeleteRange(b.store.key(start), b.store.key(end), opts)
+}
+
+type storeSnapshot struct {
+	*pebble.Snapshot
+	store *store
+}
+
+func (s *storeSnapshot) Get(key []byte) ([]byte, io.Closer, error) {
+	return s.Snapshot.Get(s.store.key(key))
+}
+
+func (s *storeSnapshot) NewIter(o *pebble.IterOptions) (*storeIter, error) {
+	iter, err := s.Snapshot.NewIter(s.store.bounds(o))
+	if err != nil {
+		return nil, err
+	}
+	return &storeIter{Iterator: iter, store: s.store}, nil
+}
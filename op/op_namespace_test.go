@@ -0,0 +1,194 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rivulet-io/tower/util/size"
+)
+
+func TestWithNamespace(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	alpha := tower.WithNamespace("alpha:")
+	beta := tower.WithNamespace("beta:")
+
+	if alpha.Namespace() != "alpha:" || tower.Namespace() != "" {
+		t.Errorf("Unexpected namespaces %q and %q", alpha.Namespace(), tower.Namespace())
+	}
+
+	for _, ns := range []*Operator{alpha, beta} {
+		if err := ns.SetString("name", ns.Namespace()); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if err := ns.CreateList("queue"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		if _, err := ns.PushRightList("queue", PrimitiveString(ns.Namespace()+"job")); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+		if err := ns.CreateMap("config"); err != nil {
+			t.Fatalf("Failed to create map: %v", err)
+		}
+		if err := ns.SetMapKey("config", PrimitiveString("mode"), PrimitiveString(ns.Namespace())); err != nil {
+			t.Fatalf("Failed to set map key: %v", err)
+		}
+	}
+	if err := alpha.SetStringEx("session", "token", time.Hour); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+
+	// Each namespace reads back its own values under the same keys
+	for _, ns := range []*Operator{alpha, beta} {
+		if v, err := ns.GetString("name"); err != nil || v != ns.Namespace() {
+			t.Errorf("Expected name %q, got %q (%v)", ns.Namespace(), v, err)
+		}
+		if v, err := ns.GetListIndex("queue", 0); err != nil {
+			t.Errorf("Failed to get list item: %v", err)
+		} else if s, _ := v.String(); s != ns.Namespace()+"job" {
+			t.Errorf("Expected list item %q, got %q", ns.Namespace()+"job", s)
+		}
+		if v, err := ns.GetMapKey("config", PrimitiveString("mode")); err != nil {
+			t.Errorf("Failed to get map key: %v", err)
+		} else if s, _ := v.String(); s != ns.Namespace() {
+			t.Errorf("Expected map value %q, got %q", ns.Namespace(), s)
+		}
+	}
+
+	scan := func(op *Operator) []string {
+		t.Helper()
+		var keys []string
+		if err := op.ScanKeys("", func(key string, typ DataType) bool {
+			keys = append(keys, key)
+			return true
+		}); err != nil {
+			t.Fatalf("Failed to scan keys: %v", err)
+		}
+		return keys
+	}
+
+	// Scans stay inside the namespace, skip its TTL bookkeeping and strip
+	// the prefix; the root operator sees every namespace
+	if keys := scan(alpha); !slices.Equal(keys, []string{"config", "name", "queue", "session"}) {
+		t.Errorf("Unexpected alpha keys %v", keys)
+	}
+	if keys := scan(beta); !slices.Equal(keys, []string{"config", "name", "queue"}) {
+		t.Errorf("Unexpected beta keys %v", keys)
+	}
+	if _, err := tower.GetString("name"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected root to miss the unprefixed key, got %v", err)
+	}
+	if v, err := tower.GetString("alpha:name"); err != nil || v != "alpha:" {
+		t.Errorf("Expected root to read the prefixed key, got %q (%v)", v, err)
+	}
+	if keys := scan(tower); len(keys) != 7 {
+		t.Errorf("Expected 7 keys across both namespaces, got %v", keys)
+	}
+
+	// DeletePrefix only clears keys of its own namespace
+	deleted, err := alpha.DeletePrefix("n")
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected to delete 1 key, got %d (%v)", deleted, err)
+	}
+	if _, err := alpha.GetString("name"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected alpha name to be deleted, got %v", err)
+	}
+	if v, err := beta.GetString("name"); err != nil || v != "beta:" {
+		t.Errorf("Expected beta name to survive, got %q (%v)", v, err)
+	}
+
+	if err := beta.DeleteList("queue"); err != nil {
+		t.Fatalf("Failed to delete list: %v", err)
+	}
+	if n, err := alpha.GetListLength("queue"); err != nil || n != 1 {
+		t.Errorf("Expected alpha queue to keep 1 item, got %d (%v)", n, err)
+	}
+
+	// Events and transactions use keys relative to the namespace
+	events := make(chan KeyEvent, 4)
+	defer beta.Subscribe("na*", func(event KeyEvent) {
+		events <- event
+	})()
+
+	err = beta.Transaction([]string{"name"}, func(tx *Txn) error {
+		value, err := tx.Get("name")
+		if err != nil {
+			return err
+		}
+		s, _ := value.String()
+		tx.Set("name", PrimitiveString(s+"updated"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if err := alpha.SetString("name", "ignored"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.Key != "name" || event.Kind != KeyEventSet {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Expected no event from another namespace, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if v, _ := beta.GetString("name"); v != "beta:updated" {
+		t.Errorf("Expected beta name to be updated, got %q", v)
+	}
+
+	// Nested namespaces compose prefixes
+	nested := alpha.WithNamespace("inner:")
+	if err := nested.SetInt("count", 1); err != nil {
+		t.Fatalf("Failed to set int: %v", err)
+	}
+	if v, err := tower.GetInt("alpha:inner:count"); err != nil || v != 1 {
+		t.Errorf("Expected nested key under both prefixes, got %d (%v)", v, err)
+	}
+}
+
+func TestDerivedOperatorClose(t *testing.T) {
+	tower, err := NewOperator(&Options{
+		Path:           "test.db",
+		FS:             InMemory(),
+		CacheSize:      size.NewSizeFromMegabytes(8),
+		MemTableSize:   size.NewSizeFromMegabytes(4),
+		GroupCommit:    true,
+		MaxStoreSize:   size.NewSizeFromMegabytes(64),
+		EvictionPolicy: EvictionLRU,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tower: %v", err)
+	}
+
+	ns := tower.WithNamespace("tenant:")
+	bound := ns.WithContext(context.Background())
+	for _, derived := range []*Operator{ns, bound, ns} {
+		if err := derived.Close(); err != nil {
+			t.Errorf("Expected closing a derived operator to succeed, got %v", err)
+		}
+	}
+
+	// The shared store and its group committer keep working
+	if err := ns.SetString("name", "value"); err != nil {
+		t.Fatalf("Failed to set string after closing the namespace: %v", err)
+	}
+	if v, err := tower.GetString("tenant:name"); err != nil || v != "value" {
+		t.Errorf("Expected value, got %q (%v)", v, err)
+	}
+
+	if err := tower.Close(); err != nil {
+		t.Errorf("Failed to close tower: %v", err)
+	}
+}
@@ -1,10 +1,10 @@
 ﻿package op
 
 import (
-	"bytes"
 	"crypto/pbkdf2"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"fmt"
 
 	"golang.org/x/crypto/argon2"
@@ -129,13 +129,22 @@ func WithArgon2Params(time, memory uint32, threads uint8, keyLen uint32) Passwor
 	}
 }
 
-func (op *Operator) UpsertPassword(key string, password []byte, algorithm PasswordAlgorithm, saltLength int, options ...PasswordOption) error {
+func (op *Operator) UpsertPassword(key string, password []byte, algorithm PasswordAlgorithm, saltLength int, options ...PasswordOption) (err error) {
+	defer op.traceOperation("UpsertPassword", key)(&err)
+
+	return op.upsertPassword(key, password, algorithm, saltLength, options...)
+}
+
+func (op *Operator) upsertPassword(key string, password []byte, algorithm PasswordAlgorithm, saltLength int, options ...PasswordOption) error {
 	opts := DefaultPasswordOptions(algorithm)
 	for _, option := range options {
 		option(opts)
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	salt := make([]byte, saltLength)
@@ -160,6 +169,15 @@ func (op *Operator) UpsertPassword(key string, password []byte, algorithm Passwo
 	return nil
 }
 
+// SetPasswordFromPlaintext hashes plaintext with algorithm, its default
+// options and a random salt of DefaultPasswordSaltLength bytes, and stores
+// the result at key.
+func (op *Operator) SetPasswordFromPlaintext(key string, algorithm PasswordAlgorithm, plaintext []byte) (err error) {
+	defer op.traceOperation("SetPasswordFromPlaintext", key)(&err)
+
+	return op.upsertPassword(key, plaintext, algorithm, DefaultPasswordSaltLength)
+}
+
 // Unified password hash calculation function
 func (op *Operator) computePasswordHash(password, salt []byte, algorithm PasswordAlgorithm, opts *PasswordOptions) ([]byte, error) {
 	switch algorithm {
@@ -186,8 +204,13 @@ func (op *Operator) computePasswordHash(password, salt []byte, algorithm Passwor
 	}
 }
 
-func (op *Operator) VerifyPassword(key string, password []byte) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) VerifyPassword(key string, password []byte) (_ bool, err error) {
+	defer op.traceOperation("VerifyPassword", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -196,7 +219,7 @@ func (op *Operator) VerifyPassword(key string, password []byte) (bool, error) {
 	}
 
 	if df.typ != TypePassword {
-		return false, fmt.Errorf("key %s is not a password type", key)
+		return false, fmt.Errorf("key %s is not a password type: %w", key, ErrTypeMismatch)
 	}
 
 	algorithm, hash, salt, opts, err := df.Password()
@@ -225,7 +248,8 @@ func (op *Operator) VerifyPassword(key string, password []byte) (bool, error) {
 		if err != nil {
 			return false, fmt.Errorf("failed to compute password hash: %w", err)
 		}
-		return bytes.Equal(computed, hash), nil
+		// Compare in constant time so the check leaks nothing about the hash
+		return subtle.ConstantTimeCompare(computed, hash) == 1, nil
 	}
 }
 
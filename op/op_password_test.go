@@ -1,6 +1,7 @@
 ﻿package op
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/rivulet-io/tower/util/size"
@@ -373,6 +374,42 @@ func TestPasswordOperations(t *testing.T) {
 	})
 }
 
+func TestVerifyPasswordAllAlgorithms(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	algorithms := map[string]PasswordAlgorithm{
+		"bcrypt":   PasswordAlgorithmBcrypt,
+		"scrypt":   PasswordAlgorithmScrypt,
+		"pbkdf2":   PasswordAlgorithmPBKDF2,
+		"argon2i":  PasswordAlgorithmArgon2i,
+		"argon2id": PasswordAlgorithmArgon2id,
+	}
+
+	password := []byte("correct horse battery staple")
+	for name, algorithm := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			key := "verify_" + name
+			if err := tower.SetPasswordFromPlaintext(key, algorithm, password); err != nil {
+				t.Fatalf("SetPasswordFromPlaintext failed: %v", err)
+			}
+
+			if ok, err := tower.VerifyPassword(key, password); err != nil || !ok {
+				t.Errorf("Expected password to verify, got %v (%v)", ok, err)
+			}
+
+			// Same length with one byte changed, a prefix and an empty candidate
+			nearMiss := bytes.Clone(password)
+			nearMiss[len(nearMiss)-1] ^= 1
+			for _, candidate := range [][]byte{nearMiss, password[:len(password)-1], {}} {
+				if ok, err := tower.VerifyPassword(key, candidate); err != nil || ok {
+					t.Errorf("Expected %q to be rejected, got %v (%v)", candidate, ok, err)
+				}
+			}
+		})
+	}
+}
+
 // Benchmark tests
 func BenchmarkPasswordOperations(b *testing.B) {
 	tower, err := NewOperator(&Options{
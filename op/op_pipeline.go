@@ -0,0 +1,124 @@
+package op
+
+import (
+	"fmt"
+)
+
+// KeyPipeline runs a sequence of operations on one key under a single lock
+// acquisition. The value is loaded on first use and written back once when
+// the WithKey callback returns, so intermediate results never reach the
+// store. A KeyPipeline is only valid inside its callback.
+type KeyPipeline struct {
+	op     *Operator
+	key    string
+	df     *DataFrame
+	loaded bool
+	dirty  bool
+}
+
+// WithKey locks key, calls fn, and writes the value back if fn changed it.
+// If fn returns an error nothing is written and the error is returned. fn
+// must not call other operations on the same key, which would deadlock.
+func (op *Operator) WithKey(key string, fn func(kp *KeyPipeline) error) (err error) {
+	defer op.traceOperation("WithKey", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	kp := &KeyPipeline{op: op, key: key}
+	if err := fn(kp); err != nil {
+		return err
+	}
+
+	if !kp.dirty {
+		return nil
+	}
+
+	if err := op.set(key, kp.df); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// Key returns the key the pipeline operates on.
+func (kp *KeyPipeline) Key() string {
+	return kp.key
+}
+
+func (kp *KeyPipeline) load() (*DataFrame, error) {
+	if !kp.loaded {
+		df, err := kp.op.get(kp.key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get key %s: %w", kp.key, err)
+		}
+		kp.df, kp.loaded = df, true
+	}
+
+	return kp.df, nil
+}
+
+func (kp *KeyPipeline) int() (int64, error) {
+	df, err := kp.load()
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := df.Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get int value for key %s: %w", kp.key, err)
+	}
+
+	return value, nil
+}
+
+func (kp *KeyPipeline) setInt(value int64) (int64, error) {
+	if err := kp.df.SetInt(value); err != nil {
+		return 0, fmt.Errorf("failed to set int value: %w", err)
+	}
+	kp.dirty = true
+
+	return value, nil
+}
+
+// GetInt returns the int at the key, including changes made earlier in the
+// pipeline.
+func (kp *KeyPipeline) GetInt() (int64, error) {
+	return kp.int()
+}
+
+// SetInt replaces the value at the key with an int, like Operator.SetInt.
+func (kp *KeyPipeline) SetInt(value int64) error {
+	kp.df, kp.loaded = NULLDataFrame(), true
+	_, err := kp.setInt(value)
+	return err
+}
+
+// AddInt adds delta to the int at the key and returns the result, wrapping
+// around on overflow like Operator.AddInt.
+func (kp *KeyPipeline) AddInt(delta int64) (int64, error) {
+	current, err := kp.int()
+	if err != nil {
+		return 0, err
+	}
+
+	return kp.setInt(current + delta)
+}
+
+// SubInt subtracts delta from the int at the key and returns the result.
+func (kp *KeyPipeline) SubInt(delta int64) (int64, error) {
+	return kp.AddInt(-delta)
+}
+
+// MulInt multiplies the int at the key by factor and returns the result.
+func (kp *KeyPipeline) MulInt(factor int64) (int64, error) {
+	current, err := kp.int()
+	if err != nil {
+		return 0, err
+	}
+
+	return kp.setInt(current * factor)
+}
@@ -0,0 +1,134 @@
+package op
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rivulet-io/tower/util/size"
+)
+
+func TestWithKey(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.SetInt("counter", 5); err != nil {
+		t.Fatalf("Failed to set int: %v", err)
+	}
+
+	err := tower.WithKey("counter", func(kp *KeyPipeline) error {
+		if v, err := kp.AddInt(3); err != nil || v != 8 {
+			t.Errorf("Expected AddInt to return 8, got %d (%v)", v, err)
+		}
+		if v, err := kp.MulInt(4); err != nil || v != 32 {
+			t.Errorf("Expected MulInt to return 32, got %d (%v)", v, err)
+		}
+		if v, err := kp.SubInt(2); err != nil || v != 30 {
+			t.Errorf("Expected SubInt to return 30, got %d (%v)", v, err)
+		}
+		if v, err := kp.GetInt(); err != nil || v != 30 {
+			t.Errorf("Expected GetInt to return 30, got %d (%v)", v, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithKey failed: %v", err)
+	}
+	if v, err := tower.GetInt("counter"); err != nil || v != 30 {
+		t.Errorf("Expected stored 30, got %d (%v)", v, err)
+	}
+
+	// An error from fn discards every change
+	abort := errors.New("abort")
+	err = tower.WithKey("counter", func(kp *KeyPipeline) error {
+		if _, err := kp.AddInt(100); err != nil {
+			return err
+		}
+		return abort
+	})
+	if !errors.Is(err, abort) {
+		t.Errorf("Expected the error of fn, got %v", err)
+	}
+	if v, _ := tower.GetInt("counter"); v != 30 {
+		t.Errorf("Expected counter to stay 30, got %d", v)
+	}
+
+	// Operating on a missing key fails until it is set
+	err = tower.WithKey("fresh", func(kp *KeyPipeline) error {
+		if _, err := kp.AddInt(1); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Expected ErrKeyNotFound, got %v", err)
+		}
+		if err := kp.SetInt(10); err != nil {
+			return err
+		}
+		_, err := kp.AddInt(1)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithKey failed: %v", err)
+	}
+	if v, err := tower.GetInt("fresh"); err != nil || v != 11 {
+		t.Errorf("Expected fresh = 11, got %d (%v)", v, err)
+	}
+
+	if err := tower.SetString("text", "hello"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	err = tower.WithKey("text", func(kp *KeyPipeline) error {
+		_, err := kp.AddInt(1)
+		return err
+	})
+	if err == nil {
+		t.Error("Expected error for AddInt on a string")
+	}
+}
+
+func BenchmarkWithKey(b *testing.B) {
+	tower, err := NewOperator(&Options{
+		Path:         "data",
+		FS:           InMemory(),
+		CacheSize:    size.NewSizeFromMegabytes(64),
+		MemTableSize: size.NewSizeFromMegabytes(16),
+		BytesPerSync: size.NewSizeFromKilobytes(512),
+	})
+	if err != nil {
+		b.Fatalf("Failed to create tower: %v", err)
+	}
+	defer tower.Close()
+
+	key := "bench_counter"
+	if err := tower.SetInt(key, 1); err != nil {
+		b.Fatalf("Failed to set int: %v", err)
+	}
+
+	b.Run("separate calls", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := tower.AddInt(key, 1); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := tower.MulInt(key, 1); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := tower.GetInt(key); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("WithKey", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			err := tower.WithKey(key, func(kp *KeyPipeline) error {
+				if _, err := kp.AddInt(1); err != nil {
+					return err
+				}
+				if _, err := kp.MulInt(1); err != nil {
+					return err
+				}
+				_, err := kp.GetInt()
+				return err
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
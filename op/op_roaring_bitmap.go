@@ -6,8 +6,13 @@ import (
 	"github.com/RoaringBitmap/roaring/v2"
 )
 
-func (op *Operator) SetRoaringBitmap(key string, value *roaring.Bitmap) error {
-	unlock := op.lock(key)
+func (op *Operator) SetRoaringBitmap(key string, value *roaring.Bitmap) (err error) {
+	defer op.traceOperation("SetRoaringBitmap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -22,8 +27,13 @@ func (op *Operator) SetRoaringBitmap(key string, value *roaring.Bitmap) error {
 	return nil
 }
 
-func (op *Operator) GetRoaringBitmap(key string) (*roaring.Bitmap, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetRoaringBitmap(key string) (_ *roaring.Bitmap, err error) {
+	defer op.traceOperation("GetRoaringBitmap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -63,8 +73,13 @@ func (op *Operator) GetRoaringBitmapBytes(key string) ([]byte, error) {
 }
 
 // Basic bit operations
-func (op *Operator) AddBitmapBit(key string, bit uint32) error {
-	unlock := op.lock(key)
+func (op *Operator) AddBitmapBit(key string, bit uint32) (err error) {
+	defer op.traceOperation("AddBitmapBit", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -90,8 +105,13 @@ func (op *Operator) AddBitmapBit(key string, bit uint32) error {
 	return nil
 }
 
-func (op *Operator) DeleteBitmapBit(key string, bit uint32) error {
-	unlock := op.lock(key)
+func (op *Operator) DeleteBitmapBit(key string, bit uint32) (err error) {
+	defer op.traceOperation("DeleteBitmapBit", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -117,8 +137,13 @@ func (op *Operator) DeleteBitmapBit(key string, bit uint32) error {
 	return nil
 }
 
-func (op *Operator) ContainsBitmapBit(key string, bit uint32) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ContainsBitmapBit(key string, bit uint32) (_ bool, err error) {
+	defer op.traceOperation("ContainsBitmapBit", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -135,8 +160,13 @@ func (op *Operator) ContainsBitmapBit(key string, bit uint32) (bool, error) {
 }
 
 // Set operations
-func (op *Operator) UnionBitmap(key string, other *roaring.Bitmap) error {
-	unlock := op.lock(key)
+func (op *Operator) UnionBitmap(key string, other *roaring.Bitmap) (err error) {
+	defer op.traceOperation("UnionBitmap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -162,8 +192,13 @@ func (op *Operator) UnionBitmap(key string, other *roaring.Bitmap) error {
 	return nil
 }
 
-func (op *Operator) IntersectBitmap(key string, other *roaring.Bitmap) error {
-	unlock := op.lock(key)
+func (op *Operator) IntersectBitmap(key string, other *roaring.Bitmap) (err error) {
+	defer op.traceOperation("IntersectBitmap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -189,8 +224,13 @@ func (op *Operator) IntersectBitmap(key string, other *roaring.Bitmap) error {
 	return nil
 }
 
-func (op *Operator) DifferenceBitmap(key string, other *roaring.Bitmap) error {
-	unlock := op.lock(key)
+func (op *Operator) DifferenceBitmap(key string, other *roaring.Bitmap) (err error) {
+	defer op.traceOperation("DifferenceBitmap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -216,9 +256,46 @@ func (op *Operator) DifferenceBitmap(key string, other *roaring.Bitmap) error {
 	return nil
 }
 
+func (op *Operator) SymmetricDifferenceBitmap(key string, other *roaring.Bitmap) (err error) {
+	defer op.traceOperation("SymmetricDifferenceBitmap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	bitmap, err := df.RoaringBitmap()
+	if err != nil {
+		return fmt.Errorf("failed to get roaring bitmap value for key %s: %w", key, err)
+	}
+
+	bitmap.Xor(other)
+
+	if err := df.SetRoaringBitmap(bitmap); err != nil {
+		return fmt.Errorf("failed to set roaring bitmap value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return nil
+}
+
 // Bit operations using variable parameters
-func (op *Operator) AndBits(key string, bits ...uint32) error {
-	unlock := op.lock(key)
+func (op *Operator) AndBits(key string, bits ...uint32) (err error) {
+	defer op.traceOperation("AndBits", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -251,8 +328,13 @@ func (op *Operator) AndBits(key string, bits ...uint32) error {
 	return nil
 }
 
-func (op *Operator) OrBits(key string, bits ...uint32) error {
-	unlock := op.lock(key)
+func (op *Operator) OrBits(key string, bits ...uint32) (err error) {
+	defer op.traceOperation("OrBits", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -281,8 +363,13 @@ func (op *Operator) OrBits(key string, bits ...uint32) error {
 	return nil
 }
 
-func (op *Operator) XorBits(key string, bits ...uint32) error {
-	unlock := op.lock(key)
+func (op *Operator) XorBits(key string, bits ...uint32) (err error) {
+	defer op.traceOperation("XorBits", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -315,9 +402,49 @@ func (op *Operator) XorBits(key string, bits ...uint32) error {
 	return nil
 }
 
+func (op *Operator) AndNotBits(key string, bits ...uint32) (err error) {
+	defer op.traceOperation("AndNotBits", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	bitmap, err := df.RoaringBitmap()
+	if err != nil {
+		return fmt.Errorf("failed to get roaring bitmap value for key %s: %w", key, err)
+	}
+
+	// Remove given bits
+	for _, bit := range bits {
+		bitmap.Remove(bit)
+	}
+
+	if err := df.SetRoaringBitmap(bitmap); err != nil {
+		return fmt.Errorf("failed to set roaring bitmap value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return nil
+}
+
 // Additional utility functions
-func (op *Operator) GetBitmapCardinality(key string) (uint64, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetBitmapCardinality(key string) (_ uint64, err error) {
+	defer op.traceOperation("GetBitmapCardinality", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -333,8 +460,13 @@ func (op *Operator) GetBitmapCardinality(key string) (uint64, error) {
 	return bitmap.GetCardinality(), nil
 }
 
-func (op *Operator) ClearRoaringBitmap(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) ClearRoaringBitmap(key string) (err error) {
+	defer op.traceOperation("ClearRoaringBitmap", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -7,8 +7,13 @@ import (
 )
 
 // RoaringBitmap64 operations
-func (op *Operator) SetRoaringBitmap64(key string, value *roaring64.Bitmap) error {
-	unlock := op.lock(key)
+func (op *Operator) SetRoaringBitmap64(key string, value *roaring64.Bitmap) (err error) {
+	defer op.traceOperation("SetRoaringBitmap64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -23,8 +28,13 @@ func (op *Operator) SetRoaringBitmap64(key string, value *roaring64.Bitmap) erro
 	return nil
 }
 
-func (op *Operator) GetRoaringBitmap64(key string) (*roaring64.Bitmap, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetRoaringBitmap64(key string) (_ *roaring64.Bitmap, err error) {
+	defer op.traceOperation("GetRoaringBitmap64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -64,8 +74,13 @@ func (op *Operator) GetRoaringBitmap64Bytes(key string) ([]byte, error) {
 }
 
 // Basic bit operations (64-bit)
-func (op *Operator) AddBitmap64Bit(key string, bit uint64) error {
-	unlock := op.lock(key)
+func (op *Operator) AddBitmap64Bit(key string, bit uint64) (err error) {
+	defer op.traceOperation("AddBitmap64Bit", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -91,8 +106,13 @@ func (op *Operator) AddBitmap64Bit(key string, bit uint64) error {
 	return nil
 }
 
-func (op *Operator) DeleteBitmap64Bit(key string, bit uint64) error {
-	unlock := op.lock(key)
+func (op *Operator) DeleteBitmap64Bit(key string, bit uint64) (err error) {
+	defer op.traceOperation("DeleteBitmap64Bit", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -118,8 +138,13 @@ func (op *Operator) DeleteBitmap64Bit(key string, bit uint64) error {
 	return nil
 }
 
-func (op *Operator) ContainsBitmap64Bit(key string, bit uint64) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ContainsBitmap64Bit(key string, bit uint64) (_ bool, err error) {
+	defer op.traceOperation("ContainsBitmap64Bit", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -136,8 +161,13 @@ func (op *Operator) ContainsBitmap64Bit(key string, bit uint64) (bool, error) {
 }
 
 // Set operations (64-bit)
-func (op *Operator) UnionBitmap64(key string, other *roaring64.Bitmap) error {
-	unlock := op.lock(key)
+func (op *Operator) UnionBitmap64(key string, other *roaring64.Bitmap) (err error) {
+	defer op.traceOperation("UnionBitmap64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -163,8 +193,13 @@ func (op *Operator) UnionBitmap64(key string, other *roaring64.Bitmap) error {
 	return nil
 }
 
-func (op *Operator) IntersectBitmap64(key string, other *roaring64.Bitmap) error {
-	unlock := op.lock(key)
+func (op *Operator) IntersectBitmap64(key string, other *roaring64.Bitmap) (err error) {
+	defer op.traceOperation("IntersectBitmap64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -190,8 +225,13 @@ func (op *Operator) IntersectBitmap64(key string, other *roaring64.Bitmap) error
 	return nil
 }
 
-func (op *Operator) DifferenceBitmap64(key string, other *roaring64.Bitmap) error {
-	unlock := op.lock(key)
+func (op *Operator) DifferenceBitmap64(key string, other *roaring64.Bitmap) (err error) {
+	defer op.traceOperation("DifferenceBitmap64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -217,8 +257,45 @@ func (op *Operator) DifferenceBitmap64(key string, other *roaring64.Bitmap) erro
 	return nil
 }
 
-func (op *Operator) AndBits64(key string, bits ...uint64) error {
-	unlock := op.lock(key)
+func (op *Operator) SymmetricDifferenceBitmap64(key string, other *roaring64.Bitmap) (err error) {
+	defer op.traceOperation("SymmetricDifferenceBitmap64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	bitmap, err := df.RoaringBitmap64()
+	if err != nil {
+		return fmt.Errorf("failed to get roaring bitmap64 value for key %s: %w", key, err)
+	}
+
+	bitmap.Xor(other)
+
+	if err := df.SetRoaringBitmap64(bitmap); err != nil {
+		return fmt.Errorf("failed to set roaring bitmap64 value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (op *Operator) AndBits64(key string, bits ...uint64) (err error) {
+	defer op.traceOperation("AndBits64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -251,8 +328,13 @@ func (op *Operator) AndBits64(key string, bits ...uint64) error {
 	return nil
 }
 
-func (op *Operator) OrBits64(key string, bits ...uint64) error {
-	unlock := op.lock(key)
+func (op *Operator) OrBits64(key string, bits ...uint64) (err error) {
+	defer op.traceOperation("OrBits64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -281,8 +363,13 @@ func (op *Operator) OrBits64(key string, bits ...uint64) error {
 	return nil
 }
 
-func (op *Operator) XorBits64(key string, bits ...uint64) error {
-	unlock := op.lock(key)
+func (op *Operator) XorBits64(key string, bits ...uint64) (err error) {
+	defer op.traceOperation("XorBits64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -315,8 +402,48 @@ func (op *Operator) XorBits64(key string, bits ...uint64) error {
 	return nil
 }
 
-func (op *Operator) GetBitmap64Cardinality(key string) (uint64, error) {
-	unlock := op.lock(key)
+func (op *Operator) AndNotBits64(key string, bits ...uint64) (err error) {
+	defer op.traceOperation("AndNotBits64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	bitmap, err := df.RoaringBitmap64()
+	if err != nil {
+		return fmt.Errorf("failed to get roaring bitmap64 value for key %s: %w", key, err)
+	}
+
+	// Remove given bits
+	for _, bit := range bits {
+		bitmap.Remove(bit)
+	}
+
+	if err := df.SetRoaringBitmap64(bitmap); err != nil {
+		return fmt.Errorf("failed to set roaring bitmap64 value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (op *Operator) GetBitmap64Cardinality(key string) (_ uint64, err error) {
+	defer op.traceOperation("GetBitmap64Cardinality", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -332,8 +459,13 @@ func (op *Operator) GetBitmap64Cardinality(key string) (uint64, error) {
 	return bitmap.GetCardinality(), nil
 }
 
-func (op *Operator) ClearRoaringBitmap64(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) ClearRoaringBitmap64(key string) (err error) {
+	defer op.traceOperation("ClearRoaringBitmap64", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
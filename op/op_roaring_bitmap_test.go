@@ -318,4 +318,83 @@ func TestRoaringBitmapOperations(t *testing.T) {
 	})
 }
 
+func TestRoaringBitmapSetOperations(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	// a holds [0, 100000) and b holds [50000, 150000)
+	a := roaring.New()
+	a.AddRange(0, 100000)
+	b := roaring.New()
+	b.AddRange(50000, 150000)
+
+	tests := []struct {
+		name        string
+		apply       func(key string) error
+		cardinality uint64
+	}{
+		{"union", func(key string) error { return tower.UnionBitmap(key, b) }, 150000},
+		{"intersect", func(key string) error { return tower.IntersectBitmap(key, b) }, 50000},
+		{"difference", func(key string) error { return tower.DifferenceBitmap(key, b) }, 50000},
+		{"symmetric difference", func(key string) error { return tower.SymmetricDifferenceBitmap(key, b) }, 100000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "bitmap_" + tt.name
+			if err := tower.SetRoaringBitmap(key, a); err != nil {
+				t.Fatalf("Failed to set bitmap: %v", err)
+			}
+			if err := tt.apply(key); err != nil {
+				t.Fatalf("Failed to combine bitmaps: %v", err)
+			}
+
+			cardinality, err := tower.GetBitmapCardinality(key)
+			if err != nil || cardinality != tt.cardinality {
+				t.Errorf("Expected cardinality %d, got %d (%v)", tt.cardinality, cardinality, err)
+			}
+		})
+	}
+
+	t.Run("symmetric difference members", func(t *testing.T) {
+		key := "bitmap_xor_members"
+		if err := tower.SetRoaringBitmap(key, a); err != nil {
+			t.Fatalf("Failed to set bitmap: %v", err)
+		}
+		if err := tower.SymmetricDifferenceBitmap(key, b); err != nil {
+			t.Fatalf("Failed to combine bitmaps: %v", err)
+		}
+
+		for _, bit := range []uint32{0, 49999, 100000, 149999} {
+			if ok, err := tower.ContainsBitmapBit(key, bit); err != nil || !ok {
+				t.Errorf("Expected bit %d to be set, got %v (%v)", bit, ok, err)
+			}
+		}
+		for _, bit := range []uint32{50000, 99999, 150000} {
+			if ok, err := tower.ContainsBitmapBit(key, bit); err != nil || ok {
+				t.Errorf("Expected bit %d to be clear, got %v (%v)", bit, ok, err)
+			}
+		}
+	})
+
+	t.Run("and not bits", func(t *testing.T) {
+		key := "bitmap_and_not"
+		if err := tower.SetRoaringBitmap(key, a); err != nil {
+			t.Fatalf("Failed to set bitmap: %v", err)
+		}
+		if err := tower.AndNotBits(key, 1, 2, 3, 200000); err != nil {
+			t.Fatalf("Failed to remove bits: %v", err)
+		}
+
+		cardinality, err := tower.GetBitmapCardinality(key)
+		if err != nil || cardinality != 99997 {
+			t.Errorf("Expected cardinality 99997, got %d (%v)", cardinality, err)
+		}
+		if ok, _ := tower.ContainsBitmapBit(key, 2); ok {
+			t.Error("Expected bit 2 to be removed")
+		}
+	})
 
+	if err := tower.SymmetricDifferenceBitmap("missing_bitmap", b); err == nil {
+		t.Error("Expected error for missing bitmap")
+	}
+}
@@ -260,8 +260,13 @@ func encryptData(plainText, key []byte, algorithm EncryptionAlgorithm) ([]byte,
 	return encryptedData, nonce, nil
 }
 
-func (op *Operator) UpsertSafeBox(key string, data []byte, encKey []byte, algorithm EncryptionAlgorithm) ([]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) UpsertSafeBox(key string, data []byte, encKey []byte, algorithm EncryptionAlgorithm) (_ []byte, err error) {
+	defer op.traceOperation("UpsertSafeBox", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	encryptedData, nonce, err := encryptData(data, encKey, algorithm)
@@ -284,8 +289,13 @@ func (op *Operator) UpsertSafeBox(key string, data []byte, encKey []byte, algori
 	return payload, nil
 }
 
-func (op *Operator) GetSafeBox(key string) (EncryptionAlgorithm, []byte, []byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetSafeBox(key string) (_ EncryptionAlgorithm, _ []byte, _ []byte, err error) {
+	defer op.traceOperation("GetSafeBox", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, nil, nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -1,13 +1,34 @@
 ﻿package op
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/pebble"
 )
 
+// ErrSetEmpty is returned by operations that need at least one member when
+// the set has none.
+var ErrSetEmpty = errors.New("set is empty")
+
+// errStopRange is returned by a rangeSetMembers or rangePrefix callback to
+// end the range early without failing it.
+var errStopRange = errors.New("stop range")
+
 // Set operations
-func (op *Operator) CreateSet(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) CreateSet(key string) (err error) {
+	defer op.traceOperation("CreateSet", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	// Store Set metadata directly to key
@@ -15,9 +36,49 @@ func (op *Operator) CreateSet(key string) error {
 
 	// Check if already exists
 	if _, err := op.get(setKey); err == nil {
-		return fmt.Errorf("set %s already exists", key)
+		return fmt.Errorf("set %s: %w", key, ErrContainerExists)
+	}
+
+	return op.createSet(key)
+}
+
+// EnsureSet creates an empty set at key unless one already exists. It
+// fails with a type mismatch when key holds something other than a set.
+func (op *Operator) EnsureSet(key string) error {
+	_, err := op.EnsureSetCreated(key)
+	return err
+}
+
+// EnsureSetCreated is EnsureSet that also reports whether the set was
+// created by this call.
+func (op *Operator) EnsureSetCreated(key string) (_ bool, err error) {
+	defer op.traceOperation("EnsureSetCreated", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if df, err := op.get(key); err == nil {
+		if _, err := df.Set(); err != nil {
+			return false, fmt.Errorf("set %s: %w", key, err)
+		}
+		return false, nil
+	}
+
+	if err := op.createSet(key); err != nil {
+		return false, err
 	}
 
+	return true, nil
+}
+
+// createSet stores the metadata of an empty set at key, which the caller
+// holds the lock for.
+func (op *Operator) createSet(key string) error {
+	setKey := key
+
 	// Create new Set data
 	setData := &SetData{
 		Prefix: key,
@@ -36,8 +97,13 @@ func (op *Operator) CreateSet(key string) error {
 	return nil
 }
 
-func (op *Operator) DeleteSet(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) DeleteSet(key string) (err error) {
+	defer op.traceOperation("DeleteSet", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	return op.deleteSet(key)
@@ -59,8 +125,7 @@ func (op *Operator) deleteSet(key string) error {
 
 	// Delete all members
 	if setData.Count > 0 {
-		prefix := string(MakeSetEntryKey(setData.Prefix)) + ":"
-		err = op.rangePrefix(prefix, func(k string, df *DataFrame) error {
+		_, err = op.rangeSetMembers(setData, func(k string, df *DataFrame) error {
 			return op.delete(k)
 		})
 		if err != nil {
@@ -76,19 +141,71 @@ func (op *Operator) deleteSet(key string) error {
 	return nil
 }
 
-func (op *Operator) ExistsSet(key string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ExistsSet(key string) (_ bool, err error) {
+	defer op.traceOperation("ExistsSet", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	setKey := key
-	_, err := op.get(setKey)
+	_, err = op.get(setKey)
 	return err == nil, nil
 }
 
-func (op *Operator) AddSetMember(key string, member PrimitiveData) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) AddSetMember(key string, member PrimitiveData) (_ int64, err error) {
+	defer op.traceOperation("AddSetMember", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return op.addSetMember(key, member, time.Time{})
+}
+
+// AddSetMemberWithTTL adds member so that it drops out of the set on its own
+// at expireAt. Adding an existing member again moves its expiration.
+func (op *Operator) AddSetMemberWithTTL(key string, member PrimitiveData, expireAt time.Time) (_ int64, err error) {
+	defer op.traceOperation("AddSetMemberWithTTL", key)(&err)
+
+	if !expireAt.After(Now()) {
+		return 0, fmt.Errorf("expiration %v is not in the future", expireAt)
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
+	count, err := op.addSetMember(key, member, expireAt)
+	if err != nil {
+		return 0, err
+	}
+
+	// The sweep purges expired members of sets it finds among the candidates
+	if err := op.addCandidatesForExpiration(key, expireAt); err != nil {
+		return 0, fmt.Errorf("failed to add set %s to expiration candidates: %w", key, err)
+	}
+
+	return count, nil
+}
+
+// AddSetMemberEx adds member so that it drops out of the set on its own after
+// ttl.
+func (op *Operator) AddSetMemberEx(key string, member PrimitiveData, ttl time.Duration) (int64, error) {
+	if ttl <= 0 {
+		return 0, fmt.Errorf("ttl must be positive, got %v", ttl)
+	}
+
+	return op.AddSetMemberWithTTL(key, member, Now().Add(ttl))
+}
+
+func (op *Operator) addSetMember(key string, member PrimitiveData, expireAt time.Time) (int64, error) {
 	setKey := key
 
 	// Get Set metadata
@@ -103,59 +220,40 @@ func (op *Operator) AddSetMember(key string, member PrimitiveData) (int64, error
 	}
 
 	// Generate member key
-	memberStr, err := member.String()
+	memberStr, err := setMemberName(member)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get member string: %w", err)
 	}
 	memberKey := string(MakeSetItemKey(key, memberStr))
 
 	// Check if already exists
-	if _, err := op.get(memberKey); err == nil {
+	current, _, _ := op.getSetMember(memberKey, setData, true)
+	exists := current != nil
+	if exists && expireAt.IsZero() {
 		return int64(setData.Count), nil // No count change if already exists
 	}
 
 	// Check member count
-	if setData.Count >= math.MaxUint64-1 {
+	if !exists && setData.Count >= math.MaxUint64-1 {
 		return 0, fmt.Errorf("set has too many members")
 	}
 
 	// Set value to DataFrame
-	memberDf := NULLDataFrame()
-	switch member.Type() {
-	case TypeInt:
-		intVal, _ := member.Int()
-		if err := memberDf.SetInt(intVal); err != nil {
-			return 0, fmt.Errorf("failed to set int value: %w", err)
-		}
-	case TypeFloat:
-		floatVal, _ := member.Float()
-		if err := memberDf.SetFloat(floatVal); err != nil {
-			return 0, fmt.Errorf("failed to set float value: %w", err)
-		}
-	case TypeString:
-		strVal, _ := member.String()
-		if err := memberDf.SetString(strVal); err != nil {
-			return 0, fmt.Errorf("failed to set string value: %w", err)
-		}
-	case TypeBool:
-		boolVal, _ := member.Bool()
-		if err := memberDf.SetBool(boolVal); err != nil {
-			return 0, fmt.Errorf("failed to set bool value: %w", err)
-		}
-	case TypeBinary:
-		binVal, _ := member.Binary()
-		if err := memberDf.SetBinary(binVal); err != nil {
-			return 0, fmt.Errorf("failed to set binary value: %w", err)
-		}
-	default:
-		return 0, fmt.Errorf("unsupported value type")
+	memberDf, err := listItemDataFrame(member)
+	if err != nil {
+		return 0, err
 	}
+	memberDf.SetExpiration(expireAt)
 
 	// Store member
 	if err := op.set(memberKey, memberDf); err != nil {
 		return 0, fmt.Errorf("failed to set set member: %w", err)
 	}
 
+	if exists {
+		return int64(setData.Count), nil // Only the expiration moved
+	}
+
 	// Update metadata
 	setData.Count++
 
@@ -170,8 +268,13 @@ func (op *Operator) AddSetMember(key string, member PrimitiveData) (int64, error
 	return int64(setData.Count), nil
 }
 
-func (op *Operator) DeleteSetMember(key string, member PrimitiveData) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) DeleteSetMember(key string, member PrimitiveData) (_ int64, err error) {
+	defer op.traceOperation("DeleteSetMember", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	setKey := key
@@ -188,14 +291,20 @@ func (op *Operator) DeleteSetMember(key string, member PrimitiveData) (int64, er
 	}
 
 	// Generate member key
-	memberStr, err := member.String()
+	memberStr, err := setMemberName(member)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get member string: %w", err)
 	}
 	memberKey := string(MakeSetItemKey(key, memberStr))
 
 	// Check if exists
-	if _, err := op.get(memberKey); err != nil {
+	current, expired, _ := op.getSetMember(memberKey, setData, false)
+	if current == nil {
+		if expired {
+			if err := op.updateSetData(setKey, df, setData); err != nil {
+				return 0, err
+			}
+		}
 		return int64(setData.Count), nil // No count change if not exists
 	}
 
@@ -218,8 +327,211 @@ func (op *Operator) DeleteSetMember(key string, member PrimitiveData) (int64, er
 	return int64(setData.Count), nil
 }
 
-func (op *Operator) ContainsSetMember(key string, member PrimitiveData) (bool, error) {
-	unlock := op.lock(key)
+// SetRenameMember replaces oldMember with newMember in one batch, carrying
+// over its expiration. When newMember is already in the set it is replaced
+// if overwrite is set and ErrItemExists is returned otherwise. Renaming a
+// member to itself does nothing.
+func (op *Operator) SetRenameMember(key string, oldMember, newMember PrimitiveData, overwrite bool) (err error) {
+	defer op.traceOperation("SetRenameMember", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return fmt.Errorf("set %s does not exist: %w", key, err)
+	}
+
+	setData, err := df.Set()
+	if err != nil {
+		return fmt.Errorf("failed to get set data: %w", err)
+	}
+
+	oldStr, err := setMemberName(oldMember)
+	if err != nil {
+		return fmt.Errorf("failed to get member string: %w", err)
+	}
+	newStr, err := setMemberName(newMember)
+	if err != nil {
+		return fmt.Errorf("failed to get member string: %w", err)
+	}
+	oldKey := string(MakeSetItemKey(key, oldStr))
+	newKey := string(MakeSetItemKey(key, newStr))
+
+	oldDf, expired, err := op.getSetMember(oldKey, setData, false)
+	if oldDf == nil {
+		if expired {
+			if err := op.updateSetData(key, df, setData); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("member does not exist: %w", err)
+	}
+
+	if oldKey == newKey {
+		return nil
+	}
+
+	current, expired, _ := op.getSetMember(newKey, setData, true)
+	exists := current != nil
+	if exists && !overwrite {
+		return fmt.Errorf("member %s of set %s: %w", newStr, key, ErrItemExists)
+	}
+
+	memberDf, err := listItemDataFrame(newMember)
+	if err != nil {
+		return err
+	}
+	memberDf.SetExpiration(oldDf.expiresAt)
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if err := op.setInBatch(batch, newKey, memberDf); err != nil {
+		return fmt.Errorf("failed to set set member: %w", err)
+	}
+	if err := batch.Delete([]byte(oldKey), nil); err != nil {
+		return fmt.Errorf("failed to delete set member: %w", err)
+	}
+
+	if exists || expired {
+		if exists && setData.Count > 0 {
+			setData.Count--
+		}
+
+		if err := df.SetSet(setData); err != nil {
+			return fmt.Errorf("failed to update set metadata: %w", err)
+		}
+
+		if err := op.setInBatch(batch, key, df); err != nil {
+			return fmt.Errorf("failed to update set metadata: %w", err)
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return fmt.Errorf("failed to commit set batch: %w", err)
+	}
+
+	return nil
+}
+
+// SetMove moves member from the set at srcKey to the set at dstKey and
+// reports whether it was found in srcKey. Both sets are locked for the whole
+// move and the change is committed in one batch. The member keeps its type
+// and expiration; when dstKey already holds it, it is only removed from
+// srcKey. A member missing from srcKey leaves both sets untouched.
+func (op *Operator) SetMove(srcKey, dstKey string, member PrimitiveData) (_ bool, err error) {
+	defer op.traceOperation("SetMove", srcKey)(&err)
+
+	unlock, err := op.lockKeys(srcKey, dstKey)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	srcDf, err := op.get(srcKey)
+	if err != nil {
+		return false, fmt.Errorf("set %s does not exist: %w", srcKey, err)
+	}
+
+	srcData, err := srcDf.Set()
+	if err != nil {
+		return false, fmt.Errorf("failed to get set data: %w", err)
+	}
+
+	dstDf, dstData := srcDf, srcData
+	if dstKey != srcKey {
+		dstDf, err = op.get(dstKey)
+		if err != nil {
+			return false, fmt.Errorf("set %s does not exist: %w", dstKey, err)
+		}
+
+		dstData, err = dstDf.Set()
+		if err != nil {
+			return false, fmt.Errorf("failed to get set data: %w", err)
+		}
+	}
+
+	memberStr, err := setMemberName(member)
+	if err != nil {
+		return false, fmt.Errorf("failed to get member string: %w", err)
+	}
+	srcMemberKey := string(MakeSetItemKey(srcKey, memberStr))
+	dstMemberKey := string(MakeSetItemKey(dstKey, memberStr))
+
+	memberDf, expired, err := op.getSetMember(srcMemberKey, srcData, false)
+	if memberDf == nil {
+		if expired {
+			return false, op.updateSetData(srcKey, srcDf, srcData)
+		}
+		if isNotExist(err) || IsDataframeExpiredError(err) != nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get set member: %w", err)
+	}
+
+	if dstKey == srcKey {
+		return true, nil
+	}
+
+	current, expired, _ := op.getSetMember(dstMemberKey, dstData, true)
+	exists := current != nil
+	if !exists && dstData.Count >= math.MaxUint64-1 {
+		return false, fmt.Errorf("set has too many members")
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if err := batch.Delete([]byte(srcMemberKey), nil); err != nil {
+		return false, fmt.Errorf("failed to delete set member: %w", err)
+	}
+
+	srcData.Count--
+
+	if err := srcDf.SetSet(srcData); err != nil {
+		return false, fmt.Errorf("failed to update set metadata: %w", err)
+	}
+
+	if err := op.setInBatch(batch, srcKey, srcDf); err != nil {
+		return false, fmt.Errorf("failed to update set metadata: %w", err)
+	}
+
+	if !exists {
+		if err := op.setInBatch(batch, dstMemberKey, memberDf); err != nil {
+			return false, fmt.Errorf("failed to set set member: %w", err)
+		}
+
+		dstData.Count++
+	}
+
+	if !exists || expired {
+		if err := dstDf.SetSet(dstData); err != nil {
+			return false, fmt.Errorf("failed to update set metadata: %w", err)
+		}
+
+		if err := op.setInBatch(batch, dstKey, dstDf); err != nil {
+			return false, fmt.Errorf("failed to update set metadata: %w", err)
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return false, fmt.Errorf("failed to commit set batch: %w", err)
+	}
+
+	return true, nil
+}
+
+func (op *Operator) ContainsSetMember(key string, member PrimitiveData) (_ bool, err error) {
+	defer op.traceOperation("ContainsSetMember", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	setKey := key
@@ -230,25 +542,36 @@ func (op *Operator) ContainsSetMember(key string, member PrimitiveData) (bool, e
 		return false, fmt.Errorf("set %s does not exist: %w", key, err)
 	}
 
-	_, err = df.Set()
+	setData, err := df.Set()
 	if err != nil {
 		return false, fmt.Errorf("failed to get set data: %w", err)
 	}
 
 	// Generate member key
-	memberStr, err := member.String()
+	memberStr, err := setMemberName(member)
 	if err != nil {
 		return false, fmt.Errorf("failed to get member string: %w", err)
 	}
 	memberKey := string(MakeSetItemKey(key, memberStr))
 
 	// Check if exists
-	_, err = op.get(memberKey)
-	return err == nil, nil
+	current, expired, _ := op.getSetMember(memberKey, setData, false)
+	if expired {
+		if err := op.updateSetData(setKey, df, setData); err != nil {
+			return false, err
+		}
+	}
+
+	return current != nil, nil
 }
 
-func (op *Operator) GetSetMembers(key string) ([]PrimitiveData, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetSetMembers(key string) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("GetSetMembers", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	setKey := key
@@ -270,26 +593,9 @@ func (op *Operator) GetSetMembers(key string) ([]PrimitiveData, error) {
 
 	// Collect all members
 	result := make([]PrimitiveData, 0, setData.Count)
-	prefix := string(MakeSetEntryKey(setData.Prefix)) + ":"
-	err = op.rangePrefix(prefix, func(k string, df *DataFrame) error {
-		var value PrimitiveData
-		switch df.Type() {
-		case TypeInt:
-			intVal, _ := df.Int()
-			value = PrimitiveInt(intVal)
-		case TypeFloat:
-			floatVal, _ := df.Float()
-			value = PrimitiveFloat(floatVal)
-		case TypeString:
-			strVal, _ := df.String()
-			value = PrimitiveString(strVal)
-		case TypeBool:
-			boolVal, _ := df.Bool()
-			value = PrimitiveBool(boolVal)
-		case TypeBinary:
-			binVal, _ := df.Binary()
-			value = PrimitiveBinary(binVal)
-		default:
+	removed, err := op.rangeSetMembers(setData, func(k string, df *DataFrame) error {
+		value, err := listItemValue(df)
+		if err != nil {
 			return nil // skip unsupported types
 		}
 		result = append(result, value)
@@ -299,11 +605,22 @@ func (op *Operator) GetSetMembers(key string) ([]PrimitiveData, error) {
 		return nil, fmt.Errorf("failed to range set members: %w", err)
 	}
 
+	if removed > 0 {
+		if err := op.updateSetData(setKey, df, setData); err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
 
-func (op *Operator) GetSetMembersFiltered(key string, filter func(string, PrimitiveData) bool) ([]PrimitiveData, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetSetMembersFiltered(key string, filter func(string, PrimitiveData) bool) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("GetSetMembersFiltered", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	setKey := key
@@ -325,26 +642,9 @@ func (op *Operator) GetSetMembersFiltered(key string, filter func(string, Primit
 
 	// Collect all members
 	result := make([]PrimitiveData, 0, setData.Count)
-	prefix := string(MakeSetEntryKey(setData.Prefix)) + ":"
-	err = op.rangePrefix(prefix, func(k string, df *DataFrame) error {
-		var value PrimitiveData
-		switch df.Type() {
-		case TypeInt:
-			intVal, _ := df.Int()
-			value = PrimitiveInt(intVal)
-		case TypeFloat:
-			floatVal, _ := df.Float()
-			value = PrimitiveFloat(floatVal)
-		case TypeString:
-			strVal, _ := df.String()
-			value = PrimitiveString(strVal)
-		case TypeBool:
-			boolVal, _ := df.Bool()
-			value = PrimitiveBool(boolVal)
-		case TypeBinary:
-			binVal, _ := df.Binary()
-			value = PrimitiveBinary(binVal)
-		default:
+	removed, err := op.rangeSetMembers(setData, func(k string, df *DataFrame) error {
+		value, err := listItemValue(df)
+		if err != nil {
 			return nil // skip unsupported types
 		}
 		if filter(k, value) {
@@ -356,11 +656,141 @@ func (op *Operator) GetSetMembersFiltered(key string, filter func(string, Primit
 		return nil, fmt.Errorf("failed to range set members: %w", err)
 	}
 
+	if removed > 0 {
+		if err := op.updateSetData(setKey, df, setData); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// ForEachSetMember calls fn for every member until fn returns false. Members
+// are decoded one at a time, so unlike GetSetMembersFiltered a caller that
+// only needs the first match doesn't pay for the rest. The key lock is held
+// throughout, so fn must not operate on the same set.
+func (op *Operator) ForEachSetMember(key string, fn func(member PrimitiveData) bool) (err error) {
+	defer op.traceOperation("ForEachSetMember", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	setKey := key
+
+	// Get Set metadata
+	df, err := op.get(setKey)
+	if err != nil {
+		return fmt.Errorf("set %s does not exist: %w", key, err)
+	}
+
+	setData, err := df.Set()
+	if err != nil {
+		return fmt.Errorf("failed to get set data: %w", err)
+	}
+
+	if setData.Count == 0 {
+		return nil
+	}
+
+	removed, err := op.rangeSetMembers(setData, func(k string, df *DataFrame) error {
+		value, err := dataFramePrimitive(df)
+		if err != nil {
+			return nil // skip unsupported types
+		}
+		if !fn(value) {
+			return errStopRange
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to range set members: %w", err)
+	}
+
+	if removed > 0 {
+		if err := op.updateSetData(setKey, df, setData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SortOrder is the direction of a sorted read.
+type SortOrder uint8
+
+const (
+	SortAscending SortOrder = iota
+	SortDescending
+)
+
+// GetSetMembersSorted returns the members of the set sorted by value when
+// they all share a numeric type, so ints 2, 10, 1 come back as 1, 2, 10
+// rather than in the byte order of their text form, which is how members are
+// stored. A set mixing types, or holding non-numeric members, falls back to
+// that byte order, the same order GetSetMembers returns. Members of
+// unsupported types are skipped, as in GetSetMembers.
+func (op *Operator) GetSetMembersSorted(key string, order SortOrder) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("GetSetMembersSorted", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	_, _, members, err := op.liveSetMembers(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Members come in byte order of their text form
+	result := make([]PrimitiveData, 0, len(members))
+	for _, m := range members {
+		value, err := dataFramePrimitive(m.df)
+		if err != nil {
+			continue // skip unsupported types
+		}
+		result = append(result, value)
+	}
+
+	numeric := len(result) > 0
+	for _, value := range result {
+		if typ := value.Type(); typ != result[0].Type() || (typ != TypeInt && typ != TypeFloat) {
+			numeric = false
+			break
+		}
+	}
+
+	if numeric {
+		sort.SliceStable(result, func(i, j int) bool {
+			if result[0].Type() == TypeInt {
+				a, _ := result[i].Int()
+				b, _ := result[j].Int()
+				return a < b
+			}
+			a, _ := result[i].Float()
+			b, _ := result[j].Float()
+			return a < b
+		})
+	}
+
+	if order == SortDescending {
+		slices.Reverse(result)
+	}
+
 	return result, nil
 }
 
-func (op *Operator) GetSetCardinality(key string) (int64, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetSetCardinality(key string) (_ int64, err error) {
+	defer op.traceOperation("GetSetCardinality", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	setKey := key
@@ -375,11 +805,21 @@ func (op *Operator) GetSetCardinality(key string) (int64, error) {
 		return 0, fmt.Errorf("failed to get set data: %w", err)
 	}
 
+	// Purge expired members so the count covers live ones only
+	if err := op.removeExpiredSetMembers(setKey, df, setData); err != nil {
+		return 0, err
+	}
+
 	return int64(setData.Count), nil
 }
 
-func (op *Operator) ClearSet(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) ClearSet(key string) (err error) {
+	defer op.traceOperation("ClearSet", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	setKey := key
@@ -397,8 +837,7 @@ func (op *Operator) ClearSet(key string) error {
 
 	// Delete all members
 	if setData.Count > 0 {
-		prefix := string(MakeSetEntryKey(setData.Prefix)) + ":"
-		err = op.rangePrefix(prefix, func(k string, df *DataFrame) error {
+		_, err = op.rangeSetMembers(setData, func(k string, df *DataFrame) error {
 			return op.delete(k)
 		})
 		if err != nil {
@@ -418,3 +857,546 @@ func (op *Operator) ClearSet(key string) error {
 
 	return nil
 }
+
+// SetPop removes and returns a member chosen uniformly at random from the
+// live members of the set. The choice is not repeatable between calls, so
+// concurrent consumers each receive a different member. It returns
+// ErrSetEmpty when the set has no members.
+func (op *Operator) SetPop(key string) (_ PrimitiveData, err error) {
+	defer op.traceOperation("SetPop", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	setKey := key
+
+	df, setData, members, err := op.liveSetMembers(setKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, ErrSetEmpty
+	}
+
+	chosen := members[rand.IntN(len(members))]
+	value, err := listItemValue(chosen.df)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delete member and update metadata together
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if err := batch.Delete([]byte(chosen.key), nil); err != nil {
+		return nil, fmt.Errorf("failed to delete set member: %w", err)
+	}
+
+	setData.Count--
+
+	if err := df.SetSet(setData); err != nil {
+		return nil, fmt.Errorf("failed to update set metadata: %w", err)
+	}
+
+	if err := op.setInBatch(batch, setKey, df); err != nil {
+		return nil, fmt.Errorf("failed to update set metadata: %w", err)
+	}
+
+	if err := op.commit(batch); err != nil {
+		return nil, fmt.Errorf("failed to commit set batch: %w", err)
+	}
+
+	return value, nil
+}
+
+// SetRandomMember returns members chosen at random without removing them.
+// A positive count returns up to count distinct members, all of them when
+// count exceeds the cardinality. A negative count returns exactly -count
+// members, each drawn independently so the same member may repeat. The
+// choice is not repeatable between calls. It returns ErrSetEmpty when the
+// set has no members.
+func (op *Operator) SetRandomMember(key string, count int) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("SetRandomMember", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	_, _, members, err := op.liveSetMembers(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, ErrSetEmpty
+	}
+
+	var chosen []setMember
+	if count >= 0 {
+		// Partial Fisher-Yates shuffle picks distinct members
+		n := min(count, len(members))
+		for i := 0; i < n; i++ {
+			j := i + rand.IntN(len(members)-i)
+			members[i], members[j] = members[j], members[i]
+		}
+		chosen = members[:n]
+	} else {
+		chosen = make([]setMember, -count)
+		for i := range chosen {
+			chosen[i] = members[rand.IntN(len(members))]
+		}
+	}
+
+	result := make([]PrimitiveData, 0, len(chosen))
+	for _, member := range chosen {
+		value, err := listItemValue(member.df)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+// setMemberName returns the text that identifies member within a set.
+// Members are keyed by their text form, so PrimitiveInt(1) and
+// PrimitiveString("1") are the same member, and the set keeps the type it
+// was first added with.
+func setMemberName(member PrimitiveData) (string, error) {
+	switch member.Type() {
+	case TypeInt:
+		v, _ := member.Int()
+		return strconv.FormatInt(v, 10), nil
+	case TypeFloat:
+		v, _ := member.Float()
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case TypeBool:
+		v, _ := member.Bool()
+		return strconv.FormatBool(v), nil
+	case TypeBinary:
+		v, _ := member.Binary()
+		return string(v), nil
+	case TypeTimestamp:
+		v, _ := member.Timestamp()
+		return strconv.FormatInt(v, 10), nil
+	case TypeTime:
+		// Times are stored as timestamps, so they share their names
+		v, _ := member.Time()
+		return strconv.FormatInt(v.UnixNano(), 10), nil
+	case TypeDuration:
+		v, _ := member.Duration()
+		return v.String(), nil
+	case TypeUUID:
+		v, _ := member.UUID()
+		return v.String(), nil
+	case TypeDecimal:
+		coefficient, scale, err := member.Decimal()
+		if err != nil {
+			return "", err
+		}
+		return formatDecimal(coefficient, scale), nil
+	case TypeBigInt:
+		v, err := member.BigInt()
+		if err != nil {
+			return "", err
+		}
+		return v.String(), nil
+	default:
+		return member.String()
+	}
+}
+
+type setMember struct {
+	key string
+	df  *DataFrame
+}
+
+// liveSetMembers loads the live members of the set at key, persisting the
+// count when expired members were purged along the way.
+func (op *Operator) liveSetMembers(key string) (*DataFrame, *SetData, []setMember, error) {
+	df, err := op.get(key)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("set %s does not exist: %w", key, err)
+	}
+
+	setData, err := df.Set()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get set data: %w", err)
+	}
+
+	members := make([]setMember, 0, setData.Count)
+	removed, err := op.rangeSetMembers(setData, func(k string, memberDf *DataFrame) error {
+		members = append(members, setMember{key: k, df: memberDf})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to range set members: %w", err)
+	}
+
+	if removed > 0 {
+		if err := op.updateSetData(key, df, setData); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return df, setData, members, nil
+}
+
+// getSetMember returns the live member stored at memberKey, or nil and the
+// error reading it. A member found expired is taken off setData.Count, with
+// expired set so the caller can persist the metadata, once it leaves the
+// store: get deletes it under lazy expiry, and callers pass replacing when
+// they overwrite or delete it themselves. Otherwise it stays counted until
+// the TTL sweep removes it.
+func (op *Operator) getSetMember(memberKey string, setData *SetData, replacing bool) (member *DataFrame, expired bool, err error) {
+	member, err = op.get(memberKey)
+	if err == nil {
+		return member, false, nil
+	}
+
+	if IsDataframeExpiredError(err) != nil && (op.lazyExpire || replacing) {
+		if setData.Count > 0 {
+			setData.Count--
+		}
+		return nil, true, err
+	}
+
+	return nil, false, err
+}
+
+// rangeSetMembers calls fn for every live member of the set. Expired members
+// are deleted and taken off setData.Count; the number removed is returned so
+// the caller can persist the metadata.
+func (op *Operator) rangeSetMembers(setData *SetData, fn func(key string, df *DataFrame) error) (int, error) {
+	if err := op.ctxErr(); err != nil {
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
+	}
+
+	entryKey := string(MakeSetEntryKey(setData.Prefix))
+	iter, err := op.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(entryKey + ":"),
+		UpperBound: []byte(entryKey + ";"),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
+
+	var expired []string
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := op.ctxErr(); err != nil {
+			return 0, fmt.Errorf("iterator error: %w", err)
+		}
+
+		key := string(iter.Key())
+		df, err := op.unmarshal(key, iter.Value())
+		if err != nil {
+			if IsDataframeExpiredError(err) != nil {
+				expired = append(expired, key)
+				continue
+			}
+			return 0, fmt.Errorf("failed to unmarshal dataframe for key %s: %w", key, err)
+		}
+		if fn == nil {
+			continue
+		}
+		if err := fn(key, df); err != nil {
+			if errors.Is(err, errStopRange) {
+				break
+			}
+			return 0, fmt.Errorf("callback error for key %s: %w", key, err)
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("iterator error: %w", err)
+	}
+
+	for _, key := range expired {
+		if err := op.delete(key); err != nil {
+			return 0, fmt.Errorf("failed to delete expired set member: %w", err)
+		}
+		if setData.Count > 0 {
+			setData.Count--
+		}
+	}
+
+	return len(expired), nil
+}
+
+// removeExpiredSetMembers deletes the expired members of the set stored at
+// key and saves the adjusted count.
+func (op *Operator) removeExpiredSetMembers(key string, df *DataFrame, setData *SetData) error {
+	if setData.Count == 0 {
+		return nil
+	}
+
+	removed, err := op.rangeSetMembers(setData, nil)
+	if err != nil {
+		return fmt.Errorf("failed to range set members: %w", err)
+	}
+	if removed == 0 {
+		return nil
+	}
+
+	return op.updateSetData(key, df, setData)
+}
+
+func (op *Operator) updateSetData(key string, df *DataFrame, setData *SetData) error {
+	if err := df.SetSet(setData); err != nil {
+		return fmt.Errorf("failed to update set metadata: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to update set metadata: %w", err)
+	}
+
+	return nil
+}
+
+// Set algebra operations. Source keys that do not exist read as empty sets,
+// while a source holding another type is an error. Results are snapshots:
+// member TTLs are not carried over.
+type setAlgebra int
+
+const (
+	setAlgebraUnion setAlgebra = iota
+	setAlgebraIntersection
+	setAlgebraDifference
+)
+
+// SetUnion stores the members found in any of keys at destKey, replacing
+// its contents or creating it, and returns the resulting cardinality.
+func (op *Operator) SetUnion(destKey string, keys ...string) (_ int64, err error) {
+	defer op.traceOperation("SetUnion", destKey)(&err)
+
+	return op.storeSetAlgebra(setAlgebraUnion, destKey, keys)
+}
+
+// SetIntersection stores the members found in every one of keys at destKey,
+// replacing its contents or creating it, and returns the resulting
+// cardinality.
+func (op *Operator) SetIntersection(destKey string, keys ...string) (_ int64, err error) {
+	defer op.traceOperation("SetIntersection", destKey)(&err)
+
+	return op.storeSetAlgebra(setAlgebraIntersection, destKey, keys)
+}
+
+// SetDifference stores the members of the first key found in none of the
+// others at destKey, replacing its contents or creating it, and returns the
+// resulting cardinality.
+func (op *Operator) SetDifference(destKey string, keys ...string) (_ int64, err error) {
+	defer op.traceOperation("SetDifference", destKey)(&err)
+
+	return op.storeSetAlgebra(setAlgebraDifference, destKey, keys)
+}
+
+// SetUnionMembers returns the members found in any of keys.
+func (op *Operator) SetUnionMembers(keys ...string) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("SetUnionMembers", "")(&err)
+
+	return op.setAlgebraMembers(setAlgebraUnion, keys)
+}
+
+// SetIntersectionMembers returns the members found in every one of keys.
+func (op *Operator) SetIntersectionMembers(keys ...string) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("SetIntersectionMembers", "")(&err)
+
+	return op.setAlgebraMembers(setAlgebraIntersection, keys)
+}
+
+// SetDifferenceMembers returns the members of the first key found in none of
+// the others.
+func (op *Operator) SetDifferenceMembers(keys ...string) (_ []PrimitiveData, err error) {
+	defer op.traceOperation("SetDifferenceMembers", "")(&err)
+
+	return op.setAlgebraMembers(setAlgebraDifference, keys)
+}
+
+func (op *Operator) storeSetAlgebra(kind setAlgebra, destKey string, keys []string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, fmt.Errorf("no source sets given")
+	}
+
+	unlock, err := op.lockKeys(append([]string{destKey}, keys...)...)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	members, err := op.computeSetAlgebra(kind, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := op.storeSet(destKey, members); err != nil {
+		return 0, err
+	}
+
+	return int64(len(members)), nil
+}
+
+func (op *Operator) setAlgebraMembers(kind setAlgebra, keys []string) ([]PrimitiveData, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no source sets given")
+	}
+
+	unlock, err := op.lockKeys(keys...)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	members, err := op.computeSetAlgebra(kind, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]PrimitiveData, 0, len(members))
+	for _, name := range names {
+		value, err := listItemValue(members[name])
+		if err != nil {
+			continue // skip unsupported types
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+// computeSetAlgebra combines the members of keys. The caller must hold the
+// locks of all keys.
+func (op *Operator) computeSetAlgebra(kind setAlgebra, keys []string) (map[string]*DataFrame, error) {
+	result, err := op.setMembers(keys[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys[1:] {
+		if len(result) == 0 && kind != setAlgebraUnion {
+			break // Nothing left to intersect or subtract from
+		}
+
+		members, err := op.setMembers(key)
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case setAlgebraUnion:
+			for name, df := range members {
+				result[name] = df
+			}
+		case setAlgebraIntersection:
+			for name := range result {
+				if _, ok := members[name]; !ok {
+					delete(result, name)
+				}
+			}
+		case setAlgebraDifference:
+			for name := range members {
+				delete(result, name)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// setMembers returns the live members of the set at key by their string
+// form. A missing key reads as an empty set. The caller must hold the lock.
+func (op *Operator) setMembers(key string) (map[string]*DataFrame, error) {
+	df, err := op.get(key)
+	if err != nil {
+		if isNotExist(err) {
+			return map[string]*DataFrame{}, nil
+		}
+		return nil, fmt.Errorf("failed to get set %s: %w", key, err)
+	}
+
+	setData, err := df.Set()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get set data of %s: %w", key, err)
+	}
+
+	entryKey := string(MakeSetEntryKey(setData.Prefix))
+	members := make(map[string]*DataFrame, setData.Count)
+	removed, err := op.rangeSetMembers(setData, func(k string, memberDf *DataFrame) error {
+		memberDf.SetExpiration(time.Time{})
+		members[k[len(entryKey)+1:]] = memberDf
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to range set members of %s: %w", key, err)
+	}
+
+	if removed > 0 {
+		if err := op.updateSetData(key, df, setData); err != nil {
+			return nil, err
+		}
+	}
+
+	return members, nil
+}
+
+// storeSet replaces the contents of the set at key with members in one batch,
+// creating the set if it does not exist. The caller must hold the lock.
+func (op *Operator) storeSet(key string, members map[string]*DataFrame) error {
+	setData := &SetData{Prefix: key}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	df, err := op.get(key)
+	switch {
+	case err == nil:
+		existing, err := df.Set()
+		if err != nil {
+			return fmt.Errorf("failed to get set data of %s: %w", key, err)
+		}
+		setData.Prefix = existing.Prefix
+
+		entryKey := string(MakeSetEntryKey(existing.Prefix))
+		if err := batch.DeleteRange([]byte(entryKey+":"), []byte(entryKey+";"), nil); err != nil {
+			return fmt.Errorf("failed to clear set members: %w", err)
+		}
+	case isNotExist(err):
+	default:
+		return fmt.Errorf("failed to get set %s: %w", key, err)
+	}
+
+	for name, memberDf := range members {
+		memberKey := string(MakeSetItemKey(setData.Prefix, name))
+		if err := op.setInBatch(batch, memberKey, memberDf); err != nil {
+			return fmt.Errorf("failed to set set member: %w", err)
+		}
+	}
+
+	setData.Count = uint64(len(members))
+
+	df = NULLDataFrame()
+	if err := df.SetSet(setData); err != nil {
+		return fmt.Errorf("failed to create set data: %w", err)
+	}
+
+	if err := op.setInBatch(batch, key, df); err != nil {
+		return fmt.Errorf("failed to set set metadata: %w", err)
+	}
+
+	if err := op.commit(batch); err != nil {
+		return fmt.Errorf("failed to commit set batch: %w", err)
+	}
+
+	return nil
+}
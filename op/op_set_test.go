@@ -1,9 +1,16 @@
 ﻿package op
 
 import (
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/rivulet-io/tower/util/size"
 )
 
 func TestSetBasicOperations(t *testing.T) {
@@ -678,3 +685,774 @@ func TestSetMembersFilteredErrorCases(t *testing.T) {
 		t.Fatalf("Expected no error with empty set and safe filter: %v", err)
 	}
 }
+
+func TestSetMemberTTL(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "online_users"
+	if err := tower.CreateSet(key); err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+
+	if _, err := tower.AddSetMemberWithTTL(key, PrimitiveString("alice"), time.Now().Add(1*time.Second)); err != nil {
+		t.Fatalf("Failed to add member with TTL: %v", err)
+	}
+	if _, err := tower.AddSetMemberWithTTL(key, PrimitiveString("carol"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to add member with TTL: %v", err)
+	}
+	if _, err := tower.AddSetMemberEx(key, PrimitiveString("erin"), time.Second); err != nil {
+		t.Fatalf("Failed to add member with TTL: %v", err)
+	}
+	count, err := tower.AddSetMember(key, PrimitiveString("bob"))
+	if err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Expected 4 members, got %d", count)
+	}
+
+	// Re-adding a member only moves its expiration
+	count, err = tower.AddSetMemberWithTTL(key, PrimitiveString("carol"), time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to refresh member TTL: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Expected refresh to keep 4 members, got %d", count)
+	}
+
+	if _, err := tower.AddSetMemberWithTTL(key, PrimitiveString("dave"), time.Now().Add(-time.Second)); err == nil {
+		t.Error("Expected error for expiration in the past")
+	}
+	if _, err := tower.AddSetMemberEx(key, PrimitiveString("dave"), 0); err == nil {
+		t.Error("Expected error for a non-positive TTL")
+	}
+
+	// Wait for alice and erin to expire
+	time.Sleep(2 * time.Second)
+
+	members, err := tower.GetSetMembers(key)
+	if err != nil {
+		t.Fatalf("Failed to get members: %v", err)
+	}
+	names := make([]string, 0, len(members))
+	for _, m := range members {
+		name, _ := m.String()
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "bob" || names[1] != "carol" {
+		t.Errorf("Expected [bob carol], got %v", names)
+	}
+
+	count, err = tower.GetSetCardinality(key)
+	if err != nil {
+		t.Fatalf("Failed to get cardinality: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected cardinality 2, got %d", count)
+	}
+
+	contains, err := tower.ContainsSetMember(key, PrimitiveString("alice"))
+	if err != nil {
+		t.Fatalf("Failed to check member: %v", err)
+	}
+	if contains {
+		t.Error("Expected expired member to be gone")
+	}
+
+	count, err = tower.AddSetMemberWithTTL(key, PrimitiveString("alice"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to re-add member: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 members after re-adding, got %d", count)
+	}
+}
+
+func setMemberStrings(t *testing.T, members []PrimitiveData) []string {
+	t.Helper()
+
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		name, err := member.String()
+		if err != nil {
+			t.Fatalf("Expected string member, got %v", member)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSetAlgebra(t *testing.T) {
+	setup := func(t *testing.T) *Operator {
+		tower := createTestTower(t)
+		sets := map[string][]string{
+			"a": {"1", "2", "3", "4"},
+			"b": {"3", "4", "5"},
+			"c": {"4", "6"},
+		}
+		for key, members := range sets {
+			if err := tower.CreateSet(key); err != nil {
+				t.Fatalf("Failed to create set: %v", err)
+			}
+			for _, member := range members {
+				if _, err := tower.AddSetMember(key, PrimitiveString(member)); err != nil {
+					t.Fatalf("Failed to add member: %v", err)
+				}
+			}
+		}
+		if err := tower.CreateSet("empty"); err != nil {
+			t.Fatalf("Failed to create set: %v", err)
+		}
+		return tower
+	}
+
+	t.Run("members", func(t *testing.T) {
+		tower := setup(t)
+		defer tower.Close()
+
+		tests := []struct {
+			name string
+			fn   func(keys ...string) ([]PrimitiveData, error)
+			keys []string
+			want string
+		}{
+			{"union", tower.SetUnionMembers, []string{"a", "b", "c"}, "[1 2 3 4 5 6]"},
+			{"intersection", tower.SetIntersectionMembers, []string{"a", "b", "c"}, "[4]"},
+			{"difference", tower.SetDifferenceMembers, []string{"a", "b", "c"}, "[1 2]"},
+			{"union with empty", tower.SetUnionMembers, []string{"c", "empty"}, "[4 6]"},
+			{"intersection with empty", tower.SetIntersectionMembers, []string{"a", "empty"}, "[]"},
+			{"difference of empty", tower.SetDifferenceMembers, []string{"empty", "a"}, "[]"},
+			{"union with missing", tower.SetUnionMembers, []string{"c", "missing"}, "[4 6]"},
+			{"intersection with missing", tower.SetIntersectionMembers, []string{"a", "missing"}, "[]"},
+			{"difference with missing", tower.SetDifferenceMembers, []string{"c", "missing"}, "[4 6]"},
+		}
+		for _, tt := range tests {
+			members, err := tt.fn(tt.keys...)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			if got := fmt.Sprint(setMemberStrings(t, members)); got != tt.want {
+				t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+			}
+		}
+
+		// Reading must not change the sources
+		count, err := tower.GetSetCardinality("a")
+		if err != nil || count != 4 {
+			t.Errorf("Expected source cardinality 4, got %d (%v)", count, err)
+		}
+		if exists, _ := tower.ExistsSet("missing"); exists {
+			t.Error("Expected missing key to stay absent")
+		}
+	})
+
+	t.Run("store", func(t *testing.T) {
+		tower := setup(t)
+		defer tower.Close()
+
+		count, err := tower.SetUnion("dest", "a", "b")
+		if err != nil || count != 5 {
+			t.Fatalf("Expected union cardinality 5, got %d (%v)", count, err)
+		}
+		members, err := tower.GetSetMembers("dest")
+		if err != nil {
+			t.Fatalf("Failed to get members: %v", err)
+		}
+		if got := fmt.Sprint(setMemberStrings(t, members)); got != "[1 2 3 4 5]" {
+			t.Errorf("Expected [1 2 3 4 5], got %s", got)
+		}
+
+		// An existing destination is replaced
+		count, err = tower.SetIntersection("dest", "b", "c")
+		if err != nil || count != 1 {
+			t.Fatalf("Expected intersection cardinality 1, got %d (%v)", count, err)
+		}
+		members, err = tower.GetSetMembers("dest")
+		if err != nil {
+			t.Fatalf("Failed to get members: %v", err)
+		}
+		if got := fmt.Sprint(setMemberStrings(t, members)); got != "[4]" {
+			t.Errorf("Expected [4], got %s", got)
+		}
+
+		// The destination may be one of the sources
+		count, err = tower.SetDifference("a", "a", "b")
+		if err != nil || count != 2 {
+			t.Fatalf("Expected difference cardinality 2, got %d (%v)", count, err)
+		}
+		if contains, _ := tower.ContainsSetMember("a", PrimitiveString("3")); contains {
+			t.Error("Expected 3 to be removed from a")
+		}
+		if card, _ := tower.GetSetCardinality("a"); card != 2 {
+			t.Errorf("Expected cardinality 2, got %d", card)
+		}
+
+		count, err = tower.SetIntersection("dest", "a", "missing")
+		if err != nil || count != 0 {
+			t.Fatalf("Expected empty intersection, got %d (%v)", count, err)
+		}
+		if card, err := tower.GetSetCardinality("dest"); err != nil || card != 0 {
+			t.Errorf("Expected empty destination set, got %d (%v)", card, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tower := setup(t)
+		defer tower.Close()
+
+		if err := tower.SetString("text", "value"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+
+		if _, err := tower.SetUnionMembers("a", "text"); err == nil {
+			t.Error("Expected error for a source that is not a set")
+		}
+		if _, err := tower.SetUnion("text", "a"); err == nil {
+			t.Error("Expected error for a destination that is not a set")
+		}
+		if s, err := tower.GetString("text"); err != nil || s != "value" {
+			t.Errorf("Expected destination to be left alone, got %q (%v)", s, err)
+		}
+		if _, err := tower.SetUnionMembers(); err == nil {
+			t.Error("Expected error without source keys")
+		}
+	})
+
+	t.Run("concurrent opposing calls", func(t *testing.T) {
+		tower := setup(t)
+		defer tower.Close()
+
+		// Each call locks both keys; opposing orders must not deadlock
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if _, err := tower.SetUnion("a", "a", "b"); err != nil {
+					t.Errorf("Failed to union: %v", err)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if _, err := tower.SetIntersection("b", "b", "a"); err != nil {
+					t.Errorf("Failed to intersect: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("large sets", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		const size = 10000
+		for _, key := range []string{"evens", "threes"} {
+			if err := tower.CreateSet(key); err != nil {
+				t.Fatalf("Failed to create set: %v", err)
+			}
+		}
+		for i := 0; i < size; i++ {
+			if i%2 == 0 {
+				if _, err := tower.AddSetMember("evens", PrimitiveString(fmt.Sprintf("m%d", i))); err != nil {
+					t.Fatalf("Failed to add member: %v", err)
+				}
+			}
+			if i%3 == 0 {
+				if _, err := tower.AddSetMember("threes", PrimitiveString(fmt.Sprintf("m%d", i))); err != nil {
+					t.Fatalf("Failed to add member: %v", err)
+				}
+			}
+		}
+
+		// Multiples of 2 or 3, of 6, and of 2 but not 3 below size
+		count, err := tower.SetUnion("union", "evens", "threes")
+		if err != nil || count != 6667 {
+			t.Errorf("Expected union of 6667, got %d (%v)", count, err)
+		}
+		count, err = tower.SetIntersection("both", "evens", "threes")
+		if err != nil || count != 1667 {
+			t.Errorf("Expected intersection of 1667, got %d (%v)", count, err)
+		}
+		count, err = tower.SetDifference("only", "evens", "threes")
+		if err != nil || count != 3333 {
+			t.Errorf("Expected difference of 3333, got %d (%v)", count, err)
+		}
+
+		card, err := tower.GetSetCardinality("union")
+		if err != nil || card != 6667 {
+			t.Errorf("Expected stored cardinality 6667, got %d (%v)", card, err)
+		}
+		members, err := tower.SetIntersectionMembers("evens", "threes")
+		if err != nil || len(members) != 1667 {
+			t.Errorf("Expected 1667 members, got %d (%v)", len(members), err)
+		}
+	})
+}
+
+func TestSetPop(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "work"
+	if err := tower.CreateSet(key); err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+
+	const size = 50
+	for i := 0; i < size; i++ {
+		if _, err := tower.AddSetMember(key, PrimitiveString(fmt.Sprintf("job%d", i))); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+
+	popped := make(map[string]bool, size)
+	for i := 0; i < size; i++ {
+		member, err := tower.SetPop(key)
+		if err != nil {
+			t.Fatalf("Failed to pop member %d: %v", i, err)
+		}
+		name, _ := member.String()
+		if popped[name] {
+			t.Fatalf("Member %s popped twice", name)
+		}
+		popped[name] = true
+
+		count, err := tower.GetSetCardinality(key)
+		if err != nil || count != int64(size-i-1) {
+			t.Fatalf("Expected cardinality %d, got %d (%v)", size-i-1, count, err)
+		}
+	}
+
+	if len(popped) != size {
+		t.Errorf("Expected %d distinct members, got %d", size, len(popped))
+	}
+
+	if _, err := tower.SetPop(key); !errors.Is(err, ErrSetEmpty) {
+		t.Errorf("Expected ErrSetEmpty, got %v", err)
+	}
+
+	_, err := tower.SetPop("missing")
+	if err == nil || errors.Is(err, ErrSetEmpty) {
+		t.Errorf("Expected does-not-exist error for missing set, got %v", err)
+	}
+}
+
+func TestSetRandomMember(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "pool"
+	if err := tower.CreateSet(key); err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+
+	if _, err := tower.SetRandomMember(key, 1); !errors.Is(err, ErrSetEmpty) {
+		t.Errorf("Expected ErrSetEmpty, got %v", err)
+	}
+	if _, err := tower.SetRandomMember("missing", 1); err == nil || errors.Is(err, ErrSetEmpty) {
+		t.Errorf("Expected does-not-exist error for missing set, got %v", err)
+	}
+
+	for _, member := range []string{"a", "b", "c"} {
+		if _, err := tower.AddSetMember(key, PrimitiveString(member)); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+
+	t.Run("distinct", func(t *testing.T) {
+		members, err := tower.SetRandomMember(key, 2)
+		if err != nil {
+			t.Fatalf("Failed to get random members: %v", err)
+		}
+		names := setMemberStrings(t, members)
+		if len(names) != 2 || names[0] == names[1] {
+			t.Errorf("Expected 2 distinct members, got %v", names)
+		}
+
+		members, err = tower.SetRandomMember(key, 10)
+		if err != nil {
+			t.Fatalf("Failed to get random members: %v", err)
+		}
+		if got := fmt.Sprint(setMemberStrings(t, members)); got != "[a b c]" {
+			t.Errorf("Expected every member once, got %s", got)
+		}
+
+		members, err = tower.SetRandomMember(key, 0)
+		if err != nil || len(members) != 0 {
+			t.Errorf("Expected no members, got %v (%v)", members, err)
+		}
+	})
+
+	t.Run("with duplicates", func(t *testing.T) {
+		members, err := tower.SetRandomMember(key, -30)
+		if err != nil {
+			t.Fatalf("Failed to get random members: %v", err)
+		}
+		if len(members) != 30 {
+			t.Fatalf("Expected 30 members, got %d", len(members))
+		}
+		for _, name := range setMemberStrings(t, members) {
+			if name != "a" && name != "b" && name != "c" {
+				t.Errorf("Unexpected member %s", name)
+			}
+		}
+	})
+
+	count, err := tower.GetSetCardinality(key)
+	if err != nil || count != 3 {
+		t.Errorf("Expected members to stay, got cardinality %d (%v)", count, err)
+	}
+}
+
+// countingCodec is Snappy under a custom ID that counts its decodes, which
+// happen once per encoded value read.
+type countingCodec struct{}
+
+var countingCodecDecodes atomic.Int64
+
+func (countingCodec) ID() byte { return 16 }
+
+func (countingCodec) Encode(src []byte) []byte { return SnappyCodec{}.Encode(src) }
+
+func (countingCodec) Decode(src []byte) ([]byte, error) {
+	countingCodecDecodes.Add(1)
+	return SnappyCodec{}.Decode(src)
+}
+
+func TestForEachSetMember(t *testing.T) {
+	tower, err := NewOperator(&Options{
+		Path:         "test.db",
+		CacheSize:    size.NewSizeFromMegabytes(8),
+		MemTableSize: size.NewSizeFromMegabytes(4),
+		FS:           InMemory(),
+		Codec:        countingCodec{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tower: %v", err)
+	}
+	defer tower.Close()
+
+	key := "members"
+	if err := tower.CreateSet(key); err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+	// Members long enough for the codec, so every decode is counted
+	members := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		member := fmt.Sprintf("member-%02d-%s", i, strings.Repeat("x", 200))
+		members[member] = true
+		if _, err := tower.AddSetMember(key, PrimitiveString(member)); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+
+	countingCodecDecodes.Store(0)
+	calls := 0
+	var first string
+	if err := tower.ForEachSetMember(key, func(member PrimitiveData) bool {
+		calls++
+		first, _ = member.String()
+		return false
+	}); err != nil {
+		t.Fatalf("ForEachSetMember failed: %v", err)
+	}
+	if calls != 1 || !members[first] {
+		t.Errorf("Expected one call with a member, got %d calls with %q", calls, first)
+	}
+	if n := countingCodecDecodes.Load(); n != 1 {
+		t.Errorf("Expected 1 member decode after stopping, got %d", n)
+	}
+
+	seen := map[string]bool{}
+	if err := tower.ForEachSetMember(key, func(member PrimitiveData) bool {
+		s, _ := member.String()
+		seen[s] = true
+		return true
+	}); err != nil {
+		t.Fatalf("ForEachSetMember failed: %v", err)
+	}
+	if len(seen) != len(members) {
+		t.Errorf("Expected %d members, got %d", len(members), len(seen))
+	}
+
+	if err := tower.ForEachSetMember("missing", func(PrimitiveData) bool { return true }); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestGetSetMembersSorted(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.CreateSet("ints"); err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+	for _, v := range []int64{2, 10, 1} {
+		if _, err := tower.AddSetMember("ints", PrimitiveInt(v)); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+
+	members, err := tower.GetSetMembersSorted("ints", SortAscending)
+	if err != nil {
+		t.Fatalf("Failed to get sorted members: %v", err)
+	}
+	var got []int64
+	for _, member := range members {
+		v, _ := member.Int()
+		got = append(got, v)
+	}
+	if fmt.Sprint(got) != "[1 2 10]" {
+		t.Errorf("Expected ascending [1 2 10], got %v", got)
+	}
+
+	members, err = tower.GetSetMembersSorted("ints", SortDescending)
+	if err != nil {
+		t.Fatalf("Failed to get sorted members: %v", err)
+	}
+	got = got[:0]
+	for _, member := range members {
+		v, _ := member.Int()
+		got = append(got, v)
+	}
+	if fmt.Sprint(got) != "[10 2 1]" {
+		t.Errorf("Expected descending [10 2 1], got %v", got)
+	}
+
+	if err := tower.CreateSet("floats"); err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+	for _, v := range []float64{2.5, 10, -0.5} {
+		if _, err := tower.AddSetMember("floats", PrimitiveFloat(v)); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+	members, err = tower.GetSetMembersSorted("floats", SortAscending)
+	if err != nil {
+		t.Fatalf("Failed to get sorted members: %v", err)
+	}
+	var floats []float64
+	for _, member := range members {
+		v, _ := member.Float()
+		floats = append(floats, v)
+	}
+	if fmt.Sprint(floats) != "[-0.5 2.5 10]" {
+		t.Errorf("Expected ascending [-0.5 2.5 10], got %v", floats)
+	}
+
+	// Strings keep their lexicographic order
+	if err := tower.CreateSet("strings"); err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+	for _, v := range []string{"2", "10", "1"} {
+		if _, err := tower.AddSetMember("strings", PrimitiveString(v)); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+	var strs []string
+	members, err = tower.GetSetMembersSorted("strings", SortAscending)
+	if err != nil {
+		t.Fatalf("Failed to get sorted members: %v", err)
+	}
+	for _, member := range members {
+		v, _ := member.String()
+		strs = append(strs, v)
+	}
+	if strings.Join(strs, ",") != "1,10,2" {
+		t.Errorf("Expected lexicographic 1,10,2, got %v", strs)
+	}
+
+	// A mixed set falls back to the same order GetSetMembers uses
+	if _, err := tower.AddSetMember("ints", PrimitiveString("x")); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	members, err = tower.GetSetMembersSorted("ints", SortAscending)
+	if err != nil {
+		t.Fatalf("Failed to get sorted members: %v", err)
+	}
+	plain, err := tower.GetSetMembers("ints")
+	if err != nil {
+		t.Fatalf("Failed to get members: %v", err)
+	}
+	if fmt.Sprint(members) != fmt.Sprint(plain) {
+		t.Errorf("Expected mixed set in byte order %v, got %v", plain, members)
+	}
+
+	if _, err := tower.GetSetMembersSorted("missing", SortAscending); err == nil {
+		t.Error("Expected error for missing set")
+	}
+}
+
+func TestSetRenameMember(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "tags"
+	if err := tower.CreateSet(key); err != nil {
+		t.Fatalf("Failed to create set: %v", err)
+	}
+	if _, err := tower.AddSetMember(key, PrimitiveString("go")); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	if _, err := tower.AddSetMember(key, PrimitiveString("rust")); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	if _, err := tower.AddSetMemberEx(key, PrimitiveString("draft"), time.Hour); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	if err := tower.SetRenameMember(key, PrimitiveString("go"), PrimitiveString("golang"), false); err != nil {
+		t.Fatalf("SetRenameMember failed: %v", err)
+	}
+	if ok, _ := tower.ContainsSetMember(key, PrimitiveString("go")); ok {
+		t.Error("Expected old member to be gone")
+	}
+	if ok, _ := tower.ContainsSetMember(key, PrimitiveString("golang")); !ok {
+		t.Error("Expected new member to be present")
+	}
+
+	// Renaming onto an existing member without overwrite changes nothing
+	err := tower.SetRenameMember(key, PrimitiveString("golang"), PrimitiveString("rust"), false)
+	if !errors.Is(err, ErrItemExists) {
+		t.Errorf("Expected ErrItemExists, got %v", err)
+	}
+	if ok, _ := tower.ContainsSetMember(key, PrimitiveString("golang")); !ok {
+		t.Error("Expected member to survive a failed rename")
+	}
+
+	if err := tower.SetRenameMember(key, PrimitiveString("golang"), PrimitiveString("rust"), true); err != nil {
+		t.Fatalf("SetRenameMember with overwrite failed: %v", err)
+	}
+	if card, err := tower.GetSetCardinality(key); err != nil || card != 2 {
+		t.Errorf("Expected 2 members after overwrite, got %d (%v)", card, err)
+	}
+
+	// The expiration moves with the member
+	if err := tower.SetRenameMember(key, PrimitiveString("draft"), PrimitiveString("wip"), false); err != nil {
+		t.Fatalf("SetRenameMember failed: %v", err)
+	}
+	df, err := tower.get(string(MakeSetItemKey(key, "wip")))
+	if err != nil {
+		t.Fatalf("Failed to get renamed member: %v", err)
+	}
+	if df.expiresAt.IsZero() {
+		t.Error("Expected renamed member to keep its expiration")
+	}
+
+	if err := tower.SetRenameMember(key, PrimitiveString("missing"), PrimitiveString("other"), false); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for a missing member, got %v", err)
+	}
+
+	// An expired member can't be renamed and is taken off the count
+	if _, err := tower.AddSetMemberEx(key, PrimitiveString("stale"), time.Second); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if err := tower.SetRenameMember(key, PrimitiveString("stale"), PrimitiveString("fresh"), false); IsDataframeExpiredError(err) == nil {
+		t.Errorf("Expected an expired member error, got %v", err)
+	}
+	if ok, _ := tower.ContainsSetMember(key, PrimitiveString("fresh")); ok {
+		t.Error("Expected expired member not to be renamed")
+	}
+	df, err = tower.get(key)
+	if err != nil {
+		t.Fatalf("Failed to get set: %v", err)
+	}
+	if setData, _ := df.Set(); setData.Count != 2 {
+		t.Errorf("Expected 2 members counted, got %d", setData.Count)
+	}
+}
+
+func TestSetMove(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	for _, key := range []string{"pending", "done"} {
+		if err := tower.CreateSet(key); err != nil {
+			t.Fatalf("Failed to create set: %v", err)
+		}
+	}
+	for _, job := range []string{"job1", "job2", "job3"} {
+		if _, err := tower.AddSetMember("pending", PrimitiveString(job)); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+	}
+	if _, err := tower.AddSetMember("done", PrimitiveString("job0")); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	cardinality := func(key string) int64 {
+		t.Helper()
+		n, err := tower.GetSetCardinality(key)
+		if err != nil {
+			t.Fatalf("Failed to get cardinality: %v", err)
+		}
+		return n
+	}
+
+	moved, err := tower.SetMove("pending", "done", PrimitiveString("job2"))
+	if err != nil || !moved {
+		t.Fatalf("Expected job2 to be moved, got %v (%v)", moved, err)
+	}
+	if ok, _ := tower.ContainsSetMember("pending", PrimitiveString("job2")); ok {
+		t.Error("Expected job2 to leave the source set")
+	}
+	if ok, _ := tower.ContainsSetMember("done", PrimitiveString("job2")); !ok {
+		t.Error("Expected job2 in the destination set")
+	}
+	if src, dst := cardinality("pending"), cardinality("done"); src != 2 || dst != 2 {
+		t.Errorf("Expected cardinalities 2 and 2, got %d and %d", src, dst)
+	}
+
+	// A missing member modifies neither set
+	moved, err = tower.SetMove("pending", "done", PrimitiveString("job2"))
+	if err != nil || moved {
+		t.Errorf("Expected missing member to report false, got %v (%v)", moved, err)
+	}
+	if src, dst := cardinality("pending"), cardinality("done"); src != 2 || dst != 2 {
+		t.Errorf("Expected cardinalities to stay 2 and 2, got %d and %d", src, dst)
+	}
+
+	// A member already in the destination is only removed from the source
+	if _, err := tower.AddSetMember("done", PrimitiveString("job1")); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	moved, err = tower.SetMove("pending", "done", PrimitiveString("job1"))
+	if err != nil || !moved {
+		t.Errorf("Expected job1 to be moved, got %v (%v)", moved, err)
+	}
+	if src, dst := cardinality("pending"), cardinality("done"); src != 1 || dst != 3 {
+		t.Errorf("Expected cardinalities 1 and 3, got %d and %d", src, dst)
+	}
+
+	if _, err := tower.SetMove("pending", "missing", PrimitiveString("job3")); err == nil {
+		t.Error("Expected error for a missing destination set")
+	}
+	if ok, _ := tower.ContainsSetMember("pending", PrimitiveString("job3")); !ok {
+		t.Error("Expected job3 to stay in the source after a failed move")
+	}
+
+	// An expired member is not moved and is taken off the source count
+	if _, err := tower.AddSetMemberEx("pending", PrimitiveString("job4"), time.Second); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	moved, err = tower.SetMove("pending", "done", PrimitiveString("job4"))
+	if err != nil || moved {
+		t.Errorf("Expected expired member to report false, got %v (%v)", moved, err)
+	}
+	if ok, _ := tower.ContainsSetMember("done", PrimitiveString("job4")); ok {
+		t.Error("Expected expired member to stay out of the destination")
+	}
+	df, err := tower.get("pending")
+	if err != nil {
+		t.Fatalf("Failed to get set: %v", err)
+	}
+	if setData, _ := df.Set(); setData.Count != 1 {
+		t.Errorf("Expected 1 member counted in the source, got %d", setData.Count)
+	}
+}
@@ -1,14 +1,28 @@
 ﻿package op
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/corvus-ch/shamir"
 )
 
+// ErrInvalidShareCount is returned by SplitSecret when the number of shares
+// is outside 2..255.
+var ErrInvalidShareCount = errors.New("share count must be between 2 and 255")
+
+// ErrInvalidThreshold is returned by SplitSecret when the threshold is below
+// 2 or above the number of shares.
+var ErrInvalidThreshold = errors.New("threshold must be between 2 and the share count")
+
 // SetShamirShare stores a set of Shamir secret shares
-func (op *Operator) SetShamirShare(key string, shares map[byte][]byte) error {
-	unlock := op.lock(key)
+func (op *Operator) SetShamirShare(key string, shares map[byte][]byte) (err error) {
+	defer op.traceOperation("SetShamirShare", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -24,8 +38,13 @@ func (op *Operator) SetShamirShare(key string, shares map[byte][]byte) error {
 }
 
 // GetShamirShare retrieves the Shamir secret shares
-func (op *Operator) GetShamirShare(key string) (map[byte][]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetShamirShare(key string) (_ map[byte][]byte, err error) {
+	defer op.traceOperation("GetShamirShare", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -42,8 +61,20 @@ func (op *Operator) GetShamirShare(key string) (map[byte][]byte, error) {
 }
 
 // SplitSecret splits a secret into n shares requiring threshold shares to reconstruct
-func (op *Operator) SplitSecret(key string, secret []byte, n, threshold int) (map[byte][]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) SplitSecret(key string, secret []byte, n, threshold int) (_ map[byte][]byte, err error) {
+	defer op.traceOperation("SplitSecret", key)(&err)
+
+	if n < 2 || n > 255 {
+		return nil, fmt.Errorf("%w: got %d", ErrInvalidShareCount, n)
+	}
+	if threshold < 2 || threshold > n {
+		return nil, fmt.Errorf("%w: got %d of %d", ErrInvalidThreshold, threshold, n)
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	shares, err := shamir.Split(secret, n, threshold)
@@ -64,8 +95,13 @@ func (op *Operator) SplitSecret(key string, secret []byte, n, threshold int) (ma
 }
 
 // CombineShares reconstructs the secret from the stored shares
-func (op *Operator) CombineShares(key string) ([]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) CombineShares(key string) (_ []byte, err error) {
+	defer op.traceOperation("CombineShares", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -101,8 +137,13 @@ func (op *Operator) CombineSharesFrom(shares map[byte][]byte) ([]byte, error) {
 }
 
 // GetShareCount returns the number of shares stored
-func (op *Operator) GetShareCount(key string) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetShareCount(key string) (_ int, err error) {
+	defer op.traceOperation("GetShareCount", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -119,8 +160,13 @@ func (op *Operator) GetShareCount(key string) (int, error) {
 }
 
 // AddShare adds a single share to the existing shares
-func (op *Operator) AddShare(key string, shareID byte, share []byte) error {
-	unlock := op.lock(key)
+func (op *Operator) AddShare(key string, shareID byte, share []byte) (err error) {
+	defer op.traceOperation("AddShare", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -149,8 +195,13 @@ func (op *Operator) AddShare(key string, shareID byte, share []byte) error {
 }
 
 // DeleteShare removes a specific share by ID
-func (op *Operator) DeleteShare(key string, shareID byte) error {
-	unlock := op.lock(key)
+func (op *Operator) DeleteShare(key string, shareID byte) (err error) {
+	defer op.traceOperation("DeleteShare", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -181,8 +232,13 @@ func (op *Operator) DeleteShare(key string, shareID byte) error {
 }
 
 // HasShare checks if a specific share ID exists
-func (op *Operator) HasShare(key string, shareID byte) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) HasShare(key string, shareID byte) (_ bool, err error) {
+	defer op.traceOperation("HasShare", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -200,8 +256,13 @@ func (op *Operator) HasShare(key string, shareID byte) (bool, error) {
 }
 
 // GetShare retrieves a specific share by ID
-func (op *Operator) GetShare(key string, shareID byte) ([]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetShare(key string, shareID byte) (_ []byte, err error) {
+	defer op.traceOperation("GetShare", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -225,8 +286,13 @@ func (op *Operator) GetShare(key string, shareID byte) ([]byte, error) {
 }
 
 // ListShareIDs returns all share IDs
-func (op *Operator) ListShareIDs(key string) ([]byte, error) {
-	unlock := op.lock(key)
+func (op *Operator) ListShareIDs(key string) (_ []byte, err error) {
+	defer op.traceOperation("ListShareIDs", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -2,6 +2,7 @@
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -327,9 +328,63 @@ func TestShamirErrorCases(t *testing.T) {
 		if err == nil {
 			t.Error("Expected error when t > n in secret splitting")
 		}
+
+		tests := []struct {
+			n, threshold int
+			want         error
+		}{
+			{1, 1, ErrInvalidShareCount},
+			{256, 3, ErrInvalidShareCount},
+			{5, 1, ErrInvalidThreshold},
+			{5, 6, ErrInvalidThreshold},
+		}
+		for _, tt := range tests {
+			if _, err := tower.SplitSecret(key, secret, tt.n, tt.threshold); !errors.Is(err, tt.want) {
+				t.Errorf("SplitSecret(%d, %d): expected %v, got %v", tt.n, tt.threshold, tt.want, err)
+			}
+		}
+
+		if _, err := tower.GetShamirShare(key); err == nil {
+			t.Error("Expected no shares to be stored for invalid parameters")
+		}
 	})
 }
 
+func TestShamirThresholdReconstruction(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test:shamir:threshold"
+	secret := []byte("launch codes")
+
+	shares, err := tower.SplitSecret(key, secret, 5, 3)
+	if err != nil {
+		t.Fatalf("Failed to split secret: %v", err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("Expected 5 shares, got %d", len(shares))
+	}
+
+	// Losing any two shares must not matter with a threshold of 3
+	ids, err := tower.ListShareIDs(key)
+	if err != nil {
+		t.Fatalf("Failed to list share IDs: %v", err)
+	}
+	for _, id := range ids[:2] {
+		if err := tower.DeleteShare(key, id); err != nil {
+			t.Fatalf("Failed to delete share %d: %v", id, err)
+		}
+	}
+
+	reconstructed, err := tower.CombineShares(key)
+	if err != nil {
+		t.Fatalf("Failed to combine remaining shares: %v", err)
+	}
+	if !bytes.Equal(reconstructed, secret) {
+		t.Errorf("Expected %q, got %q", secret, reconstructed)
+	}
+}
+
 func TestShamirConcurrentAccess(t *testing.T) {
 	tower := createTestTower(t)
 	defer tower.Close()
@@ -0,0 +1,197 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrSnapshotClosed is returned when a snapshot is read after Close.
+var ErrSnapshotClosed = errors.New("snapshot is closed")
+
+// Snapshot is a read-only, point-in-time view of the store. Writes made
+// after it was taken are not visible through it, so backup tooling can read a
+// stable image while the store keeps serving writes. A snapshot pins the
+// SSTables it references, which keeps compaction from reclaiming their space,
+// so it must be closed as soon as it is no longer needed.
+type Snapshot struct {
+	op     *Operator
+	snap   *storeSnapshot
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Snapshot captures the current state of the store.
+func (op *Operator) Snapshot() (_ *Snapshot, err error) {
+	defer op.traceOperation("Snapshot", "")(&err)
+
+	if err := op.ctxErr(); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	return &Snapshot{op: op, snap: op.db.NewSnapshot()}, nil
+}
+
+// Close releases the snapshot. It is safe to call more than once.
+func (s *Snapshot) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if err := s.snap.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// get reads key as of the snapshot. Expired keys are reported as such but,
+// unlike Operator.get, never deleted.
+func (s *Snapshot) get(key string) (*DataFrame, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrSnapshotClosed
+	}
+	if err := s.op.ctxErr(); err != nil {
+		return nil, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	data, closer, err := s.snap.Get([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key %s: %w", key, keyNotFound(err))
+	}
+	defer closer.Close()
+
+	df, err := s.op.unmarshal(key, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dataframe for key %s: %w", key, err)
+	}
+
+	return df, nil
+}
+
+func (s *Snapshot) GetString(key string) (_ string, err error) {
+	defer s.op.traceOperation("SnapshotGetString", key)(&err)
+
+	df, err := s.get(key)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := df.String()
+	if err != nil {
+		return "", fmt.Errorf("failed to get string value for key %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+func (s *Snapshot) GetInt(key string) (_ int64, err error) {
+	defer s.op.traceOperation("SnapshotGetInt", key)(&err)
+
+	df, err := s.get(key)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := df.Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get int value for key %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+func (s *Snapshot) GetFloat(key string) (_ float64, err error) {
+	defer s.op.traceOperation("SnapshotGetFloat", key)(&err)
+
+	df, err := s.get(key)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := df.Float()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get float value for key %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+func (s *Snapshot) GetBool(key string) (_ bool, err error) {
+	defer s.op.traceOperation("SnapshotGetBool", key)(&err)
+
+	df, err := s.get(key)
+	if err != nil {
+		return false, err
+	}
+
+	value, err := df.Bool()
+	if err != nil {
+		return false, fmt.Errorf("failed to get bool value for key %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+func (s *Snapshot) GetBinary(key string) (_ []byte, err error) {
+	defer s.op.traceOperation("SnapshotGetBinary", key)(&err)
+
+	df, err := s.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := df.Binary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get binary value for key %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// TypeOf returns the type of the value stored at key as of the snapshot.
+func (s *Snapshot) TypeOf(key string) (_ DataType, err error) {
+	defer s.op.traceOperation("SnapshotTypeOf", key)(&err)
+
+	df, err := s.get(key)
+	if err != nil {
+		return TypeNull, err
+	}
+
+	return df.Type(), nil
+}
+
+// Exists reports whether key held a live value when the snapshot was taken.
+func (s *Snapshot) Exists(key string) (_ bool, err error) {
+	defer s.op.traceOperation("SnapshotExists", key)(&err)
+
+	if _, err := s.get(key); err != nil {
+		if isNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ScanKeys is Operator.ScanKeys over the snapshot.
+func (s *Snapshot) ScanKeys(prefix string, fn func(key string, typ DataType) bool) (err error) {
+	defer s.op.traceOperation("SnapshotScanKeys", prefix)(&err)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrSnapshotClosed
+	}
+
+	return s.op.scanKeys(s.snap, prefix, nil, fn)
+}
@@ -0,0 +1,91 @@
+package op
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.SetString("name", "before"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if err := tower.SetInt("count", 1); err != nil {
+		t.Fatalf("Failed to set int: %v", err)
+	}
+	if err := tower.SetBool("gone", true); err != nil {
+		t.Fatalf("Failed to set bool: %v", err)
+	}
+
+	snap, err := tower.Snapshot()
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+	defer snap.Close()
+
+	// Writes after the snapshot must not be visible through it
+	if err := tower.SetString("name", "after"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if _, err := tower.AddInt("count", 10); err != nil {
+		t.Fatalf("Failed to add int: %v", err)
+	}
+	if err := tower.Remove("gone"); err != nil {
+		t.Fatalf("Failed to remove key: %v", err)
+	}
+	if err := tower.SetFloat("added", 1.5); err != nil {
+		t.Fatalf("Failed to set float: %v", err)
+	}
+
+	if v, err := snap.GetString("name"); err != nil || v != "before" {
+		t.Errorf("Expected snapshot to see before, got %q (%v)", v, err)
+	}
+	if v, err := tower.GetString("name"); err != nil || v != "after" {
+		t.Errorf("Expected store to see after, got %q (%v)", v, err)
+	}
+	if v, err := snap.GetInt("count"); err != nil || v != 1 {
+		t.Errorf("Expected snapshot count 1, got %d (%v)", v, err)
+	}
+	if v, err := snap.GetBool("gone"); err != nil || !v {
+		t.Errorf("Expected snapshot to still see removed key, got %v (%v)", v, err)
+	}
+	if exists, err := snap.Exists("added"); err != nil || exists {
+		t.Errorf("Expected key added later to be missing, got %v (%v)", exists, err)
+	}
+	if typ, err := snap.TypeOf("count"); err != nil || typ != TypeInt {
+		t.Errorf("Expected TypeInt, got %v (%v)", typ, err)
+	}
+
+	var keys []string
+	if err := snap.ScanKeys("", func(key string, typ DataType) bool {
+		keys = append(keys, key)
+		return true
+	}); err != nil {
+		t.Fatalf("Failed to scan snapshot: %v", err)
+	}
+	want := []string{"count", "gone", "name"}
+	if len(keys) != len(want) {
+		t.Fatalf("Expected keys %v, got %v", want, keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("Expected keys %v, got %v", want, keys)
+			break
+		}
+	}
+
+	if err := snap.Close(); err != nil {
+		t.Fatalf("Failed to close snapshot: %v", err)
+	}
+	if err := snap.Close(); err != nil {
+		t.Errorf("Expected second Close to succeed, got %v", err)
+	}
+	if _, err := snap.GetString("name"); !errors.Is(err, ErrSnapshotClosed) {
+		t.Errorf("Expected ErrSnapshotClosed, got %v", err)
+	}
+	if err := snap.ScanKeys("", func(string, DataType) bool { return true }); !errors.Is(err, ErrSnapshotClosed) {
+		t.Errorf("Expected ErrSnapshotClosed from ScanKeys, got %v", err)
+	}
+}
@@ -0,0 +1,635 @@
+package op
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// SortedSetMember is a member of a sorted set together with its score.
+type SortedSetMember struct {
+	Member PrimitiveData
+	Score  float64
+}
+
+// Sorted set operations
+func (op *Operator) CreateSortedSet(key string) (err error) {
+	defer op.traceOperation("CreateSortedSet", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Check if already exists
+	if _, err := op.get(key); err == nil {
+		return fmt.Errorf("sorted set %s: %w", key, ErrContainerExists)
+	}
+
+	sortedSetData := &SortedSetData{
+		Prefix: key,
+		Count:  0,
+	}
+
+	df := NULLDataFrame()
+	if err := df.SetSortedSet(sortedSetData); err != nil {
+		return fmt.Errorf("failed to create sorted set data: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to set sorted set metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (op *Operator) DeleteSortedSet(key string) (err error) {
+	defer op.traceOperation("DeleteSortedSet", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return op.deleteSortedSet(key)
+}
+
+func (op *Operator) deleteSortedSet(key string) error {
+	_, sortedSetData, err := op.getSortedSet(key)
+	if err != nil {
+		return err
+	}
+
+	return op.deleteSortedSetItems(key, sortedSetData)
+}
+
+// deleteSortedSetItems deletes the members and the metadata of the sorted set
+// or geo set at key.
+func (op *Operator) deleteSortedSetItems(key string, sortedSetData *SortedSetData) error {
+	// Members and the score index share the entry key, so one range covers both
+	entryKey := string(MakeSortedSetEntryKey(sortedSetData.Prefix))
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if err := batch.DeleteRange([]byte(entryKey+":"), []byte(entryKey+";"), nil); err != nil {
+		return fmt.Errorf("failed to delete sorted set members: %w", err)
+	}
+	if err := batch.Delete([]byte(key), nil); err != nil {
+		return fmt.Errorf("failed to delete sorted set metadata: %w", err)
+	}
+
+	if err := op.commit(batch); err != nil {
+		return fmt.Errorf("failed to commit sorted set batch: %w", err)
+	}
+
+	return nil
+}
+
+func (op *Operator) ExistsSortedSet(key string) (_ bool, err error) {
+	defer op.traceOperation("ExistsSortedSet", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	_, err = op.get(key)
+	return err == nil, nil
+}
+
+// AddSortedSetMember adds member with score and returns the cardinality.
+// Adding an existing member again moves it to the new score.
+func (op *Operator) AddSortedSetMember(key string, member PrimitiveData, score float64) (_ int64, err error) {
+	defer op.traceOperation("AddSortedSetMember", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, sortedSetData, err := op.getSortedSet(key)
+	if err != nil {
+		return 0, err
+	}
+
+	_, count, err := op.setSortedSetMember(key, df, sortedSetData, member, score, false)
+	return count, err
+}
+
+// IncrSortedSetScore adds delta to the score of member and returns the new
+// score. A missing member is added with delta as its score.
+func (op *Operator) IncrSortedSetScore(key string, member PrimitiveData, delta float64) (_ float64, err error) {
+	defer op.traceOperation("IncrSortedSetScore", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, sortedSetData, err := op.getSortedSet(key)
+	if err != nil {
+		return 0, err
+	}
+
+	score, _, err := op.setSortedSetMember(key, df, sortedSetData, member, delta, true)
+	return score, err
+}
+
+// setSortedSetMember stores member at score, or at its current score plus
+// score when incr is set, and returns the stored score and the cardinality.
+// df holds the metadata of a sorted set or geo set.
+func (op *Operator) setSortedSetMember(key string, df *DataFrame, sortedSetData *SortedSetData, member PrimitiveData, score float64, incr bool) (float64, int64, error) {
+	memberID, memberDf, err := sortedSetMemberID(member)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	oldScore, exists, err := op.sortedSetScore(sortedSetData, memberID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if incr && exists {
+		score += oldScore
+	}
+	if math.IsNaN(score) {
+		return 0, 0, fmt.Errorf("score is not a number")
+	}
+
+	if exists && score == oldScore {
+		return score, int64(sortedSetData.Count), nil
+	}
+
+	if !exists && sortedSetData.Count >= math.MaxUint64-1 {
+		return 0, 0, fmt.Errorf("sorted set has too many members")
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	// Drop the old index entry when re-scoring
+	if exists {
+		oldScoreKey := MakeSortedSetScoreKey(sortedSetData.Prefix, oldScore, memberID)
+		if err := batch.Delete(oldScoreKey, nil); err != nil {
+			return 0, 0, fmt.Errorf("failed to delete old score: %w", err)
+		}
+	}
+
+	scoreDf := NULLDataFrame()
+	if err := scoreDf.SetFloat(score); err != nil {
+		return 0, 0, fmt.Errorf("failed to set score value: %w", err)
+	}
+
+	memberKey := string(MakeSortedSetMemberKey(sortedSetData.Prefix, memberID))
+	if err := op.setInBatch(batch, memberKey, scoreDf); err != nil {
+		return 0, 0, fmt.Errorf("failed to set sorted set member: %w", err)
+	}
+
+	scoreKey := string(MakeSortedSetScoreKey(sortedSetData.Prefix, score, memberID))
+	if err := op.setInBatch(batch, scoreKey, memberDf); err != nil {
+		return 0, 0, fmt.Errorf("failed to set sorted set score: %w", err)
+	}
+
+	// Update metadata
+	if !exists {
+		sortedSetData.Count++
+
+		if err := df.setScoredSetData(sortedSetData); err != nil {
+			return 0, 0, fmt.Errorf("failed to update sorted set metadata: %w", err)
+		}
+
+		if err := op.setInBatch(batch, key, df); err != nil {
+			return 0, 0, fmt.Errorf("failed to update sorted set metadata: %w", err)
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit sorted set batch: %w", err)
+	}
+
+	return score, int64(sortedSetData.Count), nil
+}
+
+// DeleteSortedSetMember removes member and returns the cardinality.
+func (op *Operator) DeleteSortedSetMember(key string, member PrimitiveData) (_ int64, err error) {
+	defer op.traceOperation("DeleteSortedSetMember", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, sortedSetData, err := op.getSortedSet(key)
+	if err != nil {
+		return 0, err
+	}
+
+	memberID, _, err := sortedSetMemberID(member)
+	if err != nil {
+		return 0, err
+	}
+
+	score, exists, err := op.sortedSetScore(sortedSetData, memberID)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return int64(sortedSetData.Count), nil // No count change if not exists
+	}
+
+	return op.removeSortedSetMembers(key, df, sortedSetData, [][]byte{
+		MakeSortedSetScoreKey(sortedSetData.Prefix, score, memberID),
+	})
+}
+
+func (op *Operator) GetSortedSetScore(key string, member PrimitiveData) (_ float64, err error) {
+	defer op.traceOperation("GetSortedSetScore", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	_, sortedSetData, err := op.getSortedSet(key)
+	if err != nil {
+		return 0, err
+	}
+
+	memberID, _, err := sortedSetMemberID(member)
+	if err != nil {
+		return 0, err
+	}
+
+	memberKey := string(MakeSortedSetMemberKey(sortedSetData.Prefix, memberID))
+	scoreDf, err := op.get(memberKey)
+	if err != nil {
+		return 0, fmt.Errorf("member does not exist: %w", err)
+	}
+
+	return scoreDf.Float()
+}
+
+func (op *Operator) GetSortedSetCardinality(key string) (_ int64, err error) {
+	defer op.traceOperation("GetSortedSetCardinality", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	_, sortedSetData, err := op.getSortedSet(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(sortedSetData.Count), nil
+}
+
+// GetSortedSetRangeByRank returns the members ranked start to end inclusive,
+// lowest score first. Negative ranks count from the end like list ranges, so
+// -1 is the highest scored member.
+func (op *Operator) GetSortedSetRangeByRank(key string, start, end int64) (_ []SortedSetMember, err error) {
+	defer op.traceOperation("GetSortedSetRangeByRank", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	_, sortedSetData, err := op.getSortedSet(key)
+	if err != nil {
+		return nil, err
+	}
+
+	actualStart, actualEnd, ok := sortedSetRankRange(sortedSetData.Count, start, end)
+	if !ok {
+		return []SortedSetMember{}, nil
+	}
+
+	result := make([]SortedSetMember, 0, actualEnd-actualStart+1)
+	rank := int64(0)
+	err = op.rangeSortedSet(sortedSetData, math.Inf(-1), func(scoreKey []byte, score float64, memberDf *DataFrame) (bool, error) {
+		if rank >= actualStart {
+			value, err := listItemValue(memberDf)
+			if err != nil {
+				return false, err
+			}
+			result = append(result, SortedSetMember{Member: value, Score: score})
+		}
+		rank++
+		return rank <= actualEnd, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to range sorted set: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetSortedSetRangeByScore returns the members scored min to max inclusive,
+// lowest score first. Pass math.Inf(-1) or math.Inf(1) for an open bound.
+func (op *Operator) GetSortedSetRangeByScore(key string, min, max float64) (_ []SortedSetMember, err error) {
+	defer op.traceOperation("GetSortedSetRangeByScore", key)(&err)
+
+	if math.IsNaN(min) || math.IsNaN(max) {
+		return nil, fmt.Errorf("score range is not a number")
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	_, sortedSetData, err := op.getSortedSet(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []SortedSetMember{}
+	if min > max {
+		return result, nil
+	}
+
+	err = op.rangeSortedSet(sortedSetData, min, func(scoreKey []byte, score float64, memberDf *DataFrame) (bool, error) {
+		if score > max {
+			return false, nil
+		}
+		value, err := listItemValue(memberDf)
+		if err != nil {
+			return false, err
+		}
+		result = append(result, SortedSetMember{Member: value, Score: score})
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to range sorted set: %w", err)
+	}
+
+	return result, nil
+}
+
+// ZRemRangeByRank removes the members ranked start to stop inclusive and
+// returns how many were removed. Ranks follow GetSortedSetRangeByRank, so
+// ZRemRangeByRank(key, 0, -N-1) keeps only the N highest scored members.
+func (op *Operator) ZRemRangeByRank(key string, start, stop int64) (removed int64, err error) {
+	defer op.traceOperation("ZRemRangeByRank", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, sortedSetData, err := op.getSortedSet(key)
+	if err != nil {
+		return 0, err
+	}
+
+	actualStart, actualStop, ok := sortedSetRankRange(sortedSetData.Count, start, stop)
+	if !ok {
+		return 0, nil
+	}
+
+	scoreKeys := make([][]byte, 0, actualStop-actualStart+1)
+	rank := int64(0)
+	err = op.rangeSortedSet(sortedSetData, math.Inf(-1), func(scoreKey []byte, score float64, memberDf *DataFrame) (bool, error) {
+		if rank >= actualStart {
+			scoreKeys = append(scoreKeys, scoreKey)
+		}
+		rank++
+		return rank <= actualStop, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to range sorted set: %w", err)
+	}
+
+	before := int64(sortedSetData.Count)
+	count, err := op.removeSortedSetMembers(key, df, sortedSetData, scoreKeys)
+	if err != nil {
+		return 0, err
+	}
+
+	return before - count, nil
+}
+
+// ZRemRangeByScore removes the members scored min to max inclusive and
+// returns how many were removed. Pass math.Inf(-1) or math.Inf(1) for an open
+// bound.
+func (op *Operator) ZRemRangeByScore(key string, min, max float64) (removed int64, err error) {
+	defer op.traceOperation("ZRemRangeByScore", key)(&err)
+
+	if math.IsNaN(min) || math.IsNaN(max) {
+		return 0, fmt.Errorf("score range is not a number")
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, sortedSetData, err := op.getSortedSet(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if min > max {
+		return 0, nil
+	}
+
+	var scoreKeys [][]byte
+	err = op.rangeSortedSet(sortedSetData, min, func(scoreKey []byte, score float64, memberDf *DataFrame) (bool, error) {
+		if score > max {
+			return false, nil
+		}
+		scoreKeys = append(scoreKeys, scoreKey)
+		return true, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to range sorted set: %w", err)
+	}
+
+	before := int64(sortedSetData.Count)
+	count, err := op.removeSortedSetMembers(key, df, sortedSetData, scoreKeys)
+	if err != nil {
+		return 0, err
+	}
+
+	return before - count, nil
+}
+
+func (op *Operator) getSortedSet(key string) (*DataFrame, *SortedSetData, error) {
+	df, err := op.get(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sorted set %s does not exist: %w", key, err)
+	}
+
+	sortedSetData, err := df.SortedSet()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get sorted set data: %w", err)
+	}
+
+	return df, sortedSetData, nil
+}
+
+// sortedSetScore looks up the score of the member identified by memberID.
+func (op *Operator) sortedSetScore(sortedSetData *SortedSetData, memberID string) (float64, bool, error) {
+	memberKey := string(MakeSortedSetMemberKey(sortedSetData.Prefix, memberID))
+	scoreDf, err := op.get(memberKey)
+	if err != nil {
+		if isNotExist(err) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get sorted set member: %w", err)
+	}
+
+	score, err := scoreDf.Float()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get member score: %w", err)
+	}
+
+	return score, true, nil
+}
+
+// removeSortedSetMembers deletes the members behind scoreKeys in one batch and
+// returns the new cardinality.
+func (op *Operator) removeSortedSetMembers(key string, df *DataFrame, sortedSetData *SortedSetData, scoreKeys [][]byte) (int64, error) {
+	if len(scoreKeys) == 0 {
+		return int64(sortedSetData.Count), nil
+	}
+
+	scorePrefixLen := len(sortedSetScorePrefix(sortedSetData.Prefix))
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	for _, scoreKey := range scoreKeys {
+		memberID := string(scoreKey[scorePrefixLen+8:])
+		memberKey := MakeSortedSetMemberKey(sortedSetData.Prefix, memberID)
+
+		if err := batch.Delete(memberKey, nil); err != nil {
+			return 0, fmt.Errorf("failed to delete sorted set member: %w", err)
+		}
+		if err := batch.Delete(scoreKey, nil); err != nil {
+			return 0, fmt.Errorf("failed to delete sorted set score: %w", err)
+		}
+	}
+
+	// Update metadata
+	sortedSetData.Count -= uint64(len(scoreKeys))
+
+	if err := df.setScoredSetData(sortedSetData); err != nil {
+		return 0, fmt.Errorf("failed to update sorted set metadata: %w", err)
+	}
+
+	if err := op.setInBatch(batch, key, df); err != nil {
+		return 0, fmt.Errorf("failed to update sorted set metadata: %w", err)
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit sorted set batch: %w", err)
+	}
+
+	return int64(sortedSetData.Count), nil
+}
+
+// rangeSortedSet walks the score index from min upwards and calls fn for each
+// member until it returns false.
+func (op *Operator) rangeSortedSet(sortedSetData *SortedSetData, min float64, fn func(scoreKey []byte, score float64, memberDf *DataFrame) (bool, error)) error {
+	if err := op.ctxErr(); err != nil {
+		return fmt.Errorf("failed to create iterator: %w", err)
+	}
+
+	scorePrefix := sortedSetScorePrefix(sortedSetData.Prefix)
+
+	iter, err := op.db.NewIter(&pebble.IterOptions{
+		LowerBound: MakeSortedSetScoreKey(sortedSetData.Prefix, min, ""),
+		UpperBound: []byte(scorePrefix[:len(scorePrefix)-1] + ";"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := op.ctxErr(); err != nil {
+			return fmt.Errorf("iterator error: %w", err)
+		}
+
+		scoreKey := append([]byte(nil), iter.Key()...)
+		if len(scoreKey) < len(scorePrefix)+8 {
+			continue
+		}
+		score := scoreFromSortable(binary.BigEndian.Uint64(scoreKey[len(scorePrefix):]))
+
+		memberDf, err := op.unmarshal(string(scoreKey), iter.Value())
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal dataframe for key %s: %w", scoreKey, err)
+		}
+
+		cont, err := fn(scoreKey, score, memberDf)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			break
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("iterator error: %w", err)
+	}
+
+	return nil
+}
+
+func sortedSetScorePrefix(prefix string) string {
+	return string(MakeSortedSetEntryKey(prefix)) + ":" + string(sortedSetScoreSpace) + ":"
+}
+
+// sortedSetRankRange resolves negative ranks against count and clamps the
+// range, reporting false when it is empty.
+func sortedSetRankRange(count uint64, start, end int64) (int64, int64, bool) {
+	length := int64(count)
+
+	if start < 0 {
+		start = length + start
+	}
+	if end < 0 {
+		end = length + end
+	}
+
+	if start < 0 {
+		start = 0
+	}
+	if end >= length {
+		end = length - 1
+	}
+
+	return start, end, start <= end
+}
+
+// sortedSetMemberID encodes member with its type so that members of
+// different types never collide, and returns the DataFrame stored in the
+// score index.
+func sortedSetMemberID(member PrimitiveData) (string, *DataFrame, error) {
+	memberDf, err := listItemDataFrame(member)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return string(append([]byte{byte(memberDf.typ)}, memberDf.payload...)), memberDf, nil
+}
@@ -0,0 +1,357 @@
+package op
+
+import (
+	"math"
+	"testing"
+)
+
+func createTestSortedSet(t *testing.T, tower *Operator, key string, members map[string]float64) {
+	t.Helper()
+
+	if err := tower.CreateSortedSet(key); err != nil {
+		t.Fatalf("Failed to create sorted set: %v", err)
+	}
+	for member, score := range members {
+		if _, err := tower.AddSortedSetMember(key, PrimitiveString(member), score); err != nil {
+			t.Fatalf("Failed to add member %s: %v", member, err)
+		}
+	}
+}
+
+func sortedSetMemberNames(t *testing.T, members []SortedSetMember) []string {
+	t.Helper()
+
+	names := make([]string, 0, len(members))
+	for _, m := range members {
+		name, err := m.Member.String()
+		if err != nil {
+			t.Fatalf("Expected string member, got %v", m.Member)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSortedSetBasicOperations(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test_zset"
+
+	if err := tower.CreateSortedSet(key); err != nil {
+		t.Fatalf("Failed to create sorted set: %v", err)
+	}
+	if err := tower.CreateSortedSet(key); err == nil {
+		t.Error("Expected error creating an existing sorted set")
+	}
+
+	exists, err := tower.ExistsSortedSet(key)
+	if err != nil || !exists {
+		t.Fatalf("Expected sorted set to exist, got %v (%v)", exists, err)
+	}
+
+	count, err := tower.AddSortedSetMember(key, PrimitiveString("alice"), 10)
+	if err != nil || count != 1 {
+		t.Fatalf("Expected cardinality 1, got %d (%v)", count, err)
+	}
+	count, err = tower.AddSortedSetMember(key, PrimitiveInt(42), -5)
+	if err != nil || count != 2 {
+		t.Fatalf("Expected cardinality 2, got %d (%v)", count, err)
+	}
+
+	score, err := tower.GetSortedSetScore(key, PrimitiveString("alice"))
+	if err != nil || score != 10 {
+		t.Errorf("Expected score 10, got %v (%v)", score, err)
+	}
+	score, err = tower.GetSortedSetScore(key, PrimitiveInt(42))
+	if err != nil || score != -5 {
+		t.Errorf("Expected score -5, got %v (%v)", score, err)
+	}
+	if _, err := tower.GetSortedSetScore(key, PrimitiveString("missing")); err == nil {
+		t.Error("Expected error for missing member")
+	}
+
+	count, err = tower.DeleteSortedSetMember(key, PrimitiveInt(42))
+	if err != nil || count != 1 {
+		t.Errorf("Expected cardinality 1 after delete, got %d (%v)", count, err)
+	}
+	count, err = tower.DeleteSortedSetMember(key, PrimitiveInt(42))
+	if err != nil || count != 1 {
+		t.Errorf("Expected unchanged cardinality deleting a missing member, got %d (%v)", count, err)
+	}
+
+	if err := tower.DeleteSortedSet(key); err != nil {
+		t.Fatalf("Failed to delete sorted set: %v", err)
+	}
+	exists, err = tower.ExistsSortedSet(key)
+	if err != nil || exists {
+		t.Errorf("Expected sorted set to not exist after deletion, got %v (%v)", exists, err)
+	}
+
+	// No member or index keys may outlive the sorted set
+	raw := 0
+	if err := tower.rangePrefix(key, func(k string, df *DataFrame) error {
+		raw++
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to scan keys: %v", err)
+	}
+	if raw != 0 {
+		t.Errorf("Expected no keys left, got %d", raw)
+	}
+}
+
+func TestSortedSetRescoring(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test_zset"
+	createTestSortedSet(t, tower, key, map[string]float64{"a": 1, "b": 2, "c": 3})
+
+	// Adding an existing member moves it without changing the cardinality
+	count, err := tower.AddSortedSetMember(key, PrimitiveString("a"), 10)
+	if err != nil || count != 3 {
+		t.Fatalf("Expected cardinality 3, got %d (%v)", count, err)
+	}
+
+	members, err := tower.GetSortedSetRangeByRank(key, 0, -1)
+	if err != nil {
+		t.Fatalf("Failed to get range: %v", err)
+	}
+	if names := sortedSetMemberNames(t, members); !equalStrings(names, []string{"b", "c", "a"}) {
+		t.Errorf("Expected [b c a], got %v", names)
+	}
+
+	// The old score must not linger in the index
+	members, err = tower.GetSortedSetRangeByScore(key, 0, 1.5)
+	if err != nil {
+		t.Fatalf("Failed to get range: %v", err)
+	}
+	if len(members) != 0 {
+		t.Errorf("Expected no members scored up to 1.5, got %v", sortedSetMemberNames(t, members))
+	}
+
+	score, err := tower.IncrSortedSetScore(key, PrimitiveString("b"), 2.5)
+	if err != nil || score != 4.5 {
+		t.Errorf("Expected score 4.5, got %v (%v)", score, err)
+	}
+	score, err = tower.IncrSortedSetScore(key, PrimitiveString("d"), -1)
+	if err != nil || score != -1 {
+		t.Errorf("Expected new member with score -1, got %v (%v)", score, err)
+	}
+
+	cardinality, err := tower.GetSortedSetCardinality(key)
+	if err != nil || cardinality != 4 {
+		t.Errorf("Expected cardinality 4, got %d (%v)", cardinality, err)
+	}
+
+	members, err = tower.GetSortedSetRangeByRank(key, 0, -1)
+	if err != nil {
+		t.Fatalf("Failed to get range: %v", err)
+	}
+	if names := sortedSetMemberNames(t, members); !equalStrings(names, []string{"d", "c", "b", "a"}) {
+		t.Errorf("Expected [d c b a], got %v", names)
+	}
+	if members[2].Score != 4.5 {
+		t.Errorf("Expected score 4.5 for b, got %v", members[2].Score)
+	}
+}
+
+func TestSortedSetRanges(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test_zset"
+	createTestSortedSet(t, tower, key, map[string]float64{
+		"neg": -2.5, "zero": 0, "one": 1, "two": 2, "big": 1e9,
+	})
+
+	t.Run("by rank", func(t *testing.T) {
+		tests := []struct {
+			start, end int64
+			want       []string
+		}{
+			{0, 1, []string{"neg", "zero"}},
+			{-2, -1, []string{"two", "big"}},
+			{1, 100, []string{"zero", "one", "two", "big"}},
+			{-100, 0, []string{"neg"}},
+			{3, 1, []string{}},
+		}
+		for _, tt := range tests {
+			members, err := tower.GetSortedSetRangeByRank(key, tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("Failed to get range %d..%d: %v", tt.start, tt.end, err)
+			}
+			if names := sortedSetMemberNames(t, members); !equalStrings(names, tt.want) {
+				t.Errorf("Range %d..%d: expected %v, got %v", tt.start, tt.end, tt.want, names)
+			}
+		}
+	})
+
+	t.Run("by score", func(t *testing.T) {
+		tests := []struct {
+			min, max float64
+			want     []string
+		}{
+			{0, 2, []string{"zero", "one", "two"}},
+			{math.Inf(-1), 0, []string{"neg", "zero"}},
+			{1.5, math.Inf(1), []string{"two", "big"}},
+			{-1, -0.5, []string{}},
+			{2, 1, []string{}},
+		}
+		for _, tt := range tests {
+			members, err := tower.GetSortedSetRangeByScore(key, tt.min, tt.max)
+			if err != nil {
+				t.Fatalf("Failed to get range %v..%v: %v", tt.min, tt.max, err)
+			}
+			if names := sortedSetMemberNames(t, members); !equalStrings(names, tt.want) {
+				t.Errorf("Range %v..%v: expected %v, got %v", tt.min, tt.max, tt.want, names)
+			}
+		}
+
+		if _, err := tower.GetSortedSetRangeByScore(key, math.NaN(), 1); err == nil {
+			t.Error("Expected error for NaN bound")
+		}
+	})
+}
+
+func TestSortedSetRemoveRange(t *testing.T) {
+	t.Run("by rank keeps top N", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		key := "leaderboard"
+		createTestSortedSet(t, tower, key, map[string]float64{
+			"p1": 10, "p2": 50, "p3": 30, "p4": 20, "p5": 40,
+		})
+
+		removed, err := tower.ZRemRangeByRank(key, 0, -3)
+		if err != nil || removed != 3 {
+			t.Fatalf("Expected 3 removed, got %d (%v)", removed, err)
+		}
+
+		members, err := tower.GetSortedSetRangeByRank(key, 0, -1)
+		if err != nil {
+			t.Fatalf("Failed to get range: %v", err)
+		}
+		if names := sortedSetMemberNames(t, members); !equalStrings(names, []string{"p5", "p2"}) {
+			t.Errorf("Expected [p5 p2], got %v", names)
+		}
+
+		cardinality, err := tower.GetSortedSetCardinality(key)
+		if err != nil || cardinality != 2 {
+			t.Errorf("Expected cardinality 2, got %d (%v)", cardinality, err)
+		}
+		if _, err := tower.GetSortedSetScore(key, PrimitiveString("p1")); err == nil {
+			t.Error("Expected removed member to have no score")
+		}
+
+		removed, err = tower.ZRemRangeByRank(key, 5, 10)
+		if err != nil || removed != 0 {
+			t.Errorf("Expected nothing removed out of range, got %d (%v)", removed, err)
+		}
+	})
+
+	t.Run("by negative rank", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		key := "ladder"
+		createTestSortedSet(t, tower, key, map[string]float64{
+			"a": 1, "b": 2, "c": 3, "d": 4, "e": 5,
+		})
+
+		// The two highest scored members
+		removed, err := tower.ZRemRangeByRank(key, -2, -1)
+		if err != nil || removed != 2 {
+			t.Fatalf("Expected 2 removed, got %d (%v)", removed, err)
+		}
+
+		// A start before the first rank is clamped to it
+		removed, err = tower.ZRemRangeByRank(key, -100, -3)
+		if err != nil || removed != 1 {
+			t.Fatalf("Expected 1 removed, got %d (%v)", removed, err)
+		}
+
+		members, err := tower.GetSortedSetRangeByRank(key, 0, -1)
+		if err != nil {
+			t.Fatalf("Failed to get range: %v", err)
+		}
+		if names := sortedSetMemberNames(t, members); !equalStrings(names, []string{"b", "c"}) {
+			t.Errorf("Expected [b c], got %v", names)
+		}
+
+		removed, err = tower.ZRemRangeByRank(key, -1, -2)
+		if err != nil || removed != 0 {
+			t.Errorf("Expected nothing removed for an inverted range, got %d (%v)", removed, err)
+		}
+	})
+
+	t.Run("by score", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		key := "scores"
+		createTestSortedSet(t, tower, key, map[string]float64{
+			"a": -10, "b": 0, "c": 5, "d": 10,
+		})
+
+		removed, err := tower.ZRemRangeByScore(key, math.Inf(-1), 0)
+		if err != nil || removed != 2 {
+			t.Fatalf("Expected 2 removed, got %d (%v)", removed, err)
+		}
+
+		removed, err = tower.ZRemRangeByScore(key, 6, 9)
+		if err != nil || removed != 0 {
+			t.Errorf("Expected nothing removed, got %d (%v)", removed, err)
+		}
+
+		removed, err = tower.ZRemRangeByScore(key, 5, math.Inf(1))
+		if err != nil || removed != 2 {
+			t.Errorf("Expected 2 removed, got %d (%v)", removed, err)
+		}
+
+		cardinality, err := tower.GetSortedSetCardinality(key)
+		if err != nil || cardinality != 0 {
+			t.Errorf("Expected empty sorted set, got %d (%v)", cardinality, err)
+		}
+	})
+
+	t.Run("by unbounded score", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		key := "extremes"
+		createTestSortedSet(t, tower, key, map[string]float64{
+			"low": -math.MaxFloat64, "mid": 0, "high": math.MaxFloat64, "inf": math.Inf(1),
+		})
+
+		removed, err := tower.ZRemRangeByScore(key, math.Inf(-1), math.Inf(1))
+		if err != nil || removed != 4 {
+			t.Fatalf("Expected every member removed, got %d (%v)", removed, err)
+		}
+
+		removed, err = tower.ZRemRangeByScore(key, 1, -1)
+		if err != nil || removed != 0 {
+			t.Errorf("Expected nothing removed for an inverted range, got %d (%v)", removed, err)
+		}
+		if _, err := tower.ZRemRangeByScore(key, math.NaN(), 0); err == nil {
+			t.Error("Expected error for NaN bound")
+		}
+		if _, err := tower.ZRemRangeByScore("missing", math.Inf(-1), math.Inf(1)); err == nil {
+			t.Error("Expected error for a missing sorted set")
+		}
+	})
+}
@@ -3,10 +3,16 @@
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
-func (op *Operator) SetString(key string, value string) error {
-	unlock := op.lock(key)
+func (op *Operator) SetString(key string, value string) (err error) {
+	defer op.traceOperation("SetString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -21,8 +27,32 @@ func (op *Operator) SetString(key string, value string) error {
 	return nil
 }
 
-func (op *Operator) GetString(key string) (string, error) {
-	unlock := op.lock(key)
+// SetStringEx sets key to value and makes it expire after ttl, in a single
+// write.
+func (op *Operator) SetStringEx(key string, value string, ttl time.Duration) (err error) {
+	defer op.traceOperation("SetStringEx", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	df := NULLDataFrame()
+	if err := df.SetString(value); err != nil {
+		return fmt.Errorf("failed to set string value: %w", err)
+	}
+
+	return op.setExpiring(key, df, ttl)
+}
+
+func (op *Operator) GetString(key string) (_ string, err error) {
+	defer op.traceOperation("GetString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return "", err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -39,8 +69,13 @@ func (op *Operator) GetString(key string) (string, error) {
 }
 
 // String manipulation operations
-func (op *Operator) AppendString(key string, suffix string) (string, error) {
-	unlock := op.lock(key)
+func (op *Operator) AppendString(key string, suffix string) (_ string, err error) {
+	defer op.traceOperation("AppendString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return "", err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -65,8 +100,13 @@ func (op *Operator) AppendString(key string, suffix string) (string, error) {
 	return newValue, nil
 }
 
-func (op *Operator) PrependString(key string, prefix string) (string, error) {
-	unlock := op.lock(key)
+func (op *Operator) PrependString(key string, prefix string) (_ string, err error) {
+	defer op.traceOperation("PrependString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return "", err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -91,8 +131,13 @@ func (op *Operator) PrependString(key string, prefix string) (string, error) {
 	return newValue, nil
 }
 
-func (op *Operator) ReplaceString(key string, old, new string) (string, error) {
-	unlock := op.lock(key)
+func (op *Operator) ReplaceString(key string, old, new string) (_ string, err error) {
+	defer op.traceOperation("ReplaceString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return "", err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -118,8 +163,13 @@ func (op *Operator) ReplaceString(key string, old, new string) (string, error) {
 }
 
 // Search operations
-func (op *Operator) ContainsString(key string, substr string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ContainsString(key string, substr string) (_ bool, err error) {
+	defer op.traceOperation("ContainsString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -135,8 +185,13 @@ func (op *Operator) ContainsString(key string, substr string) (bool, error) {
 	return strings.Contains(current, substr), nil
 }
 
-func (op *Operator) StartsWithString(key string, prefix string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) StartsWithString(key string, prefix string) (_ bool, err error) {
+	defer op.traceOperation("StartsWithString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -152,8 +207,13 @@ func (op *Operator) StartsWithString(key string, prefix string) (bool, error) {
 	return strings.HasPrefix(current, prefix), nil
 }
 
-func (op *Operator) EndsWithString(key string, suffix string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) EndsWithString(key string, suffix string) (_ bool, err error) {
+	defer op.traceOperation("EndsWithString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -170,8 +230,13 @@ func (op *Operator) EndsWithString(key string, suffix string) (bool, error) {
 }
 
 // Length and substring operations
-func (op *Operator) GetStringLength(key string) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetStringLength(key string) (_ int, err error) {
+	defer op.traceOperation("GetStringLength", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -187,8 +252,13 @@ func (op *Operator) GetStringLength(key string) (int, error) {
 	return len(current), nil
 }
 
-func (op *Operator) GetStringSubstring(key string, start, length int) (string, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetStringSubstring(key string, start, length int) (_ string, err error) {
+	defer op.traceOperation("GetStringSubstring", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return "", err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -215,8 +285,129 @@ func (op *Operator) GetStringSubstring(key string, start, length int) (string, e
 }
 
 // Comparison operations
-func (op *Operator) CompareString(key string, other string) (int, error) {
-	unlock := op.lock(key)
+// GetStringRange returns the bytes of the string at key between start and
+// end, both inclusive. Negative indexes count back from the end, so -1 is the
+// last byte, and out-of-range indexes are clamped as in Redis GETRANGE.
+// Indexes are byte offsets, not rune offsets, so a range can cut through a
+// multi-byte UTF-8 character; use GetStringSubstring to slice by rune.
+func (op *Operator) GetStringRange(key string, start, end int) (_ string, err error) {
+	defer op.traceOperation("GetStringRange", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	current, err := df.String()
+	if err != nil {
+		return "", fmt.Errorf("failed to get string value for key %s: %w", key, err)
+	}
+
+	n := len(current)
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if end < 0 {
+		end = n + end
+	}
+	end = min(end, n-1)
+	if start > end {
+		return "", nil
+	}
+
+	return current[start : end+1], nil
+}
+
+// SetStringRange overwrites the string at key with value starting at byte
+// offset, padding with zero bytes when offset is past the end, and returns
+// the new length in bytes. A missing key is treated as an empty string.
+func (op *Operator) SetStringRange(key string, offset int, value string) (_ int, err error) {
+	defer op.traceOperation("SetStringRange", key)(&err)
+
+	if offset < 0 {
+		return 0, fmt.Errorf("offset cannot be negative, got %d", offset)
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var current string
+	df, err := op.get(key)
+	if err != nil {
+		if !isNotExist(err) {
+			return 0, fmt.Errorf("failed to get key %s: %w", key, err)
+		}
+		df = NULLDataFrame()
+	} else {
+		current, err = df.String()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get string value for key %s: %w", key, err)
+		}
+	}
+
+	buf := []byte(current)
+	if end := offset + len(value); end > len(buf) {
+		buf = append(buf, make([]byte, end-len(buf))...)
+	}
+	copy(buf[offset:], value)
+
+	if err := df.SetString(string(buf)); err != nil {
+		return 0, fmt.Errorf("failed to set string value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return 0, fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return len(buf), nil
+}
+
+func (op *Operator) SwapString(key string, newValue string) (_ string, err error) {
+	defer op.traceOperation("SwapString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	current, err := df.String()
+	if err != nil {
+		return "", fmt.Errorf("failed to get string value for key %s: %w", key, err)
+	}
+
+	if err := df.SetString(newValue); err != nil {
+		return "", fmt.Errorf("failed to set string value: %w", err)
+	}
+
+	if err := op.set(key, df); err != nil {
+		return "", fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return current, nil
+}
+
+func (op *Operator) CompareString(key string, other string) (_ int, err error) {
+	defer op.traceOperation("CompareString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -232,8 +423,13 @@ func (op *Operator) CompareString(key string, other string) (int, error) {
 	return strings.Compare(current, other), nil
 }
 
-func (op *Operator) CompareStringEqual(key string, other string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareStringEqual(key string, other string) (_ bool, err error) {
+	defer op.traceOperation("CompareStringEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -250,8 +446,13 @@ func (op *Operator) CompareStringEqual(key string, other string) (bool, error) {
 }
 
 // Conversion operations
-func (op *Operator) UpperString(key string) (string, error) {
-	unlock := op.lock(key)
+func (op *Operator) UpperString(key string) (_ string, err error) {
+	defer op.traceOperation("UpperString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return "", err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -276,8 +477,13 @@ func (op *Operator) UpperString(key string) (string, error) {
 	return newValue, nil
 }
 
-func (op *Operator) LowerString(key string) (string, error) {
-	unlock := op.lock(key)
+func (op *Operator) LowerString(key string) (_ string, err error) {
+	defer op.traceOperation("LowerString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return "", err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -302,3 +508,43 @@ func (op *Operator) LowerString(key string) (string, error) {
 	return newValue, nil
 }
 
+// CompareAndSwapString writes newValue only if the value stored at key equals
+// expected, and reports whether it did. A missing key matches an empty
+// expected value, so it can also be used to create the key.
+func (op *Operator) CompareAndSwapString(key string, expected, newValue string) (_ bool, err error) {
+	defer op.traceOperation("CompareAndSwapString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		if !isNotExist(err) {
+			return false, fmt.Errorf("failed to get key %s: %w", key, err)
+		}
+		if expected != "" {
+			return false, nil
+		}
+		df = NULLDataFrame()
+	} else {
+		current, err := df.String()
+		if err != nil {
+			return false, fmt.Errorf("failed to get string value for key %s: %w", key, err)
+		}
+		if current != expected {
+			return false, nil
+		}
+	}
+
+	if err := df.SetString(newValue); err != nil {
+		return false, fmt.Errorf("failed to set string value: %w", err)
+	}
+	if err := op.set(key, df); err != nil {
+		return false, fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	return true, nil
+}
@@ -1,6 +1,8 @@
 ﻿package op
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/rivulet-io/tower/util/size"
@@ -296,3 +298,183 @@ func TestStringOperations(t *testing.T) {
 	})
 }
 
+func TestCompareAndSwapString(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	// A missing key with an empty expected value is created
+	if ok, err := tower.CompareAndSwapString("cas", "", "v0"); err != nil || !ok {
+		t.Fatalf("Expected create to succeed, got %v (%v)", ok, err)
+	}
+	if ok, err := tower.CompareAndSwapString("missing", "x", "y"); err != nil || ok {
+		t.Errorf("Expected swap on missing key with non-empty expected to fail, got %v (%v)", ok, err)
+	}
+	if ok, err := tower.CompareAndSwapString("cas", "wrong", "v1"); err != nil || ok {
+		t.Errorf("Expected mismatched swap to fail, got %v (%v)", ok, err)
+	}
+	if v, _ := tower.GetString("cas"); v != "v0" {
+		t.Errorf("Expected v0 after failed swap, got %q", v)
+	}
+	if err := tower.SetInt("int", 1); err != nil {
+		t.Fatalf("Failed to set int: %v", err)
+	}
+	if _, err := tower.CompareAndSwapString("int", "1", "2"); err == nil {
+		t.Error("Expected error swapping a non-string key")
+	}
+
+	// Contended swaps from the same expected value: exactly one wins
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := []string{}
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value := fmt.Sprintf("v1-%d", i)
+			ok, err := tower.CompareAndSwapString("cas", "v0", value)
+			if err != nil {
+				t.Errorf("CompareAndSwapString failed: %v", err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				winners = append(winners, value)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(winners) != 1 {
+		t.Fatalf("Expected exactly one successful swap, got %d", len(winners))
+	}
+	if v, _ := tower.GetString("cas"); v != winners[0] {
+		t.Errorf("Expected %q, got %q", winners[0], v)
+	}
+}
+
+func TestSwapString(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.SetString("token", "old"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	prev, err := tower.SwapString("token", "new")
+	if err != nil {
+		t.Fatalf("SwapString failed: %v", err)
+	}
+	if prev != "old" {
+		t.Errorf("Expected previous value old, got %q", prev)
+	}
+	if v, _ := tower.GetString("token"); v != "new" {
+		t.Errorf("Expected new, got %q", v)
+	}
+
+	if err := tower.SetInt("int", 1); err != nil {
+		t.Fatalf("Failed to set int: %v", err)
+	}
+	if _, err := tower.SwapString("int", "x"); err == nil {
+		t.Error("Expected type mismatch error")
+	}
+	if v, _ := tower.GetInt("int"); v != 1 {
+		t.Errorf("Expected int to be untouched, got %d", v)
+	}
+	if _, err := tower.SwapString("missing", "x"); err == nil {
+		t.Error("Expected error for missing key")
+	}
+}
+
+func TestStringRange(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.SetString("s", "Hello, World"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+
+	t.Run("get", func(t *testing.T) {
+		tests := []struct {
+			start, end int
+			want       string
+		}{
+			{0, 4, "Hello"},
+			{7, -1, "World"},
+			{-5, -1, "World"},
+			{-100, 1, "He"},
+			{0, 100, "Hello, World"},
+			{5, 2, ""},
+			{20, 30, ""},
+			{-1, -2, ""},
+		}
+		for _, tt := range tests {
+			got, err := tower.GetStringRange("s", tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("GetStringRange(%d, %d) failed: %v", tt.start, tt.end, err)
+			}
+			if got != tt.want {
+				t.Errorf("GetStringRange(%d, %d) = %q, want %q", tt.start, tt.end, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		n, err := tower.SetStringRange("s", 7, "Tower")
+		if err != nil {
+			t.Fatalf("SetStringRange failed: %v", err)
+		}
+		if n != 12 {
+			t.Errorf("Expected length 12, got %d", n)
+		}
+		if v, _ := tower.GetString("s"); v != "Hello, Tower" {
+			t.Errorf("Expected Hello, Tower, got %q", v)
+		}
+
+		n, err = tower.SetStringRange("padded", 3, "ab")
+		if err != nil {
+			t.Fatalf("SetStringRange on missing key failed: %v", err)
+		}
+		if n != 5 {
+			t.Errorf("Expected length 5, got %d", n)
+		}
+		if v, _ := tower.GetString("padded"); v != "\x00\x00\x00ab" {
+			t.Errorf("Expected zero padding, got %q", v)
+		}
+
+		if _, err := tower.SetStringRange("s", -1, "x"); err == nil {
+			t.Error("Expected error for negative offset")
+		}
+	})
+
+	t.Run("utf8 byte offsets", func(t *testing.T) {
+		// "héllo" is 6 bytes: é takes two
+		if err := tower.SetString("u", "héllo"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if n, _ := tower.GetStringLength("u"); n != 6 {
+			t.Errorf("Expected byte length 6, got %d", n)
+		}
+		if v, _ := tower.GetStringRange("u", 1, 2); v != "é" {
+			t.Errorf("Expected é for bytes 1..2, got %q", v)
+		}
+		// A range ending inside é returns half of its encoding
+		if v, _ := tower.GetStringRange("u", 0, 1); v != "h\xc3" {
+			t.Errorf("Expected a split UTF-8 sequence, got %q", v)
+		}
+		if _, err := tower.SetStringRange("u", 1, "e"); err != nil {
+			t.Fatalf("SetStringRange failed: %v", err)
+		}
+		if v, _ := tower.GetString("u"); v != "he\xa9llo" {
+			t.Errorf("Expected only the first byte of é to be overwritten, got %q", v)
+		}
+	})
+
+	t.Run("append length", func(t *testing.T) {
+		if _, err := tower.AppendString("s", "!"); err != nil {
+			t.Fatalf("AppendString failed: %v", err)
+		}
+		if n, _ := tower.GetStringLength("s"); n != 13 {
+			t.Errorf("Expected length 13 after append, got %d", n)
+		}
+	})
+}
@@ -5,8 +5,13 @@ import (
 	"time"
 )
 
-func (op *Operator) SetTime(key string, value time.Time) error {
-	unlock := op.lock(key)
+func (op *Operator) SetTime(key string, value time.Time) (err error) {
+	defer op.traceOperation("SetTime", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -21,8 +26,13 @@ func (op *Operator) SetTime(key string, value time.Time) error {
 	return nil
 }
 
-func (op *Operator) GetTime(key string) (time.Time, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTime(key string) (_ time.Time, err error) {
+	defer op.traceOperation("GetTime", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -39,8 +49,13 @@ func (op *Operator) GetTime(key string) (time.Time, error) {
 }
 
 // Time calculation operations
-func (op *Operator) AddTimeWithDuration(key string, duration time.Duration) (time.Time, error) {
-	unlock := op.lock(key)
+func (op *Operator) AddTimeWithDuration(key string, duration time.Duration) (_ time.Time, err error) {
+	defer op.traceOperation("AddTimeWithDuration", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -70,8 +85,13 @@ func (op *Operator) SubTimeWithDuration(key string, duration time.Duration) (tim
 }
 
 // Comparison operations
-func (op *Operator) CompareTimeBefore(key string, other time.Time) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareTimeBefore(key string, other time.Time) (_ bool, err error) {
+	defer op.traceOperation("CompareTimeBefore", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -87,8 +107,13 @@ func (op *Operator) CompareTimeBefore(key string, other time.Time) (bool, error)
 	return current.Before(other), nil
 }
 
-func (op *Operator) CompareTimeAfter(key string, other time.Time) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareTimeAfter(key string, other time.Time) (_ bool, err error) {
+	defer op.traceOperation("CompareTimeAfter", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -104,8 +129,13 @@ func (op *Operator) CompareTimeAfter(key string, other time.Time) (bool, error)
 	return current.After(other), nil
 }
 
-func (op *Operator) CompareTimeEqual(key string, other time.Time) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareTimeEqual(key string, other time.Time) (_ bool, err error) {
+	defer op.traceOperation("CompareTimeEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -121,8 +151,13 @@ func (op *Operator) CompareTimeEqual(key string, other time.Time) (bool, error)
 	return current.Equal(other), nil
 }
 
-func (op *Operator) CalculateTimeDiff(key string, other time.Time) (time.Duration, error) {
-	unlock := op.lock(key)
+func (op *Operator) CalculateTimeDiff(key string, other time.Time) (_ time.Duration, err error) {
+	defer op.traceOperation("CalculateTimeDiff", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -139,8 +174,13 @@ func (op *Operator) CalculateTimeDiff(key string, other time.Time) (time.Duratio
 }
 
 // Utility operations
-func (op *Operator) CheckTimeZero(key string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) CheckTimeZero(key string) (_ bool, err error) {
+	defer op.traceOperation("CheckTimeZero", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -156,8 +196,13 @@ func (op *Operator) CheckTimeZero(key string) (bool, error) {
 	return current.IsZero(), nil
 }
 
-func (op *Operator) SetTimeIfGreater(key string, value time.Time) (time.Time, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetTimeIfGreater(key string, value time.Time) (_ time.Time, err error) {
+	defer op.traceOperation("SetTimeIfGreater", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -183,8 +228,13 @@ func (op *Operator) SetTimeIfGreater(key string, value time.Time) (time.Time, er
 	return current, nil
 }
 
-func (op *Operator) SetTimeIfLess(key string, value time.Time) (time.Time, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetTimeIfLess(key string, value time.Time) (_ time.Time, err error) {
+	defer op.traceOperation("SetTimeIfLess", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -210,8 +260,13 @@ func (op *Operator) SetTimeIfLess(key string, value time.Time) (time.Time, error
 	return current, nil
 }
 
-func (op *Operator) SetTimeIfEqual(key string, expected, newValue time.Time) (time.Time, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetTimeIfEqual(key string, expected, newValue time.Time) (_ time.Time, err error) {
+	defer op.traceOperation("SetTimeIfEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -238,8 +293,13 @@ func (op *Operator) SetTimeIfEqual(key string, expected, newValue time.Time) (ti
 }
 
 // Time element extraction
-func (op *Operator) GetTimeYear(key string) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTimeYear(key string) (_ int, err error) {
+	defer op.traceOperation("GetTimeYear", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -255,8 +315,13 @@ func (op *Operator) GetTimeYear(key string) (int, error) {
 	return current.Year(), nil
 }
 
-func (op *Operator) GetTimeMonth(key string) (time.Month, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTimeMonth(key string) (_ time.Month, err error) {
+	defer op.traceOperation("GetTimeMonth", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -272,8 +337,13 @@ func (op *Operator) GetTimeMonth(key string) (time.Month, error) {
 	return current.Month(), nil
 }
 
-func (op *Operator) GetTimeDay(key string) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTimeDay(key string) (_ int, err error) {
+	defer op.traceOperation("GetTimeDay", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -289,8 +359,13 @@ func (op *Operator) GetTimeDay(key string) (int, error) {
 	return current.Day(), nil
 }
 
-func (op *Operator) GetTimeHour(key string) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTimeHour(key string) (_ int, err error) {
+	defer op.traceOperation("GetTimeHour", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -306,8 +381,13 @@ func (op *Operator) GetTimeHour(key string) (int, error) {
 	return current.Hour(), nil
 }
 
-func (op *Operator) GetTimeMinute(key string) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTimeMinute(key string) (_ int, err error) {
+	defer op.traceOperation("GetTimeMinute", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -323,8 +403,13 @@ func (op *Operator) GetTimeMinute(key string) (int, error) {
 	return current.Minute(), nil
 }
 
-func (op *Operator) GetTimeSecond(key string) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTimeSecond(key string) (_ int, err error) {
+	defer op.traceOperation("GetTimeSecond", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -340,8 +425,13 @@ func (op *Operator) GetTimeSecond(key string) (int, error) {
 	return current.Second(), nil
 }
 
-func (op *Operator) GetTimeNanosecond(key string) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTimeNanosecond(key string) (_ int, err error) {
+	defer op.traceOperation("GetTimeNanosecond", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -2,20 +2,28 @@
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"time"
 
 	"github.com/cockroachdb/pebble"
 )
 
 // CreateTimeSeries creates a new time series.
-func (op *Operator) CreateTimeSeries(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) CreateTimeSeries(key string) (err error) {
+	defer op.traceOperation("CreateTimeSeries", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	// Check if the time series already exists
 	if _, err := op.get(key); err == nil {
-		return fmt.Errorf("time series %s already exists", key)
+		return fmt.Errorf("time series %s: %w", key, ErrContainerExists)
 	}
 
 	// Create the time series metadata
@@ -24,7 +32,7 @@ func (op *Operator) CreateTimeSeries(key string) error {
 	}
 
 	df := NULLDataFrame()
-	err := df.SetTimeseries(tsData)
+	err = df.SetTimeseries(tsData)
 	if err != nil {
 		return fmt.Errorf("failed to create timeseries data: %w", err)
 	}
@@ -39,8 +47,13 @@ func (op *Operator) CreateTimeSeries(key string) error {
 }
 
 // DeleteTimeSeries deletes an entire time series and all its data points.
-func (op *Operator) DeleteTimeSeries(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) DeleteTimeSeries(key string) (err error) {
+	defer op.traceOperation("DeleteTimeSeries", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	return op.deleteTimeSeries(key)
@@ -49,20 +62,33 @@ func (op *Operator) DeleteTimeSeries(key string) error {
 func (op *Operator) deleteTimeSeries(key string) error {
 	// Check if the time series exists
 	if _, err := op.get(key); err != nil {
-		return fmt.Errorf("time series %s does not exist", key)
+		return fmt.Errorf("time series %s does not exist: %w", key, err)
 	}
 
 	// For now, just delete the metadata
 	// TODO: Delete all data points in batch
-	return op.db.Delete([]byte(key), &pebble.WriteOptions{Sync: false})
+	if err := op.db.Delete([]byte(key), &pebble.WriteOptions{Sync: false}); err != nil {
+		return err
+	}
+
+	op.watches.touch(op.db.rawKey(key))
+	op.forget(op.db.rawKey(key))
+	op.publishKey(key, KeyEventDelete, TypeNull)
+
+	return nil
 }
 
 // ExistsTimeSeries checks if a time series exists.
-func (op *Operator) ExistsTimeSeries(key string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ExistsTimeSeries(key string) (_ bool, err error) {
+	defer op.traceOperation("ExistsTimeSeries", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
-	_, err := op.get(key)
+	_, err = op.get(key)
 	if err == nil {
 		return true, nil
 	}
@@ -70,13 +96,18 @@ func (op *Operator) ExistsTimeSeries(key string) (bool, error) {
 }
 
 // AddTimeSeriesPoint adds a data point to a time series at the specified timestamp.
-func (op *Operator) AddTimeSeriesPoint(key string, timestamp time.Time, value PrimitiveData) error {
-	unlock := op.lock(key)
+func (op *Operator) AddTimeSeriesPoint(key string, timestamp time.Time, value PrimitiveData) (err error) {
+	defer op.traceOperation("AddTimeSeriesPoint", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	// Check if the time series exists
 	if _, err := op.get(key); err != nil {
-		return fmt.Errorf("time series %s does not exist", key)
+		return fmt.Errorf("time series %s does not exist: %w", key, err)
 	}
 
 	// Create the data point key
@@ -124,7 +155,7 @@ func (op *Operator) AddTimeSeriesPoint(key string, timestamp time.Time, value Pr
 		return fmt.Errorf("unsupported data type: %v", value.Type())
 	}
 
-	valueBytes, err := df.Marshal()
+	valueBytes, err := op.marshal(string(dataPointKey), df)
 	if err != nil {
 		return fmt.Errorf("failed to marshal dataframe: %w", err)
 	}
@@ -134,18 +165,24 @@ func (op *Operator) AddTimeSeriesPoint(key string, timestamp time.Time, value Pr
 	if err != nil {
 		return fmt.Errorf("failed to store data point: %w", err)
 	}
+	op.watches.touch(op.db.rawKey(string(dataPointKey)))
 
 	return nil
 }
 
 // GetTimeSeriesPoint retrieves a data point from a time series at the specified timestamp.
-func (op *Operator) GetTimeSeriesPoint(key string, timestamp time.Time) (PrimitiveData, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTimeSeriesPoint(key string, timestamp time.Time) (_ PrimitiveData, err error) {
+	defer op.traceOperation("GetTimeSeriesPoint", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	// Check if the time series exists
 	if _, err := op.get(key); err != nil {
-		return nil, fmt.Errorf("time series %s does not exist", key)
+		return nil, fmt.Errorf("time series %s does not exist: %w", key, err)
 	}
 
 	// Create the data point key
@@ -154,55 +191,35 @@ func (op *Operator) GetTimeSeriesPoint(key string, timestamp time.Time) (Primiti
 	// Get the data point
 	value, closer, err := op.db.Get(dataPointKey)
 	if err != nil {
-		if err == pebble.ErrNotFound {
-			return nil, fmt.Errorf("data point does not exist")
+		if errors.Is(err, pebble.ErrNotFound) {
+			return nil, fmt.Errorf("data point does not exist: %w", ErrKeyNotFound)
 		}
 		return nil, fmt.Errorf("failed to get data point: %w", err)
 	}
 	defer closer.Close()
 
 	// Unmarshal the value
-	df, err := UnmarshalDataFrame(value)
+	df, err := op.unmarshal(string(dataPointKey), value)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal dataframe: %w", err)
 	}
 
-	// Convert DataFrame to PrimitiveData
-	switch df.Type() {
-	case TypeInt:
-		intVal, _ := df.Int()
-		return PrimitiveInt(intVal), nil
-	case TypeFloat:
-		floatVal, _ := df.Float()
-		return PrimitiveFloat(floatVal), nil
-	case TypeString:
-		strVal, _ := df.String()
-		return PrimitiveString(strVal), nil
-	case TypeBool:
-		boolVal, _ := df.Bool()
-		return PrimitiveBool(boolVal), nil
-	case TypeTimestamp:
-		timeVal, _ := df.Timestamp()
-		return PrimitiveTime(timeVal), nil
-	case TypeDuration:
-		durVal, _ := df.Duration()
-		return PrimitiveDuration(durVal), nil
-	case TypeBinary:
-		binVal, _ := df.Binary()
-		return PrimitiveBinary(binVal), nil
-	default:
-		return nil, fmt.Errorf("unsupported data type: %v", df.Type())
-	}
+	return timeSeriesPointValue(df)
 }
 
 // DeleteTimeSeriesPoint removes a data point from a time series at the specified timestamp.
-func (op *Operator) DeleteTimeSeriesPoint(key string, timestamp time.Time) error {
-	unlock := op.lock(key)
+func (op *Operator) DeleteTimeSeriesPoint(key string, timestamp time.Time) (err error) {
+	defer op.traceOperation("DeleteTimeSeriesPoint", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	// Check if the time series exists
 	if _, err := op.get(key); err != nil {
-		return fmt.Errorf("time series %s does not exist", key)
+		return fmt.Errorf("time series %s does not exist: %w", key, err)
 	}
 
 	// Create the data point key
@@ -211,8 +228,8 @@ func (op *Operator) DeleteTimeSeriesPoint(key string, timestamp time.Time) error
 	// Check if the data point exists
 	_, closer, err := op.db.Get(dataPointKey)
 	if err != nil {
-		if err == pebble.ErrNotFound {
-			return fmt.Errorf("data point does not exist")
+		if errors.Is(err, pebble.ErrNotFound) {
+			return fmt.Errorf("data point does not exist: %w", ErrKeyNotFound)
 		}
 		return fmt.Errorf("failed to check data point: %w", err)
 	}
@@ -223,81 +240,276 @@ func (op *Operator) DeleteTimeSeriesPoint(key string, timestamp time.Time) error
 	if err != nil {
 		return fmt.Errorf("failed to delete data point: %w", err)
 	}
+	op.watches.touch(op.db.rawKey(string(dataPointKey)))
 
 	return nil
 }
 
 // GetTimeSeriesRange retrieves all data points in a time series within the specified time range.
-func (op *Operator) GetTimeSeriesRange(key string, startTime, endTime time.Time) (map[time.Time]PrimitiveData, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTimeSeriesRange(key string, startTime, endTime time.Time) (_ map[time.Time]PrimitiveData, err error) {
+	defer op.traceOperation("GetTimeSeriesRange", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	// Check if the time series exists
 	if _, err := op.get(key); err != nil {
-		return nil, fmt.Errorf("time series %s does not exist", key)
+		return nil, fmt.Errorf("time series %s does not exist: %w", key, err)
 	}
 
 	result := make(map[time.Time]PrimitiveData)
+	err = op.rangeTimeSeries(key, startTime, endTime, func(point TimeSeriesPoint) error {
+		result[point.Timestamp] = point.Value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// TimeSeriesPoint is a single value of a time series.
+type TimeSeriesPoint struct {
+	Timestamp time.Time
+	Value     PrimitiveData
+}
 
-	// Create iteration bounds - use a broader range to capture all time series data points
-	keyPrefix := fmt.Sprintf("%s:%s:", key, TimeseriesTypeMarker)
+// GetTimeSeriesPoints returns the data points between startTime and endTime,
+// both inclusive, in timestamp order.
+func (op *Operator) GetTimeSeriesPoints(key string, startTime, endTime time.Time) (_ []TimeSeriesPoint, err error) {
+	defer op.traceOperation("GetTimeSeriesPoints", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Check if the time series exists
+	if _, err := op.get(key); err != nil {
+		return nil, fmt.Errorf("time series %s does not exist: %w", key, err)
+	}
+
+	var points []TimeSeriesPoint
+	err = op.rangeTimeSeries(key, startTime, endTime, func(point TimeSeriesPoint) error {
+		points = append(points, point)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+// TimeSeriesAggregation selects how AggregateTimeSeries combines the points
+// of a bucket.
+type TimeSeriesAggregation int
+
+const (
+	TimeSeriesAggregationSum TimeSeriesAggregation = iota
+	TimeSeriesAggregationAvg
+	TimeSeriesAggregationMin
+	TimeSeriesAggregationMax
+	TimeSeriesAggregationCount
+)
+
+// AggregateTimeSeries downsamples the points between startTime and endTime
+// into buckets of the given width and returns one point per non-empty bucket,
+// stamped with the bucket start. Buckets are aligned to multiples of bucket
+// since the zero time, which keeps minute, hour and day buckets on clock
+// boundaries. Count yields ints; the other aggregations yield floats and
+// require int or float points.
+func (op *Operator) AggregateTimeSeries(key string, startTime, endTime time.Time, bucket time.Duration, agg TimeSeriesAggregation) (_ []TimeSeriesPoint, err error) {
+	defer op.traceOperation("AggregateTimeSeries", key)(&err)
+
+	if bucket <= 0 {
+		return nil, fmt.Errorf("bucket must be positive")
+	}
+	if agg < TimeSeriesAggregationSum || agg > TimeSeriesAggregationCount {
+		return nil, fmt.Errorf("unsupported aggregation: %d", agg)
+	}
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Check if the time series exists
+	if _, err := op.get(key); err != nil {
+		return nil, fmt.Errorf("time series %s does not exist: %w", key, err)
+	}
+
+	var result []TimeSeriesPoint
+	var start time.Time
+	var sum, low, high float64
+	var count int64
+
+	flush := func() {
+		if count == 0 {
+			return
+		}
+
+		var value PrimitiveData
+		switch agg {
+		case TimeSeriesAggregationSum:
+			value = PrimitiveFloat(sum)
+		case TimeSeriesAggregationAvg:
+			value = PrimitiveFloat(sum / float64(count))
+		case TimeSeriesAggregationMin:
+			value = PrimitiveFloat(low)
+		case TimeSeriesAggregationMax:
+			value = PrimitiveFloat(high)
+		case TimeSeriesAggregationCount:
+			value = PrimitiveInt(count)
+		}
+		result = append(result, TimeSeriesPoint{Timestamp: start, Value: value})
+	}
+
+	err = op.rangeTimeSeries(key, startTime, endTime, func(point TimeSeriesPoint) error {
+		if bucketStart := point.Timestamp.Truncate(bucket); count == 0 || !bucketStart.Equal(start) {
+			flush()
+			start = bucketStart
+			sum, low, high, count = 0, math.Inf(1), math.Inf(-1), 0
+		}
+		count++
+
+		if agg == TimeSeriesAggregationCount {
+			return nil
+		}
+
+		var v float64
+		switch point.Value.Type() {
+		case TypeInt:
+			i, _ := point.Value.Int()
+			v = float64(i)
+		case TypeFloat:
+			v, _ = point.Value.Float()
+		default:
+			return fmt.Errorf("data point at %v is not numeric", point.Timestamp)
+		}
+		sum += v
+		low = math.Min(low, v)
+		high = math.Max(high, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	flush()
+
+	return result, nil
+}
+
+// rangeTimeSeries calls fn for the points of key between startTime and
+// endTime, both inclusive, in timestamp order. Points are keyed by their
+// Unix nanoseconds, so the scan is bounded to the range unless it reaches
+// before 1970, where the key order no longer follows time.
+func (op *Operator) rangeTimeSeries(key string, startTime, endTime time.Time, fn func(point TimeSeriesPoint) error) error {
+	if err := op.ctxErr(); err != nil {
+		return fmt.Errorf("failed to create iterator: %w", err)
+	}
+
+	entryKey := string(MakeTimeseriesEntryKey(key))
+	keyPrefix := entryKey + ":"
 	lowerBound := []byte(keyPrefix)
-	upperBound := append([]byte(keyPrefix), 0xff)
+	upperBound := []byte(entryKey + ";")
+
+	// Points before 1970 sort after all others, so such ranges scan everything
+	beforeEpoch := startTime.Before(time.Unix(0, 0))
+	if !beforeEpoch {
+		lowerBound = MakeTimeseriesDataPointKey(key, startTime)
+		if !endTime.After(time.Unix(0, math.MaxInt64)) {
+			upperBound = append(MakeTimeseriesDataPointKey(key, endTime), 0)
+		}
+	}
 
 	iter, err := op.db.NewIter(&pebble.IterOptions{
 		LowerBound: lowerBound,
 		UpperBound: upperBound,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create iterator: %w", err)
+		return fmt.Errorf("failed to create iterator: %w", err)
 	}
 	defer iter.Close()
 
+	var points []TimeSeriesPoint
 	for iter.First(); iter.Valid(); iter.Next() {
-		// Extract timestamp from key
 		keyBytes := iter.Key()
-		prefixLen := len(keyPrefix)
-		if len(keyBytes) >= prefixLen+8 {
-			timestampNanos := int64(binary.BigEndian.Uint64(keyBytes[prefixLen:]))
-			timestamp := time.Unix(0, timestampNanos).UTC()
-
-			// Check if timestamp is within range
-			if timestamp.Before(startTime) || timestamp.After(endTime) {
-				continue
-			}
-
-			// Unmarshal the value
-			df, err := UnmarshalDataFrame(iter.Value())
-			if err != nil {
-				return nil, fmt.Errorf("failed to unmarshal dataframe: %w", err)
-			}
-
-			// Convert DataFrame to PrimitiveData
-			switch df.Type() {
-			case TypeInt:
-				intVal, _ := df.Int()
-				result[timestamp] = PrimitiveInt(intVal)
-			case TypeFloat:
-				floatVal, _ := df.Float()
-				result[timestamp] = PrimitiveFloat(floatVal)
-			case TypeString:
-				strVal, _ := df.String()
-				result[timestamp] = PrimitiveString(strVal)
-			case TypeBool:
-				boolVal, _ := df.Bool()
-				result[timestamp] = PrimitiveBool(boolVal)
-			case TypeTimestamp:
-				timeVal, _ := df.Timestamp()
-				result[timestamp] = PrimitiveTime(timeVal)
-			case TypeDuration:
-				durVal, _ := df.Duration()
-				result[timestamp] = PrimitiveDuration(durVal)
-			case TypeBinary:
-				binVal, _ := df.Binary()
-				result[timestamp] = PrimitiveBinary(binVal)
-			}
+		if len(keyBytes) != len(keyPrefix)+8 {
+			continue
+		}
+
+		timestamp := time.Unix(0, int64(binary.BigEndian.Uint64(keyBytes[len(keyPrefix):]))).UTC()
+		if timestamp.Before(startTime) || timestamp.After(endTime) {
+			continue
+		}
+
+		df, err := op.unmarshal(string(keyBytes), iter.Value())
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal dataframe: %w", err)
+		}
+
+		value, err := timeSeriesPointValue(df)
+		if err != nil {
+			return err
+		}
+
+		point := TimeSeriesPoint{Timestamp: timestamp, Value: value}
+		if beforeEpoch {
+			points = append(points, point)
+			continue
+		}
+		if err := fn(point); err != nil {
+			return err
 		}
 	}
 
-	return result, nil
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("iterator error: %w", err)
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Timestamp.Before(points[j].Timestamp)
+	})
+	for _, point := range points {
+		if err := fn(point); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func timeSeriesPointValue(df *DataFrame) (PrimitiveData, error) {
+	switch df.Type() {
+	case TypeInt:
+		intVal, _ := df.Int()
+		return PrimitiveInt(intVal), nil
+	case TypeFloat:
+		floatVal, _ := df.Float()
+		return PrimitiveFloat(floatVal), nil
+	case TypeString:
+		strVal, _ := df.String()
+		return PrimitiveString(strVal), nil
+	case TypeBool:
+		boolVal, _ := df.Bool()
+		return PrimitiveBool(boolVal), nil
+	case TypeTimestamp:
+		timeVal, _ := df.Timestamp()
+		return PrimitiveTime(timeVal), nil
+	case TypeDuration:
+		durVal, _ := df.Duration()
+		return PrimitiveDuration(durVal), nil
+	case TypeBinary:
+		binVal, _ := df.Binary()
+		return PrimitiveBinary(binVal), nil
+	default:
+		return nil, fmt.Errorf("unsupported data type: %v", df.Type())
+	}
 }
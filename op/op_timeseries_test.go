@@ -1,6 +1,7 @@
 ﻿package op
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -270,6 +271,188 @@ func TestTimeSeriesWithDifferentTypes(t *testing.T) {
 	}
 }
 
+func TestTimeSeriesPoints(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test-timeseries-points"
+	if err := tower.CreateTimeSeries(key); err != nil {
+		t.Fatalf("Failed to create time series: %v", err)
+	}
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	// Appended out of order on purpose
+	for _, sec := range []int{30, 0, 90, 10, 60, 120} {
+		if err := tower.AddTimeSeriesPoint(key, base.Add(time.Duration(sec)*time.Second), PrimitiveFloat(float64(sec))); err != nil {
+			t.Fatalf("Failed to add point: %v", err)
+		}
+	}
+
+	seconds := func(points []TimeSeriesPoint) []int {
+		result := make([]int, len(points))
+		for i, p := range points {
+			result[i] = int(p.Timestamp.Sub(base) / time.Second)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Duration
+		want     string
+	}{
+		{"inclusive bounds", 10 * time.Second, 90 * time.Second, "[10 30 60 90]"},
+		{"between points", 11 * time.Second, 59 * time.Second, "[30]"},
+		{"single instant", 60 * time.Second, 60 * time.Second, "[60]"},
+		{"whole series", -time.Hour, time.Hour, "[0 10 30 60 90 120]"},
+		{"empty", 121 * time.Second, time.Hour, "[]"},
+		{"reversed", 90 * time.Second, 10 * time.Second, "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := tower.GetTimeSeriesPoints(key, base.Add(tt.from), base.Add(tt.to))
+			if err != nil {
+				t.Fatalf("Failed to get points: %v", err)
+			}
+			if got := fmt.Sprint(seconds(points)); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("unbounded range", func(t *testing.T) {
+		points, err := tower.GetTimeSeriesPoints(key, time.Time{}, base.AddDate(1000, 0, 0))
+		if err != nil {
+			t.Fatalf("Failed to get points: %v", err)
+		}
+		if got := fmt.Sprint(seconds(points)); got != "[0 10 30 60 90 120]" {
+			t.Errorf("Expected every point, got %s", got)
+		}
+	})
+
+	t.Run("before epoch", func(t *testing.T) {
+		key := "test-timeseries-epoch"
+		if err := tower.CreateTimeSeries(key); err != nil {
+			t.Fatalf("Failed to create time series: %v", err)
+		}
+		epoch := time.Unix(0, 0).UTC()
+		for _, offset := range []time.Duration{time.Hour, -time.Hour, 0} {
+			if err := tower.AddTimeSeriesPoint(key, epoch.Add(offset), PrimitiveInt(1)); err != nil {
+				t.Fatalf("Failed to add point: %v", err)
+			}
+		}
+
+		points, err := tower.GetTimeSeriesPoints(key, epoch.Add(-2*time.Hour), epoch.Add(2*time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to get points: %v", err)
+		}
+		if len(points) != 3 || !points[0].Timestamp.Equal(epoch.Add(-time.Hour)) || !points[2].Timestamp.Equal(epoch.Add(time.Hour)) {
+			t.Errorf("Expected 3 points in order, got %v", points)
+		}
+	})
+
+	if _, err := tower.GetTimeSeriesPoints("missing-series", base, base); err == nil {
+		t.Error("Expected error for missing time series")
+	}
+}
+
+func TestTimeSeriesAggregate(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	key := "test-timeseries-aggregate"
+	if err := tower.CreateTimeSeries(key); err != nil {
+		t.Fatalf("Failed to create time series: %v", err)
+	}
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	// Minute 0: 1, 2, 3 - minute 1: 10 - minute 3: 4, 8
+	points := map[time.Duration]PrimitiveData{
+		0:                              PrimitiveInt(1),
+		20 * time.Second:               PrimitiveFloat(2),
+		59 * time.Second:               PrimitiveInt(3),
+		60 * time.Second:               PrimitiveFloat(10),
+		3*time.Minute + time.Second:    PrimitiveInt(4),
+		3*time.Minute + 30*time.Second: PrimitiveInt(8),
+	}
+	for offset, value := range points {
+		if err := tower.AddTimeSeriesPoint(key, base.Add(offset), value); err != nil {
+			t.Fatalf("Failed to add point: %v", err)
+		}
+	}
+
+	tests := []struct {
+		agg  TimeSeriesAggregation
+		want []float64
+	}{
+		{TimeSeriesAggregationAvg, []float64{2, 10, 6}},
+		{TimeSeriesAggregationSum, []float64{6, 10, 12}},
+		{TimeSeriesAggregationMin, []float64{1, 10, 4}},
+		{TimeSeriesAggregationMax, []float64{3, 10, 8}},
+	}
+	for _, tt := range tests {
+		result, err := tower.AggregateTimeSeries(key, base, base.Add(time.Hour), time.Minute, tt.agg)
+		if err != nil {
+			t.Fatalf("Failed to aggregate %d: %v", tt.agg, err)
+		}
+		if len(result) != len(tt.want) {
+			t.Fatalf("Aggregation %d: expected %d buckets, got %d", tt.agg, len(tt.want), len(result))
+		}
+		for i, want := range tt.want {
+			got, err := result[i].Value.Float()
+			if err != nil || got != want {
+				t.Errorf("Aggregation %d bucket %d: expected %v, got %v (%v)", tt.agg, i, want, got, err)
+			}
+		}
+		if !result[2].Timestamp.Equal(base.Add(3 * time.Minute)) {
+			t.Errorf("Expected last bucket at minute 3, got %v", result[2].Timestamp)
+		}
+	}
 
+	t.Run("count", func(t *testing.T) {
+		result, err := tower.AggregateTimeSeries(key, base, base.Add(time.Hour), time.Minute, TimeSeriesAggregationCount)
+		if err != nil {
+			t.Fatalf("Failed to aggregate: %v", err)
+		}
+		var counts []int64
+		for _, p := range result {
+			c, _ := p.Value.Int()
+			counts = append(counts, c)
+		}
+		if fmt.Sprint(counts) != "[3 1 2]" {
+			t.Errorf("Expected [3 1 2], got %v", counts)
+		}
+	})
+
+	t.Run("range limits buckets", func(t *testing.T) {
+		result, err := tower.AggregateTimeSeries(key, base.Add(20*time.Second), base.Add(2*time.Minute), time.Minute, TimeSeriesAggregationAvg)
+		if err != nil {
+			t.Fatalf("Failed to aggregate: %v", err)
+		}
+		if len(result) != 2 {
+			t.Fatalf("Expected 2 buckets, got %d", len(result))
+		}
+		if avg, _ := result[0].Value.Float(); avg != 2.5 {
+			t.Errorf("Expected 2.5 for the partial first bucket, got %v", avg)
+		}
+	})
 
+	t.Run("errors", func(t *testing.T) {
+		if _, err := tower.AggregateTimeSeries(key, base, base.Add(time.Hour), 0, TimeSeriesAggregationAvg); err == nil {
+			t.Error("Expected error for zero bucket")
+		}
+		if _, err := tower.AggregateTimeSeries("missing-series", base, base.Add(time.Hour), time.Minute, TimeSeriesAggregationAvg); err == nil {
+			t.Error("Expected error for missing time series")
+		}
 
+		if err := tower.AddTimeSeriesPoint(key, base.Add(5*time.Minute), PrimitiveString("n/a")); err != nil {
+			t.Fatalf("Failed to add point: %v", err)
+		}
+		if _, err := tower.AggregateTimeSeries(key, base, base.Add(time.Hour), time.Minute, TimeSeriesAggregationSum); err == nil {
+			t.Error("Expected error summing a string point")
+		}
+		if _, err := tower.AggregateTimeSeries(key, base, base.Add(time.Hour), time.Minute, TimeSeriesAggregationCount); err != nil {
+			t.Errorf("Expected count to accept any point, got %v", err)
+		}
+	})
+}
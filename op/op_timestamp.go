@@ -5,8 +5,13 @@ import (
 	"time"
 )
 
-func (op *Operator) SetTimestamp(key string, value time.Time) error {
-	unlock := op.lock(key)
+func (op *Operator) SetTimestamp(key string, value time.Time) (err error) {
+	defer op.traceOperation("SetTimestamp", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -21,8 +26,13 @@ func (op *Operator) SetTimestamp(key string, value time.Time) error {
 	return nil
 }
 
-func (op *Operator) GetTimestamp(key string) (time.Time, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetTimestamp(key string) (_ time.Time, err error) {
+	defer op.traceOperation("GetTimestamp", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -38,8 +48,13 @@ func (op *Operator) GetTimestamp(key string) (time.Time, error) {
 	return value, nil
 }
 
-func (op *Operator) AddDurationToTimestamp(key string, duration time.Duration) (time.Time, error) {
-	unlock := op.lock(key)
+func (op *Operator) AddDurationToTimestamp(key string, duration time.Duration) (_ time.Time, err error) {
+	defer op.traceOperation("AddDurationToTimestamp", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -68,8 +83,13 @@ func (op *Operator) SubDurationFromTimestamp(key string, duration time.Duration)
 	return op.AddDurationToTimestamp(key, -duration)
 }
 
-func (op *Operator) CompareTimestamp(key string, value time.Time) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareTimestamp(key string, value time.Time) (_ int, err error) {
+	defer op.traceOperation("CompareTimestamp", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -90,8 +110,13 @@ func (op *Operator) CompareTimestamp(key string, value time.Time) (int, error) {
 	return 0, nil
 }
 
-func (op *Operator) SetTimestampIfGreater(key string, value time.Time) (time.Time, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetTimestampIfGreater(key string, value time.Time) (_ time.Time, err error) {
+	defer op.traceOperation("SetTimestampIfGreater", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -116,8 +141,13 @@ func (op *Operator) SetTimestampIfGreater(key string, value time.Time) (time.Tim
 	return current, nil
 }
 
-func (op *Operator) SetTimestampIfLess(key string, value time.Time) (time.Time, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetTimestampIfLess(key string, value time.Time) (_ time.Time, err error) {
+	defer op.traceOperation("SetTimestampIfLess", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -142,8 +172,13 @@ func (op *Operator) SetTimestampIfLess(key string, value time.Time) (time.Time,
 	return current, nil
 }
 
-func (op *Operator) SetTimestampIfEqual(key string, expected, newValue time.Time) (time.Time, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetTimestampIfEqual(key string, expected, newValue time.Time) (_ time.Time, err error) {
+	defer op.traceOperation("SetTimestampIfEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -0,0 +1,45 @@
+package op
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func noopTraceEnd(*error) {}
+
+// traceOperation starts a span for the named operation on key, as a child of
+// the span in the operator's context, and reports it to Options.Metrics. The
+// returned function ends it and marks it failed if *err is non-nil; use it as
+//
+//	defer op.traceOperation("GetString", key)(&err)
+func (op *Operator) traceOperation(name string, key string) func(err *error) {
+	if op.tracer == nil && op.metrics == nil {
+		return noopTraceEnd
+	}
+
+	var span trace.Span
+	if op.tracer != nil {
+		_, span = op.tracer.Start(op.Context(), name,
+			trace.WithAttributes(
+				attribute.String("tower.operation", name),
+				attribute.String("tower.key", key),
+			),
+		)
+	}
+	observed := op.observe(name)
+
+	return func(err *error) {
+		observed(err)
+		if span == nil {
+			return
+		}
+		if err != nil && *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		} else {
+			span.SetStatus(codes.Ok, "")
+		}
+		span.End()
+	}
+}
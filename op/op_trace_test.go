@@ -0,0 +1,76 @@
+package op
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/rivulet-io/tower/util/size"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("tower-test")
+
+	tower, err := NewOperator(&Options{
+		Path:         "test.db",
+		BytesPerSync: size.NewSizeFromBytes(32 * 1024),
+		CacheSize:    size.NewSizeFromMegabytes(64),
+		MemTableSize: size.NewSizeFromMegabytes(4),
+		FS:           InMemory(),
+		Tracer:       tracer,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tower: %v", err)
+	}
+	defer tower.Close()
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+
+	bound := tower.WithContext(ctx)
+	if err := bound.SetString("traced", "value"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if _, err := bound.GetInt("traced"); err == nil {
+		t.Fatal("Expected type mismatch error")
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	set, get := spans[0], spans[1]
+	if set.Name() != "SetString" || get.Name() != "GetInt" {
+		t.Fatalf("Unexpected span names %s, %s", set.Name(), get.Name())
+	}
+
+	for _, span := range []sdktrace.ReadOnlySpan{set, get} {
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected %s to be a child of the request span", span.Name())
+		}
+
+		found := false
+		for _, attr := range span.Attributes() {
+			if attr == attribute.String("tower.key", "traced") {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s to carry the key attribute", span.Name())
+		}
+	}
+
+	if set.Status().Code != codes.Ok {
+		t.Errorf("Expected ok status, got %v", set.Status().Code)
+	}
+	if get.Status().Code != codes.Error {
+		t.Errorf("Expected error status, got %v", get.Status().Code)
+	}
+}
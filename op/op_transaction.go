@@ -0,0 +1,263 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// ErrTransactionConflict is returned by Transaction when the watched keys
+// kept changing under it for every attempt.
+var ErrTransactionConflict = errors.New("transaction conflicted on every attempt")
+
+// maxTransactionAttempts bounds how often Transaction runs fn before giving
+// up with ErrTransactionConflict.
+const maxTransactionAttempts = 16
+
+// watchRegistry versions the keys watched by running transactions. Only
+// watched keys are tracked, so it stays as small as the set of keys in use
+// by transactions. Writes to item keys count as writes to the key owning
+// them.
+type watchRegistry struct {
+	mu     sync.Mutex
+	keys   map[string]*watchedKey
+	active atomic.Int32
+}
+
+type watchedKey struct {
+	refs    int
+	version uint64
+}
+
+func newWatchRegistry() *watchRegistry {
+	return &watchRegistry{keys: make(map[string]*watchedKey)}
+}
+
+// watch starts tracking keys and returns the function that stops it.
+func (w *watchRegistry) watch(keys []string) (release func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, key := range keys {
+		wk, ok := w.keys[key]
+		if !ok {
+			wk = &watchedKey{}
+			w.keys[key] = wk
+			w.active.Add(1)
+		}
+		wk.refs++
+	}
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		for _, key := range keys {
+			wk := w.keys[key]
+			if wk.refs--; wk.refs == 0 {
+				delete(w.keys, key)
+				w.active.Add(-1)
+			}
+		}
+	}
+}
+
+// versions returns the current version of every watched key in keys.
+func (w *watchRegistry) versions(keys []string) map[string]uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	result := make(map[string]uint64, len(keys))
+	for _, key := range keys {
+		result[key] = w.keys[key].version
+	}
+	return result
+}
+
+// touch bumps the version of the key owning each of keys.
+func (w *watchRegistry) touch(keys ...string) {
+	if w.active.Load() == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, key := range keys {
+		if wk, ok := w.keys[itemOwnerKey(key)]; ok {
+			wk.version++
+		}
+	}
+}
+
+// touchBatch bumps the versions of the keys written by batch.
+func (w *watchRegistry) touchBatch(batch *storeBatch) {
+	if w.active.Load() == 0 {
+		return
+	}
+
+	var keys []string
+	reader := batch.Reader()
+	for {
+		kind, ukey, value, ok, err := reader.Next()
+		if !ok || err != nil {
+			break
+		}
+		if kind == pebble.InternalKeyKindRangeDelete {
+			w.touchRange(ukey, value)
+			continue
+		}
+		keys = append(keys, string(ukey))
+	}
+
+	w.touch(keys...)
+}
+
+// touchRange bumps the versions of watched keys that fall in [lower, upper)
+// or whose items do.
+func (w *watchRegistry) touchRange(lower, upper []byte) {
+	if w.active.Load() == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key, wk := range w.keys {
+		inRange := key >= string(lower) && key < string(upper)
+		itemsInRange := string(lower) < key+";" && key+":" < string(upper)
+		if inRange || itemsInRange {
+			wk.version++
+		}
+	}
+}
+
+// Txn reads and stages the writes of one attempt of a Transaction. Staged
+// writes are applied only if the attempt commits. A Txn is not safe for
+// concurrent use.
+type Txn struct {
+	op       *Operator
+	keys     []string
+	versions map[string]uint64 // by stored key
+	batch    *Batch
+}
+
+// Get returns the value at key, or the value staged for it earlier in the
+// transaction. Only reads of watched keys are protected from concurrent
+// writes.
+func (tx *Txn) Get(key string) (PrimitiveData, error) {
+	for i := len(tx.batch.writes) - 1; i >= 0; i-- {
+		w := tx.batch.writes[i]
+		if w.key != key {
+			continue
+		}
+		if w.value == nil {
+			return nil, fmt.Errorf("key %s is deleted in this transaction: %w", key, ErrKeyNotFound)
+		}
+		return dataFramePrimitive(w.value)
+	}
+
+	unlock, err := tx.op.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	df, err := tx.op.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataFramePrimitive(df)
+}
+
+// Set stages a write of value to key.
+func (tx *Txn) Set(key string, value PrimitiveData) {
+	tx.batch.Set(key, value)
+}
+
+// Delete stages the removal of key, together with its items.
+func (tx *Txn) Delete(key string) {
+	tx.batch.Delete(key)
+}
+
+// Transaction runs fn and applies the writes it stages through tx only if
+// none of keys changed in the meantime. When one did, the staged writes are
+// dropped and fn runs again, up to a fixed number of attempts, after which
+// ErrTransactionConflict is returned. An error from fn aborts the
+// transaction without writing anything. fn may run several times, so it
+// should have no side effects besides those on tx.
+//
+// Versions are kept in memory, so only writes made through this operator,
+// or operators derived from it with WithContext, are seen as changes.
+func (op *Operator) Transaction(keys []string, fn func(tx *Txn) error) (err error) {
+	defer op.traceOperation("Transaction", "")(&err)
+
+	// The registry is shared by all namespaces, so it works on stored keys
+	rawKeys := make([]string, len(keys))
+	for i, key := range keys {
+		rawKeys[i] = op.db.rawKey(key)
+	}
+
+	release := op.watches.watch(rawKeys)
+	defer release()
+
+	for attempt := 0; attempt < maxTransactionAttempts; attempt++ {
+		tx := &Txn{
+			op:       op,
+			keys:     keys,
+			versions: op.watches.versions(rawKeys),
+			batch:    op.NewBatch(),
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		committed, err := tx.commit()
+		if err != nil {
+			return err
+		}
+		if committed {
+			return nil
+		}
+	}
+
+	return ErrTransactionConflict
+}
+
+// commit applies the staged writes under the locks of the watched and written
+// keys, and reports false without writing when a watched key has changed.
+func (tx *Txn) commit() (bool, error) {
+	b := tx.batch
+	if b.err != nil {
+		return false, b.err
+	}
+
+	unlock, err := tx.op.lockKeys(append(b.keys(), tx.keys...)...)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	watched := make([]string, 0, len(tx.versions))
+	for key := range tx.versions {
+		watched = append(watched, key)
+	}
+	current := tx.op.watches.versions(watched)
+	for key, version := range tx.versions {
+		if current[key] != version {
+			return false, nil
+		}
+	}
+
+	b.closed = true
+	if len(b.writes) == 0 {
+		return true, nil
+	}
+
+	return true, b.apply()
+}
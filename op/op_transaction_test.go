@@ -0,0 +1,185 @@
+package op
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestTransaction(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := tower.SetInt(key, 100); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	transfer := func(tx *Txn, from, to string, amount int64) error {
+		fromValue, err := tx.Get(from)
+		if err != nil {
+			return err
+		}
+		toValue, err := tx.Get(to)
+		if err != nil {
+			return err
+		}
+		fromBalance, _ := fromValue.Int()
+		toBalance, _ := toValue.Int()
+		tx.Set(from, PrimitiveInt(fromBalance-amount))
+		tx.Set(to, PrimitiveInt(toBalance+amount))
+		return nil
+	}
+
+	// The first transaction reads "b", then waits until the second one has
+	// moved money out of "b", so its first attempt must be retried
+	started := make(chan struct{})
+	secondDone := make(chan struct{})
+	attempts := 0
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		err := tower.Transaction([]string{"a", "b"}, func(tx *Txn) error {
+			attempts++
+			if err := transfer(tx, "a", "b", 10); err != nil {
+				return err
+			}
+			if attempts == 1 {
+				close(started)
+				<-secondDone
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("First transaction failed: %v", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(secondDone)
+		<-started
+		err := tower.Transaction([]string{"b", "c"}, func(tx *Txn) error {
+			return transfer(tx, "b", "c", 5)
+		})
+		if err != nil {
+			t.Errorf("Second transaction failed: %v", err)
+		}
+	}()
+	wg.Wait()
+
+	if attempts != 2 {
+		t.Errorf("Expected the first transaction to run twice, ran %d times", attempts)
+	}
+	for key, want := range map[string]int64{"a": 90, "b": 105, "c": 105} {
+		if got, err := tower.GetInt(key); err != nil || got != want {
+			t.Errorf("Expected %s = %d, got %d (%v)", key, want, got, err)
+		}
+	}
+
+	// Staged writes are visible to later reads in the same transaction
+	err := tower.Transaction([]string{"a"}, func(tx *Txn) error {
+		tx.Set("a", PrimitiveInt(1))
+		value, err := tx.Get("a")
+		if err != nil {
+			return err
+		}
+		if got, _ := value.Int(); got != 1 {
+			t.Errorf("Expected staged value 1, got %d", got)
+		}
+		tx.Delete("a")
+		if _, err := tx.Get("a"); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Expected ErrKeyNotFound for staged delete, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if _, err := tower.GetInt("a"); err == nil {
+		t.Error("Expected a to be deleted")
+	}
+
+	// An error from fn aborts without writing
+	abort := errors.New("abort")
+	err = tower.Transaction([]string{"b"}, func(tx *Txn) error {
+		tx.Set("b", PrimitiveInt(0))
+		return abort
+	})
+	if !errors.Is(err, abort) {
+		t.Errorf("Expected the error of fn, got %v", err)
+	}
+	if got, _ := tower.GetInt("b"); got != 105 {
+		t.Errorf("Expected b to stay 105, got %d", got)
+	}
+
+	// A key changing on every attempt exhausts the retries
+	err = tower.Transaction([]string{"c"}, func(tx *Txn) error {
+		if err := tower.SetInt("c", 0); err != nil {
+			return err
+		}
+		tx.Set("c", PrimitiveInt(1))
+		return nil
+	})
+	if !errors.Is(err, ErrTransactionConflict) {
+		t.Errorf("Expected ErrTransactionConflict, got %v", err)
+	}
+	if got, _ := tower.GetInt("c"); got != 0 {
+		t.Errorf("Expected c to keep the outside write, got %d", got)
+	}
+}
+
+func TestTransactionConcurrentTransfers(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	keys := []string{"w", "x", "y", "z"}
+	for _, key := range keys {
+		if err := tower.SetInt(key, 1000); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			from, to := keys[i%len(keys)], keys[(i+1)%len(keys)]
+			err := tower.Transaction([]string{from, to}, func(tx *Txn) error {
+				fromValue, err := tx.Get(from)
+				if err != nil {
+					return err
+				}
+				toValue, err := tx.Get(to)
+				if err != nil {
+					return err
+				}
+				fromBalance, _ := fromValue.Int()
+				toBalance, _ := toValue.Int()
+				tx.Set(from, PrimitiveInt(fromBalance-int64(i)))
+				tx.Set(to, PrimitiveInt(toBalance+int64(i)))
+				return nil
+			})
+			if err != nil && !errors.Is(err, ErrTransactionConflict) {
+				t.Errorf("Transaction %d failed: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var total int64
+	for _, key := range keys {
+		balance, err := tower.GetInt(key)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", key, err)
+		}
+		total += balance
+	}
+	if total != 4000 {
+		t.Errorf("Expected transfers to preserve the total of 4000, got %d", total)
+	}
+}
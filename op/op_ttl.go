@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log"
 	"strconv"
-	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -80,7 +79,7 @@ func (op *Operator) addCandidatesForExpiration(key string, expireAt time.Time) e
 	k := op.makeTTLKey(v)
 
 	// Ensure the TTL list exists
-	if err := op.CreateList(k); err != nil && !strings.Contains(err.Error(), "already exists") {
+	if err := op.EnsureList(k); err != nil {
 		return fmt.Errorf("failed to create TTL list %s: %w", k, err)
 	}
 
@@ -91,13 +90,18 @@ func (op *Operator) addCandidatesForExpiration(key string, expireAt time.Time) e
 	return nil
 }
 
-func (op *Operator) SetTTL(key string, expireAt time.Time) error {
+func (op *Operator) SetTTL(key string, expireAt time.Time) (err error) {
+	defer op.traceOperation("SetTTL", key)(&err)
+
 	now := Now()
 	if !expireAt.After(now) {
 		return nil // Ignore if already expired
 	}
 
-	unlock := op.lock(key)
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -118,8 +122,13 @@ func (op *Operator) SetTTL(key string, expireAt time.Time) error {
 	return nil
 }
 
-func (op *Operator) DeleteTTL(key string) error {
-	unlock := op.lock(key)
+func (op *Operator) DeleteTTL(key string) (err error) {
+	defer op.traceOperation("DeleteTTL", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -136,6 +145,59 @@ func (op *Operator) DeleteTTL(key string) error {
 	return nil
 }
 
+// NoExpiration is returned by GetTTL for keys that never expire.
+const NoExpiration time.Duration = -1
+
+// setExpiring stores df at key with an expiration ttl from now. The caller
+// must hold the key lock.
+func (op *Operator) setExpiring(key string, df *DataFrame, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive, got %v", ttl)
+	}
+
+	expireAt := Now().Add(ttl)
+	df.SetExpiration(expireAt)
+
+	if err := op.set(key, df); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	if err := op.addCandidatesForExpiration(key, expireAt); err != nil {
+		return fmt.Errorf("failed to add key %s to expiration candidates: %w", key, err)
+	}
+
+	return nil
+}
+
+// GetTTL returns the remaining lifetime of key, or NoExpiration if it has
+// no expiration.
+func (op *Operator) GetTTL(key string) (_ time.Duration, err error) {
+	defer op.traceOperation("GetTTL", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	expireAt := df.Expiration()
+	if expireAt.IsZero() {
+		return NoExpiration, nil
+	}
+
+	return max(expireAt.Sub(Now()), 0), nil
+}
+
+// Persist clears the expiration of key so it is kept until removed.
+func (op *Operator) Persist(key string) error {
+	return op.DeleteTTL(key)
+}
+
 func (op *Operator) TruncateExpired() error {
 	now := Now()
 	members, err := op.extractCandidatesForExpiration(now)
@@ -145,12 +207,44 @@ func (op *Operator) TruncateExpired() error {
 
 	for _, member := range members {
 		func() {
-			unlock := op.lock(member)
+			unlock, err := op.lock(member)
+			if err != nil {
+				return
+			}
 			defer unlock()
-			df, err := op.get(member)
-			if err == nil && !df.IsExpired(now) {
-				if err := op.smartDelete(member, df.typ); err != nil {
-					log.Printf("failed to delete expired key %s: %v", member, err)
+			// Read the raw value, as get leaves expired keys in place when
+			// LazyExpireOnRead is off
+			data, closer, err := op.db.Get([]byte(member))
+			if err != nil {
+				return
+			}
+			df, err := op.unmarshal(member, data)
+			closer.Close()
+			if err != nil {
+				if IsDataframeExpiredError(err) != nil {
+					if err := op.removeExpired(member, df); err != nil {
+						log.Printf("failed to delete expired key %s: %v", member, err)
+					}
+				}
+				return
+			}
+			// Sets and maps are candidates for members added with a TTL
+			switch df.typ {
+			case TypeSet:
+				setData, err := df.Set()
+				if err == nil {
+					err = op.removeExpiredSetMembers(member, df, setData)
+				}
+				if err != nil {
+					log.Printf("failed to delete expired members of set %s: %v", member, err)
+				}
+			case TypeMap:
+				mapData, err := df.Map()
+				if err == nil {
+					err = op.removeExpiredMapFields(member, df, mapData)
+				}
+				if err != nil {
+					log.Printf("failed to delete expired fields of map %s: %v", member, err)
 				}
 			}
 		}()
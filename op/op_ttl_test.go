@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cockroachdb/pebble"
+
 	"github.com/rivulet-io/tower/util/size"
 )
 
@@ -135,6 +137,114 @@ func TestDeleteTTL(t *testing.T) {
 	}
 }
 
+func TestSetEx(t *testing.T) {
+	tower := setupTower(t)
+	defer tower.Close()
+
+	if err := tower.SetStringEx("s", "value", time.Hour); err != nil {
+		t.Fatalf("SetStringEx failed: %v", err)
+	}
+	if err := tower.SetIntEx("i", 42, time.Hour); err != nil {
+		t.Fatalf("SetIntEx failed: %v", err)
+	}
+	if err := tower.SetFloatEx("f", 1.5, time.Hour); err != nil {
+		t.Fatalf("SetFloatEx failed: %v", err)
+	}
+	if err := tower.SetBinaryEx("b", []byte{1, 2}, time.Hour); err != nil {
+		t.Fatalf("SetBinaryEx failed: %v", err)
+	}
+
+	if v, err := tower.GetString("s"); err != nil || v != "value" {
+		t.Errorf("Expected value, got %q (%v)", v, err)
+	}
+	if v, err := tower.GetInt("i"); err != nil || v != 42 {
+		t.Errorf("Expected 42, got %d (%v)", v, err)
+	}
+	if v, err := tower.GetFloat("f"); err != nil || v != 1.5 {
+		t.Errorf("Expected 1.5, got %v (%v)", v, err)
+	}
+	if v, err := tower.GetBinary("b"); err != nil || len(v) != 2 {
+		t.Errorf("Expected 2 bytes, got %v (%v)", v, err)
+	}
+	for _, key := range []string{"s", "i", "f", "b"} {
+		ttl, err := tower.GetTTL(key)
+		if err != nil {
+			t.Errorf("GetTTL(%s) failed: %v", key, err)
+		}
+		if ttl <= 0 || ttl > time.Hour {
+			t.Errorf("GetTTL(%s) = %v, want within (0, 1h]", key, ttl)
+		}
+	}
+
+	if err := tower.SetStringEx("bad", "value", 0); err == nil {
+		t.Error("Expected error for zero ttl")
+	}
+
+	// Expiring keys disappear without a separate SetTTL
+	if err := tower.SetStringEx("short", "value", time.Second); err != nil {
+		t.Fatalf("SetStringEx failed: %v", err)
+	}
+	time.Sleep(2500 * time.Millisecond)
+	if _, err := tower.GetString("short"); err == nil {
+		t.Error("Expected key to expire")
+	}
+}
+
+func TestGetTTL(t *testing.T) {
+	tower := setupTower(t)
+	defer tower.Close()
+
+	if err := tower.SetString("plain", "value"); err != nil {
+		t.Fatalf("Failed to set key: %v", err)
+	}
+	if ttl, err := tower.GetTTL("plain"); err != nil || ttl != NoExpiration {
+		t.Errorf("Expected NoExpiration, got %v (%v)", ttl, err)
+	}
+	if _, err := tower.GetTTL("missing"); err == nil {
+		t.Error("Expected error for missing key")
+	}
+
+	if err := tower.SetStringEx("key", "value", time.Minute); err != nil {
+		t.Fatalf("SetStringEx failed: %v", err)
+	}
+	first, err := tower.GetTTL("key")
+	if err != nil {
+		t.Fatalf("GetTTL failed: %v", err)
+	}
+	time.Sleep(2500 * time.Millisecond)
+	second, err := tower.GetTTL("key")
+	if err != nil {
+		t.Fatalf("GetTTL failed: %v", err)
+	}
+	if second >= first {
+		t.Errorf("Expected ttl to decrease, got %v then %v", first, second)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	tower := setupTower(t)
+	defer tower.Close()
+
+	if err := tower.SetIntEx("key", 7, time.Second); err != nil {
+		t.Fatalf("SetIntEx failed: %v", err)
+	}
+	if err := tower.Persist("key"); err != nil {
+		t.Fatalf("Persist failed: %v", err)
+	}
+	if ttl, err := tower.GetTTL("key"); err != nil || ttl != NoExpiration {
+		t.Errorf("Expected NoExpiration after Persist, got %v (%v)", ttl, err)
+	}
+
+	time.Sleep(2500 * time.Millisecond)
+	if v, err := tower.GetInt("key"); err != nil || v != 7 {
+		t.Errorf("Expected persisted key to remain, got %d (%v)", v, err)
+	}
+
+	if err := tower.Persist("missing"); err == nil {
+		t.Error("Expected error for missing key")
+	}
+}
+
 func TestTruncateExpired(t *testing.T) {
 	tower := setupTower(t)
 	defer tower.Close()
@@ -168,6 +278,65 @@ func TestTruncateExpired(t *testing.T) {
 	}
 }
 
+func TestSetTTLCompound(t *testing.T) {
+	tower := setupTower(t)
+	defer tower.Close()
+
+	if err := tower.CreateList("list"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := tower.PushRightList("list", PrimitiveInt(int64(i))); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+	}
+	if _, err := tower.PushLeftList("list", PrimitiveInt(-1)); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+
+	if err := tower.CreateMap("map"); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := tower.SetMapKey("map", PrimitiveString(string(rune('a'+i))), PrimitiveInt(int64(i))); err != nil {
+			t.Fatalf("Failed to set map field: %v", err)
+		}
+	}
+
+	expireAt := time.Now().Add(1 * time.Second)
+	for _, key := range []string{"list", "map"} {
+		if err := tower.SetTTL(key, expireAt); err != nil {
+			t.Fatalf("Failed to set TTL on %s: %v", key, err)
+		}
+	}
+
+	// Wait for expiration
+	time.Sleep(2 * time.Second)
+
+	for _, key := range []string{"list", "map"} {
+		if _, err := tower.get(key); err == nil {
+			t.Errorf("Expected %s to be expired", key)
+		}
+
+		iter, err := tower.db.NewIter(&pebble.IterOptions{
+			LowerBound: []byte(key),
+			UpperBound: []byte(key + "\xff"),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create iterator: %v", err)
+		}
+		count := 0
+		for iter.First(); iter.Valid(); iter.Next() {
+			count++
+		}
+		iter.Close()
+
+		if count != 0 {
+			t.Errorf("Expected no keys left for %s, found %d", key, count)
+		}
+	}
+}
+
 func TestAddCandidatesForExpiration(t *testing.T) {
 	tower := setupTower(t)
 	defer tower.Close()
@@ -6,8 +6,13 @@ import (
 	"github.com/google/uuid"
 )
 
-func (op *Operator) SetUUID(key string, value *uuid.UUID) error {
-	unlock := op.lock(key)
+func (op *Operator) SetUUID(key string, value *uuid.UUID) (err error) {
+	defer op.traceOperation("SetUUID", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return err
+	}
 	defer unlock()
 
 	df := NULLDataFrame()
@@ -22,8 +27,13 @@ func (op *Operator) SetUUID(key string, value *uuid.UUID) error {
 	return nil
 }
 
-func (op *Operator) GetUUID(key string) (*uuid.UUID, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetUUID(key string) (_ *uuid.UUID, err error) {
+	defer op.traceOperation("GetUUID", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -40,8 +50,13 @@ func (op *Operator) GetUUID(key string) (*uuid.UUID, error) {
 }
 
 // UUID generation operations
-func (op *Operator) GenerateUUID(key string) (*uuid.UUID, error) {
-	unlock := op.lock(key)
+func (op *Operator) GenerateUUID(key string) (_ *uuid.UUID, err error) {
+	defer op.traceOperation("GenerateUUID", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	newUUID, err := uuid.NewV7()
@@ -62,8 +77,13 @@ func (op *Operator) GenerateUUID(key string) (*uuid.UUID, error) {
 }
 
 // Comparison operations
-func (op *Operator) CompareUUIDEqual(key string, other *uuid.UUID) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareUUIDEqual(key string, other *uuid.UUID) (_ bool, err error) {
+	defer op.traceOperation("CompareUUIDEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -79,8 +99,13 @@ func (op *Operator) CompareUUIDEqual(key string, other *uuid.UUID) (bool, error)
 	return current.String() == other.String(), nil
 }
 
-func (op *Operator) CompareUUID(key string, other *uuid.UUID) (int, error) {
-	unlock := op.lock(key)
+func (op *Operator) CompareUUID(key string, other *uuid.UUID) (_ int, err error) {
+	defer op.traceOperation("CompareUUID", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -105,8 +130,13 @@ func (op *Operator) CompareUUID(key string, other *uuid.UUID) (int, error) {
 }
 
 // Validation operations
-func (op *Operator) ValidateUUID(key string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) ValidateUUID(key string) (_ bool, err error) {
+	defer op.traceOperation("ValidateUUID", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -122,8 +152,13 @@ func (op *Operator) ValidateUUID(key string) (bool, error) {
 	return current != nil && current.String() != uuid.Nil.String(), nil
 }
 
-func (op *Operator) CheckUUIDNil(key string) (bool, error) {
-	unlock := op.lock(key)
+func (op *Operator) CheckUUIDNil(key string) (_ bool, err error) {
+	defer op.traceOperation("CheckUUIDNil", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -140,8 +175,13 @@ func (op *Operator) CheckUUIDNil(key string) (bool, error) {
 }
 
 // Conversion operations
-func (op *Operator) ConvertUUIDToString(key string) (string, error) {
-	unlock := op.lock(key)
+func (op *Operator) ConvertUUIDToString(key string) (_ string, err error) {
+	defer op.traceOperation("ConvertUUIDToString", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return "", err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -157,8 +197,13 @@ func (op *Operator) ConvertUUIDToString(key string) (string, error) {
 	return current.String(), nil
 }
 
-func (op *Operator) ConvertStringToUUID(key string, uuidStr string) (*uuid.UUID, error) {
-	unlock := op.lock(key)
+func (op *Operator) ConvertStringToUUID(key string, uuidStr string) (_ *uuid.UUID, err error) {
+	defer op.traceOperation("ConvertStringToUUID", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	parsedUUID, err := uuid.Parse(uuidStr)
@@ -179,8 +224,13 @@ func (op *Operator) ConvertStringToUUID(key string, uuidStr string) (*uuid.UUID,
 }
 
 // UUID information operations
-func (op *Operator) GetUUIDVersion(key string) (uuid.Version, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetUUIDVersion(key string) (_ uuid.Version, err error) {
+	defer op.traceOperation("GetUUIDVersion", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -196,8 +246,13 @@ func (op *Operator) GetUUIDVersion(key string) (uuid.Version, error) {
 	return current.Version(), nil
 }
 
-func (op *Operator) GetUUIDVariant(key string) (uuid.Variant, error) {
-	unlock := op.lock(key)
+func (op *Operator) GetUUIDVariant(key string) (_ uuid.Variant, err error) {
+	defer op.traceOperation("GetUUIDVariant", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -214,8 +269,13 @@ func (op *Operator) GetUUIDVariant(key string) (uuid.Variant, error) {
 }
 
 // Conditional set operations
-func (op *Operator) SetUUIDIfNil(key string) (*uuid.UUID, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetUUIDIfNil(key string) (_ *uuid.UUID, err error) {
+	defer op.traceOperation("SetUUIDIfNil", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
@@ -242,8 +302,13 @@ func (op *Operator) SetUUIDIfNil(key string) (*uuid.UUID, error) {
 	return current, nil
 }
 
-func (op *Operator) SetUUIDIfEqual(key string, expected, newValue *uuid.UUID) (*uuid.UUID, error) {
-	unlock := op.lock(key)
+func (op *Operator) SetUUIDIfEqual(key string, expected, newValue *uuid.UUID) (_ *uuid.UUID, err error) {
+	defer op.traceOperation("SetUUIDIfEqual", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return nil, err
+	}
 	defer unlock()
 
 	df, err := op.get(key)
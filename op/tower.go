@@ -1,11 +1,20 @@
 package op
 
 import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/cockroachdb/pebble"
 	"github.com/cockroachdb/pebble/vfs"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/rivulet-io/tower/util/size"
 	"github.com/rivulet-io/tower/util/synx"
@@ -17,8 +26,67 @@ type Options struct {
 	CacheSize    size.Size
 	MemTableSize size.Size
 	FS           vfs.FS
+
+	// TrackTimestamps records created-at and modified-at times in the header
+	// of every value written, exposed through KeyMetadata.
+	TrackTimestamps bool
+
+	// CompactEncoding writes values with MarshalCompact, which drops the
+	// fixed-width expiration and length fields. Values in either encoding
+	// are read back transparently, so it can be turned on for existing stores.
+	CompactEncoding bool
+
+	// MaxStoreSize bounds the store when EvictionPolicy is set. A background
+	// evictor checks every EvictionInterval (default 10s) and removes keys
+	// until the live data Pebble reports fits. Zero disables eviction.
+	MaxStoreSize     size.Size
+	EvictionPolicy   EvictionPolicy
+	EvictionInterval time.Duration
+
+	// GroupCommit coalesces writes from concurrent callers into one synced
+	// commit, flushed every GroupCommitInterval (default 2ms) or once
+	// GroupCommitMaxBatch (default 128) writes are pending. Each operation
+	// still returns only after its write is durable; SetAsync hands that
+	// wait back to the caller as a channel.
+	GroupCommit         bool
+	GroupCommitInterval time.Duration
+	GroupCommitMaxBatch int
+
+	// Tracer, when set, records every operation as a span carrying the
+	// operation name, key and error status. Spans are children of the span in
+	// the context given to WithContext.
+	Tracer trace.Tracer
+
+	// Codec, when set, compresses value payloads of at least 128 bytes
+	// that it manages to shrink. Every encoded value records the codec's ID,
+	// so values stay readable after the codec is changed or removed.
+	Codec Codec
+
+	// EncryptionKey, when set, seals every value payload written with
+	// AES-256-GCM under a random nonce. It must be 32 bytes. Headers holding
+	// the type and expiration stay readable but are authenticated together
+	// with the key each value is stored under, and plaintext values written
+	// before the key was set are still read back, so it can be turned on for
+	// existing stores. RotateEncryptionKey re-encrypts them all.
+	EncryptionKey []byte
+
+	// Metrics, when set, is told the duration and outcome of every
+	// operation, for graphing throughput and slow operations.
+	Metrics Metrics
+
+	// LazyExpireOnRead controls whether a read that finds an expired key
+	// deletes it, along with its items, instead of leaving it to the TTL
+	// sweep. Nil means enabled; set it to false on read-only replicas.
+	LazyExpireOnRead *bool
+
+	// KeyPrefix keeps every key of the operator under a prefix, as if the
+	// operator had been returned by WithNamespace(KeyPrefix).
+	KeyPrefix string
 }
 
+// InMemory returns a new, empty in-memory filesystem on every call, so
+// operators opened with separate InMemory() values never share data even
+// when they use the same Path. Pass the same FS value to reopen a store.
 func InMemory() vfs.FS {
 	return vfs.NewMem()
 }
@@ -28,11 +96,40 @@ func OnDisk() vfs.FS {
 }
 
 type Operator struct {
-	db      *pebble.DB
-	lockers *synx.ConcurrentMap[string, *sync.RWMutex]
+	db              *store
+	lockers         *synx.ConcurrentMap[string, *sync.RWMutex]
+	held            *synx.ConcurrentMap[string, time.Time]
+	trackTimestamps bool
+	compactEncoding bool
+	lazyExpire      bool
+	codec           Codec
+	keys            *atomic.Pointer[payloadKeyring]
+	evictor         *evictor
+	committer       *groupCommitter
+	tracer          trace.Tracer
+	metrics         Metrics
+	events          *eventBus
+	watches         *watchRegistry
+	ctx             context.Context
+	derived         bool // shares the store of the operator it was made from
 }
 
 func NewOperator(opt *Options) (*Operator, error) {
+	if opt.Codec != nil {
+		if err := registerCodec(opt.Codec); err != nil {
+			return nil, fmt.Errorf("invalid codec: %w", err)
+		}
+	}
+
+	keys := &atomic.Pointer[payloadKeyring]{}
+	if len(opt.EncryptionKey) > 0 {
+		aead, err := newPayloadCipher(opt.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key: %w", err)
+		}
+		keys.Store(&payloadKeyring{seal: aead, open: []cipher.AEAD{aead}})
+	}
+
 	options := &pebble.Options{
 		FS:           opt.FS,
 		BytesPerSync: int(opt.BytesPerSync),
@@ -45,52 +142,296 @@ func NewOperator(opt *Options) (*Operator, error) {
 		return nil, fmt.Errorf("failed to open pebble db: %w", err)
 	}
 
-	return &Operator{
-		db:      db,
-		lockers: synx.NewConcurrentMap[string, *sync.RWMutex](),
-	}, nil
+	op := &Operator{
+		db:              &store{DB: db, ns: opt.KeyPrefix},
+		lockers:         synx.NewConcurrentMap[string, *sync.RWMutex](),
+		held:            synx.NewConcurrentMap[string, time.Time](),
+		trackTimestamps: opt.TrackTimestamps,
+		compactEncoding: opt.CompactEncoding,
+		lazyExpire:      opt.LazyExpireOnRead == nil || *opt.LazyExpireOnRead,
+		codec:           opt.Codec,
+		keys:            keys,
+		evictor:         newEvictor(opt),
+		committer:       newGroupCommitter(opt),
+		tracer:          opt.Tracer,
+		metrics:         opt.Metrics,
+		events:          newEventBus(),
+		watches:         newWatchRegistry(),
+	}
+
+	if op.evictor != nil {
+		op.startEvictor()
+	}
+
+	if op.committer != nil {
+		op.startGroupCommitter()
+	}
+
+	return op, nil
 }
 
+// Close stops the background work of op and closes the store. On an operator
+// made by WithNamespace or WithContext it does nothing, as the store belongs
+// to the operator returned by NewOperator.
 func (op *Operator) Close() error {
+	if op.derived {
+		return nil
+	}
+
+	if op.evictor != nil {
+		op.stopEvictor()
+	}
+
+	if op.committer != nil {
+		op.stopGroupCommitter()
+	}
+
+	op.events.close()
+
 	return op.db.Close()
 }
 
-func (op *Operator) lock(key string) (unlock func()) {
+// WithContext returns an operator bound to ctx that shares storage and locks
+// with op. Its operations stop waiting for key locks and refuse to touch
+// Pebble once ctx is done, failing with an error that wraps ctx.Err().
+// Closing it does nothing.
+func (op *Operator) WithContext(ctx context.Context) *Operator {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	bound := *op
+	bound.ctx = ctx
+	bound.derived = true
+	return &bound
+}
+
+// Context returns the context op is bound to, or context.Background.
+func (op *Operator) Context() context.Context {
+	if op.ctx == nil {
+		return context.Background()
+	}
+	return op.ctx
+}
+
+func (op *Operator) ctxErr() error {
+	if op.ctx == nil {
+		return nil
+	}
+	return op.ctx.Err()
+}
+
+// lock takes the lock of key, waiting for it until op's context is done.
+// It returns the context error rather than the lock if the context is done
+// first, so callers never run without it.
+func (op *Operator) lock(key string) (unlock func(), err error) {
+	if op.evictor != nil {
+		op.evictor.touch(op.db.ns, key)
+	}
+
+	// Namespaces share the lock table, so it is keyed by stored key
+	key = op.db.rawKey(key)
+
 	locker, _ := op.lockers.LoadOrStore(key, &sync.RWMutex{})
-	locker.Lock()
+	if op.ctx == nil {
+		locker.Lock()
+		op.markHeld(key)
+		return func() {
+			op.held.Delete(key)
+			locker.Unlock()
+		}, nil
+	}
+
+	// Give up waiting once the context is done
+	wait := 50 * time.Microsecond
+	for op.ctx.Err() != nil || !locker.TryLock() {
+		select {
+		case <-op.ctx.Done():
+			return nil, fmt.Errorf("failed to lock key %s: %w", key, op.ctx.Err())
+		case <-time.After(wait):
+		}
+		if wait < 5*time.Millisecond {
+			wait *= 2
+		}
+	}
+	op.markHeld(key)
+
 	return func() {
+		op.held.Delete(key)
 		locker.Unlock()
+	}, nil
+}
+
+func (op *Operator) markHeld(key string) {
+	var acquiredAt time.Time
+	if op.trackTimestamps {
+		acquiredAt = time.Now()
+	}
+	op.held.Store(key, acquiredAt)
+}
+
+// LockInfo describes a key lock held at the time of a LockTableSnapshot.
+// AcquiredAt and HeldFor are zero unless Options.TrackTimestamps is set.
+type LockInfo struct {
+	Key        string
+	AcquiredAt time.Time
+	HeldFor    time.Duration
+}
+
+// LockTableSnapshot returns the key locks currently held, longest held
+// first. It only reads the lock table and is meant for debugging lock leaks
+// and slow operations; the result may be stale by the time it is returned.
+func (op *Operator) LockTableSnapshot() []LockInfo {
+	now := time.Now()
+
+	var locks []LockInfo
+	op.held.Range(func(key string, acquiredAt time.Time) bool {
+		info := LockInfo{Key: key, AcquiredAt: acquiredAt}
+		if !acquiredAt.IsZero() {
+			info.HeldFor = now.Sub(acquiredAt)
+		}
+		locks = append(locks, info)
+		return true
+	})
+
+	sort.Slice(locks, func(i, j int) bool {
+		if locks[i].HeldFor != locks[j].HeldFor {
+			return locks[i].HeldFor > locks[j].HeldFor
+		}
+		return locks[i].Key < locks[j].Key
+	})
+
+	return locks
+}
+
+// lockKeys locks every distinct key in sorted order so that callers locking
+// overlapping key sets can't deadlock each other.
+func (op *Operator) lockKeys(keys ...string) (unlock func(), err error) {
+	sorted := make([]string, 0, len(keys))
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	unlocks := make([]func(), 0, len(sorted))
+	release := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, key := range sorted {
+		unlock, err := op.lock(key)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		unlocks = append(unlocks, unlock)
 	}
+
+	return release, nil
 }
 
-func (op *Operator) set(key string, value *DataFrame) error {
+func (op *Operator) set(key string, value *DataFrame) (err error) {
+	defer op.observe("set")(&err)
+
 	if value == nil {
 		return fmt.Errorf("value cannot be nil")
 	}
 
-	data, err := value.Marshal()
+	if err := op.ctxErr(); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
+	if op.trackTimestamps {
+		op.stampTimestamps(key, value)
+	}
+
+	data, err := op.marshal(key, value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal dataframe: %w", err)
 	}
 
+	if op.committer != nil {
+		batch := op.db.NewBatch()
+		defer batch.Close()
+
+		if err := batch.Set([]byte(key), data, nil); err != nil {
+			return fmt.Errorf("failed to set key %s: %w", key, err)
+		}
+		if err := op.commit(batch); err != nil {
+			return fmt.Errorf("failed to set key %s: %w", key, err)
+		}
+		return nil
+	}
+
 	if err := op.db.Set([]byte(key), data, nil); err != nil {
 		return fmt.Errorf("failed to set key %s: %w", key, err)
 	}
 
+	op.watches.touch(op.db.rawKey(key))
+	if op.evictor != nil {
+		op.evictor.record(op.db.ns, key)
+	}
+	op.publishKey(key, KeyEventSet, value.typ)
+
 	return nil
 }
 
-func (op *Operator) get(key string) (*DataFrame, error) {
+// marshal encodes df for storage under key. Sealed payloads are bound to
+// the stored key, so a value can't be copied to another key as is; see
+// rebind.
+func (op *Operator) marshal(key string, df *DataFrame) ([]byte, error) {
+	if op.compactEncoding {
+		return df.marshalCompact(op.codec, op.keys.Load(), op.db.rawKey(key))
+	}
+	return df.marshal(op.codec, op.keys.Load(), op.db.rawKey(key))
+}
+
+func (op *Operator) unmarshal(key string, data []byte) (*DataFrame, error) {
+	return unmarshalDataFrame(data, op.keys.Load(), op.db.rawKey(key))
+}
+
+// rebind returns the stored value data of from encoded for storage under
+// to. Without an encryption key it is returned as is.
+func (op *Operator) rebind(from, to string, data []byte) ([]byte, error) {
+	if op.keys.Load() == nil {
+		return data, nil
+	}
+
+	// Expired values are moved too and left to the TTL sweep
+	df, err := op.unmarshal(from, data)
+	if err != nil && IsDataframeExpiredError(err) == nil {
+		return nil, fmt.Errorf("failed to unmarshal dataframe for key %s: %w", from, err)
+	}
+
+	return op.marshal(to, df)
+}
+
+func (op *Operator) get(key string) (_ *DataFrame, err error) {
+	defer op.observe("get")(&err)
+
+	if err := op.ctxErr(); err != nil {
+		return nil, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
 	data, closer, err := op.db.Get([]byte(key))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get key %s: %w", key, err)
+		if errors.Is(err, pebble.ErrNotFound) {
+			op.forget(op.db.rawKey(key))
+		}
+		return nil, fmt.Errorf("failed to get key %s: %w", key, keyNotFound(err))
 	}
 	defer closer.Close()
 
-	df, err := UnmarshalDataFrame(data)
+	df, err := op.unmarshal(key, data)
 	if err != nil {
-		if isReal := IsDataframeExpiredError(err); isReal != nil {
-			_ = op.smartDelete(key, df.typ) // Clean up expired data
+		if isReal := IsDataframeExpiredError(err); isReal != nil && op.lazyExpire {
+			_ = op.removeExpired(key, df) // Clean up expired data
 		}
 
 		return nil, fmt.Errorf("failed to unmarshal dataframe for key %s: %w", key, err)
@@ -99,51 +440,1005 @@ func (op *Operator) get(key string) (*DataFrame, error) {
 	return df, nil
 }
 
-func (op *Operator) delete(key string) error {
-	if err := op.db.Delete([]byte(key), nil); err != nil {
-		return fmt.Errorf("failed to delete key %s: %w", key, err)
+// setInBatch is set for writes that must commit together with others.
+func (op *Operator) setInBatch(batch *storeBatch, key string, value *DataFrame) error {
+	if value == nil {
+		return fmt.Errorf("value cannot be nil")
+	}
+
+	if err := op.ctxErr(); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
 	}
+
+	if op.trackTimestamps {
+		op.stampTimestamps(key, value)
+	}
+
+	data, err := op.marshal(key, value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dataframe: %w", err)
+	}
+
+	if err := batch.Set([]byte(key), data, nil); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+
 	return nil
 }
 
-func (op *Operator) Remove(key string) error {
-	return op.delete(key)
+// stampTimestamps sets the modified time of value to now and carries the
+// created time over from the stored value, or starts it now on first write.
+func (op *Operator) stampTimestamps(key string, value *DataFrame) {
+	now := Now()
+	value.modifiedAt = now
+	if !value.createdAt.IsZero() {
+		return
+	}
+
+	value.createdAt = now
+	data, closer, err := op.db.Get([]byte(key))
+	if err != nil {
+		return
+	}
+	defer closer.Close()
+
+	if prev, err := op.unmarshal(key, data); prev != nil && err == nil && !prev.createdAt.IsZero() {
+		value.createdAt = prev.createdAt
+	}
+}
+
+// KeyMetadata returns when key was first written and last modified. Both
+// times are zero for values written while Options.TrackTimestamps was off.
+func (op *Operator) KeyMetadata(key string) (created, modified time.Time, err error) {
+	defer op.traceOperation("KeyMetadata", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	return df.createdAt, df.modifiedAt, nil
+}
+
+// TypeOf returns the type of the value stored at key. Only the frame header
+// is decoded, so it is cheap for large values.
+func (op *Operator) TypeOf(key string) (_ DataType, err error) {
+	defer op.traceOperation("TypeOf", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return op.typeOf(key)
+}
+
+// Exists reports whether key holds a live value of any type.
+func (op *Operator) Exists(key string) (_ bool, err error) {
+	defer op.traceOperation("Exists", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if _, err := op.typeOf(key); err != nil {
+		if isNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (op *Operator) typeOf(key string) (DataType, error) {
+	if err := op.ctxErr(); err != nil {
+		return TypeNull, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	data, closer, err := op.db.Get([]byte(key))
+	if err != nil {
+		return TypeNull, fmt.Errorf("failed to get key %s: %w", key, keyNotFound(err))
+	}
+	typ, expiresAt, err := peekDataFrame(data)
+	closer.Close()
+	if err != nil {
+		return TypeNull, fmt.Errorf("failed to read dataframe header for key %s: %w", key, err)
+	}
+
+	if !expiresAt.IsZero() && Now().After(expiresAt) {
+		// Fall back to a full read, which also cleans up the expired key
+		if _, err := op.get(key); err != nil {
+			return TypeNull, err
+		}
+	}
+
+	return typ, nil
+}
+
+// MemoryUsage estimates the bytes key occupies as the sum of its raw key and
+// value lengths. For lists, sets, maps, time series and bloom filters every
+// item key is counted along with the metadata.
+func (op *Operator) MemoryUsage(key string) (_ int64, err error) {
+	defer op.traceOperation("MemoryUsage", key)(&err)
+
+	unlock, err := op.lock(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	df, err := op.get(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+
+	return op.memoryUsage(key, df)
+}
+
+func (op *Operator) memoryUsage(key string, df *DataFrame) (int64, error) {
+	data, err := op.marshal(key, df)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal dataframe: %w", err)
+	}
+	total := int64(len(key) + len(data))
+
+	entryKey, err := compoundEntryKey(df)
+	if err != nil {
+		return 0, err
+	}
+	if entryKey == "" {
+		return total, nil
+	}
+
+	items, err := op.rawItemsSize(entryKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure items of key %s: %w", key, err)
+	}
+
+	return total + items, nil
 }
 
-func (op *Operator) smartDelete(key string, dataType DataType) error {
-	switch dataType {
+// compoundEntryKey returns the key under which the items of a compound
+// structure are stored, or "" for primitive values.
+func compoundEntryKey(df *DataFrame) (string, error) {
+	switch df.typ {
 	case TypeList:
-		return op.DeleteList(key)
+		listData, err := df.List()
+		if err != nil {
+			return "", fmt.Errorf("failed to get list data: %w", err)
+		}
+		return string(MakeListEntryKey(listData.Prefix)), nil
+	case TypeSet:
+		setData, err := df.Set()
+		if err != nil {
+			return "", fmt.Errorf("failed to get set data: %w", err)
+		}
+		return string(MakeSetEntryKey(setData.Prefix)), nil
 	case TypeMap:
-		return op.DeleteMap(key)
+		mapData, err := df.Map()
+		if err != nil {
+			return "", fmt.Errorf("failed to get map data: %w", err)
+		}
+		return string(MakeMapEntryKey(mapData.Prefix)), nil
+	case TypeTimeseries:
+		tsData, err := df.Timeseries()
+		if err != nil {
+			return "", fmt.Errorf("failed to get timeseries data: %w", err)
+		}
+		return string(MakeTimeseriesEntryKey(tsData.Prefix)), nil
+	case TypeBloomFilter:
+		bfData, err := df.BloomFilter()
+		if err != nil {
+			return "", fmt.Errorf("failed to get bloom filter data: %w", err)
+		}
+		return string(MakeBloomFilterEntryKey(bfData.Prefix)), nil
+	case TypeSortedSet, TypeGeo:
+		sortedSetData, err := df.scoredSetData()
+		if err != nil {
+			return "", fmt.Errorf("failed to get sorted set data: %w", err)
+		}
+		return string(MakeSortedSetEntryKey(sortedSetData.Prefix)), nil
+	}
+
+	return "", nil
+}
+
+// setCompoundPrefix points the metadata of a compound structure at prefix.
+func setCompoundPrefix(df *DataFrame, prefix string) error {
+	switch df.typ {
+	case TypeList:
+		listData, err := df.List()
+		if err != nil {
+			return fmt.Errorf("failed to get list data: %w", err)
+		}
+		listData.Prefix = prefix
+		return df.SetList(listData)
 	case TypeSet:
-		return op.DeleteSet(key)
+		setData, err := df.Set()
+		if err != nil {
+			return fmt.Errorf("failed to get set data: %w", err)
+		}
+		setData.Prefix = prefix
+		return df.SetSet(setData)
+	case TypeMap:
+		mapData, err := df.Map()
+		if err != nil {
+			return fmt.Errorf("failed to get map data: %w", err)
+		}
+		mapData.Prefix = prefix
+		return df.SetMap(mapData)
 	case TypeTimeseries:
-		return op.DeleteTimeSeries(key)
+		tsData, err := df.Timeseries()
+		if err != nil {
+			return fmt.Errorf("failed to get timeseries data: %w", err)
+		}
+		tsData.Prefix = prefix
+		return df.SetTimeseries(tsData)
 	case TypeBloomFilter:
-		return op.DeleteBloomFilter(key)
+		bfData, err := df.BloomFilter()
+		if err != nil {
+			return fmt.Errorf("failed to get bloom filter data: %w", err)
+		}
+		bfData.Prefix = prefix
+		return df.SetBloomFilter(bfData)
+	case TypeSortedSet, TypeGeo:
+		sortedSetData, err := df.scoredSetData()
+		if err != nil {
+			return fmt.Errorf("failed to get sorted set data: %w", err)
+		}
+		sortedSetData.Prefix = prefix
+		return df.setScoredSetData(sortedSetData)
 	}
 
-	return op.delete(key)
+	return nil
 }
 
-func (op *Operator) rangePrefix(prefix string, fn func(key string, df *DataFrame) error) error {
+// rawItemsSize sums key and value lengths of the items under entryKey
+// without decoding them. The scan stops at the byte after ':' rather than at
+// 0xff because negative list indexes encode with a leading 0xff byte.
+func (op *Operator) rawItemsSize(entryKey string) (int64, error) {
+	if err := op.ctxErr(); err != nil {
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
+	}
+
 	iter, err := op.db.NewIter(&pebble.IterOptions{
-		LowerBound: []byte(prefix),
-		UpperBound: []byte(prefix + "\xff"),
+		LowerBound: []byte(entryKey + ":"),
+		UpperBound: []byte(entryKey + ";"),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create iterator: %w", err)
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
 	}
 	defer iter.Close()
 
+	var total int64
 	for iter.First(); iter.Valid(); iter.Next() {
+		total += int64(len(iter.Key()) + len(iter.Value()))
+	}
+
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("iterator error: %w", err)
+	}
+
+	return total, nil
+}
+
+// RenameKeyNX renames src to dest only if dest does not exist, returning
+// whether the rename happened. Compound structures are moved with all of
+// their items in a single batch.
+func (op *Operator) RenameKeyNX(src, dest string) (_ bool, err error) {
+	defer op.traceOperation("RenameKeyNX", src)(&err)
+
+	if src == dest {
+		return false, nil
+	}
+
+	unlock, err := op.lockKeys(src, dest)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	df, err := op.get(src)
+	if err != nil {
+		return false, fmt.Errorf("failed to get key %s: %w", src, err)
+	}
+
+	if _, err := op.get(dest); err == nil {
+		return false, nil
+	} else if !isNotExist(err) {
+		return false, fmt.Errorf("failed to get key %s: %w", dest, err)
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	srcEntryKey, err := compoundEntryKey(df)
+	if err != nil {
+		return false, err
+	}
+
+	if srcEntryKey != "" {
+		if err := setCompoundPrefix(df, dest); err != nil {
+			return false, fmt.Errorf("failed to update metadata of key %s: %w", src, err)
+		}
+
+		destEntryKey, err := compoundEntryKey(df)
+		if err != nil {
+			return false, err
+		}
+
+		// Move every item under the new prefix
+		iter, err := op.db.NewIter(&pebble.IterOptions{
+			LowerBound: []byte(srcEntryKey + ":"),
+			UpperBound: []byte(srcEntryKey + ";"),
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to create iterator: %w", err)
+		}
+
+		for iter.First(); iter.Valid(); iter.Next() {
+			itemKey := iter.Key()
+			newKey := destEntryKey + string(itemKey[len(srcEntryKey):])
+			value, err := op.rebind(string(itemKey), newKey, iter.Value())
+			if err != nil {
+				iter.Close()
+				return false, fmt.Errorf("failed to move item of key %s: %w", src, err)
+			}
+			if err := batch.Set([]byte(newKey), value, nil); err != nil {
+				iter.Close()
+				return false, fmt.Errorf("failed to move item of key %s: %w", src, err)
+			}
+			if err := batch.Delete(itemKey, nil); err != nil {
+				iter.Close()
+				return false, fmt.Errorf("failed to move item of key %s: %w", src, err)
+			}
+		}
+
+		if err := iter.Error(); err != nil {
+			iter.Close()
+			return false, fmt.Errorf("iterator error: %w", err)
+		}
+		iter.Close()
+	}
+
+	if err := op.setInBatch(batch, dest, df); err != nil {
+		return false, fmt.Errorf("failed to set key %s: %w", dest, err)
+	}
+
+	if err := batch.Delete([]byte(src), nil); err != nil {
+		return false, fmt.Errorf("failed to delete key %s: %w", src, err)
+	}
+
+	if err := op.commit(batch); err != nil {
+		return false, fmt.Errorf("failed to commit rename of key %s: %w", src, err)
+	}
+
+	if expireAt := df.Expiration(); !expireAt.IsZero() {
+		if err := op.addCandidatesForExpiration(dest, expireAt); err != nil {
+			return true, fmt.Errorf("failed to add key %s to expiration candidates: %w", dest, err)
+		}
+	}
+
+	return true, nil
+}
+
+// CheckAndSetMulti applies writes in a single batch only if every key in
+// conditions currently holds the expected value, and reports whether it did.
+// A nil expected value requires the key to be absent. All involved keys are
+// locked for the duration of the check and the write.
+func (op *Operator) CheckAndSetMulti(conditions map[string]PrimitiveData, writes map[string]PrimitiveData) (_ bool, err error) {
+	defer op.traceOperation("CheckAndSetMulti", "")(&err)
+
+	keys := make([]string, 0, len(conditions)+len(writes))
+	for key := range conditions {
+		keys = append(keys, key)
+	}
+	for key := range writes {
+		keys = append(keys, key)
+	}
+
+	unlock, err := op.lockKeys(keys...)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	for key, expected := range conditions {
+		df, err := op.get(key)
+		if err != nil {
+			if !isNotExist(err) {
+				return false, fmt.Errorf("failed to get key %s: %w", key, err)
+			}
+			if expected != nil {
+				return false, nil
+			}
+			continue
+		}
+		if expected == nil {
+			return false, nil
+		}
+
+		want, err := primitiveDataFrame(expected)
+		if err != nil {
+			return false, fmt.Errorf("invalid condition for key %s: %w", key, err)
+		}
+		if df.typ != want.typ || !bytes.Equal(df.payload, want.payload) {
+			return false, nil
+		}
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	for key, value := range writes {
+		df, err := primitiveDataFrame(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid write for key %s: %w", key, err)
+		}
+		if err := op.setInBatch(batch, key, df); err != nil {
+			return false, err
+		}
+	}
+
+	if err := op.commit(batch); err != nil {
+		return false, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return true, nil
+}
+
+// LogAndCount increments the int counter at counterKey and appends entry to
+// the list at listKey in a single batch, so either both change or neither
+// does. A missing counter starts at zero; the list must exist. It returns
+// the new count.
+func (op *Operator) LogAndCount(counterKey, listKey string, entry PrimitiveData) (count int64, err error) {
+	defer op.traceOperation("LogAndCount", counterKey)(&err)
+
+	if counterKey == listKey {
+		return 0, fmt.Errorf("counter and list must be different keys")
+	}
+
+	unlock, err := op.lockKeys(counterKey, listKey)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	counterDf, err := op.get(counterKey)
+	if err != nil {
+		if !isNotExist(err) {
+			return 0, fmt.Errorf("failed to get key %s: %w", counterKey, err)
+		}
+		counterDf = NULLDataFrame()
+		if err := counterDf.SetInt(0); err != nil {
+			return 0, fmt.Errorf("failed to set int value: %w", err)
+		}
+	}
+
+	current, err := counterDf.Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get int value for key %s: %w", counterKey, err)
+	}
+
+	count = current + 1
+	if err := counterDf.SetInt(count); err != nil {
+		return 0, fmt.Errorf("failed to set int value: %w", err)
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if _, err := op.pushRightListInBatch(batch, listKey, entry); err != nil {
+		return 0, err
+	}
+
+	if err := op.setInBatch(batch, counterKey, counterDf); err != nil {
+		return 0, err
+	}
+
+	if err := op.commit(batch); err != nil {
+		return 0, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return count, nil
+}
+
+func primitiveDataFrame(value PrimitiveData) (*DataFrame, error) {
+	if value == nil {
+		return nil, fmt.Errorf("value cannot be nil")
+	}
+
+	df := NULLDataFrame()
+	switch value.Type() {
+	case TypeInt:
+		intVal, _ := value.Int()
+		if err := df.SetInt(intVal); err != nil {
+			return nil, fmt.Errorf("failed to set int value: %w", err)
+		}
+	case TypeFloat:
+		floatVal, _ := value.Float()
+		if err := df.SetFloat(floatVal); err != nil {
+			return nil, fmt.Errorf("failed to set float value: %w", err)
+		}
+	case TypeString:
+		strVal, _ := value.String()
+		if err := df.SetString(strVal); err != nil {
+			return nil, fmt.Errorf("failed to set string value: %w", err)
+		}
+	case TypeBool:
+		boolVal, _ := value.Bool()
+		if err := df.SetBool(boolVal); err != nil {
+			return nil, fmt.Errorf("failed to set bool value: %w", err)
+		}
+	case TypeTimestamp:
+		tsVal, _ := value.Timestamp()
+		if err := df.SetTimestamp(time.Unix(0, tsVal)); err != nil {
+			return nil, fmt.Errorf("failed to set timestamp value: %w", err)
+		}
+	case TypeTime:
+		timeVal, _ := value.Time()
+		if err := df.SetTimestamp(timeVal); err != nil {
+			return nil, fmt.Errorf("failed to set timestamp value: %w", err)
+		}
+	case TypeDuration:
+		durVal, _ := value.Duration()
+		if err := df.SetDuration(durVal); err != nil {
+			return nil, fmt.Errorf("failed to set duration value: %w", err)
+		}
+	case TypeBinary:
+		binVal, _ := value.Binary()
+		if err := df.SetBinary(binVal); err != nil {
+			return nil, fmt.Errorf("failed to set binary value: %w", err)
+		}
+	case TypeUUID:
+		uuidVal, _ := value.UUID()
+		if err := df.SetUUID(&uuidVal); err != nil {
+			return nil, fmt.Errorf("failed to set UUID value: %w", err)
+		}
+	case TypeDecimal:
+		coefficient, scale, err := value.Decimal()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get decimal value: %w", err)
+		}
+		if err := df.SetDecimal(coefficient, scale); err != nil {
+			return nil, fmt.Errorf("failed to set decimal value: %w", err)
+		}
+	case TypeBigInt:
+		bigVal, err := value.BigInt()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get big int value: %w", err)
+		}
+		if err := df.SetBigInt(bigVal); err != nil {
+			return nil, fmt.Errorf("failed to set big int value: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported data type: %v", value.Type())
+	}
+
+	return df, nil
+}
+
+// dataFramePrimitive is the inverse of primitiveDataFrame.
+func dataFramePrimitive(df *DataFrame) (PrimitiveData, error) {
+	switch df.Type() {
+	case TypeNull:
+		return PrimitiveNull{}, nil
+	case TypeInt:
+		intVal, _ := df.Int()
+		return PrimitiveInt(intVal), nil
+	case TypeFloat:
+		floatVal, _ := df.Float()
+		return PrimitiveFloat(floatVal), nil
+	case TypeString:
+		strVal, _ := df.String()
+		return PrimitiveString(strVal), nil
+	case TypeBool:
+		boolVal, _ := df.Bool()
+		return PrimitiveBool(boolVal), nil
+	case TypeTimestamp:
+		timeVal, _ := df.Timestamp()
+		return PrimitiveTimestamp(timeVal.UnixNano()), nil
+	case TypeDuration:
+		durVal, _ := df.Duration()
+		return PrimitiveDuration(durVal), nil
+	case TypeBinary:
+		binVal, _ := df.Binary()
+		return PrimitiveBinary(binVal), nil
+	case TypeUUID:
+		uuidVal, err := df.UUID()
+		if err != nil {
+			return nil, err
+		}
+		return PrimitiveUUID(*uuidVal), nil
+	case TypeDecimal:
+		coefficient, scale, err := df.Decimal()
+		if err != nil {
+			return nil, err
+		}
+		return PrimitiveDecimal{Coefficient: coefficient, Scale: scale}, nil
+	case TypeBigInt:
+		bigVal, err := df.BigInt()
+		if err != nil {
+			return nil, err
+		}
+		return PrimitiveBigInt{Value: bigVal}, nil
+	default:
+		return nil, fmt.Errorf("unsupported data type: %v", df.Type())
+	}
+}
+
+// isNotExist reports whether err means the key is absent, either because it
+// was never written or because it has already expired.
+func isNotExist(err error) bool {
+	return errors.Is(err, ErrKeyNotFound) || errors.Is(err, pebble.ErrNotFound)
+}
+
+func (op *Operator) delete(key string) (err error) {
+	defer op.observe("delete")(&err)
+
+	if err := op.ctxErr(); err != nil {
+		return fmt.Errorf("failed to delete key %s: %w", key, err)
+	}
+
+	if op.committer != nil {
+		batch := op.db.NewBatch()
+		defer batch.Close()
+
+		if err := batch.Delete([]byte(key), nil); err != nil {
+			return fmt.Errorf("failed to delete key %s: %w", key, err)
+		}
+		if err := op.commit(batch); err != nil {
+			return fmt.Errorf("failed to delete key %s: %w", key, err)
+		}
+		return nil
+	}
+
+	if err := op.db.Delete([]byte(key), nil); err != nil {
+		return fmt.Errorf("failed to delete key %s: %w", key, err)
+	}
+
+	op.watches.touch(op.db.rawKey(key))
+	op.forget(op.db.rawKey(key))
+	op.publishKey(key, KeyEventDelete, TypeNull)
+
+	return nil
+}
+
+func (op *Operator) Remove(key string) (err error) {
+	defer op.traceOperation("Remove", key)(&err)
+
+	return op.delete(key)
+}
+
+// DeleteRange removes every raw key in [startKey, endKey) with a single
+// range tombstone. It works below the data structure layer: list, map, set
+// and other compound items that fall inside the span are dropped without
+// touching their metadata, so only use it on key spans you own outright
+// (e.g. a time-ordered key prefix).
+func (op *Operator) DeleteRange(startKey, endKey string) (err error) {
+	defer op.traceOperation("DeleteRange", startKey)(&err)
+
+	if startKey >= endKey {
+		return fmt.Errorf("invalid range: start key %s must be less than end key %s", startKey, endKey)
+	}
+
+	if err := op.ctxErr(); err != nil {
+		return fmt.Errorf("failed to delete range [%s, %s): %w", startKey, endKey, err)
+	}
+
+	if err := op.db.DeleteRange([]byte(startKey), []byte(endKey), nil); err != nil {
+		return fmt.Errorf("failed to delete range [%s, %s): %w", startKey, endKey, err)
+	}
+	op.watches.touchRange(op.db.key([]byte(startKey)), op.db.key([]byte(endKey)))
+	op.forgetRange(op.db.key([]byte(startKey)), op.db.key([]byte(endKey)))
+
+	return nil
+}
+
+// DeletePrefix removes every key starting with prefix, together with the
+// items of lists, sets, maps and other compound values stored under them,
+// with a single range tombstone. deleted is the number of top-level keys
+// removed.
+//
+// It takes no key locks, so it is not atomic with respect to other
+// operations: a write racing with it under the prefix may or may not
+// survive. Items of a key outside the prefix are removed too if their item
+// keys happen to start with it, e.g. the items of "tenant" for the prefix
+// "tenant:", so prefixes should end in a separator that no owning key does.
+func (op *Operator) DeletePrefix(prefix string) (deleted int, err error) {
+	defer op.traceOperation("DeletePrefix", prefix)(&err)
+
+	if strings.HasPrefix(systemKeyPrefix, prefix) || strings.HasPrefix(prefix, systemKeyPrefix) {
+		return 0, fmt.Errorf("invalid prefix %q: it covers internal system keys", prefix)
+	}
+
+	if err := op.ctxErr(); err != nil {
+		return 0, fmt.Errorf("failed to delete prefix %s: %w", prefix, err)
+	}
+
+	lower := []byte(prefix)
+	upper := prefixEnd(lower)
+	if upper == nil {
+		return 0, fmt.Errorf("invalid prefix %q: it has no upper bound", prefix)
+	}
+
+	snap := op.db.NewSnapshot()
+	defer snap.Close()
+
+	iter, err := snap.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create iterator: %w", err)
+	}
+	for iter.First(); iter.Valid(); iter.Next() {
+		if !isItemKey(string(iter.Key())) {
+			deleted++
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return 0, fmt.Errorf("iterator error: %w", err)
+	}
+
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	if err := op.db.DeleteRange(lower, upper, nil); err != nil {
+		return 0, fmt.Errorf("failed to delete prefix %s: %w", prefix, err)
+	}
+	op.watches.touchRange(op.db.key(lower), op.db.key(upper))
+	op.forgetRange(op.db.key(lower), op.db.key(upper))
+
+	return deleted, nil
+}
+
+// prefixEnd returns the smallest key greater than every key starting with
+// prefix, or nil when there is none.
+func prefixEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// ScanByType calls fn for every top-level key holding a value of type typ,
+// in key order, until fn returns false or an error. Only the frame header is
+// inspected for other keys, and the items of lists, sets, maps, time series
+// and bloom filters as well as internal system keys are never reported. The
+// scan reads a consistent snapshot without taking key locks.
+func (op *Operator) ScanByType(typ DataType, fn func(key string, df *DataFrame) (bool, error)) (err error) {
+	defer op.traceOperation("ScanByType", "")(&err)
+
+	if err := op.ctxErr(); err != nil {
+		return fmt.Errorf("failed to create iterator: %w", err)
+	}
+
+	iter, err := op.db.NewIter(&pebble.IterOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := op.ctxErr(); err != nil {
+			return fmt.Errorf("iterator error: %w", err)
+		}
+
+		value := iter.Value()
+		if valueType, _, _, err := parseFrameHeader(value); err != nil || valueType != typ {
+			continue
+		}
+
+		key := string(iter.Key())
+		if isSystemKey(key) || isItemKey(key) {
+			continue
+		}
+
+		df, err := op.unmarshal(key, value)
+		if err != nil {
+			if IsDataframeExpiredError(err) != nil {
+				continue
+			}
+			return fmt.Errorf("failed to unmarshal dataframe for key %s: %w", key, err)
+		}
+
+		more, err := fn(key, df)
+		if err != nil {
+			return fmt.Errorf("callback error for key %s: %w", key, err)
+		}
+		if !more {
+			return nil
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("iterator error: %w", err)
+	}
+
+	return nil
+}
+
+// ScanKeys calls fn with every top-level key starting with prefix and the
+// type of its value, in key order, until fn returns false. Compound items,
+// internal system keys and expired keys are skipped. Like ScanByType it reads
+// a consistent snapshot without taking key locks.
+func (op *Operator) ScanKeys(prefix string, fn func(key string, typ DataType) bool) (err error) {
+	defer op.traceOperation("ScanKeys", prefix)(&err)
+
+	return op.scanKeys(op.db, prefix, nil, fn)
+}
+
+// ScanKeysPage returns up to limit top-level keys starting with prefix,
+// resuming after cursor. Pass a nil cursor to start from the beginning; the
+// returned cursor is nil once no keys remain.
+func (op *Operator) ScanKeysPage(prefix string, cursor []byte, limit int) (keys []string, next []byte, err error) {
+	defer op.traceOperation("ScanKeysPage", prefix)(&err)
+
+	if limit <= 0 {
+		return nil, nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+	if len(cursor) > 0 && !strings.HasPrefix(string(cursor), prefix) {
+		return nil, nil, fmt.Errorf("cursor does not belong to prefix %q", prefix)
+	}
+
+	keys = make([]string, 0, limit)
+	err = op.scanKeys(op.db, prefix, cursor, func(key string, typ DataType) bool {
+		if len(keys) == limit {
+			// Another key exists, so the page is not the last one
+			next = append([]byte(keys[len(keys)-1]), 0)
+			return false
+		}
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keys, next, nil
+}
+
+// scanKeys walks the top-level keys in r starting with prefix from the
+// inclusive lower bound from, or from the prefix itself when from is empty.
+func (op *Operator) scanKeys(r storeReader, prefix string, from []byte, fn func(key string, typ DataType) bool) error {
+	if err := op.ctxErr(); err != nil {
+		return fmt.Errorf("failed to create iterator: %w", err)
+	}
+
+	lower := []byte(prefix)
+	if len(from) > 0 {
+		lower = from
+	}
+
+	iter, err := r.NewIter(&pebble.IterOptions{
+		LowerBound: lower,
+		UpperBound: []byte(prefix + "\xff"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := op.ctxErr(); err != nil {
+			return fmt.Errorf("iterator error: %w", err)
+		}
+
+		key := string(iter.Key())
+		if isSystemKey(key) || isItemKey(key) {
+			continue
+		}
+
+		df, err := op.unmarshal(key, iter.Value())
+		if err != nil {
+			if IsDataframeExpiredError(err) != nil {
+				continue
+			}
+			return fmt.Errorf("failed to unmarshal dataframe for key %s: %w", key, err)
+		}
+
+		if !fn(key, df.Type()) {
+			return nil
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("iterator error: %w", err)
+	}
+
+	return nil
+}
+
+var itemKeyMarkers = []string{
+	":" + ListTypeMarker + ":",
+	":" + SetTypeMarker + ":",
+	":" + MapTypeMarker + ":",
+	":" + TimeseriesTypeMarker + ":",
+	":" + BloomFilterTypeMarker + ":",
+	":" + SortedSetTypeMarker + ":",
+}
+
+// isItemKey reports whether key stores an item of a compound structure
+// rather than a top-level value.
+func isItemKey(key string) bool {
+	for _, marker := range itemKeyMarkers {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeExpired deletes an expired key together with every item stored under
+// it when it is a compound structure. Items carry no expiration of their own,
+// so this is what keeps them from being orphaned. The caller must hold the key
+// lock.
+func (op *Operator) removeExpired(key string, df *DataFrame) error {
+	entryKey, err := compoundEntryKey(df)
+	if err != nil {
+		return err
+	}
+
+	batch := op.db.NewBatch()
+	defer batch.Close()
+
+	if entryKey != "" {
+		if err := batch.DeleteRange([]byte(entryKey+":"), []byte(entryKey+";"), nil); err != nil {
+			return fmt.Errorf("failed to delete items of %s: %w", key, err)
+		}
+	}
+	if err := batch.Delete([]byte(key), nil); err != nil {
+		return fmt.Errorf("failed to delete key %s: %w", key, err)
+	}
+
+	if err := op.commitExpired(batch); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return nil
+}
+
+func (op *Operator) rangePrefix(prefix string, fn func(key string, df *DataFrame) error) error {
+	if err := op.ctxErr(); err != nil {
+		return fmt.Errorf("failed to create iterator: %w", err)
+	}
+
+	iter, err := op.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(prefix),
+		UpperBound: []byte(prefix + "\xff"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create iterator: %w", err)
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := op.ctxErr(); err != nil {
+			return fmt.Errorf("iterator error: %w", err)
+		}
+
 		key := string(iter.Key())
-		df, err := UnmarshalDataFrame(iter.Value())
+		df, err := op.unmarshal(key, iter.Value())
 		if err != nil {
 			return fmt.Errorf("failed to unmarshal dataframe for key %s: %w", key, err)
 		}
 		if err := fn(key, df); err != nil {
+			if errors.Is(err, errStopRange) {
+				break
+			}
 			return fmt.Errorf("callback error for key %s: %w", key, err)
 		}
 	}
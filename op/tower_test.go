@@ -1,7 +1,18 @@
 ﻿package op
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/RoaringBitmap/roaring/v2/roaring64"
+	"github.com/cockroachdb/pebble"
+	"github.com/google/uuid"
 
 	"github.com/rivulet-io/tower/util/size"
 )
@@ -208,12 +219,1371 @@ func TestTowerConcurrency(t *testing.T) {
 		key := "lock_test_key"
 
 		// Test exclusive lock
-		unlock1 := tower.lock(key)
+		unlock1, err := tower.lock(key)
+		if err != nil {
+			t.Fatalf("Failed to lock: %v", err)
+		}
 		unlock1()
 
 		// Test read lock
-		unlock2 := tower.lock(key)
+		unlock2, err := tower.lock(key)
+		if err != nil {
+			t.Fatalf("Failed to lock: %v", err)
+		}
 		unlock2()
 	})
 }
 
+func TestTowerDeleteRange(t *testing.T) {
+	tower, err := NewOperator(&Options{
+		Path:         "data",
+		FS:           InMemory(),
+		CacheSize:    size.NewSizeFromMegabytes(64),
+		MemTableSize: size.NewSizeFromMegabytes(16),
+		BytesPerSync: size.NewSizeFromKilobytes(512),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tower: %v", err)
+	}
+	defer tower.Close()
+
+	keys := []string{"event:001", "event:002", "event:003", "event:004", "other"}
+	for _, key := range keys {
+		if err := tower.SetString(key, key); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+
+	if err := tower.DeleteRange("event:001", "event:004"); err != nil {
+		t.Fatalf("DeleteRange failed: %v", err)
+	}
+
+	for _, key := range []string{"event:001", "event:002", "event:003"} {
+		if _, err := tower.GetString(key); err == nil {
+			t.Errorf("Expected %s to be deleted", key)
+		}
+	}
+
+	for _, key := range []string{"event:004", "other"} {
+		if _, err := tower.GetString(key); err != nil {
+			t.Errorf("Expected %s to survive range delete: %v", key, err)
+		}
+	}
+
+	if err := tower.DeleteRange("b", "a"); err == nil {
+		t.Error("Expected error for inverted range")
+	}
+}
+
+func TestTowerKeyMetadata(t *testing.T) {
+	newOperator := func(track bool) *Operator {
+		tower, err := NewOperator(&Options{
+			Path:            "data",
+			FS:              InMemory(),
+			CacheSize:       size.NewSizeFromMegabytes(64),
+			MemTableSize:    size.NewSizeFromMegabytes(16),
+			BytesPerSync:    size.NewSizeFromKilobytes(512),
+			TrackTimestamps: track,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create tower: %v", err)
+		}
+		return tower
+	}
+
+	t.Run("tracked", func(t *testing.T) {
+		tower := newOperator(true)
+		defer tower.Close()
+
+		key := "meta_key"
+		if err := tower.SetString(key, "v1"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+
+		created, modified, err := tower.KeyMetadata(key)
+		if err != nil {
+			t.Fatalf("Failed to get key metadata: %v", err)
+		}
+		if created.IsZero() || modified.IsZero() {
+			t.Fatalf("Expected timestamps to be recorded, got created=%v modified=%v", created, modified)
+		}
+
+		if err := tower.SetString(key, "v2"); err != nil {
+			t.Fatalf("Failed to overwrite string: %v", err)
+		}
+		if _, err := tower.AppendString(key, "!"); err != nil {
+			t.Fatalf("Failed to append string: %v", err)
+		}
+
+		created2, modified2, err := tower.KeyMetadata(key)
+		if err != nil {
+			t.Fatalf("Failed to get key metadata: %v", err)
+		}
+		if !created2.Equal(created) {
+			t.Errorf("Expected created time to be preserved, got %v want %v", created2, created)
+		}
+		if modified2.Before(modified) {
+			t.Errorf("Expected modified time to advance, got %v before %v", modified2, modified)
+		}
+
+		value, err := tower.GetString(key)
+		if err != nil {
+			t.Fatalf("Failed to get string: %v", err)
+		}
+		if value != "v2!" {
+			t.Errorf("Expected v2!, got %s", value)
+		}
+	})
+
+	t.Run("untracked", func(t *testing.T) {
+		tower := newOperator(false)
+		defer tower.Close()
+
+		key := "meta_key"
+		if err := tower.SetString(key, "v1"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+
+		created, modified, err := tower.KeyMetadata(key)
+		if err != nil {
+			t.Fatalf("Failed to get key metadata: %v", err)
+		}
+		if !created.IsZero() || !modified.IsZero() {
+			t.Errorf("Expected zero timestamps, got created=%v modified=%v", created, modified)
+		}
+
+		if _, _, err := tower.KeyMetadata("missing"); err == nil {
+			t.Error("Expected error for missing key")
+		}
+	})
+
+	t.Run("header compatibility", func(t *testing.T) {
+		df := NULLDataFrame()
+		df.SetString("plain")
+		data, err := df.Marshal()
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+		if data[1]&dataFrameTimestampFlag != 0 || len(data) != dataFrameHeaderSize+8+len(df.payload) {
+			t.Errorf("Expected no timestamps in the header of an untracked frame")
+		}
+
+		df.createdAt = time.Unix(100, 0)
+		df.modifiedAt = time.Unix(200, 0)
+		data, err = df.Marshal()
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+
+		decoded, err := UnmarshalDataFrame(data)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		if decoded.Type() != TypeString {
+			t.Errorf("Expected TypeString, got %v", decoded.Type())
+		}
+		if !decoded.CreatedAt().Equal(df.createdAt) || !decoded.ModifiedAt().Equal(df.modifiedAt) {
+			t.Errorf("Timestamps did not round-trip")
+		}
+		if value, _ := decoded.String(); value != "plain" {
+			t.Errorf("Expected payload 'plain', got %q", value)
+		}
+	})
+}
+
+func TestTowerWithContext(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	t.Run("active context", func(t *testing.T) {
+		bound := tower.WithContext(context.Background())
+		if err := bound.SetString("ctx:active", "hello"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+
+		value, err := tower.GetString("ctx:active")
+		if err != nil {
+			t.Fatalf("Failed to get string: %v", err)
+		}
+		if value != "hello" {
+			t.Errorf("Expected hello, got %s", value)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		bound := tower.WithContext(ctx)
+		if err := bound.SetString("ctx:cancelled", "hello"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+
+		if _, err := tower.GetString("ctx:cancelled"); err == nil {
+			t.Error("Expected key to be absent after cancelled write")
+		}
+	})
+
+	t.Run("deadline while waiting for lock", func(t *testing.T) {
+		if err := tower.SetString("ctx:locked", "hello"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+
+		unlock, err := tower.lock("ctx:locked")
+		if err != nil {
+			t.Fatalf("Failed to lock: %v", err)
+		}
+		defer unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err = tower.WithContext(ctx).GetString("ctx:locked")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected prompt return, took %v", elapsed)
+		}
+	})
+
+	t.Run("locks fail once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// A free lock is not taken either
+		if _, err := tower.WithContext(ctx).lock("ctx:free"); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+
+		unlock, err := tower.lock("ctx:b")
+		if err != nil {
+			t.Fatalf("Failed to lock: %v", err)
+		}
+		defer unlock()
+
+		ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := tower.WithContext(ctx).lockKeys("ctx:a", "ctx:b"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+		}
+		// The lock taken before the deadline is released again
+		if locks := tower.LockTableSnapshot(); len(locks) != 1 || locks[0].Key != "ctx:b" {
+			t.Errorf("Expected only ctx:b to stay locked, got %v", locks)
+		}
+	})
+}
+
+func TestTowerMemoryUsage(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	t.Run("primitive", func(t *testing.T) {
+		if err := tower.SetString("mem:str", "hello"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+
+		usage, err := tower.MemoryUsage("mem:str")
+		if err != nil {
+			t.Fatalf("Failed to get memory usage: %v", err)
+		}
+		if usage < int64(len("mem:str")+len("hello")) {
+			t.Errorf("Expected usage to cover key and value, got %d", usage)
+		}
+	})
+
+	t.Run("list grows with items", func(t *testing.T) {
+		if err := tower.CreateList("mem:list"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+
+		empty, err := tower.MemoryUsage("mem:list")
+		if err != nil {
+			t.Fatalf("Failed to get memory usage: %v", err)
+		}
+
+		for i := 0; i < 10; i++ {
+			if _, err := tower.PushRightList("mem:list", PrimitiveString("item-payload")); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+		}
+
+		full, err := tower.MemoryUsage("mem:list")
+		if err != nil {
+			t.Fatalf("Failed to get memory usage: %v", err)
+		}
+		if full-empty < int64(10*len("item-payload")) {
+			t.Errorf("Expected usage to grow by at least the item payloads, got %d -> %d", empty, full)
+		}
+	})
+
+	t.Run("left pushed items count", func(t *testing.T) {
+		// Left pushes take negative indexes, whose keys start with 0xff
+		for _, key := range []string{"mem:lpush", "mem:rpush"} {
+			if err := tower.CreateList(key); err != nil {
+				t.Fatalf("Failed to create list: %v", err)
+			}
+		}
+		for i := 0; i < 10; i++ {
+			if _, err := tower.PushLeftList("mem:lpush", PrimitiveString("item-payload")); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+			if _, err := tower.PushRightList("mem:rpush", PrimitiveString("item-payload")); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+		}
+
+		left, err := tower.MemoryUsage("mem:lpush")
+		if err != nil {
+			t.Fatalf("Failed to get memory usage: %v", err)
+		}
+		right, err := tower.MemoryUsage("mem:rpush")
+		if err != nil {
+			t.Fatalf("Failed to get memory usage: %v", err)
+		}
+		// Only the head and tail indexes in the metadata differ
+		if left < int64(10*len("item-payload")) || left < right-16 || left > right+16 {
+			t.Errorf("Expected left and right pushed lists to measure about the same, got %d and %d", left, right)
+		}
+	})
+
+	t.Run("set counts members", func(t *testing.T) {
+		if err := tower.CreateSet("mem:set"); err != nil {
+			t.Fatalf("Failed to create set: %v", err)
+		}
+
+		empty, err := tower.MemoryUsage("mem:set")
+		if err != nil {
+			t.Fatalf("Failed to get memory usage: %v", err)
+		}
+
+		if _, err := tower.AddSetMember("mem:set", PrimitiveString("member")); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+
+		full, err := tower.MemoryUsage("mem:set")
+		if err != nil {
+			t.Fatalf("Failed to get memory usage: %v", err)
+		}
+		if full <= empty {
+			t.Errorf("Expected usage to grow after adding a member, got %d -> %d", empty, full)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		if _, err := tower.MemoryUsage("mem:missing"); err == nil {
+			t.Error("Expected error for missing key")
+		}
+	})
+}
+
+func TestTowerRenameKeyNX(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	t.Run("primitive", func(t *testing.T) {
+		if err := tower.SetString("rename:src", "value"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+
+		renamed, err := tower.RenameKeyNX("rename:src", "rename:dest")
+		if err != nil {
+			t.Fatalf("Failed to rename: %v", err)
+		}
+		if !renamed {
+			t.Fatal("Expected rename to happen")
+		}
+
+		if _, err := tower.GetString("rename:src"); err == nil {
+			t.Error("Expected source key to be gone")
+		}
+
+		value, err := tower.GetString("rename:dest")
+		if err != nil {
+			t.Fatalf("Failed to get renamed key: %v", err)
+		}
+		if value != "value" {
+			t.Errorf("Expected value, got %s", value)
+		}
+	})
+
+	t.Run("destination exists", func(t *testing.T) {
+		if err := tower.SetString("rename:a", "a"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if err := tower.SetString("rename:b", "b"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+
+		renamed, err := tower.RenameKeyNX("rename:a", "rename:b")
+		if err != nil {
+			t.Fatalf("Failed to rename: %v", err)
+		}
+		if renamed {
+			t.Error("Expected rename to be refused")
+		}
+
+		if value, _ := tower.GetString("rename:a"); value != "a" {
+			t.Errorf("Expected source to be intact, got %s", value)
+		}
+		if value, _ := tower.GetString("rename:b"); value != "b" {
+			t.Errorf("Expected destination to be intact, got %s", value)
+		}
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		if _, err := tower.RenameKeyNX("rename:missing", "rename:other"); err == nil {
+			t.Error("Expected error for missing source")
+		}
+	})
+
+	t.Run("list with items", func(t *testing.T) {
+		if err := tower.CreateList("rename:tmp"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := tower.PushRightList("rename:tmp", PrimitiveInt(i)); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+		}
+		if _, err := tower.PushLeftList("rename:tmp", PrimitiveInt(-1)); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+
+		renamed, err := tower.RenameKeyNX("rename:tmp", "rename:final")
+		if err != nil || !renamed {
+			t.Fatalf("Expected rename to happen, got (%v, %v)", renamed, err)
+		}
+
+		values, err := tower.GetListRange("rename:final", 0, -1)
+		if err != nil {
+			t.Fatalf("Failed to get list range: %v", err)
+		}
+		expected := []int64{-1, 0, 1, 2}
+		if len(values) != len(expected) {
+			t.Fatalf("Expected %d items, got %d", len(expected), len(values))
+		}
+		for i, v := range values {
+			if n, _ := v.Int(); n != expected[i] {
+				t.Errorf("Expected item %d to be %d, got %d", i, expected[i], n)
+			}
+		}
+
+		if exists, _ := tower.ExistsList("rename:tmp"); exists {
+			t.Error("Expected source list to be gone")
+		}
+		if removed, err := tower.RepairList("rename:tmp"); err != nil || removed != 0 {
+			t.Errorf("Expected no leftover items under source, got (%d, %v)", removed, err)
+		}
+	})
+
+	t.Run("set members", func(t *testing.T) {
+		if err := tower.CreateSet("rename:set"); err != nil {
+			t.Fatalf("Failed to create set: %v", err)
+		}
+		if _, err := tower.AddSetMember("rename:set", PrimitiveString("m")); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+
+		if renamed, err := tower.RenameKeyNX("rename:set", "rename:set2"); err != nil || !renamed {
+			t.Fatalf("Expected rename to happen, got (%v, %v)", renamed, err)
+		}
+
+		ok, err := tower.ContainsSetMember("rename:set2", PrimitiveString("m"))
+		if err != nil || !ok {
+			t.Errorf("Expected member in renamed set, got (%v, %v)", ok, err)
+		}
+	})
+}
+
+func TestTowerCheckAndSetMulti(t *testing.T) {
+	t.Run("all conditions match", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		if err := tower.SetString("order:state", "pending"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if err := tower.SetInt("order:paid", 1); err != nil {
+			t.Fatalf("Failed to set int: %v", err)
+		}
+
+		ok, err := tower.CheckAndSetMulti(
+			map[string]PrimitiveData{
+				"order:state": PrimitiveString("pending"),
+				"order:paid":  PrimitiveInt(1),
+				"order:lock":  nil,
+			},
+			map[string]PrimitiveData{
+				"order:state":   PrimitiveString("shipped"),
+				"order:shipped": PrimitiveBool(true),
+			},
+		)
+		if err != nil || !ok {
+			t.Fatalf("Expected (true, nil), got (%v, %v)", ok, err)
+		}
+
+		if v, _ := tower.GetString("order:state"); v != "shipped" {
+			t.Errorf("Expected state 'shipped', got %q", v)
+		}
+		if v, _ := tower.GetBool("order:shipped"); !v {
+			t.Error("Expected shipped flag to be written")
+		}
+	})
+
+	t.Run("one condition fails", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		if err := tower.SetString("order:state", "pending"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if err := tower.SetInt("order:paid", 0); err != nil {
+			t.Fatalf("Failed to set int: %v", err)
+		}
+
+		ok, err := tower.CheckAndSetMulti(
+			map[string]PrimitiveData{
+				"order:state": PrimitiveString("pending"),
+				"order:paid":  PrimitiveInt(1),
+			},
+			map[string]PrimitiveData{
+				"order:state":   PrimitiveString("shipped"),
+				"order:shipped": PrimitiveBool(true),
+			},
+		)
+		if err != nil || ok {
+			t.Fatalf("Expected (false, nil), got (%v, %v)", ok, err)
+		}
+
+		if v, _ := tower.GetString("order:state"); v != "pending" {
+			t.Errorf("Expected state to stay 'pending', got %q", v)
+		}
+		if _, err := tower.GetBool("order:shipped"); err == nil {
+			t.Error("Expected no writes to be applied")
+		}
+	})
+
+	t.Run("type mismatch and absence", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		if err := tower.SetString("a", "1"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+
+		ok, err := tower.CheckAndSetMulti(
+			map[string]PrimitiveData{"a": PrimitiveInt(1)},
+			map[string]PrimitiveData{"b": PrimitiveInt(2)},
+		)
+		if err != nil || ok {
+			t.Errorf("Expected type mismatch to fail the check, got (%v, %v)", ok, err)
+		}
+
+		ok, err = tower.CheckAndSetMulti(
+			map[string]PrimitiveData{"a": nil},
+			map[string]PrimitiveData{"b": PrimitiveInt(2)},
+		)
+		if err != nil || ok {
+			t.Errorf("Expected existing key to fail an absence check, got (%v, %v)", ok, err)
+		}
+	})
+}
+
+func TestTowerScanByType(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	for _, key := range []string{"s1", "s2", "s3"} {
+		if err := tower.SetString(key, "value"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+	}
+	if err := tower.SetInt("counter", 1); err != nil {
+		t.Fatalf("Failed to set int: %v", err)
+	}
+
+	// Compound items hold strings too but must not be reported
+	if err := tower.CreateList("list"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if _, err := tower.PushRightList("list", PrimitiveString("item")); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if err := tower.CreateMap("map"); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+	if err := tower.SetMapKey("map", PrimitiveString("field"), PrimitiveString("value")); err != nil {
+		t.Fatalf("Failed to set map field: %v", err)
+	}
+
+	// The TTL bookkeeping list is internal
+	if err := tower.SetTTL("s3", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to set TTL: %v", err)
+	}
+
+	scan := func(typ DataType) []string {
+		var keys []string
+		err := tower.ScanByType(typ, func(key string, df *DataFrame) (bool, error) {
+			if df.Type() != typ {
+				t.Errorf("Expected type %v for key %s, got %v", typ, key, df.Type())
+			}
+			keys = append(keys, key)
+			return true, nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to scan: %v", err)
+		}
+		return keys
+	}
+
+	if keys := scan(TypeString); fmt.Sprint(keys) != "[s1 s2 s3]" {
+		t.Errorf("Expected [s1 s2 s3], got %v", keys)
+	}
+	if keys := scan(TypeList); fmt.Sprint(keys) != "[list]" {
+		t.Errorf("Expected [list], got %v", keys)
+	}
+	if keys := scan(TypeInt); fmt.Sprint(keys) != "[counter]" {
+		t.Errorf("Expected [counter], got %v", keys)
+	}
+
+	t.Run("stops early", func(t *testing.T) {
+		var keys []string
+		err := tower.ScanByType(TypeString, func(key string, df *DataFrame) (bool, error) {
+			keys = append(keys, key)
+			return len(keys) < 2, nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to scan: %v", err)
+		}
+		if len(keys) != 2 {
+			t.Errorf("Expected scan to stop after 2 keys, got %v", keys)
+		}
+	})
+}
+
+func TestTowerScanKeys(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	for _, key := range []string{"app:name", "app:version", "other:name"} {
+		if err := tower.SetString(key, "value"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+	}
+	if err := tower.CreateList("app:queue"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := tower.PushRightList("app:queue", PrimitiveInt(int64(i))); err != nil {
+			t.Fatalf("Failed to push: %v", err)
+		}
+	}
+	if err := tower.CreateMap("app:config"); err != nil {
+		t.Fatalf("Failed to create map: %v", err)
+	}
+	if err := tower.SetMapKey("app:config", PrimitiveString("debug"), PrimitiveBool(true)); err != nil {
+		t.Fatalf("Failed to set map field: %v", err)
+	}
+	if err := tower.SetTTL("app:name", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to set TTL: %v", err)
+	}
+
+	var keys []string
+	types := make(map[string]DataType)
+	err := tower.ScanKeys("app:", func(key string, typ DataType) bool {
+		keys = append(keys, key)
+		types[key] = typ
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Failed to scan keys: %v", err)
+	}
+	if fmt.Sprint(keys) != "[app:config app:name app:queue app:version]" {
+		t.Errorf("Expected only top-level app keys, got %v", keys)
+	}
+	if types["app:queue"] != TypeList || types["app:config"] != TypeMap || types["app:name"] != TypeString {
+		t.Errorf("Unexpected key types: %v", types)
+	}
+
+	t.Run("stops early", func(t *testing.T) {
+		count := 0
+		err := tower.ScanKeys("", func(key string, typ DataType) bool {
+			count++
+			return false
+		})
+		if err != nil || count != 1 {
+			t.Errorf("Expected scan to stop after 1 key, got %d (%v)", count, err)
+		}
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		var all []string
+		var cursor []byte
+		pages := 0
+		for {
+			page, next, err := tower.ScanKeysPage("app:", cursor, 3)
+			if err != nil {
+				t.Fatalf("Failed to scan page: %v", err)
+			}
+			all = append(all, page...)
+			pages++
+			if next == nil {
+				break
+			}
+			cursor = next
+		}
+		if pages != 2 {
+			t.Errorf("Expected 2 pages, got %d", pages)
+		}
+		if fmt.Sprint(all) != "[app:config app:name app:queue app:version]" {
+			t.Errorf("Expected all app keys across pages, got %v", all)
+		}
+
+		page, next, err := tower.ScanKeysPage("app:", nil, 4)
+		if err != nil || len(page) != 4 || next != nil {
+			t.Errorf("Expected a single full page without cursor, got %v %q (%v)", page, next, err)
+		}
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		if _, _, err := tower.ScanKeysPage("app:", nil, 0); err == nil {
+			t.Error("Expected error for zero limit")
+		}
+		if _, _, err := tower.ScanKeysPage("app:", []byte("other:name"), 10); err == nil {
+			t.Error("Expected error for cursor outside the prefix")
+		}
+	})
+}
+
+func TestTowerTypeOfAndExists(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	id := uuid.New()
+	tests := []struct {
+		typ   DataType
+		write func(key string) error
+	}{
+		{TypeInt, func(key string) error { return tower.SetInt(key, 1) }},
+		{TypeFloat, func(key string) error { return tower.SetFloat(key, 1.5) }},
+		{TypeDecimal, func(key string) error { return tower.SetDecimal(key, big.NewInt(125), 2) }},
+		{TypeBigInt, func(key string) error { return tower.SetBigInt(key, big.NewInt(1)) }},
+		{TypeString, func(key string) error { return tower.SetString(key, "value") }},
+		{TypeBool, func(key string) error { return tower.SetBool(key, true) }},
+		{TypeTimestamp, func(key string) error { return tower.SetTimestamp(key, time.Now()) }},
+		{TypeTime, func(key string) error { return tower.SetTime(key, time.Now()) }},
+		{TypeDuration, func(key string) error { return tower.SetDuration(key, time.Second) }},
+		{TypeBinary, func(key string) error { return tower.SetBinary(key, []byte{1, 2}) }},
+		{TypeUUID, func(key string) error { return tower.SetUUID(key, &id) }},
+		{TypeJSON, func(key string) error { return tower.SetJSON(key, []byte(`{"a":1}`)) }},
+		{TypeRoaringBitmap, func(key string) error { return tower.SetRoaringBitmap(key, roaring.BitmapOf(1, 2)) }},
+		{TypeRoaringBitmap64, func(key string) error { return tower.SetRoaringBitmap64(key, roaring64.BitmapOf(1, 2)) }},
+		{TypePassword, func(key string) error {
+			return tower.UpsertPassword(key, []byte("secret"), PasswordAlgorithmBcrypt, DefaultPasswordSaltLength)
+		}},
+		{TypeSafeBox, func(key string) error {
+			_, err := tower.UpsertSafeBox(key, []byte("secret"), []byte("my-encryption-key-32-bytes-long!"), EncryptionAlgorithmAES256GCM)
+			return err
+		}},
+		{TypeList, func(key string) error { return tower.CreateList(key) }},
+		{TypeMap, func(key string) error { return tower.CreateMap(key) }},
+		{TypeSet, func(key string) error { return tower.CreateSet(key) }},
+		{TypeTimeseries, func(key string) error { return tower.CreateTimeSeries(key) }},
+		{TypeBloomFilter, func(key string) error { return tower.CreateBloomFilter(key, 3) }},
+		{TypeShamirShare, func(key string) error {
+			return tower.SetShamirShare(key, map[byte][]byte{1: []byte("share")})
+		}},
+		{TypeSortedSet, func(key string) error { return tower.CreateSortedSet(key) }},
+		{TypeHyperLogLog, func(key string) error { return tower.CreateHLL(key) }},
+		{TypeCountMinSketch, func(key string) error { return tower.CreateCMS(key, 0.01, 0.01) }},
+		{TypeGeo, func(key string) error { return tower.CreateGeo(key) }},
+	}
+
+	for _, tt := range tests {
+		key := fmt.Sprintf("type_%d", tt.typ)
+		if err := tt.write(key); err != nil {
+			t.Fatalf("Failed to write type %v: %v", tt.typ, err)
+		}
+
+		typ, err := tower.TypeOf(key)
+		if err != nil || typ != tt.typ {
+			t.Errorf("Expected type %v for %s, got %v (%v)", tt.typ, key, typ, err)
+		}
+
+		exists, err := tower.Exists(key)
+		if err != nil || !exists {
+			t.Errorf("Expected %s to exist, got %v (%v)", key, exists, err)
+		}
+	}
+
+	t.Run("missing key", func(t *testing.T) {
+		if _, err := tower.TypeOf("missing"); !isNotExist(err) {
+			t.Errorf("Expected not-exist error, got %v", err)
+		}
+		exists, err := tower.Exists("missing")
+		if err != nil || exists {
+			t.Errorf("Expected missing key to not exist, got %v (%v)", exists, err)
+		}
+	})
+
+	t.Run("expired key", func(t *testing.T) {
+		if err := tower.SetString("expiring", "value"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if err := tower.SetTTL("expiring", time.Now().Add(50*time.Millisecond)); err != nil {
+			t.Fatalf("Failed to set TTL: %v", err)
+		}
+		time.Sleep(1500 * time.Millisecond) // Now() may lag by up to a timer tick
+
+		if _, err := tower.TypeOf("expiring"); !isNotExist(err) {
+			t.Errorf("Expected not-exist error for expired key, got %v", err)
+		}
+		exists, err := tower.Exists("expiring")
+		if err != nil || exists {
+			t.Errorf("Expected expired key to not exist, got %v (%v)", exists, err)
+		}
+	})
+
+	t.Run("compact encoding", func(t *testing.T) {
+		compact, err := NewOperator(&Options{
+			Path:            "data",
+			FS:              InMemory(),
+			CacheSize:       size.NewSizeFromMegabytes(1),
+			MemTableSize:    size.NewSizeFromMegabytes(1),
+			BytesPerSync:    size.NewSizeFromKilobytes(512),
+			CompactEncoding: true,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create tower: %v", err)
+		}
+		defer compact.Close()
+
+		if err := compact.SetString("key", "value"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if err := compact.SetTTL("key", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("Failed to set TTL: %v", err)
+		}
+		typ, err := compact.TypeOf("key")
+		if err != nil || typ != TypeString {
+			t.Errorf("Expected string type, got %v (%v)", typ, err)
+		}
+	})
+}
+
+func TestTowerLazyExpireOnRead(t *testing.T) {
+	rawKeys := func(t *testing.T, tower *Operator, prefix string) int {
+		t.Helper()
+		iter, err := tower.db.NewIter(&pebble.IterOptions{
+			LowerBound: []byte(prefix),
+			UpperBound: []byte(prefix + "\xff"),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create iterator: %v", err)
+		}
+		defer iter.Close()
+		n := 0
+		for iter.First(); iter.Valid(); iter.Next() {
+			n++
+		}
+		return n
+	}
+
+	setupExpired := func(t *testing.T, tower *Operator) {
+		t.Helper()
+		if err := tower.CreateList("jobs"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := tower.PushRightList("jobs", PrimitiveInt(int64(i))); err != nil {
+				t.Fatalf("Failed to push: %v", err)
+			}
+		}
+		if err := tower.SetTTL("jobs", Now().Add(time.Second)); err != nil {
+			t.Fatalf("Failed to set TTL: %v", err)
+		}
+		time.Sleep(2500 * time.Millisecond)
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		setupExpired(t, tower)
+		if rawKeys(t, tower, "jobs") == 0 {
+			t.Fatal("Expected expired keys to remain until read")
+		}
+
+		if _, err := tower.GetListLength("jobs"); err == nil {
+			t.Fatal("Expected error reading expired key")
+		}
+
+		if n := rawKeys(t, tower, "jobs"); n != 0 {
+			t.Errorf("Expected key and items to be deleted on read, %d keys left", n)
+		}
+		found := false
+		if err := tower.ScanKeys("", func(key string, typ DataType) bool {
+			found = found || key == "jobs"
+			return true
+		}); err != nil {
+			t.Fatalf("Failed to scan keys: %v", err)
+		}
+		if found {
+			t.Error("Expected expired key to be gone from ScanKeys")
+		}
+	})
+
+	createDisabledTower := func(t *testing.T) *Operator {
+		t.Helper()
+		disabled := false
+		tower, err := NewOperator(&Options{
+			Path:             "test.db",
+			BytesPerSync:     size.NewSizeFromBytes(32 * 1024),
+			CacheSize:        size.NewSizeFromMegabytes(64),
+			MemTableSize:     size.NewSizeFromMegabytes(4),
+			FS:               InMemory(),
+			LazyExpireOnRead: &disabled,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create tower: %v", err)
+		}
+		return tower
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		tower := createDisabledTower(t)
+		defer tower.Close()
+
+		setupExpired(t, tower)
+		before := rawKeys(t, tower, "jobs")
+
+		if _, err := tower.GetListLength("jobs"); err == nil {
+			t.Fatal("Expected error reading expired key")
+		}
+
+		if after := rawKeys(t, tower, "jobs"); after != before {
+			t.Errorf("Expected read to leave expired keys alone, had %d now %d", before, after)
+		}
+
+		// The sweep still removes them. Queue the key in the bucket it reads
+		// now rather than wait for the minute its own bucket covers.
+		bucket := tower.makeTTLKey(tower.floorTTLTimestamp(Now()))
+		if err := tower.EnsureList(bucket); err != nil {
+			t.Fatalf("Failed to create TTL list: %v", err)
+		}
+		if _, err := tower.PushRightList(bucket, PrimitiveString("jobs")); err != nil {
+			t.Fatalf("Failed to queue key: %v", err)
+		}
+		if err := tower.TruncateExpired(); err != nil {
+			t.Fatalf("Failed to truncate expired keys: %v", err)
+		}
+		if n := rawKeys(t, tower, "jobs"); n != 0 {
+			t.Errorf("Expected sweep to delete key and items, %d keys left", n)
+		}
+	})
+
+	t.Run("disabled set members", func(t *testing.T) {
+		tower := createDisabledTower(t)
+		defer tower.Close()
+
+		if err := tower.CreateSet("tags"); err != nil {
+			t.Fatalf("Failed to create set: %v", err)
+		}
+		for _, member := range []string{"a", "b"} {
+			if _, err := tower.AddSetMember("tags", PrimitiveString(member)); err != nil {
+				t.Fatalf("Failed to add member: %v", err)
+			}
+		}
+		for _, member := range []string{"x", "y"} {
+			if _, err := tower.AddSetMemberEx("tags", PrimitiveString(member), time.Second); err != nil {
+				t.Fatalf("Failed to add member with TTL: %v", err)
+			}
+		}
+		time.Sleep(2500 * time.Millisecond)
+
+		// Count as stored, without the purge GetSetCardinality does
+		storedCount := func() uint64 {
+			t.Helper()
+			df, err := tower.get("tags")
+			if err != nil {
+				t.Fatalf("Failed to get set: %v", err)
+			}
+			setData, err := df.Set()
+			if err != nil {
+				t.Fatalf("Failed to get set data: %v", err)
+			}
+			return setData.Count
+		}
+
+		// Reads neither delete the expired member nor take it off the count
+		for i := 0; i < 3; i++ {
+			if ok, err := tower.ContainsSetMember("tags", PrimitiveString("x")); err != nil || ok {
+				t.Fatalf("Expected expired member to be absent, got %v (%v)", ok, err)
+			}
+		}
+		if n := storedCount(); n != 4 {
+			t.Errorf("Expected expired members to stay counted, got %d", n)
+		}
+
+		// Re-adding an expired member replaces it rather than counting twice
+		if n, err := tower.AddSetMember("tags", PrimitiveString("y")); err != nil || n != 4 {
+			t.Errorf("Expected re-added member to keep 4, got %d (%v)", n, err)
+		}
+
+		if n, err := tower.GetSetCardinality("tags"); err != nil || n != 3 {
+			t.Errorf("Expected 3 live members, got %d (%v)", n, err)
+		}
+		if ok, err := tower.ContainsSetMember("tags", PrimitiveString("y")); err != nil || !ok {
+			t.Errorf("Expected re-added member to be live, got %v (%v)", ok, err)
+		}
+	})
+}
+
+func TestTowerLogAndCount(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	if err := tower.CreateList("events"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		count, err := tower.LogAndCount("events:total", "events", PrimitiveString(fmt.Sprintf("event%d", i)))
+		if err != nil {
+			t.Fatalf("Failed to log and count: %v", err)
+		}
+		if count != int64(i) {
+			t.Errorf("Expected count %d, got %d", i, count)
+		}
+	}
+
+	length, err := tower.GetListLength("events")
+	if err != nil || length != 3 {
+		t.Errorf("Expected 3 entries, got %d (%v)", length, err)
+	}
+	last, err := tower.GetListIndex("events", 2)
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if s, _ := last.String(); s != "event3" {
+		t.Errorf("Expected event3, got %s", s)
+	}
+
+	t.Run("nothing changes on failure", func(t *testing.T) {
+		// Unsupported entry type fails the list push
+		if _, err := tower.LogAndCount("events:total", "events", PrimitiveNull{}); err == nil {
+			t.Fatal("Expected error for unsupported entry type")
+		}
+		if v, _ := tower.GetInt("events:total"); v != 3 {
+			t.Errorf("Expected counter to stay 3, got %d", v)
+		}
+
+		// Missing list leaves the counter alone too
+		if _, err := tower.LogAndCount("other:total", "missing", PrimitiveString("x")); err == nil {
+			t.Fatal("Expected error for missing list")
+		}
+		if _, err := tower.GetInt("other:total"); err == nil {
+			t.Error("Expected counter not to be created")
+		}
+
+		// A counter holding another type leaves the list alone
+		if err := tower.SetString("bad:total", "x"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if _, err := tower.LogAndCount("bad:total", "events", PrimitiveString("x")); err == nil {
+			t.Fatal("Expected error for non-int counter")
+		}
+		if length, _ := tower.GetListLength("events"); length != 3 {
+			t.Errorf("Expected list to stay at 3 entries, got %d", length)
+		}
+	})
+}
+
+func TestTowerLockTableSnapshot(t *testing.T) {
+	t.Run("keys only", func(t *testing.T) {
+		tower := createTestTower(t)
+		defer tower.Close()
+
+		if locks := tower.LockTableSnapshot(); len(locks) != 0 {
+			t.Fatalf("Expected no held locks, got %v", locks)
+		}
+
+		unlock, err := tower.lockKeys("b", "a")
+		if err != nil {
+			t.Fatalf("Failed to lock: %v", err)
+		}
+		locks := tower.LockTableSnapshot()
+		if len(locks) != 2 || locks[0].Key != "a" || locks[1].Key != "b" {
+			t.Errorf("Expected locks on a and b, got %v", locks)
+		}
+		if !locks[0].AcquiredAt.IsZero() || locks[0].HeldFor != 0 {
+			t.Errorf("Expected no timing without timestamp tracking, got %v", locks[0])
+		}
+
+		unlock()
+		if locks := tower.LockTableSnapshot(); len(locks) != 0 {
+			t.Errorf("Expected locks to be released, got %v", locks)
+		}
+
+		// Regular operations release their locks
+		if err := tower.SetString("key", "value"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if locks := tower.LockTableSnapshot(); len(locks) != 0 {
+			t.Errorf("Expected no held locks after an operation, got %v", locks)
+		}
+	})
+
+	t.Run("with timestamps", func(t *testing.T) {
+		tower, err := NewOperator(&Options{
+			Path:            "test.db",
+			BytesPerSync:    size.NewSizeFromBytes(32 * 1024),
+			CacheSize:       size.NewSizeFromMegabytes(64),
+			MemTableSize:    size.NewSizeFromMegabytes(4),
+			FS:              InMemory(),
+			TrackTimestamps: true,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create tower: %v", err)
+		}
+		defer tower.Close()
+
+		unlockOld, err := tower.lock("old")
+		if err != nil {
+			t.Fatalf("Failed to lock: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		unlockNew, err := tower.lock("new")
+		if err != nil {
+			t.Fatalf("Failed to lock: %v", err)
+		}
+		defer unlockNew()
+
+		locks := tower.LockTableSnapshot()
+		if len(locks) != 2 {
+			t.Fatalf("Expected 2 held locks, got %v", locks)
+		}
+		if locks[0].Key != "old" {
+			t.Errorf("Expected longest held lock first, got %v", locks)
+		}
+		if locks[0].HeldFor < 20*time.Millisecond {
+			t.Errorf("Expected old lock held for at least 20ms, got %v", locks[0].HeldFor)
+		}
+
+		unlockOld()
+		locks = tower.LockTableSnapshot()
+		if len(locks) != 1 || locks[0].Key != "new" {
+			t.Errorf("Expected only new lock held, got %v", locks)
+		}
+	})
+}
+
+func TestTowerInMemoryIsolation(t *testing.T) {
+	open := func() *Operator {
+		tower, err := NewOperator(&Options{
+			Path:         "data",
+			BytesPerSync: size.NewSizeFromBytes(32 * 1024),
+			CacheSize:    size.NewSizeFromMegabytes(64),
+			MemTableSize: size.NewSizeFromMegabytes(4),
+			FS:           InMemory(),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create tower: %v", err)
+		}
+		return tower
+	}
+
+	first := open()
+	defer first.Close()
+	second := open()
+	defer second.Close()
+
+	if err := first.SetString("first", "1"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if err := second.SetString("second", "2"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if err := second.SetString("shared", "second"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+
+	if _, err := first.GetString("second"); err == nil {
+		t.Error("Expected first tower not to see keys of the second")
+	}
+	if _, err := second.GetString("first"); err == nil {
+		t.Error("Expected second tower not to see keys of the first")
+	}
+	if _, err := first.GetString("shared"); err == nil {
+		t.Error("Expected writes to the same key to stay separate")
+	}
+}
+
+func TestTowerErrorSentinels(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	t.Run("key not found", func(t *testing.T) {
+		ops := map[string]func() error{
+			"GetString": func() error { _, err := tower.GetString("missing"); return err },
+			"GetInt":    func() error { _, err := tower.GetInt("missing"); return err },
+			"GetBigInt": func() error { _, err := tower.GetBigInt("missing"); return err },
+			"TypeOf":    func() error { _, err := tower.TypeOf("missing"); return err },
+			"PopLeftList": func() error {
+				_, err := tower.PopLeftList("missing")
+				return err
+			},
+			"GetMapKey": func() error {
+				_, err := tower.GetMapKey("missing", PrimitiveString("field"))
+				return err
+			},
+			"GetSetMembers": func() error {
+				_, err := tower.GetSetMembers("missing")
+				return err
+			},
+			"GetTimeSeriesPoint": func() error {
+				_, err := tower.GetTimeSeriesPoint("missing", time.Now())
+				return err
+			},
+			"Snapshot": func() error {
+				snap, err := tower.Snapshot()
+				if err != nil {
+					return err
+				}
+				defer snap.Close()
+				_, err = snap.GetString("missing")
+				return err
+			},
+		}
+		for name, op := range ops {
+			if err := op(); !errors.Is(err, ErrKeyNotFound) {
+				t.Errorf("%s: expected ErrKeyNotFound, got %v", name, err)
+			}
+		}
+
+		if err := NewDataframeExpiredError("expired", time.Now()); !errors.Is(err, ErrKeyNotFound) {
+			t.Error("Expected an expired value to match ErrKeyNotFound")
+		}
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		if err := tower.SetString("text", "value"); err != nil {
+			t.Fatalf("Failed to set string: %v", err)
+		}
+		if _, err := tower.GetInt("text"); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("GetInt: expected ErrTypeMismatch, got %v", err)
+		}
+		if _, err := tower.GetBigInt("text"); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("GetBigInt: expected ErrTypeMismatch, got %v", err)
+		}
+		if _, err := tower.PushRightList("text", PrimitiveInt(1)); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("PushRightList: expected ErrTypeMismatch, got %v", err)
+		}
+		if errors.Is(fmt.Errorf("wrapped: %w", ErrKeyNotFound), ErrTypeMismatch) {
+			t.Error("Expected ErrKeyNotFound not to match ErrTypeMismatch")
+		}
+
+		// The sentinel is matched by identity, not by message
+		if errors.Is(&DataFrameError{Op: "Int", Type: TypeString, Msg: "type mismatch"}, ErrTypeMismatch) {
+			t.Error("Expected an error without the sentinel not to match ErrTypeMismatch")
+		}
+		df := NULLDataFrame()
+		if _, err := df.Int(); !errors.Is(err, ErrTypeMismatch) || !strings.Contains(err.Error(), "type mismatch") {
+			t.Errorf("Int: expected ErrTypeMismatch, got %v", err)
+		}
+	})
+
+	t.Run("container exists and list empty", func(t *testing.T) {
+		if err := tower.CreateList("queue"); err != nil {
+			t.Fatalf("Failed to create list: %v", err)
+		}
+		if err := tower.CreateList("queue"); !errors.Is(err, ErrContainerExists) {
+			t.Errorf("CreateList: expected ErrContainerExists, got %v", err)
+		}
+		if err := tower.CreateMap("queue"); !errors.Is(err, ErrContainerExists) {
+			t.Errorf("CreateMap: expected ErrContainerExists, got %v", err)
+		}
+
+		_, err := tower.PopLeftList("queue")
+		if !errors.Is(err, ErrListEmpty) {
+			t.Errorf("PopLeftList: expected ErrListEmpty, got %v", err)
+		}
+		if _, err := tower.GetListIndex("queue", 0); !errors.Is(err, ErrListEmpty) {
+			t.Errorf("GetListIndex: expected ErrListEmpty, got %v", err)
+		}
+		if err := tower.SetListIndex("queue", 0, PrimitiveInt(1)); !errors.Is(err, ErrListEmpty) {
+			t.Errorf("SetListIndex: expected ErrListEmpty, got %v", err)
+		}
+		if errors.Is(err, ErrKeyNotFound) {
+			t.Error("Expected an empty list not to match ErrKeyNotFound")
+		}
+	})
+}
+
+func TestTowerDeletePrefix(t *testing.T) {
+	tower := createTestTower(t)
+	defer tower.Close()
+
+	for i := 0; i < 500; i++ {
+		if err := tower.SetInt(fmt.Sprintf("tenant1:key:%03d", i), int64(i)); err != nil {
+			t.Fatalf("Failed to set key: %v", err)
+		}
+	}
+	if err := tower.CreateList("tenant1:queue"); err != nil {
+		t.Fatalf("Failed to create list: %v", err)
+	}
+	if _, err := tower.PushRightList("tenant1:queue", PrimitiveString("job")); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	for _, key := range []string{"tenant2:key:000", "tenant1", "tenant10:key"} {
+		if err := tower.SetString(key, "keep"); err != nil {
+			t.Fatalf("Failed to set key: %v", err)
+		}
+	}
+
+	deleted, err := tower.DeletePrefix("tenant1:")
+	if err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+	if deleted != 501 {
+		t.Errorf("Expected 501 deleted keys, got %d", deleted)
+	}
+
+	var left []string
+	if err := tower.ScanKeys("tenant1:", func(key string, typ DataType) bool {
+		left = append(left, key)
+		return true
+	}); err != nil {
+		t.Fatalf("ScanKeys failed: %v", err)
+	}
+	if len(left) != 0 {
+		t.Errorf("Expected no keys under the prefix, got %v", left)
+	}
+
+	iter, err := tower.db.NewIter(&pebble.IterOptions{LowerBound: []byte("tenant1:"), UpperBound: []byte("tenant1;")})
+	if err != nil {
+		t.Fatalf("Failed to create iterator: %v", err)
+	}
+	if iter.First() {
+		t.Errorf("Expected list items to be deleted, found %q", iter.Key())
+	}
+	iter.Close()
+
+	for _, key := range []string{"tenant2:key:000", "tenant1", "tenant10:key"} {
+		if value, err := tower.GetString(key); err != nil || value != "keep" {
+			t.Errorf("Expected %s to be kept, got %q (%v)", key, value, err)
+		}
+	}
+
+	if deleted, err := tower.DeletePrefix("tenant1:"); err != nil || deleted != 0 {
+		t.Errorf("Expected nothing left to delete, got %d (%v)", deleted, err)
+	}
+
+	for _, prefix := range []string{"", "__sys", "__system__:ttl"} {
+		if _, err := tower.DeletePrefix(prefix); err == nil {
+			t.Errorf("Expected prefix %q to be rejected", prefix)
+		}
+	}
+}